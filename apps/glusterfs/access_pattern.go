@@ -0,0 +1,48 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"sort"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// orderForAccessPattern returns the device list reordered according
+// to the access pattern of the volume. Bricks of write-intensive
+// volumes go to the devices hosting the fewest bricks first in order
+// to spread the write load. Bricks of read-intensive volumes go to the
+// devices with the most free space first, leaving room for future
+// replicas. For other access patterns the list is returned as is.
+// Devices that compare equal keep their original order.
+func orderForAccessPattern(devicelist SimpleDevices,
+	pattern api.VolumeAccessPattern) SimpleDevices {
+
+	var less func(a, b SimpleDevice) bool
+	switch pattern {
+	case api.AccessPatternWriteIntensive:
+		less = func(a, b SimpleDevice) bool {
+			return a.bricks < b.bricks
+		}
+	case api.AccessPatternReadIntensive:
+		less = func(a, b SimpleDevice) bool {
+			return a.free > b.free
+		}
+	default:
+		return devicelist
+	}
+
+	out := make(SimpleDevices, len(devicelist))
+	copy(out, devicelist)
+	sort.SliceStable(out, func(i, j int) bool {
+		return less(out[i], out[j])
+	})
+	return out
+}
@@ -0,0 +1,153 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/tests"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+func TestOrderForAccessPattern(t *testing.T) {
+	devices := SimpleDevices{
+		{nodeId: "n1", deviceId: "d1", free: 300, bricks: 4},
+		{nodeId: "n2", deviceId: "d2", free: 100, bricks: 0},
+		{nodeId: "n3", deviceId: "d3", free: 500, bricks: 2},
+		{nodeId: "n4", deviceId: "d4", free: 200, bricks: 0},
+	}
+	ids := func(dl SimpleDevices) []string {
+		out := []string{}
+		for _, d := range dl {
+			out = append(out, d.deviceId)
+		}
+		return out
+	}
+
+	out := orderForAccessPattern(devices, api.AccessPatternWriteIntensive)
+	tests.Assert(t, fmt.Sprint(ids(out)) == "[d2 d4 d3 d1]",
+		"unexpected order:", ids(out))
+
+	out = orderForAccessPattern(devices, api.AccessPatternReadIntensive)
+	tests.Assert(t, fmt.Sprint(ids(out)) == "[d3 d1 d4 d2]",
+		"unexpected order:", ids(out))
+
+	for _, pattern := range []api.VolumeAccessPattern{
+		api.AccessPatternNone, api.AccessPatternBalanced} {
+		out = orderForAccessPattern(devices, pattern)
+		tests.Assert(t, fmt.Sprint(ids(out)) == "[d1 d2 d3 d4]",
+			"unexpected order for", pattern, ":", ids(out))
+	}
+
+	// input is not modified
+	tests.Assert(t, devices[0].deviceId == "d1",
+		"expected devices[0].deviceId == d1, got:", devices[0].deviceId)
+}
+
+// setupSampleDbBrickDensity creates a topology where the devices of
+// every node differ in the number of bricks they host and in their
+// free space. The first device hosts 4 bricks and has 300GB free, the
+// second hosts no bricks and has 200GB free and the third hosts 2
+// bricks and has 500GB free.
+func setupSampleDbBrickDensity(t *testing.T, app *App) {
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		3,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	bricks := []int{4, 0, 2}
+	free := []uint64{300 * GB, 200 * GB, 500 * GB}
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		nl, err := NodeList(tx)
+		if err != nil {
+			return err
+		}
+		for _, nodeId := range nl {
+			node, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			for i, deviceId := range node.Devices {
+				device, err := NewDeviceEntryFromId(tx, deviceId)
+				if err != nil {
+					return err
+				}
+				for b := 0; b < bricks[i]; b++ {
+					device.BrickAdd(fmt.Sprintf("%v-brick%v", deviceId, b))
+				}
+				device.Info.Storage.Free = free[i]
+				device.Info.Storage.Used =
+					device.Info.Storage.Total - free[i]
+				if err := device.Save(tx); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestVolumeCreateAccessPatternPlacement(t *testing.T) {
+	checks := []struct {
+		pattern     api.VolumeAccessPattern
+		deviceIndex int
+	}{
+		// the least loaded devices
+		{api.AccessPatternWriteIntensive, 1},
+		// the devices with the most free space
+		{api.AccessPatternReadIntensive, 2},
+	}
+	for _, c := range checks {
+		t.Run(string(c.pattern), func(t *testing.T) {
+			tmpfile := tests.Tempfile()
+			defer os.Remove(tmpfile)
+
+			app := NewTestApp(tmpfile)
+			defer app.Close()
+
+			setupSampleDbBrickDensity(t, app)
+
+			v := createSampleReplicaVolumeEntry(100, 3)
+			v.Info.AccessPattern = c.pattern
+			err := v.Create(app.db, app.executor)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+			err = app.db.View(func(tx *bolt.Tx) error {
+				brickIds := v.BricksIds()
+				tests.Assert(t, len(brickIds) == 3,
+					"expected len(brickIds) == 3, got:", len(brickIds))
+				for _, brickId := range brickIds {
+					brick, err := NewBrickEntryFromId(tx, brickId)
+					if err != nil {
+						return err
+					}
+					node, err := NewNodeEntryFromId(tx, brick.Info.NodeId)
+					if err != nil {
+						return err
+					}
+					expected := node.Devices[c.deviceIndex]
+					tests.Assert(t, brick.Info.DeviceId == expected,
+						"expected brick on device", expected,
+						"got:", brick.Info.DeviceId)
+				}
+				return nil
+			})
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		})
+	}
+}
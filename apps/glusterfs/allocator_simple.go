@@ -9,8 +9,25 @@
 
 package glusterfs
 
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+const (
+	// devices whose free space falls in the same bucket of this
+	// fraction of the largest free space are considered comparable
+	latencyFreeSpaceBucket = 0.10
+)
+
 // Simple allocator contains a map to rings of clusters
 type SimpleAllocator struct {
+	// reorders the devices according to the expected use of
+	// the volume, if set
+	AccessPattern api.VolumeAccessPattern
 }
 
 // Create a new simple allocator
@@ -32,6 +49,8 @@ func loadRingFromDeviceSource(dsrc DeviceSource) (
 			zone:     dan.Node.Info.Zone,
 			nodeId:   dan.Node.Info.Id,
 			deviceId: dan.Device.Info.Id,
+			free:     dan.Device.Info.Storage.Free,
+			bricks:   len(dan.Device.Bricks),
 		})
 	}
 	return ring, nil
@@ -51,6 +70,10 @@ func (s *SimpleAllocator) GetNodesFromDeviceSource(dsrc DeviceSource,
 		return device, done, err
 	}
 	devicelist := ring.GetDeviceList(brickId)
+	if PreferLowLatencyNodes {
+		devicelist = preferLowLatency(devicelist, currentNodeLatency())
+	}
+	devicelist = orderForAccessPattern(devicelist, s.AccessPattern)
 
 	generateDevices(devicelist, device, done)
 	return device, done, nil
@@ -74,3 +97,56 @@ func generateDevices(devicelist SimpleDevices,
 		}
 	}()
 }
+
+// preferLowLatency returns a copy of the device list in which each run
+// of consecutive devices with comparable free space is ordered by the
+// p99 latency of their nodes, lowest first. Devices on nodes with
+// unknown latency are placed after those with known latency within
+// their run. The order of the runs is kept, so the ring's rotation for
+// the brick is preserved. Without latency data for the devices the
+// list is returned unchanged.
+func preferLowLatency(devicelist SimpleDevices,
+	latency map[string]NodeLatency) SimpleDevices {
+
+	var maxFree uint64
+	known := false
+	for _, d := range devicelist {
+		if d.free > maxFree {
+			maxFree = d.free
+		}
+		if _, found := latency[d.nodeId]; found {
+			known = true
+		}
+	}
+	if !known {
+		return devicelist
+	}
+	bucketSize := uint64(float64(maxFree) * latencyFreeSpaceBucket)
+	p99 := func(d SimpleDevice) time.Duration {
+		if l, found := latency[d.nodeId]; found {
+			return l.P99
+		}
+		return time.Duration(math.MaxInt64)
+	}
+	similar := func(a, b SimpleDevice) bool {
+		if a.free > b.free {
+			return a.free-b.free <= bucketSize
+		}
+		return b.free-a.free <= bucketSize
+	}
+
+	out := make(SimpleDevices, len(devicelist))
+	copy(out, devicelist)
+	for start := 0; start < len(out); {
+		end := start + 1
+		for end < len(out) && similar(out[start], out[end]) {
+			end++
+		}
+		run := out[start:end]
+		sort.SliceStable(run, func(i, j int) bool {
+			return p99(run[i]) < p99(run[j])
+		})
+		start = end
+	}
+	return out
+}
@@ -18,6 +18,8 @@ import (
 type SimpleDevice struct {
 	zone             int
 	nodeId, deviceId string
+	free             uint64
+	bricks           int
 }
 
 // Pretty pring a SimpleDevice
@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"net/http"
@@ -26,7 +27,12 @@ import (
 	"github.com/heketi/heketi/executors/kubeexec"
 	"github.com/heketi/heketi/executors/mockexec"
 	"github.com/heketi/heketi/executors/sshexec"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/dlock"
+	"github.com/heketi/heketi/pkg/kubernetes"
 	"github.com/heketi/heketi/pkg/logging"
+	"github.com/heketi/heketi/pkg/paths"
+	"github.com/heketi/heketi/pkg/utils"
 	"github.com/heketi/heketi/server/rest"
 )
 
@@ -42,6 +48,8 @@ const (
 	DB_CLUSTER_HAS_FILE_BLOCK_FLAG = "DB_CLUSTER_HAS_FILE_BLOCK_FLAG"
 	DB_BRICK_HAS_SUBTYPE_FIELD     = "DB_BRICK_HAS_SUBTYPE_FIELD"
 	DEFAULT_OP_LIMIT               = 8
+	DEFAULT_OP_PAGE_LIMIT          = 100
+	DEFAULT_OP_CLEANUP_BATCH_SIZE  = 100
 )
 
 var (
@@ -52,6 +60,19 @@ var (
 	// undefined.
 	// TODO: make a global not needed
 	currentNodeHealthCache *NodeHealthCache
+	// global var to track active node latency cache
+	// (same caveats as the node health cache)
+	currentNodeLatencyCache *NodeLatencyCache
+	// global var to track active thin pool usage cache
+	// (same caveats as the node health cache)
+	currentThinPoolUsageCache *ThinPoolUsageCache
+	// global var to track the bitrot status cache
+	// (same caveats as the node health cache)
+	currentBitrotStatusCache *BitrotStatusCache
+	// global var to track the volume info cache, entries are
+	// invalidated from the db entries
+	// (same caveats as the node health cache)
+	currentVolumeInfoCache *VolumeInfoCache
 
 	// global var to enable the use of the health cache + monitor
 	// when the GlusterFS App is created. This is mildly hacky but
@@ -82,11 +103,49 @@ type App struct {
 
 	// health monitor
 	nhealth *NodeHealthCache
+	// latency monitor
+	nlatency *NodeLatencyCache
+	// thin pool usage monitor
+	tpusage *ThinPoolUsageCache
+	// bitrot status of the volumes
+	bitrot *BitrotStatusCache
+	// geo-replication status of the volumes
+	georep *GeoRepStatusCache
+	// serialized volume info responses
+	volinfo *VolumeInfoCache
+	// offline brick detection
+	bfaults *BrickFaultDetector
 	// background operations cleaner
 	bgcleaner *backgroundOperationCleaner
+	// background operations archiver
+	bgarchiver *backgroundOperationArchiver
+	// kubernetes node label watcher
+	nlabels *kubernetes.NodeLabelWatcher
+	// results of the last device batches
+	dbatches *deviceBatchResults
+	// lock shared with other servers, nil if not configured
+	dlock         dlock.DistributedLock
+	dlockTtl      time.Duration
+	dlockExtender *lockExtender
+
+	// key for the ssh keys stored in the db
+	sshKeyEncKey []byte
+	// caches the ssh keys stored in the db, nil if not enabled
+	sshKeyCache sshKeyInvalidator
+	// records the host keys of the nodes, nil if not enabled
+	knownHosts knownHostUpdater
+
+	// names the vgs of new devices, nil for the default names
+	vgNameTemplate *paths.VgNameTemplate
 
 	// operations tracker
 	optracker *OpTracker
+	// built operations that may still be canceled
+	opcanceler *opCanceler
+
+	// canceled on shutdown to stop running operations
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	// For testing only.  Keep access to the object
 	// not through the interface
@@ -112,6 +171,7 @@ func (app *App) setup(conf *GlusterFSConfig) error {
 	var err error
 
 	app.conf = conf
+	app.ctx, app.cancel = context.WithCancel(context.Background())
 
 	// We would like to perform rebalance by default
 	// As it is very difficult to distinguish missing parameter from
@@ -134,6 +194,8 @@ func (app *App) setup(conf *GlusterFSConfig) error {
 	// Setup asynchronous manager
 	app.asyncManager = rest.NewAsyncHttpManager(ASYNC_ROUTE)
 
+	app.sshKeyEncKey = sshKeyEncryptionKey(app.conf.SshKeyEncryptionKey)
+
 	// Setup executor
 	switch app.conf.Executor {
 	case "mock":
@@ -142,9 +204,9 @@ func (app *App) setup(conf *GlusterFSConfig) error {
 	case "kube", "kubernetes":
 		app.executor, err = kubeexec.NewKubeExecutor(&app.conf.KubeConfig)
 	case "ssh", "":
-		app.executor, err = sshexec.NewSshExecutor(&app.conf.SshConfig)
+		app.executor, err = app.newSshExecutor()
 	case "inject/ssh":
-		app.executor, err = sshexec.NewSshExecutor(&app.conf.SshConfig)
+		app.executor, err = app.newSshExecutor()
 		app.executor = injectexec.NewInjectExecutor(
 			app.executor, &app.conf.InjectConfig)
 	case "inject/mock":
@@ -160,6 +222,12 @@ func (app *App) setup(conf *GlusterFSConfig) error {
 	}
 	logger.Info("Loaded %v executor", app.conf.Executor)
 
+	err = app.setupVgNameTemplate()
+	if err != nil {
+		logger.Err(err)
+		return err
+	}
+
 	// Set db is set in the configuration file
 	if app.conf.DBfile != "" {
 		dbfilename = app.conf.DBfile
@@ -183,16 +251,34 @@ func (app *App) setup(conf *GlusterFSConfig) error {
 				" about managing pending operations.")
 	}
 
+	err = loadVgNames(app.db)
+	if err != nil {
+		logger.Err(err)
+		return err
+	}
+
 	// Set advanced settings
 	app.setAdvSettings()
 
 	// Set block settings
 	app.setBlockSettings()
 
+	err = app.initDistributedLock()
+	if err != nil {
+		logger.Err(err)
+		return err
+	}
+
 	// initialize sub-objects and background tasks
 	app.initOpTracker()
 	app.initNodeMonitor()
 	app.initBackgroundCleaner()
+	app.bitrot = NewBitrotStatusCache(BITROT_STATUS_TTL)
+	currentBitrotStatusCache = app.bitrot
+	app.georep = NewGeoRepStatusCache(GEOREP_STATUS_TTL)
+	app.initVolumeInfoCache()
+	app.initNodeLabelSync()
+	app.dbatches = newDeviceBatchResults(DEVICE_BATCH_RESULTS_SIZE)
 
 	// Show application has loaded
 	logger.Info("GlusterFS Application Loaded")
@@ -209,6 +295,9 @@ func (app *App) initDB() error {
 		app.db, err = OpenDB(dbfilename, false)
 		if err != nil {
 			logger.LogError("Unable to open database read-write: %v", err)
+		} else {
+			wdb.SetBatchWindow(app.db,
+				time.Duration(app.conf.BatchWindowMs)*time.Millisecond)
 		}
 	}
 	if err != nil {
@@ -247,6 +336,129 @@ func (app *App) initDB() error {
 	return err
 }
 
+// newSshExecutor returns an ssh executor that uses the ssh keys
+// stored in the db, if the key store is enabled, and the per-cluster
+// ssh settings of the configuration file.
+func (app *App) newSshExecutor() (*sshexec.SshExecutor, error) {
+	s, err := sshexec.NewSshExecutor(&app.conf.SshConfig)
+	if err != nil {
+		return nil, err
+	}
+	if app.sshKeyEncKey != nil {
+		s.SetKeySource(&sshKeyStore{app: app})
+		app.sshKeyCache = s
+	}
+	if len(app.conf.SshConfig.Clusters) > 0 {
+		s.SetHostClusterSource(&hostClusterStore{app: app})
+	}
+	ttl := app.conf.ResolvedIPTTL
+	if ttl == 0 {
+		ttl = 86400
+	}
+	s.SetHostIPCache(&hostIPStore{
+		app: app,
+		ttl: time.Duration(ttl) * time.Second,
+	})
+	if app.conf.SshConfig.KnownHostsFile != "" {
+		app.knownHosts = s
+	}
+	return s, nil
+}
+
+// hostClusterStore looks up the cluster of a host from the nodes
+// in the db.
+type hostClusterStore struct {
+	app *App
+}
+
+func (hs *hostClusterStore) HostCluster(host string) (string, error) {
+	var clusterId string
+	err := hs.app.db.View(func(tx *bolt.Tx) error {
+		n, err := NewNodeEntryFromHostName(tx, host)
+		if err == ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		clusterId = n.Info.ClusterId
+		return nil
+	})
+	return clusterId, err
+}
+
+// hostIPStore keeps the addresses the hostnames of the nodes
+// resolved to in the db.
+type hostIPStore struct {
+	app *App
+	ttl time.Duration
+}
+
+func (hs *hostIPStore) expired(n *NodeEntry) bool {
+	// addresses set by the admin do not expire
+	return n.ResolvedIPTime != 0 &&
+		time.Since(time.Unix(n.ResolvedIPTime, 0)) > hs.ttl
+}
+
+func (hs *hostIPStore) HostIP(host string) (string, error) {
+	var ip string
+	err := hs.app.db.View(func(tx *bolt.Tx) error {
+		n, err := NewNodeEntryFromHostName(tx, host)
+		if err == ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !hs.expired(n) {
+			ip = n.Info.ResolvedIP
+		}
+		return nil
+	})
+	return ip, err
+}
+
+// manualIP returns true if the address of the node was set by the
+// admin, such addresses are never replaced by resolved ones.
+func (hs *hostIPStore) manualIP(n *NodeEntry) bool {
+	return n.ResolvedIPTime == 0 && n.Info.ResolvedIP != ""
+}
+
+func (hs *hostIPStore) SetHostIP(host, ip string) error {
+	// avoid a db write for every command sent to the node
+	var stale bool
+	err := hs.app.db.View(func(tx *bolt.Tx) error {
+		n, err := NewNodeEntryFromHostName(tx, host)
+		if err == ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if hs.manualIP(n) {
+			return nil
+		}
+		stale = n.Info.ResolvedIP != ip ||
+			time.Since(time.Unix(n.ResolvedIPTime, 0)) > hs.ttl/10
+		return nil
+	})
+	if err != nil || !stale {
+		return err
+	}
+	return hs.app.db.Update(func(tx *bolt.Tx) error {
+		n, err := NewNodeEntryFromHostName(tx, host)
+		if err == ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if hs.manualIP(n) {
+			// set by the admin since the check above
+			return nil
+		}
+		n.Info.ResolvedIP = ip
+		n.ResolvedIPTime = time.Now().Unix()
+		return n.Save(tx)
+	})
+}
+
 func (app *App) initNodeMonitor() {
 	//default monitor gluster node refresh time
 	var timer uint32 = 120
@@ -259,8 +471,25 @@ func (app *App) initNodeMonitor() {
 	}
 	if MonitorGlusterNodes {
 		app.nhealth = NewNodeHealthCache(timer, startDelay, app.db, app.executor)
+		fd := app.conf.FaultDetection
+		if fd.AutoReplace || fd.OfflineThresholdMinutes > 0 {
+			app.bfaults = NewBrickFaultDetector(app.db, app.executor, fd)
+			app.bfaults.ctx = app.ctx
+			app.nhealth.BrickFaults = app.bfaults
+		}
 		app.nhealth.Monitor()
 		currentNodeHealthCache = app.nhealth
+
+		app.nlatency = NewNodeLatencyCache(timer, startDelay, app.db, app.executor)
+		app.nlatency.Monitor()
+		currentNodeLatencyCache = app.nlatency
+
+		app.tpusage = NewThinPoolUsageCache(timer, startDelay, app.db, app.executor)
+		if app.conf.ThinPoolWarnThreshold > 0 {
+			app.tpusage.WarnThreshold = app.conf.ThinPoolWarnThreshold
+		}
+		app.tpusage.Monitor()
+		currentThinPoolUsageCache = app.tpusage
 	}
 }
 
@@ -276,6 +505,19 @@ func (app *App) initBackgroundCleaner() {
 		app.bgcleaner = app.BackgroundCleaner()
 		app.bgcleaner.Start()
 	}
+	if EnableBackgroundCleaner && app.conf.ArchiveAfterDays > 0 {
+		app.bgarchiver = app.BackgroundArchiver()
+		app.bgarchiver.Start()
+	}
+}
+
+func (app *App) initVolumeInfoCache() {
+	size := app.conf.VolumeInfoCacheSize
+	if size == 0 {
+		size = DEFAULT_VOLUME_INFO_CACHE_SIZE
+	}
+	app.volinfo = NewVolumeInfoCache(size)
+	currentVolumeInfoCache = app.volinfo
 }
 
 func (app *App) initOpTracker() {
@@ -284,6 +526,7 @@ func (app *App) initOpTracker() {
 		oplimit = DEFAULT_OP_LIMIT
 	}
 	app.optracker = newOpTracker(oplimit)
+	app.opcanceler = newOpCanceler()
 }
 
 func SetLogLevel(level string) error {
@@ -328,6 +571,11 @@ func (a *App) setFromEnvironmentalVariable() {
 		a.conf.Loglevel = env
 	}
 
+	env = os.Getenv("HEKETI_SSH_KEY_ENCRYPTION_KEY")
+	if env != "" {
+		a.conf.SshKeyEncryptionKey = env
+	}
+
 	env = os.Getenv("HEKETI_AUTO_CREATE_BLOCK_HOSTING_VOLUME")
 	if "" != env {
 		a.conf.CreateBlockHostingVolumes, err = strconv.ParseBool(env)
@@ -415,6 +663,14 @@ func (a *App) setAdvSettings() {
 		// Convert to KB
 		BrickMinSize = uint64(a.conf.BrickMinSize) * 1024 * 1024
 	}
+	if a.conf.MinVolumeSize != 0 {
+		logger.Info("Adv: Min volume size %v GB", a.conf.MinVolumeSize)
+		minVolumeSizeGB = a.conf.MinVolumeSize
+	}
+	if a.conf.MaxVolumeSize != 0 {
+		logger.Info("Adv: Max volume size %v GB", a.conf.MaxVolumeSize)
+		maxVolumeSizeGB = a.conf.MaxVolumeSize
+	}
 	if a.conf.AverageFileSize != 0 {
 		logger.Info("Average file size on volumes set to %v KiB", a.conf.AverageFileSize)
 		averageFileSize = a.conf.AverageFileSize
@@ -431,6 +687,10 @@ func (a *App) setAdvSettings() {
 		logger.Info("Zone checking: '%v'", a.conf.ZoneChecking)
 		ZoneChecking = ZoneCheckingStrategy(a.conf.ZoneChecking)
 	}
+	if a.conf.PreferLowLatencyNodes {
+		logger.Info("Adv: Prefer low latency nodes")
+		PreferLowLatencyNodes = a.conf.PreferLowLatencyNodes
+	}
 	if a.conf.MaxVolumesPerCluster < 0 {
 		logger.Info("Volumes per cluster limit is removed as it is set to %v", a.conf.MaxVolumesPerCluster)
 		maxVolumesPerCluster = math.MaxInt32
@@ -485,11 +745,36 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "POST",
 			Pattern:     "/clusters/{id:[A-Fa-f0-9]+}/flags",
 			HandlerFunc: a.ClusterSetFlags},
+		rest.Route{
+			Name:        "ClusterRebalanceZones",
+			Method:      "POST",
+			Pattern:     "/clusters/{id:[A-Fa-f0-9]+}/rebalance-zones",
+			HandlerFunc: a.ClusterRebalanceZones},
 		rest.Route{
 			Name:        "ClusterInfo",
 			Method:      "GET",
 			Pattern:     "/clusters/{id:[A-Fa-f0-9]+}",
 			HandlerFunc: a.ClusterInfo},
+		rest.Route{
+			Name:        "ClusterTopology",
+			Method:      "GET",
+			Pattern:     "/clusters/{id:[A-Fa-f0-9]+}/topology",
+			HandlerFunc: a.ClusterTopology},
+		rest.Route{
+			Name:        "ClusterSnapshotPolicy",
+			Method:      "GET",
+			Pattern:     "/clusters/{id:[A-Fa-f0-9]+}/snapshot-policy",
+			HandlerFunc: a.ClusterSnapshotPolicy},
+		rest.Route{
+			Name:        "ClusterSetSnapshotPolicy",
+			Method:      "PUT",
+			Pattern:     "/clusters/{id:[A-Fa-f0-9]+}/snapshot-policy",
+			HandlerFunc: a.ClusterSetSnapshotPolicy},
+		rest.Route{
+			Name:        "ClusterEvents",
+			Method:      "GET",
+			Pattern:     "/clusters/{id:[A-Fa-f0-9]+}/events",
+			HandlerFunc: a.ClusterEvents},
 		rest.Route{
 			Name:        "ClusterList",
 			Method:      "GET",
@@ -527,6 +812,26 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "POST",
 			Pattern:     "/nodes/{id:[A-Fa-f0-9]+}/tags",
 			HandlerFunc: a.NodeSetTags},
+		rest.Route{
+			Name:        "NodeSetIp",
+			Method:      "PUT",
+			Pattern:     "/nodes/{id:[A-Fa-f0-9]+}/ip",
+			HandlerFunc: a.NodeSetIp},
+		rest.Route{
+			Name:        "NodeBricks",
+			Method:      "GET",
+			Pattern:     "/nodes/{id:[A-Fa-f0-9]+}/bricks",
+			HandlerFunc: a.NodeBricks},
+		rest.Route{
+			Name:        "NodePing",
+			Method:      "POST",
+			Pattern:     "/nodes/{id:[A-Fa-f0-9]+}/ping",
+			HandlerFunc: a.NodePing},
+		rest.Route{
+			Name:        "NodeUpdateKnownHost",
+			Method:      "POST",
+			Pattern:     "/nodes/{id:[A-Fa-f0-9]+}/update-known-host",
+			HandlerFunc: a.NodeUpdateKnownHost},
 
 		// Devices
 		rest.Route{
@@ -534,6 +839,16 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "POST",
 			Pattern:     "/devices",
 			HandlerFunc: a.DeviceAdd},
+		rest.Route{
+			Name:        "DeviceBatchAdd",
+			Method:      "POST",
+			Pattern:     "/nodes/{id:[A-Fa-f0-9]+}/devices/batch",
+			HandlerFunc: a.DeviceBatchAdd},
+		rest.Route{
+			Name:        "DeviceBatchResult",
+			Method:      "GET",
+			Pattern:     "/nodes/{id:[A-Fa-f0-9]+}/devices/batch/{opid:[A-Fa-f0-9]+}",
+			HandlerFunc: a.DeviceBatchResult},
 		rest.Route{
 			Name:        "DeviceInfo",
 			Method:      "GET",
@@ -554,11 +869,36 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "GET",
 			Pattern:     "/devices/{id:[A-Fa-f0-9]+}/resync",
 			HandlerFunc: a.DeviceResync},
+		rest.Route{
+			Name:        "DeviceReplace",
+			Method:      "POST",
+			Pattern:     "/devices/{id:[A-Fa-f0-9]+}/replace",
+			HandlerFunc: a.DeviceReplace},
+		rest.Route{
+			Name:        "DeviceReinitialize",
+			Method:      "POST",
+			Pattern:     "/devices/{id:[A-Fa-f0-9]+}/reinitialize",
+			HandlerFunc: a.DeviceReinitialize},
 		rest.Route{
 			Name:        "DeviceSetTags",
 			Method:      "POST",
 			Pattern:     "/devices/{id:[A-Fa-f0-9]+}/tags",
 			HandlerFunc: a.DeviceSetTags},
+		rest.Route{
+			Name:        "LvmSnapshotCreate",
+			Method:      "POST",
+			Pattern:     "/devices/{id:[A-Fa-f0-9]+}/bricks/{brick_id:[A-Fa-f0-9]+}/lvm-snapshot",
+			HandlerFunc: a.LvmSnapshotCreate},
+		rest.Route{
+			Name:        "LvmSnapshotInfo",
+			Method:      "GET",
+			Pattern:     "/devices/{id:[A-Fa-f0-9]+}/bricks/{brick_id:[A-Fa-f0-9]+}/lvm-snapshots/{snapshot_id:[A-Fa-f0-9]+}",
+			HandlerFunc: a.LvmSnapshotInfo},
+		rest.Route{
+			Name:        "LvmSnapshotDelete",
+			Method:      "DELETE",
+			Pattern:     "/devices/{id:[A-Fa-f0-9]+}/bricks/{brick_id:[A-Fa-f0-9]+}/lvm-snapshots/{snapshot_id:[A-Fa-f0-9]+}",
+			HandlerFunc: a.LvmSnapshotDelete},
 
 		// Volume
 		rest.Route{
@@ -591,6 +931,88 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "POST",
 			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/block-restriction",
 			HandlerFunc: a.VolumeSetBlockRestriction},
+		rest.Route{
+			Name:        "VolumeSetACLConfig",
+			Method:      "PUT",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/acl-config",
+			HandlerFunc: a.VolumeSetACLConfig},
+		rest.Route{
+			Name:        "VolumeTranslators",
+			Method:      "GET",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/translators",
+			HandlerFunc: a.VolumeTranslators},
+		rest.Route{
+			Name:        "VolumeSetTranslator",
+			Method:      "PUT",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/translators",
+			HandlerFunc: a.VolumeSetTranslator},
+		rest.Route{
+			Name:        "VolumeBitrotStatus",
+			Method:      "GET",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/bitrot/status",
+			HandlerFunc: a.VolumeBitrotStatus},
+		rest.Route{
+			Name:        "VolumeGeoRepStatus",
+			Method:      "GET",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/georep/sessions/{session_id}/status",
+			HandlerFunc: a.VolumeGeoRepStatus},
+		rest.Route{
+			Name:        "VolumeProfileStart",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/profile/start",
+			HandlerFunc: a.VolumeProfileStart},
+		rest.Route{
+			Name:        "VolumeProfileStop",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/profile/stop",
+			HandlerFunc: a.VolumeProfileStop},
+		rest.Route{
+			Name:        "VolumeProfileInfo",
+			Method:      "GET",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/profile/info",
+			HandlerFunc: a.VolumeProfileInfo},
+		rest.Route{
+			Name:        "VolumeStatedump",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/statedump",
+			HandlerFunc: a.VolumeStatedump},
+
+		rest.Route{
+			Name:        "VolumeOperations",
+			Method:      "GET",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/operations",
+			HandlerFunc: a.VolumeOperations},
+
+		rest.Route{
+			Name:        "VolumePatch",
+			Method:      "PATCH",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}",
+			HandlerFunc: a.VolumePatch},
+		rest.Route{
+			Name:        "VolumePin",
+			Method:      "PUT",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/pin",
+			HandlerFunc: a.VolumePin},
+		rest.Route{
+			Name:        "VolumeImportLayout",
+			Method:      "POST",
+			Pattern:     "/volumes/import-layout",
+			HandlerFunc: a.VolumeImportLayout},
+		rest.Route{
+			Name:        "VolumeDetach",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/detach",
+			HandlerFunc: a.VolumeDetach},
+		rest.Route{
+			Name:        "VolumeRepair",
+			Method:      "POST",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/repair",
+			HandlerFunc: a.VolumeRepair},
+		rest.Route{
+			Name:        "VolumeMoveEstimate",
+			Method:      "GET",
+			Pattern:     "/volumes/{id:[A-Fa-f0-9]+}/move-estimate",
+			HandlerFunc: a.VolumeMoveEstimate},
 
 		// Volume Cloning
 		rest.Route{
@@ -625,6 +1047,11 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "POST",
 			Pattern:     "/blockvolumes/{id:[A-Fa-f0-9]+}/expand",
 			HandlerFunc: a.BlockVolumeExpand},
+		rest.Route{
+			Name:        "BlockVolumeMigrateGateway",
+			Method:      "POST",
+			Pattern:     "/blockvolumes/{id:[A-Fa-f0-9]+}/migrate-gateway",
+			HandlerFunc: a.BlockVolumeMigrateGateway},
 
 		// Brick (special)
 		rest.Route{
@@ -633,6 +1060,13 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Pattern:     "/bricks/to-evict/{id:[A-Fa-f0-9]+}",
 			HandlerFunc: a.BrickEvict},
 
+		// Api
+		rest.Route{
+			Name:        "ApiChangelog",
+			Method:      "GET",
+			Pattern:     "/api/changelog",
+			HandlerFunc: a.ApiChangelog},
+
 		// Backup
 		rest.Route{
 			Name:        "Backup",
@@ -652,6 +1086,13 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Pattern:     "/db/check",
 			HandlerFunc: a.DbCheck},
 
+		// Sandbox
+		rest.Route{
+			Name:        "SandboxOperation",
+			Method:      "POST",
+			Pattern:     "/sandbox/operations",
+			HandlerFunc: a.SandboxOperation},
+
 		// Logging
 		rest.Route{
 			Name:        "GetLogLevel",
@@ -681,12 +1122,30 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "GET",
 			Pattern:     "/operations/pending/{id:[A-Fa-f0-9]+}",
 			HandlerFunc: a.PendingOperationDetails},
+		// stream of the progress of a specific operation
+		rest.Route{
+			Name:        "PendingOperationEvents",
+			Method:      "GET",
+			Pattern:     "/operations/pending/{id:[A-Fa-f0-9]+}/events",
+			HandlerFunc: a.PendingOperationEvents},
+		// cancel an operation that has not started executing
+		rest.Route{
+			Name:        "OperationCancel",
+			Method:      "DELETE",
+			Pattern:     "/operations/{id:[A-Fa-f0-9]+}",
+			HandlerFunc: a.OperationCancel},
 		// request operation clean up
 		rest.Route{
 			Name:        "PendingOperationCleanUp",
 			Method:      "POST",
 			Pattern:     "/operations/pending/cleanup",
 			HandlerFunc: a.PendingOperationCleanUp},
+		// delete old pending operation records
+		rest.Route{
+			Name:        "PendingOperationDeleteBefore",
+			Method:      "DELETE",
+			Pattern:     "/admin/operations/cleanup",
+			HandlerFunc: a.PendingOperationDeleteBefore},
 
 		// State examination
 		rest.Route{
@@ -694,6 +1153,38 @@ func (a *App) SetRoutes(router *mux.Router) error {
 			Method:      "GET",
 			Pattern:     "/internal/state/examine/gluster",
 			HandlerFunc: a.ExamineGluster},
+		rest.Route{
+			Name:        "ConfigDriftCheck",
+			Method:      "POST",
+			Pattern:     "/admin/config-drift-check",
+			HandlerFunc: a.ConfigDriftCheck},
+
+		// Per-node ssh keys
+		rest.Route{
+			Name:        "SshKeyCreate",
+			Method:      "POST",
+			Pattern:     "/admin/ssh-keys",
+			HandlerFunc: a.SshKeyCreate},
+		rest.Route{
+			Name:        "SshKeyList",
+			Method:      "GET",
+			Pattern:     "/admin/ssh-keys",
+			HandlerFunc: a.SshKeyList},
+		rest.Route{
+			Name:        "SshKeyInfo",
+			Method:      "GET",
+			Pattern:     "/admin/ssh-keys/{id:[A-Fa-f0-9]+}",
+			HandlerFunc: a.SshKeyInfo},
+		rest.Route{
+			Name:        "SshKeyUpdate",
+			Method:      "PUT",
+			Pattern:     "/admin/ssh-keys/{id:[A-Fa-f0-9]+}",
+			HandlerFunc: a.SshKeyUpdate},
+		rest.Route{
+			Name:        "SshKeyDelete",
+			Method:      "DELETE",
+			Pattern:     "/admin/ssh-keys/{id:[A-Fa-f0-9]+}",
+			HandlerFunc: a.SshKeyDelete},
 	}
 
 	// Register all routes from the App
@@ -715,13 +1206,38 @@ func (a *App) SetRoutes(router *mux.Router) error {
 }
 
 func (a *App) Close() {
+	// stop sending commands for any running operations
+	if a.cancel != nil {
+		a.cancel()
+	}
+
 	// stop the health goroutine
 	if a.nhealth != nil {
 		a.nhealth.Stop()
 	}
+	if a.nlatency != nil {
+		a.nlatency.Stop()
+	}
+	if a.tpusage != nil {
+		a.tpusage.Stop()
+	}
 	if a.bgcleaner != nil {
 		a.bgcleaner.Stop()
 	}
+	if a.bgarchiver != nil {
+		a.bgarchiver.Stop()
+	}
+	if a.nlabels != nil {
+		a.nlabels.Stop()
+	}
+	if a.dlockExtender != nil {
+		a.dlockExtender.Stop()
+	}
+	if a.dlock != nil {
+		if err := a.dlock.Close(); err != nil {
+			logger.LogError("Unable to close distributed lock: %v", err)
+		}
+	}
 
 	// Close the DB
 	a.db.Close()
@@ -737,13 +1253,13 @@ func (a *App) Backup(w http.ResponseWriter, r *http.Request) {
 		return err
 	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
 func (a *App) NotFoundHandler(w http.ResponseWriter, r *http.Request) {
 	logger.Warning("Invalid path or request %v", r.URL.Path)
-	http.Error(w, "Invalid path or request", http.StatusNotFound)
+	utils.HttpError(w, "Invalid path or request", http.StatusNotFound)
 }
 
 // ServerReset resets the app and its components to the state desired
@@ -838,6 +1354,20 @@ func (a *App) BackgroundCleaner() *backgroundOperationCleaner {
 	}
 }
 
+// BackgroundArchiver returns a background operations archiver moving
+// pending operations older than the configured number of days to the
+// archive. It runs as often as the background cleaner.
+func (a *App) BackgroundArchiver() *backgroundOperationArchiver {
+	godbc.Require(a.optracker != nil)
+	checkSec := time.Duration(a.conf.RefreshTimeBackgroundCleaner)
+	return &backgroundOperationArchiver{
+		db:            a.db,
+		optracker:     a.optracker,
+		ArchiveAfter:  time.Duration(a.conf.ArchiveAfterDays) * 24 * time.Hour,
+		CheckInterval: checkSec * time.Second,
+	}
+}
+
 // currentNodeHealthStatus returns a map of node ids to the most
 // recently known health status (true is up, false is not up).
 // If a node is not found in the map its status is unknown.
@@ -851,3 +1381,28 @@ func currentNodeHealthStatus() (nodeUp map[string]bool) {
 	}
 	return
 }
+
+// currentNodeLatency returns a map of node ids to the most recently
+// measured latency summary. If a node is not found in the map its
+// latency is unknown. If no latency monitor is active an empty map is
+// always returned.
+func currentNodeLatency() (latency map[string]NodeLatency) {
+	if currentNodeLatencyCache != nil {
+		latency = currentNodeLatencyCache.Status()
+	} else {
+		latency = map[string]NodeLatency{}
+	}
+	return
+}
+
+// currentThinPoolUsage returns a map of device ids to the most recently
+// measured thin pool data usage in percent. If no thin pool usage
+// monitor is active an empty map is always returned.
+func currentThinPoolUsage() (usage map[string]float64) {
+	if currentThinPoolUsageCache != nil {
+		usage = currentThinPoolUsageCache.Status()
+	} else {
+		usage = map[string]float64{}
+	}
+	return
+}
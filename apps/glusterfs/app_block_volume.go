@@ -24,19 +24,19 @@ func (a *App) BlockVolumeCreate(w http.ResponseWriter, r *http.Request) {
 	var msg api.BlockVolumeCreateRequest
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 
 	err = msg.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
 	}
 
 	if msg.Size < 1 {
-		http.Error(w, "Invalid volume size", http.StatusBadRequest)
+		utils.HttpError(w, "Invalid volume size", http.StatusBadRequest)
 		logger.LogError("Invalid volume size")
 		return
 	}
@@ -48,12 +48,12 @@ func (a *App) BlockVolumeCreate(w http.ResponseWriter, r *http.Request) {
 		// :TODO: All we need to do is check for one instead of gathering all keys
 		clusters, err := ClusterList(tx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 		if len(clusters) == 0 {
 			err := logger.LogError("No clusters configured")
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			utils.HttpError(w, err.Error(), http.StatusBadRequest)
 			return ErrNotFound
 		}
 
@@ -62,7 +62,7 @@ func (a *App) BlockVolumeCreate(w http.ResponseWriter, r *http.Request) {
 			_, err := NewClusterEntryFromId(tx, clusterid)
 			if err != nil {
 				err := logger.LogError("Cluster id %v not found", clusterid)
-				http.Error(w, err.Error(), http.StatusBadRequest)
+				utils.HttpError(w, err.Error(), http.StatusBadRequest)
 				return err
 			}
 		}
@@ -99,7 +99,7 @@ func (a *App) BlockVolumeList(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		logger.Err(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -121,16 +121,16 @@ func (a *App) BlockVolumeInfo(w http.ResponseWriter, r *http.Request) {
 	err := a.db.View(func(tx *bolt.Tx) error {
 		entry, err := NewBlockVolumeEntryFromId(tx, id)
 		if err == ErrNotFound || !entry.Visible() {
-			http.Error(w, "Id not found", http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorBlockVolumeNotFound, "Id not found", http.StatusNotFound)
 			return ErrNotFound
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
 		info, err = entry.NewInfoResponse(tx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -157,10 +157,10 @@ func (a *App) BlockVolumeDelete(w http.ResponseWriter, r *http.Request) {
 		var err error
 		blockVolume, err = NewBlockVolumeEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorBlockVolumeNotFound, err.Error(), http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -184,18 +184,18 @@ func (a *App) BlockVolumeExpand(w http.ResponseWriter, r *http.Request) {
 	var msg api.BlockVolumeExpandRequest
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 	err = msg.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
 	}
 
 	if msg.Size < 1 {
-		http.Error(w, "Invalid block volume size", http.StatusBadRequest)
+		utils.HttpError(w, "Invalid block volume size", http.StatusBadRequest)
 		logger.LogError("Invalid block volume size")
 		return
 	}
@@ -204,10 +204,10 @@ func (a *App) BlockVolumeExpand(w http.ResponseWriter, r *http.Request) {
 		var err error
 		_, err = NewBlockVolumeEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorBlockVolumeNotFound, err.Error(), http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -223,3 +223,52 @@ func (a *App) BlockVolumeExpand(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (a *App) BlockVolumeMigrateGateway(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var msg api.BlockVolumeMigrateGatewayRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return
+	}
+
+	err = a.db.View(func(tx *bolt.Tx) error {
+		_, err := NewBlockVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorBlockVolumeNotFound, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		_, err = NewNodeEntryFromId(tx, msg.TargetGatewayNodeId)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorNodeNotFound, "Target gateway node not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	bmg := NewBlockVolumeMigrateGatewayOperation(id, a.db,
+		msg.TargetGatewayNodeId, msg.SourceGatewayNodeId)
+	if err := AsyncHttpOperation(a, w, r, bmg); err != nil {
+		OperationHttpErrorf(w, err,
+			"Failed to migrate block volume gateway: %v", err)
+		return
+	}
+}
@@ -0,0 +1,89 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// apiChangelog lists the changes of the REST API, newest release
+// first. Endpoints are written as "<METHOD> <path>" with the variable
+// parts of the path in braces; the unit tests check that each of them
+// is served.
+var apiChangelog = []api.ApiChangelogEntry{
+	{
+		Version: "unreleased",
+		Changes: []string{
+			"Added GET /operations/pending/{id}/events streaming the progress of a pending operation as server-sent events",
+			"The status of a pending POST /clusters/{id}/rebalance-zones reports the share of bricks moved in the X-Progress header",
+			"POST /volumes/{id}/expand returns 402 when the configured pre_expand_hook rejects the expand",
+			"Added GET /volumes/{id}/georep/sessions/{session_id}/status returning the status of a geo-replication session on each brick of a volume",
+			"Added POST /nodes/{id}/devices/batch adding several devices of a node at once, the results of the devices are served by GET /nodes/{id}/devices/batch/{opid}",
+			"Added GET and PUT /volumes/{id}/translators for the performance translator options of a volume, set options are included in the volume information as translator_options",
+			"GET /volumes/{id} returns an ETag header and replies 304 to requests whose If-None-Match matches it",
+			"Added POST /nodes/{id}/update-known-host recording the ssh host key of a node",
+			"Added progress, the percentage of the work done, to the pending operations of GET /operations/pending and GET /operations/pending/{id}",
+			"Added POST /devices/{id}/reinitialize wiping a device without bricks and setting it up again",
+			"Added GET /clusters/{id}/events listing the changes made to a cluster",
+			"Added POST /volumes/{id}/profile/start, POST /volumes/{id}/profile/stop, GET /volumes/{id}/profile/info and POST /volumes/{id}/statedump",
+			"Added tsp_id to node add and volume create requests, and trusted_storage_pools to GET /clusters/{id}",
+			"Added access_pattern to volume create requests, a hint for the placement of the bricks",
+			"Added GET /api/changelog listing the changes of the API",
+			"Added GET /volumes/{id}/bitrot/status returning the state of the bitrot scrubber of a volume",
+			"Added the last known bitrot status to the volume information",
+			"Expanding a volume that is already being expanded fails with 409 and the error code OPERATION_IN_PROGRESS",
+			"Added PUT /nodes/{id}/ip setting the address used when the hostname of a node can not be resolved",
+			"Added resolved_ip to the node information",
+			"Added the optional response envelope wrapping JSON responses in data or error and meta objects",
+			"Added the include_archived parameter to GET /operations/pending; archived operations are also returned by GET /operations/pending/{id}",
+			"Creating or expanding a volume beyond the configured size limits fails with 422",
+			"Added GET /clusters/{id}/snapshot-policy and PUT /clusters/{id}/snapshot-policy",
+			"Added POST /devices/{id}/replace moving the bricks of a device to other devices",
+			"Added GET /clusters/{id}/topology",
+			"Added POST /volumes/{id}/detach removing a volume from heketi without deleting it",
+			"Added POST /volumes/import-layout importing a volume from existing bricks",
+			"Failed volume creations report the devices that were rejected and why",
+			"Added PUT /admin/mode switching the server between read-only and read-write mode",
+			"Added POST /volumes/{id}/repair",
+			"Operations can be tagged with X-Heketi-Tag-<key> headers and GET /operations/pending can filter them by tag",
+			"Error responses are JSON objects with an error code, a message and details",
+			"Added POST /sandbox/operations simulating an operation without changing the db",
+			"Added PUT /volumes/{id}/pin restricting the bricks of a volume to nodes",
+			"Added DELETE /admin/operations/cleanup removing old pending operation records",
+			"Added POST /clusters/{id}/rebalance-zones",
+			"Added POST /blockvolumes/{id}/migrate-gateway",
+			"Added thin_pool_used_percent to the device information",
+			"Added PUT /volumes/{id}/acl-config",
+			"Added POST /admin/config-drift-check",
+			"Added POST /devices/{id}/bricks/{brick_id}/lvm-snapshot, GET /devices/{id}/bricks/{brick_id}/lvm-snapshots/{snapshot_id} and DELETE /devices/{id}/bricks/{brick_id}/lvm-snapshots/{snapshot_id}",
+			"Requests with bodies larger than the configured limit fail with 413",
+			"Added PATCH /volumes/{id} applying JSON Patch documents to the labels and tier of a volume",
+			"Added GET /volumes/{id}/move-estimate",
+			"Added storage tiers of volumes restricting the devices of their bricks",
+			"Added POST /admin/ssh-keys, GET /admin/ssh-keys, GET /admin/ssh-keys/{id}, PUT /admin/ssh-keys/{id} and DELETE /admin/ssh-keys/{id}",
+			"Added the region of nodes and the placement of volumes across regions",
+			"Added ssh_latency_p50_ms and ssh_latency_p99_ms to the node information",
+			"Added GET /nodes/{id}/bricks and POST /nodes/{id}/ping",
+			"Added DELETE /operations/{id} canceling an operation that has not started to run",
+			"Added GET /volumes/{id}/operations listing the operations of a volume",
+		},
+	},
+}
+
+func (a *App) ApiChangelog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(apiChangelog); err != nil {
+		panic(err)
+	}
+}
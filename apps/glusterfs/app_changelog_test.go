@@ -0,0 +1,95 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/server/admin"
+)
+
+func TestApiChangelog(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	r, err := http.Get(ts.URL + "/api/changelog")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusOK, "expected 200, got:", r.StatusCode)
+	var raw []map[string]interface{}
+	err = json.NewDecoder(r.Body).Decode(&raw)
+	r.Body.Close()
+	tests.Assert(t, err == nil, "expected valid json, got:", err)
+	tests.Assert(t, len(raw) > 0, "expected changelog entries")
+	for _, e := range raw {
+		for _, k := range []string{"version", "date", "changes"} {
+			_, found := e[k]
+			tests.Assert(t, found, "expected", k, "in", e)
+		}
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	changelog, err := c.ApiChangelog()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(changelog) == len(apiChangelog),
+		"expected", len(apiChangelog), "entries, got:", len(changelog))
+	for _, e := range changelog {
+		tests.Assert(t, e.Version != "", "expected a version, got:", e)
+		tests.Assert(t, len(e.Changes) > 0, "expected changes in", e.Version)
+	}
+}
+
+// TestApiChangelogPaths checks that the endpoints named in the
+// changelog are served.
+func TestApiChangelogPaths(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+	admin.New().SetRoutes(router)
+
+	endpointRe := regexp.MustCompile(`\b(GET|POST|PUT|PATCH|DELETE) (/[^\s,;]*)`)
+	varRe := regexp.MustCompile(`{[a-z_]+}`)
+	found := 0
+	for _, e := range apiChangelog {
+		for _, change := range e.Changes {
+			for _, m := range endpointRe.FindAllStringSubmatch(change, -1) {
+				found++
+				// all variable parts of the paths are ids
+				path := varRe.ReplaceAllString(m[2], "0123456789abcdef0123456789abcdef")
+				req, err := http.NewRequest(m[1], path, nil)
+				tests.Assert(t, err == nil, "expected err == nil, got:", err)
+				var match mux.RouteMatch
+				tests.Assert(t, router.Match(req, &match) && match.MatchErr == nil,
+					"no route for", m[1], m[2], "in", e.Version)
+			}
+		}
+	}
+	tests.Assert(t, found > 0, "expected endpoints in the changelog")
+}
@@ -12,6 +12,7 @@ package glusterfs
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/boltdb/bolt"
 	"github.com/gorilla/mux"
@@ -24,7 +25,7 @@ func (a *App) ClusterCreate(w http.ResponseWriter, r *http.Request) {
 
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 
@@ -35,7 +36,14 @@ func (a *App) ClusterCreate(w http.ResponseWriter, r *http.Request) {
 	err = a.db.Update(func(tx *bolt.Tx) error {
 		err := entry.Save(tx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		err = AddClusterEvent(tx, entry.Info.Id, api.ClusterEventClusterCreated,
+			requestActor(r), "Created cluster", clusterFlagsMetadata(entry))
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -62,17 +70,17 @@ func (a *App) ClusterSetFlags(w http.ResponseWriter, r *http.Request) {
 
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 
 	err = a.db.Update(func(tx *bolt.Tx) error {
 		entry, err := NewClusterEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorClusterNotFound, err.Error(), http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -81,7 +89,15 @@ func (a *App) ClusterSetFlags(w http.ResponseWriter, r *http.Request) {
 
 		err = entry.Save(tx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		err = AddClusterEvent(tx, entry.Info.Id, api.ClusterEventFlagsChanged,
+			requestActor(r), "Changed the volume types allowed in the cluster",
+			clusterFlagsMetadata(entry))
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -113,7 +129,7 @@ func (a *App) ClusterList(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		logger.Err(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -138,10 +154,10 @@ func (a *App) ClusterInfo(w http.ResponseWriter, r *http.Request) {
 		// Create a db entry from the id
 		entry, err := NewClusterEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorClusterNotFound, err.Error(), http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -154,6 +170,11 @@ func (a *App) ClusterInfo(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			return err
 		}
+		info.TrustedStoragePools, err = entry.TrustedStoragePools(tx)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
 
 		return nil
 	})
@@ -170,6 +191,161 @@ func (a *App) ClusterInfo(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// ClusterTopology returns the zones, nodes and devices of a cluster.
+func (a *App) ClusterTopology(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var topo *api.ClusterTopologyResponse
+	err := a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		topo, err = clusterTopology(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorClusterNotFound, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(topo); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) ClusterSnapshotPolicy(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	policy := &api.ClusterSnapshotPolicy{}
+	err := a.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewClusterEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorClusterNotFound, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		if entry.SnapshotPolicy != nil {
+			policy = entry.SnapshotPolicy
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(policy); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) ClusterSetSnapshotPolicy(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var msg api.ClusterSnapshotPolicy
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return
+	}
+
+	err = a.db.View(func(tx *bolt.Tx) error {
+		_, err := NewClusterEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorClusterNotFound, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	op := NewSetSnapshotPolicyOperation(id, a.db, msg)
+	op.actor = requestActor(r)
+	if err := AsyncHttpOperation(a, w, r, op); err != nil {
+		OperationHttpErrorf(w, err, "Failed to set snapshot policy: %v", err)
+		return
+	}
+}
+
+func (a *App) ClusterEvents(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var since int64
+	if s := r.URL.Query().Get("since"); s != "" {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v < 0 {
+			utils.HttpError(w, "invalid since: "+s, http.StatusBadRequest)
+			return
+		}
+		since = v
+	}
+	limit := DEFAULT_CLUSTER_EVENT_LIMIT
+	if l := r.URL.Query().Get("limit"); l != "" {
+		v, err := strconv.Atoi(l)
+		if err != nil || v < 1 {
+			utils.HttpError(w, "invalid limit: "+l, http.StatusBadRequest)
+			return
+		}
+		limit = v
+	}
+
+	resp := &api.ClusterEventListResponse{
+		Events: []api.ClusterEvent{},
+	}
+	err := a.db.View(func(tx *bolt.Tx) error {
+		_, err := NewClusterEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorClusterNotFound, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		events, err := ClusterEvents(tx, id, since, limit)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		for _, e := range events {
+			resp.Events = append(resp.Events, e.Info)
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
 func (a *App) ClusterDelete(w http.ResponseWriter, r *http.Request) {
 
 	// Get the id from the URL
@@ -182,23 +358,30 @@ func (a *App) ClusterDelete(w http.ResponseWriter, r *http.Request) {
 		// Access cluster entry
 		entry, err := NewClusterEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorClusterNotFound, err.Error(), http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return logger.Err(err)
 		}
 
 		err = entry.Delete(tx)
 		if err != nil {
 			if err == ErrConflict {
-				http.Error(w, entry.ConflictString(), http.StatusConflict)
+				utils.HttpError(w, entry.ConflictString(), http.StatusConflict)
 			} else {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			}
 			return err
 		}
 
+		// the history goes with the cluster
+		err = DeleteClusterEvents(tx, id)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -211,3 +394,77 @@ func (a *App) ClusterDelete(w http.ResponseWriter, r *http.Request) {
 	// Write msg
 	w.WriteHeader(http.StatusOK)
 }
+
+func (a *App) ClusterRebalanceZones(w http.ResponseWriter, r *http.Request) {
+	var msg api.ClusterRebalanceZonesRequest
+
+	// Get the id from the URL
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return
+	}
+
+	var plan *api.ClusterRebalanceZonesResponse
+	err = a.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewClusterEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorClusterNotFound, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		usable, err := clusterZoneIsUsable(tx, entry, msg.Zone)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		if !usable {
+			err = logger.LogError("Cluster %v has no online devices in zone %v",
+				id, msg.Zone)
+			utils.HttpError(w, err.Error(), http.StatusBadRequest)
+			return err
+		}
+
+		plan, err = PlanZoneRebalance(tx, entry, msg.Zone)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	if msg.PlanOnly || len(plan.Moves) == 0 {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(plan); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	logger.Info("Moving %v bricks of cluster %v to zone %v",
+		len(plan.Moves), id, msg.Zone)
+	a.asyncManager.AsyncHttpRedirectProgressFunc(w, r, func(progress func(int)) (string, error) {
+		err := RunZoneRebalance(a.db, a.executor, a.optracker,
+			plan, msg.BatchSize, progress)
+		if err != nil {
+			return "", err
+		}
+		return "", nil
+	})
+}
@@ -20,6 +20,7 @@ import (
 
 	"github.com/boltdb/bolt"
 	"github.com/gorilla/mux"
+	client "github.com/heketi/heketi/client/api/go-client"
 	"github.com/heketi/heketi/pkg/glusterfs/api"
 	"github.com/heketi/heketi/pkg/utils"
 	"github.com/heketi/tests"
@@ -267,6 +268,16 @@ func TestClusterInfo(t *testing.T) {
 			return err
 		}
 
+		// the trusted storage pools are made of the cluster's nodes
+		for _, id := range entry.Info.Nodes {
+			node := NewNodeEntry()
+			node.Info.Id = id
+			node.Info.ClusterId = entry.Info.Id
+			if err := node.Save(tx); err != nil {
+				return err
+			}
+		}
+
 		return nil
 
 	})
@@ -459,3 +470,83 @@ func TestClusterDelete(t *testing.T) {
 	tests.Assert(t, err == nil, err)
 
 }
+
+func TestClusterTopology(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopologyWithZones(app,
+		1,      // clusters
+		2,      // zones_per_cluster
+		6,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+	_, err = c.ClusterTopology("123")
+	assertErrorCode(t, err, api.ErrorClusterNotFound)
+
+	topo, err := c.ClusterTopology(v.Info.Cluster)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, topo.ClusterId == v.Info.Cluster,
+		"expected", v.Info.Cluster, "got:", topo.ClusterId)
+	tests.Assert(t, len(topo.Zones) == 2,
+		"expected 2 zones, got:", len(topo.Zones))
+
+	bricks := 0
+	err = app.db.View(func(tx *bolt.Tx) error {
+		for i, z := range topo.Zones {
+			tests.Assert(t, z.Name == fmt.Sprintf("%v", i),
+				"expected zone name", i, "got:", z.Name)
+			tests.Assert(t, len(z.Nodes) == 3,
+				"expected 3 nodes in zone, got:", len(z.Nodes))
+			for _, tn := range z.Nodes {
+				n, err := NewNodeEntryFromId(tx, tn.Id)
+				if err != nil {
+					return err
+				}
+				tests.Assert(t, n.Info.Zone == i,
+					"expected node in zone", i, "got:", n.Info.Zone)
+				tests.Assert(t, tn.Hostname == n.ManageHostName(),
+					"expected", n.ManageHostName(), "got:", tn.Hostname)
+				tests.Assert(t, tn.State == api.EntryStateOnline,
+					"expected node online, got:", tn.State)
+				tests.Assert(t, len(tn.Devices) == 2,
+					"expected 2 devices, got:", len(tn.Devices))
+				for _, td := range tn.Devices {
+					d, err := NewDeviceEntryFromId(tx, td.Id)
+					if err != nil {
+						return err
+					}
+					tests.Assert(t, d.NodeId == n.Info.Id,
+						"expected device on node", n.Info.Id, "got:", d.NodeId)
+					tests.Assert(t, td.Path == d.Info.Name,
+						"expected", d.Info.Name, "got:", td.Path)
+					tests.Assert(t, td.FreeGb == d.Info.Storage.Free/GB,
+						"expected", d.Info.Storage.Free/GB, "got:", td.FreeGb)
+					tests.Assert(t, td.BrickCount == len(d.Bricks),
+						"expected", len(d.Bricks), "got:", td.BrickCount)
+					bricks += td.BrickCount
+				}
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, bricks == len(v.Bricks),
+		"expected", len(v.Bricks), "bricks, got:", bricks)
+}
@@ -13,10 +13,19 @@ import (
 	"github.com/heketi/heketi/executors/injectexec"
 	"github.com/heketi/heketi/executors/kubeexec"
 	"github.com/heketi/heketi/executors/sshexec"
+	"github.com/heketi/heketi/pkg/dlock"
 )
 
 type RetryLimitConfig struct {
 	VolumeCreate int `json:"volume_create"`
+	VolumeExpand int `json:"volume_expand"`
+}
+
+type FaultDetectionConfig struct {
+	// minutes a brick must be offline before it is considered failed
+	OfflineThresholdMinutes int `json:"offline_threshold_minutes"`
+	// replace failed bricks automatically
+	AutoReplace bool `json:"auto_replace"`
 }
 
 type GlusterFSConfig struct {
@@ -29,6 +38,9 @@ type GlusterFSConfig struct {
 	InjectConfig injectexec.InjectConfig `json:"injectexec"`
 	Loglevel     string                  `json:"loglevel"`
 
+	// passphrase used to encrypt per-node ssh keys stored in the db
+	SshKeyEncryptionKey string `json:"ssh_key_encryption_key"`
+
 	// advanced settings
 	BrickMaxSize         int    `json:"brick_max_size_gb"`
 	BrickMinSize         int    `json:"brick_min_size_gb"`
@@ -38,6 +50,11 @@ type GlusterFSConfig struct {
 	PostReqVolumeOptions string `json:"post_request_volume_options"`
 	ZoneChecking         string `json:"zone_checking"`
 	MaxVolumesPerCluster int    `json:"max_volumes_per_cluster"`
+	MinVolumeSize        int    `json:"min_volume_size_gb"`
+	MaxVolumeSize        int    `json:"max_volume_size_gb"`
+
+	// prefer placing bricks on nodes with lower ssh latency
+	PreferLowLatencyNodes bool `json:"prefer_low_latency_nodes"`
 
 	//block settings
 	CreateBlockHostingVolumes bool   `json:"auto_create_block_hosting_volume"`
@@ -49,10 +66,42 @@ type GlusterFSConfig struct {
 	RefreshTimeMonitorGlusterNodes uint32 `json:"refresh_time_monitor_gluster_nodes"`
 	StartTimeMonitorGlusterNodes   uint32 `json:"start_time_monitor_gluster_nodes"`
 	MaxInflightOperations          uint64 `json:"max_inflight_operations"`
+	// how long batched db writes wait for more writes (milliseconds)
+	BatchWindowMs uint32 `json:"batch_window_ms"`
+	// how long the resolved address of a node is used when its
+	// hostname can not be resolved (seconds)
+	ResolvedIPTTL uint32 `json:"resolved_ip_ttl"`
 
 	DisableBackgroundCleaner     bool   `json:"disable_background_cleaner"`
 	RefreshTimeBackgroundCleaner uint32 `json:"refresh_time_background_cleaner"`
 	StartTimeBackgroundCleaner   uint32 `json:"start_time_background_cleaner"`
+	// move failed and stale pending operations older than this
+	// to the archive, 0 disables archival
+	ArchiveAfterDays uint32 `json:"archive_after_days"`
+
+	// offline brick detection and replacement
+	FaultDetection FaultDetectionConfig `json:"fault_detection"`
+
+	// warn when the thin pools of a device are fuller than this (percent)
+	ThinPoolWarnThreshold float64 `json:"thin_pool_warn_threshold"`
+
+	// number of volume info responses cached, a negative value
+	// disables the cache
+	VolumeInfoCacheSize int `json:"volume_info_cache_size"`
+
+	// sync node tags from the kubernetes node labels starting with
+	// this prefix, such as "heketi.io/*", disabled if empty
+	LabelSyncFilter string `json:"label_sync_filter"`
+	// seconds between full syncs of the node labels
+	LabelSyncInterval int `json:"label_sync_interval"`
+
+	// local script run before a volume is expanded, a non-zero exit
+	// status rejects the expand
+	PreExpandHook string `json:"pre_expand_hook"`
+
+	// lock shared by several heketi servers, such that only one of
+	// them changes a volume or cluster at a time
+	DistributedLock dlock.Config `json:"distributed_lock"`
 
 	// operation retry amounts
 	RetryLimits RetryLimitConfig `json:"operation_retry_limits"`
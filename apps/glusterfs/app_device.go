@@ -28,20 +28,20 @@ func (a *App) DeviceAdd(w http.ResponseWriter, r *http.Request) {
 	var msg api.DeviceAddRequest
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 
 	err = msg.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
 	}
 
 	// Check the message has devices
 	if msg.Name == "" {
-		http.Error(w, "no devices added", http.StatusBadRequest)
+		utils.HttpError(w, "no devices added", http.StatusBadRequest)
 		return
 	}
 
@@ -54,10 +54,10 @@ func (a *App) DeviceAdd(w http.ResponseWriter, r *http.Request) {
 		var err error
 		node, err = NewNodeEntryFromId(tx, msg.NodeId)
 		if err == ErrNotFound {
-			http.Error(w, "Node id does not exist", http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorNodeNotFound, "Node id does not exist", http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -67,7 +67,14 @@ func (a *App) DeviceAdd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = a.setDeviceVgName(device, node)
+	if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	// Log the devices are being added
+	actor := requestActor(r)
 	logger.Info("Adding device %v to node %v", msg.Name, msg.NodeId)
 
 	// Add device in an asynchronous function
@@ -121,7 +128,10 @@ func (a *App) DeviceAdd(w http.ResponseWriter, r *http.Request) {
 				return err
 			}
 
-			return nil
+			return AddClusterEvent(tx, nodeEntry.Info.ClusterId,
+				api.ClusterEventDeviceAdded, actor,
+				"Added device "+device.Info.Name+" to node "+nodeEntry.ManageHostName(),
+				deviceEventMetadata(device))
 
 		})
 		if err != nil {
@@ -138,6 +148,78 @@ func (a *App) DeviceAdd(w http.ResponseWriter, r *http.Request) {
 
 }
 
+func (a *App) DeviceBatchAdd(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var msg api.DeviceBatchAddRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return
+	}
+
+	var node *NodeEntry
+	err = a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		node, err = NewNodeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorNodeNotFound, "Node id does not exist", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	op := NewDeviceBatchAddOperation(id, msg.Devices, a.db)
+	for _, d := range op.devices {
+		if err := a.setDeviceVgName(d, node); err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	op.actor = requestActor(r)
+	op.batches = a.dbatches
+
+	logger.Info("Adding devices %v to node %v", msg.Devices, id)
+	if err := AsyncHttpOperation(a, w, r, op); err != nil {
+		if err == ErrConflict {
+			utils.HttpError(w, "device already on node", http.StatusConflict)
+			return
+		}
+		OperationHttpErrorf(w, err, "Failed to set up device batch add: %v", err)
+		return
+	}
+}
+
+func (a *App) DeviceBatchResult(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	opid := vars["opid"]
+
+	result, found := a.dbatches.Get(opid)
+	if !found || result.NodeId != id {
+		utils.HttpError(w, "Id not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		panic(err)
+	}
+}
+
 func (a *App) DeviceInfo(w http.ResponseWriter, r *http.Request) {
 
 	// Get device id from URL
@@ -149,16 +231,16 @@ func (a *App) DeviceInfo(w http.ResponseWriter, r *http.Request) {
 	err := a.db.View(func(tx *bolt.Tx) error {
 		entry, err := NewDeviceEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, "Id not found", http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorDeviceNotFound, "Id not found", http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
 		info, err = entry.NewInfoResponse(tx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -186,7 +268,7 @@ func (a *App) DeviceDelete(w http.ResponseWriter, r *http.Request) {
 	if r.ContentLength > 0 {
 		err := utils.GetJsonFromRequest(r, &opts)
 		if err != nil {
-			http.Error(w, "request unable to be parsed", 422)
+			utils.HttpError(w, "request unable to be parsed", 422)
 			return
 		}
 	}
@@ -201,26 +283,26 @@ func (a *App) DeviceDelete(w http.ResponseWriter, r *http.Request) {
 		// Access device entry
 		device, err = NewDeviceEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorDeviceNotFound, err.Error(), http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return logger.Err(err)
 		}
 
 		// Access node entry
 		node, err = NewNodeEntryFromId(tx, device.NodeId)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return logger.Err(err)
 		}
 
 		// Check if we can delete the device
 		if err := device.CheckDelete(); err != nil {
 			if err == ErrConflict {
-				http.Error(w, device.ConflictString(), http.StatusConflict)
+				utils.HttpError(w, device.ConflictString(), http.StatusConflict)
 			} else {
-				http.Error(w, err.Error(), http.StatusBadRequest)
+				utils.HttpError(w, err.Error(), http.StatusBadRequest)
 			}
 			return err
 		}
@@ -232,6 +314,7 @@ func (a *App) DeviceDelete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Delete device
+	actor := requestActor(r)
 	logger.Info("Deleting device %v on node %v", device.Info.Id, device.NodeId)
 	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
 
@@ -277,7 +360,10 @@ func (a *App) DeviceDelete(w http.ResponseWriter, r *http.Request) {
 				return err
 			}
 
-			return nil
+			return AddClusterEvent(tx, node.Info.ClusterId,
+				api.ClusterEventDeviceRemoved, actor,
+				"Removed device "+device.Info.Name+" from node "+node.ManageHostName(),
+				deviceEventMetadata(device))
 
 		})
 		if err != nil {
@@ -302,12 +388,12 @@ func (a *App) DeviceSetState(w http.ResponseWriter, r *http.Request) {
 	var msg api.StateRequest
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 	err = msg.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
 	}
@@ -316,10 +402,10 @@ func (a *App) DeviceSetState(w http.ResponseWriter, r *http.Request) {
 	err = a.db.View(func(tx *bolt.Tx) error {
 		device, err = NewDeviceEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, "Id not found", http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorDeviceNotFound, "Id not found", http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -379,10 +465,10 @@ func (a *App) DeviceResync(w http.ResponseWriter, r *http.Request) {
 		return err
 	})
 	if err == ErrNotFound {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		utils.HttpErrorCode(w, api.ErrorNodeNotFound, err.Error(), http.StatusNotFound)
 		return
 	} else if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 		logger.Err(err)
 		return
 	}
@@ -464,13 +550,13 @@ func (a *App) DeviceSetTags(w http.ResponseWriter, r *http.Request) {
 	var msg api.TagsChangeRequest
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 
 	err = msg.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
 	}
@@ -478,15 +564,15 @@ func (a *App) DeviceSetTags(w http.ResponseWriter, r *http.Request) {
 	err = a.db.Update(func(tx *bolt.Tx) error {
 		device, err = NewDeviceEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, "Id not found", http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorDeviceNotFound, "Id not found", http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 		ApplyTags(device, msg)
 		if err := device.Save(tx); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 		return nil
@@ -544,6 +630,136 @@ func allowDestroyDevice(db wdb.RODB, e error) error {
 	return e
 }
 
+// DeviceReplace moves the bricks of a device to a new device on the
+// same node and removes the old device.
+func (a *App) DeviceReplace(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var msg api.DeviceReplaceRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return
+	}
+
+	var (
+		device *DeviceEntry
+		node   *NodeEntry
+	)
+	err = a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		device, err = NewDeviceEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorDeviceNotFound, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		node, err = NewNodeEntryFromId(tx, device.NodeId)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		for _, deviceId := range node.Devices {
+			d, err := NewDeviceEntryFromId(tx, deviceId)
+			if err != nil {
+				utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+			if d.Info.Name == msg.NewDevicePath {
+				err := fmt.Errorf("Device %v already in use on node %v (ID: %v)",
+					msg.NewDevicePath, node.Info.Id, d.Info.Id)
+				utils.HttpError(w, err.Error(), http.StatusConflict)
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	// the new device keeps the tags of the device it replaces
+	newDevice := NewDeviceEntryFromRequest(&api.DeviceAddRequest{
+		Device: api.Device{
+			Name: msg.NewDevicePath,
+			Tags: device.Info.Tags,
+		},
+		NodeId: node.Info.Id,
+	})
+	err = a.setDeviceVgName(newDevice, node)
+	if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Replacing device %v with %v on node %v",
+		device.Info.Id, msg.NewDevicePath, node.Info.Id)
+	op := NewDeviceReplaceOperation(id, newDevice, a.db, msg.HealCheck)
+	if err := AsyncHttpOperation(a, w, r, op); err != nil {
+		OperationHttpErrorf(w, err, "Failed to set up device replace: %v", err)
+		return
+	}
+}
+
+func (a *App) DeviceReinitialize(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var msg api.DeviceReinitializeRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return
+	}
+
+	err = a.db.View(func(tx *bolt.Tx) error {
+		device, err := NewDeviceEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorDeviceNotFound, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		if len(device.Bricks) > 0 {
+			err := fmt.Errorf("Device %v has %v bricks and can not be reinitialized",
+				device.Info.Id, len(device.Bricks))
+			utils.HttpError(w, err.Error(), http.StatusConflict)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	logger.Info("Reinitializing device %v", id)
+	op := NewDeviceReinitializeOperation(id, a.db)
+	if err := AsyncHttpOperation(a, w, r, op); err != nil {
+		if err == ErrConflict {
+			utils.HttpError(w, err.Error(), http.StatusConflict)
+			return
+		}
+		OperationHttpErrorf(w, err, "Failed to set up device reinitialize: %v", err)
+		return
+	}
+}
+
 func (a *App) BrickEvict(w http.ResponseWriter, r *http.Request) {
 	// Get the id from the URL
 	vars := mux.Vars(r)
@@ -553,14 +769,14 @@ func (a *App) BrickEvict(w http.ResponseWriter, r *http.Request) {
 	if r.ContentLength > 0 {
 		err := utils.GetJsonFromRequest(r, &opts)
 		if err != nil {
-			http.Error(w, "request unable to be parsed", 422)
+			utils.HttpError(w, "request unable to be parsed", 422)
 			return
 		}
 	}
 
 	err := opts.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
 	}
@@ -570,10 +786,10 @@ func (a *App) BrickEvict(w http.ResponseWriter, r *http.Request) {
 		var err error
 		_, err = NewBrickEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			utils.HttpError(w, err.Error(), http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 		return nil
@@ -11,6 +11,7 @@ package glusterfs
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -957,7 +958,7 @@ func TestDeviceSyncBlockedByPending(t *testing.T) {
 	vop := NewVolumeCreateOperation(
 		NewVolumeEntryFromRequest(vreq),
 		app.db)
-	err = vop.Build()
+	err = vop.Build(context.Background())
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	r, err := http.Get(ts.URL + "/devices/" + deviceId + "/resync")
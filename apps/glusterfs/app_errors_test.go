@@ -0,0 +1,122 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+func assertErrorCode(t *testing.T, err error, code api.ErrorCode) {
+	tests.Assert(t, err != nil, "expected err != nil")
+	e, ok := err.(*api.ErrorResponse)
+	tests.Assert(t, ok, "expected *api.ErrorResponse, got:", err)
+	tests.Assert(t, e.Code == code, "expected code", code, "got:", e.Code)
+	tests.Assert(t, e.Message != "", "expected a message")
+}
+
+func postErrorCode(t *testing.T, url string, body []byte) *api.ErrorResponse {
+	r, err := http.Post(url, "application/json", bytes.NewReader(body))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode >= 400, "expected error status, got:", r.StatusCode)
+	err = utils.GetErrorFromResponse(r)
+	e, ok := err.(*api.ErrorResponse)
+	tests.Assert(t, ok, "expected *api.ErrorResponse, got:", err)
+	return e
+}
+
+func TestErrorCodes(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+	missing := "0123456789abcdef0123456789abcdef"
+
+	_, err = c.VolumeInfo(missing)
+	assertErrorCode(t, err, api.ErrorVolumeNotFound)
+	_, err = c.BlockVolumeInfo(missing)
+	assertErrorCode(t, err, api.ErrorBlockVolumeNotFound)
+	_, err = c.NodeInfo(missing)
+	assertErrorCode(t, err, api.ErrorNodeNotFound)
+	_, err = c.DeviceInfo(missing)
+	assertErrorCode(t, err, api.ErrorDeviceNotFound)
+	_, err = c.ClusterInfo(missing)
+	assertErrorCode(t, err, api.ErrorClusterNotFound)
+
+	// invalid and unparsable requests
+	e := postErrorCode(t, ts.URL+"/volumes", []byte(`{"size": 0}`))
+	tests.Assert(t, e.Code == api.ErrorInvalidRequest,
+		"expected INVALID_REQUEST, got:", e.Code)
+	e = postErrorCode(t, ts.URL+"/volumes", []byte(`{"size":`))
+	tests.Assert(t, e.Code == api.ErrorUnprocessable,
+		"expected UNPROCESSABLE_REQUEST, got:", e.Code)
+
+	// the volume does not fit in the cluster
+	req := &api.VolumeCreateRequest{}
+	req.Size = 1000
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+	_, err = c.VolumeCreate(req)
+	assertErrorCode(t, err, api.ErrorInsufficientSpace)
+
+	// a pending operation blocks changes to the volume
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		v.Pending.Id = "abcd"
+		return v.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, err = c.VolumePin(v.Info.Id, &api.VolumePinRequest{})
+	assertErrorCode(t, err, api.ErrorOperationInProgress)
+	tests.Assert(t, err.(*api.ErrorResponse).Detail["operation_id"] == "abcd",
+		"expected operation_id detail, got:", err.(*api.ErrorResponse).Detail)
+
+	// the server is too busy for new operations
+	app.optracker.Limit = 0
+	b, err := json.Marshal(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	e = postErrorCode(t, ts.URL+"/volumes", b)
+	tests.Assert(t, e.Code == api.ErrorServerBusy,
+		"expected SERVER_BUSY, got:", e.Code)
+}
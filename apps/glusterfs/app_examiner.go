@@ -12,6 +12,8 @@ package glusterfs
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/heketi/heketi/pkg/utils"
 )
 
 // ExamineGluster ... Compares the state of heketi db with the state of Gluster
@@ -20,7 +22,7 @@ func (a *App) ExamineGluster(w http.ResponseWriter, r *http.Request) {
 
 	response, err := a.OnDemandExaminer().ExamineGluster()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -33,3 +35,22 @@ func (a *App) ExamineGluster(w http.ResponseWriter, r *http.Request) {
 		panic(err)
 	}
 }
+
+// ConfigDriftCheck reports the differences between the live gluster
+// configuration and the configuration heketi expects.
+func (a *App) ConfigDriftCheck(w http.ResponseWriter, r *http.Request) {
+
+	report, err := CheckConfigDrift(a.db, a.executor)
+	if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Config drift check found %v volume and %v peer differences",
+		len(report.VolumeDrifts), len(report.PeerDrifts))
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		panic(err)
+	}
+}
@@ -53,7 +53,7 @@ func (a *App) SetLogLevel(w http.ResponseWriter, r *http.Request) {
 	msg := api.LogLevelInfo{}
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w,
+		utils.HttpError(w,
 			fmt.Sprintf("request unable to be parsed: %s", err.Error()),
 			http.StatusBadRequest)
 		return
@@ -61,13 +61,13 @@ func (a *App) SetLogLevel(w http.ResponseWriter, r *http.Request) {
 	wantLevel, ok := msg.LogLevel["glusterfs"]
 	if !ok {
 		err := fmt.Errorf("Only \"glusterfs\" logger may be modified")
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		utils.HttpError(w, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
 
 	err = SetLogLevel(wantLevel)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		utils.HttpError(w, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
 	logger.Info("set new log level [%s]", msg.LogLevel)
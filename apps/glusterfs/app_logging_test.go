@@ -203,10 +203,11 @@ func TestSetLogLevelBadJson(t *testing.T) {
 	tests.Assert(t, r.StatusCode == http.StatusBadRequest,
 		"expected r.StatusCode == http.StatusBadRequest, got", r.StatusCode)
 
-	var data api.LogLevelInfo
-	err = utils.GetJsonFromResponse(r, &data)
 	// check that an error message has been set
-	tests.Assert(t, err != nil, "GetJsonFromResponse unset:", err)
+	err = utils.GetErrorFromResponse(r)
+	e, ok := err.(*api.ErrorResponse)
+	tests.Assert(t, ok, "expected *api.ErrorResponse, got:", err)
+	tests.Assert(t, e.Message != "", "expected a message")
 }
 
 func TestSetLogLevelBadLogLevel(t *testing.T) {
@@ -240,10 +241,11 @@ func TestSetLogLevelBadLogLevel(t *testing.T) {
 	tests.Assert(t, r.StatusCode == http.StatusUnprocessableEntity,
 		"expected r.StatusCode == http.StatusUnprocessableEntity, got", r.StatusCode)
 
-	var data api.LogLevelInfo
-	err = utils.GetJsonFromResponse(r, &data)
 	// check that an error message has been set
-	tests.Assert(t, err != nil, "GetJsonFromResponse unset:", err)
+	err = utils.GetErrorFromResponse(r)
+	e, ok := err.(*api.ErrorResponse)
+	tests.Assert(t, ok, "expected *api.ErrorResponse, got:", err)
+	tests.Assert(t, e.Message != "", "expected a message")
 }
 
 func TestLogLevelNameUnexpected(t *testing.T) {
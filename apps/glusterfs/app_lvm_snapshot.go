@@ -0,0 +1,255 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+// loadDeviceBrick loads the brick with the given id, making sure it
+// is stored on the given device.
+func loadDeviceBrick(tx *bolt.Tx, deviceId, brickId string) (*BrickEntry, error) {
+	if _, err := NewDeviceEntryFromId(tx, deviceId); err != nil {
+		return nil, err
+	}
+	brick, err := NewBrickEntryFromId(tx, brickId)
+	if err != nil {
+		return nil, err
+	}
+	if brick.Info.DeviceId != deviceId {
+		return nil, ErrNotFound
+	}
+	return brick, nil
+}
+
+// loadBrickLvmSnapshot loads the snapshot with the given id along
+// with its brick, making sure both belong to the given device.
+func loadBrickLvmSnapshot(tx *bolt.Tx, deviceId, brickId, id string) (
+	*LvmSnapshotEntry, *BrickEntry, error) {
+
+	brick, err := loadDeviceBrick(tx, deviceId, brickId)
+	if err != nil {
+		return nil, nil, err
+	}
+	snap, err := NewLvmSnapshotEntryFromId(tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if snap.Info.BrickId != brickId {
+		return nil, nil, ErrNotFound
+	}
+	return snap, brick, nil
+}
+
+func lvmSnapshotUrl(s *LvmSnapshotEntry) string {
+	return fmt.Sprintf("/devices/%v/bricks/%v/lvm-snapshots/%v",
+		s.Info.DeviceId, s.Info.BrickId, s.Info.Id)
+}
+
+func (a *App) LvmSnapshotCreate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	deviceId := vars["id"]
+	brickId := vars["brick_id"]
+
+	var msg api.LvmSnapshotCreateRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return
+	}
+
+	var snap *LvmSnapshotEntry
+	var brick *BrickEntry
+	err = a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		brick, err = loadDeviceBrick(tx, deviceId, brickId)
+		if err == ErrNotFound {
+			utils.HttpError(w, "Brick not found on device", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		if brick.Pending.Id != "" {
+			utils.WriteErrorResponse(w, http.StatusConflict, &api.ErrorResponse{
+				Code:    api.ErrorOperationInProgress,
+				Message: "Brick is in use by a pending operation",
+				Detail:  map[string]interface{}{"operation_id": brick.Pending.Id},
+			})
+			return ErrConflict
+		}
+
+		snaps, err := LvmSnapshotsOfBrick(tx, brickId)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		for _, s := range snaps {
+			if s.Info.Name == msg.Name {
+				utils.HttpError(w, "Snapshot "+msg.Name+" already exists",
+					http.StatusConflict)
+				return ErrConflict
+			}
+		}
+
+		snap = NewLvmSnapshotEntryFromRequest(&msg, brick)
+		device, err := NewDeviceEntryFromId(tx, deviceId)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		if !device.StorageCheck(snap.Size) {
+			utils.HttpError(w, ErrNoSpace.Error(), http.StatusInsufficientStorage)
+			return ErrNoSpace
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	host, err := brick.host(a.db)
+	if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Creating lvm snapshot %v of brick %v", snap.Info.Name, brickId)
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+		info, err := a.executor.LvmSnapshotCreate(host, snap.request(brick))
+		if err != nil {
+			return "", err
+		}
+		snap.Info.Path = info.Path
+
+		err = a.db.Update(func(tx *bolt.Tx) error {
+			device, err := NewDeviceEntryFromId(tx, deviceId)
+			if err != nil {
+				return err
+			}
+			device.StorageAllocate(snap.Size)
+			if err := device.Save(tx); err != nil {
+				return err
+			}
+			return snap.Save(tx)
+		})
+		if err != nil {
+			return "", err
+		}
+		logger.Info("Created lvm snapshot %v at %v", snap.Info.Id, snap.Info.Path)
+		return lvmSnapshotUrl(snap), nil
+	})
+}
+
+func (a *App) LvmSnapshotInfo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var info *api.LvmSnapshotInfo
+	err := a.db.View(func(tx *bolt.Tx) error {
+		snap, _, err := loadBrickLvmSnapshot(tx,
+			vars["id"], vars["brick_id"], vars["snapshot_id"])
+		if err == ErrNotFound {
+			utils.HttpError(w, "Snapshot not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		info = &snap.Info
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) LvmSnapshotDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	deviceId := vars["id"]
+
+	var snap *LvmSnapshotEntry
+	var brick *BrickEntry
+	err := a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		snap, brick, err = loadBrickLvmSnapshot(tx,
+			deviceId, vars["brick_id"], vars["snapshot_id"])
+		if err == ErrNotFound {
+			utils.HttpError(w, "Snapshot not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	host, err := brick.host(a.db)
+	if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Deleting lvm snapshot %v of brick %v",
+		snap.Info.Name, snap.Info.BrickId)
+	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
+		err := a.executor.LvmSnapshotDestroy(host, snap.request(brick))
+		if err != nil {
+			return "", err
+		}
+
+		err = a.db.Update(func(tx *bolt.Tx) error {
+			// the brick may have been removed, and the snapshot
+			// with it, in the meantime
+			snap, err := NewLvmSnapshotEntryFromId(tx, snap.Info.Id)
+			if err == ErrNotFound {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			device, err := NewDeviceEntryFromId(tx, deviceId)
+			if err != nil {
+				return err
+			}
+			device.StorageFree(snap.Size)
+			if err := device.Save(tx); err != nil {
+				return err
+			}
+			return snap.Delete(tx)
+		})
+		if err != nil {
+			return "", err
+		}
+		logger.Info("Deleted lvm snapshot %v", snap.Info.Id)
+		return "", nil
+	})
+}
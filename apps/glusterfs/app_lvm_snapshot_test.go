@@ -0,0 +1,240 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+func TestLvmSnapshotCreateDelete(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var brick *BrickEntry
+	var used uint64
+	app.db.View(func(tx *bolt.Tx) error {
+		brick, err = NewBrickEntryFromId(tx, v.BricksIds()[0])
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		d, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		used = d.Info.Storage.Used
+		return nil
+	})
+
+	var snapReq *executors.LvmSnapshotRequest
+	app.xo.MockLvmSnapshotCreate = func(host string, snap *executors.LvmSnapshotRequest) (*executors.LvmSnapshotInfo, error) {
+		snapReq = snap
+		return &executors.LvmSnapshotInfo{Path: "/dev/vg_x/" + snap.Name}, nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	info, err := c.LvmSnapshotCreate(brick.Info.DeviceId, brick.Info.Id,
+		&api.LvmSnapshotCreateRequest{Name: "backup1", SizePercent: 20})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Path == "/dev/vg_x/backup1",
+		"expected info.Path == /dev/vg_x/backup1, got:", info.Path)
+	tests.Assert(t, info.BrickId == brick.Info.Id,
+		"expected info.BrickId == brick.Info.Id, got:", info.BrickId)
+
+	// the executor was asked to snapshot the lv of the brick
+	tests.Assert(t, snapReq != nil, "expected snapshot request")
+	tests.Assert(t, snapReq.VgId == brick.Info.DeviceId,
+		"expected snapReq.VgId == brick.Info.DeviceId, got:", snapReq.VgId)
+	tests.Assert(t, snapReq.LvName == brick.LvName(),
+		"expected snapReq.LvName == brick.LvName(), got:", snapReq.LvName)
+	tests.Assert(t, snapReq.Name == "backup1",
+		"expected snapReq.Name == backup1, got:", snapReq.Name)
+	tests.Assert(t, snapReq.SizePercent == 20,
+		"expected snapReq.SizePercent == 20, got:", snapReq.SizePercent)
+
+	// the snapshot is recorded and its space is accounted for
+	app.db.View(func(tx *bolt.Tx) error {
+		s, err := NewLvmSnapshotEntryFromId(tx, info.Id)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, s.Info.DeviceId == brick.Info.DeviceId,
+			"expected s.Info.DeviceId == brick.Info.DeviceId, got:",
+			s.Info.DeviceId)
+		tests.Assert(t, s.Size == brick.Info.Size/5,
+			"expected s.Size == brick.Info.Size/5, got:", s.Size)
+		d, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, d.Info.Storage.Used == used+s.Size,
+			"expected d.Info.Storage.Used == used+s.Size, got:",
+			d.Info.Storage.Used)
+		return nil
+	})
+	check, err := dbCheckConsistency(app.db)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, check.TotalInconsistencies == 0,
+		"expected no inconsistencies, got:", check.Devices)
+
+	// names are unique per brick
+	_, err = c.LvmSnapshotCreate(brick.Info.DeviceId, brick.Info.Id,
+		&api.LvmSnapshotCreateRequest{Name: "backup1", SizePercent: 20})
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "already exists"),
+		"expected already exists error, got:", err)
+
+	// the brick must be on the device
+	r, err := http.Get(ts.URL + "/devices/" + idForOtherDevice(t, app, brick) +
+		"/bricks/" + brick.Info.Id + "/lvm-snapshots/" + info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound,
+		"expected r.StatusCode == http.StatusNotFound, got:", r.StatusCode)
+
+	err = c.LvmSnapshotDelete(brick.Info.DeviceId, brick.Info.Id, info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewLvmSnapshotEntryFromId(tx, info.Id)
+		tests.Assert(t, err == ErrNotFound,
+			"expected err == ErrNotFound, got:", err)
+		d, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, d.Info.Storage.Used == used,
+			"expected d.Info.Storage.Used == used, got:", d.Info.Storage.Used)
+		return nil
+	})
+}
+
+func TestLvmSnapshotRemovedWithBrick(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var brick *BrickEntry
+	app.db.View(func(tx *bolt.Tx) error {
+		brick, err = NewBrickEntryFromId(tx, v.BricksIds()[0])
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		return nil
+	})
+
+	c := client.NewClientNoAuth(ts.URL)
+	info, err := c.LvmSnapshotCreate(brick.Info.DeviceId, brick.Info.Id,
+		&api.LvmSnapshotCreateRequest{Name: "backup1", SizePercent: 50})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	err = v.Destroy(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewLvmSnapshotEntryFromId(tx, info.Id)
+		tests.Assert(t, err == ErrNotFound,
+			"expected err == ErrNotFound, got:", err)
+		d, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, d.Info.Storage.Used == 0,
+			"expected d.Info.Storage.Used == 0, got:", d.Info.Storage.Used)
+		return nil
+	})
+}
+
+func TestLvmSnapshotCreateInvalid(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	url := ts.URL + "/devices/abc/bricks/def/lvm-snapshot"
+	for _, body := range []string{
+		`{"snapshot_name": "-bad", "size_percent": 10}`,
+		`{"snapshot_name": "good", "size_percent": 0}`,
+		`{"snapshot_name": "good", "size_percent": 101}`,
+	} {
+		r, err := http.Post(url, "application/json", strings.NewReader(body))
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, r.StatusCode == http.StatusBadRequest,
+			"expected r.StatusCode == http.StatusBadRequest, got:",
+			r.StatusCode, body)
+	}
+
+	r, err := http.Post(url, "application/json",
+		strings.NewReader(`{"snapshot_name": "good", "size_percent": 10}`))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound,
+		"expected r.StatusCode == http.StatusNotFound, got:", r.StatusCode)
+}
+
+// idForOtherDevice returns the id of a device not storing the brick.
+func idForOtherDevice(t *testing.T, app *App, brick *BrickEntry) string {
+	var id string
+	err := app.db.View(func(tx *bolt.Tx) error {
+		dl, err := DeviceList(tx)
+		if err != nil {
+			return err
+		}
+		for _, deviceId := range dl {
+			if deviceId != brick.Info.DeviceId {
+				id = deviceId
+				break
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, id != "", "expected another device")
+	return id
+}
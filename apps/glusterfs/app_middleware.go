@@ -19,6 +19,7 @@ import (
 
 	"github.com/heketi/heketi/middleware"
 	"github.com/heketi/heketi/pkg/kubernetes"
+	"github.com/heketi/heketi/pkg/utils"
 )
 
 var (
@@ -37,7 +38,7 @@ func (a *App) Auth(w http.ResponseWriter, r *http.Request, next http.HandlerFunc
 
 	// Check access
 	if "user" == claims.Issuer && r.URL.Path != "/volumes" {
-		http.Error(w, "Administrator access required", http.StatusUnauthorized)
+		utils.HttpError(w, "Administrator access required", http.StatusUnauthorized)
 		return
 	}
 
@@ -45,6 +46,21 @@ func (a *App) Auth(w http.ResponseWriter, r *http.Request, next http.HandlerFunc
 	next(w, r)
 }
 
+// requestActor returns who made the request, the issuer of its token.
+// Without authentication no one is known and an empty string is
+// returned.
+func requestActor(r *http.Request) string {
+	token, ok := context.Get(r, "jwt").(*jwt.Token)
+	if !ok {
+		return ""
+	}
+	claims, ok := token.Claims.(*middleware.HeketiJwtClaims)
+	if !ok || claims.StandardClaims == nil {
+		return ""
+	}
+	return claims.Issuer
+}
+
 // Backup database to a secret
 func (a *App) BackupToKubernetesSecret(
 	w http.ResponseWriter,
@@ -11,7 +11,9 @@ package glusterfs
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/gorilla/mux"
@@ -25,24 +27,24 @@ func (a *App) NodeAdd(w http.ResponseWriter, r *http.Request) {
 
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 
 	err = msg.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
 	}
 
 	// Check information in JSON request
 	if len(msg.Hostnames.Manage) == 0 {
-		http.Error(w, "Manage hostname missing", http.StatusBadRequest)
+		utils.HttpError(w, "Manage hostname missing", http.StatusBadRequest)
 		return
 	}
 	if len(msg.Hostnames.Storage) == 0 {
-		http.Error(w, "Storage hostname missing", http.StatusBadRequest)
+		utils.HttpError(w, "Storage hostname missing", http.StatusBadRequest)
 		return
 	}
 
@@ -50,14 +52,14 @@ func (a *App) NodeAdd(w http.ResponseWriter, r *http.Request) {
 	// if it is because it was set to zero, or it is the default
 	// value used for missing 'zone' in JSON
 	if msg.Zone == 0 {
-		http.Error(w, "Zone cannot be zero or value is missing", http.StatusBadRequest)
+		utils.HttpError(w, "Zone cannot be zero or value is missing", http.StatusBadRequest)
 		return
 	}
 
 	// Check for correct values
 	for _, name := range append(msg.Hostnames.Manage, msg.Hostnames.Storage...) {
 		if name == "" {
-			http.Error(w, "Hostname cannot be an empty string", http.StatusBadRequest)
+			utils.HttpError(w, "Hostname cannot be an empty string", http.StatusBadRequest)
 			return
 		}
 	}
@@ -72,17 +74,17 @@ func (a *App) NodeAdd(w http.ResponseWriter, r *http.Request) {
 		var err error
 		cluster, err = NewClusterEntryFromId(tx, msg.ClusterId)
 		if err == ErrNotFound {
-			http.Error(w, "Cluster id does not exist", http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorClusterNotFound, "Cluster id does not exist", http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
 		// Register node
 		err = node.Register(tx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusConflict)
+			utils.HttpError(w, err.Error(), http.StatusConflict)
 			return err
 		}
 		return nil
@@ -91,14 +93,32 @@ func (a *App) NodeAdd(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get a node's hostname in the cluster to execute the Gluster peer command
-	// only if there is more than one node
-	if len(cluster.Info.Nodes) > 0 {
-		peer_node_hostname, err = GetVerifiedManageHostname(a.db, a.executor, cluster.Info.Id)
+	// Get a node's hostname in the trusted storage pool of the new node
+	// to execute the Gluster peer command only if the pool has other nodes
+	tspNodes := 0
+	err = a.db.View(func(tx *bolt.Tx) error {
+		tsps, err := cluster.TrustedStoragePools(tx)
+		if err != nil {
+			return err
+		}
+		for _, tsp := range tsps {
+			if tsp.Id == node.Info.TspId {
+				tspNodes = len(tsp.PeerNodes)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if tspNodes > 0 {
+		peer_node_hostname, err = GetVerifiedTspManageHostname(a.db, a.executor,
+			cluster.Info.Id, node.Info.TspId)
 		if err != nil {
 			logger.Err(err)
 			err := logger.LogError("None of the nodes in cluster has glusterd running")
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			utils.HttpError(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 	} else {
@@ -106,12 +126,13 @@ func (a *App) NodeAdd(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			logger.Err(err)
 			err := logger.LogError("New Node doesn't have glusterd running")
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			utils.HttpError(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 	}
 
 	// Add node
+	actor := requestActor(r)
 	logger.Info("Adding node %v", node.ManageHostName())
 	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (seeother string, e error) {
 
@@ -139,10 +160,10 @@ func (a *App) NodeAdd(w http.ResponseWriter, r *http.Request) {
 		err = a.db.Update(func(tx *bolt.Tx) error {
 			cluster, err := NewClusterEntryFromId(tx, msg.ClusterId)
 			if err == ErrNotFound {
-				http.Error(w, "Cluster id does not exist", http.StatusNotFound)
+				utils.HttpErrorCode(w, api.ErrorClusterNotFound, "Cluster id does not exist", http.StatusNotFound)
 				return err
 			} else if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 				return err
 			}
 
@@ -152,18 +173,19 @@ func (a *App) NodeAdd(w http.ResponseWriter, r *http.Request) {
 			// Save cluster
 			err = cluster.Save(tx)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 				return err
 			}
 
 			// Save node
 			err = node.Save(tx)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 				return err
 			}
 
-			return nil
+			return AddClusterEvent(tx, cluster.Info.Id, api.ClusterEventNodeAdded,
+				actor, "Added node "+node.ManageHostName(), nodeEventMetadata(node))
 
 		})
 		if err != nil {
@@ -185,16 +207,16 @@ func (a *App) NodeInfo(w http.ResponseWriter, r *http.Request) {
 	err := a.db.View(func(tx *bolt.Tx) error {
 		entry, err := NewNodeEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, "Id not found", http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorNodeNotFound, "Id not found", http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
 		info, err = entry.NewInfoReponse(tx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -229,16 +251,16 @@ func (a *App) NodeDelete(w http.ResponseWriter, r *http.Request) {
 		var err error
 		node, err = NewNodeEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorNodeNotFound, err.Error(), http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return logger.Err(err)
 		}
 
 		// Check the node can be deleted
 		if !node.IsDeleteOk() {
-			http.Error(w, node.ConflictString(), http.StatusConflict)
+			utils.HttpError(w, node.ConflictString(), http.StatusConflict)
 			logger.LogError(node.ConflictString())
 			return ErrConflict
 		}
@@ -246,28 +268,27 @@ func (a *App) NodeDelete(w http.ResponseWriter, r *http.Request) {
 		// Access cluster information and peer node
 		cluster, err = NewClusterEntryFromId(tx, node.Info.ClusterId)
 		if err == ErrNotFound {
-			http.Error(w, "Cluster id does not exist", http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorClusterNotFound, "Cluster id does not exist", http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return logger.Err(err)
 		}
 
-		// Get a node in the cluster to execute the Gluster peer command
-		// If it only has one in the list, then there is no need to do a
-		// peer detach.
-		if len(cluster.Info.Nodes) > 1 {
-			for index := range cluster.Info.Nodes {
-				peer_node, err = cluster.NodeEntryFromClusterIndex(tx, index)
-				if err != nil {
-					return logger.Err(err)
-				}
+		// Get a node in the trusted storage pool of the node to execute
+		// the Gluster peer command. If it is the only node of the pool,
+		// then there is no need to do a peer detach.
+		for index := range cluster.Info.Nodes {
+			n, err := cluster.NodeEntryFromClusterIndex(tx, index)
+			if err != nil {
+				return logger.Err(err)
+			}
 
-				// Cannot peer detach from the same node, we need to execute
-				// the command from another node
-				if peer_node.Info.Id != node.Info.Id {
-					break
-				}
+			// Cannot peer detach from the same node, we need to execute
+			// the command from another node
+			if n.Info.Id != node.Info.Id && n.Info.TspId == node.Info.TspId {
+				peer_node = n
+				break
 			}
 		}
 		return nil
@@ -277,6 +298,7 @@ func (a *App) NodeDelete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Delete node asynchronously
+	actor := requestActor(r)
 	logger.Info("Deleting node %v [%v]", node.ManageHostName(), node.Info.Id)
 	a.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
 
@@ -325,7 +347,8 @@ func (a *App) NodeDelete(w http.ResponseWriter, r *http.Request) {
 				logger.Err(err)
 				return err
 			}
-			return nil
+			return AddClusterEvent(tx, cluster.Info.Id, api.ClusterEventNodeRemoved,
+				actor, "Removed node "+node.ManageHostName(), nodeEventMetadata(node))
 
 		})
 		if err != nil {
@@ -352,16 +375,16 @@ func refreshVolumeNodes(tx *bolt.Tx, node *NodeEntry) error {
 		return err
 	}
 
-	hosts, err := getHostsFromCluster(txdb, clusterID)
-	if err != nil {
-		return err
-	}
 	for _, volID := range cluster.Info.Volumes {
 		volEntry, err := NewVolumeEntryFromId(tx, volID)
 		if err != nil {
 			logger.LogError("Get volume entry for ID %s Failed with error %s", volID, err.Error())
 			continue
 		}
+		hosts, err := getHostsFromCluster(txdb, clusterID, volEntry.Info.TspId)
+		if err != nil {
+			return err
+		}
 		if volEntry.Info.Block {
 			for _, id := range volEntry.Info.BlockInfo.BlockVolumes {
 
@@ -400,12 +423,12 @@ func (a *App) NodeSetState(w http.ResponseWriter, r *http.Request) {
 	var msg api.StateRequest
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 	err = msg.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
 	}
@@ -414,10 +437,10 @@ func (a *App) NodeSetState(w http.ResponseWriter, r *http.Request) {
 	err = a.db.View(func(tx *bolt.Tx) error {
 		node, err = NewNodeEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, "Id not found", http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorNodeNotFound, "Id not found", http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -468,13 +491,13 @@ func (a *App) NodeSetTags(w http.ResponseWriter, r *http.Request) {
 	var msg api.TagsChangeRequest
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 
 	err = msg.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
 	}
@@ -482,15 +505,15 @@ func (a *App) NodeSetTags(w http.ResponseWriter, r *http.Request) {
 	err = a.db.Update(func(tx *bolt.Tx) error {
 		node, err = NewNodeEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, "Id not found", http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorNodeNotFound, "Id not found", http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 		ApplyTags(node, msg)
 		if err := node.Save(tx); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 		return nil
@@ -505,3 +528,207 @@ func (a *App) NodeSetTags(w http.ResponseWriter, r *http.Request) {
 		panic(err)
 	}
 }
+
+func (a *App) NodeSetIp(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	var info *api.NodeInfoResponse
+
+	var msg api.NodeSetIpRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return
+	}
+
+	err = a.db.Update(func(tx *bolt.Tx) error {
+		node, err := NewNodeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorNodeNotFound, "Id not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		// a manually set address does not expire
+		node.Info.ResolvedIP = msg.Ip
+		node.ResolvedIPTime = 0
+		if err := node.Save(tx); err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		info, err = node.NewInfoReponse(tx)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Err(err)
+		return
+	}
+	logger.Info("Set address of node %v to %v", id, msg.Ip)
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) NodeBricks(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	resp := api.NodeBricksResponse{Bricks: []api.BrickInfo{}}
+	err := a.db.View(func(tx *bolt.Tx) error {
+		node, err := NewNodeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorNodeNotFound, "Id not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		for _, deviceId := range node.Devices {
+			device, err := NewDeviceEntryFromId(tx, deviceId)
+			if err != nil {
+				utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+			for _, brickId := range device.Bricks {
+				brick, err := NewBrickEntryFromId(tx, brickId)
+				if err != nil {
+					utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+					return err
+				}
+				info, err := brick.NewInfoResponse(tx)
+				if err != nil {
+					utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+					return err
+				}
+				resp.Bricks = append(resp.Bricks, *info)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+// NodePing checks that the server can run commands on the node.
+// Unlike most node requests it runs synchronously as the check
+// is quick and does not change the state of the system.
+func (a *App) NodePing(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var host string
+	err := a.db.View(func(tx *bolt.Tx) error {
+		node, err := NewNodeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorNodeNotFound, "Id not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		host = node.ManageHostName()
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	resp := api.NodePingResponse{Health: api.NodeHealthUnknown}
+	if up, found := currentNodeHealthStatus()[id]; found {
+		if up {
+			resp.Health = api.NodeHealthUp
+		} else {
+			resp.Health = api.NodeHealthDown
+		}
+	}
+	start := time.Now()
+	if err := a.executor.NodePing(host); err != nil {
+		logger.Warning("Unable to ping node %v: %v", id, err)
+		resp.Error = err.Error()
+	} else {
+		resp.Reachable = true
+		resp.LatencyMs = durationMs(time.Since(start))
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+var ErrKnownHostsDisabled = errors.New(
+	"host key verification is disabled, no known_hosts_file configured for the ssh executor")
+
+// knownHostUpdater records the ssh host keys of the nodes.
+type knownHostUpdater interface {
+	UpdateKnownHost(host string) (string, error)
+}
+
+// NodeUpdateKnownHost records the ssh host key currently presented by
+// the node as the key the server verifies the node against, replacing
+// any key recorded before. It is used after a node is added or after
+// its host key has changed.
+func (a *App) NodeUpdateKnownHost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	if a.knownHosts == nil {
+		utils.HttpError(w, ErrKnownHostsDisabled.Error(),
+			http.StatusServiceUnavailable)
+		return
+	}
+
+	var host string
+	err := a.db.View(func(tx *bolt.Tx) error {
+		node, err := NewNodeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorNodeNotFound, "Id not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		host = node.ManageHostName()
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	fingerprint, err := a.knownHosts.UpdateKnownHost(host)
+	if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Recorded host key %v of node %v", fingerprint, id)
+
+	resp := api.NodeKnownHostResponse{Fingerprint: fingerprint}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
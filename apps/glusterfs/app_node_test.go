@@ -801,9 +801,8 @@ func TestNodePeerProbeFailure(t *testing.T) {
 			time.Sleep(time.Millisecond * 10)
 		} else {
 			tests.Assert(t, r.StatusCode == http.StatusInternalServerError)
-			s, err := utils.GetStringFromResponse(r)
-			tests.Assert(t, err == nil)
-			tests.Assert(t, strings.TrimSpace(s) == "Mock")
+			err := utils.GetErrorFromResponse(r)
+			tests.Assert(t, err.Error() == "Mock", "expected Mock, got:", err)
 			tests.Assert(t, peerprobe_called == true)
 			tests.Assert(t, peerprobe_calls == 1)
 			break
@@ -912,9 +911,8 @@ func TestNodePeerDetachFailure(t *testing.T) {
 			time.Sleep(time.Millisecond * 10)
 		} else {
 			tests.Assert(t, r.StatusCode == http.StatusInternalServerError)
-			s, err := utils.GetStringFromResponse(r)
-			tests.Assert(t, err == nil)
-			tests.Assert(t, strings.TrimSpace(s) == "Mock")
+			err := utils.GetErrorFromResponse(r)
+			tests.Assert(t, err.Error() == "Mock", "expected Mock, got:", err)
 			tests.Assert(t, peer_called == true)
 			tests.Assert(t, peer_calls == 1)
 			break
@@ -1533,3 +1531,217 @@ func TestNodeSetTags(t *testing.T) {
 	tests.Assert(t, r.StatusCode == http.StatusNotFound,
 		"expected r.StatusCode == http.StatusNotFound, got:", r.StatusCode)
 }
+
+func TestNodeBricksAndPing(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var nodeId string
+	err = app.db.View(func(tx *bolt.Tx) error {
+		nl, err := NodeList(tx)
+		nodeId = nl[0]
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+	bricks, err := c.NodeBricks(nodeId)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(bricks.Bricks) == 1,
+		"expected len(bricks.Bricks) == 1, got:", len(bricks.Bricks))
+	tests.Assert(t, bricks.Bricks[0].NodeId == nodeId,
+		"expected brick on node", nodeId, "got:", bricks.Bricks[0].NodeId)
+	tests.Assert(t, bricks.Bricks[0].VolumeId == v.Info.Id,
+		"expected brick of volume", v.Info.Id, "got:", bricks.Bricks[0].VolumeId)
+
+	ping, err := c.NodePing(nodeId)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, ping.Reachable, "expected ping.Reachable to be true")
+	tests.Assert(t, ping.Health == api.NodeHealthUnknown,
+		"expected ping.Health == unknown, got:", ping.Health)
+
+	app.xo.MockNodePing = func(host string) error {
+		return errors.New("connection refused")
+	}
+	ping, err = c.NodePing(nodeId)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, !ping.Reachable, "expected ping.Reachable to be false")
+	tests.Assert(t, ping.Error == "connection refused",
+		"expected ping.Error == connection refused, got:", ping.Error)
+
+	_, err = c.NodeBricks(idgen.GenUUID())
+	tests.Assert(t, err != nil, "expected err != nil")
+	_, err = c.NodePing(idgen.GenUUID())
+	tests.Assert(t, err != nil, "expected err != nil")
+}
+
+func TestNodeSetIp(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var nodeId string
+	err = app.db.View(func(tx *bolt.Tx) error {
+		nl, err := NodeList(tx)
+		nodeId = nl[0]
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+	info, err := c.NodeSetIp(nodeId, &api.NodeSetIpRequest{Ip: "192.168.10.1"})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.ResolvedIP == "192.168.10.1",
+		"expected address, got:", info.ResolvedIP)
+
+	info, err = c.NodeInfo(nodeId)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.ResolvedIP == "192.168.10.1",
+		"expected address, got:", info.ResolvedIP)
+	err = app.db.View(func(tx *bolt.Tx) error {
+		n, err := NewNodeEntryFromId(tx, nodeId)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, n.ResolvedIPTime == 0,
+			"expected manual address, got:", n.ResolvedIPTime)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// connecting to the node with a freshly resolved address does not
+	// replace the address set by the admin
+	var host string
+	err = app.db.View(func(tx *bolt.Tx) error {
+		n, err := NewNodeEntryFromId(tx, nodeId)
+		host = n.ManageHostName()
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	hs := &hostIPStore{app: app, ttl: time.Hour}
+	err = hs.SetHostIP(host, "10.0.0.9")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	ip, err := hs.HostIP(host)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, ip == "192.168.10.1", "expected manual address, got:", ip)
+	err = app.db.View(func(tx *bolt.Tx) error {
+		n, err := NewNodeEntryFromId(tx, nodeId)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, n.ResolvedIPTime == 0,
+			"expected manual address, got:", n.ResolvedIPTime)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	_, err = c.NodeSetIp(nodeId, &api.NodeSetIpRequest{Ip: "node1.example.com"})
+	tests.Assert(t, err != nil, "expected err != nil")
+	_, err = c.NodeSetIp(nodeId, &api.NodeSetIpRequest{})
+	tests.Assert(t, err != nil, "expected err != nil")
+	_, err = c.NodeSetIp(idgen.GenUUID(), &api.NodeSetIpRequest{Ip: "192.168.10.1"})
+	assertErrorCode(t, err, api.ErrorNodeNotFound)
+}
+
+type fakeKnownHosts struct {
+	hosts []string
+	err   error
+}
+
+func (f *fakeKnownHosts) UpdateKnownHost(host string) (string, error) {
+	f.hosts = append(f.hosts, host)
+	return "SHA256:xyz", f.err
+}
+
+func TestNodeUpdateKnownHost(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		2,    // nodes_per_cluster
+		1,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var node *NodeEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		nl, err := NodeList(tx)
+		if err != nil {
+			return err
+		}
+		node, err = NewNodeEntryFromId(tx, nl[0])
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// the mock executor does not record host keys
+	c := client.NewClientNoAuth(ts.URL)
+	_, err = c.NodeUpdateKnownHost(node.Info.Id)
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "known_hosts_file"),
+		"unexpected error:", err)
+
+	f := &fakeKnownHosts{}
+	app.knownHosts = f
+	resp, err := c.NodeUpdateKnownHost(node.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, resp.Fingerprint == "SHA256:xyz",
+		"unexpected fingerprint:", resp.Fingerprint)
+	tests.Assert(t, len(f.hosts) == 1 && f.hosts[0] == node.ManageHostName(),
+		"expected the manage hostname of the node, got:", f.hosts)
+
+	f.err = errors.New("connection refused")
+	_, err = c.NodeUpdateKnownHost(node.Info.Id)
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	_, err = c.NodeUpdateKnownHost(idgen.GenUUID())
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, len(f.hosts) == 2, "expected 2 updates, got:", f.hosts)
+}
@@ -13,6 +13,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/gorilla/mux"
@@ -44,6 +47,9 @@ func (a *App) AppOperationsInfo() (*api.OperationsInfo, error) {
 	}
 
 	info.InFlight = a.optracker.Get()
+	if a.bfaults != nil {
+		info.AutoBrickReplaced = a.bfaults.Replaced()
+	}
 
 	return info, nil
 }
@@ -67,11 +73,14 @@ func (a *App) OperationsInfo(w http.ResponseWriter, r *http.Request) {
 		return nil
 	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	info.InFlight = a.optracker.Get()
+	if a.bfaults != nil {
+		info.AutoBrickReplaced = a.bfaults.Replaced()
+	}
 
 	// Write msg
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -81,30 +90,87 @@ func (a *App) OperationsInfo(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// operationTagsMatch returns true if the operation carries all of
+// the given tags.
+func operationTagsMatch(pop *PendingOperationEntry, tags map[string]string) bool {
+	for k, v := range tags {
+		if t, ok := pop.Tags[k]; !ok || t != v {
+			return false
+		}
+	}
+	return true
+}
+
 func (a *App) PendingOperationList(w http.ResponseWriter, r *http.Request) {
 	p := &api.PendingOperationListResponse{}
 	tracked := a.optracker.Tracked()
 
+	tags := map[string]string{}
+	for name, values := range r.URL.Query() {
+		if !strings.HasPrefix(name, api.OperationTagQueryPrefix) {
+			continue
+		}
+		key := strings.ToLower(strings.TrimPrefix(name, api.OperationTagQueryPrefix))
+		if key == "" || len(values) == 0 {
+			utils.HttpError(w, "invalid tag filter: "+name, http.StatusBadRequest)
+			return
+		}
+		tags[key] = values[0]
+	}
+	includeArchived := false
+	if v := r.URL.Query().Get("include_archived"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			utils.HttpError(w, "invalid include_archived: "+v,
+				http.StatusBadRequest)
+			return
+		}
+		includeArchived = b
+	}
+
 	err := a.db.View(func(tx *bolt.Tx) error {
 		ops, err := PendingOperationList(tx)
 		if err != nil {
 			return err
 		}
-		p.PendingOperations = make([]api.PendingOperationInfo, len(ops))
-		for i, pid := range ops {
+		p.PendingOperations = make([]api.PendingOperationInfo, 0, len(ops))
+		for _, pid := range ops {
 			pop, err := NewPendingOperationEntryFromId(tx, pid)
 			if err != nil {
 				return err
 			}
-			p.PendingOperations[i] = pop.ToInfo()
+			if !operationTagsMatch(pop, tags) {
+				continue
+			}
+			info := pop.ToInfo()
 			if tracked[pop.Id] {
-				p.PendingOperations[i].SubStatus = "in-flight"
+				info.SubStatus = "in-flight"
+			}
+			p.PendingOperations = append(p.PendingOperations, info)
+		}
+		if !includeArchived {
+			return nil
+		}
+		archived, err := ArchivedPendingOperationList(tx)
+		if err != nil {
+			return err
+		}
+		for _, pid := range archived {
+			pop, err := NewArchivedPendingOperationEntryFromId(tx, pid)
+			if err != nil {
+				return err
+			}
+			if !operationTagsMatch(pop, tags) {
+				continue
 			}
+			info := pop.ToInfo()
+			info.SubStatus = "archived"
+			p.PendingOperations = append(p.PendingOperations, info)
 		}
 		return nil
 	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -123,26 +189,25 @@ func (a *App) PendingOperationDetails(w http.ResponseWriter, r *http.Request) {
 
 	err := a.db.View(func(tx *bolt.Tx) error {
 		pop, err := NewPendingOperationEntryFromId(tx, pid)
-		if err != nil {
-			return err
-		}
-		info = &api.PendingOperationDetails{
-			PendingOperationInfo: pop.ToInfo(),
-			Changes:              make([]api.PendingChangeInfo, len(pop.Actions)),
-		}
-		for i, a := range pop.Actions {
-			info.Changes[i] = api.PendingChangeInfo{
-				Id:          a.Id,
-				Description: a.Change.Name(),
+		if err == ErrNotFound {
+			pop, err = NewArchivedPendingOperationEntryFromId(tx, pid)
+			if err != nil {
+				return err
 			}
+			info = pop.ToDetails()
+			info.SubStatus = "archived"
+			return nil
+		} else if err != nil {
+			return err
 		}
+		info = pop.ToDetails()
 		return nil
 	})
 	if err == ErrNotFound {
-		http.Error(w, fmt.Sprintf("Id not found: %v", pid), http.StatusNotFound)
+		utils.HttpError(w, fmt.Sprintf("Id not found: %v", pid), http.StatusNotFound)
 		return
 	} else if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -154,18 +219,121 @@ func (a *App) PendingOperationDetails(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+var (
+	// how often a pending operation streamed to a client is checked
+	// for changes
+	pendingOperationEventInterval = time.Second
+)
+
+func writePendingOperationEvent(w http.ResponseWriter,
+	event string, info *api.PendingOperationDetails) error {
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %v\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	w.(http.Flusher).Flush()
+	return nil
+}
+
+// PendingOperationEvents streams the state of a pending operation as
+// server-sent events. A "progress" event is sent when the stream starts
+// and whenever the status or progress of the operation changes. A
+// "done" event holding the last known state is sent once the operation
+// is no longer pending, which ends the stream.
+func (a *App) PendingOperationEvents(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid := vars["id"]
+
+	if _, ok := w.(http.Flusher); !ok {
+		utils.HttpError(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var last *api.PendingOperationDetails
+	for {
+		var info *api.PendingOperationDetails
+		err := a.db.View(func(tx *bolt.Tx) error {
+			pop, err := NewPendingOperationEntryFromId(tx, pid)
+			if err != nil {
+				return err
+			}
+			info = pop.ToDetails()
+			return nil
+		})
+		switch {
+		case last == nil && err == ErrNotFound:
+			utils.HttpError(w, fmt.Sprintf("Id not found: %v", pid), http.StatusNotFound)
+			return
+		case last == nil && err != nil:
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		case err == ErrNotFound:
+			writePendingOperationEvent(w, "done", last)
+			return
+		case err != nil:
+			logger.LogError("Unable to stream operation %v: %v", pid, err)
+			return
+		}
+
+		if last == nil {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.WriteHeader(http.StatusOK)
+		}
+		if last == nil || info.Status != last.Status ||
+			info.Progress != last.Progress {
+			if err := writePendingOperationEvent(w, "progress", info); err != nil {
+				return
+			}
+		}
+		last = info
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(pendingOperationEventInterval):
+		}
+	}
+}
+
+func (a *App) OperationCancel(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	err := a.opcanceler.Cancel(id)
+	if err == ErrNotCancelable && !a.optracker.Tracked()[id] {
+		utils.HttpError(w, fmt.Sprintf("Operation %v not found", id),
+			http.StatusNotFound)
+		return
+	} else if err == ErrNotCancelable {
+		// the operation is running past its build phase
+		utils.HttpError(w, fmt.Sprintf("Operation %v can not be canceled", id),
+			http.StatusConflict)
+		return
+	} else if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Canceled operation %v", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (a *App) PendingOperationCleanUp(w http.ResponseWriter, r *http.Request) {
 
 	// Unmarshal JSON
 	var msg api.PendingOperationsCleanRequest
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 	err = msg.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
 	}
@@ -185,3 +353,120 @@ func (a *App) PendingOperationCleanUp(w http.ResponseWriter, r *http.Request) {
 		return "", nil
 	})
 }
+
+// PendingOperationDeleteBefore deletes the records of failed and/or
+// stale pending operations older than a cutoff time without cleaning
+// up the operations. Operations that have not failed may still be
+// running and are never deleted.
+func (a *App) PendingOperationDeleteBefore(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	statuses := map[OperationStatus]bool{}
+	switch status := q.Get("status"); status {
+	case string(FailedOperation):
+		statuses[FailedOperation] = true
+	case string(StaleOperation):
+		statuses[StaleOperation] = true
+	case "all":
+		statuses[FailedOperation] = true
+		statuses[StaleOperation] = true
+	case "completed":
+		// completed operations are removed from the db when they finish
+		utils.HttpError(w, "completed operations are not kept in the db",
+			http.StatusBadRequest)
+		return
+	default:
+		utils.HttpError(w, "invalid status: "+status+
+			" (expected failed, stale or all)", http.StatusBadRequest)
+		return
+	}
+
+	before, err := time.Parse(time.RFC3339, q.Get("before"))
+	if err != nil {
+		utils.HttpError(w, "invalid before timestamp: "+err.Error(),
+			http.StatusBadRequest)
+		return
+	}
+
+	batchSize := DEFAULT_OP_CLEANUP_BATCH_SIZE
+	if b := q.Get("batch_size"); b != "" {
+		v, err := strconv.Atoi(b)
+		if err != nil || v < 1 {
+			utils.HttpError(w, "invalid batch_size: "+b, http.StatusBadRequest)
+			return
+		}
+		batchSize = v
+	}
+
+	// operations tracked by the server are being cleaned up right now
+	tracked := a.optracker.Tracked()
+	deleted, err := DeletePendingOperationsBefore(a.db, statuses,
+		before.Unix(), batchSize, func(id string) bool { return tracked[id] })
+	if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Deleted %v pending operations from before %v",
+		deleted, before.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	resp := &api.PendingOperationsDeleteResponse{DeletedCount: deleted}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+// VolumeOperations lists the operations run on a volume: the ones in
+// flight or failed and the history of the ones that finished. The
+// history is kept once the volume is deleted.
+func (a *App) VolumeOperations(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	cursor := r.URL.Query().Get("cursor")
+	limit := DEFAULT_OP_PAGE_LIMIT
+	if l := r.URL.Query().Get("limit"); l != "" {
+		v, err := strconv.Atoi(l)
+		if err != nil || v < 1 {
+			utils.HttpError(w, "invalid limit: "+l, http.StatusBadRequest)
+			return
+		}
+		limit = v
+	}
+
+	resp := &api.VolumeOperationsResponse{
+		Operations: []api.PendingOperationDetails{},
+	}
+	err := a.db.View(func(tx *bolt.Tx) error {
+		pops, next, err := OperationsForIdPage(tx, id, cursor, limit)
+		if err != nil {
+			return err
+		}
+		// the history of a deleted volume is still served
+		if len(pops) == 0 && cursor == "" {
+			if _, err := NewVolumeEntryFromId(tx, id); err != nil {
+				return err
+			}
+		}
+		for _, pop := range pops {
+			resp.Operations = append(resp.Operations, *pop.ToDetails())
+		}
+		resp.NextCursor = next
+		return nil
+	})
+	if err == ErrNotFound {
+		utils.HttpError(w, fmt.Sprintf("Id not found: %v", id), http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Write msg
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
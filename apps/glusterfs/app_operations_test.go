@@ -0,0 +1,845 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+	"github.com/urfave/negroni"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/middleware"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/idgen"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+func TestVolumeOperationsHistory(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		4,    // devices_per_node,
+		6*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 10
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+
+	vol := NewVolumeEntryFromRequest(req)
+	vc := NewVolumeCreateOperation(vol, app.db)
+	err = RunOperation(vc, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	other := NewVolumeEntryFromRequest(req)
+	vc = NewVolumeCreateOperation(other, app.db)
+	err = RunOperation(vc, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// leave five operations pending on the same volume, only one
+	// expansion of a volume can be in progress at a time
+	opIds := map[string]bool{}
+	ve := NewVolumeExpandOperation(vol, app.db, 1)
+	err = ve.Build(context.Background())
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	opIds[ve.Id()] = true
+	for i := 0; i < 4; i++ {
+		ao := NewVolumeAclConfigOperation(vol, app.db, api.VolumeACLConfig{})
+		err = ao.Build(context.Background())
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		opIds[ao.Id()] = true
+	}
+	// and one on a different volume
+	ve = NewVolumeExpandOperation(other, app.db, 1)
+	err = ve.Build(context.Background())
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	r, err := http.Get(ts.URL + "/volumes/" + vol.Info.Id + "/operations")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusOK,
+		"expected r.StatusCode == http.StatusOK, got:", r.StatusCode)
+	var resp api.VolumeOperationsResponse
+	err = utils.GetJsonFromResponse(r, &resp)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	// the pending operations and the create that completed
+	tests.Assert(t, len(resp.Operations) == 6,
+		"expected len(resp.Operations) == 6, got:", len(resp.Operations))
+	tests.Assert(t, resp.NextCursor == "",
+		"expected resp.NextCursor == \"\", got:", resp.NextCursor)
+	for _, op := range resp.Operations {
+		if op.TypeName == "create-volume" {
+			tests.Assert(t, op.Status == "completed",
+				"expected op.Status == completed, got:", op.Status)
+			continue
+		}
+		tests.Assert(t, opIds[op.Id], "unexpected operation", op.Id)
+		tests.Assert(t, op.TypeName == "expand-volume" ||
+			op.TypeName == "volume-acl-config",
+			"unexpected op.TypeName:", op.TypeName)
+	}
+
+	// page through the same history two at a time
+	seen := map[string]bool{}
+	cursor := ""
+	pages := 0
+	for {
+		u := ts.URL + "/volumes/" + vol.Info.Id + "/operations?limit=2"
+		if cursor != "" {
+			u += "&cursor=" + cursor
+		}
+		r, err := http.Get(u)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, r.StatusCode == http.StatusOK,
+			"expected r.StatusCode == http.StatusOK, got:", r.StatusCode)
+		var page api.VolumeOperationsResponse
+		err = utils.GetJsonFromResponse(r, &page)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(page.Operations) <= 2,
+			"expected len(page.Operations) <= 2, got:", len(page.Operations))
+		for _, op := range page.Operations {
+			tests.Assert(t, !seen[op.Id], "operation seen twice", op.Id)
+			seen[op.Id] = true
+		}
+		pages++
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	tests.Assert(t, pages == 3, "expected pages == 3, got:", pages)
+	tests.Assert(t, len(seen) == 6, "expected len(seen) == 6, got:", len(seen))
+
+	// bad limit
+	r, err = http.Get(ts.URL + "/volumes/" + vol.Info.Id + "/operations?limit=x")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest,
+		"expected r.StatusCode == http.StatusBadRequest, got:", r.StatusCode)
+
+	// unknown volume
+	r, err = http.Get(ts.URL + "/volumes/0123456789abcdef/operations")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound,
+		"expected r.StatusCode == http.StatusNotFound, got:", r.StatusCode)
+}
+
+func TestVolumeOperationsHistoryFinished(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		4,    // devices_per_node,
+		6*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 10
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+
+	vol := NewVolumeEntryFromRequest(req)
+	err = RunOperation(NewVolumeCreateOperation(vol, app.db), app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = RunOperation(NewVolumeExpandOperation(vol, app.db, 10), app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// a failed expansion is rolled back
+	expand := app.xo.MockVolumeExpand
+	app.xo.MockVolumeExpand = func(host string,
+		volume *executors.VolumeRequest) (*executors.Volume, error) {
+		return nil, ErrNoSpace
+	}
+	err = RunOperation(NewVolumeExpandOperation(vol, app.db, 10), app.executor)
+	tests.Assert(t, err != nil, "expected err != nil")
+	app.xo.MockVolumeExpand = expand
+
+	err = RunOperation(
+		NewVolumeAclConfigOperation(vol, app.db, api.VolumeACLConfig{}),
+		app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = RunOperation(NewVolumeDeleteOperation(vol, app.db), app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// no pending operations are left, the history outlives the volume
+	tests.Assert(t, !HasPendingOperations(app.db),
+		"expected no pending operations")
+	r, err := http.Get(ts.URL + "/volumes/" + vol.Info.Id + "/operations")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusOK,
+		"expected r.StatusCode == http.StatusOK, got:", r.StatusCode)
+	var resp api.VolumeOperationsResponse
+	err = utils.GetJsonFromResponse(r, &resp)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(resp.Operations) == 5,
+		"expected len(resp.Operations) == 5, got:", len(resp.Operations))
+
+	statuses := map[string]int{}
+	types := map[string]int{}
+	for _, op := range resp.Operations {
+		statuses[op.Status]++
+		types[op.TypeName]++
+	}
+	tests.Assert(t, statuses["completed"] == 4,
+		"expected 4 completed operations, got:", statuses)
+	tests.Assert(t, statuses["rolled back"] == 1,
+		"expected 1 rolled back operation, got:", statuses)
+	tests.Assert(t, types["create-volume"] == 1 &&
+		types["expand-volume"] == 2 &&
+		types["volume-acl-config"] == 1 &&
+		types["delete-volume"] == 1,
+		"unexpected operation types:", types)
+}
+
+func totalDeviceFree(t *testing.T, app *App) uint64 {
+	var free uint64
+	err := app.db.View(func(tx *bolt.Tx) error {
+		dl, err := DeviceList(tx)
+		if err != nil {
+			return err
+		}
+		for _, id := range dl {
+			d, err := NewDeviceEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			free += d.Info.Storage.Free
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	return free
+}
+
+func TestOperationCancelAfterBuild(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		4,    // devices_per_node,
+		6*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	freeBefore := totalDeviceFree(t, app)
+
+	// count every command sent to the storage nodes
+	cmds := 0
+	app.xo.MockBrickCreate = func(host string,
+		brick *executors.BrickRequest) (*executors.BrickInfo, error) {
+		cmds++
+		return &executors.BrickInfo{}, nil
+	}
+	app.xo.MockBrickDestroy = func(host string,
+		brick *executors.BrickRequest) (bool, error) {
+		cmds++
+		return true, nil
+	}
+	app.xo.MockVolumeCreate = func(host string,
+		volume *executors.VolumeRequest) (*executors.Volume, error) {
+		cmds++
+		return &executors.Volume{}, nil
+	}
+	app.xo.MockVolumeDestroy = func(host string, volume string) error {
+		cmds++
+		return nil
+	}
+
+	// pause the async operation between build and exec
+	built := make(chan string)
+	barrier := make(chan bool)
+	defer func(f func(o Operation)) { operationBuilt = f }(operationBuilt)
+	operationBuilt = func(o Operation) {
+		built <- o.Id()
+		<-barrier
+	}
+
+	request := []byte(`{
+		"size" : 100,
+		"durability": {"type": "replicate", "replicate": {"replica": 3}}
+	}`)
+	r, err := http.Post(ts.URL+"/volumes", "application/json",
+		bytes.NewBuffer(request))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusAccepted,
+		"expected r.StatusCode == http.StatusAccepted, got:", r.StatusCode)
+	location, err := r.Location()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	opId := <-built
+
+	// the build phase allocated bricks in the db
+	app.db.View(func(tx *bolt.Tx) error {
+		l, err := PendingOperationList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(l) == 1, "expected len(l) == 1, got:", len(l))
+		bl, err := BrickList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(bl) == 3, "expected len(bl) == 3, got:", len(bl))
+		return nil
+	})
+
+	req, err := http.NewRequest("DELETE", ts.URL+"/operations/"+opId, nil)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	r, err = http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusNoContent,
+		"expected r.StatusCode == http.StatusNoContent, got:", r.StatusCode)
+
+	// a second cancel is rejected
+	r, err = http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusConflict,
+		"expected r.StatusCode == http.StatusConflict, got:", r.StatusCode)
+
+	close(barrier)
+	for {
+		r, err = http.Get(location.String())
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		if r.Header.Get("X-Pending") == "true" {
+			time.Sleep(time.Millisecond * 10)
+			continue
+		}
+		tests.Assert(t, r.StatusCode == http.StatusInternalServerError,
+			"expected r.StatusCode == http.StatusInternalServerError, got:",
+			r.StatusCode)
+		break
+	}
+
+	tests.Assert(t, cmds == 0, "expected cmds == 0, got:", cmds)
+
+	// the operation is done and no longer known
+	r, err = http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound,
+		"expected r.StatusCode == http.StatusNotFound, got:", r.StatusCode)
+	req, err = http.NewRequest("DELETE", ts.URL+"/operations/"+idgen.GenUUID(), nil)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	r, err = http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound,
+		"expected r.StatusCode == http.StatusNotFound, got:", r.StatusCode)
+
+	app.db.View(func(tx *bolt.Tx) error {
+		l, err := PendingOperationList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(l) == 0, "expected len(l) == 0, got:", len(l))
+		bl, err := BrickList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(bl) == 0, "expected len(bl) == 0, got:", len(bl))
+		vl, err := VolumeList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(vl) == 0, "expected len(vl) == 0, got:", len(vl))
+		return nil
+	})
+	freeAfter := totalDeviceFree(t, app)
+	tests.Assert(t, freeBefore == freeAfter,
+		"expected freeBefore == freeAfter, got:", freeBefore, freeAfter)
+}
+
+func TestPendingOperationDeleteBefore(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	cutoff := time.Now()
+	old := cutoff.Add(-time.Hour).Unix()
+	seed := func(n int, status OperationStatus, stamp int64) []string {
+		ids := []string{}
+		err := app.db.Update(func(tx *bolt.Tx) error {
+			for i := 0; i < n; i++ {
+				p := NewPendingOperationEntry(NEW_ID)
+				p.Type = OperationCreateVolume
+				p.Status = status
+				p.Timestamp = stamp
+				if err := p.Save(tx); err != nil {
+					return err
+				}
+				ids = append(ids, p.Id)
+			}
+			return nil
+		})
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		return ids
+	}
+	seed(100, FailedOperation, old)
+	running := seed(3, NewOperation, old)
+	recent := seed(2, FailedOperation, cutoff.Add(time.Hour).Unix())
+	stale := seed(4, StaleOperation, old)
+
+	c := client.NewClientNoAuth(ts.URL)
+
+	// completed operations are never kept
+	_, err := c.PendingOperationDeleteBefore("completed", cutoff, 0)
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	// scan in batches not aligned to the number of operations
+	resp, err := c.PendingOperationDeleteBefore("failed", cutoff, 7)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, resp.DeletedCount == 100,
+		"expected resp.DeletedCount == 100, got:", resp.DeletedCount)
+
+	remaining := func() map[string]bool {
+		m := map[string]bool{}
+		app.db.View(func(tx *bolt.Tx) error {
+			l, err := PendingOperationList(tx)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+			for _, id := range l {
+				m[id] = true
+			}
+			return nil
+		})
+		return m
+	}
+	m := remaining()
+	tests.Assert(t, len(m) == 9, "expected len(m) == 9, got:", len(m))
+	for _, id := range append(append(running, recent...), stale...) {
+		tests.Assert(t, m[id], "expected operation to be kept:", id)
+	}
+
+	resp, err = c.PendingOperationDeleteBefore("all", cutoff, 0)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, resp.DeletedCount == 4,
+		"expected resp.DeletedCount == 4, got:", resp.DeletedCount)
+	m = remaining()
+	tests.Assert(t, len(m) == 5, "expected len(m) == 5, got:", len(m))
+	for _, id := range running {
+		tests.Assert(t, m[id], "expected running operation to be kept:", id)
+	}
+}
+
+func TestPendingOperationDeleteBeforeKeepsMarked(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		4,    // devices_per_node,
+		6*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	cutoff := time.Now()
+	defer tests.Patch(&operationTimestamp,
+		func() int64 { return cutoff.Add(-time.Hour).Unix() }).Restore()
+
+	// a create whose rollback failed leaves the volume and its
+	// bricks marked pending by the failed operation
+	req := &api.VolumeCreateRequest{}
+	req.Size = 1024
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+	vc := NewVolumeCreateOperation(NewVolumeEntryFromRequest(req), app.db)
+	err = vc.Build(context.Background())
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	markFailedIfSupported(vc)
+	marked := vc.op.Id
+
+	// a failed operation that no longer marks any entry pending
+	var unmarked string
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		p := NewPendingOperationEntry(NEW_ID)
+		p.Type = OperationCreateVolume
+		p.Status = FailedOperation
+		p.Timestamp = cutoff.Add(-time.Hour).Unix()
+		v := NewVolumeEntry()
+		v.Info.Id = idgen.GenUUID()
+		p.RecordAddVolume(v)
+		unmarked = p.Id
+		return p.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+	resp, err := c.PendingOperationDeleteBefore("failed", cutoff, 0)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, resp.DeletedCount == 1,
+		"expected resp.DeletedCount == 1, got:", resp.DeletedCount)
+
+	app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewPendingOperationEntryFromId(tx, marked)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		_, err = NewPendingOperationEntryFromId(tx, unmarked)
+		tests.Assert(t, err == ErrNotFound,
+			"expected err == ErrNotFound, got:", err)
+		return nil
+	})
+
+	check, err := dbCheckConsistency(app.db)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, check.TotalInconsistencies == 0,
+		"expected check.TotalInconsistencies == 0, got:",
+		check.TotalInconsistencies, check)
+}
+
+func TestPendingOperationTags(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		4,    // devices_per_node,
+		6*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// other operations with and without tags
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		p := NewPendingOperationEntry(NEW_ID)
+		p.Type = OperationDeleteVolume
+		p.Tags = map[string]string{"env": "test"}
+		if err := p.Save(tx); err != nil {
+			return err
+		}
+		p = NewPendingOperationEntry(NEW_ID)
+		p.Type = OperationDeleteVolume
+		return p.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// pause the async operation between build and exec
+	built := make(chan string)
+	barrier := make(chan bool)
+	defer func(f func(o Operation)) { operationBuilt = f }(operationBuilt)
+	operationBuilt = func(o Operation) {
+		built <- o.Id()
+		<-barrier
+	}
+
+	opts := client.DefaultClientOptions()
+	opts.OperationTags = map[string]string{"env": "prod", "team": "storage"}
+	tc := client.NewClientWithOptions(ts.URL, "", "", opts)
+	done := make(chan error)
+	go func() {
+		req := &api.VolumeCreateRequest{}
+		req.Size = 100
+		req.Durability.Type = api.DurabilityReplicate
+		req.Durability.Replicate.Replica = 3
+		_, err := tc.VolumeCreate(req)
+		done <- err
+	}()
+	opId := <-built
+
+	c := client.NewClientNoAuth(ts.URL)
+	details, err := c.PendingOperationDetails(opId)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, details.Tags["env"] == "prod" && details.Tags["team"] == "storage",
+		"expected tags to be set, got:", details.Tags)
+
+	l, err := c.PendingOperationList()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(l.PendingOperations) == 3,
+		"expected len(l.PendingOperations) == 3, got:", len(l.PendingOperations))
+
+	l, err = c.PendingOperationListByTags(map[string]string{"env": "prod"})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(l.PendingOperations) == 1,
+		"expected len(l.PendingOperations) == 1, got:", len(l.PendingOperations))
+	tests.Assert(t, l.PendingOperations[0].Id == opId,
+		"expected tagged operation, got:", l.PendingOperations[0])
+
+	l, err = c.PendingOperationListByTags(
+		map[string]string{"env": "prod", "team": "other"})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(l.PendingOperations) == 0,
+		"expected len(l.PendingOperations) == 0, got:", len(l.PendingOperations))
+
+	close(barrier)
+	err = <-done
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestPendingOperationArchive(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	now := time.Now()
+	old := now.Add(-40 * 24 * time.Hour).Unix()
+	seed := func(n int, status OperationStatus, stamp int64) []string {
+		ids := []string{}
+		err := app.db.Update(func(tx *bolt.Tx) error {
+			for i := 0; i < n; i++ {
+				p := NewPendingOperationEntry(NEW_ID)
+				p.Type = OperationCreateVolume
+				p.Status = status
+				p.Timestamp = stamp
+				if err := p.Save(tx); err != nil {
+					return err
+				}
+				ids = append(ids, p.Id)
+			}
+			return nil
+		})
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		return ids
+	}
+	archive := append(seed(45, FailedOperation, old), seed(5, StaleOperation, old)...)
+	running := seed(2, NewOperation, old)
+	recent := seed(3, FailedOperation, now.Unix())
+
+	app.conf.ArchiveAfterDays = 30
+	n, err := app.BackgroundArchiver().Archive()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, n == 50, "expected n == 50, got:", n)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		l, err := PendingOperationList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(l) == 5, "expected len(l) == 5, got:", len(l))
+		for _, id := range append(running, recent...) {
+			_, err := NewPendingOperationEntryFromId(tx, id)
+			tests.Assert(t, err == nil, "expected operation to be kept:", id)
+		}
+		l, err = ArchivedPendingOperationList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(l) == 50, "expected len(l) == 50, got:", len(l))
+		for _, id := range archive {
+			_, err := NewPendingOperationEntryFromId(tx, id)
+			tests.Assert(t, err == ErrNotFound,
+				"expected err == ErrNotFound, got:", err)
+			pop, err := NewArchivedPendingOperationEntryFromId(tx, id)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+			tests.Assert(t, pop.Type == OperationCreateVolume,
+				"expected create volume operation, got:", pop.Type)
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// nothing left to archive
+	n, err = app.BackgroundArchiver().Archive()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, n == 0, "expected n == 0, got:", n)
+
+	c := client.NewClientNoAuth(ts.URL)
+	d, err := c.PendingOperationDetails(archive[0])
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, d.Status == string(FailedOperation),
+		"expected failed status, got:", d.Status)
+	tests.Assert(t, d.SubStatus == "archived",
+		"expected archived sub status, got:", d.SubStatus)
+
+	l, err := c.PendingOperationList()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(l.PendingOperations) == 5,
+		"expected 5 operations, got:", len(l.PendingOperations))
+
+	l, err = c.PendingOperationListArchived()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(l.PendingOperations) == 55,
+		"expected 55 operations, got:", len(l.PendingOperations))
+	archived := 0
+	for _, info := range l.PendingOperations {
+		if info.SubStatus == "archived" {
+			archived++
+		}
+	}
+	tests.Assert(t, archived == 50, "expected 50 archived, got:", archived)
+}
+
+// readPendingOperationEvent reads the next server-sent event of an
+// operation event stream.
+func readPendingOperationEvent(t *testing.T,
+	r *bufio.Reader) (string, *api.PendingOperationDetails) {
+
+	var event string
+	info := &api.PendingOperationDetails{}
+	for {
+		line, err := r.ReadString('\n')
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return event, info
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), info)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		}
+	}
+}
+
+func TestPendingOperationEvents(t *testing.T) {
+	testPendingOperationEvents(t, func(h http.Handler) http.Handler {
+		return h
+	})
+}
+
+func TestPendingOperationEventsEnvelope(t *testing.T) {
+	// the event stream must not be buffered by the response envelope
+	testPendingOperationEvents(t, func(h http.Handler) http.Handler {
+		n := negroni.New(middleware.NewEnvelope())
+		n.UseHandler(h)
+		return n
+	})
+}
+
+func testPendingOperationEvents(t *testing.T,
+	wrap func(http.Handler) http.Handler) {
+
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+	ts := httptest.NewServer(wrap(router))
+	defer ts.Close()
+
+	defer func(i time.Duration) { pendingOperationEventInterval = i }(pendingOperationEventInterval)
+	pendingOperationEventInterval = time.Millisecond
+
+	r, err := http.Get(ts.URL + "/operations/pending/" + idgen.GenUUID() + "/events")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	r.Body.Close()
+	tests.Assert(t, r.StatusCode == http.StatusNotFound,
+		"expected http.StatusNotFound, got:", r.StatusCode)
+
+	pop := NewPendingOperationEntry(NEW_ID)
+	pop.Type = OperationExpandVolume
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		return pop.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	setProgress := func(p int) {
+		err := app.db.Update(func(tx *bolt.Tx) error {
+			pop.Progress = p
+			return pop.Save(tx)
+		})
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	}
+
+	r, err = http.Get(ts.URL + "/operations/pending/" + pop.Id + "/events")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	defer r.Body.Close()
+	tests.Assert(t, r.StatusCode == http.StatusOK,
+		"expected http.StatusOK, got:", r.StatusCode)
+	tests.Assert(t, r.Header.Get("Content-Type") == "text/event-stream",
+		"expected text/event-stream, got:", r.Header.Get("Content-Type"))
+	events := bufio.NewReader(r.Body)
+
+	event, info := readPendingOperationEvent(t, events)
+	tests.Assert(t, event == "progress", "expected progress, got:", event)
+	tests.Assert(t, info.Id == pop.Id, "expected", pop.Id, "got:", info.Id)
+	tests.Assert(t, info.Progress == 0, "expected 0, got:", info.Progress)
+
+	for _, p := range []int{40, 100} {
+		setProgress(p)
+		event, info = readPendingOperationEvent(t, events)
+		tests.Assert(t, event == "progress", "expected progress, got:", event)
+		tests.Assert(t, info.Progress == p, "expected", p, "got:", info.Progress)
+	}
+
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		return pop.Delete(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	event, info = readPendingOperationEvent(t, events)
+	tests.Assert(t, event == "done", "expected done, got:", event)
+	tests.Assert(t, info.Progress == 100, "expected 100, got:", info.Progress)
+	_, err = events.ReadString('\n')
+	tests.Assert(t, err == io.EOF, "expected io.EOF, got:", err)
+}
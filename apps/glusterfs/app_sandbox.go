@@ -0,0 +1,107 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/boltdb/bolt"
+
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+// SandboxOperation simulates an operation against a copy of the db
+// and returns what the operation would change.
+func (a *App) SandboxOperation(w http.ResponseWriter, r *http.Request) {
+	var msg api.SandboxOperationRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return
+	}
+
+	var build sandboxBuildFunc
+	switch msg.OperationType {
+	case api.SandboxCreateVolume:
+		var req api.VolumeCreateRequest
+		if err := json.Unmarshal(msg.Params, &req); err != nil {
+			utils.HttpError(w, "params unable to be parsed", 422)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Size < 1 {
+			utils.HttpError(w, "Invalid volume size", http.StatusBadRequest)
+			return
+		}
+		if req.Durability.Type == "" {
+			req.Durability.Type = api.DurabilityDistributeOnly
+		}
+		build = func(db wdb.DB) (Operation, error) {
+			return NewVolumeCreateOperation(NewVolumeEntryFromRequest(&req), db), nil
+		}
+	case api.SandboxExpandVolume, api.SandboxDeleteVolume:
+		var params api.SandboxVolumeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			utils.HttpError(w, "params unable to be parsed", 422)
+			return
+		}
+		if err := params.Validate(); err != nil {
+			utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		expand := msg.OperationType == api.SandboxExpandVolume
+		if expand && params.ExpandSize < 1 {
+			utils.HttpError(w, "Invalid volume size", http.StatusBadRequest)
+			return
+		}
+		build = func(db wdb.DB) (Operation, error) {
+			var vol *VolumeEntry
+			err := db.View(func(tx *bolt.Tx) error {
+				var err error
+				vol, err = NewVolumeEntryFromId(tx, params.Id)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			if expand {
+				return NewVolumeExpandOperation(vol, db, params.ExpandSize), nil
+			}
+			return NewVolumeDeleteOperation(vol, db), nil
+		}
+	}
+
+	resp, err := RunSandboxOperation(r.Context(), a.db, msg.OperationType, build)
+	if err == ErrNotFound {
+		utils.HttpError(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,200 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+// sshKeyRequest reads and validates the body of an ssh key create
+// or update request. If the request is not valid an error is sent to
+// the client and false is returned.
+func (a *App) sshKeyRequest(w http.ResponseWriter, r *http.Request) (
+	*api.SshKeyRequest, bool) {
+
+	if a.sshKeyEncKey == nil {
+		utils.HttpError(w, ErrSshKeyStoreDisabled.Error(),
+			http.StatusServiceUnavailable)
+		return nil, false
+	}
+
+	var msg api.SshKeyRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return nil, false
+	}
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return nil, false
+	}
+	return &msg, true
+}
+
+func (a *App) SshKeyCreate(w http.ResponseWriter, r *http.Request) {
+	msg, ok := a.sshKeyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := NewSshKeyEntryFromRequest(msg, a.sshKeyEncKey)
+	if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = a.db.Update(func(tx *bolt.Tx) error {
+		return entry.Save(tx)
+	})
+	if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.sshKeysChanged()
+	logger.Info("Added ssh key %v for nodes matching %v",
+		entry.Info.Id, entry.Info.NodePattern)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(entry.Info); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) SshKeyList(w http.ResponseWriter, r *http.Request) {
+	list := api.SshKeyListResponse{SshKeys: []api.SshKeyInfo{}}
+	err := a.db.View(func(tx *bolt.Tx) error {
+		entries, err := SshKeyEntries(tx)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			list.SshKeys = append(list.SshKeys, entry.Info)
+		}
+		return nil
+	})
+	if err != nil {
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) SshKeyInfo(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var info api.SshKeyInfo
+	err := a.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewSshKeyEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpError(w, "Id not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		info = entry.Info
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) SshKeyUpdate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	msg, ok := a.sshKeyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var info api.SshKeyInfo
+	err := a.db.Update(func(tx *bolt.Tx) error {
+		entry, err := NewSshKeyEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpError(w, "Id not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		if err := entry.Update(msg, a.sshKeyEncKey); err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		if err := entry.Save(tx); err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		info = entry.Info
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	a.sshKeysChanged()
+	logger.Info("Updated ssh key %v", id)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) SshKeyDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	err := a.db.Update(func(tx *bolt.Tx) error {
+		entry, err := NewSshKeyEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpError(w, "Id not found", http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		if err := entry.Delete(tx); err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	a.sshKeysChanged()
+	logger.Info("Deleted ssh key %v", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
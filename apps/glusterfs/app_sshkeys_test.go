@@ -0,0 +1,288 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	"github.com/heketi/heketi/executors/sshexec"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+func postSshKey(t *testing.T, url string, req *api.SshKeyRequest) *api.SshKeyInfo {
+	body, err := json.Marshal(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	r, err := http.Post(url+"/admin/ssh-keys",
+		"application/json", bytes.NewBuffer(body))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusCreated,
+		"expected r.StatusCode == http.StatusCreated, got:", r.StatusCode)
+	var info api.SshKeyInfo
+	err = utils.GetJsonFromResponse(r, &info)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	return &info
+}
+
+type countingKeyCache int
+
+func (c *countingKeyCache) InvalidateSshKeys() {
+	*c++
+}
+
+func TestSshKeyCrud(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	app.sshKeyEncKey = sshKeyEncryptionKey("sekret")
+	cache := new(countingKeyCache)
+	app.sshKeyCache = cache
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	k1 := postSshKey(t, ts.URL, &api.SshKeyRequest{
+		NodePattern: "storage-1.*",
+		PrivateKey:  "KEY-ONE",
+	})
+	k2 := postSshKey(t, ts.URL, &api.SshKeyRequest{
+		NodePattern: "storage-*",
+		PrivateKey:  "KEY-TWO",
+		Passphrase:  "pass",
+	})
+	tests.Assert(t, k1.NodePattern == "storage-1.*",
+		"expected k1.NodePattern == storage-1.*, got:", k1.NodePattern)
+
+	// the key material is encrypted in the db
+	err := app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewSshKeyEntryFromId(tx, k2.Id)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, !bytes.Contains(entry.Secret, []byte("KEY-TWO")),
+			"expected key to be encrypted")
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// list is in priority order and never contains the keys
+	r, err := http.Get(ts.URL + "/admin/ssh-keys")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusOK,
+		"expected r.StatusCode == http.StatusOK, got:", r.StatusCode)
+	var list api.SshKeyListResponse
+	err = utils.GetJsonFromResponse(r, &list)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(list.SshKeys) == 2,
+		"expected len(list.SshKeys) == 2, got:", len(list.SshKeys))
+	tests.Assert(t, list.SshKeys[0].Id == k1.Id,
+		"expected list.SshKeys[0].Id == k1.Id, got:", list.SshKeys[0].Id)
+	tests.Assert(t, list.SshKeys[1].Id == k2.Id,
+		"expected list.SshKeys[1].Id == k2.Id, got:", list.SshKeys[1].Id)
+
+	// update the pattern and key of the second entry
+	body, err := json.Marshal(&api.SshKeyRequest{
+		NodePattern: "backup-*",
+		PrivateKey:  "KEY-THREE",
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	req, err := http.NewRequest("PUT", ts.URL+"/admin/ssh-keys/"+k2.Id,
+		bytes.NewBuffer(body))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	req.Header.Set("Content-Type", "application/json")
+	r, err = http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusOK,
+		"expected r.StatusCode == http.StatusOK, got:", r.StatusCode)
+
+	r, err = http.Get(ts.URL + "/admin/ssh-keys/" + k2.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusOK,
+		"expected r.StatusCode == http.StatusOK, got:", r.StatusCode)
+	var info api.SshKeyInfo
+	err = utils.GetJsonFromResponse(r, &info)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.NodePattern == "backup-*",
+		"expected info.NodePattern == backup-*, got:", info.NodePattern)
+
+	keys, err := (&sshKeyStore{app: app}).SshKeys()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(keys) == 2, "expected len(keys) == 2, got:", len(keys))
+	tests.Assert(t, keys[1].PrivateKey == "KEY-THREE",
+		"expected keys[1].PrivateKey == KEY-THREE, got:", keys[1].PrivateKey)
+	tests.Assert(t, keys[1].Passphrase == "",
+		"expected keys[1].Passphrase to be empty, got:", keys[1].Passphrase)
+
+	// delete the first key
+	req, err = http.NewRequest("DELETE", ts.URL+"/admin/ssh-keys/"+k1.Id, nil)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	r, err = http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusNoContent,
+		"expected r.StatusCode == http.StatusNoContent, got:", r.StatusCode)
+
+	r, err = http.Get(ts.URL + "/admin/ssh-keys/" + k1.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound,
+		"expected r.StatusCode == http.StatusNotFound, got:", r.StatusCode)
+
+	// each change dropped the keys cached by the executor
+	tests.Assert(t, *cache == 4, "expected 4 invalidations, got:", *cache)
+
+	// invalid patterns are rejected
+	r, err = http.Post(ts.URL+"/admin/ssh-keys", "application/json",
+		strings.NewReader(`{"node_pattern": "[", "private_key": "x"}`))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest,
+		"expected r.StatusCode == http.StatusBadRequest, got:", r.StatusCode)
+}
+
+func TestSshKeyStoreDisabled(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	r, err := http.Post(ts.URL+"/admin/ssh-keys", "application/json",
+		strings.NewReader(`{"node_pattern": "*", "private_key": "x"}`))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusServiceUnavailable,
+		"expected r.StatusCode == http.StatusServiceUnavailable, got:",
+		r.StatusCode)
+}
+
+func TestSshKeyStoreSelection(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	app.sshKeyEncKey = sshKeyEncryptionKey("sekret")
+
+	reqs := []api.SshKeyRequest{
+		{NodePattern: "node1.*", PrivateKey: "KEY-ONE"},
+		{NodePattern: "node*", PrivateKey: "KEY-ALL"},
+		{NodePattern: "node2.*", PrivateKey: "KEY-TWO"},
+	}
+	err := app.db.Update(func(tx *bolt.Tx) error {
+		for i := range reqs {
+			entry, err := NewSshKeyEntryFromRequest(&reqs[i], app.sshKeyEncKey)
+			if err != nil {
+				return err
+			}
+			// force a stable priority order
+			entry.Created = int64(i)
+			if err := entry.Save(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	keys, err := (&sshKeyStore{app: app}).SshKeys()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(keys) == 3, "expected len(keys) == 3, got:", len(keys))
+
+	checks := map[string]string{
+		"node1.example.com": "KEY-ONE",
+		// shadowed by the earlier node* pattern
+		"node2.example.com": "KEY-ALL",
+		"node3.example.com": "KEY-ALL",
+	}
+	for host, expected := range checks {
+		k := sshexec.MatchSshKey(keys, host)
+		tests.Assert(t, k != nil, "expected key for", host)
+		tests.Assert(t, k.PrivateKey == expected,
+			"expected", expected, "for", host, "got:", k.PrivateKey)
+	}
+	k := sshexec.MatchSshKey(keys, "10.1.1.1")
+	tests.Assert(t, k == nil, "expected no key for 10.1.1.1, got:", k)
+
+	// keys can not be read with a different encryption key
+	app.sshKeyEncKey = sshKeyEncryptionKey("wrong")
+	keys, err = (&sshKeyStore{app: app}).SshKeys()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(keys) == 0, "expected len(keys) == 0, got:", len(keys))
+}
+
+func TestSshKeyDbDumpRoundTrip(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	encKey := sshKeyEncryptionKey("sekret")
+	err := app.db.Update(func(tx *bolt.Tx) error {
+		entry, err := NewSshKeyEntryFromRequest(&api.SshKeyRequest{
+			NodePattern: "node1.*",
+			PrivateKey:  "KEY-ONE",
+			Passphrase:  "pass",
+		}, encKey)
+		if err != nil {
+			return err
+		}
+		return entry.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// the dump only holds the encrypted key
+	dump, err := dbDumpInternal(app.db)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(dump.SshKeys) == 1,
+		"expected len(dump.SshKeys) == 1, got:", len(dump.SshKeys))
+	b, err := json.Marshal(dump)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, !strings.Contains(string(b), "KEY-ONE"),
+		"expected no plain text key in the dump")
+	app.Close()
+
+	tmpJson := tests.Tempfile()
+	defer os.Remove(tmpJson)
+	err = DbDump(tmpJson, tmpfile)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	newDb := tests.Tempfile()
+	defer os.Remove(newDb)
+	err = DbCreate(tmpJson, newDb)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	app2 := NewTestApp(newDb)
+	defer app2.Close()
+	app2.sshKeyEncKey = encKey
+	keys, err := (&sshKeyStore{app: app2}).SshKeys()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(keys) == 1, "expected len(keys) == 1, got:", len(keys))
+	tests.Assert(t, keys[0].NodePattern == "node1.*",
+		"expected keys[0].NodePattern == node1.*, got:", keys[0].NodePattern)
+	tests.Assert(t, keys[0].PrivateKey == "KEY-ONE",
+		"expected keys[0].PrivateKey == KEY-ONE, got:", keys[0].PrivateKey)
+	tests.Assert(t, keys[0].Passphrase == "pass",
+		"expected keys[0].Passphrase == pass, got:", keys[0].Passphrase)
+}
@@ -10,6 +10,9 @@
 package glusterfs
 
 import (
+	"sort"
+	"strconv"
+
 	"github.com/boltdb/bolt"
 	"github.com/heketi/heketi/pkg/glusterfs/api"
 )
@@ -126,3 +129,57 @@ func nodeInfo(tx *bolt.Tx, id string) (*api.NodeInfoResponse, error) {
 
 	return info, err
 }
+
+// clusterTopology returns the devices of the cluster nested by zone
+// and node. Zones are sorted by zone number.
+func clusterTopology(tx *bolt.Tx, id string) (*api.ClusterTopologyResponse, error) {
+	c, err := NewClusterEntryFromId(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	zoneNodes := map[int][]api.ClusterTopologyNode{}
+	for _, nodeId := range c.Info.Nodes {
+		n, err := NewNodeEntryFromId(tx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+		node := api.ClusterTopologyNode{
+			Id:       n.Info.Id,
+			Hostname: n.ManageHostName(),
+			State:    n.State,
+			Devices:  []api.ClusterTopologyDevice{},
+		}
+		for _, deviceId := range n.Devices {
+			d, err := NewDeviceEntryFromId(tx, deviceId)
+			if err != nil {
+				return nil, err
+			}
+			node.Devices = append(node.Devices, api.ClusterTopologyDevice{
+				Id:         d.Info.Id,
+				Path:       d.Info.Name,
+				FreeGb:     d.Info.Storage.Free / GB,
+				BrickCount: len(d.Bricks),
+			})
+		}
+		zoneNodes[n.Info.Zone] = append(zoneNodes[n.Info.Zone], node)
+	}
+
+	zones := []int{}
+	for z := range zoneNodes {
+		zones = append(zones, z)
+	}
+	sort.Ints(zones)
+
+	topo := &api.ClusterTopologyResponse{
+		ClusterId: c.Info.Id,
+		Zones:     []api.ClusterTopologyZone{},
+	}
+	for _, z := range zones {
+		topo.Zones = append(topo.Zones, api.ClusterTopologyZone{
+			Name:  strconv.Itoa(z),
+			Nodes: zoneNodes[z],
+		})
+	}
+	return topo, nil
+}
@@ -10,10 +10,12 @@
 package glusterfs
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"math"
 	"net/http"
+	"strings"
 
 	"github.com/boltdb/bolt"
 	"github.com/gorilla/mux"
@@ -31,23 +33,23 @@ func (a *App) VolumeCreate(w http.ResponseWriter, r *http.Request) {
 	var msg api.VolumeCreateRequest
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 	err = msg.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
 	}
 
 	switch {
 	case msg.Gid < 0:
-		http.Error(w, "Bad group id less than zero", http.StatusBadRequest)
+		utils.HttpError(w, "Bad group id less than zero", http.StatusBadRequest)
 		logger.LogError("Bad group id less than zero")
 		return
 	case msg.Gid >= math.MaxInt32:
-		http.Error(w, "Bad group id equal or greater than 2**32", http.StatusBadRequest)
+		utils.HttpError(w, "Bad group id equal or greater than 2**32", http.StatusBadRequest)
 		logger.LogError("Bad group id equal or greater than 2**32")
 		return
 	}
@@ -59,19 +61,19 @@ func (a *App) VolumeCreate(w http.ResponseWriter, r *http.Request) {
 	case "":
 		msg.Durability.Type = api.DurabilityDistributeOnly
 	default:
-		http.Error(w, "Unknown durability type", http.StatusBadRequest)
+		utils.HttpError(w, "Unknown durability type", http.StatusBadRequest)
 		logger.LogError("Unknown durability type")
 		return
 	}
 
 	if msg.Size < 1 {
-		http.Error(w, "Invalid volume size", http.StatusBadRequest)
+		utils.HttpError(w, "Invalid volume size", http.StatusBadRequest)
 		logger.LogError("Invalid volume size")
 		return
 	}
 	if msg.Snapshot.Enable {
 		if msg.Snapshot.Factor < 1 || msg.Snapshot.Factor > VOLUME_CREATE_MAX_SNAPSHOT_FACTOR {
-			http.Error(w, "Invalid snapshot factor", http.StatusBadRequest)
+			utils.HttpError(w, "Invalid snapshot factor", http.StatusBadRequest)
 			logger.LogError("Invalid snapshot factor")
 			return
 		}
@@ -79,7 +81,7 @@ func (a *App) VolumeCreate(w http.ResponseWriter, r *http.Request) {
 
 	if msg.Durability.Type == api.DurabilityReplicate {
 		if msg.Durability.Replicate.Replica > 3 {
-			http.Error(w, "Invalid replica value", http.StatusBadRequest)
+			utils.HttpError(w, "Invalid replica value", http.StatusBadRequest)
 			logger.LogError("Invalid replica value")
 			return
 		}
@@ -94,7 +96,7 @@ func (a *App) VolumeCreate(w http.ResponseWriter, r *http.Request) {
 		case d.Data == 8 && d.Redundancy == 3:
 		case d.Data == 8 && d.Redundancy == 4:
 		default:
-			http.Error(w,
+			utils.HttpError(w,
 				fmt.Sprintf("Invalid dispersion combination: %v+%v", d.Data, d.Redundancy),
 				http.StatusBadRequest)
 			logger.LogError(fmt.Sprintf("Invalid dispersion combination: %v+%v", d.Data, d.Redundancy))
@@ -108,11 +110,11 @@ func (a *App) VolumeCreate(w http.ResponseWriter, r *http.Request) {
 		// :TODO: All we need to do is check for one instead of gathering all keys
 		clusters, err := ClusterList(tx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 		if len(clusters) == 0 {
-			http.Error(w, fmt.Sprintf("No clusters configured"), http.StatusBadRequest)
+			utils.HttpError(w, fmt.Sprintf("No clusters configured"), http.StatusBadRequest)
 			logger.LogError("No clusters configured")
 			return ErrNotFound
 		}
@@ -121,12 +123,42 @@ func (a *App) VolumeCreate(w http.ResponseWriter, r *http.Request) {
 		for _, clusterid := range msg.Clusters {
 			_, err := NewClusterEntryFromId(tx, clusterid)
 			if err != nil {
-				http.Error(w, fmt.Sprintf("Cluster id %v not found", clusterid), http.StatusBadRequest)
+				utils.HttpError(w, fmt.Sprintf("Cluster id %v not found", clusterid), http.StatusBadRequest)
 				logger.LogError(fmt.Sprintf("Cluster id %v not found", clusterid))
 				return err
 			}
 		}
 
+		// Check that the requested tier is available
+		if msg.Tier != api.VolumeTierNone {
+			ok, err := TierAvailable(tx, msg.Clusters, msg.Tier)
+			if err != nil {
+				utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+			if !ok {
+				utils.HttpError(w, fmt.Sprintf("No devices available in tier %v",
+					msg.Tier), 422)
+				logger.LogError("No devices available in tier %v", msg.Tier)
+				return ErrNotFound
+			}
+		}
+
+		// Check that the requested trusted storage pool exists
+		if msg.TspId != "" {
+			ok, err := TspAvailable(tx, msg.Clusters, msg.TspId)
+			if err != nil {
+				utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+			if !ok {
+				utils.HttpError(w, fmt.Sprintf(
+					"No nodes in trusted storage pool %v", msg.TspId), 422)
+				logger.LogError("No nodes in trusted storage pool %v", msg.TspId)
+				return ErrNotFound
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -136,7 +168,7 @@ func (a *App) VolumeCreate(w http.ResponseWriter, r *http.Request) {
 	vol := NewVolumeEntryFromRequest(&msg)
 
 	if uint64(msg.Size)*GB < vol.Durability.MinVolumeSize() {
-		http.Error(w, fmt.Sprintf("Requested volume size (%v GB) is "+
+		utils.HttpError(w, fmt.Sprintf("Requested volume size (%v GB) is "+
 			"smaller than the minimum supported volume size (%v)",
 			msg.Size, vol.Durability.MinVolumeSize()),
 			http.StatusBadRequest)
@@ -146,11 +178,79 @@ func (a *App) VolumeCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(msg.RequiredRegions) > vol.Durability.BricksInSet() {
+		utils.HttpError(w, fmt.Sprintf("Number of required regions (%v) exceeds "+
+			"the number of bricks in a brick set (%v)",
+			len(msg.RequiredRegions), vol.Durability.BricksInSet()),
+			http.StatusBadRequest)
+		logger.LogError("Number of required regions (%v) exceeds "+
+			"the number of bricks in a brick set (%v)",
+			len(msg.RequiredRegions), vol.Durability.BricksInSet())
+		return
+	}
+
+	if len(vol.Info.PinnedNodeIds) > 0 {
+		err = a.db.View(func(tx *bolt.Tx) error {
+			clusters, err := PinnedNodesClusters(tx, vol.Info.PinnedNodeIds)
+			if err == ErrNotFound {
+				utils.HttpError(w, "Pinned node not found", http.StatusBadRequest)
+				return err
+			} else if err != nil {
+				utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+			// only consider the clusters of the pinned nodes
+			if len(vol.Info.Clusters) == 0 {
+				vol.Info.Clusters = clusters
+			} else {
+				requested := map[string]bool{}
+				for _, c := range vol.Info.Clusters {
+					requested[c] = true
+				}
+				for _, c := range clusters {
+					if !requested[c] {
+						err := fmt.Errorf("Pinned nodes are not in the requested clusters")
+						utils.HttpError(w, err.Error(), http.StatusBadRequest)
+						return err
+					}
+				}
+			}
+
+			reason, err := PinnedNodesFit(tx, vol, uint64(msg.Size)*GB)
+			if err != nil {
+				utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+			if reason != "" {
+				err := logger.LogError("Volume does not fit on pinned nodes: %v",
+					reason)
+				utils.HttpErrorCode(w, api.ErrorInsufficientSpace,
+					err.Error(), 422)
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return
+		}
+	}
+
 	vc := NewVolumeCreateOperation(vol, a.db)
 	if a.conf.RetryLimits.VolumeCreate > 0 {
 		vc.maxRetries = a.conf.RetryLimits.VolumeCreate
 	}
 	if err := AsyncHttpOperation(a, w, r, vc); err != nil {
+		if trace := vol.PlacementTrace(); err == ErrNoSpace && len(trace) > 0 {
+			utils.WriteErrorResponse(w, http.StatusInternalServerError,
+				&api.ErrorResponse{
+					Code:    api.ErrorInsufficientSpace,
+					Message: fmt.Sprintf("Failed to allocate new volume: %v", err),
+					Detail: map[string]interface{}{
+						"placement_trace": trace,
+					},
+				})
+			return
+		}
 		OperationHttpErrorf(w, err, "Failed to allocate new volume: %v", err)
 		return
 	}
@@ -174,7 +274,7 @@ func (a *App) VolumeList(w http.ResponseWriter, r *http.Request) {
 
 	if err != nil {
 		logger.Err(err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -190,41 +290,466 @@ func (a *App) VolumeInfo(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 
+	if cached, ok := a.volinfo.Get(id); ok {
+		writeVolumeInfo(w, r, cached)
+		return
+	}
+	gen := a.volinfo.Generation()
+
 	var info *api.VolumeInfoResponse
 	err := a.db.View(func(tx *bolt.Tx) error {
 		entry, err := NewVolumeEntryFromId(tx, id)
 		if err == ErrNotFound || !entry.Visible() {
 			// treat an invisible entry like it doesn't exist
-			http.Error(w, "Id not found", http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, "Id not found", http.StatusNotFound)
+			return ErrNotFound
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		info, err = entry.NewInfoResponse(tx)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		err = UpdateVolumeInfoComplete(tx, info)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	var data bytes.Buffer
+	if err := json.NewEncoder(&data).Encode(info); err != nil {
+		panic(err)
+	}
+	writeVolumeInfo(w, r, a.volinfo.Put(id, data.Bytes(), gen))
+}
+
+// writeVolumeInfo replies with the serialized volume info, or with
+// 304 if the client already has the current version.
+func writeVolumeInfo(w http.ResponseWriter, r *http.Request,
+	e *VolumeInfoCacheEntry) {
+
+	w.Header().Set("ETag", e.ETag)
+	if r.Header.Get("If-None-Match") == e.ETag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(e.Data)
+}
+
+func (a *App) VolumeMoveEstimate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	targets := []string{}
+	for _, t := range strings.Split(r.URL.Query().Get("target_device_ids"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) == 0 {
+		utils.HttpError(w, "target_device_ids must be provided", http.StatusBadRequest)
+		return
+	}
+
+	var est *api.VolumeMoveEstimateResponse
+	err := a.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound || !entry.Visible() {
+			// treat an invisible entry like it doesn't exist
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, "Id not found", http.StatusNotFound)
+			return ErrNotFound
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		est, err = EstimateVolumeMove(tx, entry, targets)
+		if err == ErrNotFound {
+			utils.HttpError(w, "Target device not found", http.StatusBadRequest)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(est); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) VolumePatch(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	ct := r.Header.Get("Content-Type")
+	if !strings.HasPrefix(ct, "application/json-patch+json") {
+		utils.HttpError(w, "Content-Type must be application/json-patch+json",
+			http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var ops []api.JsonPatchOperation
+	err := utils.GetJsonFromRequest(r, &ops)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+
+	var info *api.VolumeInfoResponse
+	err = a.db.Update(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, "Id not found", http.StatusNotFound)
 			return ErrNotFound
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		if entry.Pending.Id != "" {
+			// an in-flight operation may overwrite the changes
+			utils.WriteErrorResponse(w, http.StatusConflict, &api.ErrorResponse{
+				Code:    api.ErrorOperationInProgress,
+				Message: "Volume has a pending operation",
+				Detail:  map[string]interface{}{"operation_id": entry.Pending.Id},
+			})
+			return ErrConflict
+		}
+
+		// the entry is only saved if all operations apply
+		tier := entry.Info.Tier
+		if err := entry.ApplyPatch(ops); err != nil {
+			utils.HttpError(w, err.Error(), 422)
+			logger.LogError("Unable to patch volume %v: %v", id, err)
+			return err
+		}
+		if entry.Info.Tier != tier && entry.Info.Tier != api.VolumeTierNone {
+			ok, err := TierAvailable(tx,
+				[]string{entry.Info.Cluster}, entry.Info.Tier)
+			if err != nil {
+				utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+			if !ok {
+				utils.HttpError(w, fmt.Sprintf("No devices available in tier %v",
+					entry.Info.Tier), 422)
+				return ErrNotFound
+			}
+		}
+		if err := entry.Save(tx); err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
 		info, err = entry.NewInfoResponse(tx)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 		err = UpdateVolumeInfoComplete(tx, info)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	logger.Info("Patched volume %v", id)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) VolumePin(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
 
+	var msg api.VolumePinRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return
+	}
+
+	// another server may be running an operation on the volume
+	unlock, err := a.lockKeys("Pin Volume", volumeLockKey(id))
+	if err != nil {
+		OperationHttpErrorf(w, err, "Failed to pin volume %v: %v", id, err)
+		return
+	}
+	defer unlock()
+
+	var info *api.VolumeInfoResponse
+	err = a.db.Update(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, "Id not found", http.StatusNotFound)
+			return ErrNotFound
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		if entry.Pending.Id != "" {
+			// an in-flight operation may overwrite the changes
+			utils.WriteErrorResponse(w, http.StatusConflict, &api.ErrorResponse{
+				Code:    api.ErrorOperationInProgress,
+				Message: "Volume has a pending operation",
+				Detail:  map[string]interface{}{"operation_id": entry.Pending.Id},
+			})
+			return ErrConflict
+		}
+
+		clusters, err := PinnedNodesClusters(tx, msg.PinnedNodeIds)
+		if err == ErrNotFound {
+			utils.HttpError(w, "Pinned node not found", http.StatusBadRequest)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		for _, c := range clusters {
+			if c != entry.Info.Cluster {
+				err := fmt.Errorf("Pinned nodes must be in cluster %v",
+					entry.Info.Cluster)
+				utils.HttpError(w, err.Error(), http.StatusBadRequest)
+				return err
+			}
+		}
+
+		// existing bricks stay in place, only new bricks are pinned
+		entry.Info.PinnedNodeIds = msg.PinnedNodeIds
+		if len(entry.Info.PinnedNodeIds) == 0 {
+			entry.Info.PinnedNodeIds = nil
+		}
+		if err := entry.Save(tx); err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		info, err = entry.NewInfoResponse(tx)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		err = UpdateVolumeInfoComplete(tx, info)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
 		return nil
 	})
 	if err != nil {
 		return
 	}
+	logger.Info("Volume %v pinned to nodes %v", id, msg.PinnedNodeIds)
 
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(info); err != nil {
 		panic(err)
 	}
+}
+
+// VolumeImportLayout creates a volume out of bricks that were created
+// outside of heketi. The bricks are checked on their nodes but no lvs
+// are created.
+func (a *App) VolumeImportLayout(w http.ResponseWriter, r *http.Request) {
+	var msg api.VolumeImportLayoutRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return
+	}
 
+	op := NewVolumeImportOperation(&msg, a.db)
+	if err := AsyncHttpOperation(a, w, r, op); err != nil {
+		if _, ok := err.(*ImportLayoutError); ok {
+			utils.HttpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err == ErrNotFound {
+			utils.HttpError(w, "Cluster, node or device of the layout not found",
+				http.StatusNotFound)
+			return
+		}
+		OperationHttpErrorf(w, err, "Failed to import volume: %v", err)
+		return
+	}
+}
+
+// VolumeDetach removes a volume and its bricks from the db without
+// running any gluster or lvm commands. The gluster volume and its data
+// are left in place so the volume can be imported elsewhere. The
+// response holds the information of the volume as it was detached.
+func (a *App) VolumeDetach(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var info *api.VolumeInfoResponse
+	err := a.db.Update(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound || !entry.Visible() {
+			// treat an invisible entry like it doesn't exist
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, "Id not found", http.StatusNotFound)
+			return ErrNotFound
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		if len(entry.Info.BlockInfo.BlockVolumes) > 0 {
+			err := logger.LogError("Cannot detach a block hosting volume containing block volumes")
+			utils.HttpError(w, err.Error(), http.StatusConflict)
+			return err
+		}
+
+		bricks, err := entry.deleteVolumeComponents(db.WrapTx(tx))
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		pendingId := entry.Pending.Id
+		for _, b := range bricks {
+			if pendingId == "" {
+				pendingId = b.Pending.Id
+			}
+		}
+		if pendingId != "" {
+			utils.WriteErrorResponse(w, http.StatusConflict, &api.ErrorResponse{
+				Code:    api.ErrorOperationInProgress,
+				Message: "Volume has a pending operation",
+				Detail:  map[string]interface{}{"operation_id": pendingId},
+			})
+			return ErrConflict
+		}
+
+		info, err = entry.NewInfoResponse(tx)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		err = UpdateVolumeInfoComplete(tx, info)
+		if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		// the bricks are not destroyed, all of their space is given back
+		reclaimed := ReclaimMap{}
+		for _, b := range bricks {
+			reclaimed[b.Info.DeviceId] = true
+		}
+		if err := entry.teardown(db.WrapTx(tx), bricks, reclaimed); err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+	logger.Info("Volume %v (%v) detached", id, info.Name)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		panic(err)
+	}
+}
+
+// VolumeRepair checks the health of a volume and fixes the problems
+// it can. The response lists the actions taken and the problems left.
+func (a *App) VolumeRepair(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	err := a.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound {
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, "Id not found", http.StatusNotFound)
+			return ErrNotFound
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		if entry.Pending.Id != "" {
+			utils.WriteErrorResponse(w, http.StatusConflict, &api.ErrorResponse{
+				Code:    api.ErrorOperationInProgress,
+				Message: "Volume has a pending operation",
+				Detail:  map[string]interface{}{"operation_id": entry.Pending.Id},
+			})
+			return ErrConflict
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	op := NewVolumeRepairOperation(id, a.db)
+	if a.optracker.ThrottleOrAdd(op.Id(), TrackNormal) {
+		OperationHttpErrorf(w, ErrTooManyOperations, "")
+		return
+	}
+	defer a.optracker.Remove(op.Id())
+	if tags := operationTagsFromRequest(r); tags != nil {
+		op.SetTags(tags)
+	}
+	unlockAlloc, unlock, err := a.lockOperation(op)
+	if err != nil {
+		OperationHttpErrorf(w, err, "Failed to repair volume %v: %v", id, err)
+		return
+	}
+	defer unlock()
+	defer unlockAlloc()
+
+	// the repair runs within the request, the caller needs the result
+	if err := RunOperationContext(r.Context(), op, a.executor); err != nil {
+		OperationHttpErrorf(w, err, "Failed to repair volume %v: %v", id, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(op.Result()); err != nil {
+		panic(err)
+	}
 }
 
 func (a *App) VolumeDelete(w http.ResponseWriter, r *http.Request) {
@@ -237,16 +762,16 @@ func (a *App) VolumeDelete(w http.ResponseWriter, r *http.Request) {
 		var err error
 		volume, err = NewVolumeEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, err.Error(), http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
 		if volume.Info.Name == db.HeketiStorageVolumeName {
 			err := fmt.Errorf("Cannot delete volume containing the Heketi database")
-			http.Error(w, err.Error(), http.StatusConflict)
+			utils.HttpError(w, err.Error(), http.StatusConflict)
 			return err
 		}
 
@@ -259,13 +784,13 @@ func (a *App) VolumeDelete(w http.ResponseWriter, r *http.Request) {
 			_, err = NewBlockVolumeEntryFromId(tx, bvId)
 			if err == nil {
 				err = logger.LogError("Cannot delete a block hosting volume containing block volumes")
-				http.Error(w, err.Error(), http.StatusConflict)
+				utils.HttpError(w, err.Error(), http.StatusConflict)
 				return err
 			}
 			if err != ErrNotFound {
 				err = logger.LogError("Refusing to delete block-hosting volume: "+
 					"Error loading block-volume [%v]: %v", bvId, err)
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 				return err
 			}
 		}
@@ -292,19 +817,19 @@ func (a *App) VolumeExpand(w http.ResponseWriter, r *http.Request) {
 	var msg api.VolumeExpandRequest
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 	logger.Debug("Msg: %v", msg)
 	err = msg.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
 	}
 
 	if msg.Size < 1 {
-		http.Error(w, "Invalid volume size", http.StatusBadRequest)
+		utils.HttpError(w, "Invalid volume size", http.StatusBadRequest)
 		return
 	}
 	logger.Debug("Size: %v", msg.Size)
@@ -315,10 +840,10 @@ func (a *App) VolumeExpand(w http.ResponseWriter, r *http.Request) {
 		var err error
 		volume, err = NewVolumeEntryFromId(tx, id)
 		if err == ErrNotFound {
-			http.Error(w, err.Error(), http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, err.Error(), http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -329,7 +854,20 @@ func (a *App) VolumeExpand(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := a.runPreExpandHook(volume, msg.Size); err != nil {
+		if _, ok := err.(*PreExpandHookError); ok {
+			utils.HttpError(w, err.Error(), http.StatusPaymentRequired)
+			return
+		}
+		logger.LogError("Unable to run pre-expand hook: %v", err)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	ve := NewVolumeExpandOperation(volume, a.db, msg.Size)
+	if a.conf.RetryLimits.VolumeExpand > 0 {
+		ve.maxRetries = a.conf.RetryLimits.VolumeExpand
+	}
 	if err := AsyncHttpOperation(a, w, r, ve); err != nil {
 		OperationHttpErrorf(w, err, "Failed to allocate volume expansion: %v", err)
 		return
@@ -343,12 +881,12 @@ func (a *App) VolumeClone(w http.ResponseWriter, r *http.Request) {
 	var msg api.VolumeCloneRequest
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", http.StatusUnprocessableEntity)
+		utils.HttpError(w, "request unable to be parsed", http.StatusUnprocessableEntity)
 		return
 	}
 	err = msg.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(),
+		utils.HttpError(w, "validation failed: "+err.Error(),
 			http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
@@ -360,10 +898,10 @@ func (a *App) VolumeClone(w http.ResponseWriter, r *http.Request) {
 		volume, err = NewVolumeEntryFromId(tx, vol_id)
 		if err == ErrNotFound || !volume.Visible() {
 			// treat an invisible volume like it doesn't exist
-			http.Error(w, err.Error(), http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, err.Error(), http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -390,12 +928,12 @@ func (a *App) VolumeSetBlockRestriction(w http.ResponseWriter, r *http.Request)
 	var msg api.VolumeBlockRestrictionRequest
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w, "request unable to be parsed", 422)
+		utils.HttpError(w, "request unable to be parsed", 422)
 		return
 	}
 	err = msg.Validate()
 	if err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		logger.LogError("validation failed: " + err.Error())
 		return
 	}
@@ -405,10 +943,10 @@ func (a *App) VolumeSetBlockRestriction(w http.ResponseWriter, r *http.Request)
 		volume, err = NewVolumeEntryFromId(tx, id)
 		if err == ErrNotFound || !volume.Visible() {
 			// treat an invisible volume like it doesn't exist
-			http.Error(w, err.Error(), http.StatusNotFound)
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, err.Error(), http.StatusNotFound)
 			return err
 		} else if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
 
@@ -424,3 +962,224 @@ func (a *App) VolumeSetBlockRestriction(w http.ResponseWriter, r *http.Request)
 		return
 	}
 }
+
+func (a *App) VolumeSetACLConfig(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var volume *VolumeEntry
+	var msg api.VolumeACLConfig
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return
+	}
+
+	err = a.db.View(func(tx *bolt.Tx) error {
+		volume, err = NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound || !volume.Visible() {
+			// treat an invisible volume like it doesn't exist
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, err.Error(), http.StatusNotFound)
+			return err
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	op := NewVolumeAclConfigOperation(volume, a.db, msg)
+	if err := AsyncHttpOperation(a, w, r, op); err != nil {
+		OperationHttpErrorf(w, err, "Failed to set volume acl config: %v", err)
+		return
+	}
+}
+
+func (a *App) VolumeTranslators(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var volume *VolumeEntry
+	err := a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		volume, err = NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound || (err == nil && !volume.Visible()) {
+			// treat an invisible volume like it doesn't exist
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, "Id not found", http.StatusNotFound)
+			return ErrNotFound
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	host, err := GetVerifiedTspManageHostname(a.db, a.executor,
+		volume.Info.Cluster, volume.Info.TspId)
+	if err != nil {
+		utils.HttpError(w, "Unable to find a node of the volume: "+err.Error(),
+			http.StatusServiceUnavailable)
+		return
+	}
+	vinfo, err := a.executor.VolumeInfo(host, volume.Info.Name)
+	if err != nil {
+		logger.LogError("Unable to get info of volume %v: %v", id, err)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := api.VolumeTranslatorsResponse{
+		Translators: translatorOptions(vinfo),
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) VolumeSetTranslator(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var volume *VolumeEntry
+	var msg api.VolumeTranslatorRequest
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w, "request unable to be parsed", 422)
+		return
+	}
+	err = msg.Validate()
+	if err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		logger.LogError("validation failed: %v", err)
+		return
+	}
+
+	err = a.db.View(func(tx *bolt.Tx) error {
+		volume, err = NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound || (err == nil && !volume.Visible()) {
+			// treat an invisible volume like it doesn't exist
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, "Id not found", http.StatusNotFound)
+			return ErrNotFound
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	op := NewVolumeTranslatorsOperation(volume, a.db, msg)
+	if err := AsyncHttpOperation(a, w, r, op); err != nil {
+		OperationHttpErrorf(w, err, "Failed to set volume translator options: %v", err)
+		return
+	}
+}
+
+func (a *App) VolumeBitrotStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var volume *VolumeEntry
+	err := a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		volume, err = NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound || (err == nil && !volume.Visible()) {
+			// treat an invisible volume like it doesn't exist
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, "Id not found", http.StatusNotFound)
+			return ErrNotFound
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	host, err := GetVerifiedTspManageHostname(a.db, a.executor,
+		volume.Info.Cluster, volume.Info.TspId)
+	if err != nil {
+		utils.HttpError(w, "Unable to find a node of the volume: "+err.Error(),
+			http.StatusServiceUnavailable)
+		return
+	}
+	status, err := a.bitrot.Get(a.executor, host, volume)
+	if err != nil {
+		logger.LogError("Unable to get bitrot status of volume %v: %v", id, err)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) VolumeGeoRepStatus(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	session := vars["session_id"]
+
+	if err := api.ValidateGeoRepSession(session); err != nil {
+		utils.HttpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var volume *VolumeEntry
+	err := a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		volume, err = NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound || (err == nil && !volume.Visible()) {
+			// treat an invisible volume like it doesn't exist
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, "Id not found", http.StatusNotFound)
+			return ErrNotFound
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	host, err := GetVerifiedTspManageHostname(a.db, a.executor,
+		volume.Info.Cluster, volume.Info.TspId)
+	if err != nil {
+		utils.HttpError(w, "Unable to find a node of the volume: "+err.Error(),
+			http.StatusServiceUnavailable)
+		return
+	}
+	status, err := a.georep.Get(a.executor, host, volume, session)
+	if err != nil {
+		logger.LogError("Unable to get geo-replication status of volume %v: %v", id, err)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,154 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+// volumeCommandHost returns the name of the volume of the request
+// and a node the gluster commands of the volume can be run on. If the
+// volume does not exist or no node can be used an error is sent to the
+// client and false is returned.
+func (a *App) volumeCommandHost(w http.ResponseWriter, r *http.Request) (
+	string, string, bool) {
+
+	id := mux.Vars(r)["id"]
+
+	var volume *VolumeEntry
+	err := a.db.View(func(tx *bolt.Tx) error {
+		var err error
+		volume, err = NewVolumeEntryFromId(tx, id)
+		if err == ErrNotFound || (err == nil && !volume.Visible()) {
+			// treat an invisible volume like it doesn't exist
+			utils.HttpErrorCode(w, api.ErrorVolumeNotFound, "Id not found", http.StatusNotFound)
+			return ErrNotFound
+		} else if err != nil {
+			utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return "", "", false
+	}
+
+	host, err := GetVerifiedTspManageHostname(a.db, a.executor,
+		volume.Info.Cluster, volume.Info.TspId)
+	if err != nil {
+		utils.HttpError(w, "Unable to find a node of the volume: "+err.Error(),
+			http.StatusServiceUnavailable)
+		return "", "", false
+	}
+	return volume.Info.Name, host, true
+}
+
+func (a *App) VolumeProfileStart(w http.ResponseWriter, r *http.Request) {
+	name, host, ok := a.volumeCommandHost(w, r)
+	if !ok {
+		return
+	}
+	if err := a.executor.VolumeProfileStart(host, name); err != nil {
+		logger.LogError("Unable to start profiling volume %v: %v", name, err)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Started profiling volume %v", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) VolumeProfileStop(w http.ResponseWriter, r *http.Request) {
+	name, host, ok := a.volumeCommandHost(w, r)
+	if !ok {
+		return
+	}
+	if err := a.executor.VolumeProfileStop(host, name); err != nil {
+		logger.LogError("Unable to stop profiling volume %v: %v", name, err)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Stopped profiling volume %v", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *App) VolumeProfileInfo(w http.ResponseWriter, r *http.Request) {
+	name, host, ok := a.volumeCommandHost(w, r)
+	if !ok {
+		return
+	}
+	profile, err := a.executor.VolumeProfileInfo(host, name)
+	if err != nil {
+		logger.LogError("Unable to get profile info of volume %v: %v", name, err)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(NewVolumeProfileInfoResponse(profile)); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) VolumeStatedump(w http.ResponseWriter, r *http.Request) {
+	name, host, ok := a.volumeCommandHost(w, r)
+	if !ok {
+		return
+	}
+	if err := a.executor.VolumeStatedump(host, name); err != nil {
+		logger.LogError("Unable to take statedump of volume %v: %v", name, err)
+		utils.HttpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logger.Info("Took statedump of volume %v", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func NewVolumeProfileInfoResponse(p *executors.VolumeProfile) *api.VolumeProfileInfoResponse {
+	resp := &api.VolumeProfileInfoResponse{
+		Bricks: []api.BrickProfileInfo{},
+	}
+	for _, b := range p.Bricks {
+		info := api.BrickProfileInfo{
+			Brick:           b.Name,
+			DurationSeconds: b.Stats.DurationSeconds,
+			BytesRead:       b.Stats.TotalRead,
+			BytesWritten:    b.Stats.TotalWrite,
+			BlockStats:      []api.ProfileBlockStats{},
+			FopStats:        []api.ProfileFopStats{},
+		}
+		for _, bs := range b.Stats.Blocks {
+			info.BlockStats = append(info.BlockStats, api.ProfileBlockStats{
+				Size:   bs.Size,
+				Reads:  bs.Reads,
+				Writes: bs.Writes,
+			})
+		}
+		for _, fs := range b.Stats.Fops {
+			info.FopStats = append(info.FopStats, api.ProfileFopStats{
+				Fop:          fs.Name,
+				Hits:         fs.Hits,
+				AvgLatencyUs: fs.AvgLatency,
+				MinLatencyUs: fs.MinLatency,
+				MaxLatencyUs: fs.MaxLatency,
+			})
+		}
+		resp.Bricks = append(resp.Bricks, info)
+	}
+	return resp
+}
@@ -22,12 +22,14 @@ import (
 	"os/exec"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/gorilla/mux"
 	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
 	"github.com/heketi/heketi/pkg/db"
 	"github.com/heketi/heketi/pkg/glusterfs/api"
 	"github.com/heketi/heketi/pkg/sortedstrings"
@@ -1124,6 +1126,101 @@ func TestVolumeExpand(t *testing.T) {
 	tests.Assert(t, len(vc.Bricks) < len(info.Bricks))
 }
 
+func TestVolumeExpandConcurrent(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		5*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// hold the expansion that gets through until both requests
+	// have been answered
+	release := make(chan struct{})
+	app.xo.MockVolumeExpand = func(host string,
+		volume *executors.VolumeRequest) (*executors.Volume, error) {
+		<-release
+		return &executors.Volume{}, nil
+	}
+
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	locations := make([]string, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r, err := http.Post(ts.URL+"/volumes/"+v.Info.Id+"/expand",
+				"application/json",
+				bytes.NewBufferString(`{"expand_size": 100}`))
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+			r.Body.Close()
+			codes[i] = r.StatusCode
+			locations[i] = r.Header.Get("Location")
+		}(i)
+	}
+	wg.Wait()
+
+	accepted := -1
+	conflicts := 0
+	for i, code := range codes {
+		switch code {
+		case http.StatusAccepted:
+			accepted = i
+		case http.StatusConflict:
+			conflicts++
+		}
+	}
+	tests.Assert(t, conflicts == 1, "expected exactly one 409, got:", codes)
+	tests.Assert(t, accepted >= 0, "expected one 202, got:", codes)
+
+	close(release)
+	for {
+		r, err := http.Get(ts.URL + locations[accepted])
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		r.Body.Close()
+		if r.Header.Get("X-Pending") != "true" {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		vol, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, vol.Info.Size == 200,
+			"expected size 200, got:", vol.Info.Size)
+		tests.Assert(t, vol.Expanding.Id == "",
+			"expected no expansion in progress, got:", vol.Expanding.Id)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// the volume can be expanded again once the expansion is done
+	c := client.NewClientNoAuth(ts.URL)
+	info, err := c.VolumeExpand(v.Info.Id, &api.VolumeExpandRequest{Size: 100})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Size == 300, "expected size 300, got:", info.Size)
+}
+
 func TestVolumeClusterResizeByAddingDevices(t *testing.T) {
 	tmpfile := tests.Tempfile()
 	defer os.Remove(tmpfile)
@@ -1426,3 +1523,550 @@ func TestVolumeCreateWithVariousOptions(t *testing.T) {
 		tests.Assert(t, err == nil, err)
 	}
 }
+
+func TestVolumeDetach(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// no gluster or lvm command may be run
+	calls := 0
+	app.xo.MockVolumeDestroy = func(host string, volume string) error {
+		calls++
+		return nil
+	}
+	app.xo.MockVolumeDestroyCheck = func(host, volume string) error {
+		calls++
+		return nil
+	}
+	app.xo.MockBrickDestroy = func(host string, brick *executors.BrickRequest) (bool, error) {
+		calls++
+		return true, nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	info, err := c.VolumeDetach(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Id == v.Info.Id, "expected", v.Info.Id, "got:", info.Id)
+	tests.Assert(t, len(info.Bricks) == 3,
+		"expected 3 bricks, got:", len(info.Bricks))
+	tests.Assert(t, calls == 0, "expected no executor calls, got:", calls)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == ErrNotFound, "expected err == ErrNotFound, got:", err)
+		bl, err := BrickList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(bl) == 0, "expected no bricks, got:", bl)
+		c, err := NewClusterEntryFromId(tx, v.Info.Cluster)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(c.Info.Volumes) == 0,
+			"expected no volumes in cluster, got:", c.Info.Volumes)
+		dl, err := DeviceList(tx)
+		if err != nil {
+			return err
+		}
+		for _, id := range dl {
+			d, err := NewDeviceEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			tests.Assert(t, d.Info.Storage.Used == 0,
+				"expected no space used, got:", d.Info.Storage.Used)
+			tests.Assert(t, len(d.Bricks) == 0,
+				"expected no bricks on device, got:", d.Bricks)
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestVolumeDetachConflicts(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+	_, err = c.VolumeDetach("123")
+	assertErrorCode(t, err, api.ErrorVolumeNotFound)
+
+	// a brick of the volume has a pending operation
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		b, err := NewBrickEntryFromId(tx, v.Bricks[0])
+		if err != nil {
+			return err
+		}
+		b.Pending.Id = "abc"
+		return b.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, err = c.VolumeDetach(v.Info.Id)
+	assertErrorCode(t, err, api.ErrorOperationInProgress)
+
+	// the volume hosts block volumes
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		b, err := NewBrickEntryFromId(tx, v.Bricks[0])
+		if err != nil {
+			return err
+		}
+		b.Pending.Id = ""
+		if err := b.Save(tx); err != nil {
+			return err
+		}
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		entry.Info.Block = true
+		entry.BlockVolumeAdd("def")
+		return entry.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, err = c.VolumeDetach(v.Info.Id)
+	assertErrorCode(t, err, api.ErrorConflict)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		return err
+	})
+	tests.Assert(t, err == nil, "expected volume kept, got:", err)
+}
+
+func TestVolumeCreateSizeBounds(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	defer tests.Patch(&minVolumeSizeGB, 2).Restore()
+	defer tests.Patch(&maxVolumeSizeGB, 100*1024).Restore()
+
+	c := client.NewClientNoAuth(ts.URL)
+	req := &api.VolumeCreateRequest{}
+	req.Size = 1
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+	_, err = c.VolumeCreate(req)
+	assertErrorCode(t, err, api.ErrorUnprocessable)
+	tests.Assert(t, strings.Contains(err.Error(), "minimum volume size of 2 GiB"),
+		`expected "minimum volume size of 2 GiB" in error, got:`, err)
+
+	req.Size = 200 * 1024
+	_, err = c.VolumeCreate(req)
+	assertErrorCode(t, err, api.ErrorUnprocessable)
+	tests.Assert(t, strings.Contains(err.Error(), "maximum volume size of 102400 GiB"),
+		`expected "maximum volume size of 102400 GiB" in error, got:`, err)
+
+	req.Size = 2
+	_, err = c.VolumeCreate(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		vl, err := VolumeList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(vl) == 1, "expected 1 volume, got:", vl)
+		l, err := PendingOperationList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(l) == 0, "expected no pending ops, got:", l)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestVolumeExpandSizeBounds(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	defer tests.Patch(&maxVolumeSizeGB, 150).Restore()
+
+	c := client.NewClientNoAuth(ts.URL)
+	_, err = c.VolumeExpand(v.Info.Id, &api.VolumeExpandRequest{Size: 100})
+	assertErrorCode(t, err, api.ErrorUnprocessable)
+	tests.Assert(t, strings.Contains(err.Error(), "maximum volume size of 150 GiB"),
+		`expected "maximum volume size of 150 GiB" in error, got:`, err)
+
+	info, err := c.VolumeExpand(v.Info.Id, &api.VolumeExpandRequest{Size: 50})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Size == 150, "expected size 150, got:", info.Size)
+}
+
+func TestVolumeBitrotStatus(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	calls := 0
+	app.xo.MockVolumeBitrotStatus = func(host string, volume string) (*executors.BitrotStatus, error) {
+		tests.Assert(t, volume == v.Info.Name, "expected", v.Info.Name, "got:", volume)
+		calls++
+		return &executors.BitrotStatus{
+			State:           "Active",
+			DurationSeconds: 3600,
+			FilesScrubbed:   1000 * calls,
+			FilesCorrupted:  calls,
+			LastCompleted:   "2018-08-20 11:02:10",
+		}, nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	status, err := c.VolumeBitrotStatus(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, *status == api.BitrotStatusResponse{
+		State:           "Active",
+		DurationSeconds: 3600,
+		FilesScrubbed:   1000,
+		FilesCorrupted:  1,
+		LastCompleted:   "2018-08-20 11:02:10",
+	}, "unexpected status:", status)
+
+	// the status is cached
+	status, err = c.VolumeBitrotStatus(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, calls == 1, "expected 1 call, got:", calls)
+	tests.Assert(t, status.FilesCorrupted == 1,
+		"expected 1 corrupted file, got:", status.FilesCorrupted)
+
+	// and reported in the volume info
+	info, err := c.VolumeInfo(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Bitrot != nil && info.Bitrot.FilesCorrupted == 1,
+		"expected bitrot status in volume info, got:", info.Bitrot)
+
+	// until it expires
+	app.bitrot.TTL = 0
+	status, err = c.VolumeBitrotStatus(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, calls == 2, "expected 2 calls, got:", calls)
+	tests.Assert(t, status.FilesCorrupted == 2,
+		"expected 2 corrupted files, got:", status.FilesCorrupted)
+
+	app.xo.MockVolumeBitrotStatus = func(host string, volume string) (*executors.BitrotStatus, error) {
+		return nil, errors.New("bitrot is not enabled")
+	}
+	_, err = c.VolumeBitrotStatus(v.Info.Id)
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	_, err = c.VolumeBitrotStatus("0000000000000000000000000000000a")
+	assertErrorCode(t, err, api.ErrorVolumeNotFound)
+}
+
+func TestVolumeGeoRepStatus(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	calls := 0
+	app.xo.MockVolumeGeoRepStatus = func(host string, volume string, slave string) (*executors.GeoRepStatus, error) {
+		tests.Assert(t, volume == v.Info.Name, "expected", v.Info.Name, "got:", volume)
+		tests.Assert(t, slave == "geoaccount@dr1::gv0-dr", "got:", slave)
+		calls++
+		return &executors.GeoRepStatus{
+			Bricks: []executors.GeoRepBrickStatus{
+				{
+					MasterNode:   "node1",
+					BrickPath:    "/bricks/b1",
+					SlaveNode:    "dr1",
+					Status:       "Active",
+					CrawlStatus:  "Changelog Crawl",
+					FilesSynced:  100 * calls,
+					FilesPending: 7,
+					BytesPending: 4096,
+					LastSynced:   "2018-10-15 09:47:12",
+				},
+				{
+					MasterNode: "node2",
+					BrickPath:  "/bricks/b2",
+					Status:     "Faulty",
+				},
+			},
+		}, nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	status, err := c.VolumeGeoRepStatus(v.Info.Id, "geoaccount@dr1::gv0-dr")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	expected := &api.GeoRepSessionStatusResponse{
+		Session: "geoaccount@dr1::gv0-dr",
+		Bricks: []api.GeoRepBrickStatus{
+			{
+				MasterNode:   "node1",
+				BrickPath:    "/bricks/b1",
+				SlaveNode:    "dr1",
+				Status:       "Active",
+				CrawlStatus:  "Changelog Crawl",
+				FilesSynced:  100,
+				FilesPending: 7,
+				BytesPending: 4096,
+				LastSynced:   "2018-10-15 09:47:12",
+			},
+			{
+				MasterNode: "node2",
+				BrickPath:  "/bricks/b2",
+				Status:     "Faulty",
+			},
+		},
+	}
+	tests.Assert(t, reflect.DeepEqual(status, expected),
+		"expected", expected, "got:", status)
+
+	// the status is cached
+	status, err = c.VolumeGeoRepStatus(v.Info.Id, "geoaccount@dr1::gv0-dr")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, calls == 1, "expected 1 call, got:", calls)
+	tests.Assert(t, status.Bricks[0].FilesSynced == 100,
+		"expected 100 files synced, got:", status.Bricks[0].FilesSynced)
+
+	// until it expires
+	app.georep.TTL = 0
+	status, err = c.VolumeGeoRepStatus(v.Info.Id, "geoaccount@dr1::gv0-dr")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, calls == 2, "expected 2 calls, got:", calls)
+	tests.Assert(t, status.Bricks[0].FilesSynced == 200,
+		"expected 200 files synced, got:", status.Bricks[0].FilesSynced)
+
+	app.xo.MockVolumeGeoRepStatus = func(host string, volume string, slave string) (*executors.GeoRepStatus, error) {
+		return nil, errors.New("no active geo-replication sessions")
+	}
+	_, err = c.VolumeGeoRepStatus(v.Info.Id, "geoaccount@dr1::gv0-dr")
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	_, err = c.VolumeGeoRepStatus(v.Info.Id, "dr1:gv0-dr;reboot")
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	_, err = c.VolumeGeoRepStatus("0000000000000000000000000000000a", "dr1::gv0-dr")
+	assertErrorCode(t, err, api.ErrorVolumeNotFound)
+}
+
+func TestVolumeProfile(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var ran []string
+	app.xo.MockVolumeProfileStart = func(host string, volume string) error {
+		tests.Assert(t, volume == v.Info.Name, "expected", v.Info.Name, "got:", volume)
+		ran = append(ran, "start")
+		return nil
+	}
+	app.xo.MockVolumeProfileStop = func(host string, volume string) error {
+		tests.Assert(t, volume == v.Info.Name, "expected", v.Info.Name, "got:", volume)
+		ran = append(ran, "stop")
+		return nil
+	}
+	app.xo.MockVolumeStatedump = func(host string, volume string) error {
+		tests.Assert(t, volume == v.Info.Name, "expected", v.Info.Name, "got:", volume)
+		ran = append(ran, "statedump")
+		return nil
+	}
+	app.xo.MockVolumeProfileInfo = func(host string, volume string) (*executors.VolumeProfile, error) {
+		tests.Assert(t, volume == v.Info.Name, "expected", v.Info.Name, "got:", volume)
+		return &executors.VolumeProfile{
+			Bricks: []executors.BrickProfile{
+				{
+					Name: "host1:/bricks/b1",
+					Stats: executors.ProfileStats{
+						Blocks: []executors.ProfileBlockStats{
+							{Size: 4096, Reads: 10, Writes: 120},
+						},
+						Fops: []executors.ProfileFopStats{
+							{Name: "WRITE", Hits: 120, AvgLatency: 96.21,
+								MinLatency: 31, MaxLatency: 1543},
+						},
+						DurationSeconds: 348,
+						TotalRead:       40960,
+						TotalWrite:      491520,
+					},
+				},
+				{Name: "host2:/bricks/b2"},
+			},
+		}, nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	err = c.VolumeProfileStart(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	profile, err := c.VolumeProfileInfo(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(profile.Bricks) == 2,
+		"expected 2 bricks, got:", len(profile.Bricks))
+	b := profile.Bricks[0]
+	tests.Assert(t, b.Brick == "host1:/bricks/b1", b.Brick)
+	tests.Assert(t, b.DurationSeconds == 348, b.DurationSeconds)
+	tests.Assert(t, b.BytesRead == 40960, b.BytesRead)
+	tests.Assert(t, b.BytesWritten == 491520, b.BytesWritten)
+	tests.Assert(t, len(b.BlockStats) == 1 && b.BlockStats[0] == api.ProfileBlockStats{
+		Size: 4096, Reads: 10, Writes: 120,
+	}, "unexpected block stats:", b.BlockStats)
+	tests.Assert(t, len(b.FopStats) == 1 && b.FopStats[0] == api.ProfileFopStats{
+		Fop: "WRITE", Hits: 120, AvgLatencyUs: 96.21,
+		MinLatencyUs: 31, MaxLatencyUs: 1543,
+	}, "unexpected fop stats:", b.FopStats)
+	// bricks without statistics have empty lists
+	b = profile.Bricks[1]
+	tests.Assert(t, b.BlockStats != nil && len(b.BlockStats) == 0, b.BlockStats)
+	tests.Assert(t, b.FopStats != nil && len(b.FopStats) == 0, b.FopStats)
+
+	err = c.VolumeStatedump(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = c.VolumeProfileStop(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, strings.Join(ran, ",") == "start,statedump,stop",
+		"unexpected commands:", ran)
+
+	app.xo.MockVolumeProfileInfo = func(host string, volume string) (*executors.VolumeProfile, error) {
+		return nil, errors.New("Profile on Volume is not started")
+	}
+	_, err = c.VolumeProfileInfo(v.Info.Id)
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "not started"),
+		"expected error to mention the profile is not started, got:", err)
+
+	err = c.VolumeProfileStart("0000000000000000000000000000000a")
+	tests.Assert(t, err != nil, "expected err != nil")
+	_, err = c.VolumeProfileInfo("0000000000000000000000000000000a")
+	tests.Assert(t, err != nil, "expected err != nil")
+}
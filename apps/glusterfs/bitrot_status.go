@@ -0,0 +1,109 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+const (
+	// how long the bitrot status of a volume is reused before
+	// gluster is asked again
+	BITROT_STATUS_TTL = 60 * time.Second
+)
+
+type bitrotStatusEntry struct {
+	status  api.BitrotStatusResponse
+	updated time.Time
+}
+
+// BitrotStatusCache keeps the bitrot status of the volumes such that
+// polling clients do not run the scrub status command on every
+// request.
+type BitrotStatusCache struct {
+	TTL time.Duration
+
+	lock    sync.RWMutex
+	volumes map[string]bitrotStatusEntry
+}
+
+func NewBitrotStatusCache(ttl time.Duration) *BitrotStatusCache {
+	return &BitrotStatusCache{
+		TTL:     ttl,
+		volumes: map[string]bitrotStatusEntry{},
+	}
+}
+
+// Get returns the bitrot status of the volume. The status is read
+// from the given host if the cached status is older than the TTL.
+func (bc *BitrotStatusCache) Get(e executors.Executor,
+	host string, v *VolumeEntry) (*api.BitrotStatusResponse, error) {
+
+	bc.lock.RLock()
+	entry, found := bc.volumes[v.Info.Id]
+	bc.lock.RUnlock()
+	if found && time.Since(entry.updated) < bc.TTL {
+		status := entry.status
+		return &status, nil
+	}
+
+	bs, err := e.VolumeBitrotStatus(host, v.Info.Name)
+	if err != nil {
+		return nil, err
+	}
+	status := api.BitrotStatusResponse{
+		State:           bs.State,
+		DurationSeconds: bs.DurationSeconds,
+		FilesScrubbed:   bs.FilesScrubbed,
+		FilesCorrupted:  bs.FilesCorrupted,
+		LastCompleted:   bs.LastCompleted,
+	}
+	if status.FilesCorrupted > 0 {
+		logger.Warning("Bitrot scrubber found %v corrupted files in volume %v",
+			status.FilesCorrupted, v.Info.Name)
+	}
+
+	bc.lock.Lock()
+	defer bc.lock.Unlock()
+	bc.volumes[v.Info.Id] = bitrotStatusEntry{
+		status:  status,
+		updated: time.Now(),
+	}
+	// the volume info includes the bitrot status
+	if currentVolumeInfoCache != nil {
+		currentVolumeInfoCache.Invalidate(v.Info.Id)
+	}
+	return &status, nil
+}
+
+// Status returns a map of volume ids to the last known bitrot status
+// of the volume, regardless of its age.
+func (bc *BitrotStatusCache) Status() map[string]api.BitrotStatusResponse {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	out := map[string]api.BitrotStatusResponse{}
+	for k, v := range bc.volumes {
+		out[k] = v.status
+	}
+	return out
+}
+
+// currentBitrotStatus returns a map of volume ids to the last known
+// bitrot status of the volume.
+func currentBitrotStatus() map[string]api.BitrotStatusResponse {
+	if currentBitrotStatusCache != nil {
+		return currentBitrotStatusCache.Status()
+	}
+	return map[string]api.BitrotStatusResponse{}
+}
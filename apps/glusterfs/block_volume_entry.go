@@ -111,10 +111,13 @@ func (v *BlockVolumeEntry) Save(tx *bolt.Tx) error {
 	godbc.Require(tx != nil)
 	godbc.Require(len(v.Info.Id) > 0)
 
+	// the hosting volume lists its block volumes
+	invalidateVolumeInfo(tx, v.Info.BlockHostingVolume)
 	return EntrySave(tx, v, v.Info.Id)
 }
 
 func (v *BlockVolumeEntry) Delete(tx *bolt.Tx) error {
+	invalidateVolumeInfo(tx, v.Info.BlockHostingVolume)
 	return EntryDelete(tx, v, v.Info.Id)
 }
 
@@ -300,7 +303,17 @@ func (v *BlockVolumeEntry) deleteBlockVolumeExec(db wdb.RODB,
 	hvname string,
 	executor executors.Executor) error {
 
-	executorhost, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	var tspId string
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		tspId, err = v.blockHostingVolumeTsp(tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	executorhost, err := GetVerifiedTspManageHostname(db, executor,
+		v.Info.Cluster, tspId)
 	if err != nil {
 		return err
 	}
@@ -440,12 +453,29 @@ func (v *BlockVolumeEntry) hosts(db wdb.RODB) (nodeHosts, error) {
 		if err != nil {
 			return err
 		}
-		hosts, err = cluster.hosts(wdb.WrapTx(tx))
+		tspId, err := v.blockHostingVolumeTsp(tx)
+		if err != nil {
+			return err
+		}
+		hosts, err = cluster.tspHosts(wdb.WrapTx(tx), tspId)
 		return err
 	})
 	return hosts, err
 }
 
+// blockHostingVolumeTsp returns the trusted storage pool of the
+// block hosting volume of the block volume.
+func (v *BlockVolumeEntry) blockHostingVolumeTsp(tx *bolt.Tx) (string, error) {
+	if v.Info.BlockHostingVolume == "" {
+		return "", nil
+	}
+	volume, err := NewVolumeEntryFromId(tx, v.Info.BlockHostingVolume)
+	if err != nil {
+		return "", err
+	}
+	return volume.Info.TspId, nil
+}
+
 // hasPendingBlockHostingVolume returns true if the db contains pending
 // block hosting volumes.
 func hasPendingBlockHostingVolume(tx *bolt.Tx) (bool, error) {
@@ -55,6 +55,7 @@ func (v *BlockVolumeEntry) createBlockVolumeRequest(db wdb.RODB,
 	godbc.Require(blockHostingVolumeId != "")
 
 	var blockHostingVolumeName string
+	var tspId string
 
 	err := db.View(func(tx *bolt.Tx) error {
 		logger.Debug("Getting info for block hosting volume %v", blockHostingVolumeId)
@@ -88,6 +89,7 @@ func (v *BlockVolumeEntry) createBlockVolumeRequest(db wdb.RODB,
 
 		v.Info.Cluster = bhvol.Info.Cluster
 		blockHostingVolumeName = bhvol.Info.Name
+		tspId = bhvol.Info.TspId
 
 		return nil
 	})
@@ -97,7 +99,8 @@ func (v *BlockVolumeEntry) createBlockVolumeRequest(db wdb.RODB,
 	}
 
 	// Select the host on which glusterd is running. To avoid request failing on host down senario.
-	executorhost, err := GetVerifiedManageHostname(db, executor, v.Info.Cluster)
+	executorhost, err := GetVerifiedTspManageHostname(db, executor,
+		v.Info.Cluster, tspId)
 	if err != nil {
 		return nil, "", err
 	}
@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -58,9 +59,9 @@ func TestBlockVolumeExpandOperationFinalize(t *testing.T) {
 		return nil
 	})
 
-	e := bvc.Build()
+	e := bvc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
-	e = bvc.Exec(app.executor)
+	e = bvc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 	e = bvc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
@@ -101,7 +102,7 @@ func TestBlockVolumeExpandOperationFinalize(t *testing.T) {
 
 	bve := NewBlockVolumeExpandOperation(bvol.Info.Id, app.db, 150) // New size
 
-	e = bve.Build()
+	e = bve.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify,
@@ -137,7 +138,7 @@ func TestBlockVolumeExpandOperationFinalize(t *testing.T) {
 		return nil
 	})
 
-	e = bve.Exec(app.executor)
+	e = bve.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	e = bve.Finalize()
@@ -214,9 +215,9 @@ func TestBlockVolumeExpandOperationBuildFailed(t *testing.T) {
 		return nil
 	})
 
-	e := bvc.Build()
+	e := bvc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
-	e = bvc.Exec(app.executor)
+	e = bvc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 	e = bvc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
@@ -257,14 +258,14 @@ func TestBlockVolumeExpandOperationBuildFailed(t *testing.T) {
 	// request a size larger than the BlockHostingVolumeSize
 	bve := NewBlockVolumeExpandOperation(bvol.Info.Id, app.db, 1100)
 
-	e = bve.Build()
+	e = bve.Build(context.Background())
 	tests.Assert(t, e != nil, "expected e != nil, got", e)
 	tests.Assert(t, e == ErrNoSpace, "expected e == ErrNoSpace', got", e)
 
 	// request a size same as current block volume size
 	bve = NewBlockVolumeExpandOperation(bvol.Info.Id, app.db, 100)
 
-	e = bve.Build()
+	e = bve.Build(context.Background())
 	err_str := "Requested new-size 100 is same as current block volume size 100, nothing to be done."
 	tests.Assert(t, e != nil, "expected e != nil, got", e)
 	tests.Assert(t, e.Error() == err_str,
@@ -273,7 +274,7 @@ func TestBlockVolumeExpandOperationBuildFailed(t *testing.T) {
 	// try shrink, request a size less than current block volume size
 	bve = NewBlockVolumeExpandOperation(bvol.Info.Id, app.db, 50)
 
-	e = bve.Build()
+	e = bve.Build(context.Background())
 	err_str = "Requested new-size 50 is less than current block volume size 100, shrinking is not allowed."
 	tests.Assert(t, e != nil, "expected e != nil, got", e)
 	tests.Assert(t, e.Error() == err_str,
@@ -317,9 +318,9 @@ func TestBlockVolumeExpandOperationRollbackGbCliFailedCompletely(t *testing.T) {
 		return nil
 	})
 
-	e := bvc.Build()
+	e := bvc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
-	e = bvc.Exec(app.executor)
+	e = bvc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 	e = bvc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
@@ -360,7 +361,7 @@ func TestBlockVolumeExpandOperationRollbackGbCliFailedCompletely(t *testing.T) {
 
 	bve := NewBlockVolumeExpandOperation(bvol.Info.Id, app.db, 150) // New size
 
-	e = bve.Build()
+	e = bve.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify,
@@ -404,7 +405,7 @@ func TestBlockVolumeExpandOperationRollbackGbCliFailedCompletely(t *testing.T) {
 		return fmt.Errorf("Failed to expand block volume")
 	}
 
-	e = bve.Exec(app.executor)
+	e = bve.Exec(context.Background(), app.executor)
 	tests.Assert(t, e != nil, "expected e != nil, got", e)
 
 	// pretend that we have called gluster-block cli and got some info
@@ -427,7 +428,7 @@ func TestBlockVolumeExpandOperationRollbackGbCliFailedCompletely(t *testing.T) {
 		return &blockVolumeInfo, nil
 	}
 
-	e = bve.Rollback(app.executor)
+	e = bve.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify,
@@ -502,9 +503,9 @@ func TestBlockVolumeExpandOperationRollbackGbCliFailedPartially(t *testing.T) {
 		return nil
 	})
 
-	e := bvc.Build()
+	e := bvc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
-	e = bvc.Exec(app.executor)
+	e = bvc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 	e = bvc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
@@ -545,7 +546,7 @@ func TestBlockVolumeExpandOperationRollbackGbCliFailedPartially(t *testing.T) {
 
 	bve := NewBlockVolumeExpandOperation(bvol.Info.Id, app.db, 150) // New size
 
-	e = bve.Build()
+	e = bve.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify,
@@ -589,7 +590,7 @@ func TestBlockVolumeExpandOperationRollbackGbCliFailedPartially(t *testing.T) {
 		return fmt.Errorf("Failed to expand block volume")
 	}
 
-	e = bve.Exec(app.executor)
+	e = bve.Exec(context.Background(), app.executor)
 	tests.Assert(t, e != nil, "expected e != nil, got", e)
 
 	// pretend that we have called gluster-block cli and got some info
@@ -612,7 +613,7 @@ func TestBlockVolumeExpandOperationRollbackGbCliFailedPartially(t *testing.T) {
 		return &blockVolumeInfo, nil
 	}
 
-	e = bve.Rollback(app.executor)
+	e = bve.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify,
@@ -695,16 +696,16 @@ func TestBlockVolumeExpandOperationBuildParallel(t *testing.T) {
 		return nil
 	})
 
-	e := bvc1.Build()
+	e := bvc1.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
-	e = bvc1.Exec(app.executor)
+	e = bvc1.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 	e = bvc1.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
-	e = bvc2.Build()
+	e = bvc2.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
-	e = bvc2.Exec(app.executor)
+	e = bvc2.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 	e = bvc2.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
@@ -746,10 +747,10 @@ func TestBlockVolumeExpandOperationBuildParallel(t *testing.T) {
 	bve1 := NewBlockVolumeExpandOperation(bvol1.Info.Id, app.db, 978) // newSize = oldSize + remaining FreeSize on BHV
 	bve2 := NewBlockVolumeExpandOperation(bvol2.Info.Id, app.db, 978) // newSize = oldSize + remaining FreeSize on BHV
 
-	e = bve1.Build()
+	e = bve1.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
-	e = bve2.Build()
+	e = bve2.Build(context.Background())
 	tests.Assert(t, e == ErrNoSpace, "expected e == ErrNoSpace', got", e)
 
 	// verify,
@@ -810,7 +811,7 @@ func TestBlockVolumeExpandOperationBuildParallel(t *testing.T) {
 		return &blockVolumeInfo, nil
 	}
 
-	e = bve1.Rollback(app.executor)
+	e = bve1.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify,
@@ -849,7 +850,7 @@ func TestBlockVolumeExpandOperationBuildParallel(t *testing.T) {
 
 	// retry: now that block volume expand 1 request is Rollback'ed,
 	//        the free size can be claimed by block volume expand 2 request
-	e = bve2.Build()
+	e = bve2.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify,
@@ -884,7 +885,7 @@ func TestBlockVolumeExpandOperationBuildParallel(t *testing.T) {
 		return nil
 	})
 
-	e = bve2.Exec(app.executor)
+	e = bve2.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	e = bve2.Finalize()
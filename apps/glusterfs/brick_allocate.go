@@ -15,6 +15,7 @@ import (
 	"github.com/boltdb/bolt"
 	"github.com/lpabon/godbc"
 
+	"github.com/heketi/heketi/pkg/glusterfs/api"
 	"github.com/heketi/heketi/pkg/idgen"
 )
 
@@ -408,6 +409,10 @@ func (vp *VolumePlacementOpts) AverageFileSize() uint64 {
 	return vp.v.GetAverageFileSize()
 }
 
+func (vp *VolumePlacementOpts) AccessPattern() api.VolumeAccessPattern {
+	return vp.v.Info.AccessPattern
+}
+
 type StandardBrickPlacer struct{}
 
 func NewStandardBrickPlacer() *StandardBrickPlacer {
@@ -434,6 +439,7 @@ func (bp *StandardBrickPlacer) PlaceAll(
 		brickId := idgen.GenUUID()
 
 		a := NewSimpleAllocator()
+		a.AccessPattern = opts.AccessPattern()
 		deviceCh, done, err := a.GetNodesFromDeviceSource(dsrc, brickId)
 		defer close(done)
 		if err != nil {
@@ -481,6 +487,7 @@ func (bp *StandardBrickPlacer) Replace(
 
 	brickId := idgen.GenUUID()
 	a := NewSimpleAllocator()
+	a.AccessPattern = opts.AccessPattern()
 	deviceCh, done, err := a.GetNodesFromDeviceSource(dsrc, brickId)
 	defer close(done)
 	if err != nil {
@@ -131,10 +131,12 @@ func (b *BrickEntry) Save(tx *bolt.Tx) error {
 	godbc.Require(tx != nil)
 	godbc.Require(len(b.Info.Id) > 0)
 
+	invalidateVolumeInfo(tx, b.Info.VolumeId)
 	return EntrySave(tx, b, b.Info.Id)
 }
 
 func (b *BrickEntry) Delete(tx *bolt.Tx) error {
+	invalidateVolumeInfo(tx, b.Info.VolumeId)
 	return EntryDelete(tx, b, b.Info.Id)
 }
 
@@ -342,6 +344,13 @@ func (b *BrickEntry) RemoveFromDevice(tx *bolt.Tx) error {
 	// Delete brick from device
 	device.BrickDelete(b.Info.Id)
 
+	// Removing the lv of the brick removes its snapshots as well
+	freed, err := removeBrickLvmSnapshots(tx, b.Info.Id)
+	if err != nil {
+		return err
+	}
+	device.StorageFree(freed)
+
 	// Save device
 	err = device.Save(tx)
 	if err != nil {
@@ -0,0 +1,253 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+const (
+	// default time a brick must be offline before it is replaced
+	DEFAULT_BRICK_OFFLINE_THRESHOLD = 5 * time.Minute
+)
+
+// BrickFaultDetector tracks how long the brick processes of the
+// volumes managed by heketi have been offline and, if enabled,
+// replaces bricks that have been offline for longer than the
+// threshold by starting a brick evict operation.
+type BrickFaultDetector struct {
+	Threshold   time.Duration
+	AutoReplace bool
+
+	db   wdb.DB
+	exec executors.Executor
+	lock sync.Mutex
+	// time each brick was first seen offline
+	offline map[string]time.Time
+	// bricks for which an evict operation was started
+	evicting map[string]bool
+	replaced uint64
+
+	// context the evict operations run with
+	ctx context.Context
+	// runs the built evict operation
+	launch func(op Operation)
+}
+
+func NewBrickFaultDetector(db wdb.DB, e executors.Executor,
+	conf FaultDetectionConfig) *BrickFaultDetector {
+
+	threshold := DEFAULT_BRICK_OFFLINE_THRESHOLD
+	if conf.OfflineThresholdMinutes > 0 {
+		threshold = time.Minute * time.Duration(conf.OfflineThresholdMinutes)
+	}
+	d := &BrickFaultDetector{
+		Threshold:   threshold,
+		AutoReplace: conf.AutoReplace,
+		db:          db,
+		exec:        e,
+		offline:     map[string]time.Time{},
+		evicting:    map[string]bool{},
+		ctx:         context.Background(),
+	}
+	d.launch = func(op Operation) {
+		go func() {
+			if err := runOperationAfterBuild(d.ctx, op, e); err != nil {
+				logger.LogError("Automatic brick replacement failed: %v", err)
+			}
+		}()
+	}
+	return d
+}
+
+// Replaced returns the number of brick evict operations started
+// by the detector.
+func (d *BrickFaultDetector) Replaced() uint64 {
+	return atomic.LoadUint64(&d.replaced)
+}
+
+// OfflineSince returns the time the brick was first seen offline.
+// If the brick is not known to be offline found will be false.
+func (d *BrickFaultDetector) OfflineSince(brickId string) (t time.Time, found bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	t, found = d.offline[brickId]
+	return
+}
+
+// brickRef identifies a brick in the output of volume status.
+type brickRef struct {
+	host string
+	path string
+}
+
+type faultVolume struct {
+	id      string
+	name    string
+	cluster string
+	tspId   string
+	bricks  map[brickRef]string
+	pending map[string]bool
+}
+
+func (d *BrickFaultDetector) volumes() ([]*faultVolume, error) {
+	vols := []*faultVolume{}
+	err := d.db.View(func(tx *bolt.Tx) error {
+		vl, err := ListCompleteVolumes(tx)
+		if err != nil {
+			return err
+		}
+		for _, volId := range vl {
+			v, err := NewVolumeEntryFromId(tx, volId)
+			if err != nil {
+				return err
+			}
+			fv := &faultVolume{
+				id:      v.Info.Id,
+				name:    v.Info.Name,
+				cluster: v.Info.Cluster,
+				tspId:   v.Info.TspId,
+				bricks:  map[brickRef]string{},
+				pending: map[string]bool{},
+			}
+			for _, brickId := range v.BricksIds() {
+				b, err := NewBrickEntryFromId(tx, brickId)
+				if err != nil {
+					return err
+				}
+				n, err := NewNodeEntryFromId(tx, b.Info.NodeId)
+				if err != nil {
+					return err
+				}
+				fv.bricks[brickRef{n.StorageHostName(), b.Info.Path}] = brickId
+				if b.Pending.Id != "" {
+					fv.pending[brickId] = true
+				}
+			}
+			vols = append(vols, fv)
+		}
+		return nil
+	})
+	return vols, err
+}
+
+// Check queries the status of all volumes, updates the time each
+// brick has been offline and replaces bricks that have been offline
+// for longer than the threshold.
+func (d *BrickFaultDetector) Check() error {
+	vols, err := d.volumes()
+	if err != nil {
+		return err
+	}
+	now := healthNow()
+	seen := map[string]bool{}
+	for _, fv := range vols {
+		for _, brickId := range fv.bricks {
+			seen[brickId] = true
+		}
+		host, err := GetVerifiedTspManageHostname(d.db, d.exec,
+			fv.cluster, fv.tspId)
+		if err != nil {
+			logger.LogError("No host available to check volume %v: %v",
+				fv.name, err)
+			continue
+		}
+		status, err := d.exec.VolumeStatus(host, fv.name)
+		if err != nil {
+			logger.LogError("Unable to get status of volume %v: %v",
+				fv.name, err)
+			continue
+		}
+		for _, bs := range status.Bricks {
+			brickId, ok := fv.bricks[brickRef{bs.Hostname, bs.Path}]
+			if !ok {
+				// not a brick (e.g. self-heal daemon) or unknown
+				continue
+			}
+			if d.update(brickId, bs.Online(), now) && !fv.pending[brickId] {
+				d.replace(fv, brickId, now)
+			}
+		}
+	}
+	d.forget(seen)
+	return nil
+}
+
+// update records the current state of a brick and returns true if
+// the brick has been offline for longer than the threshold.
+func (d *BrickFaultDetector) update(brickId string, online bool, now time.Time) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if online {
+		delete(d.offline, brickId)
+		return false
+	}
+	since, found := d.offline[brickId]
+	if !found {
+		logger.Warning("Brick %v is offline", brickId)
+		d.offline[brickId] = now
+		return false
+	}
+	return now.Sub(since) >= d.Threshold && !d.evicting[brickId]
+}
+
+// forget drops the state of bricks that no longer exist.
+func (d *BrickFaultDetector) forget(seen map[string]bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for brickId := range d.offline {
+		if !seen[brickId] {
+			delete(d.offline, brickId)
+		}
+	}
+	for brickId := range d.evicting {
+		if !seen[brickId] {
+			delete(d.evicting, brickId)
+		}
+	}
+}
+
+func (d *BrickFaultDetector) replace(fv *faultVolume, brickId string, now time.Time) {
+	since, _ := d.OfflineSince(brickId)
+	if !d.AutoReplace {
+		logger.Warning("brick-fault: action=none brick=%v volume=%v "+
+			"offline_since=%v offline_for=%v",
+			brickId, fv.id, since.Format(time.RFC3339), now.Sub(since))
+		d.lock.Lock()
+		d.evicting[brickId] = true
+		d.lock.Unlock()
+		return
+	}
+
+	// the brick is offline, heal info can not be trusted
+	op := NewBrickEvictOperation(brickId, d.db, api.HealCheckDisable)
+	if err := op.Build(d.ctx); err != nil {
+		logger.LogError("brick-fault: action=evict brick=%v volume=%v "+
+			"error=%q", brickId, fv.id, err.Error())
+		return
+	}
+	d.lock.Lock()
+	d.evicting[brickId] = true
+	d.lock.Unlock()
+	atomic.AddUint64(&d.replaced, 1)
+	logger.Info("brick-fault: action=evict brick=%v volume=%v "+
+		"offline_since=%v offline_for=%v operation=%v",
+		brickId, fv.id, since.Format(time.RFC3339), now.Sub(since), op.Id())
+	d.launch(op)
+}
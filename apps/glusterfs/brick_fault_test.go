@@ -0,0 +1,221 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/tests"
+
+	"github.com/heketi/heketi/executors"
+)
+
+// mockVolumeStatus makes the mock executor report all bricks of the
+// volume as online except for the brick with the id in offline.
+func mockVolumeStatus(t *testing.T, app *App, v *VolumeEntry, offline *string) {
+	status := map[string]executors.BrickStatus{}
+	err := app.db.View(func(tx *bolt.Tx) error {
+		for _, brickId := range v.BricksIds() {
+			b, err := NewBrickEntryFromId(tx, brickId)
+			if err != nil {
+				return err
+			}
+			n, err := NewNodeEntryFromId(tx, b.Info.NodeId)
+			if err != nil {
+				return err
+			}
+			status[brickId] = executors.BrickStatus{
+				Hostname: n.StorageHostName(),
+				Path:     b.Info.Path,
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	app.xo.MockVolumeStatus = func(host string, volume string) (*executors.VolumeStatus, error) {
+		vs := &executors.VolumeStatus{VolumeName: volume}
+		for brickId, bs := range status {
+			bs.Status = 1
+			if brickId == *offline {
+				bs.Status = 0
+			}
+			vs.Bricks = append(vs.Bricks, bs)
+		}
+		// the self-heal daemon is reported alongside the bricks
+		vs.Bricks = append(vs.Bricks, executors.BrickStatus{
+			Hostname: "Self-heal Daemon",
+			Path:     "localhost",
+			Status:   1,
+		})
+		return vs, nil
+	}
+}
+
+func TestBrickFaultDetectorEvict(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	nowfunc := healthNow
+	defer func() { healthNow = nowfunc }()
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		2,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	offline := v.BricksIds()[0]
+	mockVolumeStatus(t, app, v, &offline)
+
+	currTime := time.Now()
+	healthNow = func() time.Time { return currTime }
+
+	d := NewBrickFaultDetector(app.db, app.executor, FaultDetectionConfig{
+		OfflineThresholdMinutes: 5,
+		AutoReplace:             true,
+	})
+	launched := []Operation{}
+	d.launch = func(op Operation) {
+		launched = append(launched, op)
+	}
+
+	err = d.Check()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	since, found := d.OfflineSince(offline)
+	tests.Assert(t, found, "expected brick to be tracked as offline")
+	tests.Assert(t, since.Equal(currTime),
+		"expected since == currTime, got:", since)
+	for _, brickId := range v.BricksIds()[1:] {
+		_, found := d.OfflineSince(brickId)
+		tests.Assert(t, !found, "expected brick to be online:", brickId)
+	}
+
+	// below the threshold nothing happens
+	currTime = currTime.Add(4 * time.Minute)
+	err = d.Check()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(launched) == 0,
+		"expected len(launched) == 0, got:", len(launched))
+
+	// past the threshold the brick is evicted
+	currTime = currTime.Add(2 * time.Minute)
+	err = d.Check()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(launched) == 1,
+		"expected len(launched) == 1, got:", len(launched))
+	tests.Assert(t, d.Replaced() == 1,
+		"expected d.Replaced() == 1, got:", d.Replaced())
+
+	app.db.View(func(tx *bolt.Tx) error {
+		p, err := NewPendingOperationEntryFromId(tx, launched[0].Id())
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, p.Type == OperationBrickEvict,
+			"expected p.Type == OperationBrickEvict, got:", p.Type)
+		return nil
+	})
+
+	// the eviction is only started once
+	currTime = currTime.Add(2 * time.Minute)
+	err = d.Check()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(launched) == 1,
+		"expected len(launched) == 1, got:", len(launched))
+
+	// completing the operation replaces the offline brick
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		return mockVolumeInfoFromDb(app.db, volume)
+	}
+	app.xo.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		return mockHealStatusFromDb(app.db, volume)
+	}
+	err = runOperationAfterBuild(context.Background(), launched[0], app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewBrickEntryFromId(tx, offline)
+		tests.Assert(t, err == ErrNotFound,
+			"expected err == ErrNotFound, got:", err)
+		return nil
+	})
+}
+
+func TestBrickFaultDetectorRecovered(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	nowfunc := healthNow
+	defer func() { healthNow = nowfunc }()
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	offline := v.BricksIds()[1]
+	mockVolumeStatus(t, app, v, &offline)
+
+	currTime := time.Now()
+	healthNow = func() time.Time { return currTime }
+
+	d := NewBrickFaultDetector(app.db, app.executor, FaultDetectionConfig{
+		AutoReplace: true,
+	})
+	tests.Assert(t, d.Threshold == DEFAULT_BRICK_OFFLINE_THRESHOLD,
+		"expected default threshold, got:", d.Threshold)
+	launched := 0
+	d.launch = func(op Operation) {
+		launched++
+	}
+
+	err = d.Check()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, found := d.OfflineSince(offline)
+	tests.Assert(t, found, "expected brick to be tracked as offline")
+
+	// the brick comes back before the threshold
+	currTime = currTime.Add(3 * time.Minute)
+	brickId := offline
+	offline = ""
+	err = d.Check()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, found = d.OfflineSince(brickId)
+	tests.Assert(t, !found, "expected brick to be online")
+
+	// the offline time starts over
+	offline = brickId
+	currTime = currTime.Add(3 * time.Minute)
+	err = d.Check()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	currTime = currTime.Add(3 * time.Minute)
+	err = d.Check()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, launched == 0, "expected launched == 0, got:", launched)
+}
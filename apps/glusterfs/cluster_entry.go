@@ -25,6 +25,9 @@ import (
 
 type ClusterEntry struct {
 	Info api.ClusterInfoResponse
+
+	// the snapshot policy set on the cluster, nil if never set
+	SnapshotPolicy *api.ClusterSnapshotPolicy `json:",omitempty"`
 }
 
 func ClusterList(tx *bolt.Tx) ([]string, error) {
@@ -104,6 +107,37 @@ func (c *ClusterEntry) NewClusterInfoResponse(tx *bolt.Tx) (*api.ClusterInfoResp
 	return info, nil
 }
 
+// TrustedStoragePools returns the trusted storage pools formed by the
+// nodes of the cluster, ordered by pool id. Nodes that were not added
+// to a specific pool are part of the default pool with the empty id.
+func (c *ClusterEntry) TrustedStoragePools(tx *bolt.Tx) ([]api.TrustedStoragePool, error) {
+	peers := map[string][]string{}
+	for _, nodeId := range c.Info.Nodes {
+		node, err := NewNodeEntryFromId(tx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+		peers[node.Info.TspId] = append(peers[node.Info.TspId], nodeId)
+	}
+	if len(peers) == 0 {
+		return nil, nil
+	}
+
+	ids := []string{}
+	for id := range peers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	tsps := []api.TrustedStoragePool{}
+	for _, id := range ids {
+		tsps = append(tsps, api.TrustedStoragePool{
+			Id:        id,
+			PeerNodes: peers[id],
+		})
+	}
+	return tsps, nil
+}
+
 func (c *ClusterEntry) Marshal() ([]byte, error) {
 	var buffer bytes.Buffer
 	enc := gob.NewEncoder(&buffer)
@@ -248,6 +282,20 @@ func (c *ClusterEntry) DeleteBricksWithEmptyPath(tx *bolt.Tx) error {
 // hosts returns a node-to-host mapping for all nodes in the
 // cluster.
 func (c *ClusterEntry) hosts(db wdb.RODB) (nodeHosts, error) {
+	return c.filterHosts(db, func(n *NodeEntry) bool { return true })
+}
+
+// tspHosts returns a node-to-host mapping for the nodes of the given
+// trusted storage pool of the cluster.
+func (c *ClusterEntry) tspHosts(db wdb.RODB, tspId string) (nodeHosts, error) {
+	return c.filterHosts(db, func(n *NodeEntry) bool {
+		return n.Info.TspId == tspId
+	})
+}
+
+func (c *ClusterEntry) filterHosts(db wdb.RODB,
+	accept func(*NodeEntry) bool) (nodeHosts, error) {
+
 	hosts := nodeHosts{}
 	err := db.View(func(tx *bolt.Tx) error {
 		for _, nodeId := range c.Info.Nodes {
@@ -255,7 +303,9 @@ func (c *ClusterEntry) hosts(db wdb.RODB) (nodeHosts, error) {
 			if err != nil {
 				return err
 			}
-			hosts[nodeId] = node.ManageHostName()
+			if accept(node) {
+				hosts[nodeId] = node.ManageHostName()
+			}
 		}
 		return nil
 	})
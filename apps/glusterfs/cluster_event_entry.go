@@ -0,0 +1,201 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/lpabon/godbc"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/idgen"
+)
+
+const (
+	BOLTDB_BUCKET_CLUSTER_EVENT = "CLUSTER_EVENT"
+
+	// number of events returned if the request sets no limit
+	DEFAULT_CLUSTER_EVENT_LIMIT = 100
+)
+
+var (
+	clusterEventTimestamp = func() int64 { return time.Now().Unix() }
+)
+
+// ClusterEventEntry records a change made to a cluster. The entries
+// form the history of the cluster and are only removed when the
+// cluster is deleted.
+type ClusterEventEntry struct {
+	Info api.ClusterEvent
+	// orders the events of the same second
+	Seq uint64
+}
+
+func ClusterEventList(tx *bolt.Tx) ([]string, error) {
+	list := EntryKeys(tx, BOLTDB_BUCKET_CLUSTER_EVENT)
+	if list == nil {
+		return nil, ErrAccessList
+	}
+	return list, nil
+}
+
+func NewClusterEventEntry() *ClusterEventEntry {
+	return &ClusterEventEntry{}
+}
+
+func NewClusterEventEntryFromId(tx *bolt.Tx, id string) (*ClusterEventEntry, error) {
+	entry := NewClusterEventEntry()
+	err := EntryLoad(tx, entry, id)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// AddClusterEvent appends an event to the history of the cluster.
+// The metadata may be nil.
+func AddClusterEvent(tx *bolt.Tx, clusterId, eventType, actorId,
+	description string, metadata map[string]string) error {
+
+	godbc.Require(clusterId != "")
+	godbc.Require(eventType != "")
+
+	b := tx.Bucket([]byte(BOLTDB_BUCKET_CLUSTER_EVENT))
+	if b == nil {
+		return ErrDbAccess
+	}
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	entry := NewClusterEventEntry()
+	entry.Info = api.ClusterEvent{
+		Id:          idgen.GenUUID(),
+		ClusterId:   clusterId,
+		Timestamp:   clusterEventTimestamp(),
+		EventType:   eventType,
+		ActorId:     actorId,
+		Description: description,
+		Metadata:    metadata,
+	}
+	entry.Seq = seq
+	return entry.Save(tx)
+}
+
+// ClusterEvents returns the events of the cluster that happened at
+// or after the given time, oldest first. At most limit events are
+// returned.
+func ClusterEvents(tx *bolt.Tx, clusterId string,
+	since int64, limit int) ([]*ClusterEventEntry, error) {
+
+	ids, err := ClusterEventList(tx)
+	if err != nil {
+		return nil, err
+	}
+	events := []*ClusterEventEntry{}
+	for _, id := range ids {
+		entry, err := NewClusterEventEntryFromId(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Info.ClusterId == clusterId && entry.Info.Timestamp >= since {
+			events = append(events, entry)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Info.Timestamp != events[j].Info.Timestamp {
+			return events[i].Info.Timestamp < events[j].Info.Timestamp
+		}
+		return events[i].Seq < events[j].Seq
+	})
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// DeleteClusterEvents removes the history of the cluster.
+func DeleteClusterEvents(tx *bolt.Tx, clusterId string) error {
+	ids, err := ClusterEventList(tx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		entry, err := NewClusterEventEntryFromId(tx, id)
+		if err != nil {
+			return err
+		}
+		if entry.Info.ClusterId != clusterId {
+			continue
+		}
+		if err := entry.Delete(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func clusterFlagsMetadata(c *ClusterEntry) map[string]string {
+	return map[string]string{
+		"file":  strconv.FormatBool(c.Info.File),
+		"block": strconv.FormatBool(c.Info.Block),
+	}
+}
+
+func nodeEventMetadata(n *NodeEntry) map[string]string {
+	return map[string]string{
+		"node_id": n.Info.Id,
+		"manage":  n.ManageHostName(),
+		"storage": n.StorageHostName(),
+		"zone":    strconv.Itoa(n.Info.Zone),
+	}
+}
+
+func deviceEventMetadata(d *DeviceEntry) map[string]string {
+	return map[string]string{
+		"node_id":   d.NodeId,
+		"device_id": d.Info.Id,
+		"name":      d.Info.Name,
+	}
+}
+
+func (e *ClusterEventEntry) BucketName() string {
+	return BOLTDB_BUCKET_CLUSTER_EVENT
+}
+
+func (e *ClusterEventEntry) Save(tx *bolt.Tx) error {
+	godbc.Require(tx != nil)
+	godbc.Require(len(e.Info.Id) > 0)
+
+	return EntrySave(tx, e, e.Info.Id)
+}
+
+func (e *ClusterEventEntry) Delete(tx *bolt.Tx) error {
+	return EntryDelete(tx, e, e.Info.Id)
+}
+
+func (e *ClusterEventEntry) Marshal() ([]byte, error) {
+	var buffer bytes.Buffer
+	enc := gob.NewEncoder(&buffer)
+	err := enc.Encode(*e)
+
+	return buffer.Bytes(), err
+}
+
+func (e *ClusterEventEntry) Unmarshal(buffer []byte) error {
+	dec := gob.NewDecoder(bytes.NewReader(buffer))
+	return dec.Decode(e)
+}
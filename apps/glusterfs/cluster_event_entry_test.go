@@ -0,0 +1,169 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"net/http/httptest"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/tests"
+)
+
+func TestClusterEvents(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	now := int64(1000)
+	defer func(f func() int64) { clusterEventTimestamp = f }(clusterEventTimestamp)
+	clusterEventTimestamp = func() int64 { return now }
+
+	err := app.db.Update(func(tx *bolt.Tx) error {
+		// two events in the same second keep their order
+		for _, e := range []string{"a", "b"} {
+			err := AddClusterEvent(tx, "c1", api.ClusterEventNodeAdded, "admin", e, nil)
+			if err != nil {
+				return err
+			}
+		}
+		now = 2000
+		err := AddClusterEvent(tx, "c2", api.ClusterEventNodeAdded, "", "x", nil)
+		if err != nil {
+			return err
+		}
+		return AddClusterEvent(tx, "c1", api.ClusterEventDeviceAdded, "", "c",
+			map[string]string{"device_id": "d1"})
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	descriptions := func(events []*ClusterEventEntry) []string {
+		d := []string{}
+		for _, e := range events {
+			d = append(d, e.Info.Description)
+		}
+		return d
+	}
+
+	app.db.View(func(tx *bolt.Tx) error {
+		events, err := ClusterEvents(tx, "c1", 0, 10)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(events) == 3, "expected 3 events, got:", len(events))
+		d := descriptions(events)
+		tests.Assert(t, d[0] == "a" && d[1] == "b" && d[2] == "c",
+			"unexpected order:", d)
+		tests.Assert(t, events[0].Info.ActorId == "admin", events[0].Info)
+		tests.Assert(t, events[2].Info.Timestamp == 2000, events[2].Info)
+		tests.Assert(t, events[2].Info.Metadata["device_id"] == "d1", events[2].Info)
+
+		events, err = ClusterEvents(tx, "c1", 1500, 10)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		d = descriptions(events)
+		tests.Assert(t, len(d) == 1 && d[0] == "c", "unexpected events:", d)
+
+		events, err = ClusterEvents(tx, "c1", 0, 2)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		d = descriptions(events)
+		tests.Assert(t, len(d) == 2 && d[0] == "a" && d[1] == "b",
+			"unexpected events:", d)
+		return nil
+	})
+
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		return DeleteClusterEvents(tx, "c1")
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	app.db.View(func(tx *bolt.Tx) error {
+		events, err := ClusterEvents(tx, "c1", 0, 10)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(events) == 0, "expected no events, got:", len(events))
+		events, err = ClusterEvents(tx, "c2", 0, 10)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(events) == 1, "expected 1 event, got:", len(events))
+		return nil
+	})
+}
+
+func TestClusterEventsRecorded(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	c := client.NewClientNoAuth(ts.URL)
+	cluster, err := c.ClusterCreate(&api.ClusterCreateRequest{
+		ClusterFlags: api.ClusterFlags{Block: true, File: true},
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	nodeReq := &api.NodeAddRequest{
+		Zone:      1,
+		ClusterId: cluster.Id,
+	}
+	nodeReq.Hostnames.Manage = sort.StringSlice{"manage.host"}
+	nodeReq.Hostnames.Storage = sort.StringSlice{"storage.host"}
+	node, err := c.NodeAdd(nodeReq)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	deviceReq := &api.DeviceAddRequest{}
+	deviceReq.Name = "/dev/fake1"
+	deviceReq.NodeId = node.Id
+	err = c.DeviceAdd(deviceReq)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	resp, err := c.ClusterEvents(cluster.Id, 0, 0)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	events := resp.Events
+	tests.Assert(t, len(events) == 3, "expected 3 events, got:", events)
+	tests.Assert(t, events[0].EventType == api.ClusterEventClusterCreated,
+		"unexpected event:", events[0])
+
+	// the node and the device
+	tests.Assert(t, events[1].EventType == api.ClusterEventNodeAdded,
+		"unexpected event:", events[1])
+	tests.Assert(t, events[1].ClusterId == cluster.Id, events[1])
+	tests.Assert(t, events[1].Metadata["node_id"] == node.Id, events[1])
+	tests.Assert(t, events[1].Metadata["manage"] == "manage.host", events[1])
+	tests.Assert(t, events[2].EventType == api.ClusterEventDeviceAdded,
+		"unexpected event:", events[2])
+	tests.Assert(t, events[2].Metadata["node_id"] == node.Id, events[2])
+	tests.Assert(t, events[2].Metadata["name"] == "/dev/fake1", events[2])
+	tests.Assert(t, events[2].Timestamp >= events[1].Timestamp, events)
+
+	resp, err = c.ClusterEvents(cluster.Id, 0, 1)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(resp.Events) == 1, "expected 1 event, got:", resp.Events)
+
+	// events of other clusters are not returned
+	other, err := c.ClusterCreate(&api.ClusterCreateRequest{})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	resp, err = c.ClusterEvents(other.Id, 0, 0)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(resp.Events) == 1, "expected 1 event, got:", resp.Events)
+
+	_, err = c.ClusterEvents("0000000000000000000000000000000a", 0, 0)
+	tests.Assert(t, err != nil, "expected err != nil")
+}
@@ -0,0 +1,271 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"sort"
+
+	"github.com/boltdb/bolt"
+)
+
+// ClusterSelector orders the clusters a new volume may be placed on.
+// The bricks of the volume are allocated in the first cluster of the
+// result that has room for them.
+type ClusterSelector interface {
+	SelectClusters(tx *bolt.Tx, v *VolumeEntry, clusters []string) ([]string, error)
+}
+
+// clusterStats holds what a cluster selector knows about a cluster.
+// Sizes are in KiB.
+type clusterStats struct {
+	Id      string
+	Free    uint64
+	Nodes   int
+	Zones   int
+	Pending int
+}
+
+// WeightedClusterSelector scores the clusters that can hold the volume
+// by a weighted sum of factors, each scaled to [0, 1] across the
+// clusters:
+//
+// Fit: the less free space the volume leaves behind the better (best
+// fit), so that large free areas are kept for large volumes.
+// Nodes: the more online nodes the better for the brick distribution.
+// Load: the fewer pending operations the better.
+// Zones: the more zones the better for availability.
+//
+// Clusters that cannot hold the volume come last, in the given order.
+type WeightedClusterSelector struct {
+	FitWeight  float64
+	NodeWeight float64
+	LoadWeight float64
+	ZoneWeight float64
+}
+
+func NewWeightedClusterSelector() *WeightedClusterSelector {
+	return &WeightedClusterSelector{
+		FitWeight:  0.4,
+		NodeWeight: 0.2,
+		LoadWeight: 0.2,
+		ZoneWeight: 0.2,
+	}
+}
+
+func (s *WeightedClusterSelector) SelectClusters(tx *bolt.Tx,
+	v *VolumeEntry, clusters []string) ([]string, error) {
+
+	need, err := volumeRawSize(v)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := pendingOperationsByCluster(tx)
+	if err != nil {
+		return nil, err
+	}
+	stats := []*clusterStats{}
+	for _, id := range clusters {
+		cs, err := newClusterStats(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		cs.Pending = pending[id]
+		stats = append(stats, cs)
+	}
+	return s.order(stats, need), nil
+}
+
+// order returns the ids of the clusters, the best scoring first.
+func (s *WeightedClusterSelector) order(stats []*clusterStats, need uint64) []string {
+	fits := []*clusterStats{}
+	rest := []string{}
+	for _, cs := range stats {
+		if cs.Free >= need {
+			fits = append(fits, cs)
+		} else {
+			rest = append(rest, cs.Id)
+		}
+	}
+
+	fit := scaleFactor(fits, func(cs *clusterStats) float64 {
+		return -float64(cs.Free - need)
+	})
+	nodes := scaleFactor(fits, func(cs *clusterStats) float64 {
+		return float64(cs.Nodes)
+	})
+	load := scaleFactor(fits, func(cs *clusterStats) float64 {
+		return -float64(cs.Pending)
+	})
+	zones := scaleFactor(fits, func(cs *clusterStats) float64 {
+		return float64(cs.Zones)
+	})
+	score := map[string]float64{}
+	for i, cs := range fits {
+		score[cs.Id] = s.FitWeight*fit[i] + s.NodeWeight*nodes[i] +
+			s.LoadWeight*load[i] + s.ZoneWeight*zones[i]
+		logger.Debug("cluster %v: free %v KiB, %v nodes, %v zones, "+
+			"%v pending operations, score %.3f",
+			cs.Id, cs.Free, cs.Nodes, cs.Zones, cs.Pending, score[cs.Id])
+	}
+	sort.SliceStable(fits, func(i, j int) bool {
+		return score[fits[i].Id] > score[fits[j].Id]
+	})
+
+	ordered := []string{}
+	for _, cs := range fits {
+		ordered = append(ordered, cs.Id)
+	}
+	return append(ordered, rest...)
+}
+
+// scaleFactor returns the value of f for each cluster scaled to [0, 1],
+// where 1 is the highest value. All clusters get 1 if the values are
+// the same.
+func scaleFactor(stats []*clusterStats, f func(*clusterStats) float64) []float64 {
+	values := make([]float64, len(stats))
+	for i, cs := range stats {
+		values[i] = f(cs)
+	}
+	if len(values) == 0 {
+		return values
+	}
+	min, max := values[0], values[0]
+	for _, x := range values {
+		if x < min {
+			min = x
+		}
+		if x > max {
+			max = x
+		}
+	}
+	for i, x := range values {
+		if max == min {
+			values[i] = 1
+		} else {
+			values[i] = (x - min) / (max - min)
+		}
+	}
+	return values
+}
+
+// volumeRawSize returns an estimate of the device space in KiB taken
+// by the bricks of the volume, at the largest brick size.
+func volumeRawSize(v *VolumeEntry) (uint64, error) {
+	sets, brickSize, err := v.Durability.BrickSizeGenerator(
+		uint64(v.Info.Size) * GB)()
+	if err != nil {
+		return 0, err
+	}
+	bricks := uint64(sets * v.Durability.BricksInSet())
+	factor := float64(v.Info.Snapshot.Factor)
+	if factor < 1 {
+		factor = 1
+	}
+	return uint64(float64(bricks*brickSize) * factor), nil
+}
+
+// newClusterStats sums up the online nodes and devices of the cluster.
+func newClusterStats(tx *bolt.Tx, id string) (*clusterStats, error) {
+	c, err := NewClusterEntryFromId(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	cs := &clusterStats{Id: id}
+	zones := map[int]bool{}
+	for _, nodeId := range c.Info.Nodes {
+		n, err := NewNodeEntryFromId(tx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+		if !n.isOnline() {
+			continue
+		}
+		cs.Nodes++
+		zones[n.Info.Zone] = true
+		for _, deviceId := range n.Devices {
+			d, err := NewDeviceEntryFromId(tx, deviceId)
+			if err != nil {
+				return nil, err
+			}
+			if d.isOnline() {
+				cs.Free += d.Info.Storage.Free
+			}
+		}
+	}
+	cs.Zones = len(zones)
+	return cs, nil
+}
+
+// pendingOperationsByCluster returns the number of pending operations
+// changing the volumes of each cluster.
+func pendingOperationsByCluster(tx *bolt.Tx) (map[string]int, error) {
+	counts := map[string]int{}
+	ids, err := PendingOperationList(tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, opId := range ids {
+		op, err := NewPendingOperationEntryFromId(tx, opId)
+		if err != nil {
+			return nil, err
+		}
+		seen := map[string]bool{}
+		for _, a := range op.Actions {
+			clusterId, err := pendingActionCluster(tx, a)
+			if err != nil {
+				return nil, err
+			}
+			if clusterId != "" && !seen[clusterId] {
+				seen[clusterId] = true
+				counts[clusterId]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+// pendingActionCluster returns the cluster of the item changed by the
+// action, or an empty string if the action does not change a volume
+// or its item is gone.
+func pendingActionCluster(tx *bolt.Tx, a PendingOperationAction) (string, error) {
+	switch a.Change {
+	case OpAddVolume, OpDeleteVolume, OpExpandVolume, OpAddVolumeClone:
+		v, err := NewVolumeEntryFromId(tx, a.Id)
+		if err == ErrNotFound {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+		return v.Info.Cluster, nil
+	case OpAddBrick, OpDeleteBrick:
+		b, err := NewBrickEntryFromId(tx, a.Id)
+		if err == ErrNotFound {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+		n, err := NewNodeEntryFromId(tx, b.Info.NodeId)
+		if err == ErrNotFound {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+		return n.Info.ClusterId, nil
+	case OpAddBlockVolume, OpDeleteBlockVolume, OpExpandBlockVolume:
+		bv, err := NewBlockVolumeEntryFromId(tx, a.Id)
+		if err == ErrNotFound {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+		return bv.Info.Cluster, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,241 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/tests"
+
+	"github.com/heketi/heketi/pkg/idgen"
+)
+
+// sampleClusterStats returns clusters that are alike in every factor.
+func sampleClusterStats() []*clusterStats {
+	stats := []*clusterStats{}
+	for _, id := range []string{"c1", "c2", "c3"} {
+		stats = append(stats, &clusterStats{
+			Id:    id,
+			Free:  100,
+			Nodes: 3,
+			Zones: 1,
+		})
+	}
+	return stats
+}
+
+func TestWeightedClusterSelectorTie(t *testing.T) {
+	s := NewWeightedClusterSelector()
+	ordered := s.order(sampleClusterStats(), 10)
+	tests.Assert(t, reflect.DeepEqual(ordered, []string{"c1", "c2", "c3"}),
+		"expected the given order, got:", ordered)
+}
+
+func TestWeightedClusterSelectorFit(t *testing.T) {
+	s := NewWeightedClusterSelector()
+	stats := sampleClusterStats()
+	stats[0].Free = 100
+	stats[1].Free = 50
+	stats[2].Free = 30
+
+	// the cluster left with the least free space comes first, the
+	// cluster that is too small last
+	ordered := s.order(stats, 40)
+	tests.Assert(t, reflect.DeepEqual(ordered, []string{"c2", "c1", "c3"}),
+		"unexpected order:", ordered)
+
+	ordered = s.order(stats, 20)
+	tests.Assert(t, reflect.DeepEqual(ordered, []string{"c3", "c2", "c1"}),
+		"unexpected order:", ordered)
+
+	// nothing fits
+	ordered = s.order(stats, 200)
+	tests.Assert(t, reflect.DeepEqual(ordered, []string{"c1", "c2", "c3"}),
+		"unexpected order:", ordered)
+}
+
+func TestWeightedClusterSelectorNodes(t *testing.T) {
+	s := NewWeightedClusterSelector()
+	stats := sampleClusterStats()
+	stats[0].Nodes = 3
+	stats[1].Nodes = 6
+	stats[2].Nodes = 4
+
+	ordered := s.order(stats, 10)
+	tests.Assert(t, reflect.DeepEqual(ordered, []string{"c2", "c3", "c1"}),
+		"unexpected order:", ordered)
+}
+
+func TestWeightedClusterSelectorLoad(t *testing.T) {
+	s := NewWeightedClusterSelector()
+	stats := sampleClusterStats()
+	stats[0].Pending = 5
+	stats[1].Pending = 0
+	stats[2].Pending = 2
+
+	ordered := s.order(stats, 10)
+	tests.Assert(t, reflect.DeepEqual(ordered, []string{"c2", "c3", "c1"}),
+		"unexpected order:", ordered)
+}
+
+func TestWeightedClusterSelectorZones(t *testing.T) {
+	s := NewWeightedClusterSelector()
+	stats := sampleClusterStats()
+	stats[0].Zones = 1
+	stats[1].Zones = 3
+	stats[2].Zones = 2
+
+	ordered := s.order(stats, 10)
+	tests.Assert(t, reflect.DeepEqual(ordered, []string{"c2", "c3", "c1"}),
+		"unexpected order:", ordered)
+}
+
+func TestWeightedClusterSelectorWeights(t *testing.T) {
+	stats := sampleClusterStats()[:2]
+	stats[0].Free = 50
+	stats[1].Zones = 3
+
+	// c1 is the better fit, c2 has more zones
+	s := NewWeightedClusterSelector()
+	ordered := s.order(stats, 10)
+	tests.Assert(t, reflect.DeepEqual(ordered, []string{"c1", "c2"}),
+		"unexpected order:", ordered)
+
+	s.ZoneWeight = 1
+	ordered = s.order(stats, 10)
+	tests.Assert(t, reflect.DeepEqual(ordered, []string{"c2", "c1"}),
+		"unexpected order:", ordered)
+}
+
+func TestWeightedClusterSelectorFromDb(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopologyWithZones(app,
+		3,    // clusters
+		1,    // zones_per_cluster
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var clusters []string
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		clusters, err = ClusterList(tx)
+		if err != nil {
+			return err
+		}
+		// the second cluster spans three zones
+		c, err := NewClusterEntryFromId(tx, clusters[1])
+		if err != nil {
+			return err
+		}
+		for i, nodeId := range c.Info.Nodes {
+			n, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			n.Info.Zone = i + 1
+			if err := n.Save(tx); err != nil {
+				return err
+			}
+		}
+		// the first cluster has a volume being created
+		v := createSampleReplicaVolumeEntry(10, 3)
+		v.Info.Cluster = clusters[0]
+		op := NewPendingOperationEntry(idgen.GenUUID())
+		op.RecordAddVolume(v)
+		if err := v.Save(tx); err != nil {
+			return err
+		}
+		return op.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	var ordered []string
+	err = app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		ordered, err = NewWeightedClusterSelector().SelectClusters(
+			tx, v, clusters)
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	expected := []string{clusters[1], clusters[2], clusters[0]}
+	tests.Assert(t, reflect.DeepEqual(ordered, expected),
+		"expected", expected, "got:", ordered)
+}
+
+func TestVolumeCreateBestFitCluster(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		2,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// the devices of the second cluster have less, but enough, room
+	var small string
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		clusters, err := ClusterList(tx)
+		if err != nil {
+			return err
+		}
+		small = clusters[1]
+		c, err := NewClusterEntryFromId(tx, small)
+		if err != nil {
+			return err
+		}
+		for _, nodeId := range c.Info.Nodes {
+			n, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			for _, deviceId := range n.Devices {
+				d, err := NewDeviceEntryFromId(tx, deviceId)
+				if err != nil {
+					return err
+				}
+				d.StorageSet(TB, 400*GB, TB-400*GB)
+				if err := d.Save(tx); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(200, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, v.Info.Cluster == small,
+		"expected volume in cluster", small, "got:", v.Info.Cluster)
+
+	// too large for the second cluster
+	v = createSampleReplicaVolumeEntry(500, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, v.Info.Cluster != small,
+		"expected volume not in cluster", small)
+}
@@ -0,0 +1,266 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+const (
+	glusterVolumeStarted = "Started"
+)
+
+// driftVolume is the expected state of a volume as recorded in the db.
+type driftVolume struct {
+	id      string
+	name    string
+	cluster string
+	tspId   string
+	bricks  int
+	options map[string]string
+}
+
+// driftNode is a node and the storage hostnames of the other nodes
+// of its cluster and trusted storage pool that it is expected to be
+// peered with.
+type driftNode struct {
+	id    string
+	host  string
+	peers []string
+}
+
+// volumeOptionsMap converts the volume options as stored in the db
+// ("<key> <value>") to a map. Options that gluster does not report
+// back as set, such as option groups, are skipped.
+func volumeOptionsMap(options []string) map[string]string {
+	m := map[string]string{}
+	for _, o := range options {
+		f := strings.Fields(o)
+		if len(f) < 2 || f[0] == "group" {
+			continue
+		}
+		m[f[0]] = strings.Join(f[1:], " ")
+	}
+	return m
+}
+
+// normalizeOptionValue maps the different spellings gluster accepts
+// for boolean options to a single one.
+func normalizeOptionValue(v string) string {
+	switch strings.ToLower(v) {
+	case "on", "yes", "true", "enable", "1":
+		return "on"
+	case "off", "no", "false", "disable", "0":
+		return "off"
+	}
+	return v
+}
+
+func driftState(db wdb.RODB) ([]*driftVolume, []*driftNode, error) {
+	vols := []*driftVolume{}
+	nodes := []*driftNode{}
+	err := db.View(func(tx *bolt.Tx) error {
+		vl, err := ListCompleteVolumes(tx)
+		if err != nil {
+			return err
+		}
+		for _, id := range vl {
+			v, err := NewVolumeEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			vols = append(vols, &driftVolume{
+				id:      v.Info.Id,
+				name:    v.Info.Name,
+				cluster: v.Info.Cluster,
+				tspId:   v.Info.TspId,
+				bricks:  len(v.Bricks),
+				options: volumeOptionsMap(v.GlusterVolumeOptions),
+			})
+		}
+
+		cl, err := ClusterList(tx)
+		if err != nil {
+			return err
+		}
+		for _, clusterId := range cl {
+			c, err := NewClusterEntryFromId(tx, clusterId)
+			if err != nil {
+				return err
+			}
+			cnodes := []*NodeEntry{}
+			for _, nodeId := range c.Info.Nodes {
+				n, err := NewNodeEntryFromId(tx, nodeId)
+				if err != nil {
+					return err
+				}
+				if n.isOnline() {
+					cnodes = append(cnodes, n)
+				}
+			}
+			for _, n := range cnodes {
+				dn := &driftNode{
+					id:    n.Info.Id,
+					host:  n.ManageHostName(),
+					peers: []string{},
+				}
+				for _, p := range cnodes {
+					if p.Info.Id != n.Info.Id && p.Info.TspId == n.Info.TspId {
+						dn.peers = append(dn.peers, p.StorageHostName())
+					}
+				}
+				sort.Strings(dn.peers)
+				nodes = append(nodes, dn)
+			}
+		}
+		return nil
+	})
+	return vols, nodes, err
+}
+
+func checkVolumeDrift(v *driftVolume, info *executors.Volume) []api.VolumeDrift {
+	drifts := []api.VolumeDrift{}
+	add := func(field, expected, actual string) {
+		drifts = append(drifts, api.VolumeDrift{
+			VolumeId: v.id,
+			Field:    field,
+			Expected: expected,
+			Actual:   actual,
+		})
+	}
+
+	if info.StatusStr != glusterVolumeStarted {
+		add("status", glusterVolumeStarted, info.StatusStr)
+	}
+	if info.BrickCount != v.bricks {
+		add("brick_count", strconv.Itoa(v.bricks), strconv.Itoa(info.BrickCount))
+	}
+
+	live := map[string]string{}
+	for _, o := range info.Options.OptionList {
+		live[o.Name] = o.Value
+	}
+	keys := []string{}
+	for k := range v.options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		expected := v.options[k]
+		actual, found := live[k]
+		if !found {
+			add("options."+k, expected, "")
+		} else if normalizeOptionValue(actual) != normalizeOptionValue(expected) {
+			add("options."+k, expected, actual)
+		}
+	}
+	return drifts
+}
+
+func checkPeerDrift(n *driftNode, peers []executors.Peer) []api.PeerDrift {
+	drifts := []api.PeerDrift{}
+	add := func(peer, field, expected, actual string) {
+		drifts = append(drifts, api.PeerDrift{
+			NodeId:   n.id,
+			Peer:     peer,
+			Field:    field,
+			Expected: expected,
+			Actual:   actual,
+		})
+	}
+
+	known := map[int]bool{}
+	for _, h := range n.peers {
+		found := false
+		for i, p := range peers {
+			if !p.HasHostname(h) {
+				continue
+			}
+			found = true
+			known[i] = true
+			if !p.Online() {
+				add(h, "connected", "true", "false")
+			}
+			break
+		}
+		if !found {
+			add(h, "peer", "present", "absent")
+		}
+	}
+	for i, p := range peers {
+		if !known[i] {
+			add(p.Hostname, "peer", "absent", "present")
+		}
+	}
+	return drifts
+}
+
+// CheckConfigDrift compares the configuration of the volumes and the
+// trusted storage pools in gluster with what is recorded in the db.
+// Volumes or nodes that can not be queried are reported as drifted.
+func CheckConfigDrift(db wdb.RODB, e executors.Executor) (*api.DriftReport, error) {
+	vols, nodes, err := driftState(db)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &api.DriftReport{
+		VolumeDrifts: []api.VolumeDrift{},
+		PeerDrifts:   []api.PeerDrift{},
+	}
+	for _, v := range vols {
+		host, err := GetVerifiedTspManageHostname(db, e, v.cluster, v.tspId)
+		if err != nil {
+			report.VolumeDrifts = append(report.VolumeDrifts, api.VolumeDrift{
+				VolumeId: v.id,
+				Field:    "reachable",
+				Expected: "true",
+				Actual:   err.Error(),
+			})
+			continue
+		}
+		info, err := e.VolumeInfo(host, v.name)
+		if err != nil {
+			report.VolumeDrifts = append(report.VolumeDrifts, api.VolumeDrift{
+				VolumeId: v.id,
+				Field:    "volume",
+				Expected: "present",
+				Actual:   err.Error(),
+			})
+			continue
+		}
+		report.VolumeDrifts = append(report.VolumeDrifts,
+			checkVolumeDrift(v, info)...)
+	}
+
+	for _, n := range nodes {
+		peers, err := e.PeerStatus(n.host)
+		if err != nil {
+			report.PeerDrifts = append(report.PeerDrifts, api.PeerDrift{
+				NodeId:   n.id,
+				Field:    "reachable",
+				Expected: "true",
+				Actual:   err.Error(),
+			})
+			continue
+		}
+		report.PeerDrifts = append(report.PeerDrifts,
+			checkPeerDrift(n, peers)...)
+	}
+	return report, nil
+}
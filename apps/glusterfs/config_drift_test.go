@@ -0,0 +1,213 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// storageHosts maps the manage hostnames of all nodes in the db to
+// their storage hostnames.
+func storageHosts(t *testing.T, app *App) map[string]string {
+	hosts := map[string]string{}
+	err := app.db.View(func(tx *bolt.Tx) error {
+		nl, err := NodeList(tx)
+		if err != nil {
+			return err
+		}
+		for _, nodeId := range nl {
+			n, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			hosts[n.ManageHostName()] = n.StorageHostName()
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	return hosts
+}
+
+// mockConnectedPeers makes every node report all other nodes as
+// connected peers.
+func mockConnectedPeers(t *testing.T, app *App) {
+	hosts := storageHosts(t, app)
+	app.xo.MockPeerStatus = func(host string) ([]executors.Peer, error) {
+		peers := []executors.Peer{}
+		for _, h := range hosts {
+			if h != hosts[host] {
+				peers = append(peers, executors.Peer{
+					Hostname:  h,
+					Connected: 1,
+				})
+			}
+		}
+		return peers, nil
+	}
+}
+
+func TestConfigDriftCheck(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+	req.GlusterVolumeOptions = []string{
+		"performance.readdir-ahead on",
+		"server.tcp-user-timeout 42",
+		"group virt",
+	}
+	v := NewVolumeEntryFromRequest(req)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	live := map[string]string{
+		"performance.readdir-ahead": "enable",
+		"server.tcp-user-timeout":   "42",
+	}
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		vinfo := &executors.Volume{
+			VolumeName: volume,
+			StatusStr:  "Started",
+			BrickCount: 3,
+		}
+		for k, v := range live {
+			vinfo.Options.OptionList = append(vinfo.Options.OptionList,
+				executors.Option{Name: k, Value: v})
+		}
+		return vinfo, nil
+	}
+	mockConnectedPeers(t, app)
+
+	// the live configuration matches
+	c := client.NewClientNoAuth(ts.URL)
+	report, err := c.ConfigDriftCheck()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(report.VolumeDrifts) == 0,
+		"expected len(report.VolumeDrifts) == 0, got:", report.VolumeDrifts)
+	tests.Assert(t, len(report.PeerDrifts) == 0,
+		"expected len(report.PeerDrifts) == 0, got:", report.PeerDrifts)
+
+	// someone changed an option by hand
+	live["server.tcp-user-timeout"] = "10"
+	report, err = c.ConfigDriftCheck()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(report.VolumeDrifts) == 1,
+		"expected len(report.VolumeDrifts) == 1, got:", report.VolumeDrifts)
+	d := report.VolumeDrifts[0]
+	tests.Assert(t, d.VolumeId == v.Info.Id,
+		"expected d.VolumeId == v.Info.Id, got:", d.VolumeId)
+	tests.Assert(t, d.Field == "options.server.tcp-user-timeout",
+		"expected d.Field == options.server.tcp-user-timeout, got:", d.Field)
+	tests.Assert(t, d.Expected == "42", "expected d.Expected == 42, got:",
+		d.Expected)
+	tests.Assert(t, d.Actual == "10", "expected d.Actual == 10, got:",
+		d.Actual)
+
+	// and reset another one
+	delete(live, "performance.readdir-ahead")
+	report, err = c.ConfigDriftCheck()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(report.VolumeDrifts) == 2,
+		"expected len(report.VolumeDrifts) == 2, got:", report.VolumeDrifts)
+	d = report.VolumeDrifts[0]
+	tests.Assert(t, d.Field == "options.performance.readdir-ahead",
+		"expected d.Field == options.performance.readdir-ahead, got:", d.Field)
+	tests.Assert(t, d.Actual == "", "expected d.Actual to be empty, got:",
+		d.Actual)
+}
+
+func TestConfigDriftCheckPeers(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var node *NodeEntry
+	app.db.View(func(tx *bolt.Tx) error {
+		nl, err := NodeList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		node, err = NewNodeEntryFromId(tx, nl[0])
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		return nil
+	})
+
+	// the first node lost one peer and gained an unknown one
+	mockConnectedPeers(t, app)
+	peers := app.xo.MockPeerStatus
+	app.xo.MockPeerStatus = func(host string) ([]executors.Peer, error) {
+		p, err := peers(host)
+		if host != node.ManageHostName() {
+			return p, err
+		}
+		p[0].Connected = 0
+		p[1] = executors.Peer{Hostname: "stranger", Connected: 1}
+		return p, err
+	}
+
+	report, err := CheckConfigDrift(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(report.PeerDrifts) == 3,
+		"expected len(report.PeerDrifts) == 3, got:", report.PeerDrifts)
+	fields := map[string]api.PeerDrift{}
+	for _, d := range report.PeerDrifts {
+		tests.Assert(t, d.NodeId == node.Info.Id,
+			"expected d.NodeId == node.Info.Id, got:", d.NodeId)
+		fields[d.Peer+"/"+d.Field] = d
+	}
+	tests.Assert(t, fields["stranger/peer"].Expected == "absent",
+		"expected unknown peer to be reported, got:", report.PeerDrifts)
+	for _, h := range storageHosts(t, app) {
+		if h == node.StorageHostName() {
+			continue
+		}
+		_, disconnected := fields[h+"/connected"]
+		_, missing := fields[h+"/peer"]
+		tests.Assert(t, disconnected || missing,
+			"expected drift for peer", h, "got:", report.PeerDrifts)
+	}
+}
@@ -30,6 +30,8 @@ func dbDumpInternal(db wdb.DB) (Db, error) {
 	blockvolEntryList := make(map[string]BlockVolumeEntry, 0)
 	dbattributeEntryList := make(map[string]DbAttributeEntry, 0)
 	pendingOpEntryList := make(map[string]PendingOperationEntry, 0)
+	lvmSnapshotEntryList := make(map[string]LvmSnapshotEntry, 0)
+	sshKeyEntryList := make(map[string]SshKeyEntry, 0)
 
 	err := db.View(func(tx *bolt.Tx) error {
 
@@ -189,6 +191,41 @@ func dbDumpInternal(db wdb.DB) (Db, error) {
 			}
 		}
 
+		if b := tx.Bucket([]byte(BOLTDB_BUCKET_LVMSNAPSHOT)); b == nil {
+			logger.Warning("unable to find lvm snapshot bucket... skipping")
+		} else {
+			snapshots, err := LvmSnapshotList(tx)
+			if err != nil {
+				return err
+			}
+
+			for _, id := range snapshots {
+				entry, err := NewLvmSnapshotEntryFromId(tx, id)
+				if err != nil {
+					return err
+				}
+				lvmSnapshotEntryList[id] = *entry
+			}
+		}
+
+		// the keys are dumped as stored, encrypted
+		if b := tx.Bucket([]byte(BOLTDB_BUCKET_SSHKEY)); b == nil {
+			logger.Warning("unable to find ssh key bucket... skipping")
+		} else {
+			keys, err := SshKeyList(tx)
+			if err != nil {
+				return err
+			}
+
+			for _, id := range keys {
+				entry, err := NewSshKeyEntryFromId(tx, id)
+				if err != nil {
+					return err
+				}
+				sshKeyEntryList[id] = *entry
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -203,6 +240,8 @@ func dbDumpInternal(db wdb.DB) (Db, error) {
 	dump.BlockVolumes = blockvolEntryList
 	dump.DbAttributes = dbattributeEntryList
 	dump.PendingOperations = pendingOpEntryList
+	dump.LvmSnapshots = lvmSnapshotEntryList
+	dump.SshKeys = sshKeyEntryList
 
 	return dump, nil
 }
@@ -359,6 +398,20 @@ func DbCreate(jsonfile string, dbfile string) error {
 				return fmt.Errorf("Could not save pending operation bucket: %v", err.Error())
 			}
 		}
+		for _, snapshot := range dump.LvmSnapshots {
+			logger.Debug("adding lvm snapshot entry %v", snapshot.Info.Id)
+			err := snapshot.Save(tx)
+			if err != nil {
+				return fmt.Errorf("Could not save lvm snapshot bucket: %v", err.Error())
+			}
+		}
+		for _, key := range dump.SshKeys {
+			logger.Debug("adding ssh key entry %v", key.Info.Id)
+			err := key.Save(tx)
+			if err != nil {
+				return fmt.Errorf("Could not save ssh key bucket: %v", err.Error())
+			}
+		}
 		// always record a new generation id on db import as the db contents
 		// were no longer fully under heketi's control
 		logger.Debug("recording new DB generation ID")
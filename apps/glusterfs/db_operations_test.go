@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -280,7 +281,7 @@ func TestDbDumpInternal(t *testing.T) {
 		req.Size = 1
 		v := NewVolumeEntryFromRequest(req)
 		vcr := NewVolumeCreateOperation(v, app.db)
-		err := vcr.Build()
+		err := vcr.Build(context.Background())
 		tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 		db, err := dbDumpInternal(app.db)
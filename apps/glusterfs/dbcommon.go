@@ -32,6 +32,8 @@ type Db struct {
 	BlockVolumes      map[string]BlockVolumeEntry      `json:"blockvolumeentries"`
 	DbAttributes      map[string]DbAttributeEntry      `json:"dbattributeentries"`
 	PendingOperations map[string]PendingOperationEntry `json:"pendingoperations"`
+	LvmSnapshots      map[string]LvmSnapshotEntry      `json:"lvmsnapshotentries,omitempty"`
+	SshKeys           map[string]SshKeyEntry           `json:"sshkeyentries,omitempty"`
 }
 
 //DbEntryCheckResponse ... is summary of check on a db entry.
@@ -117,6 +119,36 @@ func initializeBuckets(tx *bolt.Tx) error {
 		return err
 	}
 
+	_, err = tx.CreateBucketIfNotExists([]byte(BOLTDB_BUCKET_PENDING_OPS_ARCHIVE))
+	if err != nil {
+		logger.LogError("Unable to create pending ops archive bucket in DB")
+		return err
+	}
+
+	_, err = tx.CreateBucketIfNotExists([]byte(BOLTDB_BUCKET_SSHKEY))
+	if err != nil {
+		logger.LogError("Unable to create ssh key bucket in DB")
+		return err
+	}
+
+	_, err = tx.CreateBucketIfNotExists([]byte(BOLTDB_BUCKET_LVMSNAPSHOT))
+	if err != nil {
+		logger.LogError("Unable to create lvm snapshot bucket in DB")
+		return err
+	}
+
+	_, err = tx.CreateBucketIfNotExists([]byte(BOLTDB_BUCKET_CLUSTER_EVENT))
+	if err != nil {
+		logger.LogError("Unable to create cluster event bucket in DB")
+		return err
+	}
+
+	_, err = tx.CreateBucketIfNotExists([]byte(BOLTDB_BUCKET_OPERATION_HISTORY))
+	if err != nil {
+		logger.LogError("Unable to create operation history bucket in DB")
+		return err
+	}
+
 	return nil
 }
 
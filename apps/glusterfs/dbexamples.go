@@ -12,6 +12,7 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -104,7 +105,7 @@ func LeakPendingVolumeCreate(t *testing.T, filename string) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify volumes, bricks, & pending ops exist
@@ -35,6 +35,8 @@ type DeviceEntry struct {
 	Bricks     sort.StringSlice
 	NodeId     string
 	ExtentSize uint64
+	// name of the vg if not named by the default scheme
+	VgName string
 }
 
 func DeviceList(tx *bolt.Tx) ([]string, error) {
@@ -244,6 +246,7 @@ func (d *DeviceEntry) NewInfoResponse(tx *bolt.Tx) (*api.DeviceInfoResponse, err
 	info.PvUUID = d.Info.PvUUID
 	info.Paths = make([]string, len(d.Info.Paths))
 	copy(info.Paths, d.Info.Paths)
+	info.ThinPoolUsedPercent = currentThinPoolUsage()[d.Info.Id]
 
 	// Add each drive information
 	for _, id := range d.Bricks {
@@ -648,6 +651,12 @@ func (d *DeviceEntry) consistencyCheck(db Db) (response DbEntryCheckResponse) {
 			aggregateBricksSize += brickEntry.TpSize + brickEntry.PoolMetadataSize
 		}
 	}
+	// LVM snapshots of the bricks use space of the device too
+	for _, snapshot := range db.LvmSnapshots {
+		if snapshot.Info.DeviceId == d.Info.Id {
+			aggregateBricksSize += snapshot.Size
+		}
+	}
 
 	// Size validation
 	if d.Info.Storage.Total != d.Info.Storage.Free+d.Info.Storage.Used {
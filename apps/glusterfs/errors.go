@@ -25,6 +25,7 @@ var (
 	ErrKeyExists        = errors.New("Key already exists in the database")
 	ErrNoReplacement    = errors.New("No Replacement was found for resource requested to be removed")
 	ErrCloneBlockVol    = errors.New("Cloning of block hosting volumes is not supported")
+	ErrVolumeExpanding  = errors.New("Volume is already being expanded")
 
 	// well known errors for cluster device source
 	ErrEmptyCluster = errors.New("No nodes in cluster")
@@ -32,4 +33,7 @@ var (
 
 	// returned by code related to operations load
 	ErrTooManyOperations = errors.New("Server handling too many operations")
+	ErrNotCancelable     = errors.New("Operation can not be canceled")
+	ErrOperationCanceled = errors.New("Operation was canceled")
+	ErrOperationLocked   = errors.New("Resource is locked by an operation on another server")
 )
@@ -0,0 +1,76 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// how long the pre-expand hook may run before it is killed
+	PRE_EXPAND_HOOK_TIMEOUT = 60 * time.Second
+)
+
+// PreExpandHookError is returned when the pre-expand hook rejects the
+// expand of a volume. The message is what the hook wrote to stderr.
+type PreExpandHookError struct {
+	Message string
+}
+
+func (e *PreExpandHookError) Error() string {
+	return e.Message
+}
+
+// runPreExpandHook runs the configured pre-expand hook before the
+// volume is expanded by the given size. The hook gets the volume id,
+// the current size, the size after the expand and the "owner" label of
+// the volume in its environment and rejects the expand by exiting with
+// a non-zero status.
+func (app *App) runPreExpandHook(v *VolumeEntry, size int) error {
+	hook := app.conf.PreExpandHook
+	if hook == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		PRE_EXPAND_HOOK_TIMEOUT)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, hook)
+	cmd.Env = append(os.Environ(),
+		"HEKETI_VOLUME_ID="+v.Info.Id,
+		fmt.Sprintf("HEKETI_CURRENT_SIZE_GB=%v", v.Info.Size),
+		fmt.Sprintf("HEKETI_REQUESTED_SIZE_GB=%v", v.Info.Size+size),
+		"HEKETI_OWNER_ID="+v.Info.Labels["owner"])
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return fmt.Errorf("Pre-expand hook %v timed out", hook)
+	}
+	if _, ok := err.(*exec.ExitError); ok {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = "Volume expand rejected by the pre-expand hook"
+		}
+		logger.Info("Pre-expand hook rejected the expand of volume %v: %v",
+			v.Info.Id, msg)
+		return &PreExpandHookError{Message: msg}
+	} else if err != nil {
+		return fmt.Errorf("Unable to run pre-expand hook %v: %v", hook, err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// rejects expands of volumes to more than 1 TiB
+const budgetHook = `#!/bin/sh
+echo "$HEKETI_VOLUME_ID $HEKETI_CURRENT_SIZE_GB $HEKETI_REQUESTED_SIZE_GB $HEKETI_OWNER_ID" > "$(dirname "$0")/env"
+if [ "$HEKETI_REQUESTED_SIZE_GB" -gt 1024 ]; then
+	echo "no budget left for $HEKETI_OWNER_ID" >&2
+	exit 1
+fi
+`
+
+func TestVolumeExpandPreExpandHook(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	v.Info.Labels = map[string]string{"owner": "team-a"}
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	dir, err := ioutil.TempDir("", "heketi-hook")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	defer os.RemoveAll(dir)
+	hook := filepath.Join(dir, "pre-expand")
+	err = ioutil.WriteFile(hook, []byte(budgetHook), 0755)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	app.conf.PreExpandHook = hook
+
+	hookEnv := func() string {
+		env, err := ioutil.ReadFile(filepath.Join(dir, "env"))
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		return strings.TrimSpace(string(env))
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	info, err := c.VolumeExpand(v.Info.Id, &api.VolumeExpandRequest{Size: 100})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Size == 200, "expected size 200, got:", info.Size)
+	tests.Assert(t, hookEnv() == v.Info.Id+" 100 200 team-a",
+		"unexpected hook environment:", hookEnv())
+
+	// past 1 TiB the hook rejects the expand
+	r, err := http.Post(ts.URL+"/volumes/"+v.Info.Id+"/expand",
+		"application/json", bytes.NewBufferString(`{"expand_size": 900}`))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusPaymentRequired,
+		"expected http.StatusPaymentRequired, got:", r.StatusCode)
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, strings.Contains(string(body), "no budget left for team-a"),
+		"unexpected error:", string(body))
+	tests.Assert(t, hookEnv() == v.Info.Id+" 200 1100 team-a",
+		"unexpected hook environment:", hookEnv())
+
+	info, err = c.VolumeInfo(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Size == 200, "expected size 200, got:", info.Size)
+
+	// a hook that can not be run fails the expand
+	app.conf.PreExpandHook = filepath.Join(dir, "missing")
+	_, err = c.VolumeExpand(v.Info.Id, &api.VolumeExpandRequest{Size: 100})
+	tests.Assert(t, err != nil, "expected err != nil")
+}
@@ -0,0 +1,96 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+const (
+	// how long the status of a geo-replication session is reused
+	// before gluster is asked again
+	GEOREP_STATUS_TTL = 30 * time.Second
+)
+
+type geoRepStatusEntry struct {
+	status  api.GeoRepSessionStatusResponse
+	updated time.Time
+}
+
+// GeoRepStatusCache keeps the status of the geo-replication sessions
+// of the volumes such that polling clients do not run the status
+// command on every request.
+type GeoRepStatusCache struct {
+	TTL time.Duration
+
+	lock     sync.RWMutex
+	sessions map[string]geoRepStatusEntry
+}
+
+func NewGeoRepStatusCache(ttl time.Duration) *GeoRepStatusCache {
+	return &GeoRepStatusCache{
+		TTL:      ttl,
+		sessions: map[string]geoRepStatusEntry{},
+	}
+}
+
+// Get returns the status of the geo-replication session of the volume
+// with the given slave. The status is read from the given host if the
+// cached status is older than the TTL.
+func (gc *GeoRepStatusCache) Get(e executors.Executor, host string,
+	v *VolumeEntry, session string) (*api.GeoRepSessionStatusResponse, error) {
+
+	key := v.Info.Id + "/" + session
+	gc.lock.RLock()
+	entry, found := gc.sessions[key]
+	gc.lock.RUnlock()
+	if found && time.Since(entry.updated) < gc.TTL {
+		status := entry.status
+		return &status, nil
+	}
+
+	gs, err := e.VolumeGeoRepStatus(host, v.Info.Name, session)
+	if err != nil {
+		return nil, err
+	}
+	status := api.GeoRepSessionStatusResponse{
+		Session: session,
+		Bricks:  []api.GeoRepBrickStatus{},
+	}
+	for _, b := range gs.Bricks {
+		if b.Status == "Faulty" {
+			logger.Warning("Geo-replication session %v of volume %v is faulty on brick %v:%v",
+				session, v.Info.Name, b.MasterNode, b.BrickPath)
+		}
+		status.Bricks = append(status.Bricks, api.GeoRepBrickStatus{
+			MasterNode:   b.MasterNode,
+			BrickPath:    b.BrickPath,
+			SlaveNode:    b.SlaveNode,
+			Status:       b.Status,
+			CrawlStatus:  b.CrawlStatus,
+			FilesSynced:  b.FilesSynced,
+			FilesPending: b.FilesPending,
+			BytesPending: b.BytesPending,
+			LastSynced:   b.LastSynced,
+		})
+	}
+
+	gc.lock.Lock()
+	defer gc.lock.Unlock()
+	gc.sessions[key] = geoRepStatusEntry{
+		status:  status,
+		updated: time.Now(),
+	}
+	return &status, nil
+}
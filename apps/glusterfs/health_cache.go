@@ -37,6 +37,9 @@ type NodeHealthCache struct {
 	CheckInterval time.Duration
 	Expiration    time.Duration
 
+	// optional detection of offline bricks
+	BrickFaults *BrickFaultDetector
+
 	db    wdb.RODB
 	exec  executors.Executor
 	nodes map[string]*NodeHealthStatus
@@ -77,6 +80,11 @@ func (hc *NodeHealthCache) Refresh() error {
 		hc.updateNode(s)
 	}
 	hc.cleanOld()
+	if hc.BrickFaults != nil {
+		if err := hc.BrickFaults.Check(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -9,10 +9,49 @@
 
 package glusterfs
 
+import (
+	"fmt"
+)
+
 var (
 	// Default limits
 	BrickMinSize         = uint64(1 * GB)
 	BrickMaxSize         = uint64(4 * TB)
 	BrickMaxNum          = 32
 	maxVolumesPerCluster = 1000
+
+	// volume size bounds in GiB, zero for no bound
+	minVolumeSizeGB = 0
+	maxVolumeSizeGB = 0
 )
+
+// volumeSizeError is returned for volume sizes outside of the
+// configured bounds.
+type volumeSizeError struct {
+	sizeGB  int
+	boundGB int
+	max     bool
+}
+
+func (e volumeSizeError) Error() string {
+	if e.max {
+		return fmt.Sprintf(
+			"Volume size %v GiB is larger than the maximum volume size of %v GiB",
+			e.sizeGB, e.boundGB)
+	}
+	return fmt.Sprintf(
+		"Volume size %v GiB is smaller than the minimum volume size of %v GiB",
+		e.sizeGB, e.boundGB)
+}
+
+// checkVolumeSize returns an error if the volume size is outside of
+// the configured bounds.
+func checkVolumeSize(sizeGB int) error {
+	if minVolumeSizeGB > 0 && sizeGB < minVolumeSizeGB {
+		return volumeSizeError{sizeGB: sizeGB, boundGB: minVolumeSizeGB}
+	}
+	if maxVolumeSizeGB > 0 && sizeGB > maxVolumeSizeGB {
+		return volumeSizeError{sizeGB: sizeGB, boundGB: maxVolumeSizeGB, max: true}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/boltdb/bolt"
+	"github.com/lpabon/godbc"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/idgen"
+)
+
+const (
+	BOLTDB_BUCKET_LVMSNAPSHOT = "LVMSNAPSHOT"
+)
+
+// LvmSnapshotEntry records an LVM snapshot of the lv of a brick.
+// The snapshot is not managed by gluster; it exists only to allow
+// brick data to be backed up from a consistent point in time.
+type LvmSnapshotEntry struct {
+	Info api.LvmSnapshotInfo
+	// space reserved on the device for the snapshot (KiB)
+	Size uint64
+}
+
+func LvmSnapshotList(tx *bolt.Tx) ([]string, error) {
+	list := EntryKeys(tx, BOLTDB_BUCKET_LVMSNAPSHOT)
+	if list == nil {
+		return nil, ErrAccessList
+	}
+	return list, nil
+}
+
+func NewLvmSnapshotEntry() *LvmSnapshotEntry {
+	return &LvmSnapshotEntry{}
+}
+
+func NewLvmSnapshotEntryFromRequest(req *api.LvmSnapshotCreateRequest,
+	brick *BrickEntry) *LvmSnapshotEntry {
+
+	godbc.Require(req != nil)
+	godbc.Require(brick != nil)
+
+	entry := NewLvmSnapshotEntry()
+	entry.Info.Id = idgen.GenUUID()
+	entry.Info.Name = req.Name
+	entry.Info.SizePercent = req.SizePercent
+	entry.Info.BrickId = brick.Info.Id
+	entry.Info.DeviceId = brick.Info.DeviceId
+	// lvm rounds the size up to the next extent, which is not
+	// accounted for here, much like for bricks
+	entry.Size = brick.Info.Size * uint64(req.SizePercent) / 100
+	return entry
+}
+
+func NewLvmSnapshotEntryFromId(tx *bolt.Tx, id string) (*LvmSnapshotEntry, error) {
+	entry := NewLvmSnapshotEntry()
+	err := EntryLoad(tx, entry, id)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// LvmSnapshotsOfBrick returns the snapshot entries of the given brick.
+func LvmSnapshotsOfBrick(tx *bolt.Tx, brickId string) ([]*LvmSnapshotEntry, error) {
+	ids, err := LvmSnapshotList(tx)
+	if err != nil {
+		return nil, err
+	}
+	entries := []*LvmSnapshotEntry{}
+	for _, id := range ids {
+		entry, err := NewLvmSnapshotEntryFromId(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if entry.Info.BrickId == brickId {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (s *LvmSnapshotEntry) BucketName() string {
+	return BOLTDB_BUCKET_LVMSNAPSHOT
+}
+
+func (s *LvmSnapshotEntry) Save(tx *bolt.Tx) error {
+	godbc.Require(tx != nil)
+	godbc.Require(len(s.Info.Id) > 0)
+
+	return EntrySave(tx, s, s.Info.Id)
+}
+
+func (s *LvmSnapshotEntry) Delete(tx *bolt.Tx) error {
+	return EntryDelete(tx, s, s.Info.Id)
+}
+
+func (s *LvmSnapshotEntry) Marshal() ([]byte, error) {
+	var buffer bytes.Buffer
+	enc := gob.NewEncoder(&buffer)
+	err := enc.Encode(*s)
+
+	return buffer.Bytes(), err
+}
+
+func (s *LvmSnapshotEntry) Unmarshal(buffer []byte) error {
+	dec := gob.NewDecoder(bytes.NewReader(buffer))
+	return dec.Decode(s)
+}
+
+func (s *LvmSnapshotEntry) request(brick *BrickEntry) *executors.LvmSnapshotRequest {
+	return &executors.LvmSnapshotRequest{
+		VgId:        s.Info.DeviceId,
+		LvName:      brick.LvName(),
+		Name:        s.Info.Name,
+		SizePercent: s.Info.SizePercent,
+	}
+}
+
+// removeBrickLvmSnapshots deletes the entries of the snapshots of a
+// brick that is being removed and returns the space they used on the
+// device. Removing the lv of the brick removes its snapshots too.
+func removeBrickLvmSnapshots(tx *bolt.Tx, brickId string) (uint64, error) {
+	if tx.Bucket([]byte(BOLTDB_BUCKET_LVMSNAPSHOT)) == nil {
+		// db from an older version
+		return 0, nil
+	}
+	snaps, err := LvmSnapshotsOfBrick(tx, brickId)
+	if err != nil {
+		return 0, err
+	}
+	var freed uint64
+	for _, s := range snaps {
+		if err := s.Delete(tx); err != nil {
+			return 0, err
+		}
+		freed += s.Size
+	}
+	return freed, nil
+}
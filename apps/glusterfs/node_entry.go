@@ -29,6 +29,10 @@ type NodeEntry struct {
 
 	Info    api.NodeInfo
 	Devices sort.StringSlice
+	// when Info.ResolvedIP was resolved (unix time), 0 if set manually
+	ResolvedIPTime int64
+	// names of the tags last set from the labels of the kubernetes node
+	LabelTags []string
 }
 
 func NewNodeEntry() *NodeEntry {
@@ -47,6 +51,8 @@ func NewNodeEntryFromRequest(req *api.NodeAddRequest) *NodeEntry {
 	node.Info.ClusterId = req.ClusterId
 	node.Info.Hostnames = req.Hostnames
 	node.Info.Zone = req.Zone
+	node.Info.Region = req.Region
+	node.Info.TspId = req.TspId
 	node.Info.Tags = copyTags(req.Tags)
 
 	return node
@@ -74,6 +80,23 @@ func (n *NodeEntry) registerStorageKey(host string) string {
 
 // Verify gluster process in the node and return the manage hostname of a node in the cluster
 func GetVerifiedManageHostname(db wdb.RODB, e executors.Executor, clusterId string) (string, error) {
+	return getVerifiedManageHostname(db, e, clusterId,
+		func(n *NodeEntry) bool { return true })
+}
+
+// GetVerifiedTspManageHostname returns the manage hostname of a node
+// in the given trusted storage pool of the cluster that has glusterd
+// running.
+func GetVerifiedTspManageHostname(db wdb.RODB, e executors.Executor,
+	clusterId, tspId string) (string, error) {
+
+	return getVerifiedManageHostname(db, e, clusterId,
+		func(n *NodeEntry) bool { return n.Info.TspId == tspId })
+}
+
+func getVerifiedManageHostname(db wdb.RODB, e executors.Executor,
+	clusterId string, accept func(*NodeEntry) bool) (string, error) {
+
 	godbc.Require(clusterId != "")
 	var cluster *ClusterEntry
 	var node *NodeEntry
@@ -102,7 +125,7 @@ func GetVerifiedManageHostname(db wdb.RODB, e executors.Executor, clusterId stri
 		}
 
 		// Ignore if the node is not online
-		if !newNode.isOnline() {
+		if !newNode.isOnline() || !accept(newNode) {
 			continue
 		}
 		err = e.GlusterdCheck(newNode.ManageHostName())
@@ -239,6 +262,8 @@ func (n *NodeEntry) Save(tx *bolt.Tx) error {
 	godbc.Require(tx != nil)
 	godbc.Require(len(n.Info.Id) > 0)
 
+	// the mount info of the volumes lists the hosts of the nodes
+	invalidateAllVolumeInfo(tx)
 	return EntrySave(tx, n, n.Info.Id)
 
 }
@@ -257,6 +282,25 @@ func (n *NodeEntry) StorageHostName() string {
 	return n.Info.Hostnames.Storage[0]
 }
 
+// NewNodeEntryFromHostName returns the node with the given manage or
+// storage hostname.
+func NewNodeEntryFromHostName(tx *bolt.Tx, host string) (*NodeEntry, error) {
+	nodes, err := NodeList(tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range nodes {
+		n, err := NewNodeEntryFromId(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if n.ManageHostName() == host || n.StorageHostName() == host {
+			return n, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
 func (n *NodeEntry) IsDeleteOk() bool {
 	// Check if the nodes still has drives
 	if len(n.Devices) > 0 {
@@ -278,6 +322,7 @@ func (n *NodeEntry) Delete(tx *bolt.Tx) error {
 		return ErrConflict
 	}
 
+	invalidateAllVolumeInfo(tx)
 	return EntryDelete(tx, n, n.Info.Id)
 }
 
@@ -391,9 +436,16 @@ func (n *NodeEntry) NewInfoReponse(tx *bolt.Tx) (*api.NodeInfoResponse, error) {
 	info.Hostnames = n.Info.Hostnames
 	info.Id = n.Info.Id
 	info.Zone = n.Info.Zone
+	info.Region = n.Info.Region
+	info.TspId = n.Info.TspId
 	info.State = n.State
 	info.DevicesInfo = make([]api.DeviceInfoResponse, 0)
 	info.Tags = copyTags(n.Info.Tags)
+	info.ResolvedIP = n.Info.ResolvedIP
+	if l, found := currentNodeLatency()[n.Info.Id]; found {
+		info.SshLatencyP50Ms = durationMs(l.P50)
+		info.SshLatencyP99Ms = durationMs(l.P99)
+	}
 
 	// Add each drive information
 	for _, deviceid := range n.Devices {
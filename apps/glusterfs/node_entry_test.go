@@ -13,6 +13,7 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/heketi/heketi/pkg/glusterfs/api"
@@ -330,6 +331,49 @@ func TestNewNodeEntryFromId(t *testing.T) {
 
 }
 
+func TestNewNodeEntryFromHostName(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	n1 := createSampleNodeEntry()
+	n2 := createSampleNodeEntry()
+	n2.Info.ClusterId = "456"
+	err := app.db.Update(func(tx *bolt.Tx) error {
+		if err := n1.Save(tx); err != nil {
+			return err
+		}
+		return n2.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		n, err := NewNodeEntryFromHostName(tx, n1.ManageHostName())
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, n.Info.Id == n1.Info.Id,
+			"expected", n1.Info.Id, "got:", n.Info.Id)
+		n, err = NewNodeEntryFromHostName(tx, n2.StorageHostName())
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, n.Info.Id == n2.Info.Id,
+			"expected", n2.Info.Id, "got:", n.Info.Id)
+		_, err = NewNodeEntryFromHostName(tx, "unknown")
+		tests.Assert(t, err == ErrNotFound,
+			"expected err == ErrNotFound, got:", err)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	hs := &hostClusterStore{app: app}
+	c, err := hs.HostCluster(n2.ManageHostName())
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, c == "456", "expected cluster 456, got:", c)
+	c, err = hs.HostCluster("unknown")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, c == "", "expected no cluster, got:", c)
+}
+
 func TestNewNodeEntrySaveDelete(t *testing.T) {
 	tmpfile := tests.Tempfile()
 	defer os.Remove(tmpfile)
@@ -669,3 +713,63 @@ func TestGetVerifiedManageHostname(t *testing.T) {
 	tests.Assert(t, hostname != "manage")
 	tests.Assert(t, err != nil)
 }
+
+func TestHostIPStore(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	n := createSampleNodeEntry()
+	err := app.db.Update(func(tx *bolt.Tx) error {
+		return n.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	host := n.ManageHostName()
+
+	hs := &hostIPStore{app: app, ttl: time.Hour}
+	ip, err := hs.HostIP(host)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, ip == "", "expected no address, got:", ip)
+
+	err = hs.SetHostIP(host, "10.0.0.5")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	ip, err = hs.HostIP(host)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, ip == "10.0.0.5", "expected address, got:", ip)
+
+	// addresses older than the ttl are not used
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		n, err := NewNodeEntryFromId(tx, n.Info.Id)
+		if err != nil {
+			return err
+		}
+		n.ResolvedIPTime = time.Now().Add(-2 * time.Hour).Unix()
+		return n.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	ip, err = hs.HostIP(host)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, ip == "", "expected expired address, got:", ip)
+
+	// unless set manually
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		n, err := NewNodeEntryFromId(tx, n.Info.Id)
+		if err != nil {
+			return err
+		}
+		n.ResolvedIPTime = 0
+		return n.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	ip, err = hs.HostIP(host)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, ip == "10.0.0.5", "expected address, got:", ip)
+
+	ip, err = hs.HostIP("unknown")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, ip == "", "expected no address, got:", ip)
+	err = hs.SetHostIP("unknown", "10.0.0.6")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
@@ -0,0 +1,135 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/kubernetes"
+)
+
+var (
+	newNodeLabelWatcher = kubernetes.NewInClusterNodeLabelWatcher
+)
+
+const (
+	// seconds between full syncs of the node labels
+	DEFAULT_LABEL_SYNC_INTERVAL = 300
+)
+
+// NodeLabelSync keeps the tags of the heketi nodes in sync with the
+// labels of the kubernetes nodes of the same name. Only the labels
+// starting with the filter, less an optional trailing "*", are synced
+// and the prefix is removed to get the tag name, so that with
+// "heketi.io/*" the label "heketi.io/rack" sets the tag "rack". Tags
+// set by other means are left alone.
+type NodeLabelSync struct {
+	Filter string
+
+	db wdb.DB
+}
+
+func NewNodeLabelSync(db wdb.DB, filter string) *NodeLabelSync {
+	return &NodeLabelSync{
+		Filter: filter,
+		db:     db,
+	}
+}
+
+// labelTags returns the tags for the labels matching the filter.
+// Labels that do not make valid tags are skipped.
+func (ls *NodeLabelSync) labelTags(labels map[string]string) map[string]string {
+	prefix := strings.TrimSuffix(ls.Filter, "*")
+	tags := map[string]string{}
+	for k, v := range labels {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(k, prefix)
+		if err := api.ValidateTags(map[string]string{name: v}); err != nil {
+			logger.Warning("Not syncing node label %v: %v", k, err)
+			continue
+		}
+		tags[name] = v
+	}
+	return tags
+}
+
+// SyncNode updates the tags of the heketi node managed by the given
+// host name from the labels of the kubernetes node. Tags synced
+// before whose label is gone are removed. Nodes not known to heketi
+// are ignored.
+func (ls *NodeLabelSync) SyncNode(name string, labels map[string]string) error {
+	tags := ls.labelTags(labels)
+	var synced []string
+	for k := range tags {
+		synced = append(synced, k)
+	}
+	sort.Strings(synced)
+
+	return ls.db.Update(func(tx *bolt.Tx) error {
+		n, err := NewNodeEntryFromHostName(tx, name)
+		if err == ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		current := copyTags(n.Info.Tags)
+		updated := copyTags(current)
+		for _, k := range n.LabelTags {
+			delete(updated, k)
+		}
+		for k, v := range tags {
+			updated[k] = v
+		}
+		if reflect.DeepEqual(current, updated) &&
+			reflect.DeepEqual(n.LabelTags, synced) {
+			return nil
+		}
+		logger.Info("Updating tags of node %v from kubernetes node %v",
+			n.Info.Id, name)
+		n.Info.Tags = updated
+		n.LabelTags = synced
+		return n.Save(tx)
+	})
+}
+
+// syncNodeLabels is the callback of the node label watcher.
+func (ls *NodeLabelSync) syncNodeLabels(name string, labels map[string]string) {
+	if err := ls.SyncNode(name, labels); err != nil {
+		logger.LogError("Unable to sync labels of node %v: %v", name, err)
+	}
+}
+
+func (app *App) initNodeLabelSync() {
+	if app.conf.LabelSyncFilter == "" || app.dbReadOnly {
+		return
+	}
+	interval := app.conf.LabelSyncInterval
+	if interval <= 0 {
+		interval = DEFAULT_LABEL_SYNC_INTERVAL
+	}
+	ls := NewNodeLabelSync(app.db, app.conf.LabelSyncFilter)
+	w, err := newNodeLabelWatcher(
+		time.Duration(interval)*time.Second, ls.syncNodeLabels)
+	if err != nil {
+		logger.LogError("Unable to sync node labels: %v", err)
+		return
+	}
+	app.nlabels = w
+	app.nlabels.Start()
+}
@@ -0,0 +1,125 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/tests"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/heketi/heketi/pkg/kubernetes"
+)
+
+func TestNodeLabelSyncFilter(t *testing.T) {
+	ls := NewNodeLabelSync(nil, "heketi.io/*")
+	tags := ls.labelTags(map[string]string{
+		"heketi.io/zone":         "1",
+		"heketi.io/rack":         "r1",
+		"heketi.io/tier/ssd":     "true",
+		"kubernetes.io/hostname": "node1",
+	})
+	expected := map[string]string{"zone": "1", "rack": "r1"}
+	tests.Assert(t, reflect.DeepEqual(tags, expected),
+		"expected", expected, "got:", tags)
+
+	// the trailing * is optional
+	ls.Filter = "heketi.io/"
+	tags = ls.labelTags(map[string]string{"heketi.io/zone": "1"})
+	tests.Assert(t, reflect.DeepEqual(tags, map[string]string{"zone": "1"}),
+		"unexpected tags:", tags)
+}
+
+func TestNodeLabelSync(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// a tag set by the admin
+	var node *NodeEntry
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		nodes, err := NodeList(tx)
+		if err != nil {
+			return err
+		}
+		node, err = NewNodeEntryFromId(tx, nodes[0])
+		if err != nil {
+			return err
+		}
+		node.Info.Tags = map[string]string{"owner": "ops"}
+		return node.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	kn := &v1.Node{}
+	kn.Name = node.ManageHostName()
+	kn.Labels = map[string]string{
+		"heketi.io/zone":        "a",
+		"heketi.io/rack":        "r1",
+		"beta.kubernetes.io/os": "linux",
+	}
+	fakeclient := fakeclientset.NewSimpleClientset(kn)
+	defer func(f func(time.Duration, kubernetes.NodeLabelsFunc) (
+		*kubernetes.NodeLabelWatcher, error)) {
+		newNodeLabelWatcher = f
+	}(newNodeLabelWatcher)
+	newNodeLabelWatcher = func(resync time.Duration,
+		f kubernetes.NodeLabelsFunc) (*kubernetes.NodeLabelWatcher, error) {
+		return kubernetes.NewNodeLabelWatcher(fakeclient, resync, f), nil
+	}
+	app.conf.LabelSyncFilter = "heketi.io/*"
+	app.conf.LabelSyncInterval = 1
+	app.initNodeLabelSync()
+	tests.Assert(t, app.nlabels != nil, "expected the watcher to be started")
+
+	nodeTags := func() map[string]string {
+		var tags map[string]string
+		app.db.View(func(tx *bolt.Tx) error {
+			n, err := NewNodeEntryFromId(tx, node.Info.Id)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+			tags = n.AllTags()
+			return nil
+		})
+		return tags
+	}
+	waitForTags := func(expected map[string]string) {
+		// within the sync interval
+		for i := 0; i < 20 && !reflect.DeepEqual(nodeTags(), expected); i++ {
+			time.Sleep(50 * time.Millisecond)
+		}
+		tests.Assert(t, reflect.DeepEqual(nodeTags(), expected),
+			"expected", expected, "got:", nodeTags())
+	}
+	waitForTags(map[string]string{"owner": "ops", "zone": "a", "rack": "r1"})
+
+	// a changed label is updated, a removed label is removed
+	kn, err = fakeclient.CoreV1().Nodes().Get(kn.Name, metav1.GetOptions{})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	kn.Labels["heketi.io/zone"] = "b"
+	delete(kn.Labels, "heketi.io/rack")
+	_, err = fakeclient.CoreV1().Nodes().Update(kn)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	waitForTags(map[string]string{"owner": "ops", "zone": "b"})
+}
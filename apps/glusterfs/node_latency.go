@@ -0,0 +1,229 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+)
+
+const (
+	// number of round-trip samples retained per node
+	NODE_LATENCY_SAMPLES = 10
+)
+
+// nodeLatencySamples is a fixed size rolling buffer of the most
+// recent round-trip times measured for a node.
+type nodeLatencySamples struct {
+	samples [NODE_LATENCY_SAMPLES]time.Duration
+	count   int
+	next    int
+}
+
+func (s *nodeLatencySamples) add(d time.Duration) {
+	s.samples[s.next] = d
+	s.next = (s.next + 1) % NODE_LATENCY_SAMPLES
+	if s.count < NODE_LATENCY_SAMPLES {
+		s.count++
+	}
+}
+
+// percentile returns the p-th percentile (0-100) of the retained
+// samples using the nearest-rank method.
+func (s *nodeLatencySamples) percentile(p int) time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, s.count)
+	copy(sorted, s.samples[:s.count])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := (p*s.count + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// NodeLatency summarizes the recent round-trip times to a node.
+type NodeLatency struct {
+	P50 time.Duration
+	P99 time.Duration
+}
+
+// durationMs converts a duration to fractional milliseconds.
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// NodeLatencyCache periodically runs a no-op command on each online
+// node and tracks the round-trip time of those commands.
+type NodeLatencyCache struct {
+	// tunables
+	StartInterval time.Duration
+	CheckInterval time.Duration
+
+	db    wdb.RODB
+	exec  executors.Executor
+	nodes map[string]*nodeLatencySamples
+	lock  sync.RWMutex
+
+	// to stop the monitor
+	stop chan<- interface{}
+}
+
+func NewNodeLatencyCache(reftime, starttime uint32, db wdb.RODB, e executors.Executor) *NodeLatencyCache {
+	return &NodeLatencyCache{
+		db:            db,
+		exec:          e,
+		nodes:         map[string]*nodeLatencySamples{},
+		StartInterval: time.Second * time.Duration(starttime),
+		CheckInterval: time.Second * time.Duration(reftime),
+	}
+}
+
+// Record adds a round-trip sample for the given node.
+func (lc *NodeLatencyCache) Record(nodeId string, d time.Duration) {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	s, found := lc.nodes[nodeId]
+	if !found {
+		s = &nodeLatencySamples{}
+		lc.nodes[nodeId] = s
+	}
+	s.add(d)
+}
+
+// Latency returns the latency summary for a node. If no samples
+// have been recorded for the node found will be false.
+func (lc *NodeLatencyCache) Latency(nodeId string) (l NodeLatency, found bool) {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+	s, found := lc.nodes[nodeId]
+	if !found || s.count == 0 {
+		return l, false
+	}
+	l.P50 = s.percentile(50)
+	l.P99 = s.percentile(99)
+	return l, true
+}
+
+// Status returns a map of node ids to latency summaries for all
+// nodes with recorded samples.
+func (lc *NodeLatencyCache) Status() map[string]NodeLatency {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+	out := map[string]NodeLatency{}
+	for k, s := range lc.nodes {
+		if s.count == 0 {
+			continue
+		}
+		out[k] = NodeLatency{
+			P50: s.percentile(50),
+			P99: s.percentile(99),
+		}
+	}
+	return out
+}
+
+func (lc *NodeLatencyCache) Refresh() error {
+	logger.Debug("Starting Node Latency refresh")
+	hosts, err := lc.toProbe()
+	if err != nil {
+		return err
+	}
+	for nodeId, host := range hosts {
+		start := time.Now()
+		if err := lc.exec.NodePing(host); err != nil {
+			logger.Warning("Unable to ping node %v: %v", nodeId, err)
+			continue
+		}
+		lc.Record(nodeId, time.Since(start))
+	}
+	lc.cleanOld(hosts)
+	return nil
+}
+
+// cleanOld drops samples for nodes that are no longer probed.
+func (lc *NodeLatencyCache) cleanOld(probed map[string]string) {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+	for k := range lc.nodes {
+		if _, found := probed[k]; !found {
+			delete(lc.nodes, k)
+		}
+	}
+}
+
+func (lc *NodeLatencyCache) Monitor() {
+	startTimer := time.NewTimer(lc.StartInterval)
+	ticker := time.NewTicker(lc.CheckInterval)
+	stop := make(chan interface{})
+	lc.stop = stop
+
+	go func() {
+		logger.Info("Started Node Latency Monitor")
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				logger.Info("Stopping Node Latency Monitor")
+				return
+			case <-startTimer.C:
+				err := lc.Refresh()
+				if err != nil {
+					logger.LogError("Node Latency Monitor: %v", err.Error())
+				}
+			case <-ticker.C:
+				err := lc.Refresh()
+				if err != nil {
+					logger.LogError("Node Latency Monitor: %v", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+func (lc *NodeLatencyCache) Stop() {
+	lc.stop <- true
+}
+
+// toProbe returns a map of online node ids to management hostnames.
+func (lc *NodeLatencyCache) toProbe() (map[string]string, error) {
+	hosts := map[string]string{}
+	err := lc.db.View(func(tx *bolt.Tx) error {
+		n, err := NodeList(tx)
+		if err != nil {
+			return err
+		}
+		for _, nodeId := range n {
+			if strings.HasPrefix(nodeId, "MANAGE") ||
+				strings.HasPrefix(nodeId, "STORAGE") {
+				continue
+			}
+			node, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			if !node.isOnline() {
+				continue
+			}
+			hosts[nodeId] = node.ManageHostName()
+		}
+		return nil
+	})
+	return hosts, err
+}
@@ -0,0 +1,131 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/heketi/tests"
+)
+
+func TestNodeLatencySamplesRolling(t *testing.T) {
+	s := &nodeLatencySamples{}
+	tests.Assert(t, s.percentile(50) == 0,
+		"expected percentile(50) == 0, got:", s.percentile(50))
+
+	for i := 1; i <= NODE_LATENCY_SAMPLES; i++ {
+		s.add(time.Duration(i) * time.Millisecond)
+	}
+	tests.Assert(t, s.percentile(50) == 5*time.Millisecond,
+		"expected percentile(50) == 5ms, got:", s.percentile(50))
+	tests.Assert(t, s.percentile(99) == 10*time.Millisecond,
+		"expected percentile(99) == 10ms, got:", s.percentile(99))
+
+	// the oldest (1ms) sample is dropped when the buffer wraps
+	s.add(100 * time.Millisecond)
+	tests.Assert(t, s.count == NODE_LATENCY_SAMPLES,
+		"expected s.count == NODE_LATENCY_SAMPLES, got:", s.count)
+	tests.Assert(t, s.percentile(0) == 2*time.Millisecond,
+		"expected percentile(0) == 2ms, got:", s.percentile(0))
+	tests.Assert(t, s.percentile(99) == 100*time.Millisecond,
+		"expected percentile(99) == 100ms, got:", s.percentile(99))
+}
+
+func TestNodeLatencyCacheRefresh(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		6*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	lc := NewNodeLatencyCache(1, 0, app.db, app.executor)
+	tests.Assert(t, len(lc.Status()) == 0,
+		"expected len(lc.Status()) == 0, got:", len(lc.Status()))
+
+	pings := 0
+	app.xo.MockNodePing = func(host string) error {
+		pings++
+		return nil
+	}
+	err = lc.Refresh()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, pings == 3, "expected pings == 3, got:", pings)
+	tests.Assert(t, len(lc.Status()) == 3,
+		"expected len(lc.Status()) == 3, got:", len(lc.Status()))
+
+	// samples for nodes that no longer exist are dropped
+	lc.Record("deadbeef", time.Second)
+	_, found := lc.Latency("deadbeef")
+	tests.Assert(t, found, "expected found to be true")
+	err = lc.Refresh()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, found = lc.Latency("deadbeef")
+	tests.Assert(t, !found, "expected found to be false")
+}
+
+func TestPreferLowLatency(t *testing.T) {
+	devices := SimpleDevices{
+		{nodeId: "slow", deviceId: "d1", free: 1000},
+		{nodeId: "fast", deviceId: "d2", free: 980},
+		{nodeId: "unknown", deviceId: "d3", free: 990},
+		{nodeId: "fast", deviceId: "d4", free: 100},
+	}
+	latency := map[string]NodeLatency{
+		"slow": {P50: 40 * time.Millisecond, P99: 90 * time.Millisecond},
+		"fast": {P50: 2 * time.Millisecond, P99: 5 * time.Millisecond},
+	}
+
+	out := preferLowLatency(devices, latency)
+	tests.Assert(t, len(out) == len(devices),
+		"expected len(out) == len(devices), got:", len(out))
+	// devices with similar free space are ordered by latency, unknown last
+	tests.Assert(t, out[0].deviceId == "d2",
+		"expected out[0].deviceId == d2, got:", out[0].deviceId)
+	tests.Assert(t, out[1].deviceId == "d1",
+		"expected out[1].deviceId == d1, got:", out[1].deviceId)
+	tests.Assert(t, out[2].deviceId == "d3",
+		"expected out[2].deviceId == d3, got:", out[2].deviceId)
+	// free space still dominates when the difference is large
+	tests.Assert(t, out[3].deviceId == "d4",
+		"expected out[3].deviceId == d4, got:", out[3].deviceId)
+	// input is not modified
+	tests.Assert(t, devices[0].deviceId == "d1",
+		"expected devices[0].deviceId == d1, got:", devices[0].deviceId)
+
+	// the ring's rotation is kept when free space differs
+	devices = SimpleDevices{
+		{nodeId: "fast", deviceId: "d1", free: 100},
+		{nodeId: "slow", deviceId: "d2", free: 1000},
+		{nodeId: "slow", deviceId: "d3", free: 500},
+		{nodeId: "fast", deviceId: "d4", free: 510},
+	}
+	out = preferLowLatency(devices, latency)
+	for i, id := range []string{"d1", "d2", "d4", "d3"} {
+		tests.Assert(t, out[i].deviceId == id,
+			"expected", id, "at", i, "got:", out[i].deviceId)
+	}
+
+	// without latency data the order is unchanged
+	out = preferLowLatency(devices, map[string]NodeLatency{})
+	for i := range devices {
+		tests.Assert(t, out[i].deviceId == devices[i].deviceId,
+			"expected", devices[i].deviceId, "at", i, "got:", out[i].deviceId)
+	}
+}
@@ -0,0 +1,173 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/boltdb/bolt"
+	"github.com/lpabon/godbc"
+)
+
+const (
+	// pending operation entries of finished operations, keyed by
+	// "<target id>/<operation id>"
+	BOLTDB_BUCKET_OPERATION_HISTORY = "OPERATION_HISTORY"
+)
+
+// statuses of the operations in the operation history
+const (
+	CompletedOperation  OperationStatus = "completed"
+	RolledBackOperation OperationStatus = "rolled back"
+)
+
+// historyOperation is implemented by operations whose pending
+// operation entry is kept in the operation history once they finish.
+type historyOperation interface {
+	historyEntry() *PendingOperationEntry
+	recordHistory(p *PendingOperationEntry, status OperationStatus) error
+}
+
+// historyEntry returns a copy of the pending operation entry of the
+// operation. The copy must be taken before the operation is finalized
+// or rolled back, as these remove the entry and reset its actions.
+func (om *OperationManager) historyEntry() *PendingOperationEntry {
+	p := *om.op
+	p.Actions = append([]PendingOperationAction{}, om.op.Actions...)
+	return &p
+}
+
+// recordHistory saves the given entry of the finished operation in
+// the operation history.
+func (om *OperationManager) recordHistory(
+	p *PendingOperationEntry, status OperationStatus) error {
+
+	return om.db.Update(func(tx *bolt.Tx) error {
+		return AddOperationHistory(tx, p, status)
+	})
+}
+
+func operationHistoryKey(targetId, opId string) []byte {
+	return []byte(targetId + "/" + opId)
+}
+
+// AddOperationHistory adds the pending operation entry of a finished
+// operation to the history of each of the ids its actions target.
+func AddOperationHistory(tx *bolt.Tx,
+	p *PendingOperationEntry, status OperationStatus) error {
+
+	godbc.Require(p.Id != "")
+
+	b := tx.Bucket([]byte(BOLTDB_BUCKET_OPERATION_HISTORY))
+	if b == nil {
+		return ErrDbAccess
+	}
+	entry := *p
+	entry.Status = status
+	buffer, err := entry.Marshal()
+	if err != nil {
+		return err
+	}
+	for _, a := range entry.Actions {
+		if a.Id == "" {
+			continue
+		}
+		if err := b.Put(operationHistoryKey(a.Id, p.Id), buffer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OperationHistoryForId returns the entries of the finished operations
+// that targeted the given id.
+func OperationHistoryForId(tx *bolt.Tx, id string) (
+	[]*PendingOperationEntry, error) {
+
+	entries := []*PendingOperationEntry{}
+	b := tx.Bucket([]byte(BOLTDB_BUCKET_OPERATION_HISTORY))
+	if b == nil {
+		// a db opened read-only may predate the history
+		return entries, nil
+	}
+	prefix := []byte(id + "/")
+	c := b.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		entry := &PendingOperationEntry{}
+		if err := entry.Unmarshal(v); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// OperationsForIdPage returns up to limit operation entries that have
+// at least one action targeting the given id: the pending and archived
+// entries of operations that are in flight or failed, and the history
+// of the operations that finished. Entries are returned ordered by id
+// starting after the cursor id (an empty cursor starts at the
+// beginning). If more matching entries remain, the id of the last
+// entry returned is provided as the next cursor, otherwise the next
+// cursor is empty.
+func OperationsForIdPage(tx *bolt.Tx,
+	id, cursor string, limit int) ([]*PendingOperationEntry, string, error) {
+
+	godbc.Require(limit > 0)
+
+	found := map[string]*PendingOperationEntry{}
+	history, err := OperationHistoryForId(tx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, p := range history {
+		found[p.Id] = p
+	}
+	archived, err := ArchivedPendingOperationList(tx)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, opId := range archived {
+		p, err := NewArchivedPendingOperationEntryFromId(tx, opId)
+		if err != nil {
+			return nil, "", err
+		}
+		if p.Targets(id) {
+			found[p.Id] = p
+		}
+	}
+	// an entry still pending is more recent than a copy in the history
+	pops, err := PendingOperationEntrySelection(tx,
+		func(p *PendingOperationEntry) bool {
+			return p.Targets(id)
+		})
+	if err != nil {
+		return nil, "", err
+	}
+	for _, p := range pops {
+		found[p.Id] = p
+	}
+
+	entries := []*PendingOperationEntry{}
+	for opId, p := range found {
+		if opId > cursor {
+			entries = append(entries, p)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Id < entries[j].Id
+	})
+	if len(entries) <= limit {
+		return entries, "", nil
+	}
+	entries = entries[:limit]
+	return entries, entries[limit-1].Id, nil
+}
@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/heketi/heketi/executors"
@@ -66,19 +67,20 @@ type Operation interface {
 	// be performed in a single transaction. This phase is responsible
 	// for creating the PendingOperationEntry items in the db and
 	// associating them with other elements.
-	Build() error
+	Build(ctx context.Context) error
 	// Exec functions implement the exec phase of an operation; the
 	// exec phase is responsible for manipulating the storage nodes
 	// to apply the expected changes to the gluster system. The
 	// exec phase is expected to take a large amount of time relative
 	// to the other operation phases. DB transactions within the
-	// exec phase should be read-only.
-	Exec(executor executors.Executor) error
+	// exec phase should be read-only. Commands must not be sent to
+	// the storage nodes once ctx is done.
+	Exec(ctx context.Context, executor executors.Executor) error
 	// Rollback functions are responsible for undoing any state left
 	// in the DB and/or storage nodes in case of a Build phase error.
 	// Calling rollback should make it like Build and Exec never ran,
 	// this includes removing pending operation entries from the db.
-	Rollback(executor executors.Executor) error
+	Rollback(ctx context.Context, executor executors.Executor) error
 	// Finalize functions implement the finalize phase of the operation;
 	// it takes any of the db changes that were marked pending
 	// by the build phase and removes the pending markers and pending
@@ -117,6 +119,18 @@ type FailableOperation interface {
 	MarkFailed() error
 }
 
+// CancelableOperation is any operation that can be canceled after
+// the build phase has completed but before the exec phase has started.
+type CancelableOperation interface {
+	Operation
+
+	// CancelBuild undoes the db changes made by the Build function,
+	// including removing the pending operation entry. Because the
+	// exec phase never ran it must not issue any commands to the
+	// storage system.
+	CancelBuild() error
+}
+
 type noRetriesOperation struct{}
 
 func (n *noRetriesOperation) MaxRetries() int {
@@ -135,6 +149,68 @@ func (om *OperationManager) Id() string {
 	return om.op.Id
 }
 
+// SetTags sets the user defined tags of the operation's pending
+// operation entry. It must be called before the operation is built.
+func (om *OperationManager) SetTags(tags map[string]string) {
+	om.op.Tags = tags
+}
+
+// updateChildOperation records the given pending operation entry as
+// the child of the operation's pending operation entry.
+func (om *OperationManager) updateChildOperation(
+	db wdb.DB, childOp *PendingOperationEntry) error {
+
+	return db.Update(func(tx *bolt.Tx) error {
+		var err error
+		om.op, err = NewPendingOperationEntryFromId(tx, om.op.Id)
+		if err != nil {
+			return err
+		}
+		om.op.RecordChild(childOp)
+		// RecordChild alters both parent and child so save them both
+		if err := childOp.Save(tx); err != nil {
+			return err
+		}
+		if err := om.op.Save(tx); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// clearChildOperation removes the child of the operation's pending
+// operation entry.
+func (om *OperationManager) clearChildOperation(db wdb.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		var err error
+		om.op, err = NewPendingOperationEntryFromId(tx, om.op.Id)
+		if err != nil {
+			return err
+		}
+		om.op.ClearChild()
+		return om.op.Save(tx)
+	})
+}
+
+// setProgress records the percentage of the work of the operation
+// that is done in the operation's pending operation entry.
+func (om *OperationManager) setProgress(percent int) error {
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+	return om.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		om.op, err = NewPendingOperationEntryFromId(tx, om.op.Id)
+		if err != nil {
+			return err
+		}
+		om.op.Progress = percent
+		return om.op.Save(tx)
+	})
+}
+
 // MarkFailed marks the pending operation entry associated with
 // the operation as failed.
 func (om *OperationManager) MarkFailed() error {
@@ -196,6 +272,24 @@ func volumesFromOp(db wdb.RODB,
 	return volumeEntries, err
 }
 
+// reclaimAllFromOp returns a reclaim map marking the devices of every
+// brick tracked by the given pending operation entry as reclaimed. This
+// is only correct for bricks that were allocated in the db but never
+// created on the storage nodes.
+func reclaimAllFromOp(db wdb.RODB,
+	op *PendingOperationEntry) (ReclaimMap, error) {
+
+	bricks, err := bricksFromOp(db, op, 0)
+	if err != nil {
+		return nil, err
+	}
+	reclaimed := ReclaimMap{}
+	for _, b := range bricks {
+		reclaimed[b.Info.DeviceId] = true
+	}
+	return reclaimed, nil
+}
+
 // expandSizeFromOp returns the size of a volume expand operation assuming
 // the given pending operation entry includes a volume expand change item.
 // If the operation is of the wrong type error will be non-nil.
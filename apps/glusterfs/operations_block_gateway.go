@@ -0,0 +1,212 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+)
+
+// isGateway returns true if the host is one of the gateway hosts.
+func isGateway(hosts []string, host string) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockVolumeMigrateGatewayOperation implements the operation functions
+// used to move the export of a block volume from one gateway node to
+// another, for example ahead of maintenance of the old gateway.
+type BlockVolumeMigrateGatewayOperation struct {
+	OperationManager
+	noRetriesOperation
+	bvolId       string
+	targetNodeId string
+	sourceNodeId string
+
+	// set by Build
+	bvName     string
+	hvName     string
+	cluster    string
+	sourceHost string
+	targetHost string
+}
+
+// NewBlockVolumeMigrateGatewayOperation returns a new
+// BlockVolumeMigrateGatewayOperation populated with the given params.
+// The source node id may be empty if the block volume is exported by
+// a single gateway.
+func NewBlockVolumeMigrateGatewayOperation(
+	bvolId string, db wdb.DB,
+	targetNodeId, sourceNodeId string) *BlockVolumeMigrateGatewayOperation {
+
+	return &BlockVolumeMigrateGatewayOperation{
+		OperationManager: OperationManager{
+			db: db,
+			op: NewPendingOperationEntry(NEW_ID),
+		},
+		bvolId:       bvolId,
+		targetNodeId: targetNodeId,
+		sourceNodeId: sourceNodeId,
+	}
+}
+
+func (bmg *BlockVolumeMigrateGatewayOperation) Label() string {
+	return "Migrate Block Volume Gateway"
+}
+
+func (bmg *BlockVolumeMigrateGatewayOperation) ResourceUrl() string {
+	return fmt.Sprintf("/blockvolumes/%v", bmg.bvolId)
+}
+
+// Build determines the gateways the export is moved between and
+// records the pending change of the block volume in the db.
+func (bmg *BlockVolumeMigrateGatewayOperation) Build(ctx context.Context) error {
+	return bmg.db.Update(func(tx *bolt.Tx) error {
+		bv, err := NewBlockVolumeEntryFromId(tx, bmg.bvolId)
+		if err != nil {
+			return err
+		}
+		if bv.Pending.Id != "" {
+			logger.LogError("Pending block volume %v can not be migrated",
+				bmg.bvolId)
+			return ErrConflict
+		}
+		bhv, err := NewVolumeEntryFromId(tx, bv.Info.BlockHostingVolume)
+		if err != nil {
+			return err
+		}
+		hosts := bv.Info.BlockVolume.Hosts
+
+		target, err := NewNodeEntryFromId(tx, bmg.targetNodeId)
+		if err != nil {
+			return err
+		}
+		if target.Info.ClusterId != bv.Info.Cluster {
+			return fmt.Errorf("Node %v is not in the cluster of block volume %v",
+				target.Info.Id, bv.Info.Id)
+		}
+		if !target.isOnline() {
+			return fmt.Errorf("Node %v is not online", target.Info.Id)
+		}
+		if isGateway(hosts, target.StorageHostName()) {
+			return fmt.Errorf("Node %v is already a gateway of block volume %v",
+				target.Info.Id, bv.Info.Id)
+		}
+
+		var sourceHost string
+		if bmg.sourceNodeId != "" {
+			source, err := NewNodeEntryFromId(tx, bmg.sourceNodeId)
+			if err != nil {
+				return err
+			}
+			sourceHost = source.StorageHostName()
+			if !isGateway(hosts, sourceHost) {
+				return fmt.Errorf("Node %v is not a gateway of block volume %v",
+					source.Info.Id, bv.Info.Id)
+			}
+		} else if len(hosts) == 1 {
+			sourceHost = hosts[0]
+		} else {
+			return fmt.Errorf(
+				"Block volume %v has %v gateways: the source gateway must be given",
+				bv.Info.Id, len(hosts))
+		}
+
+		bmg.bvName = bv.Info.Name
+		bmg.hvName = bhv.Info.Name
+		bmg.cluster = bv.Info.Cluster
+		bmg.sourceHost = sourceHost
+		bmg.targetHost = target.StorageHostName()
+		bmg.op.RecordMigrateBlockGateway(bv)
+		return bmg.op.Save(tx)
+	})
+}
+
+// Exec reads the current configuration of the block volume to make
+// sure it is still exported by the source gateway and then has
+// gluster-block configure the target on the new gateway and remove
+// it from the old one.
+func (bmg *BlockVolumeMigrateGatewayOperation) Exec(ctx context.Context, executor executors.Executor) error {
+	host, err := GetVerifiedManageHostname(bmg.db, executor, bmg.cluster)
+	if err != nil {
+		return err
+	}
+
+	steps := []opStep{
+		{
+			name: "export-source-config",
+			run: func() error {
+				info, err := executor.BlockVolumeInfo(host, bmg.hvName, bmg.bvName)
+				if err != nil {
+					return err
+				}
+				if info != nil && !isGateway(info.BlockHosts, bmg.sourceHost) {
+					return fmt.Errorf("Block volume %v is not exported by %v",
+						bmg.bvName, bmg.sourceHost)
+				}
+				return nil
+			},
+		},
+		{
+			name: "replace-gateway",
+			run: func() error {
+				return executor.BlockVolumeReplace(host,
+					&executors.BlockGatewayReplaceRequest{
+						GlusterVolumeName: bmg.hvName,
+						Name:              bmg.bvName,
+						OldNode:           bmg.sourceHost,
+						NewNode:           bmg.targetHost,
+					})
+			},
+		},
+	}
+	return runSteps(bmg.db, bmg.op, steps)
+}
+
+// Rollback removes the pending operation. Nothing needs to be undone
+// on the storage system as the gateways are only changed by the final
+// step of Exec.
+func (bmg *BlockVolumeMigrateGatewayOperation) Rollback(ctx context.Context, executor executors.Executor) error {
+	return bmg.db.Update(func(tx *bolt.Tx) error {
+		return bmg.op.Delete(tx)
+	})
+}
+
+// Finalize replaces the source gateway with the target gateway in the
+// hosts of the block volume.
+func (bmg *BlockVolumeMigrateGatewayOperation) Finalize() error {
+	return bmg.db.Update(func(tx *bolt.Tx) error {
+		bv, err := NewBlockVolumeEntryFromId(tx, bmg.bvolId)
+		if err != nil {
+			return err
+		}
+		hosts := []string{}
+		for _, h := range bv.Info.BlockVolume.Hosts {
+			if h == bmg.sourceHost {
+				h = bmg.targetHost
+			}
+			hosts = append(hosts, h)
+		}
+		bv.updateHosts(hosts)
+		if err := bv.Save(tx); err != nil {
+			return err
+		}
+		return bmg.op.Delete(tx)
+	})
+}
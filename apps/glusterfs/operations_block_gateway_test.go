@@ -0,0 +1,197 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// gatewayNodes returns the nodes in the cluster of the block volume
+// that are, and that are not, gateways of the block volume.
+func gatewayNodes(t *testing.T, app *App,
+	info *api.BlockVolumeInfoResponse) (gateways, others []*NodeEntry) {
+
+	err := app.db.View(func(tx *bolt.Tx) error {
+		c, err := NewClusterEntryFromId(tx, info.Cluster)
+		if err != nil {
+			return err
+		}
+		for _, nodeId := range c.Info.Nodes {
+			n, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			if isGateway(info.BlockVolume.Hosts, n.StorageHostName()) {
+				gateways = append(gateways, n)
+			} else {
+				others = append(others, n)
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	return
+}
+
+func TestBlockVolumeMigrateGateway(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		1,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+	bv, err := c.BlockVolumeCreate(&api.BlockVolumeCreateRequest{
+		Size:    10,
+		Hacount: 2,
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(bv.BlockVolume.Hosts) == 2,
+		"expected len(bv.BlockVolume.Hosts) == 2, got:", bv.BlockVolume.Hosts)
+	gateways, others := gatewayNodes(t, app, bv)
+	tests.Assert(t, len(others) > 0, "expected a node that is not a gateway")
+	source := gateways[0]
+	target := others[0]
+
+	app.xo.MockBlockVolumeInfo = func(host string, bhv string, name string) (*executors.BlockVolumeInfo, error) {
+		return &executors.BlockVolumeInfo{
+			Name:       name,
+			BlockHosts: bv.BlockVolume.Hosts,
+		}, nil
+	}
+	var replaced *executors.BlockGatewayReplaceRequest
+	app.xo.MockBlockVolumeReplace = func(host string, req *executors.BlockGatewayReplaceRequest) error {
+		replaced = req
+		return nil
+	}
+
+	// the source gateway is required with more than one gateway
+	_, err = c.BlockVolumeMigrateGateway(bv.Id,
+		&api.BlockVolumeMigrateGatewayRequest{
+			TargetGatewayNodeId: target.Info.Id,
+		})
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, replaced == nil, "expected no replace, got:", replaced)
+
+	// the target must not be a gateway already
+	_, err = c.BlockVolumeMigrateGateway(bv.Id,
+		&api.BlockVolumeMigrateGatewayRequest{
+			TargetGatewayNodeId: gateways[1].Info.Id,
+			SourceGatewayNodeId: source.Info.Id,
+		})
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, replaced == nil, "expected no replace, got:", replaced)
+
+	info, err := c.BlockVolumeMigrateGateway(bv.Id,
+		&api.BlockVolumeMigrateGatewayRequest{
+			TargetGatewayNodeId: target.Info.Id,
+			SourceGatewayNodeId: source.Info.Id,
+		})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, replaced != nil, "expected replace request")
+	tests.Assert(t, replaced.Name == bv.Name,
+		"expected replaced.Name == bv.Name, got:", replaced.Name)
+	tests.Assert(t, replaced.OldNode == source.StorageHostName(),
+		"expected replaced.OldNode == source, got:", replaced.OldNode)
+	tests.Assert(t, replaced.NewNode == target.StorageHostName(),
+		"expected replaced.NewNode == target, got:", replaced.NewNode)
+
+	// the gateway reference was updated in the db
+	tests.Assert(t, len(info.BlockVolume.Hosts) == 2,
+		"expected len(info.BlockVolume.Hosts) == 2, got:", info.BlockVolume.Hosts)
+	tests.Assert(t, isGateway(info.BlockVolume.Hosts, target.StorageHostName()),
+		"expected target in hosts, got:", info.BlockVolume.Hosts)
+	tests.Assert(t, !isGateway(info.BlockVolume.Hosts, source.StorageHostName()),
+		"expected source not in hosts, got:", info.BlockVolume.Hosts)
+	tests.Assert(t, isGateway(info.BlockVolume.Hosts, gateways[1].StorageHostName()),
+		"expected other gateway in hosts, got:", info.BlockVolume.Hosts)
+	app.db.View(func(tx *bolt.Tx) error {
+		l, err := PendingOperationList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(l) == 0, "expected len(l) == 0, got:", len(l))
+		return nil
+	})
+}
+
+func TestBlockVolumeMigrateGatewayFails(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		1,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+	bv, err := c.BlockVolumeCreate(&api.BlockVolumeCreateRequest{
+		Size:    10,
+		Hacount: 1,
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, others := gatewayNodes(t, app, bv)
+
+	app.xo.MockBlockVolumeReplace = func(host string, req *executors.BlockGatewayReplaceRequest) error {
+		return fmt.Errorf("replace failed")
+	}
+
+	// with a single gateway the source may be omitted
+	_, err = c.BlockVolumeMigrateGateway(bv.Id,
+		&api.BlockVolumeMigrateGatewayRequest{
+			TargetGatewayNodeId: others[0].Info.Id,
+		})
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewBlockVolumeEntryFromId(tx, bv.Id)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(entry.Info.BlockVolume.Hosts) == 1 &&
+			entry.Info.BlockVolume.Hosts[0] == bv.BlockVolume.Hosts[0],
+			"expected hosts to be unchanged, got:", entry.Info.BlockVolume.Hosts)
+		l, err := PendingOperationList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(l) == 0, "expected len(l) == 0, got:", len(l))
+		return nil
+	})
+}
@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/heketi/heketi/executors"
@@ -53,7 +54,7 @@ func (ro *VolumeSetBlockRestrictionOperation) ResourceUrl() string {
 }
 
 // Build sets the state when adding restrictions.
-func (ro *VolumeSetBlockRestrictionOperation) Build() error {
+func (ro *VolumeSetBlockRestrictionOperation) Build(ctx context.Context) error {
 	if !ro.vol.Info.Block {
 		return fmt.Errorf(
 			"Block restrictions can only be set on block hosting volumes")
@@ -78,7 +79,7 @@ func (ro *VolumeSetBlockRestrictionOperation) Build() error {
 }
 
 // Exec creates new bricks and volume on the underlying glusterfs storage system.
-func (ro *VolumeSetBlockRestrictionOperation) Exec(executor executors.Executor) error {
+func (ro *VolumeSetBlockRestrictionOperation) Exec(ctx context.Context, executor executors.Executor) error {
 	// currently does nothing. should do gluster-block sanity checks in the
 	// future
 	return nil
@@ -107,7 +108,7 @@ func (ro *VolumeSetBlockRestrictionOperation) Finalize() error {
 }
 
 // Rollback does nothing for this operation type.
-func (ro *VolumeSetBlockRestrictionOperation) Rollback(executor executors.Executor) error {
+func (ro *VolumeSetBlockRestrictionOperation) Rollback(ctx context.Context, executor executors.Executor) error {
 	return nil
 }
 
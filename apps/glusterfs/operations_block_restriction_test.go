@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -66,27 +67,27 @@ func TestVolumeSetBlockRestriction(t *testing.T) {
 
 	t.Run("SetLocked", func(t *testing.T) {
 		sro := NewVolumeSetBlockRestrictionOperation(vol, app.db, api.Locked)
-		e := sro.Build()
+		e := sro.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
-		e = sro.Exec(app.executor)
+		e = sro.Exec(context.Background(), app.executor)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		e = sro.Finalize()
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	})
 	t.Run("SetLockedWhenLocked", func(t *testing.T) {
 		sro := NewVolumeSetBlockRestrictionOperation(vol, app.db, api.Locked)
-		e := sro.Build()
+		e := sro.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
-		e = sro.Exec(app.executor)
+		e = sro.Exec(context.Background(), app.executor)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		e = sro.Finalize()
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	})
 	t.Run("SetUnrestricted", func(t *testing.T) {
 		sro := NewVolumeSetBlockRestrictionOperation(vol, app.db, api.Unrestricted)
-		e := sro.Build()
+		e := sro.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
-		e = sro.Exec(app.executor)
+		e = sro.Exec(context.Background(), app.executor)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		e = sro.Finalize()
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -99,9 +100,9 @@ func TestVolumeSetBlockRestriction(t *testing.T) {
 			return nil
 		})
 		sro := NewVolumeSetBlockRestrictionOperation(vol, app.db, api.Unrestricted)
-		e := sro.Build()
+		e := sro.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
-		e = sro.Exec(app.executor)
+		e = sro.Exec(context.Background(), app.executor)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		e = sro.Finalize()
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -124,9 +125,9 @@ func TestVolumeSetBlockRestriction(t *testing.T) {
 			return nil
 		})
 		sro := NewVolumeSetBlockRestrictionOperation(vol, app.db, api.Unrestricted)
-		e := sro.Build()
+		e := sro.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
-		e = sro.Exec(app.executor)
+		e = sro.Exec(context.Background(), app.executor)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		e = sro.Finalize()
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -150,7 +151,7 @@ func TestVolumeSetBlockRestriction(t *testing.T) {
 			return nil
 		})
 		sro := NewVolumeSetBlockRestrictionOperation(vol, app.db, api.Unrestricted)
-		e := sro.Build()
+		e := sro.Build(context.Background())
 		tests.Assert(t, e != nil, "expected e != nil, got:", e)
 	})
 	t.Run("GarbageValue", func(t *testing.T) {
@@ -161,7 +162,7 @@ func TestVolumeSetBlockRestriction(t *testing.T) {
 			return nil
 		})
 		sro := NewVolumeSetBlockRestrictionOperation(vol, app.db, api.Unrestricted)
-		e := sro.Build()
+		e := sro.Build(context.Background())
 		tests.Assert(t, e != nil, "expected e != nil, got:", e)
 	})
 }
@@ -197,6 +198,6 @@ func TestVolumeSetBlockRestrictionNotBHV(t *testing.T) {
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	sro := NewVolumeSetBlockRestrictionOperation(vol, app.db, api.Locked)
-	e = sro.Build()
+	e = sro.Build(context.Background())
 	tests.Assert(t, e != nil, "expected e != nil, got:", e)
 }
@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 
@@ -72,13 +73,21 @@ func (bvc *BlockVolumeCreateOperation) Label() string {
 	return "Create Block Volume"
 }
 
+func (bvc *BlockVolumeCreateOperation) LockKeys() ([]string, error) {
+	return []string{blockVolumeLockKey(bvc.bvol.Info.Id)}, nil
+}
+
+func (bvc *BlockVolumeCreateOperation) AllocationLockKeys() ([]string, error) {
+	return clusterLockKeys(bvc.db, bvc.bvol.Info.Clusters)
+}
+
 func (bvc *BlockVolumeCreateOperation) ResourceUrl() string {
 	return fmt.Sprintf("/blockvolumes/%v", bvc.bvol.Info.Id)
 }
 
 // Build allocates and saves new volume and brick entries (tagged as pending)
 // in the db.
-func (bvc *BlockVolumeCreateOperation) Build() error {
+func (bvc *BlockVolumeCreateOperation) Build(ctx context.Context) error {
 	return bvc.db.Update(func(tx *bolt.Tx) error {
 		txdb := wdb.WrapTx(tx)
 		clusters, volumes, err := bvc.bvol.eligibleClustersAndVolumes(txdb)
@@ -195,7 +204,7 @@ func (bvc *BlockVolumeCreateOperation) volAndBricks(db wdb.RODB) (
 }
 
 // Exec creates new bricks and volume on the underlying glusterfs storage system.
-func (bvc *BlockVolumeCreateOperation) Exec(executor executors.Executor) error {
+func (bvc *BlockVolumeCreateOperation) Exec(ctx context.Context, executor executors.Executor) error {
 	vol, brick_entries, err := bvc.volAndBricks(bvc.db)
 	if err != nil {
 		return err
@@ -264,7 +273,7 @@ func (bvc *BlockVolumeCreateOperation) Finalize() error {
 // Rollback removes any dangling volume and bricks from the underlying storage
 // systems and removes the corresponding pending volume and brick entries from
 // the db.
-func (bvc *BlockVolumeCreateOperation) Rollback(executor executors.Executor) error {
+func (bvc *BlockVolumeCreateOperation) Rollback(ctx context.Context, executor executors.Executor) error {
 	return rollbackViaClean(bvc, executor)
 }
 
@@ -449,11 +458,15 @@ func (bve *BlockVolumeExpandOperation) Label() string {
 	return "Expand Block Volume"
 }
 
+func (bve *BlockVolumeExpandOperation) LockKeys() ([]string, error) {
+	return []string{blockVolumeLockKey(bve.bvolId)}, nil
+}
+
 func (bve *BlockVolumeExpandOperation) ResourceUrl() string {
 	return fmt.Sprintf("/blockvolumes/%v", bve.bvolId)
 }
 
-func (bve *BlockVolumeExpandOperation) Build() error {
+func (bve *BlockVolumeExpandOperation) Build(ctx context.Context) error {
 	var bv *BlockVolumeEntry
 	return bve.db.Update(func(tx *bolt.Tx) error {
 		var err error
@@ -515,7 +528,7 @@ func (bve *BlockVolumeExpandOperation) Build() error {
 	})
 }
 
-func (bve *BlockVolumeExpandOperation) Exec(executor executors.Executor) error {
+func (bve *BlockVolumeExpandOperation) Exec(ctx context.Context, executor executors.Executor) error {
 	var (
 		err     error
 		bv      *BlockVolumeEntry
@@ -556,7 +569,7 @@ func (bve *BlockVolumeExpandOperation) Exec(executor executors.Executor) error {
 	})
 }
 
-func (bve *BlockVolumeExpandOperation) Rollback(executor executors.Executor) error {
+func (bve *BlockVolumeExpandOperation) Rollback(ctx context.Context, executor executors.Executor) error {
 	logger.Info("Starting Rollback for %v op:%v", bve.Label(), bve.op.Id)
 	return rollbackViaClean(bve, executor)
 }
@@ -721,13 +734,17 @@ func (vdel *BlockVolumeDeleteOperation) Label() string {
 	return "Delete Block Volume"
 }
 
+func (vdel *BlockVolumeDeleteOperation) LockKeys() ([]string, error) {
+	return []string{blockVolumeLockKey(vdel.bvol.Info.Id)}, nil
+}
+
 func (vdel *BlockVolumeDeleteOperation) ResourceUrl() string {
 	return ""
 }
 
 // Build determines what volumes and bricks need to be deleted and
 // marks the db entries as such.
-func (vdel *BlockVolumeDeleteOperation) Build() error {
+func (vdel *BlockVolumeDeleteOperation) Build(ctx context.Context) error {
 	return vdel.db.Update(func(tx *bolt.Tx) error {
 		v, err := NewBlockVolumeEntryFromId(tx, vdel.bvol.Info.Id)
 		if err != nil {
@@ -751,7 +768,7 @@ func (vdel *BlockVolumeDeleteOperation) Build() error {
 }
 
 // Exec performs the volume and brick deletions on the storage systems.
-func (vdel *BlockVolumeDeleteOperation) Exec(executor executors.Executor) error {
+func (vdel *BlockVolumeDeleteOperation) Exec(ctx context.Context, executor executors.Executor) error {
 	var (
 		err     error
 		bv      *BlockVolumeEntry
@@ -787,7 +804,7 @@ func (vdel *BlockVolumeDeleteOperation) Exec(executor executors.Executor) error
 	})
 }
 
-func (vdel *BlockVolumeDeleteOperation) Rollback(executor executors.Executor) error {
+func (vdel *BlockVolumeDeleteOperation) Rollback(ctx context.Context, executor executors.Executor) error {
 	// currently rollback only removes the pending operation for delete block volume,
 	// leaving the db in the same state as it was before an exec failure.
 	// In the future we should make this operation resume-able
@@ -823,7 +840,7 @@ func (vdel *BlockVolumeDeleteOperation) Clean(executor executors.Executor) error
 	// for a delete, clean is essentially a replay of exec
 	// because exec must be robust against restarts now we can just call Exec
 	logger.Info("Starting Clean for %v op:%v", vdel.Label(), vdel.op.Id)
-	return vdel.Exec(executor)
+	return vdel.Exec(context.Background(), executor)
 }
 
 func (vdel *BlockVolumeDeleteOperation) CleanDone() error {
@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -46,9 +47,9 @@ func TestBlockHostingVolumeExpandOperation(t *testing.T) {
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	e = vc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -71,10 +72,10 @@ func TestBlockHostingVolumeExpandOperation(t *testing.T) {
 	})
 
 	ve := NewVolumeExpandOperation(vol, app.db, 100)
-	e = ve.Build()
+	e = ve.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
-	e = ve.Exec(app.executor)
+	e = ve.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	e = ve.Finalize()
@@ -134,7 +135,7 @@ func TestBlockVolumeCreateOperation(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify there is one pending op, volume and some bricks
@@ -151,7 +152,7 @@ func TestBlockVolumeCreateOperation(t *testing.T) {
 		return nil
 	})
 
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	e = vc.Finalize()
@@ -211,7 +212,7 @@ func TestBlockVolumeCreateOperationTooLargeSizeRequested(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	error_string := "The size configured for automatic creation of block hosting volumes (1100) is too small to host the requested block volume of size 1100. The available size on this block hosting volume, minus overhead, is 1078. Please create a sufficiently large block hosting volume manually."
 	tests.Assert(t, e != nil, "expected e != nil, got nil")
 	tests.Assert(t, e.Error() == error_string,
@@ -256,7 +257,7 @@ func TestBlockVolumeCreateBlockHostingVolumeCreationDisabled(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	error_string := "Block Hosting Volume Creation is disabled. Create a Block hosting volume and try again."
 	tests.Assert(t, e != nil, "expected e != nil, got nil")
 	tests.Assert(t, e.Error() == error_string,
@@ -290,9 +291,9 @@ func TestBlockVolumeCreateOperationExistingHostVol(t *testing.T) {
 	vol := NewVolumeEntryFromRequest(vreq)
 	vc := NewVolumeCreateOperation(vol, app.db)
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	e = vc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -319,7 +320,7 @@ func TestBlockVolumeCreateOperationExistingHostVol(t *testing.T) {
 	bvol := NewBlockVolumeEntryFromRequest(breq)
 	bco := NewBlockVolumeCreateOperation(bvol, app.db)
 
-	e = bco.Build()
+	e = bco.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	// at this point we shouldn't have a new volume or bricks,
@@ -340,7 +341,7 @@ func TestBlockVolumeCreateOperationExistingHostVol(t *testing.T) {
 		return nil
 	})
 
-	e = bco.Exec(app.executor)
+	e = bco.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	e = bco.Finalize()
@@ -400,7 +401,7 @@ func TestBlockVolumeCreateOperationRollback(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify there is one pending op, volume and some bricks
@@ -417,11 +418,11 @@ func TestBlockVolumeCreateOperationRollback(t *testing.T) {
 		return nil
 	})
 
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// it doesn't matter if exec worked, were going to rollback for test
-	e = vc.Rollback(app.executor)
+	e = vc.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify that everything got trashed
@@ -469,9 +470,9 @@ func TestBlockVolumeCreateOperationExistingHostVolRollback(t *testing.T) {
 	vol := NewVolumeEntryFromRequest(vreq)
 	vc := NewVolumeCreateOperation(vol, app.db)
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	e = vc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -498,7 +499,7 @@ func TestBlockVolumeCreateOperationExistingHostVolRollback(t *testing.T) {
 	bvol := NewBlockVolumeEntryFromRequest(breq)
 	bco := NewBlockVolumeCreateOperation(bvol, app.db)
 
-	e = bco.Build()
+	e = bco.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	// at this point we shouldn't have a new volume or bricks,
@@ -519,11 +520,11 @@ func TestBlockVolumeCreateOperationExistingHostVolRollback(t *testing.T) {
 		return nil
 	})
 
-	e = bco.Exec(app.executor)
+	e = bco.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	// it doesn't matter if exec worked, were going to rollback for test
-	e = bco.Rollback(app.executor)
+	e = bco.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify that only the block volume got trashed
@@ -580,9 +581,9 @@ func TestBlockVolumeDeleteOperation(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 	e = vc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
@@ -606,7 +607,7 @@ func TestBlockVolumeDeleteOperation(t *testing.T) {
 
 	bdel := NewBlockVolumeDeleteOperation(vol, app.db)
 
-	e = bdel.Build()
+	e = bdel.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// we should now have a pending op for the delete
@@ -626,7 +627,7 @@ func TestBlockVolumeDeleteOperation(t *testing.T) {
 		return nil
 	})
 
-	e = bdel.Exec(app.executor)
+	e = bdel.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	e = bdel.Finalize()
@@ -686,9 +687,9 @@ func TestBlockVolumeDeleteOperationRollback(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 	e = vc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
@@ -712,7 +713,7 @@ func TestBlockVolumeDeleteOperationRollback(t *testing.T) {
 
 	bdel := NewBlockVolumeDeleteOperation(vol, app.db)
 
-	e = bdel.Build()
+	e = bdel.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// we should now have a pending op for the delete
@@ -732,7 +733,7 @@ func TestBlockVolumeDeleteOperationRollback(t *testing.T) {
 		return nil
 	})
 
-	e = bdel.Rollback(app.executor)
+	e = bdel.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// the pending op should be gone, but other items remain
@@ -782,9 +783,9 @@ func TestBlockVolumeDeleteOperationTwice(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 	e = vc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
@@ -801,7 +802,7 @@ func TestBlockVolumeDeleteOperationTwice(t *testing.T) {
 	tests.Assert(t, vol2.Pending.Id == "")
 
 	bdel := NewBlockVolumeDeleteOperation(vol, app.db)
-	e = bdel.Build()
+	e = bdel.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.View(func(tx *bolt.Tx) error {
@@ -812,7 +813,7 @@ func TestBlockVolumeDeleteOperationTwice(t *testing.T) {
 	})
 
 	bdel2 := NewBlockVolumeDeleteOperation(vol2, app.db)
-	e = bdel2.Build()
+	e = bdel2.Build(context.Background())
 	tests.Assert(t, e == ErrConflict, "expected e ErrConflict, got", e)
 
 	app.db.View(func(tx *bolt.Tx) error {
@@ -862,7 +863,7 @@ func TestBlockVolumeCreateBuildRollback(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.View(func(tx *bolt.Tx) error {
@@ -875,7 +876,7 @@ func TestBlockVolumeCreateBuildRollback(t *testing.T) {
 		return nil
 	})
 
-	e = vc.Rollback(app.executor)
+	e = vc.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.View(func(tx *bolt.Tx) error {
@@ -960,15 +961,15 @@ func TestBlockVolumesCreateRejectPendingBHV(t *testing.T) {
 		return nil
 	})
 
-	e := vc1.Build()
+	e := vc1.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
-	e = vc2.Build()
+	e = vc2.Build(context.Background())
 	tests.Assert(t, e != nil, "expected e != nil, got", e)
 	tests.Assert(t, e == ErrTooManyOperations,
 		"expected e == ErrTooManyOperations, got:", e)
 
-	e = vc1.Exec(app.executor)
+	e = vc1.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	e = vc1.Finalize()
@@ -988,9 +989,9 @@ func TestBlockVolumesCreateRejectPendingBHV(t *testing.T) {
 	// try the same request again
 	// it should work and used the just created BHV
 	vc2 = NewBlockVolumeCreateOperation(vol2, app.db)
-	e = vc2.Build()
+	e = vc2.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
-	e = vc2.Exec(app.executor)
+	e = vc2.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 	e = vc2.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
@@ -1042,7 +1043,7 @@ func TestBlockVolumesCreatePendingBHVIgnoredItems(t *testing.T) {
 		return nil
 	})
 
-	e := vc1.Build()
+	e := vc1.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// make the current pending operation stale
@@ -1065,13 +1066,13 @@ func TestBlockVolumesCreatePendingBHVIgnoredItems(t *testing.T) {
 	req2.Size = 5
 	vol := NewVolumeEntryFromRequest(req2)
 	vco := NewVolumeCreateOperation(vol, app.db)
-	e = vco.Build()
+	e = vco.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
-	e = vc2.Build()
+	e = vc2.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
-	e = vc2.Exec(app.executor)
+	e = vc2.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	e = vc2.Finalize()
@@ -1122,7 +1123,7 @@ func TestBlockVolumeCreateRollbackCleanupFailure(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// now we're going to pretend exec failed and inject an
@@ -1131,7 +1132,7 @@ func TestBlockVolumeCreateRollbackCleanupFailure(t *testing.T) {
 		return fmt.Errorf("fake error")
 	}
 
-	e = vc.Rollback(app.executor)
+	e = vc.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e != nil, "expected e != nil, got", e)
 
 	// verify that the pending items remain in the db due to rollback
@@ -1181,9 +1182,9 @@ func TestBlockVolumeCreateOperationOveruse(t *testing.T) {
 	vol := NewVolumeEntryFromRequest(vreq)
 	vc := NewVolumeCreateOperation(vol, app.db)
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	e = vc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -1210,10 +1211,10 @@ func TestBlockVolumeCreateOperationOveruse(t *testing.T) {
 	bco1 := NewBlockVolumeCreateOperation(bvol1, app.db)
 	bco2 := NewBlockVolumeCreateOperation(bvol2, app.db)
 
-	e = bco1.Build()
+	e = bco1.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
-	e = bco2.Build()
+	e = bco2.Build(context.Background())
 	tests.Assert(t, e != nil, "expected e != nil, got:", e)
 
 	// at this point we shouldn't have a new volume or bricks,
@@ -1231,7 +1232,7 @@ func TestBlockVolumeCreateOperationOveruse(t *testing.T) {
 		return nil
 	})
 
-	e = bco1.Exec(app.executor)
+	e = bco1.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	e = bco1.Finalize()
@@ -1283,9 +1284,9 @@ func TestBlockVolumeCreateOperationLockedBHV(t *testing.T) {
 	vol := NewVolumeEntryFromRequest(vreq)
 	vc := NewVolumeCreateOperation(vol, app.db)
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	e = vc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -1308,9 +1309,9 @@ func TestBlockVolumeCreateOperationLockedBHV(t *testing.T) {
 
 	bvol1 := NewBlockVolumeEntryFromRequest(breq)
 	bco1 := NewBlockVolumeCreateOperation(bvol1, app.db)
-	e = bco1.Build()
+	e = bco1.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
-	e = bco1.Exec(app.executor)
+	e = bco1.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	e = bco1.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -1344,7 +1345,7 @@ func TestBlockVolumeCreateOperationLockedBHV(t *testing.T) {
 
 	bvol2 := NewBlockVolumeEntryFromRequest(breq)
 	bco2 := NewBlockVolumeCreateOperation(bvol2, app.db)
-	e = bco2.Build()
+	e = bco2.Build(context.Background())
 	tests.Assert(t, e != nil, "expected e != nil, got:", e)
 
 	// check that db state is unchanged
@@ -1456,9 +1457,9 @@ func TestListCompleteBlockVolumesDuringOperation(t *testing.T) {
 		req.Size = 1024
 		bvol := NewBlockVolumeEntryFromRequest(req)
 		bvco := NewBlockVolumeCreateOperation(bvol, app.db)
-		e := bvco.Build()
+		e := bvco.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
-		defer bvco.Rollback(app.executor)
+		defer bvco.Rollback(context.Background(), app.executor)
 
 		app.db.View(func(tx *bolt.Tx) error {
 			vols, err := ListCompleteBlockVolumes(tx)
@@ -1493,10 +1494,10 @@ func TestListCompleteBlockVolumesDuringOperation(t *testing.T) {
 		})
 
 		bvdo := NewBlockVolumeDeleteOperation(bvol, app.db)
-		e = bvdo.Build()
+		e = bvdo.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		defer func() {
-			e := bvdo.Exec(app.executor)
+			e := bvdo.Exec(context.Background(), app.executor)
 			tests.Assert(t, e == nil, "expected e == nil, got:", e)
 			e = bvdo.Finalize()
 			tests.Assert(t, e == nil, "expected e == nil, got:", e)
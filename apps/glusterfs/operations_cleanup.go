@@ -210,6 +210,66 @@ func (boc *backgroundOperationCleaner) Stop() {
 	boc.stop <- true
 }
 
+// backgroundOperationArchiver periodically moves old failed and stale
+// pending operations to the archive bucket of the db.
+type backgroundOperationArchiver struct {
+	db wdb.DB
+
+	// operations tracked by the server are never archived
+	optracker *OpTracker
+
+	// age of the operations to archive
+	ArchiveAfter time.Duration
+	// timing params
+	CheckInterval time.Duration
+
+	// to stop the archiver
+	stop chan<- interface{}
+}
+
+// Archive moves the failed and stale pending operations older than
+// ArchiveAfter to the archive bucket and returns how many were moved.
+func (boa *backgroundOperationArchiver) Archive() (int, error) {
+	before := operationTimestamp() - int64(boa.ArchiveAfter/time.Second)
+	tracked := boa.optracker.Tracked()
+	return ArchivePendingOperationsBefore(boa.db, before,
+		DEFAULT_OP_CLEANUP_BATCH_SIZE,
+		func(id string) bool { return tracked[id] })
+}
+
+// Start creates a background goroutine to run periodic archivals
+// of old pending operations.
+func (boa *backgroundOperationArchiver) Start() {
+	ticker := time.NewTicker(boa.CheckInterval)
+	stop := make(chan interface{})
+	boa.stop = stop
+
+	go func() {
+		logger.Info("Started background pending operations archiver")
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				logger.Info("Stopping background pending operations archiver")
+				return
+			case <-ticker.C:
+				n, err := boa.Archive()
+				if err != nil {
+					logger.LogError(
+						"Background pending operations archiver: %v", err)
+				} else if n > 0 {
+					logger.Info("Archived %v pending operations", n)
+				}
+			}
+		}
+	}()
+}
+
+// Stop the background operations archiver.
+func (boa *backgroundOperationArchiver) Stop() {
+	boa.stop <- true
+}
+
 func CleanAll(p *PendingOperationEntry) bool {
 	return p.Status == StaleOperation || p.Status == FailedOperation
 }
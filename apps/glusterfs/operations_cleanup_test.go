@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -44,7 +45,7 @@ func TestBasicOperationsCleanup(t *testing.T) {
 
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
@@ -111,18 +112,18 @@ func TestOperationsCleanupThreeOps(t *testing.T) {
 	// create 1st pending op
 	vol = NewVolumeEntryFromRequest(req)
 	vc = NewVolumeCreateOperation(vol, app.db)
-	e = vc.Build()
+	e = vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// create 2nd pending op
 	vol = NewVolumeEntryFromRequest(req)
 	vc = NewVolumeCreateOperation(vol, app.db)
-	e = vc.Build()
+	e = vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// create 3rd pending op
 	vdel := NewVolumeDeleteOperation(dvol, app.db)
-	e = vdel.Build()
+	e = vdel.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
@@ -187,7 +188,7 @@ func TestOperationsCleanupSkipNonLoadable(t *testing.T) {
 
 	// clone is the last non loadable operation
 	vco := NewVolumeCloneOperation(vol, app.db, "foo")
-	e = vco.Build()
+	e = vco.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
@@ -251,7 +252,7 @@ func TestOperationsCleanupVolumeExpand(t *testing.T) {
 	})
 
 	ve := NewVolumeExpandOperation(vol, app.db, 50)
-	e = ve.Build()
+	e = ve.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
@@ -307,7 +308,7 @@ func TestOperationsCleanupBlockVolumeCreate(t *testing.T) {
 
 	vol := NewBlockVolumeEntryFromRequest(req)
 	vc := NewBlockVolumeCreateOperation(vol, app.db)
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
@@ -360,7 +361,7 @@ func TestOperationsCleanupBlockVolumeDelete(t *testing.T) {
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	vdel := NewBlockVolumeDeleteOperation(vol, app.db)
-	e = vdel.Build()
+	e = vdel.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
@@ -411,7 +412,7 @@ func TestOperationsCleanupCleanFail(t *testing.T) {
 
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
@@ -469,7 +470,7 @@ func TestOperationsCleanupBrokenOp(t *testing.T) {
 
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
@@ -549,7 +550,7 @@ func TestOperationCleanerMarkStale(t *testing.T) {
 
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
@@ -648,7 +649,7 @@ func TestOperationCleanerBlockedByThrottle(t *testing.T) {
 
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
@@ -796,7 +797,7 @@ func TestBackgroundOperationCleaner(t *testing.T) {
 
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
@@ -833,7 +834,7 @@ func TestBackgroundOperationCleaner(t *testing.T) {
 	req.Name = "vol2"
 	vol2 := NewVolumeEntryFromRequest(req)
 	vc = NewVolumeCreateOperation(vol2, app.db)
-	e = vc.Build()
+	e = vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
@@ -879,7 +880,7 @@ func TestBackgroundOperationCleanerWithTracking(t *testing.T) {
 
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
@@ -920,7 +921,7 @@ func TestBackgroundOperationCleanerWithTracking(t *testing.T) {
 	req.Name = "vol2"
 	vol2 := NewVolumeEntryFromRequest(req)
 	vc = NewVolumeCreateOperation(vol2, app.db)
-	e = vc.Build()
+	e = vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	app.db.Update(func(tx *bolt.Tx) error {
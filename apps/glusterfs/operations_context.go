@@ -0,0 +1,412 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/heketi/heketi/executors"
+)
+
+// OperationCanceledError is returned when an operation stopped
+// because its context was canceled. The pending operation entry is
+// left in the db, marked failed, for the background cleaner.
+type OperationCanceledError struct {
+	Id    string
+	Label string
+	Cause error
+}
+
+func (oce OperationCanceledError) Error() string {
+	return fmt.Sprintf("%v (%v) canceled: %v", oce.Label, oce.Id, oce.Cause)
+}
+
+// ctxExecutor wraps an executor such that no further commands are
+// sent to the storage nodes once the context is done.
+type ctxExecutor struct {
+	ctx context.Context
+	e   executors.Executor
+}
+
+func newCtxExecutor(ctx context.Context, e executors.Executor) executors.Executor {
+	if ce, ok := e.(*ctxExecutor); ok && ce.ctx == ctx {
+		return e
+	}
+	return &ctxExecutor{ctx: ctx, e: e}
+}
+
+func (ce *ctxExecutor) GlusterdCheck(host string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.GlusterdCheck(host)
+}
+
+func (ce *ctxExecutor) NodePing(host string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.NodePing(host)
+}
+
+func (ce *ctxExecutor) PeerProbe(exec_host, newnode string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.PeerProbe(exec_host, newnode)
+}
+
+func (ce *ctxExecutor) PeerDetach(exec_host, detachnode string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.PeerDetach(exec_host, detachnode)
+}
+
+func (ce *ctxExecutor) PeerStatus(host string) ([]executors.Peer, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.PeerStatus(host)
+}
+
+func (ce *ctxExecutor) DeviceSetup(host, device, vgid string, destroy bool) (*executors.DeviceInfo, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.DeviceSetup(host, device, vgid, destroy)
+}
+
+func (ce *ctxExecutor) GetDeviceInfo(host string, dh *executors.DeviceVgHandle) (*executors.DeviceInfo, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.GetDeviceInfo(host, dh)
+}
+
+func (ce *ctxExecutor) DeviceTeardown(host string, dh *executors.DeviceVgHandle) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.DeviceTeardown(host, dh)
+}
+
+func (ce *ctxExecutor) DeviceForget(host string, dh *executors.DeviceVgHandle) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.DeviceForget(host, dh)
+}
+
+func (ce *ctxExecutor) DeviceReinitialize(host, device, vgid string) (*executors.DeviceInfo, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.DeviceReinitialize(host, device, vgid)
+}
+
+func (ce *ctxExecutor) BrickCreate(host string, brick *executors.BrickRequest) (*executors.BrickInfo, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.BrickCreate(host, brick)
+}
+
+func (ce *ctxExecutor) BrickDestroy(host string, brick *executors.BrickRequest) (bool, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return false, err
+	}
+	return ce.e.BrickDestroy(host, brick)
+}
+
+func (ce *ctxExecutor) BrickLayoutCheck(host string, path string) (*executors.BrickLvInfo, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.BrickLayoutCheck(host, path)
+}
+
+func (ce *ctxExecutor) VolumeCreate(host string, volume *executors.VolumeRequest) (*executors.Volume, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.VolumeCreate(host, volume)
+}
+
+func (ce *ctxExecutor) VolumeDestroy(host string, volume string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.VolumeDestroy(host, volume)
+}
+
+func (ce *ctxExecutor) VolumeDestroyCheck(host, volume string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.VolumeDestroyCheck(host, volume)
+}
+
+func (ce *ctxExecutor) VolumeExpand(host string, volume *executors.VolumeRequest) (*executors.Volume, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.VolumeExpand(host, volume)
+}
+
+func (ce *ctxExecutor) VolumeReplaceBrick(host string, volume string, oldBrick *executors.BrickInfo, newBrick *executors.BrickInfo) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.VolumeReplaceBrick(host, volume, oldBrick, newBrick)
+}
+
+func (ce *ctxExecutor) VolumeInfo(host string, volume string) (*executors.Volume, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.VolumeInfo(host, volume)
+}
+
+func (ce *ctxExecutor) VolumesInfo(host string) (*executors.VolInfo, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.VolumesInfo(host)
+}
+
+func (ce *ctxExecutor) VolumeClone(host string, vsr *executors.VolumeCloneRequest) (*executors.Volume, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.VolumeClone(host, vsr)
+}
+
+func (ce *ctxExecutor) VolumeSnapshot(host string, vsr *executors.VolumeSnapshotRequest) (*executors.Snapshot, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.VolumeSnapshot(host, vsr)
+}
+
+func (ce *ctxExecutor) VolumeModify(host string, mod *executors.VolumeModifyRequest) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.VolumeModify(host, mod)
+}
+
+func (ce *ctxExecutor) SnapshotCloneVolume(host string, scr *executors.SnapshotCloneRequest) (*executors.Volume, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.SnapshotCloneVolume(host, scr)
+}
+
+func (ce *ctxExecutor) SnapshotCloneBlockVolume(host string, scr *executors.SnapshotCloneRequest) (*executors.BlockVolumeInfo, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.SnapshotCloneBlockVolume(host, scr)
+}
+
+func (ce *ctxExecutor) SnapshotDestroy(host string, snapshot string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.SnapshotDestroy(host, snapshot)
+}
+
+func (ce *ctxExecutor) SnapshotConfigSet(host string, option string, value string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.SnapshotConfigSet(host, option, value)
+}
+
+func (ce *ctxExecutor) SnapshotScheduleSet(host string, volume string, schedule string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.SnapshotScheduleSet(host, volume, schedule)
+}
+
+func (ce *ctxExecutor) HealInfo(host string, volume string) (*executors.HealInfo, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.HealInfo(host, volume)
+}
+
+func (ce *ctxExecutor) VolumeStatus(host string, volume string) (*executors.VolumeStatus, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.VolumeStatus(host, volume)
+}
+
+func (ce *ctxExecutor) HealSplitBrainInfo(host string, volume string) (*executors.HealInfo, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.HealSplitBrainInfo(host, volume)
+}
+
+func (ce *ctxExecutor) HealSplitBrainResolve(host string, volume string, file string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.HealSplitBrainResolve(host, volume, file)
+}
+
+func (ce *ctxExecutor) VolumeRebalanceStatus(host string, volume string) (*executors.RebalanceStatus, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.VolumeRebalanceStatus(host, volume)
+}
+
+func (ce *ctxExecutor) VolumeRebalanceStart(host string, volume string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.VolumeRebalanceStart(host, volume)
+}
+
+func (ce *ctxExecutor) VolumeBitrotStatus(host string, volume string) (*executors.BitrotStatus, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.VolumeBitrotStatus(host, volume)
+}
+
+func (ce *ctxExecutor) VolumeGeoRepStatus(host string, volume string, slave string) (*executors.GeoRepStatus, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.VolumeGeoRepStatus(host, volume, slave)
+}
+
+func (ce *ctxExecutor) VolumeProfileStart(host string, volume string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.VolumeProfileStart(host, volume)
+}
+
+func (ce *ctxExecutor) VolumeProfileStop(host string, volume string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.VolumeProfileStop(host, volume)
+}
+
+func (ce *ctxExecutor) VolumeProfileInfo(host string, volume string) (*executors.VolumeProfile, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.VolumeProfileInfo(host, volume)
+}
+
+func (ce *ctxExecutor) VolumeStatedump(host string, volume string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.VolumeStatedump(host, volume)
+}
+
+func (ce *ctxExecutor) LvmSnapshotCreate(host string, snap *executors.LvmSnapshotRequest) (*executors.LvmSnapshotInfo, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.LvmSnapshotCreate(host, snap)
+}
+
+func (ce *ctxExecutor) LvmSnapshotDestroy(host string, snap *executors.LvmSnapshotRequest) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.LvmSnapshotDestroy(host, snap)
+}
+
+func (ce *ctxExecutor) SetLogLevel(level string) {
+	ce.e.SetLogLevel(level)
+}
+
+func (ce *ctxExecutor) BlockVolumeCreate(host string, blockVolume *executors.BlockVolumeRequest) (*executors.BlockVolumeInfo, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.BlockVolumeCreate(host, blockVolume)
+}
+
+func (ce *ctxExecutor) BlockVolumeDestroy(host string, blockHostingVolumeName string, blockVolumeName string) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.BlockVolumeDestroy(host, blockHostingVolumeName, blockVolumeName)
+}
+
+func (ce *ctxExecutor) BlockVolumeExpand(host string, blockHostingVolumeName string, blockVolumeName string, newSize int) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.BlockVolumeExpand(host, blockHostingVolumeName, blockVolumeName, newSize)
+}
+
+func (ce *ctxExecutor) BlockVolumeReplace(host string, req *executors.BlockGatewayReplaceRequest) error {
+	if err := ce.ctx.Err(); err != nil {
+		return err
+	}
+	return ce.e.BlockVolumeReplace(host, req)
+}
+
+func (ce *ctxExecutor) BlockVolumeInfo(host string, blockhostingvolume string, blockVolumeName string) (*executors.BlockVolumeInfo, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.BlockVolumeInfo(host, blockhostingvolume, blockVolumeName)
+}
+
+func (ce *ctxExecutor) PVS(host string) (*executors.PVSCommandOutput, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.PVS(host)
+}
+
+func (ce *ctxExecutor) VGS(host string) (*executors.VGSCommandOutput, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.VGS(host)
+}
+
+func (ce *ctxExecutor) LVS(host string) (*executors.LVSCommandOutput, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.LVS(host)
+}
+
+func (ce *ctxExecutor) GetBrickMountStatus(host string) (*executors.BricksMountStatus, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.GetBrickMountStatus(host)
+}
+
+func (ce *ctxExecutor) ListBlockVolumes(host string, blockhostingvolume string) ([]string, error) {
+	if err := ce.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ce.e.ListBlockVolumes(host, blockhostingvolume)
+}
@@ -0,0 +1,143 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/tests"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+func TestCtxExecutor(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	calls := 0
+	app.xo.MockGlusterdCheck = func(host string) error {
+		calls++
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := newCtxExecutor(ctx, app.executor)
+	tests.Assert(t, newCtxExecutor(ctx, e) == e,
+		"expected executor not to be wrapped twice")
+
+	err := e.GlusterdCheck("host1")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, calls == 1, "expected calls == 1, got:", calls)
+
+	cancel()
+	err = e.GlusterdCheck("host1")
+	tests.Assert(t, err == context.Canceled,
+		"expected err == context.Canceled, got:", err)
+	tests.Assert(t, calls == 1, "expected calls == 1, got:", calls)
+}
+
+func TestRunOperationCanceled(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// cancel the context while the bricks are being created and
+	// count every command that reaches the storage nodes afterwards
+	var after int32
+	counted := func() {
+		if ctx.Err() != nil {
+			atomic.AddInt32(&after, 1)
+		}
+	}
+	app.xo.MockBrickCreate = func(host string, brick *executors.BrickRequest) (*executors.BrickInfo, error) {
+		cancel()
+		return &executors.BrickInfo{
+			Path: brick.Path,
+		}, nil
+	}
+	app.xo.MockGlusterdCheck = func(host string) error {
+		counted()
+		return nil
+	}
+	app.xo.MockVolumeCreate = func(host string, volume *executors.VolumeRequest) (*executors.Volume, error) {
+		counted()
+		return &executors.Volume{}, nil
+	}
+	app.xo.MockBrickDestroy = func(host string, brick *executors.BrickRequest) (bool, error) {
+		counted()
+		return true, nil
+	}
+	app.xo.MockVolumeDestroy = func(host string, volume string) error {
+		counted()
+		return nil
+	}
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+	vc := NewVolumeCreateOperation(NewVolumeEntryFromRequest(req), app.db)
+
+	err = RunOperationContext(ctx, vc, app.executor)
+	tests.Assert(t, err != nil, "expected err != nil")
+	cerr, ok := err.(OperationCanceledError)
+	tests.Assert(t, ok, "expected OperationCanceledError, got:", err)
+	tests.Assert(t, cerr.Id == vc.Id(),
+		"expected cerr.Id == vc.Id(), got:", cerr.Id)
+	tests.Assert(t, cerr.Cause == context.Canceled,
+		"expected cerr.Cause == context.Canceled, got:", cerr.Cause)
+	tests.Assert(t, atomic.LoadInt32(&after) == 0,
+		"expected no commands after cancellation, got:", after)
+
+	// the operation was not rolled back, it is left for the cleaner
+	app.db.View(func(tx *bolt.Tx) error {
+		p, err := NewPendingOperationEntryFromId(tx, vc.Id())
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, p.Status == FailedOperation,
+			"expected p.Status == FailedOperation, got:", p.Status)
+		bl, err := BrickList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(bl) == 3, "expected len(bl) == 3, got:", len(bl))
+		return nil
+	})
+
+	// an already canceled context does not build the operation
+	vc = NewVolumeCreateOperation(NewVolumeEntryFromRequest(req), app.db)
+	err = RunOperationContext(ctx, vc, app.executor)
+	_, ok = err.(OperationCanceledError)
+	tests.Assert(t, ok, "expected OperationCanceledError, got:", err)
+	app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewPendingOperationEntryFromId(tx, vc.Id())
+		tests.Assert(t, err == ErrNotFound,
+			"expected err == ErrNotFound, got:", err)
+		return nil
+	})
+}
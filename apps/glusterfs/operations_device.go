@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/heketi/heketi/executors"
@@ -82,7 +83,7 @@ func (dro *DeviceRemoveOperation) ResourceUrl() string {
 	return ""
 }
 
-func (dro *DeviceRemoveOperation) Build() error {
+func (dro *DeviceRemoveOperation) Build(ctx context.Context) error {
 	return dro.db.Update(func(tx *bolt.Tx) error {
 		d, err := NewDeviceEntryFromId(tx, dro.DeviceId)
 		if err != nil {
@@ -132,7 +133,7 @@ func (dro *DeviceRemoveOperation) deviceId() (string, error) {
 	return dro.op.Actions[0].Id, nil
 }
 
-func (dro *DeviceRemoveOperation) Exec(executor executors.Executor) error {
+func (dro *DeviceRemoveOperation) Exec(ctx context.Context, executor executors.Executor) error {
 	id, err := dro.deviceId()
 	if err != nil {
 		return err
@@ -149,10 +150,10 @@ func (dro *DeviceRemoveOperation) Exec(executor executors.Executor) error {
 		return e
 	}
 
-	return dro.migrateBricks(executor, d)
+	return dro.migrateBricks(ctx, executor, d)
 }
 
-func (dro *DeviceRemoveOperation) migrateBricks(
+func (dro *DeviceRemoveOperation) migrateBricks(ctx context.Context,
 	executor executors.Executor, d *DeviceEntry) error {
 
 	toEvict, err := d.removeableBricks(dro.db)
@@ -161,9 +162,9 @@ func (dro *DeviceRemoveOperation) migrateBricks(
 	}
 	for _, brickId := range toEvict {
 		nestedOp := newRemoveBrickComboOperation(
-			dro,
+			&dro.OperationManager,
 			NewBrickEvictOperation(brickId, dro.db, dro.healCheck))
-		err = RunOperation(nestedOp, executor)
+		err = RunOperationContext(ctx, nestedOp, executor)
 		if err != nil {
 			return err
 		}
@@ -171,40 +172,7 @@ func (dro *DeviceRemoveOperation) migrateBricks(
 	return nil
 }
 
-func (dro *DeviceRemoveOperation) updateChildOperation(
-	db wdb.DB, childOp *PendingOperationEntry) error {
-
-	return db.Update(func(tx *bolt.Tx) error {
-		var err error
-		dro.op, err = NewPendingOperationEntryFromId(tx, dro.op.Id)
-		if err != nil {
-			return err
-		}
-		dro.op.RecordChild(childOp)
-		// RecordChild alters both parent and child so save them both
-		if err := childOp.Save(tx); err != nil {
-			return err
-		}
-		if err := dro.op.Save(tx); err != nil {
-			return err
-		}
-		return nil
-	})
-}
-
-func (dro *DeviceRemoveOperation) clearChildOperation(db wdb.DB) error {
-	return db.Update(func(tx *bolt.Tx) error {
-		var err error
-		dro.op, err = NewPendingOperationEntryFromId(tx, dro.op.Id)
-		if err != nil {
-			return err
-		}
-		dro.op.ClearChild()
-		return dro.op.Save(tx)
-	})
-}
-
-func (dro *DeviceRemoveOperation) Rollback(executor executors.Executor) error {
+func (dro *DeviceRemoveOperation) Rollback(ctx context.Context, executor executors.Executor) error {
 	return rollbackViaClean(dro, executor)
 }
 
@@ -255,7 +223,7 @@ func (dro *DeviceRemoveOperation) Clean(executor executors.Executor) error {
 		// only to need to swap it around later :-(
 		brickEvictOp.db = dro.db
 		dro.currentChild = brickEvictOp
-		nestedOp := newRemoveBrickComboOperation(dro, dro.currentChild)
+		nestedOp := newRemoveBrickComboOperation(&dro.OperationManager, dro.currentChild)
 		return nestedOp.Clean(executor)
 	}
 	return nil
@@ -277,7 +245,7 @@ func (dro *DeviceRemoveOperation) CleanDone() error {
 	if dro.currentChild != nil {
 		logger.Info("need to finish clean child [%s] of %s [%s]",
 			dro.currentChild.Id(), dro.Label(), dro.Id())
-		nestedOp := newRemoveBrickComboOperation(dro, dro.currentChild)
+		nestedOp := newRemoveBrickComboOperation(&dro.OperationManager, dro.currentChild)
 		if err := nestedOp.CleanDone(); err != nil {
 			return err
 		}
@@ -331,6 +299,9 @@ type BrickEvictOperation struct {
 
 	healCheck api.HealInfoCheck
 
+	// optionally restricts the devices the new brick may be placed on
+	deviceFilter DeviceFilter
+
 	// internal caching params
 	replaceBrickSet *BrickSet
 	replaceIndex    int
@@ -398,7 +369,7 @@ func (beo *BrickEvictOperation) ResourceUrl() string {
 	return ""
 }
 
-func (beo *BrickEvictOperation) Build() error {
+func (beo *BrickEvictOperation) Build(ctx context.Context) error {
 	// this build is pretty minimal as it only can record the brick
 	// in need of eviction. The replacement brick can not be determined
 	// without running (gluster) commands.
@@ -449,7 +420,8 @@ func (beo *BrickEvictOperation) buildNewBrick(bs *BrickSet, index int) error {
 		}
 		// determine the placement for the new brick
 		newBrickEntry, newDeviceEntry, err := old.volume.allocBrickReplacement(
-			wdb.WrapTx(tx), old.brick, old.device, bs, index)
+			wdb.WrapTx(tx), old.brick, old.device, bs, index,
+			beo.deviceFilter)
 		if err != nil {
 			return err
 		}
@@ -594,7 +566,7 @@ func (beo *BrickEvictOperation) execReplaceBrick(
 	return err
 }
 
-func (beo *BrickEvictOperation) Exec(executor executors.Executor) error {
+func (beo *BrickEvictOperation) Exec(ctx context.Context, executor executors.Executor) error {
 	// PHASE I
 	// first we need to determine a) if we can remove a brick at this
 	// time. and b) what brick set our current brick belongs to.
@@ -613,7 +585,7 @@ func (beo *BrickEvictOperation) Exec(executor executors.Executor) error {
 	return beo.execReplaceBrick(executor)
 }
 
-func (beo *BrickEvictOperation) Rollback(executor executors.Executor) error {
+func (beo *BrickEvictOperation) Rollback(ctx context.Context, executor executors.Executor) error {
 	return rollbackViaClean(beo, executor)
 }
 
@@ -882,31 +854,31 @@ func evictStatus(op *PendingOperationEntry) (brickEvictStatus, error) {
 }
 
 // removeBrickComboOperation are ephemeral operations that combine
-// db changes for the parent operation (device remove) and child
+// db changes for the parent operation (device remove, volume repair) and child
 // (brick evict) such that certain changes to both are made within
 // a single db transaction but we can still re-use as much of the
 // existing functions from the child.
 type removeBrickComboOperation struct {
 	noRetriesOperation
 
-	deviceRemoveOp *DeviceRemoveOperation
-	brickEvictOp   *BrickEvictOperation
+	parentOp     *OperationManager
+	brickEvictOp *BrickEvictOperation
 }
 
-func newRemoveBrickComboOperation(dro *DeviceRemoveOperation, beo *BrickEvictOperation) *removeBrickComboOperation {
+func newRemoveBrickComboOperation(parent *OperationManager, beo *BrickEvictOperation) *removeBrickComboOperation {
 
 	return &removeBrickComboOperation{
-		deviceRemoveOp: dro,
-		brickEvictOp:   beo,
+		parentOp:     parent,
+		brickEvictOp: beo,
 	}
 }
 
 func (bco *removeBrickComboOperation) Id() string {
-	return bco.deviceRemoveOp.Id()
+	return bco.parentOp.Id()
 }
 
 func (bco *removeBrickComboOperation) Label() string {
-	return "Remove Brick from Device"
+	return "Remove Brick for Parent Operation"
 }
 
 func (bco *removeBrickComboOperation) ResourceUrl() string {
@@ -919,48 +891,48 @@ func (bco *removeBrickComboOperation) childPushDB(db wdb.DB) {
 }
 
 func (bco *removeBrickComboOperation) childPopDB() {
-	bco.brickEvictOp.db = bco.deviceRemoveOp.db
+	bco.brickEvictOp.db = bco.parentOp.db
 }
 
-func (bco *removeBrickComboOperation) Build() error {
+func (bco *removeBrickComboOperation) Build(ctx context.Context) error {
 	beo := bco.brickEvictOp
-	dro := bco.deviceRemoveOp
-	return dro.db.Update(func(tx *bolt.Tx) error {
+	parent := bco.parentOp
+	return parent.db.Update(func(tx *bolt.Tx) error {
 		txdb := wdb.WrapTx(tx)
 		bco.childPushDB(txdb)
 		defer bco.childPopDB()
-		if err := beo.Build(); err != nil {
+		if err := beo.Build(ctx); err != nil {
 			return fmt.Errorf(
-				"failed to construct brick-evict for device remove (%v): %v",
-				dro.op.Id,
+				"failed to construct brick-evict for parent op (%v): %v",
+				parent.op.Id,
 				err)
 		}
-		if err := dro.updateChildOperation(txdb, beo.op); err != nil {
+		if err := parent.updateChildOperation(txdb, beo.op); err != nil {
 			return fmt.Errorf(
-				"failed to add brick-evict as child op for device remove (%v): %v",
-				dro.op.Id,
+				"failed to add brick-evict as child op for parent op (%v): %v",
+				parent.op.Id,
 				err)
 		}
 		return nil
 	})
 }
 
-func (bco *removeBrickComboOperation) Exec(executor executors.Executor) error {
-	return bco.brickEvictOp.Exec(executor)
+func (bco *removeBrickComboOperation) Exec(ctx context.Context, executor executors.Executor) error {
+	return bco.brickEvictOp.Exec(ctx, executor)
 }
 
 func (bco *removeBrickComboOperation) Clean(executor executors.Executor) error {
 	return bco.brickEvictOp.Clean(executor)
 }
 
-func (bco *removeBrickComboOperation) Rollback(executor executors.Executor) error {
+func (bco *removeBrickComboOperation) Rollback(ctx context.Context, executor executors.Executor) error {
 	return rollbackViaClean(bco, executor)
 }
 
 func (bco *removeBrickComboOperation) CleanDone() error {
 	beo := bco.brickEvictOp
-	dro := bco.deviceRemoveOp
-	return dro.db.Update(func(tx *bolt.Tx) error {
+	parent := bco.parentOp
+	return parent.db.Update(func(tx *bolt.Tx) error {
 		txdb := wdb.WrapTx(tx)
 		bco.childPushDB(txdb)
 		defer bco.childPopDB()
@@ -970,11 +942,11 @@ func (bco *removeBrickComboOperation) CleanDone() error {
 				beo.op.Id,
 				err)
 		}
-		if err := dro.clearChildOperation(txdb); err != nil {
+		if err := parent.clearChildOperation(txdb); err != nil {
 			return fmt.Errorf(
 				"failed to clear child op [%v] from pending op [%v]: %v",
 				beo.op.Id,
-				dro.op.Id,
+				parent.op.Id,
 				err)
 		}
 		return nil
@@ -983,8 +955,8 @@ func (bco *removeBrickComboOperation) CleanDone() error {
 
 func (bco *removeBrickComboOperation) Finalize() error {
 	beo := bco.brickEvictOp
-	dro := bco.deviceRemoveOp
-	return dro.db.Update(func(tx *bolt.Tx) error {
+	parent := bco.parentOp
+	return parent.db.Update(func(tx *bolt.Tx) error {
 		txdb := wdb.WrapTx(tx)
 		bco.childPushDB(txdb)
 		defer bco.childPopDB()
@@ -994,11 +966,11 @@ func (bco *removeBrickComboOperation) Finalize() error {
 				beo.op.Id,
 				err)
 		}
-		if err := dro.clearChildOperation(txdb); err != nil {
+		if err := parent.clearChildOperation(txdb); err != nil {
 			return fmt.Errorf(
 				"failed to clear child op [%v] from pending op [%v]: %v",
 				beo.op.Id,
-				dro.op.Id,
+				parent.op.Id,
 				err)
 		}
 		return nil
@@ -1009,7 +981,8 @@ func getWorkingNode(n *NodeEntry, db wdb.RODB, executor executors.Executor) (str
 	node := n.ManageHostName()
 	err := executor.GlusterdCheck(node)
 	if err != nil {
-		node, err = GetVerifiedManageHostname(db, executor, n.Info.NodeAddRequest.ClusterId)
+		node, err = GetVerifiedTspManageHostname(db, executor,
+			n.Info.NodeAddRequest.ClusterId, n.Info.TspId)
 		if err != nil {
 			return "", err
 		}
@@ -0,0 +1,276 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+const (
+	// number of completed device batches whose results are kept
+	DEVICE_BATCH_RESULTS_SIZE = 64
+)
+
+// DeviceBatchError is returned when a device of a batch could not be
+// set up. It holds the results of all the devices of the batch.
+type DeviceBatchError struct {
+	Results map[string]api.DeviceBatchResult
+}
+
+func (e *DeviceBatchError) Error() string {
+	names := []string{}
+	for name := range e.Results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := []string{}
+	for _, name := range names {
+		r := e.Results[name]
+		s := name + ": " + r.Status
+		if r.Error != "" {
+			s += " (" + r.Error + ")"
+		}
+		parts = append(parts, s)
+	}
+	return "Unable to add devices: " + strings.Join(parts, ", ")
+}
+
+// deviceBatchResults keeps the results of the last device batches
+// that were added so that they can be fetched once the operation is
+// done.
+type deviceBatchResults struct {
+	lock    sync.Mutex
+	size    int
+	ids     []string
+	results map[string]*api.DeviceBatchAddResponse
+}
+
+func newDeviceBatchResults(size int) *deviceBatchResults {
+	return &deviceBatchResults{
+		size:    size,
+		results: map[string]*api.DeviceBatchAddResponse{},
+	}
+}
+
+func (b *deviceBatchResults) Add(id string, r *api.DeviceBatchAddResponse) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, found := b.results[id]; !found {
+		b.ids = append(b.ids, id)
+	}
+	b.results[id] = r
+	for len(b.ids) > b.size {
+		delete(b.results, b.ids[0])
+		b.ids = b.ids[1:]
+	}
+}
+
+func (b *deviceBatchResults) Get(id string) (*api.DeviceBatchAddResponse, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	r, found := b.results[id]
+	return r, found
+}
+
+// DeviceBatchAddOperation sets up several devices of a node and adds
+// them all to the node at once. The devices are set up one after the
+// other and only saved to the db once all of them are ready. If any of
+// the devices can not be set up the devices already set up are torn
+// down again and none of the devices are added.
+//
+// The operation is not loadable.
+type DeviceBatchAddOperation struct {
+	OperationManager
+	noRetriesOperation
+	NodeId string
+	// who asked for the devices, recorded in the cluster events
+	actor string
+	// where the results are kept once the devices are added
+	batches *deviceBatchResults
+
+	devices []*DeviceEntry
+	results map[string]api.DeviceBatchResult
+
+	// set by Build
+	node *NodeEntry
+
+	// the number of devices set up by Exec, in order
+	setup int
+}
+
+// NewDeviceBatchAddOperation returns a new DeviceBatchAddOperation
+// for the devices with the given names on the node with the given id.
+func NewDeviceBatchAddOperation(nodeId string, names []string,
+	db wdb.DB) *DeviceBatchAddOperation {
+
+	dbo := &DeviceBatchAddOperation{
+		OperationManager: OperationManager{
+			db: db,
+			op: NewPendingOperationEntry(NEW_ID),
+		},
+		NodeId:  nodeId,
+		results: map[string]api.DeviceBatchResult{},
+	}
+	for _, name := range names {
+		req := &api.DeviceAddRequest{NodeId: nodeId}
+		req.Name = name
+		d := NewDeviceEntryFromRequest(req)
+		dbo.devices = append(dbo.devices, d)
+		dbo.results[name] = api.DeviceBatchResult{
+			Id:     d.Info.Id,
+			Status: api.DeviceBatchSkipped,
+		}
+	}
+	return dbo
+}
+
+func (dbo *DeviceBatchAddOperation) Label() string {
+	return "Add Devices"
+}
+
+func (dbo *DeviceBatchAddOperation) ResourceUrl() string {
+	return fmt.Sprintf("/nodes/%v/devices/batch/%v", dbo.NodeId, dbo.op.Id)
+}
+
+// Results returns the result of each device of the batch.
+func (dbo *DeviceBatchAddOperation) Results() *api.DeviceBatchAddResponse {
+	r := &api.DeviceBatchAddResponse{
+		NodeId:  dbo.NodeId,
+		Devices: map[string]api.DeviceBatchResult{},
+	}
+	for name, result := range dbo.results {
+		r.Devices[name] = result
+	}
+	return r
+}
+
+func (dbo *DeviceBatchAddOperation) setResult(d *DeviceEntry,
+	status string, err error) {
+
+	r := api.DeviceBatchResult{Id: d.Info.Id, Status: status}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	dbo.results[d.Info.Name] = r
+}
+
+// Build checks that none of the devices is already on the node and
+// records the devices in the pending operation.
+func (dbo *DeviceBatchAddOperation) Build(ctx context.Context) error {
+	return dbo.db.Update(func(tx *bolt.Tx) error {
+		node, err := NewNodeEntryFromId(tx, dbo.NodeId)
+		if err != nil {
+			return err
+		}
+		for _, id := range node.Devices {
+			d, err := NewDeviceEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			if _, found := dbo.results[d.Info.Name]; found {
+				logger.LogError("Device %v is already on node %v",
+					d.Info.Name, node.Info.Id)
+				return ErrConflict
+			}
+		}
+		dbo.node = node
+		for _, d := range dbo.devices {
+			dbo.op.RecordAddDevice(d)
+		}
+		return dbo.op.Save(tx)
+	})
+}
+
+// Exec sets up the devices in order, stopping at the first device that
+// fails.
+func (dbo *DeviceBatchAddOperation) Exec(ctx context.Context, executor executors.Executor) error {
+	host := dbo.node.ManageHostName()
+	for _, d := range dbo.devices[dbo.setup:] {
+		info, err := executor.DeviceSetup(host, d.Info.Name, d.Info.Id, false)
+		if err != nil {
+			logger.LogError("Unable to set up device %v: %v", d.Info.Name, err)
+			dbo.setResult(d, api.DeviceBatchFailed, err)
+			return &DeviceBatchError{Results: dbo.results}
+		}
+		d.UpdateInfo(info)
+		dbo.setResult(d, api.DeviceBatchAdded, nil)
+		dbo.setup++
+	}
+	return nil
+}
+
+// Rollback tears down the devices set up by Exec. If a device can not
+// be torn down the pending operation is kept.
+func (dbo *DeviceBatchAddOperation) Rollback(ctx context.Context, executor executors.Executor) error {
+	host := dbo.node.ManageHostName()
+	var failed error
+	for _, d := range dbo.devices[:dbo.setup] {
+		err := executor.DeviceTeardown(host, d.ToHandle())
+		if err != nil {
+			logger.LogError("Unable to tear down device %v: %v", d.Info.Name, err)
+			dbo.setResult(d, api.DeviceBatchAdded,
+				fmt.Errorf("teardown failed: %v", err))
+			failed = err
+			continue
+		}
+		dbo.setResult(d, api.DeviceBatchRolledBack, nil)
+	}
+	if failed != nil {
+		return failed
+	}
+	return dbo.db.Update(func(tx *bolt.Tx) error {
+		return dbo.op.Delete(tx)
+	})
+}
+
+// Finalize saves the devices and adds them to the node.
+func (dbo *DeviceBatchAddOperation) Finalize() error {
+	err := dbo.db.Update(func(tx *bolt.Tx) error {
+		node, err := NewNodeEntryFromId(tx, dbo.NodeId)
+		if err != nil {
+			return err
+		}
+		for _, d := range dbo.devices {
+			node.DeviceAdd(d.Info.Id)
+			if err := d.Save(tx); err != nil {
+				return err
+			}
+			err = AddClusterEvent(tx, node.Info.ClusterId,
+				api.ClusterEventDeviceAdded, dbo.actor,
+				"Added device "+d.Info.Name+" to node "+node.ManageHostName(),
+				deviceEventMetadata(d))
+			if err != nil {
+				return err
+			}
+		}
+		if err := node.Save(tx); err != nil {
+			return err
+		}
+		return dbo.op.Delete(tx)
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("Added %v devices to node %v", len(dbo.devices), dbo.NodeId)
+	if dbo.batches != nil {
+		dbo.batches.Add(dbo.op.Id, dbo.Results())
+	}
+	return nil
+}
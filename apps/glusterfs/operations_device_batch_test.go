@@ -0,0 +1,226 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+func setupDeviceBatchTest(t *testing.T, tmpfile string) (
+	*App, *httptest.Server, *NodeEntry) {
+
+	app := NewTestApp(tmpfile)
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+	ts := httptest.NewServer(router)
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		1,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var node *NodeEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		nl, err := NodeList(tx)
+		if err != nil {
+			return err
+		}
+		node, err = NewNodeEntryFromId(tx, nl[0])
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	return app, ts, node
+}
+
+func TestDeviceBatchAdd(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts, node := setupDeviceBatchTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	c := client.NewClientNoAuth(ts.URL)
+	names := []string{"/dev/sdb", "/dev/sdc", "/dev/sdd", "/dev/sde", "/dev/sdf"}
+	result, err := c.DeviceBatchAdd(node.Info.Id,
+		&api.DeviceBatchAddRequest{Devices: names})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, result.NodeId == node.Info.Id,
+		"expected", node.Info.Id, "got:", result.NodeId)
+	tests.Assert(t, len(result.Devices) == len(names),
+		"expected", len(names), "results, got:", result.Devices)
+
+	info, err := c.NodeInfo(node.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(info.DevicesInfo) == 6,
+		"expected 6 devices, got:", len(info.DevicesInfo))
+	for _, d := range info.DevicesInfo {
+		r, found := result.Devices[d.Name]
+		if !found {
+			// the device of the sample topology
+			continue
+		}
+		tests.Assert(t, r.Id == d.Id && r.Status == api.DeviceBatchAdded,
+			"unexpected result for", d.Name, r)
+		tests.Assert(t, d.Storage.Total > 0, "expected device size for", d.Name)
+		delete(result.Devices, d.Name)
+	}
+	tests.Assert(t, len(result.Devices) == 0,
+		"expected all devices on the node, missing:", result.Devices)
+
+	// devices already on the node are refused
+	_, err = c.DeviceBatchAdd(node.Info.Id,
+		&api.DeviceBatchAddRequest{Devices: []string{"/dev/sdg", "/dev/sdc"}})
+	tests.Assert(t, err != nil, "expected err != nil")
+	_, err = c.DeviceBatchAdd("0123456789abcdef0123456789abcdef",
+		&api.DeviceBatchAddRequest{Devices: []string{"/dev/sdg"}})
+	tests.Assert(t, err != nil, "expected err != nil")
+}
+
+func TestDeviceBatchAddInvalid(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts, node := setupDeviceBatchTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	for _, body := range []string{
+		`{"devices": []}`,
+		`{"devices": ["sdb"]}`,
+		`{"devices": ["/dev/sdb; reboot"]}`,
+		`{"devices": ["/dev/sdb", "/dev/sdb"]}`,
+	} {
+		r, err := http.Post(ts.URL+"/nodes/"+node.Info.Id+"/devices/batch",
+			"application/json", strings.NewReader(body))
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, r.StatusCode == http.StatusBadRequest,
+			"expected r.StatusCode == http.StatusBadRequest, got:",
+			r.StatusCode, body)
+	}
+}
+
+func TestDeviceBatchAddRollback(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts, node := setupDeviceBatchTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	// pvcreate fails on the third device
+	setup := app.xo.MockDeviceSetup
+	app.xo.MockDeviceSetup = func(host, device, vgid string, destroy bool) (*executors.DeviceInfo, error) {
+		if device == "/dev/sdd" {
+			return nil, fmt.Errorf("pvcreate failed")
+		}
+		return setup(host, device, vgid, destroy)
+	}
+	tornDown := []string{}
+	app.xo.MockDeviceTeardown = func(host string, dh *executors.DeviceVgHandle) error {
+		tornDown = append(tornDown, dh.Paths[0])
+		return nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	names := []string{"/dev/sdb", "/dev/sdc", "/dev/sdd", "/dev/sde", "/dev/sdf"}
+	_, err := c.DeviceBatchAdd(node.Info.Id,
+		&api.DeviceBatchAddRequest{Devices: names})
+	tests.Assert(t, err != nil, "expected err != nil")
+	for _, s := range []string{
+		"/dev/sdb: rolled-back",
+		"/dev/sdc: rolled-back",
+		"/dev/sdd: failed (pvcreate failed)",
+		"/dev/sde: skipped",
+		"/dev/sdf: skipped",
+	} {
+		tests.Assert(t, strings.Contains(err.Error(), s),
+			"expected", s, "in", err)
+	}
+	expected := []string{"/dev/sdb", "/dev/sdc"}
+	tests.Assert(t, reflect.DeepEqual(tornDown, expected),
+		"expected", expected, "got:", tornDown)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		dl, err := DeviceList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(dl) == 1, "expected 1 device, got:", dl)
+
+		n, err := NewNodeEntryFromId(tx, node.Info.Id)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(n.Devices) == 1,
+			"expected 1 device on node, got:", n.Devices)
+
+		l, err := PendingOperationList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(l) == 0, "expected no pending operations, got:", l)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestDeviceBatchAddTeardownFails(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts, node := setupDeviceBatchTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	app.xo.MockDeviceSetup = func(host, device, vgid string, destroy bool) (*executors.DeviceInfo, error) {
+		if device == "/dev/sdc" {
+			return nil, fmt.Errorf("pvcreate failed")
+		}
+		return &executors.DeviceInfo{TotalSize: 100 * GB, FreeSize: 100 * GB}, nil
+	}
+	app.xo.MockDeviceTeardown = func(host string, dh *executors.DeviceVgHandle) error {
+		return fmt.Errorf("vgremove failed")
+	}
+
+	op := NewDeviceBatchAddOperation(node.Info.Id,
+		[]string{"/dev/sdb", "/dev/sdc"}, app.db)
+	err := RunOperation(op, app.executor)
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "teardown failed"),
+		"expected teardown failure in", err)
+
+	// the pending operation is kept for the device left behind
+	err = app.db.View(func(tx *bolt.Tx) error {
+		l, err := PendingOperationList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(l) == 1, "expected 1 pending operation, got:", l)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestDeviceBatchResults(t *testing.T) {
+	b := newDeviceBatchResults(2)
+	for _, id := range []string{"a", "b", "c"} {
+		b.Add(id, &api.DeviceBatchAddResponse{NodeId: id})
+	}
+	_, found := b.Get("a")
+	tests.Assert(t, !found, "expected the oldest result to be dropped")
+	r, found := b.Get("c")
+	tests.Assert(t, found && r.NodeId == "c", "unexpected result:", r)
+}
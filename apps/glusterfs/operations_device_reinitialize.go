@@ -0,0 +1,145 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// DeviceReinitializeOperation wipes a device that holds no bricks and
+// creates its pv and vg again. It is meant for devices whose lvm
+// metadata was damaged. The device is kept offline while it is being
+// reinitialized and the sizes in the db are updated from the new vg.
+//
+// The operation is not loadable.
+type DeviceReinitializeOperation struct {
+	OperationManager
+	noRetriesOperation
+	DeviceId string
+
+	// set by Build
+	node     *NodeEntry
+	device   *DeviceEntry
+	oldState api.EntryState
+
+	// set by Exec
+	info *executors.DeviceInfo
+}
+
+// NewDeviceReinitializeOperation returns a new
+// DeviceReinitializeOperation for the device with the given id.
+func NewDeviceReinitializeOperation(deviceId string,
+	db wdb.DB) *DeviceReinitializeOperation {
+
+	return &DeviceReinitializeOperation{
+		OperationManager: OperationManager{
+			db: db,
+			op: NewPendingOperationEntry(NEW_ID),
+		},
+		DeviceId: deviceId,
+	}
+}
+
+func (dro *DeviceReinitializeOperation) Label() string {
+	return "Reinitialize Device"
+}
+
+func (dro *DeviceReinitializeOperation) ResourceUrl() string {
+	return fmt.Sprintf("/devices/%v", dro.DeviceId)
+}
+
+// Build checks that the device holds no bricks, records it and takes
+// it offline so that no bricks are placed on it.
+func (dro *DeviceReinitializeOperation) Build(ctx context.Context) error {
+	return dro.db.Update(func(tx *bolt.Tx) error {
+		d, err := NewDeviceEntryFromId(tx, dro.DeviceId)
+		if err != nil {
+			return err
+		}
+		if len(d.Bricks) > 0 {
+			logger.LogError("Device %v has %v bricks and can not be reinitialized",
+				d.Info.Id, len(d.Bricks))
+			return ErrConflict
+		}
+		txdb := wdb.WrapTx(tx)
+		if p, err := PendingOperationsOnDevice(txdb, d.Info.Id); err != nil {
+			return err
+		} else if p {
+			logger.LogError("Found operations still pending on device."+
+				" Can not reinitialize device %v at this time.",
+				d.Info.Id)
+			return ErrConflict
+		}
+		dro.node, err = NewNodeEntryFromId(tx, d.NodeId)
+		if err != nil {
+			return err
+		}
+
+		dro.oldState = d.State
+		d.State = api.EntryStateOffline
+		if err := d.Save(tx); err != nil {
+			return err
+		}
+		dro.device = d
+		dro.op.RecordReinitializeDevice(d)
+		return dro.op.Save(tx)
+	})
+}
+
+// Exec wipes the device and sets it up again.
+func (dro *DeviceReinitializeOperation) Exec(ctx context.Context, executor executors.Executor) error {
+	info, err := executor.DeviceReinitialize(dro.node.ManageHostName(),
+		dro.device.Info.Name, dro.device.Info.Id)
+	if err != nil {
+		return err
+	}
+	dro.info = info
+	return nil
+}
+
+// Rollback restores the state of the device.
+func (dro *DeviceReinitializeOperation) Rollback(ctx context.Context, executor executors.Executor) error {
+	return dro.db.Update(func(tx *bolt.Tx) error {
+		d, err := NewDeviceEntryFromId(tx, dro.DeviceId)
+		if err != nil {
+			return err
+		}
+		d.State = dro.oldState
+		if err := d.Save(tx); err != nil {
+			return err
+		}
+		return dro.op.Delete(tx)
+	})
+}
+
+// Finalize updates the device from its new vg and restores the state
+// of the device.
+func (dro *DeviceReinitializeOperation) Finalize() error {
+	return dro.db.Update(func(tx *bolt.Tx) error {
+		d, err := NewDeviceEntryFromId(tx, dro.DeviceId)
+		if err != nil {
+			return err
+		}
+		d.UpdateInfo(dro.info)
+		d.State = dro.oldState
+		if err := d.Save(tx); err != nil {
+			return err
+		}
+		logger.Info("Reinitialized device %v (%v)", d.Info.Id, d.Info.Name)
+		return dro.op.Delete(tx)
+	})
+}
@@ -0,0 +1,184 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+func TestDeviceReinitialize(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		1,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var d *DeviceEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		dl, err := DeviceList(tx)
+		if err != nil {
+			return err
+		}
+		d, err = NewDeviceEntryFromId(tx, dl[0])
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	reinitialized := []string{}
+	app.xo.MockDeviceReinitialize = func(host, device, vgid string) (*executors.DeviceInfo, error) {
+		reinitialized = append(reinitialized, vgid)
+		return &executors.DeviceInfo{
+			TotalSize:  400 * GB,
+			FreeSize:   400 * GB,
+			ExtentSize: 4096,
+		}, nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+
+	// the confirmation is required
+	_, err = c.DeviceReinitialize(d.Info.Id, &api.DeviceReinitializeRequest{})
+	tests.Assert(t, err != nil, "expected err != nil")
+	_, err = c.DeviceReinitialize(d.Info.Id, &api.DeviceReinitializeRequest{
+		Confirm: "yes",
+	})
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, len(reinitialized) == 0,
+		"expected no device reinitialized, got:", reinitialized)
+
+	info, err := c.DeviceReinitialize(d.Info.Id, &api.DeviceReinitializeRequest{
+		Confirm: api.DeviceReinitializeConfirmation,
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(reinitialized) == 1 && reinitialized[0] == d.Info.Id,
+		"expected device reinitialized, got:", reinitialized)
+	tests.Assert(t, info.Storage.Total == 400*GB,
+		"expected new total size, got:", info.Storage.Total)
+	tests.Assert(t, info.Storage.Free == 400*GB,
+		"expected new free size, got:", info.Storage.Free)
+	tests.Assert(t, info.State == api.EntryStateOnline,
+		"expected device online, got:", info.State)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		l, err := PendingOperationList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(l) == 0, "expected no pending ops, got:", l)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestDeviceReinitializeFails(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		1,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var d *DeviceEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		dl, err := DeviceList(tx)
+		if err != nil {
+			return err
+		}
+		d, err = NewDeviceEntryFromId(tx, dl[0])
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	app.xo.MockDeviceReinitialize = func(host, device, vgid string) (*executors.DeviceInfo, error) {
+		return nil, fmt.Errorf("device %v is busy", device)
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	_, err = c.DeviceReinitialize(d.Info.Id, &api.DeviceReinitializeRequest{
+		Confirm: api.DeviceReinitializeConfirmation,
+	})
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	// the device is left as it was
+	err = app.db.View(func(tx *bolt.Tx) error {
+		d2, err := NewDeviceEntryFromId(tx, d.Info.Id)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, d2.State == api.EntryStateOnline,
+			"expected device online, got:", d2.State)
+		tests.Assert(t, d2.Info.Storage.Total == d.Info.Storage.Total,
+			"expected unchanged size, got:", d2.Info.Storage.Total)
+		l, err := PendingOperationList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(l) == 0, "expected no pending ops, got:", l)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestDeviceReinitializeWithBricks(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	d := setupDeviceReplaceTest(t, app)
+
+	c := client.NewClientNoAuth(ts.URL)
+	_, err := c.DeviceReinitialize(d.Info.Id, &api.DeviceReinitializeRequest{
+		Confirm: api.DeviceReinitializeConfirmation,
+	})
+	assertErrorCode(t, err, api.ErrorConflict)
+}
@@ -0,0 +1,398 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+var (
+	// how often and for how long to wait for the heal of a volume
+	// after one of its bricks was moved to the new device
+	deviceReplaceHealInterval = 10 * time.Second
+	deviceReplaceHealTimeout  = 30 * time.Minute
+)
+
+// DeviceReplaceOperation moves all the bricks of a device to a new
+// device on the same node and then removes the old device. The new
+// device is set up on the node and added to the db first, each brick
+// is then moved by a child brick evict operation restricted to the
+// new device. Once all bricks are moved the vg of the old device is
+// torn down.
+//
+// The operation is not loadable. Bricks already moved when the
+// operation fails stay on the new device.
+type DeviceReplaceOperation struct {
+	OperationManager
+	noRetriesOperation
+	DeviceId string
+
+	healCheck api.HealInfoCheck
+	newDevice *DeviceEntry
+
+	// set by Build
+	node     *NodeEntry
+	oldState api.EntryState
+
+	// set once the new device is in the db
+	newDeviceAdded bool
+}
+
+// NewDeviceReplaceOperation returns a new DeviceReplaceOperation
+// replacing the device with the given id by the new device. The new
+// device entry is saved by the operation.
+func NewDeviceReplaceOperation(deviceId string, newDevice *DeviceEntry,
+	db wdb.DB, h api.HealInfoCheck) *DeviceReplaceOperation {
+
+	return &DeviceReplaceOperation{
+		OperationManager: OperationManager{
+			db: db,
+			op: NewPendingOperationEntry(NEW_ID),
+		},
+		DeviceId:  deviceId,
+		healCheck: h,
+		newDevice: newDevice,
+	}
+}
+
+func (dro *DeviceReplaceOperation) Label() string {
+	return "Replace Device"
+}
+
+// LockKeys returns the keys of the cluster of the device and of the
+// volumes that have bricks on the device.
+func (dro *DeviceReplaceOperation) LockKeys() ([]string, error) {
+	keys := []string{}
+	err := dro.db.View(func(tx *bolt.Tx) error {
+		d, err := NewDeviceEntryFromId(tx, dro.DeviceId)
+		if err != nil {
+			return err
+		}
+		n, err := NewNodeEntryFromId(tx, d.NodeId)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, clusterLockKey(n.Info.ClusterId))
+		vols := map[string]bool{}
+		for _, id := range d.Bricks {
+			b, err := NewBrickEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			if !vols[b.Info.VolumeId] {
+				vols[b.Info.VolumeId] = true
+				keys = append(keys, volumeLockKey(b.Info.VolumeId))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (dro *DeviceReplaceOperation) ResourceUrl() string {
+	return fmt.Sprintf("/devices/%v", dro.newDevice.Info.Id)
+}
+
+// Build records the device being replaced and takes it offline so
+// that no new bricks are placed on it.
+func (dro *DeviceReplaceOperation) Build(ctx context.Context) error {
+	return dro.db.Update(func(tx *bolt.Tx) error {
+		d, err := NewDeviceEntryFromId(tx, dro.DeviceId)
+		if err != nil {
+			return err
+		}
+		if d.State == api.EntryStateFailed {
+			logger.LogError("Device %v has failed and can not be replaced",
+				d.Info.Id)
+			return ErrConflict
+		}
+		txdb := wdb.WrapTx(tx)
+		if p, err := PendingOperationsOnDevice(txdb, d.Info.Id); err != nil {
+			return err
+		} else if p {
+			logger.LogError("Found operations still pending on device."+
+				" Can not replace device %v at this time.",
+				d.Info.Id)
+			return ErrConflict
+		}
+		dro.node, err = NewNodeEntryFromId(tx, d.NodeId)
+		if err != nil {
+			return err
+		}
+
+		dro.oldState = d.State
+		d.State = api.EntryStateOffline
+		if err := d.Save(tx); err != nil {
+			return err
+		}
+		dro.op.RecordReplaceDevice(d)
+		return dro.op.Save(tx)
+	})
+}
+
+// Exec sets up the new device, moves the bricks of the old device to
+// it and tears down the vg of the old device.
+func (dro *DeviceReplaceOperation) Exec(ctx context.Context, executor executors.Executor) error {
+	if err := dro.addNewDevice(executor); err != nil {
+		return err
+	}
+	if err := dro.migrateBricks(ctx, executor); err != nil {
+		return err
+	}
+
+	var d *DeviceEntry
+	err := dro.db.View(func(tx *bolt.Tx) error {
+		var err error
+		d, err = NewDeviceEntryFromId(tx, dro.DeviceId)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(d.Bricks) > 0 {
+		return fmt.Errorf("Device %v still has %v bricks",
+			d.Info.Id, len(d.Bricks))
+	}
+	return executor.DeviceTeardown(dro.node.ManageHostName(), d.ToHandle())
+}
+
+// addNewDevice creates the vg of the new device on the node and adds
+// the device to the node in the db.
+func (dro *DeviceReplaceOperation) addNewDevice(executor executors.Executor) (e error) {
+	host := dro.node.ManageHostName()
+	d := dro.newDevice
+	info, err := executor.DeviceSetup(host, d.Info.Name, d.Info.Id, false)
+	if err != nil {
+		return err
+	}
+	d.UpdateInfo(info)
+	defer func() {
+		if e != nil {
+			executor.DeviceTeardown(host, d.ToHandle())
+		}
+	}()
+
+	err = dro.db.Update(func(tx *bolt.Tx) error {
+		n, err := NewNodeEntryFromId(tx, dro.node.Info.Id)
+		if err != nil {
+			return err
+		}
+		n.DeviceAdd(d.Info.Id)
+		if err := n.Save(tx); err != nil {
+			return err
+		}
+		return d.Save(tx)
+	})
+	if err != nil {
+		return err
+	}
+	dro.newDeviceAdded = true
+	logger.Info("Added device %v (%v) to replace device %v",
+		d.Info.Id, d.Info.Name, dro.DeviceId)
+	return nil
+}
+
+// migrateBricks moves the bricks of the old device to the new device
+// one at a time, waiting for the volume of a brick to heal before
+// moving the next brick. The progress of the operation is the share
+// of the bricks moved.
+func (dro *DeviceReplaceOperation) migrateBricks(ctx context.Context,
+	executor executors.Executor) error {
+
+	var d *DeviceEntry
+	err := dro.db.View(func(tx *bolt.Tx) error {
+		var err error
+		d, err = NewDeviceEntryFromId(tx, dro.DeviceId)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	toEvict, err := d.removeableBricks(dro.db)
+	if err != nil {
+		return err
+	}
+
+	newDeviceId := dro.newDevice.Info.Id
+	onNewDevice := func(bs *BrickSet, d *DeviceEntry) bool {
+		return d.Info.Id == newDeviceId
+	}
+	for i, brickId := range toEvict {
+		var volName string
+		err := dro.db.View(func(tx *bolt.Tx) error {
+			b, err := NewBrickEntryFromId(tx, brickId)
+			if err != nil {
+				return err
+			}
+			v, err := NewVolumeEntryFromId(tx, b.Info.VolumeId)
+			if err != nil {
+				return err
+			}
+			volName = v.Info.Name
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		beo := NewBrickEvictOperation(brickId, dro.db, dro.healCheck)
+		beo.deviceFilter = onNewDevice
+		nestedOp := newRemoveBrickComboOperation(&dro.OperationManager, beo)
+		if err := RunOperationContext(ctx, nestedOp, executor); err != nil {
+			return err
+		}
+		logger.Info("Moved brick %v of volume %v to device %v",
+			brickId, volName, newDeviceId)
+
+		if dro.healCheck != api.HealCheckDisable {
+			host, err := getWorkingNode(dro.node, dro.db, executor)
+			if err != nil {
+				return err
+			}
+			err = waitForHeal(ctx, executor, host, volName,
+				deviceReplaceHealInterval, deviceReplaceHealTimeout)
+			if err != nil {
+				return err
+			}
+		}
+		if err := dro.setProgress((i + 1) * 100 / len(toEvict)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForHeal polls the heal info of the volume until no brick of the
+// volume has entries left to heal or the timeout expires.
+func waitForHeal(ctx context.Context, executor executors.Executor,
+	host, volume string, interval, timeout time.Duration) error {
+
+	deadline := time.Now().Add(timeout)
+	for {
+		info, err := executor.HealInfo(host, volume)
+		if err != nil {
+			return err
+		}
+		healing := 0
+		for _, b := range info.Bricks.BrickList {
+			switch b.NumberOfEntries {
+			case "", "0", "-":
+			default:
+				healing++
+			}
+		}
+		if healing == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Volume %v still has entries to heal on %v bricks after %v",
+				volume, healing, timeout)
+		}
+		logger.Debug("Waiting for heal of volume %v (%v bricks healing)",
+			volume, healing)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Rollback restores the state of the old device and removes the new
+// device if no brick was moved to it. Failed child brick evict
+// operations clean up after themselves.
+func (dro *DeviceReplaceOperation) Rollback(ctx context.Context, executor executors.Executor) error {
+	removeNew := false
+	if dro.newDeviceAdded {
+		err := dro.db.View(func(tx *bolt.Tx) error {
+			d, err := NewDeviceEntryFromId(tx, dro.newDevice.Info.Id)
+			if err != nil {
+				return err
+			}
+			removeNew = len(d.Bricks) == 0
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if removeNew {
+		err := executor.DeviceTeardown(dro.node.ManageHostName(),
+			dro.newDevice.ToHandle())
+		if err != nil {
+			logger.Warning("Unable to tear down new device %v: %v",
+				dro.newDevice.Info.Id, err)
+			removeNew = false
+		}
+	}
+
+	return dro.db.Update(func(tx *bolt.Tx) error {
+		d, err := NewDeviceEntryFromId(tx, dro.DeviceId)
+		if err != nil {
+			return err
+		}
+		d.State = dro.oldState
+		if err := d.Save(tx); err != nil {
+			return err
+		}
+		if removeNew {
+			n, err := NewNodeEntryFromId(tx, dro.node.Info.Id)
+			if err != nil {
+				return err
+			}
+			n.DeviceDelete(dro.newDevice.Info.Id)
+			if err := n.Save(tx); err != nil {
+				return err
+			}
+			dro.newDevice.State = api.EntryStateFailed
+			if err := dro.newDevice.Delete(tx); err != nil {
+				return err
+			}
+		}
+		return dro.op.Delete(tx)
+	})
+}
+
+// Finalize removes the old device from the db.
+func (dro *DeviceReplaceOperation) Finalize() error {
+	return dro.db.Update(func(tx *bolt.Tx) error {
+		d, err := NewDeviceEntryFromId(tx, dro.DeviceId)
+		if err != nil {
+			return err
+		}
+		n, err := NewNodeEntryFromId(tx, d.NodeId)
+		if err != nil {
+			return err
+		}
+		n.DeviceDelete(d.Info.Id)
+		if err := n.Save(tx); err != nil {
+			return err
+		}
+		// the device is empty, mark it failed so that it can be deleted
+		d.State = api.EntryStateFailed
+		if err := d.Delete(tx); err != nil {
+			return err
+		}
+		logger.Info("Replaced device %v with device %v",
+			d.Info.Id, dro.newDevice.Info.Id)
+		return dro.op.Delete(tx)
+	})
+}
@@ -0,0 +1,314 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// setupDeviceReplaceTest creates two replica 3 volumes on three nodes
+// with one device each and returns a device holding two bricks.
+func setupDeviceReplaceTest(t *testing.T, app *App) *DeviceEntry {
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	for i := 0; i < 2; i++ {
+		v := createSampleReplicaVolumeEntry(10, 3)
+		err = v.Create(app.db, app.executor)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	}
+
+	var d *DeviceEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		dl, err := DeviceList(tx)
+		if err != nil {
+			return err
+		}
+		d, err = NewDeviceEntryFromId(tx, dl[0])
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(d.Bricks) == 2,
+		"expected 2 bricks on device, got:", len(d.Bricks))
+
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		return mockVolumeInfoFromDb(app.db, volume)
+	}
+	app.xo.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		return mockHealStatusFromDb(app.db, volume)
+	}
+	return d
+}
+
+func TestDeviceReplace(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	defer func(i time.Duration) { deviceReplaceHealInterval = i }(deviceReplaceHealInterval)
+	deviceReplaceHealInterval = time.Millisecond
+
+	d := setupDeviceReplaceTest(t, app)
+
+	// every volume reports entries to heal once after a brick was moved
+	replaced := 0
+	healing := false
+	healPolls := 0
+	app.xo.MockVolumeReplaceBrick = func(host string, volume string,
+		oldBrick *executors.BrickInfo, newBrick *executors.BrickInfo) error {
+		replaced++
+		healing = true
+		return nil
+	}
+	app.xo.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		hi, err := mockHealStatusFromDb(app.db, volume)
+		if healing && err == nil {
+			healing = false
+			healPolls++
+			hi.Bricks.BrickList[0].NumberOfEntries = "3"
+		}
+		return hi, err
+	}
+	tornDown := []string{}
+	app.xo.MockDeviceTeardown = func(host string, dh *executors.DeviceVgHandle) error {
+		tornDown = append(tornDown, dh.VgId)
+		return nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	info, err := c.DeviceReplace(d.Info.Id, &api.DeviceReplaceRequest{
+		NewDevicePath: "/dev/replacement",
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Name == "/dev/replacement",
+		"expected /dev/replacement, got:", info.Name)
+	tests.Assert(t, len(info.Bricks) == 2,
+		"expected 2 bricks on new device, got:", len(info.Bricks))
+	tests.Assert(t, replaced == 2, "expected 2 bricks replaced, got:", replaced)
+	tests.Assert(t, healPolls == 2,
+		"expected to wait for heal twice, got:", healPolls)
+	tests.Assert(t, len(tornDown) == 1 && tornDown[0] == d.Info.Id,
+		"expected old device torn down, got:", tornDown)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		_, err := NewDeviceEntryFromId(tx, d.Info.Id)
+		tests.Assert(t, err == ErrNotFound,
+			"expected err == ErrNotFound, got:", err)
+		n, err := NewNodeEntryFromId(tx, d.NodeId)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(n.Devices) == 1 && n.Devices[0] == info.Id,
+			"expected only the new device on node, got:", n.Devices)
+		for _, brickId := range d.Bricks {
+			_, err := NewBrickEntryFromId(tx, brickId)
+			tests.Assert(t, err == ErrNotFound,
+				"expected old brick removed, got:", err)
+		}
+		for _, b := range info.Bricks {
+			brick, err := NewBrickEntryFromId(tx, b.Id)
+			if err != nil {
+				return err
+			}
+			tests.Assert(t, brick.Info.DeviceId == info.Id,
+				"expected brick on new device, got:", brick.Info.DeviceId)
+			tests.Assert(t, brick.Info.NodeId == d.NodeId,
+				"expected brick on same node, got:", brick.Info.NodeId)
+		}
+		l, err := PendingOperationList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(l) == 0, "expected no pending ops, got:", l)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestDeviceReplaceProgress(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	defer func(i time.Duration) { deviceReplaceHealInterval = i }(deviceReplaceHealInterval)
+	deviceReplaceHealInterval = time.Millisecond
+
+	d := setupDeviceReplaceTest(t, app)
+
+	replaceProgress := func() int {
+		progress := -1
+		err := app.db.View(func(tx *bolt.Tx) error {
+			l, err := PendingOperationList(tx)
+			if err != nil {
+				return err
+			}
+			for _, id := range l {
+				pop, err := NewPendingOperationEntryFromId(tx, id)
+				if err != nil {
+					return err
+				}
+				if pop.Type == OperationReplaceDevice {
+					progress = pop.Progress
+				}
+			}
+			return nil
+		})
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		return progress
+	}
+
+	// the volume of each moved brick needs three polls to heal
+	seen := []int{}
+	healing := 0
+	app.xo.MockVolumeReplaceBrick = func(host string, volume string,
+		oldBrick *executors.BrickInfo, newBrick *executors.BrickInfo) error {
+		healing = 2
+		return nil
+	}
+	app.xo.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		hi, err := mockHealStatusFromDb(app.db, volume)
+		if err == nil {
+			seen = append(seen, replaceProgress())
+			if healing > 0 {
+				healing--
+				hi.Bricks.BrickList[0].NumberOfEntries = "3"
+			}
+		}
+		return hi, err
+	}
+	atTeardown := -1
+	app.xo.MockDeviceTeardown = func(host string, dh *executors.DeviceVgHandle) error {
+		atTeardown = replaceProgress()
+		return nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	_, err := c.DeviceReplace(d.Info.Id, &api.DeviceReplaceRequest{
+		NewDevicePath: "/dev/replacement",
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// the progress does not go down and increases once per brick
+	tests.Assert(t, len(seen) > 0, "expected heal polls")
+	tests.Assert(t, seen[0] == 0, "expected no progress at first, got:", seen)
+	for i := 1; i < len(seen); i++ {
+		tests.Assert(t, seen[i] >= seen[i-1],
+			"expected progress to increase, got:", seen)
+	}
+	tests.Assert(t, seen[len(seen)-1] == 50,
+		"expected half of the bricks moved, got:", seen)
+	tests.Assert(t, atTeardown == 100,
+		"expected all bricks moved before teardown, got:", atTeardown)
+}
+
+func TestDeviceReplaceSetupFails(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	d := setupDeviceReplaceTest(t, app)
+
+	app.xo.MockDeviceSetup = func(host, device, vgid string, destroy bool) (*executors.DeviceInfo, error) {
+		return nil, fmt.Errorf("device %v is not usable", device)
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	_, err := c.DeviceReplace(d.Info.Id, &api.DeviceReplaceRequest{
+		NewDevicePath: "/dev/replacement",
+	})
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	// the old device is left as it was
+	err = app.db.View(func(tx *bolt.Tx) error {
+		d2, err := NewDeviceEntryFromId(tx, d.Info.Id)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, d2.State == api.EntryStateOnline,
+			"expected device online, got:", d2.State)
+		tests.Assert(t, len(d2.Bricks) == 2,
+			"expected 2 bricks on device, got:", len(d2.Bricks))
+		n, err := NewNodeEntryFromId(tx, d.NodeId)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(n.Devices) == 1,
+			"expected one device on node, got:", n.Devices)
+		l, err := PendingOperationList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(l) == 0, "expected no pending ops, got:", l)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestDeviceReplacePathInUse(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	d := setupDeviceReplaceTest(t, app)
+
+	c := client.NewClientNoAuth(ts.URL)
+	_, err := c.DeviceReplace(d.Info.Id, &api.DeviceReplaceRequest{
+		NewDevicePath: d.Info.Name,
+	})
+	assertErrorCode(t, err, api.ErrorConflict)
+
+	_, err = c.DeviceReplace(d.Info.Id, &api.DeviceReplaceRequest{})
+	tests.Assert(t, err != nil, "expected err != nil")
+}
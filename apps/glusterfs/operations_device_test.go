@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -60,7 +61,7 @@ func TestDeviceRemoveOperationEmpty(t *testing.T) {
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	dro := NewDeviceRemoveOperation(d.Info.Id, app.db, api.HealCheckEnable)
-	err = dro.Build()
+	err = dro.Build(context.Background())
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	// because there are no bricks on this device it can be disabled
@@ -72,7 +73,7 @@ func TestDeviceRemoveOperationEmpty(t *testing.T) {
 		return nil
 	})
 
-	err = dro.Exec(app.executor)
+	err = dro.Exec(context.Background(), app.executor)
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	err = dro.Finalize()
@@ -131,7 +132,7 @@ func TestDeviceRemoveOperationWithBricks(t *testing.T) {
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	dro := NewDeviceRemoveOperation(d.Info.Id, app.db, api.HealCheckEnable)
-	err = dro.Build()
+	err = dro.Build(context.Background())
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	// because there were bricks on this device it needs to perform
@@ -150,7 +151,7 @@ func TestDeviceRemoveOperationWithBricks(t *testing.T) {
 		return mockHealStatusFromDb(app.db, volume)
 	}
 
-	err = dro.Exec(app.executor)
+	err = dro.Exec(context.Background(), app.executor)
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	// operation is not over. we should still have a pending op
@@ -236,7 +237,7 @@ func TestDeviceRemoveOperationTooFewDevices(t *testing.T) {
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	dro := NewDeviceRemoveOperation(d.Info.Id, app.db, api.HealCheckEnable)
-	err = dro.Build()
+	err = dro.Build(context.Background())
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	// because there were bricks on this device it needs to perform
@@ -255,7 +256,7 @@ func TestDeviceRemoveOperationTooFewDevices(t *testing.T) {
 		return mockHealStatusFromDb(app.db, volume)
 	}
 
-	err = dro.Exec(app.executor)
+	err = dro.Exec(context.Background(), app.executor)
 	tests.Assert(t, strings.Contains(err.Error(), ErrNoReplacement.Error()),
 		"expected strings.Contains(err.Error(), ErrNoReplacement.Error()), got:",
 		err.Error())
@@ -268,7 +269,7 @@ func TestDeviceRemoveOperationTooFewDevices(t *testing.T) {
 		return nil
 	})
 
-	err = dro.Rollback(app.executor)
+	err = dro.Rollback(context.Background(), app.executor)
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	// operation is over. we should _not_ have a pending op now
@@ -342,7 +343,7 @@ func TestDeviceRemoveOperationOtherPendingOps(t *testing.T) {
 	// now start a volume create operation but don't finish it
 	vol := NewVolumeEntryFromRequest(vreq)
 	vc := NewVolumeCreateOperation(vol, app.db)
-	err = vc.Build()
+	err = vc.Build(context.Background())
 	tests.Assert(t, err == nil, "expected e == nil, got", err)
 	// we should have one pending operation
 	err = app.db.View(func(tx *bolt.Tx) error {
@@ -356,7 +357,7 @@ func TestDeviceRemoveOperationOtherPendingOps(t *testing.T) {
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	dro := NewDeviceRemoveOperation(d.Info.Id, app.db, api.HealCheckEnable)
-	err = dro.Build()
+	err = dro.Build(context.Background())
 	tests.Assert(t, err == ErrConflict, "expected err == ErrConflict, got:", err)
 
 	// we should have one pending operation (the volume create)
@@ -423,7 +424,7 @@ func TestDeviceRemoveOperationMultipleRequests(t *testing.T) {
 
 	// perform the build step of one remove operation
 	dro := NewDeviceRemoveOperation(d.Info.Id, app.db, api.HealCheckEnable)
-	err = dro.Build()
+	err = dro.Build(context.Background())
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	// perform the build step of a 2nd remove operation
@@ -431,7 +432,7 @@ func TestDeviceRemoveOperationMultipleRequests(t *testing.T) {
 	// that cover the Build steps are effectively serializing
 	// these actions.
 	dro2 := NewDeviceRemoveOperation(d.Info.Id, app.db, api.HealCheckEnable)
-	err = dro2.Build()
+	err = dro2.Build(context.Background())
 	tests.Assert(t, err == ErrConflict, "expected err == ErrConflict, got:", err)
 
 	// we should have one pending operation (the device remove)
@@ -479,7 +480,7 @@ func TestBrickEvictOperation(t *testing.T) {
 	})
 
 	beo := NewBrickEvictOperation(b.Info.Id, app.db, api.HealCheckEnable)
-	err = beo.Build()
+	err = beo.Build(context.Background())
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 	err = app.db.View(func(tx *bolt.Tx) error {
 		l, err := PendingOperationList(tx)
@@ -500,7 +501,7 @@ func TestBrickEvictOperation(t *testing.T) {
 		return mockHealStatusFromDb(app.db, volume)
 	}
 
-	err = beo.Exec(app.executor)
+	err = beo.Exec(context.Background(), app.executor)
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	// operation is not over. we should still have a pending op
@@ -593,17 +594,17 @@ func TestBrickEvictOperationOneAtATime(t *testing.T) {
 	})
 
 	beo1 := NewBrickEvictOperation(b1.Info.Id, app.db, api.HealCheckEnable)
-	err = beo1.Build()
+	err = beo1.Build(context.Background())
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	beo2 := NewBrickEvictOperation(b2.Info.Id, app.db, api.HealCheckEnable)
-	err = beo2.Build()
+	err = beo2.Build(context.Background())
 	tests.Assert(t, err != nil, "expected err != nil, got:", err)
 	tests.Assert(t, strings.Contains(err.Error(), "pending"),
 		"expected 'pedning' in error, got:", err)
 
 	beo3 := NewBrickEvictOperation(b1.Info.Id, app.db, api.HealCheckEnable)
-	err = beo3.Build()
+	err = beo3.Build(context.Background())
 	tests.Assert(t, err != nil, "expected err != nil, got:", err)
 	tests.Assert(t, strings.Contains(err.Error(), "pending"),
 		"expected 'pedning' in error, got:", err)
@@ -783,7 +784,7 @@ func TestBrickEvictOperationInvalidExec(t *testing.T) {
 	})
 
 	beo := NewBrickEvictOperation(b.Info.Id, app.db, api.HealCheckEnable)
-	err = beo.Build()
+	err = beo.Build(context.Background())
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 	var pop *PendingOperationEntry
 	err = app.db.Update(func(tx *bolt.Tx) error {
@@ -821,7 +822,7 @@ func TestBrickEvictOperationInvalidExec(t *testing.T) {
 		return mockHealStatusFromDb(app.db, volume)
 	}
 
-	err = beo.Exec(app.executor)
+	err = beo.Exec(context.Background(), app.executor)
 	tests.Assert(t, err != nil, "expected err != nil, got:", err)
 }
 
@@ -968,7 +969,7 @@ func TestDeviceRemoveOperationChildOpError(t *testing.T) {
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	dro := NewDeviceRemoveOperation(d.Info.Id, app.db, api.HealCheckEnable)
-	err = dro.Build()
+	err = dro.Build(context.Background())
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	// because there were bricks on this device it needs to perform
@@ -996,7 +997,7 @@ func TestDeviceRemoveOperationChildOpError(t *testing.T) {
 				x = fmt.Errorf("panicked")
 			}
 		}()
-		x = dro.Exec(app.executor)
+		x = dro.Exec(context.Background(), app.executor)
 		t.Fatalf("Test should not reach this line")
 		return x
 	}()
@@ -1091,7 +1092,7 @@ func TestDeviceRemoveOperationChildOpCleanup(t *testing.T) {
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	dro := NewDeviceRemoveOperation(d.Info.Id, app.db, api.HealCheckEnable)
-	err = dro.Build()
+	err = dro.Build(context.Background())
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	// because there were bricks on this device it needs to perform
@@ -1119,7 +1120,7 @@ func TestDeviceRemoveOperationChildOpCleanup(t *testing.T) {
 				x = fmt.Errorf("panicked")
 			}
 		}()
-		x = dro.Exec(app.executor)
+		x = dro.Exec(context.Background(), app.executor)
 		t.Fatalf("Test should not reach this line")
 		return x
 	}()
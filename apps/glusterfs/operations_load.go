@@ -46,6 +46,8 @@ func LoadOperation(
 		op, err = loadVolumeDeleteOperation(db, p)
 	case OperationExpandVolume:
 		op, err = loadVolumeExpandOperation(db, p)
+	case OperationSetVolumeTranslators:
+		op, err = loadVolumeTranslatorsOperation(db, p)
 	// block volume operations
 	case OperationCreateBlockVolume:
 		op, err = loadBlockVolumeCreateOperation(db, p)
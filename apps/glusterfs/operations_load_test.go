@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"os"
 	"strings"
 	"testing"
@@ -52,7 +53,7 @@ func TestLoadOperation(t *testing.T) {
 		req.Durability.Replicate.Replica = 3
 		vol := NewVolumeEntryFromRequest(req)
 		vc := NewVolumeCreateOperation(vol, app.db)
-		e := vc.Build()
+		e := vc.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		o, e := LoadOperation(app.db, vc.op)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -75,7 +76,7 @@ func TestLoadOperation(t *testing.T) {
 		e := RunOperation(vc, app.executor)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		vdel := NewVolumeDeleteOperation(vol, app.db)
-		e = vdel.Build()
+		e = vdel.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		o, e := LoadOperation(app.db, vdel.op)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -98,7 +99,7 @@ func TestLoadOperation(t *testing.T) {
 		e := RunOperation(vc, app.executor)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		ve := NewVolumeExpandOperation(vol, app.db, 6)
-		e = ve.Build()
+		e = ve.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		o, e := LoadOperation(app.db, ve.op)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -116,9 +117,9 @@ func TestLoadOperation(t *testing.T) {
 		req.Size = 1024
 		vol := NewBlockVolumeEntryFromRequest(req)
 		vc := NewBlockVolumeCreateOperation(vol, app.db)
-		e := vc.Build()
+		e := vc.Build(context.Background())
 		// need to roll back the bhv create in order to test other ops later
-		defer vc.Rollback(app.executor)
+		defer vc.Rollback(context.Background(), app.executor)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		o, e := LoadOperation(app.db, vc.op)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -139,7 +140,7 @@ func TestLoadOperation(t *testing.T) {
 		e := RunOperation(vc, app.executor)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		vdel := NewBlockVolumeDeleteOperation(vol, app.db)
-		e = vdel.Build()
+		e = vdel.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		o, e := LoadOperation(app.db, vdel.op)
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
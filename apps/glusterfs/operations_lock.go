@@ -0,0 +1,193 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/dlock"
+)
+
+// lockableOperation is implemented by operations that change volumes
+// or allocate space in clusters. When several heketi servers share a
+// distributed lock only one of them runs an operation on the same
+// volume or cluster at a time.
+type lockableOperation interface {
+	LockKeys() ([]string, error)
+}
+
+// allocatingOperation is implemented by lockable operations that
+// allocate space in clusters when they are built. The locks of these
+// clusters are only held while the operation is built, so that other
+// servers can allocate space in the clusters while it runs.
+type allocatingOperation interface {
+	AllocationLockKeys() ([]string, error)
+}
+
+func volumeLockKey(id string) string {
+	return "volume/" + id
+}
+
+func blockVolumeLockKey(id string) string {
+	return "blockvolume/" + id
+}
+
+func clusterLockKey(id string) string {
+	return "cluster/" + id
+}
+
+// clusterLockKeys returns the keys of the given clusters. If no
+// clusters are given space may be allocated in any of them and the
+// keys of all clusters are returned.
+func clusterLockKeys(db wdb.RODB, clusters []string) ([]string, error) {
+	if len(clusters) == 0 {
+		err := db.View(func(tx *bolt.Tx) error {
+			var err error
+			clusters, err = ClusterList(tx)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	keys := []string{}
+	for _, c := range clusters {
+		keys = append(keys, clusterLockKey(c))
+	}
+	return keys, nil
+}
+
+// lockExtender renews the distributed locks held by the server before
+// they expire.
+type lockExtender struct {
+	dl  dlock.DistributedLock
+	ttl time.Duration
+
+	// to stop the extender
+	stop chan<- interface{}
+}
+
+func (le *lockExtender) Start() {
+	ticker := time.NewTicker(le.ttl / 3)
+	stop := make(chan interface{})
+	le.stop = stop
+
+	go func() {
+		logger.Info("Started Distributed Lock Extender")
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				logger.Info("Stopping Distributed Lock Extender")
+				return
+			case <-ticker.C:
+				if err := le.dl.Extend(le.ttl); err != nil {
+					logger.LogError("Distributed Lock Extender: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (le *lockExtender) Stop() {
+	le.stop <- true
+}
+
+func (app *App) initDistributedLock() error {
+	c := app.conf.DistributedLock
+	if c.Type == "" {
+		return nil
+	}
+	dl, err := dlock.New(c)
+	if err != nil {
+		return err
+	}
+	app.dlock = dl
+	app.dlockTtl = c.TtlDuration()
+	app.dlockExtender = &lockExtender{dl: dl, ttl: app.dlockTtl}
+	app.dlockExtender.Start()
+	logger.Info("Using %v distributed lock", c.Type)
+	return nil
+}
+
+// lockOperation takes the distributed locks of the volumes and
+// clusters the operation changes. It returns the function releasing
+// the locks of the clusters the operation allocates space in, to be
+// called once the operation is built, and the function releasing the
+// other locks, to be called once the operation is done. Nothing is
+// locked if no distributed lock is configured or the operation does
+// not change a volume or cluster.
+func (app *App) lockOperation(op Operation) (func(), func(), error) {
+	noop := func() {}
+	lo, ok := op.(lockableOperation)
+	if app.dlock == nil || !ok {
+		return noop, noop, nil
+	}
+	keys, err := lo.LockKeys()
+	if err != nil {
+		logger.LogError("Unable to get lock keys of %v: %v", op.Label(), err)
+		return nil, nil, err
+	}
+	allocKeys := []string{}
+	if ao, ok := op.(allocatingOperation); ok {
+		allocKeys, err = ao.AllocationLockKeys()
+		if err != nil {
+			logger.LogError("Unable to get lock keys of %v: %v",
+				op.Label(), err)
+			return nil, nil, err
+		}
+	}
+	unlock, err := app.lockKeys(op.Label(), keys...)
+	if err != nil {
+		return nil, nil, err
+	}
+	unlockAlloc, err := app.lockKeys(op.Label(), allocKeys...)
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return unlockAlloc, unlock, nil
+}
+
+// lockKeys takes the distributed locks of all the given keys or none
+// of them and returns the function releasing them.
+func (app *App) lockKeys(label string, keys ...string) (func(), error) {
+	if app.dlock == nil {
+		return func() {}, nil
+	}
+	// servers take the keys in the same order
+	keys = append([]string{}, keys...)
+	sort.Strings(keys)
+
+	unlocks := []func(){}
+	unlock := func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for _, key := range keys {
+		u, err := app.dlock.TryLock(key, app.dlockTtl)
+		if err == dlock.ErrLocked {
+			logger.Info("%v of %v is locked by another server", label, key)
+			unlock()
+			return nil, ErrOperationLocked
+		} else if err != nil {
+			logger.LogError("Unable to lock %v: %v", key, err)
+			unlock()
+			return nil, err
+		}
+		unlocks = append(unlocks, u)
+	}
+	return unlock, nil
+}
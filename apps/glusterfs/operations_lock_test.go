@@ -0,0 +1,285 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/dlock"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// testLockService stands in for the coordination service shared by
+// the servers
+type testLockService struct {
+	lock sync.Mutex
+	// holder of each key
+	held map[string]string
+}
+
+// testLockHolder is the distributed lock of one server
+type testLockHolder struct {
+	s    *testLockService
+	name string
+}
+
+func (h *testLockHolder) TryLock(key string, ttl time.Duration) (func(), error) {
+	h.s.lock.Lock()
+	defer h.s.lock.Unlock()
+	if _, found := h.s.held[key]; found {
+		return nil, dlock.ErrLocked
+	}
+	h.s.held[key] = h.name
+	return func() {
+		h.s.lock.Lock()
+		defer h.s.lock.Unlock()
+		delete(h.s.held, key)
+	}, nil
+}
+
+func (h *testLockHolder) Extend(ttl time.Duration) error {
+	return nil
+}
+
+func (h *testLockHolder) Close() error {
+	return nil
+}
+
+func TestOperationDistributedLock(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	locks := &testLockService{held: map[string]string{}}
+	app.dlock = &testLockHolder{locks, "server-a"}
+	app.dlockTtl = dlock.DEFAULT_TTL
+	other := &testLockHolder{locks, "server-b"}
+
+	c := client.NewClientNoAuth(ts.URL)
+	v, err := c.VolumeCreate(&api.VolumeCreateRequest{Size: 100})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(locks.held) == 0, "unexpected locks:", locks.held)
+
+	// another server is changing the volume
+	unlock, err := other.TryLock("volume/"+v.Id, dlock.DEFAULT_TTL)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	r, err := http.Post(ts.URL+"/volumes/"+v.Id+"/expand",
+		"application/json", bytes.NewBufferString(`{"expand_size": 100}`))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	r.Body.Close()
+	tests.Assert(t, r.StatusCode == http.StatusTooManyRequests,
+		"expected http.StatusTooManyRequests, got:", r.StatusCode)
+	req, err := http.NewRequest("DELETE", ts.URL+"/volumes/"+v.Id, nil)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	r, err = http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	r.Body.Close()
+	tests.Assert(t, r.StatusCode == http.StatusTooManyRequests,
+		"expected http.StatusTooManyRequests, got:", r.StatusCode)
+
+	// no operation is left behind
+	tests.Assert(t, app.optracker.Get() == 0,
+		"expected no tracked operations, got:", app.optracker.Get())
+	tests.Assert(t, len(locks.held) == 1, "unexpected locks:", locks.held)
+
+	unlock()
+	info, err := c.VolumeExpand(v.Id, &api.VolumeExpandRequest{Size: 100})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Size == 200, "expected size 200, got:", info.Size)
+	tests.Assert(t, len(locks.held) == 0, "unexpected locks:", locks.held)
+}
+
+func TestOperationDistributedLockKeys(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	locks := &testLockService{held: map[string]string{}}
+	app.dlock = &testLockHolder{locks, "server-a"}
+	app.dlockTtl = dlock.DEFAULT_TTL
+	other := &testLockHolder{locks, "server-b"}
+
+	c := client.NewClientNoAuth(ts.URL)
+	clusters, err := c.ClusterList()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	clusterId := clusters.Clusters[0]
+
+	request := func(method, url, body string) int {
+		req, err := http.NewRequest(method, ts.URL+url,
+			bytes.NewBufferString(body))
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		req.Header.Set("Content-Type", "application/json")
+		r, err := http.DefaultClient.Do(req)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		r.Body.Close()
+		return r.StatusCode
+	}
+
+	// another server is allocating space in the cluster
+	unlock, err := other.TryLock("cluster/"+clusterId, dlock.DEFAULT_TTL)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	code := request("POST", "/volumes", `{"size": 100}`)
+	tests.Assert(t, code == http.StatusTooManyRequests,
+		"expected http.StatusTooManyRequests, got:", code)
+	code = request("POST", "/blockvolumes", `{"size": 10}`)
+	tests.Assert(t, code == http.StatusTooManyRequests,
+		"expected http.StatusTooManyRequests, got:", code)
+	tests.Assert(t, len(locks.held) == 1, "unexpected locks:", locks.held)
+	unlock()
+
+	v, err := c.VolumeCreate(&api.VolumeCreateRequest{Size: 100})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// the volume key taken before the cluster key is released
+	unlock, err = other.TryLock("cluster/"+clusterId, dlock.DEFAULT_TTL)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	code = request("POST", "/volumes/"+v.Id+"/expand", `{"expand_size": 100}`)
+	tests.Assert(t, code == http.StatusTooManyRequests,
+		"expected http.StatusTooManyRequests, got:", code)
+	tests.Assert(t, len(locks.held) == 1, "unexpected locks:", locks.held)
+	tests.Assert(t, locks.held["cluster/"+clusterId] == "server-b",
+		"unexpected locks:", locks.held)
+	unlock()
+
+	// another server is changing the volume
+	unlock, err = other.TryLock("volume/"+v.Id, dlock.DEFAULT_TTL)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	code = request("PUT", "/volumes/"+v.Id+"/pin", `{"pinned_node_ids": []}`)
+	tests.Assert(t, code == http.StatusTooManyRequests,
+		"expected http.StatusTooManyRequests, got:", code)
+	code = request("PUT", "/volumes/"+v.Id+"/acl-config", `{"root_squash": true}`)
+	tests.Assert(t, code == http.StatusTooManyRequests,
+		"expected http.StatusTooManyRequests, got:", code)
+	code = request("POST", "/volumes/"+v.Id+"/repair", ``)
+	tests.Assert(t, code == http.StatusTooManyRequests,
+		"expected http.StatusTooManyRequests, got:", code)
+	tests.Assert(t, app.optracker.Get() == 0,
+		"expected no tracked operations, got:", app.optracker.Get())
+	tests.Assert(t, len(locks.held) == 1, "unexpected locks:", locks.held)
+	unlock()
+
+	code = request("PUT", "/volumes/"+v.Id+"/pin", `{"pinned_node_ids": []}`)
+	tests.Assert(t, code == http.StatusOK, "expected http.StatusOK, got:", code)
+	tests.Assert(t, len(locks.held) == 0, "unexpected locks:", locks.held)
+}
+
+func TestOperationDistributedLockBuild(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		2,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	locks := &testLockService{held: map[string]string{}}
+	app.dlock = &testLockHolder{locks, "server-a"}
+	app.dlockTtl = dlock.DEFAULT_TTL
+	other := &testLockHolder{locks, "server-b"}
+
+	c := client.NewClientNoAuth(ts.URL)
+	clusters, err := c.ClusterList()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	clusterId := clusters.Clusters[0]
+
+	// pause the async operations between build and exec
+	built := make(chan string)
+	barrier := make(chan bool)
+	defer func(f func(o Operation)) { operationBuilt = f }(operationBuilt)
+	operationBuilt = func(o Operation) {
+		built <- o.Id()
+		<-barrier
+	}
+	create := func() {
+		r, err := http.Post(ts.URL+"/volumes", "application/json",
+			bytes.NewBufferString(`{"size": 100}`))
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		r.Body.Close()
+		tests.Assert(t, r.StatusCode == http.StatusAccepted,
+			"expected http.StatusAccepted, got:", r.StatusCode)
+	}
+
+	// only the new volume stays locked once the space is allocated
+	create()
+	<-built
+	tests.Assert(t, len(locks.held) == 1, "unexpected locks:", locks.held)
+	for key := range locks.held {
+		tests.Assert(t, strings.HasPrefix(key, "volume/"),
+			"unexpected locks:", locks.held)
+	}
+	unlock, err := other.TryLock("cluster/"+clusterId, dlock.DEFAULT_TTL)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	unlock()
+
+	// more volumes are created while the first one is being created
+	create()
+	<-built
+	tests.Assert(t, len(locks.held) == 2, "unexpected locks:", locks.held)
+
+	close(barrier)
+	for i := 0; i < 100 && app.optracker.Get() != 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	tests.Assert(t, app.optracker.Get() == 0,
+		"expected no tracked operations, got:", app.optracker.Get())
+	tests.Assert(t, len(locks.held) == 0, "unexpected locks:", locks.held)
+	l, err := c.VolumeList()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(l.Volumes) == 2, "expected 2 volumes, got:", l.Volumes)
+}
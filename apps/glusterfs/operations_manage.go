@@ -10,14 +10,24 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 
 	"github.com/lpabon/godbc"
 
 	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
 	"github.com/heketi/heketi/pkg/idgen"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+var (
+	// support unit test dep. injection to pause async operations
+	// between the build and exec phases
+	operationBuilt = func(o Operation) {}
 )
 
 type OpClass int
@@ -149,16 +159,70 @@ func (ot *OpTracker) ThrottleOrToken() (bool, string) {
 	return false, token
 }
 
-func runOperationAfterBuild(o Operation,
+// opCanceler tracks operations that have completed their build phase
+// but have not yet started their exec phase. These operations may be
+// canceled.
+type opCanceler struct {
+	lock    sync.Mutex
+	waiting map[string]CancelableOperation
+}
+
+func newOpCanceler() *opCanceler {
+	return &opCanceler{
+		waiting: map[string]CancelableOperation{},
+	}
+}
+
+// Wait records a built operation as eligible for cancellation.
+func (oc *opCanceler) Wait(o CancelableOperation) {
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	oc.waiting[o.Id()] = o
+}
+
+// Start removes an operation from the set of cancelable operations
+// and returns true if the operation may continue on to the exec phase.
+// If false is returned the operation has been canceled.
+func (oc *opCanceler) Start(id string) bool {
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	if _, ok := oc.waiting[id]; !ok {
+		return false
+	}
+	delete(oc.waiting, id)
+	return true
+}
+
+// Cancel cancels the operation with the given id, undoing the db
+// changes of its build phase. If the operation is unknown or has
+// already started executing ErrNotCancelable is returned.
+func (oc *opCanceler) Cancel(id string) error {
+	oc.lock.Lock()
+	o, ok := oc.waiting[id]
+	delete(oc.waiting, id)
+	oc.lock.Unlock()
+	if !ok {
+		return ErrNotCancelable
+	}
+	if err := o.CancelBuild(); err != nil {
+		logger.LogError("%v Cancel Failed: %v", o.Label(), err)
+		markFailedIfSupported(o)
+		return err
+	}
+	return nil
+}
+
+func runOperationAfterBuild(ctx context.Context, o Operation,
 	executor executors.Executor) (err error) {
 
 	label := o.Label()
 	max_tries := o.MaxRetries() + 1
+	executor = newCtxExecutor(ctx, executor)
 
 	for attempt := 1; ; attempt++ {
 		logger.Info("Trying %v (attempt #%v/%v)", label, attempt, max_tries)
 
-		err = o.Exec(executor)
+		err = o.Exec(ctx, executor)
 		if err == nil {
 			// success, exit
 			break
@@ -166,16 +230,38 @@ func runOperationAfterBuild(o Operation,
 
 		logger.LogError("%v Failed: %v", label, err)
 
+		if cerr := ctx.Err(); cerr != nil {
+			// no commands can be sent to roll back the operation,
+			// leave it to the background cleaner
+			markFailedIfSupported(o)
+			return OperationCanceledError{
+				Id:    o.Id(),
+				Label: label,
+				Cause: cerr,
+			}
+		}
+
+		if serr, ok := err.(StepRetryError); ok {
+			if attempt < max_tries {
+				// completed steps are kept, no rollback needed
+				logger.Info("Retrying %v from step %v", label, serr.Step)
+				continue
+			}
+			err = serr.OriginalError
+		}
+
 		oerr, isRetryError := err.(OperationRetryError)
 		if isRetryError {
 			err = oerr.OriginalError
 		}
 
-		if rerr := o.Rollback(executor); rerr != nil {
+		history := historyEntryIfSupported(o)
+		if rerr := o.Rollback(ctx, executor); rerr != nil {
 			logger.LogError("%v Rollback error: %v", label, rerr)
 			markFailedIfSupported(o)
 			return err
 		}
+		recordHistoryIfSupported(o, history, RolledBackOperation)
 
 		if attempt >= max_tries {
 			logger.LogError("Max tries (%v) consumed", max_tries)
@@ -189,14 +275,70 @@ func runOperationAfterBuild(o Operation,
 
 		logger.Info("Retrying %v", label)
 
-		if err := o.Build(); err != nil {
+		if err := o.Build(ctx); err != nil {
 			logger.LogError("%v Build Failed: %v", label, err)
 			return err
 		}
 	}
 
 	// if we reach this, we have succeeded
-	return o.Finalize()
+	history := historyEntryIfSupported(o)
+	if err := o.Finalize(); err != nil {
+		return err
+	}
+	recordHistoryIfSupported(o, history, CompletedOperation)
+	return nil
+}
+
+// historyEntryIfSupported returns a copy of the pending operation entry
+// of the operation if it keeps an operation history, otherwise nil.
+func historyEntryIfSupported(o Operation) *PendingOperationEntry {
+	if ho, ok := o.(historyOperation); ok {
+		return ho.historyEntry()
+	}
+	return nil
+}
+
+// recordHistoryIfSupported adds the entry of the finished operation to
+// the operation history. The operation has already finished, failing
+// to record its history is only logged.
+func recordHistoryIfSupported(o Operation,
+	p *PendingOperationEntry, status OperationStatus) {
+
+	ho, ok := o.(historyOperation)
+	if !ok || p == nil {
+		return
+	}
+	if err := ho.recordHistory(p, status); err != nil {
+		logger.LogError("Unable to record history of %v: %v", o.Label(), err)
+	}
+}
+
+// taggableOperation is implemented by operations that can carry user
+// defined tags on their pending operation entry.
+type taggableOperation interface {
+	SetTags(tags map[string]string)
+}
+
+// operationTagsFromRequest returns the operation tags set in the
+// headers of the request. Header names are not case sensitive so the
+// tag keys are converted to lower case.
+func operationTagsFromRequest(r *http.Request) map[string]string {
+	var tags map[string]string
+	for name, values := range r.Header {
+		if !strings.HasPrefix(name, api.OperationTagHeaderPrefix) {
+			continue
+		}
+		key := strings.ToLower(strings.TrimPrefix(name, api.OperationTagHeaderPrefix))
+		if key == "" || len(values) == 0 {
+			continue
+		}
+		if tags == nil {
+			tags = map[string]string{}
+		}
+		tags[key] = values[0]
+	}
+	return tags
 }
 
 // AsyncHttpOperation runs all the steps of an operation with the long-running
@@ -204,7 +346,8 @@ func runOperationAfterBuild(o Operation,
 // then it has started the async function and the caller should respond to the
 // client with success - otherwise an error object is returned. In the async
 // function the Exec and Finalize or Rollback steps of the operation will be
-// performed.
+// performed. Async operations outlive the http request and so run with the
+// app's context, which is only canceled when the server shuts down.
 func AsyncHttpOperation(app *App,
 	w http.ResponseWriter,
 	r *http.Request,
@@ -215,21 +358,48 @@ func AsyncHttpOperation(app *App,
 		return ErrTooManyOperations
 	}
 
+	if tags := operationTagsFromRequest(r); tags != nil {
+		if t, ok := op.(taggableOperation); ok {
+			t.SetTags(tags)
+		}
+	}
+
+	unlockAlloc, unlock, err := app.lockOperation(op)
+	if err != nil {
+		app.optracker.Remove(op.Id())
+		return err
+	}
+
 	label := op.Label()
-	if err := op.Build(); err != nil {
+	err = op.Build(app.ctx)
+	// the space of the operation is allocated once it is built
+	unlockAlloc()
+	if err != nil {
 		logger.LogError("%v Build Failed: %v", label, err)
 		// creating the operation db data failed. this is no longer
 		// an in-flight operation
 		app.optracker.Remove(op.Id())
+		unlock()
 		return err
 	}
 
+	co, cancelable := op.(CancelableOperation)
+	if cancelable {
+		app.opcanceler.Wait(co)
+	}
+
 	app.asyncManager.AsyncHttpRedirectFunc(w, r, func() (string, error) {
 		// decrement the op counter once the operation is done
 		// either success or failure
 		defer app.optracker.Remove(op.Id())
+		defer unlock()
+		operationBuilt(op)
+		if cancelable && !app.opcanceler.Start(op.Id()) {
+			logger.Info("Canceled async operation: %v", label)
+			return "", ErrOperationCanceled
+		}
 		logger.Info("Started async operation: %v", label)
-		if err := runOperationAfterBuild(op, app.executor); err != nil {
+		if err := runOperationAfterBuild(app.ctx, op, app.executor); err != nil {
 			return "", err
 		}
 
@@ -245,6 +415,15 @@ func AsyncHttpOperation(app *App,
 func RunOperation(o Operation,
 	executor executors.Executor) (err error) {
 
+	return RunOperationContext(context.Background(), o, executor)
+}
+
+// RunOperationContext performs all steps of an Operation like
+// RunOperation but stops issuing commands once ctx is done. Synchronous
+// callers should pass the context of the request they are serving.
+func RunOperationContext(ctx context.Context, o Operation,
+	executor executors.Executor) (err error) {
+
 	label := o.Label()
 	defer func() {
 		if err != nil {
@@ -253,12 +432,15 @@ func RunOperation(o Operation,
 	}()
 
 	logger.Info("Running %v", o.Label())
-	if err := o.Build(); err != nil {
+	if err := ctx.Err(); err != nil {
+		return OperationCanceledError{Id: o.Id(), Label: label, Cause: err}
+	}
+	if err := o.Build(ctx); err != nil {
 		logger.LogError("%v Build Failed: %v", label, err)
 		return err
 	}
 
-	return runOperationAfterBuild(o, executor)
+	return runOperationAfterBuild(ctx, o, executor)
 }
 
 // rollbackViaClean runs a CleanableOperation's clean methods as
@@ -309,13 +491,32 @@ func OperationHttpErrorf(
 
 	var msg string
 	status := http.StatusInternalServerError
+	code := api.ErrorInternal
 	switch e {
 	case ErrTooManyOperations:
 		status = http.StatusTooManyRequests
+		code = api.ErrorServerBusy
 		msg = "Server busy. Retry operation later."
+	case ErrNoSpace:
+		code = api.ErrorInsufficientSpace
+		msg = fmt.Sprintf(f, v...)
+	case ErrVolumeExpanding:
+		status = http.StatusConflict
+		code = api.ErrorOperationInProgress
+		msg = fmt.Sprintf(f, v...)
+	case ErrOperationLocked:
+		// the other server releases the lock once done, clients
+		// retry requests that are throttled
+		status = http.StatusTooManyRequests
+		code = api.ErrorOperationInProgress
+		msg = fmt.Sprintf(f, v...)
 	default:
 		msg = fmt.Sprintf(f, v...)
+		if _, ok := e.(volumeSizeError); ok {
+			status = http.StatusUnprocessableEntity
+			code = api.ErrorUnprocessable
+		}
 	}
 
-	http.Error(w, msg, status)
+	utils.HttpErrorCode(w, code, msg, status)
 }
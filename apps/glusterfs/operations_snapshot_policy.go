@@ -0,0 +1,226 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+const (
+	// the snap-max-hard-limit of gluster if not configured
+	glusterDefaultSnapMaxHardLimit = 256
+)
+
+// snapshotPolicyStep is a single gluster setting implementing part of
+// a snapshot policy. A step without a volume sets a snapshot config
+// option of the pool, a step with a volume sets the snapshot schedule
+// of that volume.
+type snapshotPolicyStep struct {
+	option string
+	volume string
+	value  string
+}
+
+func (s snapshotPolicyStep) apply(executor executors.Executor, host string) error {
+	if s.volume == "" {
+		return executor.SnapshotConfigSet(host, s.option, s.value)
+	}
+	return executor.SnapshotScheduleSet(host, s.volume, s.value)
+}
+
+// snapshotPolicySteps returns the gluster settings implementing the
+// policy, in order. A nil policy results in the gluster defaults. The
+// schedule is set for each of the given volumes.
+func snapshotPolicySteps(p *api.ClusterSnapshotPolicy,
+	volumes []string) []snapshotPolicyStep {
+
+	if p == nil {
+		p = &api.ClusterSnapshotPolicy{}
+	}
+	limit := p.MaxSnapshotsPerVolume
+	if limit == 0 {
+		limit = glusterDefaultSnapMaxHardLimit
+	}
+	autoDelete := "disable"
+	if p.AutoDelete {
+		autoDelete = "enable"
+	}
+	steps := []snapshotPolicyStep{
+		{option: "snap-max-hard-limit", value: strconv.Itoa(limit)},
+		{option: "auto-delete", value: autoDelete},
+	}
+	for _, v := range volumes {
+		steps = append(steps, snapshotPolicyStep{volume: v, value: p.CronSchedule})
+	}
+	return steps
+}
+
+// SetSnapshotPolicyOperation implements the operation functions used
+// to change the gluster snapshot settings of a cluster.
+type SetSnapshotPolicyOperation struct {
+	OperationManager
+	noRetriesOperation
+	clusterId string
+	policy    api.ClusterSnapshotPolicy
+	// who asked for the change, recorded in the cluster events
+	actor string
+
+	// set by Build
+	prev    *api.ClusterSnapshotPolicy
+	volumes []string
+
+	// the number of steps applied by Exec, in order
+	applied int
+}
+
+// NewSetSnapshotPolicyOperation returns a new SetSnapshotPolicyOperation
+// populated with the given params.
+func NewSetSnapshotPolicyOperation(clusterId string, db wdb.DB,
+	policy api.ClusterSnapshotPolicy) *SetSnapshotPolicyOperation {
+
+	return &SetSnapshotPolicyOperation{
+		OperationManager: OperationManager{
+			db: db,
+			op: NewPendingOperationEntry(NEW_ID),
+		},
+		clusterId: clusterId,
+		policy:    policy,
+	}
+}
+
+func (so *SetSnapshotPolicyOperation) Label() string {
+	return "Set Cluster Snapshot Policy"
+}
+
+func (so *SetSnapshotPolicyOperation) LockKeys() ([]string, error) {
+	return []string{clusterLockKey(so.clusterId)}, nil
+}
+
+func (so *SetSnapshotPolicyOperation) ResourceUrl() string {
+	return fmt.Sprintf("/clusters/%v/snapshot-policy", so.clusterId)
+}
+
+// Build records the current policy of the cluster and the pending
+// change in the db. The schedules of the volumes of the cluster are
+// only touched if the current or the new policy has a schedule.
+func (so *SetSnapshotPolicyOperation) Build(ctx context.Context) error {
+	return so.db.Update(func(tx *bolt.Tx) error {
+		c, err := NewClusterEntryFromId(tx, so.clusterId)
+		if err != nil {
+			return err
+		}
+		ops, err := PendingOperationList(tx)
+		if err != nil {
+			return err
+		}
+		for _, id := range ops {
+			pop, err := NewPendingOperationEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			if pop.Type == OperationSetSnapshotPolicy && pop.Targets(c.Info.Id) {
+				logger.LogError("Snapshot policy of cluster %v is already"+
+					" being changed by operation %v", c.Info.Id, id)
+				return ErrConflict
+			}
+		}
+
+		so.prev = c.SnapshotPolicy
+		so.volumes = nil
+		if so.policy.CronSchedule != "" ||
+			(so.prev != nil && so.prev.CronSchedule != "") {
+			for _, id := range c.Info.Volumes {
+				v, err := NewVolumeEntryFromId(tx, id)
+				if err != nil {
+					return err
+				}
+				so.volumes = append(so.volumes, v.Info.Name)
+			}
+		}
+		so.applied = 0
+		so.op.RecordSetSnapshotPolicy(c)
+		return so.op.Save(tx)
+	})
+}
+
+// Exec applies the settings one at a time so that a failure part way
+// through leaves a known set of settings to be rolled back.
+func (so *SetSnapshotPolicyOperation) Exec(ctx context.Context, executor executors.Executor) error {
+	host, err := GetVerifiedManageHostname(so.db, executor, so.clusterId)
+	if err != nil {
+		return err
+	}
+	for _, s := range snapshotPolicySteps(&so.policy, so.volumes) {
+		if err := s.apply(executor, host); err != nil {
+			logger.LogError("Failed to set snapshot policy of cluster %v: %v",
+				so.clusterId, err)
+			return err
+		}
+		so.applied++
+	}
+	return nil
+}
+
+// Rollback restores the previous values of the settings applied by
+// Exec and removes the pending operation.
+func (so *SetSnapshotPolicyOperation) Rollback(ctx context.Context, executor executors.Executor) error {
+	if so.applied > 0 {
+		host, err := GetVerifiedManageHostname(so.db, executor, so.clusterId)
+		if err != nil {
+			return err
+		}
+		prev := snapshotPolicySteps(so.prev, so.volumes)[:so.applied]
+		for _, s := range prev {
+			if err := s.apply(executor, host); err != nil {
+				logger.LogError("Failed to restore snapshot policy of cluster %v: %v",
+					so.clusterId, err)
+				return err
+			}
+		}
+		so.applied = 0
+	}
+	return so.db.Update(func(tx *bolt.Tx) error {
+		return so.op.Delete(tx)
+	})
+}
+
+// Finalize records the new policy in the cluster entry.
+func (so *SetSnapshotPolicyOperation) Finalize() error {
+	return so.db.Update(func(tx *bolt.Tx) error {
+		c, err := NewClusterEntryFromId(tx, so.clusterId)
+		if err != nil {
+			return err
+		}
+		policy := so.policy
+		c.SnapshotPolicy = &policy
+		if err := c.Save(tx); err != nil {
+			return err
+		}
+		err = AddClusterEvent(tx, so.clusterId,
+			api.ClusterEventSnapshotPolicyChanged, so.actor,
+			"Changed the snapshot policy", map[string]string{
+				"max_snapshots_per_volume": strconv.Itoa(policy.MaxSnapshotsPerVolume),
+				"auto_delete":              strconv.FormatBool(policy.AutoDelete),
+				"cron_schedule":            policy.CronSchedule,
+			})
+		if err != nil {
+			return err
+		}
+		return so.op.Delete(tx)
+	})
+}
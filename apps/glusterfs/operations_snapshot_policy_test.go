@@ -0,0 +1,242 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// setupSnapshotPolicyTest creates a cluster with two volumes and
+// returns the id of the cluster and the names of the volumes, in the
+// order of the volumes of the cluster.
+func setupSnapshotPolicyTest(t *testing.T, app *App) (string, []string) {
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	for i := 0; i < 2; i++ {
+		v := createSampleReplicaVolumeEntry(10, 3)
+		err = v.Create(app.db, app.executor)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	}
+
+	var clusterId string
+	names := []string{}
+	err = app.db.View(func(tx *bolt.Tx) error {
+		cl, err := ClusterList(tx)
+		if err != nil {
+			return err
+		}
+		c, err := NewClusterEntryFromId(tx, cl[0])
+		if err != nil {
+			return err
+		}
+		clusterId = c.Info.Id
+		for _, id := range c.Info.Volumes {
+			v, err := NewVolumeEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			names = append(names, v.Info.Name)
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(names) == 2, "expected 2 volumes, got:", names)
+	return clusterId, names
+}
+
+func TestClusterSnapshotPolicy(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	clusterId, names := setupSnapshotPolicyTest(t, app)
+
+	configs := []string{}
+	app.xo.MockSnapshotConfigSet = func(host string, option string, value string) error {
+		configs = append(configs, option+" "+value)
+		return nil
+	}
+	schedules := map[string]string{}
+	app.xo.MockSnapshotScheduleSet = func(host string, volume string, schedule string) error {
+		schedules[volume] = schedule
+		return nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+
+	// the gluster defaults before a policy is set
+	policy, err := c.ClusterSnapshotPolicy(clusterId)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, *policy == api.ClusterSnapshotPolicy{},
+		"expected empty policy, got:", policy)
+
+	policy, err = c.ClusterSetSnapshotPolicy(clusterId, &api.ClusterSnapshotPolicy{
+		MaxSnapshotsPerVolume: 10,
+		AutoDelete:            true,
+		CronSchedule:          "0 */4 * * *",
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, policy.MaxSnapshotsPerVolume == 10,
+		"expected 10 snapshots, got:", policy.MaxSnapshotsPerVolume)
+	tests.Assert(t, policy.AutoDelete, "expected auto delete")
+	tests.Assert(t, policy.CronSchedule == "0 */4 * * *",
+		"expected cron schedule, got:", policy.CronSchedule)
+
+	tests.Assert(t, len(configs) == 2, "expected 2 config options, got:", configs)
+	tests.Assert(t, configs[0] == "snap-max-hard-limit 10", configs[0])
+	tests.Assert(t, configs[1] == "auto-delete enable", configs[1])
+	tests.Assert(t, len(schedules) == 2, "expected 2 schedules, got:", schedules)
+	for _, name := range names {
+		tests.Assert(t, schedules[name] == "0 */4 * * *",
+			"expected schedule of", name, "got:", schedules)
+	}
+
+	// a policy without schedules and the gluster default limit
+	configs = []string{}
+	_, err = c.ClusterSetSnapshotPolicy(clusterId, &api.ClusterSnapshotPolicy{})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(configs) == 2, "expected 2 config options, got:", configs)
+	tests.Assert(t, configs[0] == "snap-max-hard-limit 256", configs[0])
+	tests.Assert(t, configs[1] == "auto-delete disable", configs[1])
+	for _, name := range names {
+		tests.Assert(t, schedules[name] == "",
+			"expected schedule of", name, "removed, got:", schedules)
+	}
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		l, err := PendingOperationList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(l) == 0, "expected no pending ops, got:", l)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestClusterSnapshotPolicyRollback(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	clusterId, names := setupSnapshotPolicyTest(t, app)
+
+	c := client.NewClientNoAuth(ts.URL)
+	prev := &api.ClusterSnapshotPolicy{
+		MaxSnapshotsPerVolume: 20,
+		CronSchedule:          "30 2 * * *",
+	}
+	_, err := c.ClusterSetSnapshotPolicy(clusterId, prev)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	configs := []string{}
+	app.xo.MockSnapshotConfigSet = func(host string, option string, value string) error {
+		configs = append(configs, option+" "+value)
+		return nil
+	}
+	// the schedule of the second volume can not be set
+	schedules := []string{}
+	app.xo.MockSnapshotScheduleSet = func(host string, volume string, schedule string) error {
+		if volume == names[1] && schedule != prev.CronSchedule {
+			return fmt.Errorf("scheduler not initialized")
+		}
+		schedules = append(schedules, volume+" "+schedule)
+		return nil
+	}
+
+	_, err = c.ClusterSetSnapshotPolicy(clusterId, &api.ClusterSnapshotPolicy{
+		MaxSnapshotsPerVolume: 50,
+		AutoDelete:            true,
+		CronSchedule:          "0 * * * *",
+	})
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	// the settings applied before the failure are restored in order
+	tests.Assert(t, len(configs) == 4, "expected 4 config options, got:", configs)
+	tests.Assert(t, configs[0] == "snap-max-hard-limit 50", configs[0])
+	tests.Assert(t, configs[1] == "auto-delete enable", configs[1])
+	tests.Assert(t, configs[2] == "snap-max-hard-limit 20", configs[2])
+	tests.Assert(t, configs[3] == "auto-delete disable", configs[3])
+	tests.Assert(t, len(schedules) == 2, "expected 2 schedules, got:", schedules)
+	tests.Assert(t, schedules[0] == names[0]+" 0 * * * *", schedules[0])
+	tests.Assert(t, schedules[1] == names[0]+" 30 2 * * *", schedules[1])
+
+	policy, err := c.ClusterSnapshotPolicy(clusterId)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, *policy == *prev, "expected", prev, "got:", policy)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		l, err := PendingOperationList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(l) == 0, "expected no pending ops, got:", l)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestClusterSnapshotPolicyInvalid(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	clusterId, _ := setupSnapshotPolicyTest(t, app)
+
+	c := client.NewClientNoAuth(ts.URL)
+	_, err := c.ClusterSetSnapshotPolicy(clusterId, &api.ClusterSnapshotPolicy{
+		CronSchedule: "every hour",
+	})
+	tests.Assert(t, err != nil, "expected err != nil")
+	_, err = c.ClusterSetSnapshotPolicy(clusterId, &api.ClusterSnapshotPolicy{
+		MaxSnapshotsPerVolume: 300,
+	})
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	_, err = c.ClusterSnapshotPolicy("0000000000000000000000000000000a")
+	assertErrorCode(t, err, api.ErrorClusterNotFound)
+}
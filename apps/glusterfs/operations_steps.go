@@ -0,0 +1,114 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+
+	wdb "github.com/heketi/heketi/pkg/db"
+)
+
+// OperationStep records the progress of one named step of an
+// operation's exec phase.
+type OperationStep struct {
+	Name     string
+	Executed bool
+	Retried  int
+}
+
+// StepRetryError is returned by an operation's Exec function when one
+// of its steps failed. The steps that completed are recorded in the
+// pending operation's checkpoint and the operation may be retried
+// without a rollback; only the steps that were not executed will be
+// run again.
+type StepRetryError struct {
+	Step          string
+	OriginalError error
+}
+
+func (sre StepRetryError) Error() string {
+	return fmt.Sprintf("Operation Step %v Should Be Retried; Error: %v",
+		sre.Step, sre.OriginalError.Error())
+}
+
+// opStep is a single named unit of work within an operation's
+// exec phase.
+type opStep struct {
+	name string
+	run  func() error
+}
+
+// checkpointMatches returns true if the checkpoint of the pending
+// operation was recorded for the given list of steps.
+func checkpointMatches(p *PendingOperationEntry, steps []opStep) bool {
+	if len(p.Checkpoint) != len(steps) {
+		return false
+	}
+	for i, s := range steps {
+		if p.Checkpoint[i].Name != s.name {
+			return false
+		}
+	}
+	return true
+}
+
+// runSteps executes the steps in order, skipping any step the pending
+// operation's checkpoint records as already executed. The checkpoint
+// is saved to the db after every step, together with the progress of
+// the operation. If a step fails the remaining
+// steps are not run and a StepRetryError is returned.
+func runSteps(db wdb.DB, p *PendingOperationEntry, steps []opStep) error {
+	resumed := checkpointMatches(p, steps)
+	if !resumed {
+		p.Checkpoint = make([]OperationStep, len(steps))
+		for i, s := range steps {
+			p.Checkpoint[i].Name = s.name
+		}
+	}
+	// checkpoints of concurrent operations are committed together
+	save := func() error {
+		return wdb.BatchWrite(db, func(tx *bolt.Tx) error {
+			return p.Save(tx)
+		})
+	}
+
+	first := true
+	for i, s := range steps {
+		cp := &p.Checkpoint[i]
+		if cp.Executed {
+			logger.Debug("Skipping executed step %v of operation %v",
+				cp.Name, p.Id)
+			continue
+		}
+		if resumed && first {
+			// the first unexecuted step of a resumed operation is
+			// the one that failed previously
+			cp.Retried++
+		}
+		first = false
+
+		logger.Debug("Running step %v of operation %v", cp.Name, p.Id)
+		if err := s.run(); err != nil {
+			if serr := save(); serr != nil {
+				logger.LogError("Failed to save checkpoint for %v: %v",
+					p.Id, serr)
+			}
+			return StepRetryError{Step: cp.Name, OriginalError: err}
+		}
+		cp.Executed = true
+		p.Progress = (i + 1) * 100 / len(steps)
+		if err := save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
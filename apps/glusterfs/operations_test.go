@@ -1,6 +1,7 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -45,21 +46,21 @@ func (o *testOperation) MaxRetries() int {
 	return o.retryMax
 }
 
-func (o *testOperation) Build() error {
+func (o *testOperation) Build(ctx context.Context) error {
 	if o.build == nil {
 		return nil
 	}
 	return o.build()
 }
 
-func (o *testOperation) Exec(executor executors.Executor) error {
+func (o *testOperation) Exec(ctx context.Context, executor executors.Executor) error {
 	if o.exec == nil {
 		return nil
 	}
 	return o.exec()
 }
 
-func (o *testOperation) Rollback(executor executors.Executor) error {
+func (o *testOperation) Rollback(ctx context.Context, executor executors.Executor) error {
 	if o.rollback == nil {
 		return nil
 	}
@@ -569,12 +570,12 @@ func TestAppServerResetStaleOps(t *testing.T) {
 
 	vol1 := NewVolumeEntryFromRequest(req)
 	vc1 := NewVolumeCreateOperation(vol1, app.db)
-	err = vc1.Build()
+	err = vc1.Build(context.Background())
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	vol2 := NewVolumeEntryFromRequest(req)
 	vc2 := NewVolumeCreateOperation(vol2, app.db)
-	err = vc2.Build()
+	err = vc2.Build(context.Background())
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 
 	app.db.View(func(tx *bolt.Tx) error {
@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/heketi/heketi/executors"
@@ -72,6 +73,14 @@ func (vc *VolumeCreateOperation) Label() string {
 	return "Create Volume"
 }
 
+func (vc *VolumeCreateOperation) LockKeys() ([]string, error) {
+	return []string{volumeLockKey(vc.vol.Info.Id)}, nil
+}
+
+func (vc *VolumeCreateOperation) AllocationLockKeys() ([]string, error) {
+	return clusterLockKeys(vc.db, vc.vol.Info.Clusters)
+}
+
 func (vc *VolumeCreateOperation) ResourceUrl() string {
 	return fmt.Sprintf("/volumes/%v", vc.vol.Info.Id)
 }
@@ -82,7 +91,10 @@ func (vc *VolumeCreateOperation) MaxRetries() int {
 
 // Build allocates and saves new volume and brick entries (tagged as pending)
 // in the db.
-func (vc *VolumeCreateOperation) Build() error {
+func (vc *VolumeCreateOperation) Build(ctx context.Context) error {
+	if err := checkVolumeSize(vc.vol.Info.Size); err != nil {
+		return err
+	}
 	return vc.db.Update(func(tx *bolt.Tx) error {
 		txdb := wdb.WrapTx(tx)
 		brick_entries, err := vc.vol.createVolumeComponents(txdb)
@@ -107,7 +119,7 @@ func (vc *VolumeCreateOperation) Build() error {
 }
 
 // Exec creates new bricks and volume on the underlying glusterfs storage system.
-func (vc *VolumeCreateOperation) Exec(executor executors.Executor) error {
+func (vc *VolumeCreateOperation) Exec(ctx context.Context, executor executors.Executor) error {
 	brick_entries, err := bricksFromOp(vc.db, vc.op, vc.vol.Info.Gid)
 	if err != nil {
 		logger.LogError("Failed to get bricks from op: %v", err)
@@ -148,7 +160,7 @@ func (vc *VolumeCreateOperation) Finalize() error {
 // Rollback removes any dangling volume and bricks from the underlying storage
 // systems and removes the corresponding pending volume and brick entries from
 // the db.
-func (vc *VolumeCreateOperation) Rollback(executor executors.Executor) error {
+func (vc *VolumeCreateOperation) Rollback(ctx context.Context, executor executors.Executor) error {
 	return rollbackViaClean(vc, executor)
 }
 
@@ -171,12 +183,24 @@ func (vc *VolumeCreateOperation) CleanDone() error {
 	return err
 }
 
+// CancelBuild removes the pending volume and brick entries created
+// by Build from the db without touching the storage systems.
+func (vc *VolumeCreateOperation) CancelBuild() error {
+	var err error
+	logger.Info("Canceling %v op:%v", vc.Label(), vc.op.Id)
+	vc.reclaimed, err = reclaimAllFromOp(vc.db, vc.op)
+	if err != nil {
+		return err
+	}
+	return vc.CleanDone()
+}
+
 // VolumeExpandOperation implements the operation functions used to
 // expand an existing volume.
 type VolumeExpandOperation struct {
 	OperationManager
-	noRetriesOperation
-	vol *VolumeEntry
+	maxRetries int
+	vol        *VolumeEntry
 
 	// modification values
 	ExpandSize int
@@ -194,6 +218,7 @@ func NewVolumeExpandOperation(
 			db: db,
 			op: NewPendingOperationEntry(NEW_ID),
 		},
+		maxRetries: VOLUME_MAX_RETRIES,
 		vol:        vol,
 		ExpandSize: sizeGB,
 	}
@@ -219,7 +244,8 @@ func loadVolumeExpandOperation(
 			db: db,
 			op: p,
 		},
-		vol: vols[0],
+		maxRetries: VOLUME_MAX_RETRIES,
+		vol:        vols[0],
 	}, nil
 }
 
@@ -227,16 +253,44 @@ func (ve *VolumeExpandOperation) Label() string {
 	return "Expand Volume"
 }
 
+func (ve *VolumeExpandOperation) LockKeys() ([]string, error) {
+	return []string{volumeLockKey(ve.vol.Info.Id)}, nil
+}
+
+func (ve *VolumeExpandOperation) AllocationLockKeys() ([]string, error) {
+	return []string{clusterLockKey(ve.vol.Info.Cluster)}, nil
+}
+
 func (ve *VolumeExpandOperation) ResourceUrl() string {
 	return fmt.Sprintf("/volumes/%v", ve.vol.Info.Id)
 }
 
+func (ve *VolumeExpandOperation) MaxRetries() int {
+	return ve.maxRetries
+}
+
 // Build determines what new bricks needs to be created to satisfy the
 // new volume size. It marks new bricks as pending in the db.
-func (ve *VolumeExpandOperation) Build() error {
-	return ve.db.Update(func(tx *bolt.Tx) error {
+func (ve *VolumeExpandOperation) Build(ctx context.Context) error {
+	var v *VolumeEntry
+	err := ve.db.Update(func(tx *bolt.Tx) error {
 		txdb := wdb.WrapTx(tx)
-		brick_entries, err := ve.vol.expandVolumeComponents(
+		// the volume entry may have been loaded before another
+		// expansion started, check and expand the entry in the db
+		var err error
+		v, err = NewVolumeEntryFromId(tx, ve.vol.Info.Id)
+		if err != nil {
+			return err
+		}
+		if v.Expanding.Id != "" {
+			logger.LogError("Volume %v is already being expanded by op %v",
+				v.Info.Id, v.Expanding.Id)
+			return ErrVolumeExpanding
+		}
+		if err := checkVolumeSize(v.Info.Size + ve.ExpandSize); err != nil {
+			return err
+		}
+		brick_entries, err := v.expandVolumeComponents(
 			txdb, ve.ExpandSize, false)
 		if err != nil {
 			return err
@@ -247,22 +301,56 @@ func (ve *VolumeExpandOperation) Build() error {
 				return e
 			}
 		}
-		ve.op.RecordExpandVolume(ve.vol, ve.ExpandSize)
+		ve.op.RecordExpandVolume(v, ve.ExpandSize)
+		if e := v.Save(tx); e != nil {
+			return e
+		}
 		if e := ve.op.Save(tx); e != nil {
 			return e
 		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	// the caller's entry sees the new bricks and size
+	*ve.vol = *v
+	return nil
 }
 
 // Exec creates new bricks on the underlying storage systems.
-func (ve *VolumeExpandOperation) Exec(executor executors.Executor) error {
+func (ve *VolumeExpandOperation) Exec(ctx context.Context, executor executors.Executor) error {
 	brick_entries, err := bricksFromOp(ve.db, ve.op, ve.vol.Info.Gid)
 	if err != nil {
 		logger.LogError("Failed to get bricks from op: %v", err)
 		return err
 	}
-	err = ve.vol.expandVolumeExec(ve.db, executor, brick_entries)
+
+	// each brick is created in its own step followed by the volume
+	// expansion such that a retry only repeats the incomplete steps
+	steps := []opStep{}
+	for _, brick := range brick_entries {
+		b := brick
+		steps = append(steps, opStep{
+			name: "create-brick-" + b.Info.Id,
+			run: func() error {
+				return CreateBricks(ve.db, executor, []*BrickEntry{b})
+			},
+		})
+	}
+	steps = append(steps, opStep{
+		name: "expand-volume",
+		run: func() error {
+			vr, host, err := ve.vol.createVolumeRequest(ve.db, brick_entries)
+			if err != nil {
+				return err
+			}
+			_, err = executor.VolumeExpand(host, vr)
+			return err
+		},
+	})
+
+	err = runSteps(ve.db, ve.op, steps)
 	if err != nil {
 		logger.LogError("Error executing expand volume: %v", err)
 	}
@@ -271,7 +359,7 @@ func (ve *VolumeExpandOperation) Exec(executor executors.Executor) error {
 
 // Rollback cancels the volume expansion and remove pending brick entries
 // from the db.
-func (ve *VolumeExpandOperation) Rollback(executor executors.Executor) error {
+func (ve *VolumeExpandOperation) Rollback(ctx context.Context, executor executors.Executor) error {
 	return rollbackViaClean(ve, executor)
 }
 
@@ -303,6 +391,7 @@ func (ve *VolumeExpandOperation) Finalize() error {
 			}
 		}
 		ve.op.FinalizeVolume(ve.vol)
+		ve.op.FinalizeExpandVolume(ve.vol)
 		if e := ve.vol.Save(tx); e != nil {
 			return e
 		}
@@ -361,6 +450,7 @@ func (ve *VolumeExpandOperation) CleanDone() error {
 				return err
 			}
 		}
+		ve.op.FinalizeExpandVolume(v)
 		if err := v.Save(tx); err != nil {
 			return err
 		}
@@ -368,6 +458,18 @@ func (ve *VolumeExpandOperation) CleanDone() error {
 	})
 }
 
+// CancelBuild removes the pending brick entries created by Build
+// from the db without touching the storage systems.
+func (ve *VolumeExpandOperation) CancelBuild() error {
+	var err error
+	logger.Info("Canceling %v op:%v", ve.Label(), ve.op.Id)
+	ve.reclaimed, err = reclaimAllFromOp(ve.db, ve.op)
+	if err != nil {
+		return err
+	}
+	return ve.CleanDone()
+}
+
 // VolumeDeleteOperation implements the operation functions used to
 // delete an existing volume.
 type VolumeDeleteOperation struct {
@@ -417,13 +519,17 @@ func (vdel *VolumeDeleteOperation) Label() string {
 	return "Delete Volume"
 }
 
+func (vdel *VolumeDeleteOperation) LockKeys() ([]string, error) {
+	return []string{volumeLockKey(vdel.vol.Info.Id)}, nil
+}
+
 func (vdel *VolumeDeleteOperation) ResourceUrl() string {
 	return ""
 }
 
 // Build determines what volumes and bricks need to be deleted and
 // marks the db entries as such.
-func (vdel *VolumeDeleteOperation) Build() error {
+func (vdel *VolumeDeleteOperation) Build(ctx context.Context) error {
 	return vdel.db.Update(func(tx *bolt.Tx) error {
 		v, err := NewVolumeEntryFromId(tx, vdel.vol.Info.Id)
 		if err != nil {
@@ -463,7 +569,7 @@ func (vdel *VolumeDeleteOperation) Build() error {
 }
 
 // Exec performs the volume and brick deletions on the storage systems.
-func (vdel *VolumeDeleteOperation) Exec(executor executors.Executor) error {
+func (vdel *VolumeDeleteOperation) Exec(ctx context.Context, executor executors.Executor) error {
 	var err error
 	vdel.reclaimed, err = removeVolumeWithOp(
 		vdel.db, executor, vdel.op, vdel.vol.Info.Id)
@@ -473,7 +579,7 @@ func (vdel *VolumeDeleteOperation) Exec(executor executors.Executor) error {
 	return err
 }
 
-func (vdel *VolumeDeleteOperation) Rollback(executor executors.Executor) error {
+func (vdel *VolumeDeleteOperation) Rollback(ctx context.Context, executor executors.Executor) error {
 	// currently rollback only removes the pending operation for delete volume,
 	// leaving the db in the same state as it was before an exec failure.
 	// In the future we should make this operation resume-able
@@ -516,7 +622,7 @@ func (vdel *VolumeDeleteOperation) Clean(executor executors.Executor) error {
 	// for a delete, clean is essentially a replay of exec
 	// because exec must be robust against restarts now we can just call Exec
 	logger.Info("Starting Clean for %v op:%v", vdel.Label(), vdel.op.Id)
-	return vdel.Exec(executor)
+	return vdel.Exec(context.Background(), executor)
 }
 
 func (vdel *VolumeDeleteOperation) CleanDone() error {
@@ -561,11 +667,19 @@ func (vc *VolumeCloneOperation) Label() string {
 	return "Create Clone of a Volume"
 }
 
+func (vc *VolumeCloneOperation) LockKeys() ([]string, error) {
+	return []string{volumeLockKey(vc.vol.Info.Id)}, nil
+}
+
+func (vc *VolumeCloneOperation) AllocationLockKeys() ([]string, error) {
+	return []string{clusterLockKey(vc.vol.Info.Cluster)}, nil
+}
+
 func (vc *VolumeCloneOperation) ResourceUrl() string {
 	return fmt.Sprintf("/volumes/%v", vc.clone.Info.Id)
 }
 
-func (vc *VolumeCloneOperation) Build() error {
+func (vc *VolumeCloneOperation) Build(ctx context.Context) error {
 	return vc.db.Update(func(tx *bolt.Tx) error {
 		vc.op.RecordCloneVolume(vc.vol)
 		clone, bricks, devices, err := vc.vol.prepareVolumeClone(tx, vc.clonename)
@@ -610,7 +724,7 @@ func (vc *VolumeCloneOperation) Build() error {
 	})
 }
 
-func (vc *VolumeCloneOperation) Exec(executor executors.Executor) error {
+func (vc *VolumeCloneOperation) Exec(ctx context.Context, executor executors.Executor) error {
 	vcr, host, err := vc.vol.cloneVolumeRequest(vc.db, vc.clone.Info.Name)
 	if err != nil {
 		return err
@@ -633,7 +747,7 @@ func (vc *VolumeCloneOperation) Exec(executor executors.Executor) error {
 	return nil
 }
 
-func (vc *VolumeCloneOperation) Rollback(executor executors.Executor) error {
+func (vc *VolumeCloneOperation) Rollback(ctx context.Context, executor executors.Executor) error {
 	return vc.db.Update(func(tx *bolt.Tx) error {
 		vc.op.FinalizeVolumeClone(vc.vol)
 		if e := vc.vol.Save(tx); e != nil {
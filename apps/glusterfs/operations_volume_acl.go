@@ -0,0 +1,212 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+const (
+	// gluster uses the id of nfsnobody for squashed and anonymous access
+	glusterDefaultAnonId = 65534
+)
+
+// aclVolumeOptions returns the gluster volume options, as "<key> <value>"
+// strings, implementing the given acl config. A nil config results in
+// the gluster defaults.
+func aclVolumeOptions(acl *api.VolumeACLConfig) []string {
+	if acl == nil {
+		acl = &api.VolumeACLConfig{}
+	}
+	rootSquash := "off"
+	if acl.RootSquash {
+		rootSquash = "on"
+	}
+	anonUid := acl.AnonUID
+	if anonUid == 0 {
+		anonUid = glusterDefaultAnonId
+	}
+	anonGid := acl.AnonGID
+	if anonGid == 0 {
+		anonGid = glusterDefaultAnonId
+	}
+	allow := "*"
+	if len(acl.AllowedHosts) > 0 {
+		allow = strings.Join(acl.AllowedHosts, ",")
+	}
+	return []string{
+		fmt.Sprintf("server.root-squash %v", rootSquash),
+		fmt.Sprintf("server.anonuid %v", anonUid),
+		fmt.Sprintf("server.anongid %v", anonGid),
+		fmt.Sprintf("auth.allow %v", allow),
+	}
+}
+
+// replaceVolumeOptions returns the volume options with any option set
+// by the new options replaced by the new value.
+func replaceVolumeOptions(options, newOptions []string) []string {
+	keys := map[string]bool{}
+	for _, o := range newOptions {
+		keys[strings.Fields(o)[0]] = true
+	}
+	result := []string{}
+	for _, o := range options {
+		f := strings.Fields(o)
+		if len(f) > 0 && keys[f[0]] {
+			continue
+		}
+		result = append(result, o)
+	}
+	return append(result, newOptions...)
+}
+
+// currentAclOptions returns the acl related options of the volume as
+// recorded in the db, in the same order as aclVolumeOptions. Options
+// given at volume create take precedence over the defaults.
+func currentAclOptions(v *VolumeEntry) []string {
+	stored := volumeOptionsMap(v.GlusterVolumeOptions)
+	options := aclVolumeOptions(v.Info.ACLConfig)
+	for i, o := range options {
+		key := strings.Fields(o)[0]
+		if value, ok := stored[key]; ok {
+			options[i] = key + " " + value
+		}
+	}
+	return options
+}
+
+// VolumeAclConfigOperation implements the operation functions used to
+// change the access control settings of an existing volume.
+type VolumeAclConfigOperation struct {
+	OperationManager
+	noRetriesOperation
+	vol *VolumeEntry
+	acl api.VolumeACLConfig
+
+	// the number of options set by Exec, in order
+	applied int
+}
+
+// NewVolumeAclConfigOperation returns a new VolumeAclConfigOperation
+// populated with the given params.
+func NewVolumeAclConfigOperation(
+	vol *VolumeEntry, db wdb.DB,
+	acl api.VolumeACLConfig) *VolumeAclConfigOperation {
+
+	return &VolumeAclConfigOperation{
+		OperationManager: OperationManager{
+			db: db,
+			op: NewPendingOperationEntry(NEW_ID),
+		},
+		vol: vol,
+		acl: acl,
+	}
+}
+
+func (ao *VolumeAclConfigOperation) Label() string {
+	return "Configure Volume ACL"
+}
+
+func (ao *VolumeAclConfigOperation) LockKeys() ([]string, error) {
+	return []string{volumeLockKey(ao.vol.Info.Id)}, nil
+}
+
+func (ao *VolumeAclConfigOperation) ResourceUrl() string {
+	return fmt.Sprintf("/volumes/%v", ao.vol.Info.Id)
+}
+
+// Build records the pending change of the volume in the db.
+func (ao *VolumeAclConfigOperation) Build(ctx context.Context) error {
+	return ao.db.Update(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, ao.vol.Info.Id)
+		if err != nil {
+			return err
+		}
+		ao.vol = v
+		ao.applied = 0
+		ao.op.RecordVolumeAclConfig(v)
+		return ao.op.Save(tx)
+	})
+}
+
+// Exec sets the volume options one at a time so that a failure part
+// way through leaves a known set of options to be rolled back.
+func (ao *VolumeAclConfigOperation) Exec(ctx context.Context, executor executors.Executor) error {
+	host, err := GetVerifiedTspManageHostname(ao.db, executor, ao.vol.Info.Cluster, ao.vol.Info.TspId)
+	if err != nil {
+		return err
+	}
+	for _, o := range aclVolumeOptions(&ao.acl) {
+		err := executor.VolumeModify(host, &executors.VolumeModifyRequest{
+			Name:                 ao.vol.Info.Name,
+			GlusterVolumeOptions: []string{o},
+		})
+		if err != nil {
+			logger.LogError("Failed to set volume option [%v] on %v: %v",
+				o, ao.vol.Info.Name, err)
+			return err
+		}
+		ao.applied++
+	}
+	return nil
+}
+
+// Rollback restores the previous values of the options set by Exec
+// and removes the pending operation.
+func (ao *VolumeAclConfigOperation) Rollback(ctx context.Context, executor executors.Executor) error {
+	if ao.applied > 0 {
+		host, err := GetVerifiedTspManageHostname(ao.db, executor, ao.vol.Info.Cluster, ao.vol.Info.TspId)
+		if err != nil {
+			return err
+		}
+		prev := currentAclOptions(ao.vol)[:ao.applied]
+		err = executor.VolumeModify(host, &executors.VolumeModifyRequest{
+			Name:                 ao.vol.Info.Name,
+			GlusterVolumeOptions: prev,
+		})
+		if err != nil {
+			logger.LogError("Failed to restore volume options on %v: %v",
+				ao.vol.Info.Name, err)
+			return err
+		}
+		ao.applied = 0
+	}
+	return ao.db.Update(func(tx *bolt.Tx) error {
+		return ao.op.Delete(tx)
+	})
+}
+
+// Finalize records the new acl config and the volume options
+// implementing it in the volume entry.
+func (ao *VolumeAclConfigOperation) Finalize() error {
+	return ao.db.Update(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, ao.vol.Info.Id)
+		if err != nil {
+			return err
+		}
+		acl := ao.acl
+		v.Info.ACLConfig = &acl
+		v.GlusterVolumeOptions = replaceVolumeOptions(
+			v.GlusterVolumeOptions, aclVolumeOptions(&acl))
+		if err := v.Save(tx); err != nil {
+			return err
+		}
+		ao.vol = v
+		return ao.op.Delete(tx)
+	})
+}
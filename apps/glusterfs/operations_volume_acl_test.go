@@ -0,0 +1,182 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// recordVolumeSets makes the mock executor record the "gluster volume
+// set" commands it is asked to run. The command setting an option
+// starting with failOption fails.
+func recordVolumeSets(app *App, failOption string) *[]string {
+	cmds := []string{}
+	app.xo.MockVolumeModify = func(host string, mod *executors.VolumeModifyRequest) error {
+		for _, o := range mod.GlusterVolumeOptions {
+			if failOption != "" && strings.HasPrefix(o, failOption) {
+				return fmt.Errorf("volume set: failed: %v", o)
+			}
+			cmds = append(cmds,
+				fmt.Sprintf("gluster volume set %v %v", mod.Name, o))
+		}
+		return nil
+	}
+	return &cmds
+}
+
+func TestVolumeAclConfig(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	name := v.Info.Name
+
+	cmds := recordVolumeSets(app, "")
+	c := client.NewClientNoAuth(ts.URL)
+	info, err := c.VolumeSetACLConfig(v.Info.Id, &api.VolumeACLConfig{
+		RootSquash:   true,
+		AnonUID:      1000,
+		AnonGID:      2000,
+		AllowedHosts: []string{"10.0.0.1", "192.168.*.*"},
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	expected := []string{
+		"gluster volume set " + name + " server.root-squash on",
+		"gluster volume set " + name + " server.anonuid 1000",
+		"gluster volume set " + name + " server.anongid 2000",
+		"gluster volume set " + name + " auth.allow 10.0.0.1,192.168.*.*",
+	}
+	tests.Assert(t, strings.Join(*cmds, "\n") == strings.Join(expected, "\n"),
+		"expected commands", expected, "got:", *cmds)
+	tests.Assert(t, info.ACLConfig != nil, "expected info.ACLConfig != nil")
+	tests.Assert(t, info.ACLConfig.AnonUID == 1000,
+		"expected info.ACLConfig.AnonUID == 1000, got:", info.ACLConfig.AnonUID)
+
+	app.db.View(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		options := volumeOptionsMap(v.GlusterVolumeOptions)
+		tests.Assert(t, options["auth.allow"] == "10.0.0.1,192.168.*.*",
+			"expected auth.allow to be recorded, got:", v.GlusterVolumeOptions)
+		l, err := PendingOperationList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(l) == 0, "expected len(l) == 0, got:", len(l))
+		return nil
+	})
+
+	// unset fields fall back to the gluster defaults
+	cmds = recordVolumeSets(app, "")
+	_, err = c.VolumeSetACLConfig(v.Info.Id, &api.VolumeACLConfig{})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	expected = []string{
+		"gluster volume set " + name + " server.root-squash off",
+		"gluster volume set " + name + " server.anonuid 65534",
+		"gluster volume set " + name + " server.anongid 65534",
+		"gluster volume set " + name + " auth.allow *",
+	}
+	tests.Assert(t, strings.Join(*cmds, "\n") == strings.Join(expected, "\n"),
+		"expected commands", expected, "got:", *cmds)
+
+	// invalid hosts are rejected
+	_, err = c.VolumeSetACLConfig(v.Info.Id, &api.VolumeACLConfig{
+		AllowedHosts: []string{"10.0.0.1;rm -rf /"},
+	})
+	tests.Assert(t, err != nil, "expected err != nil")
+}
+
+func TestVolumeAclConfigRollback(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+	req.GlusterVolumeOptions = []string{"server.anonuid 500"}
+	v := NewVolumeEntryFromRequest(req)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	name := v.Info.Name
+	options := strings.Join(v.GlusterVolumeOptions, ",")
+
+	// the third setting of the batch fails
+	cmds := recordVolumeSets(app, "server.anongid 2000")
+	op := NewVolumeAclConfigOperation(v, app.db, api.VolumeACLConfig{
+		RootSquash: true,
+		AnonUID:    1000,
+		AnonGID:    2000,
+	})
+	err = RunOperation(op, app.executor)
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	// the applied settings are restored to their previous values
+	expected := []string{
+		"gluster volume set " + name + " server.root-squash on",
+		"gluster volume set " + name + " server.anonuid 1000",
+		"gluster volume set " + name + " server.root-squash off",
+		"gluster volume set " + name + " server.anonuid 500",
+	}
+	tests.Assert(t, strings.Join(*cmds, "\n") == strings.Join(expected, "\n"),
+		"expected commands", expected, "got:", *cmds)
+
+	app.db.View(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, v.Info.ACLConfig == nil,
+			"expected v.Info.ACLConfig == nil, got:", v.Info.ACLConfig)
+		tests.Assert(t, strings.Join(v.GlusterVolumeOptions, ",") == options,
+			"expected options to be unchanged, got:", v.GlusterVolumeOptions)
+		l, err := PendingOperationList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(l) == 0, "expected len(l) == 0, got:", len(l))
+		return nil
+	})
+}
@@ -0,0 +1,244 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/paths"
+)
+
+// ImportLayoutError is returned when the bricks of a volume import do
+// not match the topology known to heketi.
+type ImportLayoutError struct {
+	Reason string
+}
+
+func (e *ImportLayoutError) Error() string {
+	return "Invalid brick layout: " + e.Reason
+}
+
+func importLayoutErrorf(f string, v ...interface{}) error {
+	return &ImportLayoutError{fmt.Sprintf(f, v...)}
+}
+
+// VolumeImportOperation creates a volume out of bricks that were
+// created outside of heketi. No lvs are created: the bricks are checked
+// on their nodes, the gluster volume is created and the volume and
+// bricks are registered in the db. Rolling back the operation only
+// removes the db entries, the bricks are never destroyed.
+//
+// The operation is not loadable. A stale import operation is left for
+// the administrator to clean up.
+type VolumeImportOperation struct {
+	OperationManager
+	noRetriesOperation
+	req *api.VolumeImportLayoutRequest
+	vol *VolumeEntry
+
+	// set by Build, in the order of the request
+	bricks []*BrickEntry
+}
+
+// NewVolumeImportOperation returns a new VolumeImportOperation for the
+// given layout.
+func NewVolumeImportOperation(
+	req *api.VolumeImportLayoutRequest, db wdb.DB) *VolumeImportOperation {
+
+	return &VolumeImportOperation{
+		OperationManager: OperationManager{
+			db: db,
+			op: NewPendingOperationEntry(NEW_ID),
+		},
+		req: req,
+	}
+}
+
+func (vio *VolumeImportOperation) Label() string {
+	return "Import Volume"
+}
+
+func (vio *VolumeImportOperation) ResourceUrl() string {
+	return fmt.Sprintf("/volumes/%v", vio.vol.Info.Id)
+}
+
+// newImportVolumeEntry returns the volume entry for the layout. Each
+// replica set is as large as its smallest brick.
+func newImportVolumeEntry(req *api.VolumeImportLayoutRequest) *VolumeEntry {
+	replica := req.ReplicaCount()
+	size := 0
+	for i := 0; i < len(req.Bricks); i += replica {
+		setSize := req.Bricks[i].SizeGb
+		for _, b := range req.Bricks[i : i+replica] {
+			if b.SizeGb < setSize {
+				setSize = b.SizeGb
+			}
+		}
+		size += setSize
+	}
+
+	vreq := &api.VolumeCreateRequest{}
+	vreq.Name = req.Name
+	vreq.Size = size
+	vreq.Clusters = []string{req.ClusterId}
+	if replica > 1 {
+		vreq.Durability.Type = api.DurabilityReplicate
+		vreq.Durability.Replicate.Replica = replica
+	} else {
+		vreq.Durability.Type = api.DurabilityDistributeOnly
+	}
+	v := NewVolumeEntryFromRequest(vreq)
+	v.Info.Cluster = req.ClusterId
+	return v
+}
+
+// Build checks the layout against the db and records the new volume
+// and its bricks. The space of the bricks is taken from their devices.
+func (vio *VolumeImportOperation) Build(ctx context.Context) error {
+	return vio.db.Update(func(tx *bolt.Tx) error {
+		txdb := wdb.WrapTx(tx)
+		cluster, err := NewClusterEntryFromId(tx, vio.req.ClusterId)
+		if err != nil {
+			return err
+		}
+		vio.vol = newImportVolumeEntry(vio.req)
+		v := vio.vol
+
+		replica := vio.req.ReplicaCount()
+		setNodes := map[string]bool{}
+		seen := map[string]bool{}
+		for i, ib := range vio.req.Bricks {
+			if i%replica == 0 {
+				setNodes = map[string]bool{}
+			}
+			n, err := NewNodeEntryFromId(tx, ib.NodeId)
+			if err != nil {
+				return err
+			}
+			if n.Info.ClusterId != cluster.Info.Id {
+				return importLayoutErrorf("node %v is not in cluster %v",
+					ib.NodeId, cluster.Info.Id)
+			}
+			d, err := NewDeviceEntryFromId(tx, ib.DeviceId)
+			if err != nil {
+				return err
+			}
+			if d.NodeId != n.Info.Id {
+				return importLayoutErrorf("device %v is not on node %v",
+					ib.DeviceId, ib.NodeId)
+			}
+			if setNodes[n.Info.Id] {
+				return importLayoutErrorf("replica set %v has more than one brick on node %v",
+					i/replica, ib.NodeId)
+			}
+			setNodes[n.Info.Id] = true
+			key := n.Info.Id + ":" + ib.Path
+			if seen[key] {
+				return importLayoutErrorf("brick %v on node %v is listed twice",
+					ib.Path, ib.NodeId)
+			}
+			seen[key] = true
+
+			size := uint64(ib.SizeGb) * GB
+			sn := d.SpaceNeeded(size, 1)
+			if !d.StorageCheck(sn.Total) {
+				logger.LogError("Device %v has no space for imported brick %v",
+					d.Info.Id, ib.Path)
+				return ErrNoSpace
+			}
+			b := NewBrickEntry(size, sn.TpSize, sn.PoolMetadataSize,
+				d.Info.Id, n.Info.Id, v.Info.Gid, v.Info.Id)
+			b.Info.Path = ib.Path
+			b.SubType = NormalSubType
+			d.StorageAllocate(sn.Total)
+			d.BrickAdd(b.Info.Id)
+			if err := d.Save(tx); err != nil {
+				return err
+			}
+			v.BrickAdd(b.Info.Id)
+			vio.op.RecordAddBrick(b)
+			if err := b.Save(tx); err != nil {
+				return err
+			}
+			vio.bricks = append(vio.bricks, b)
+		}
+
+		if err := v.updateMountInfo(txdb, &v.Info); err != nil {
+			return err
+		}
+		vio.op.RecordImportVolume(v)
+		if err := v.Save(tx); err != nil {
+			return err
+		}
+		cluster.VolumeAdd(v.Info.Id)
+		if err := cluster.Save(tx); err != nil {
+			return err
+		}
+		return vio.op.Save(tx)
+	})
+}
+
+// Exec checks that every brick is mounted on a thin lv of the vg of
+// its device and creates the gluster volume.
+func (vio *VolumeImportOperation) Exec(ctx context.Context, executor executors.Executor) error {
+	for _, b := range vio.bricks {
+		host, err := b.host(vio.db)
+		if err != nil {
+			return err
+		}
+		info, err := executor.BrickLayoutCheck(host, b.Info.Path)
+		if err != nil {
+			return err
+		}
+		if vg := paths.VgIdToName(b.Info.DeviceId); info.VgName != vg {
+			return importLayoutErrorf("brick %v is in vg %v, not in vg %v of device %v",
+				b.Info.Path, info.VgName, vg, b.Info.DeviceId)
+		}
+		b.LvmLv = info.LvName
+		b.LvmThinPool = info.TpName
+	}
+	return vio.vol.createVolume(vio.db, executor, vio.bricks)
+}
+
+// Rollback removes the volume and bricks from the db and returns the
+// space of the bricks to their devices. The bricks and the gluster
+// volume, if any, are left in place.
+func (vio *VolumeImportOperation) Rollback(ctx context.Context, executor executors.Executor) error {
+	reclaimed, err := reclaimAllFromOp(vio.db, vio.op)
+	if err != nil {
+		return err
+	}
+	_, err = expungeVolumeWithOp(vio.db, vio.op, vio.vol.Info.Id, reclaimed)
+	return err
+}
+
+// Finalize records the lvs found by Exec and marks the volume and
+// bricks as no longer pending.
+func (vio *VolumeImportOperation) Finalize() error {
+	return vio.db.Update(func(tx *bolt.Tx) error {
+		for _, b := range vio.bricks {
+			vio.op.FinalizeBrick(b)
+			if err := b.Save(tx); err != nil {
+				return err
+			}
+		}
+		vio.op.FinalizeVolume(vio.vol)
+		if err := vio.vol.Save(tx); err != nil {
+			return err
+		}
+		return vio.op.Delete(tx)
+	})
+}
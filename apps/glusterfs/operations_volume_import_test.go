@@ -0,0 +1,297 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/paths"
+)
+
+// sampleImportLayout returns a request importing one brick of the
+// given size from each node of the first cluster in the db.
+func sampleImportLayout(app *App, sizeGb int) (*api.VolumeImportLayoutRequest, error) {
+	req := &api.VolumeImportLayoutRequest{Name: "imported"}
+	err := app.db.View(func(tx *bolt.Tx) error {
+		cl, err := ClusterList(tx)
+		if err != nil {
+			return err
+		}
+		c, err := NewClusterEntryFromId(tx, cl[0])
+		if err != nil {
+			return err
+		}
+		req.ClusterId = c.Info.Id
+		for i, nodeId := range c.Info.Nodes {
+			n, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			req.Bricks = append(req.Bricks, api.VolumeImportBrick{
+				NodeId:   nodeId,
+				DeviceId: n.Devices[0],
+				Path:     fmt.Sprintf("/bricks/b%v/brick", i),
+				SizeGb:   sizeGb,
+			})
+		}
+		return nil
+	})
+	return req, err
+}
+
+func TestVolumeImportLayout(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	req, err := sampleImportLayout(app, 20)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	deviceOf := map[string]string{}
+	for _, b := range req.Bricks {
+		deviceOf[b.Path] = b.DeviceId
+	}
+
+	checked := map[string]bool{}
+	app.xo.MockBrickLayoutCheck = func(host string, path string) (*executors.BrickLvInfo, error) {
+		checked[path] = true
+		return &executors.BrickLvInfo{
+			VgName: paths.VgIdToName(deviceOf[path]),
+			LvName: "lv_" + path[len("/bricks/"):len("/bricks/b0")],
+			TpName: "tp_" + path[len("/bricks/"):len("/bricks/b0")],
+		}, nil
+	}
+	var vreq *executors.VolumeRequest
+	app.xo.MockVolumeCreate = func(host string, volume *executors.VolumeRequest) (*executors.Volume, error) {
+		vreq = volume
+		return &executors.Volume{}, nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	info, err := c.VolumeImportLayout(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Name == "imported", "expected name imported, got:", info.Name)
+	tests.Assert(t, info.Size == 20, "expected size 20, got:", info.Size)
+	tests.Assert(t, info.Durability.Type == api.DurabilityReplicate,
+		"expected replicate durability, got:", info.Durability.Type)
+	tests.Assert(t, info.Durability.Replicate.Replica == 3,
+		"expected replica 3, got:", info.Durability.Replicate.Replica)
+	tests.Assert(t, len(checked) == 3, "expected 3 bricks checked, got:", checked)
+
+	tests.Assert(t, vreq != nil, "expected volume create to be called")
+	tests.Assert(t, vreq.Replica == 3, "expected replica 3, got:", vreq.Replica)
+	tests.Assert(t, len(vreq.Bricks) == 3,
+		"expected 3 bricks, got:", len(vreq.Bricks))
+	for i, b := range vreq.Bricks {
+		tests.Assert(t, b.Path == req.Bricks[i].Path,
+			"expected", req.Bricks[i].Path, "got:", b.Path)
+	}
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, info.Id)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, v.Pending.Id == "", "expected volume not pending")
+		tests.Assert(t, len(v.Bricks) == 3,
+			"expected 3 bricks, got:", len(v.Bricks))
+		for _, id := range v.Bricks {
+			b, err := NewBrickEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			tests.Assert(t, b.Pending.Id == "", "expected brick not pending")
+			tests.Assert(t, deviceOf[b.Info.Path] == b.Info.DeviceId,
+				"expected device", deviceOf[b.Info.Path], "got:", b.Info.DeviceId)
+			tests.Assert(t, b.Info.Size == 20*GB,
+				"expected size 20GB, got:", b.Info.Size)
+			tests.Assert(t, strings.HasPrefix(b.LvmLv, "lv_b"),
+				"expected adopted lv name, got:", b.LvmLv)
+			tests.Assert(t, strings.HasPrefix(b.LvmThinPool, "tp_b"),
+				"expected adopted thin pool name, got:", b.LvmThinPool)
+			d, err := NewDeviceEntryFromId(tx, b.Info.DeviceId)
+			if err != nil {
+				return err
+			}
+			tests.Assert(t, d.Info.Storage.Used > 0,
+				"expected space of brick used on device", d.Info.Id)
+		}
+		pol, err := PendingOperationList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(pol) == 0, "expected no pending ops, got:", pol)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestVolumeImportLayoutWrongVg(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	req, err := sampleImportLayout(app, 20)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	app.xo.MockBrickLayoutCheck = func(host string, path string) (*executors.BrickLvInfo, error) {
+		return &executors.BrickLvInfo{
+			VgName: "vg_other",
+			LvName: "lv_other",
+			TpName: "tp_other",
+		}, nil
+	}
+	volumeCreated := false
+	app.xo.MockVolumeCreate = func(host string, volume *executors.VolumeRequest) (*executors.Volume, error) {
+		volumeCreated = true
+		return &executors.Volume{}, nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	_, err = c.VolumeImportLayout(req)
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "vg_other"),
+		`expected "vg_other" in error, got:`, err)
+	tests.Assert(t, !volumeCreated, "expected volume create not called")
+
+	// the volume and bricks are removed, the space is given back
+	err = app.db.View(func(tx *bolt.Tx) error {
+		vl, err := VolumeList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(vl) == 0, "expected no volumes, got:", vl)
+		bl, err := BrickList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(bl) == 0, "expected no bricks, got:", bl)
+		for _, ib := range req.Bricks {
+			d, err := NewDeviceEntryFromId(tx, ib.DeviceId)
+			if err != nil {
+				return err
+			}
+			tests.Assert(t, d.Info.Storage.Used == 0,
+				"expected no space used on device, got:", d.Info.Storage.Used)
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestVolumeImportLayoutBadLayout(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+
+	// device not on the node of the brick
+	req, err := sampleImportLayout(app, 20)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	req.Bricks[0].DeviceId = req.Bricks[1].DeviceId
+	_, err = c.VolumeImportLayout(req)
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "is not on node"),
+		`expected "is not on node" in error, got:`, err)
+
+	// two bricks of a replica set on the same node
+	req, err = sampleImportLayout(app, 20)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	req.Bricks[1].NodeId = req.Bricks[0].NodeId
+	req.Bricks[1].DeviceId = req.Bricks[0].DeviceId
+	_, err = c.VolumeImportLayout(req)
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "more than one brick"),
+		`expected "more than one brick" in error, got:`, err)
+
+	// brick not at the root of its mount point
+	req, err = sampleImportLayout(app, 20)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	req.Bricks[2].Path = "/bricks/b2/data"
+	_, err = c.VolumeImportLayout(req)
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "not a brick directory"),
+		`expected "not a brick directory" in error, got:`, err)
+
+	// bricks do not split in replica sets
+	req, err = sampleImportLayout(app, 20)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	req.Replica = 2
+	_, err = c.VolumeImportLayout(req)
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "can not be split"),
+		`expected "can not be split" in error, got:`, err)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		vl, err := VolumeList(tx)
+		if err != nil {
+			return err
+		}
+		tests.Assert(t, len(vl) == 0, "expected no volumes, got:", vl)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
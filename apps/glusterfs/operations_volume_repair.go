@@ -0,0 +1,332 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+var (
+	// how often and for how long to wait for a rebalance started by
+	// a repair to complete
+	repairRebalanceInterval = 30 * time.Second
+	repairRebalanceTimeout  = 12 * time.Hour
+)
+
+// VolumeRepairOperation checks the health of a volume and tries to
+// fix the problems it finds: entries in split-brain are healed, offline
+// bricks are replaced and a rebalance is run if the volume was never
+// (successfully) rebalanced. Brick replacements are run as child brick
+// evict operations. The progress of the operation is the progress of
+// the rebalance. Problems that can not be fixed do not fail the
+// operation, they are reported in the result instead.
+type VolumeRepairOperation struct {
+	OperationManager
+	noRetriesOperation
+	volId string
+
+	// set by Build
+	volName     string
+	cluster     string
+	tspId       string
+	redundant   bool
+	distributed bool
+	bricks      map[brickRef]string
+
+	result api.VolumeRepairResponse
+}
+
+// NewVolumeRepairOperation returns a new VolumeRepairOperation for the
+// volume with the given id.
+func NewVolumeRepairOperation(
+	volId string, db wdb.DB) *VolumeRepairOperation {
+
+	return &VolumeRepairOperation{
+		OperationManager: OperationManager{
+			db: db,
+			op: NewPendingOperationEntry(NEW_ID),
+		},
+		volId: volId,
+		result: api.VolumeRepairResponse{
+			ActionsTaken: []string{},
+			UnableToFix:  []string{},
+		},
+	}
+}
+
+func (vro *VolumeRepairOperation) Label() string {
+	return "Repair Volume"
+}
+
+// LockKeys returns the keys of the volume and of its cluster, offline
+// bricks are replaced by bricks allocated in the cluster.
+func (vro *VolumeRepairOperation) LockKeys() ([]string, error) {
+	var cluster string
+	err := vro.db.View(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, vro.volId)
+		if err != nil {
+			return err
+		}
+		cluster = v.Info.Cluster
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []string{volumeLockKey(vro.volId), clusterLockKey(cluster)}, nil
+}
+
+func (vro *VolumeRepairOperation) ResourceUrl() string {
+	return fmt.Sprintf("/volumes/%v", vro.volId)
+}
+
+// Result returns the actions taken by the repair and the problems
+// that were left in place.
+func (vro *VolumeRepairOperation) Result() *api.VolumeRepairResponse {
+	return &vro.result
+}
+
+// Build records the volume being repaired and caches the bricks of
+// the volume to match them against the output of volume status.
+func (vro *VolumeRepairOperation) Build(ctx context.Context) error {
+	return vro.db.Update(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, vro.volId)
+		if err != nil {
+			return err
+		}
+		if v.Pending.Id != "" {
+			logger.LogError("Pending volume %v can not be repaired",
+				vro.volId)
+			return ErrConflict
+		}
+		vro.bricks = map[brickRef]string{}
+		for _, brickId := range v.BricksIds() {
+			b, err := NewBrickEntryFromId(tx, brickId)
+			if err != nil {
+				return err
+			}
+			n, err := NewNodeEntryFromId(tx, b.Info.NodeId)
+			if err != nil {
+				return err
+			}
+			vro.bricks[brickRef{n.StorageHostName(), b.Info.Path}] = brickId
+		}
+		vro.volName = v.Info.Name
+		vro.cluster = v.Info.Cluster
+		vro.tspId = v.Info.TspId
+		vro.redundant = v.Info.Durability.Type == api.DurabilityReplicate ||
+			v.Info.Durability.Type == api.DurabilityEC
+		vro.distributed = len(v.Bricks) > v.Durability.BricksInSet()
+		vro.op.RecordRepairVolume(v)
+		return vro.op.Save(tx)
+	})
+}
+
+func (vro *VolumeRepairOperation) tookAction(f string, v ...interface{}) {
+	msg := fmt.Sprintf(f, v...)
+	logger.Info("Repair of volume %v: %v", vro.volId, msg)
+	vro.result.ActionsTaken = append(vro.result.ActionsTaken, msg)
+}
+
+func (vro *VolumeRepairOperation) unableToFix(f string, v ...interface{}) {
+	msg := fmt.Sprintf(f, v...)
+	logger.Warning("Repair of volume %v: %v", vro.volId, msg)
+	vro.result.UnableToFix = append(vro.result.UnableToFix, msg)
+}
+
+// Exec checks the volume for split-brain, offline bricks and a missing
+// rebalance, in that order, and fixes what it can. Only failing to
+// determine the health of the volume is an error.
+func (vro *VolumeRepairOperation) Exec(ctx context.Context, executor executors.Executor) error {
+	host, err := GetVerifiedTspManageHostname(vro.db, executor, vro.cluster, vro.tspId)
+	if err != nil {
+		return err
+	}
+	if err := vro.healSplitBrain(executor, host); err != nil {
+		return err
+	}
+	offline, err := vro.replaceOfflineBricks(ctx, executor, host)
+	if err != nil {
+		return err
+	}
+	return vro.rebalance(ctx, executor, host, offline)
+}
+
+// healSplitBrain resolves every entry in split-brain using the copy
+// with the latest modification time.
+func (vro *VolumeRepairOperation) healSplitBrain(
+	executor executors.Executor, host string) error {
+
+	info, err := executor.HealSplitBrainInfo(host, vro.volName)
+	if err != nil {
+		return err
+	}
+	// an entry is listed by every brick holding a copy of it
+	seen := map[string]bool{}
+	for _, b := range info.Bricks.BrickList {
+		for _, file := range b.Files {
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+			err := executor.HealSplitBrainResolve(host, vro.volName, file)
+			if err != nil {
+				vro.unableToFix("split-brain of %v: %v", file, err)
+				continue
+			}
+			vro.tookAction("healed split-brain of %v using latest-mtime", file)
+		}
+	}
+	return nil
+}
+
+// replaceOfflineBricks evicts the bricks volume status reports as
+// offline. It returns the number of offline bricks left in place.
+func (vro *VolumeRepairOperation) replaceOfflineBricks(ctx context.Context,
+	executor executors.Executor, host string) (int, error) {
+
+	status, err := executor.VolumeStatus(host, vro.volName)
+	if err != nil {
+		return 0, err
+	}
+	left := 0
+	for _, bs := range status.Bricks {
+		brickId, ok := vro.bricks[brickRef{bs.Hostname, bs.Path}]
+		if !ok || bs.Online() {
+			// not a brick (e.g. self-heal daemon) or nothing to do
+			continue
+		}
+		if !vro.redundant {
+			vro.unableToFix("brick %v is offline: volume %v has no redundancy",
+				brickId, vro.volName)
+			left++
+			continue
+		}
+		// the brick is offline, heal info can not be trusted
+		beo := NewBrickEvictOperation(brickId, vro.db, api.HealCheckDisable)
+		nestedOp := newRemoveBrickComboOperation(&vro.OperationManager, beo)
+		err := RunOperationContext(ctx, nestedOp, executor)
+		if err != nil {
+			if ctx.Err() != nil {
+				return left, err
+			}
+			vro.unableToFix("brick %v is offline: %v", brickId, err)
+			left++
+			continue
+		}
+		vro.tookAction("replaced offline brick %v (operation %v)",
+			brickId, beo.Id())
+	}
+	return left, nil
+}
+
+// rebalance runs a rebalance of a distributed volume if the volume was
+// never rebalanced or the last rebalance did not complete. It waits for
+// the rebalance to finish and records its progress meanwhile.
+func (vro *VolumeRepairOperation) rebalance(ctx context.Context,
+	executor executors.Executor, host string, offline int) error {
+
+	if !vro.distributed {
+		return nil
+	}
+	status, err := executor.VolumeRebalanceStatus(host, vro.volName)
+	if err != nil {
+		return err
+	}
+	switch status.StatusStr {
+	case executors.RebalanceNotStarted,
+		executors.RebalanceStopped,
+		executors.RebalanceFailed:
+	default:
+		return nil
+	}
+	if offline > 0 {
+		vro.unableToFix("rebalance %v: volume has %v offline bricks",
+			status.StatusStr, offline)
+		return nil
+	}
+	if err := executor.VolumeRebalanceStart(host, vro.volName); err != nil {
+		vro.unableToFix("rebalance %v: %v", status.StatusStr, err)
+		return nil
+	}
+	vro.tookAction("started rebalance (was %v)", status.StatusStr)
+
+	done, err := waitForRebalance(ctx, executor, host, vro.volName,
+		repairRebalanceInterval, repairRebalanceTimeout, vro.setProgress)
+	if ctx.Err() != nil {
+		return err
+	} else if err != nil {
+		vro.unableToFix("rebalance: %v", err)
+		return nil
+	}
+	if done.StatusStr != executors.RebalanceCompleted {
+		vro.unableToFix("rebalance %v after it was started", done.StatusStr)
+		return nil
+	}
+	vro.tookAction("rebalance completed (%v files migrated)", done.Files)
+	return nil
+}
+
+// waitForRebalance polls the rebalance status of the volume until the
+// rebalance is no longer running or the timeout expires. The progress
+// of the rebalance is passed to the given function after every poll.
+func waitForRebalance(ctx context.Context, executor executors.Executor,
+	host, volume string, interval, timeout time.Duration,
+	progress func(int) error) (*executors.RebalanceStatus, error) {
+
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := executor.VolumeRebalanceStatus(host, volume)
+		if err != nil {
+			return nil, err
+		}
+		if err := progress(status.Progress()); err != nil {
+			return nil, err
+		}
+		if !status.Running() {
+			return status, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Rebalance of volume %v still in progress after %v",
+				volume, timeout)
+		}
+		logger.Debug("Waiting for rebalance of volume %v (%v%% done)",
+			volume, status.Progress())
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Rollback removes the pending operation. Child brick evict operations
+// clean up after themselves when they fail.
+func (vro *VolumeRepairOperation) Rollback(ctx context.Context, executor executors.Executor) error {
+	return vro.db.Update(func(tx *bolt.Tx) error {
+		return vro.op.Delete(tx)
+	})
+}
+
+// Finalize removes the pending operation. The volume itself is only
+// changed by the child brick evict operations.
+func (vro *VolumeRepairOperation) Finalize() error {
+	return vro.db.Update(func(tx *bolt.Tx) error {
+		return vro.op.Delete(tx)
+	})
+}
@@ -0,0 +1,402 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+func setupVolumeRepairTest(t *testing.T, tmpfile string) (*App, *httptest.Server) {
+	app := NewTestApp(tmpfile)
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+	ts := httptest.NewServer(router)
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		3,    // devices_per_node,
+		8*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// replacing a brick needs the volume as it is in the db
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		return mockVolumeInfoFromDb(app.db, volume)
+	}
+	app.xo.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		return mockHealStatusFromDb(app.db, volume)
+	}
+	return app, ts
+}
+
+func assertNoPendingOperations(t *testing.T, app *App) {
+	err := app.db.View(func(tx *bolt.Tx) error {
+		l, err := PendingOperationList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(l) == 0, "expected len(l) == 0, got:", len(l))
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestVolumeRepairHealthy(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts := setupVolumeRepairTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err := v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+	r, err := c.VolumeRepair(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(r.ActionsTaken) == 0,
+		"expected len(r.ActionsTaken) == 0, got:", r.ActionsTaken)
+	tests.Assert(t, len(r.UnableToFix) == 0,
+		"expected len(r.UnableToFix) == 0, got:", r.UnableToFix)
+	assertNoPendingOperations(t, app)
+
+	// unknown volumes are not found
+	_, err = c.VolumeRepair("0123456789abcdef0123456789abcdef")
+	assertErrorCode(t, err, api.ErrorVolumeNotFound)
+
+	// pending volumes are not repaired
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		v.Pending.Id = "abcd"
+		return v.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, err = c.VolumeRepair(v.Info.Id)
+	assertErrorCode(t, err, api.ErrorOperationInProgress)
+}
+
+func TestVolumeRepairSplitBrain(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts := setupVolumeRepairTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err := v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	app.xo.MockHealSplitBrainInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		hi := &executors.HealInfo{}
+		hi.Bricks.BrickList = []executors.BrickHealStatus{
+			{Name: "b1", Files: []string{"/a", "<gfid:1234>"}},
+			{Name: "b2", Files: []string{"/a", "<gfid:1234>"}},
+			{Name: "b3"},
+		}
+		return hi, nil
+	}
+	resolved := []string{}
+	app.xo.MockHealSplitBrainResolve = func(host string, volume string, file string) error {
+		if file == "/a" {
+			return fmt.Errorf("no source")
+		}
+		resolved = append(resolved, file)
+		return nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	r, err := c.VolumeRepair(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(resolved) == 1 && resolved[0] == "<gfid:1234>",
+		"expected one entry to be resolved, got:", resolved)
+	tests.Assert(t, len(r.ActionsTaken) == 1,
+		"expected len(r.ActionsTaken) == 1, got:", r.ActionsTaken)
+	tests.Assert(t, len(r.UnableToFix) == 1,
+		"expected len(r.UnableToFix) == 1, got:", r.UnableToFix)
+	tests.Assert(t, strings.Contains(r.UnableToFix[0], "/a"),
+		"expected /a in unable to fix, got:", r.UnableToFix[0])
+	assertNoPendingOperations(t, app)
+
+	// failing to determine the health of the volume is an error
+	app.xo.MockHealSplitBrainInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		return nil, fmt.Errorf("heal info failed")
+	}
+	_, err = c.VolumeRepair(v.Info.Id)
+	tests.Assert(t, err != nil, "expected err != nil")
+	assertNoPendingOperations(t, app)
+}
+
+func TestVolumeRepairOfflineBrick(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts := setupVolumeRepairTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err := v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	offline := v.BricksIds()[0]
+	mockVolumeStatus(t, app, v, &offline)
+
+	c := client.NewClientNoAuth(ts.URL)
+	r, err := c.VolumeRepair(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(r.ActionsTaken) == 1,
+		"expected len(r.ActionsTaken) == 1, got:", r.ActionsTaken)
+	tests.Assert(t, strings.Contains(r.ActionsTaken[0], offline),
+		"expected brick id in action, got:", r.ActionsTaken[0])
+	tests.Assert(t, len(r.UnableToFix) == 0,
+		"expected len(r.UnableToFix) == 0, got:", r.UnableToFix)
+	assertNoPendingOperations(t, app)
+
+	// the brick was replaced
+	err = app.db.View(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(v.Bricks) == 3,
+			"expected len(v.Bricks) == 3, got:", len(v.Bricks))
+		for _, brickId := range v.Bricks {
+			tests.Assert(t, brickId != offline,
+				"expected brick to be evicted:", offline)
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestVolumeRepairOfflineBrickNoRedundancy(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts := setupVolumeRepairTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Durability.Type = api.DurabilityDistributeOnly
+	v := NewVolumeEntryFromRequest(req)
+	err := v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	offline := v.BricksIds()[0]
+	mockVolumeStatus(t, app, v, &offline)
+
+	c := client.NewClientNoAuth(ts.URL)
+	r, err := c.VolumeRepair(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(r.ActionsTaken) == 0,
+		"expected len(r.ActionsTaken) == 0, got:", r.ActionsTaken)
+	tests.Assert(t, len(r.UnableToFix) == 1,
+		"expected len(r.UnableToFix) == 1, got:", r.UnableToFix)
+	tests.Assert(t, strings.Contains(r.UnableToFix[0], "no redundancy"),
+		"expected no redundancy, got:", r.UnableToFix[0])
+	assertNoPendingOperations(t, app)
+}
+
+func TestVolumeRepairRebalance(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts := setupVolumeRepairTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err := v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	rebalanced := 0
+	app.xo.MockVolumeRebalanceStatus = func(host string, volume string) (*executors.RebalanceStatus, error) {
+		if rebalanced == 0 {
+			return &executors.RebalanceStatus{
+				StatusStr: executors.RebalanceNotStarted,
+			}, nil
+		}
+		return &executors.RebalanceStatus{
+			StatusStr: executors.RebalanceCompleted,
+		}, nil
+	}
+	app.xo.MockVolumeRebalanceStart = func(host string, volume string) error {
+		rebalanced++
+		return nil
+	}
+
+	// a volume with a single brick set never needs a rebalance
+	c := client.NewClientNoAuth(ts.URL)
+	r, err := c.VolumeRepair(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(r.ActionsTaken) == 0,
+		"expected len(r.ActionsTaken) == 0, got:", r.ActionsTaken)
+	tests.Assert(t, rebalanced == 0, "expected rebalanced == 0, got:", rebalanced)
+
+	err = RunOperation(NewVolumeExpandOperation(v, app.db, 100), app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	r, err = c.VolumeRepair(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, rebalanced == 1, "expected rebalanced == 1, got:", rebalanced)
+	tests.Assert(t, len(r.ActionsTaken) == 2,
+		"expected len(r.ActionsTaken) == 2, got:", r.ActionsTaken)
+	tests.Assert(t, strings.HasPrefix(r.ActionsTaken[1], "rebalance completed"),
+		"expected rebalance completed, got:", r.ActionsTaken[1])
+	tests.Assert(t, len(r.UnableToFix) == 0,
+		"expected len(r.UnableToFix) == 0, got:", r.UnableToFix)
+
+	// a completed rebalance is left alone
+	app.xo.MockVolumeRebalanceStatus = func(host string, volume string) (*executors.RebalanceStatus, error) {
+		return &executors.RebalanceStatus{
+			StatusStr: executors.RebalanceCompleted,
+		}, nil
+	}
+	r, err = c.VolumeRepair(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, rebalanced == 1, "expected rebalanced == 1, got:", rebalanced)
+	tests.Assert(t, len(r.ActionsTaken) == 0,
+		"expected len(r.ActionsTaken) == 0, got:", r.ActionsTaken)
+
+	// a failed rebalance can not be restarted with offline bricks
+	app.xo.MockVolumeRebalanceStatus = func(host string, volume string) (*executors.RebalanceStatus, error) {
+		return &executors.RebalanceStatus{
+			StatusStr: executors.RebalanceFailed,
+		}, nil
+	}
+	var vol *VolumeEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		vol, err = NewVolumeEntryFromId(tx, v.Info.Id)
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	offline := vol.BricksIds()[0]
+	mockVolumeStatus(t, app, vol, &offline)
+	app.xo.MockVolumeReplaceBrick = func(host string, volume string,
+		oldBrick *executors.BrickInfo, newBrick *executors.BrickInfo) error {
+		return fmt.Errorf("replace failed")
+	}
+	r, err = c.VolumeRepair(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, rebalanced == 1, "expected rebalanced == 1, got:", rebalanced)
+	tests.Assert(t, len(r.UnableToFix) == 2,
+		"expected len(r.UnableToFix) == 2, got:", r.UnableToFix)
+	assertNoPendingOperations(t, app)
+}
+
+func TestVolumeRepairRebalanceProgress(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts := setupVolumeRepairTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	defer func(i time.Duration) { repairRebalanceInterval = i }(repairRebalanceInterval)
+	repairRebalanceInterval = time.Millisecond
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err := v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = RunOperation(NewVolumeExpandOperation(v, app.db, 100), app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// the rebalance finishes on one more node with every poll
+	nodes := []string{"node1", "node2", "node3"}
+	started := false
+	polls := 0
+	progress := []int{}
+	app.xo.MockVolumeRebalanceStart = func(host string, volume string) error {
+		started = true
+		return nil
+	}
+	app.xo.MockVolumeRebalanceStatus = func(host string, volume string) (*executors.RebalanceStatus, error) {
+		if !started {
+			return &executors.RebalanceStatus{
+				StatusStr: executors.RebalanceNotStarted,
+			}, nil
+		}
+		// progress recorded after the previous poll
+		err := app.db.View(func(tx *bolt.Tx) error {
+			l, err := PendingOperationList(tx)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+			tests.Assert(t, len(l) == 1, "expected len(l) == 1, got:", len(l))
+			op, err := NewPendingOperationEntryFromId(tx, l[0])
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+			progress = append(progress, op.Progress)
+			return nil
+		})
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+		status := &executors.RebalanceStatus{
+			StatusStr: executors.RebalanceInProgress,
+			Files:     polls * 10,
+		}
+		for i, n := range nodes {
+			s := executors.RebalanceInProgress
+			if i < polls {
+				s = executors.RebalanceCompleted
+			}
+			status.Nodes = append(status.Nodes,
+				executors.RebalanceNodeStatus{NodeName: n, StatusStr: s})
+		}
+		if polls == len(nodes) {
+			status.StatusStr = executors.RebalanceCompleted
+		}
+		polls++
+		return status, nil
+	}
+
+	vro := NewVolumeRepairOperation(v.Info.Id, app.db)
+	err = vro.Build(context.Background())
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = vro.Exec(context.Background(), app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	tests.Assert(t, polls == 4, "expected polls == 4, got:", polls)
+	expected := []int{0, 0, 33, 66}
+	tests.Assert(t, len(progress) == len(expected),
+		"expected", expected, "got:", progress)
+	for i := range expected {
+		tests.Assert(t, progress[i] == expected[i],
+			"expected", expected, "got:", progress)
+	}
+	err = app.db.View(func(tx *bolt.Tx) error {
+		op, err := NewPendingOperationEntryFromId(tx, vro.Id())
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, op.Progress == 100,
+			"expected op.Progress == 100, got:", op.Progress)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	r := vro.Result()
+	tests.Assert(t, len(r.ActionsTaken) == 2,
+		"expected len(r.ActionsTaken) == 2, got:", r.ActionsTaken)
+	tests.Assert(t, r.ActionsTaken[1] == "rebalance completed (30 files migrated)",
+		"expected rebalance completed, got:", r.ActionsTaken[1])
+
+	err = vro.Finalize()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	assertNoPendingOperations(t, app)
+}
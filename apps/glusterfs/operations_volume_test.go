@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -61,7 +62,7 @@ func TestVolumeCreatePendingCreatedCleared(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify volumes, bricks, & pending ops exist
@@ -91,7 +92,7 @@ func TestVolumeCreatePendingCreatedCleared(t *testing.T) {
 		return nil
 	})
 
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	e = vc.Finalize()
@@ -163,7 +164,7 @@ func TestVolumeCreatePendingRollback(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify volumes, bricks, & pending ops exist
@@ -193,10 +194,10 @@ func TestVolumeCreatePendingRollback(t *testing.T) {
 		return nil
 	})
 
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
-	e = vc.Rollback(app.executor)
+	e = vc.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify that there are no volumes, bricks or pending operations
@@ -252,10 +253,10 @@ func TestVolumeCreateRollbackCleanupFailure(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// now we're going to pretend exec failed and inject an
@@ -265,7 +266,7 @@ func TestVolumeCreateRollbackCleanupFailure(t *testing.T) {
 		return fmt.Errorf("fake error")
 	}
 
-	e = vc.Rollback(app.executor)
+	e = vc.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e != nil, "expected e != nil, got", e)
 	markFailedIfSupported(vc)
 
@@ -327,7 +328,7 @@ func TestVolumeCreatePendingNoSpace(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	// verify that we failed to allocate due to lack of space
 	tests.Assert(t, strings.Contains(e.Error(), ErrNoSpace.Error()),
 		"expected strings.Contains(e.Error(), ErrNoSpace.Error()) got", e)
@@ -385,7 +386,7 @@ func TestVolumeCreatePendingBrickMissing(t *testing.T) {
 		return nil
 	})
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	// verify volumes, bricks, & pending ops exist
@@ -428,13 +429,13 @@ func TestVolumeCreatePendingBrickMissing(t *testing.T) {
 	// now that the brick list in the db is broken Exec/Finalize/Rollback
 	// will return errors
 
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e != nil, "expected e != nil, got", e)
 
 	e = vc.Finalize()
 	tests.Assert(t, e != nil, "expected e != nil, got", e)
 
-	e = vc.Rollback(app.executor)
+	e = vc.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e != nil, "expected e != nil, got", e)
 }
 
@@ -564,9 +565,9 @@ func TestVolumeDeleteOperation(t *testing.T) {
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	e = vc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -582,7 +583,7 @@ func TestVolumeDeleteOperation(t *testing.T) {
 	})
 
 	vd := NewVolumeDeleteOperation(vol, app.db)
-	e = vd.Build()
+	e = vd.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	app.db.View(func(tx *bolt.Tx) error {
@@ -595,7 +596,7 @@ func TestVolumeDeleteOperation(t *testing.T) {
 		return nil
 	})
 
-	e = vd.Exec(app.executor)
+	e = vd.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	e = vd.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -636,9 +637,9 @@ func TestVolumeDeleteOperationRollback(t *testing.T) {
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	e = vc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -654,7 +655,7 @@ func TestVolumeDeleteOperationRollback(t *testing.T) {
 	})
 
 	vd := NewVolumeDeleteOperation(vol, app.db)
-	e = vd.Build()
+	e = vd.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	app.db.View(func(tx *bolt.Tx) error {
@@ -667,7 +668,7 @@ func TestVolumeDeleteOperationRollback(t *testing.T) {
 		return nil
 	})
 
-	e = vd.Rollback(app.executor)
+	e = vd.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	app.db.View(func(tx *bolt.Tx) error {
@@ -709,9 +710,9 @@ func TestVolumeDeleteOperationTwice(t *testing.T) {
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	e = vc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -727,7 +728,7 @@ func TestVolumeDeleteOperationTwice(t *testing.T) {
 	})
 
 	vd := NewVolumeDeleteOperation(vol, app.db)
-	e = vd.Build()
+	e = vd.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	app.db.View(func(tx *bolt.Tx) error {
@@ -743,7 +744,7 @@ func TestVolumeDeleteOperationTwice(t *testing.T) {
 	})
 
 	vd2 := NewVolumeDeleteOperation(vol, app.db)
-	e = vd2.Build()
+	e = vd2.Build(context.Background())
 	tests.Assert(t, e == ErrConflict, "expected e == ErrConflict, got:", e)
 }
 
@@ -772,9 +773,9 @@ func TestVolumeDeleteOperationDuringExpand(t *testing.T) {
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	e = vc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -790,7 +791,7 @@ func TestVolumeDeleteOperationDuringExpand(t *testing.T) {
 	})
 
 	ve := NewVolumeExpandOperation(vol, app.db, 50)
-	e = ve.Build()
+	e = ve.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	app.db.View(func(tx *bolt.Tx) error {
@@ -801,7 +802,7 @@ func TestVolumeDeleteOperationDuringExpand(t *testing.T) {
 	})
 
 	vd := NewVolumeDeleteOperation(vol, app.db)
-	e = vd.Build()
+	e = vd.Build(context.Background())
 	tests.Assert(t, e == ErrConflict, "expected e == ErrConflict, got:", e)
 }
 
@@ -830,9 +831,9 @@ func TestVolumeExpandOperation(t *testing.T) {
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 	e = vc.Finalize()
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -848,7 +849,7 @@ func TestVolumeExpandOperation(t *testing.T) {
 	})
 
 	ve := NewVolumeExpandOperation(vol, app.db, 100)
-	e = ve.Build()
+	e = ve.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	app.db.View(func(tx *bolt.Tx) error {
@@ -870,7 +871,7 @@ func TestVolumeExpandOperation(t *testing.T) {
 		return nil
 	})
 
-	e = ve.Exec(app.executor)
+	e = ve.Exec(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got:", e)
 
 	e = ve.Finalize()
@@ -919,9 +920,9 @@ func TestListCompleteVolumesDuringOperation(t *testing.T) {
 		req.Durability.Replicate.Replica = 3
 		vol := NewVolumeEntryFromRequest(req)
 		o := NewVolumeCreateOperation(vol, app.db)
-		e := o.Build()
+		e := o.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
-		defer o.Rollback(app.executor)
+		defer o.Rollback(context.Background(), app.executor)
 
 		app.db.View(func(tx *bolt.Tx) error {
 			vols, err := ListCompleteVolumes(tx)
@@ -958,10 +959,10 @@ func TestListCompleteVolumesDuringOperation(t *testing.T) {
 		})
 
 		vdo := NewVolumeDeleteOperation(vol, app.db)
-		e = vdo.Build()
+		e = vdo.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
 		defer func() {
-			e := vdo.Exec(app.executor)
+			e := vdo.Exec(context.Background(), app.executor)
 			tests.Assert(t, e == nil, "expected e == nil, got:", e)
 			e = vdo.Finalize()
 			tests.Assert(t, e == nil, "expected e == nil, got:", e)
@@ -984,9 +985,9 @@ func TestListCompleteVolumesDuringOperation(t *testing.T) {
 		req.Size = 1024
 		bvol := NewBlockVolumeEntryFromRequest(req)
 		bvco := NewBlockVolumeCreateOperation(bvol, app.db)
-		e := bvco.Build()
+		e := bvco.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got:", e)
-		defer bvco.Rollback(app.executor)
+		defer bvco.Rollback(context.Background(), app.executor)
 
 		app.db.View(func(tx *bolt.Tx) error {
 			vols, err := ListCompleteVolumes(tx)
@@ -1114,7 +1115,7 @@ func TestVolumeCreateLimits(t *testing.T) {
 		req.Durability.Replicate.Replica = 3
 		vol := NewVolumeEntryFromRequest(req)
 		vco := NewVolumeCreateOperation(vol, app.db)
-		e := vco.Build()
+		e := vco.Build(context.Background())
 		tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 		// Next volume create should fail
@@ -1140,7 +1141,7 @@ func TestVolumeCreateLimits(t *testing.T) {
 		})
 
 		// Check the volume in pending can still proceed
-		e = vco.Exec(app.executor)
+		e = vco.Exec(context.Background(), app.executor)
 		tests.Assert(t, e == nil, "expected e == nil, got", e)
 		e = vco.Finalize()
 		tests.Assert(t, e == nil, "expected e == nil, got", e)
@@ -1284,3 +1285,157 @@ func TestVolumeCreateLimits(t *testing.T) {
 	})
 
 }
+
+func TestVolumeExpandOperationStepRetry(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		4,    // nodes_per_cluster
+		4,    // devices_per_node,
+		6*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 4
+
+	vol := NewVolumeEntryFromRequest(req)
+	vc := NewVolumeCreateOperation(vol, app.db)
+	e := RunOperation(vc, app.executor)
+	tests.Assert(t, e == nil, "expected e == nil, got:", e)
+
+	// expanding creates four bricks and then expands the volume
+	// for a total of five steps. fail the third step.
+	brickCreates := 0
+	app.xo.MockBrickCreate = func(host string,
+		brick *executors.BrickRequest) (*executors.BrickInfo, error) {
+		brickCreates++
+		if brickCreates == 3 {
+			return nil, fmt.Errorf("fake brick create error")
+		}
+		return &executors.BrickInfo{Path: brick.Path}, nil
+	}
+	volExpands := 0
+	app.xo.MockVolumeExpand = func(host string,
+		volume *executors.VolumeRequest) (*executors.Volume, error) {
+		volExpands++
+		return &executors.Volume{}, nil
+	}
+
+	ve := NewVolumeExpandOperation(vol, app.db, 100)
+	e = ve.Build(context.Background())
+	tests.Assert(t, e == nil, "expected e == nil, got:", e)
+	e = ve.Exec(context.Background(), app.executor)
+	serr, ok := e.(StepRetryError)
+	tests.Assert(t, ok, "expected StepRetryError, got:", e)
+	tests.Assert(t, strings.HasPrefix(serr.Step, "create-brick-"),
+		"expected brick step, got:", serr.Step)
+	tests.Assert(t, brickCreates == 3,
+		"expected brickCreates == 3, got:", brickCreates)
+	tests.Assert(t, volExpands == 0,
+		"expected volExpands == 0, got:", volExpands)
+
+	// the checkpoint was persisted with the pending operation
+	var p *PendingOperationEntry
+	app.db.View(func(tx *bolt.Tx) error {
+		p, e = NewPendingOperationEntryFromId(tx, ve.Id())
+		tests.Assert(t, e == nil, "expected e == nil, got", e)
+		return nil
+	})
+	tests.Assert(t, len(p.Checkpoint) == 5,
+		"expected len(p.Checkpoint) == 5, got:", len(p.Checkpoint))
+	for i, step := range p.Checkpoint {
+		tests.Assert(t, step.Executed == (i < 2),
+			"unexpected Executed state for step", i, step)
+	}
+	tests.Assert(t, p.Checkpoint[4].Name == "expand-volume",
+		"expected expand-volume step, got:", p.Checkpoint[4].Name)
+	tests.Assert(t, p.Progress == 40,
+		"expected p.Progress == 40, got:", p.Progress)
+
+	// resume the operation from the db, steps 1 & 2 are skipped
+	brickCreates = 0
+	ve2, e := loadVolumeExpandOperation(app.db, p)
+	tests.Assert(t, e == nil, "expected e == nil, got:", e)
+	e = ve2.Exec(context.Background(), app.executor)
+	tests.Assert(t, e == nil, "expected e == nil, got:", e)
+	tests.Assert(t, brickCreates == 2,
+		"expected brickCreates == 2, got:", brickCreates)
+	tests.Assert(t, volExpands == 1,
+		"expected volExpands == 1, got:", volExpands)
+	tests.Assert(t, ve2.op.Checkpoint[2].Retried == 1,
+		"expected Retried == 1, got:", ve2.op.Checkpoint[2].Retried)
+	tests.Assert(t, ve2.op.Checkpoint[3].Retried == 0,
+		"expected Retried == 0, got:", ve2.op.Checkpoint[3].Retried)
+	tests.Assert(t, ve2.op.Progress == 100,
+		"expected Progress == 100, got:", ve2.op.Progress)
+
+	e = ve2.Finalize()
+	tests.Assert(t, e == nil, "expected e == nil, got:", e)
+}
+
+func TestVolumeExpandOperationRetryNoRollback(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		4,    // devices_per_node,
+		6*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+
+	vol := NewVolumeEntryFromRequest(req)
+	vc := NewVolumeCreateOperation(vol, app.db)
+	e := RunOperation(vc, app.executor)
+	tests.Assert(t, e == nil, "expected e == nil, got:", e)
+
+	volExpands := 0
+	app.xo.MockVolumeExpand = func(host string,
+		volume *executors.VolumeRequest) (*executors.Volume, error) {
+		volExpands++
+		if volExpands == 1 {
+			return nil, fmt.Errorf("fake volume expand error")
+		}
+		return &executors.Volume{}, nil
+	}
+	brickDestroys := 0
+	app.xo.MockBrickDestroy = func(host string,
+		brick *executors.BrickRequest) (bool, error) {
+		brickDestroys++
+		return true, nil
+	}
+
+	ve := NewVolumeExpandOperation(vol, app.db, 100)
+	e = RunOperation(ve, app.executor)
+	tests.Assert(t, e == nil, "expected e == nil, got:", e)
+	tests.Assert(t, volExpands == 2,
+		"expected volExpands == 2, got:", volExpands)
+	tests.Assert(t, brickDestroys == 0,
+		"expected brickDestroys == 0, got:", brickDestroys)
+
+	app.db.View(func(tx *bolt.Tx) error {
+		po, e := PendingOperationList(tx)
+		tests.Assert(t, e == nil, "expected e == nil, got", e)
+		tests.Assert(t, len(po) == 0, "expected len(po) == 0, got:", len(po))
+		return nil
+	})
+}
@@ -0,0 +1,262 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// translatorOptions returns the options of the volume reported by
+// gluster that belong to a performance translator, by translator.
+func translatorOptions(vol *executors.Volume) map[string]map[string]string {
+	result := map[string]map[string]string{}
+	for _, o := range vol.Options.OptionList {
+		t := api.PerformanceTranslatorOf(o.Name)
+		if t == "" {
+			continue
+		}
+		if result[t] == nil {
+			result[t] = map[string]string{}
+		}
+		result[t][o.Name] = o.Value
+	}
+	return result
+}
+
+// VolumeTranslatorsOperation implements the operation functions used
+// to set the options of a performance translator of an existing volume.
+// Every option is set by its own step so that an interrupted operation
+// can be completed, instead of rolled back, by the operation cleaner.
+type VolumeTranslatorsOperation struct {
+	OperationManager
+	vol *VolumeEntry
+	req api.VolumeTranslatorRequest
+}
+
+// NewVolumeTranslatorsOperation returns a new VolumeTranslatorsOperation
+// populated with the given params.
+func NewVolumeTranslatorsOperation(
+	vol *VolumeEntry, db wdb.DB,
+	req api.VolumeTranslatorRequest) *VolumeTranslatorsOperation {
+
+	return &VolumeTranslatorsOperation{
+		OperationManager: OperationManager{
+			db: db,
+			op: NewPendingOperationEntry(NEW_ID),
+		},
+		vol: vol,
+		req: req,
+	}
+}
+
+// loadVolumeTranslatorsOperation returns a VolumeTranslatorsOperation
+// populated from an existing pending operation entry in the db.
+func loadVolumeTranslatorsOperation(
+	db wdb.DB, p *PendingOperationEntry) (*VolumeTranslatorsOperation, error) {
+
+	i := findChange(p.Actions, OpSetVolumeTranslators)
+	if i < 0 {
+		return nil, fmt.Errorf(
+			"Missing translator change in operation: %v", p.Id)
+	}
+	req, err := p.Actions[i].TranslatorRequest()
+	if err != nil {
+		return nil, err
+	}
+	var vol *VolumeEntry
+	err = db.View(func(tx *bolt.Tx) error {
+		var err error
+		vol, err = NewVolumeEntryFromId(tx, p.Actions[i].Id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &VolumeTranslatorsOperation{
+		OperationManager: OperationManager{
+			db: db,
+			op: p,
+		},
+		vol: vol,
+		req: req,
+	}, nil
+}
+
+func (to *VolumeTranslatorsOperation) Label() string {
+	return "Set Volume Translator Options"
+}
+
+func (to *VolumeTranslatorsOperation) LockKeys() ([]string, error) {
+	return []string{volumeLockKey(to.vol.Info.Id)}, nil
+}
+
+func (to *VolumeTranslatorsOperation) ResourceUrl() string {
+	return fmt.Sprintf("/volumes/%v", to.vol.Info.Id)
+}
+
+// MaxRetries allows a failed step to be retried once before the
+// operation is rolled back.
+func (to *VolumeTranslatorsOperation) MaxRetries() int {
+	return 1
+}
+
+// Build records the pending change, including the requested options,
+// in the db.
+func (to *VolumeTranslatorsOperation) Build(ctx context.Context) error {
+	return to.db.Update(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, to.vol.Info.Id)
+		if err != nil {
+			return err
+		}
+		to.vol = v
+		to.op.RecordSetVolumeTranslators(v, to.req)
+		return to.op.Save(tx)
+	})
+}
+
+// steps returns a step setting each requested option, ordered by the
+// option name so that a resumed operation runs the same steps.
+func (to *VolumeTranslatorsOperation) steps(executor executors.Executor) []opStep {
+	names := []string{}
+	for name := range to.req.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	steps := []opStep{}
+	for _, name := range names {
+		option := name + " " + to.req.Options[name]
+		steps = append(steps, opStep{
+			name: "set-" + name,
+			run: func() error {
+				host, err := GetVerifiedTspManageHostname(to.db, executor,
+					to.vol.Info.Cluster, to.vol.Info.TspId)
+				if err != nil {
+					return err
+				}
+				return executor.VolumeModify(host, &executors.VolumeModifyRequest{
+					Name:                 to.vol.Info.Name,
+					GlusterVolumeOptions: []string{option},
+				})
+			},
+		})
+	}
+	return steps
+}
+
+// Exec sets the options one at a time, skipping the options a previous
+// attempt of the operation already set.
+func (to *VolumeTranslatorsOperation) Exec(ctx context.Context, executor executors.Executor) error {
+	return runSteps(to.db, to.op, to.steps(executor))
+}
+
+// Rollback restores the previous values of the options that were set
+// through heketi before. Options that had no such value keep the new
+// value on the volume. The pending operation is removed.
+func (to *VolumeTranslatorsOperation) Rollback(ctx context.Context, executor executors.Executor) error {
+	stored := volumeOptionsMap(to.vol.GlusterVolumeOptions)
+	prev := []string{}
+	for _, cp := range to.op.Checkpoint {
+		if !cp.Executed {
+			continue
+		}
+		name := cp.Name[len("set-"):]
+		if value, ok := stored[name]; ok {
+			prev = append(prev, name+" "+value)
+		} else {
+			logger.Warning("No previous value of option %v of volume %v "+
+				"to restore", name, to.vol.Info.Name)
+		}
+	}
+	if len(prev) > 0 {
+		host, err := GetVerifiedTspManageHostname(to.db, executor,
+			to.vol.Info.Cluster, to.vol.Info.TspId)
+		if err != nil {
+			return err
+		}
+		err = executor.VolumeModify(host, &executors.VolumeModifyRequest{
+			Name:                 to.vol.Info.Name,
+			GlusterVolumeOptions: prev,
+		})
+		if err != nil {
+			logger.LogError("Failed to restore volume options on %v: %v",
+				to.vol.Info.Name, err)
+			return err
+		}
+	}
+	return to.db.Update(func(tx *bolt.Tx) error {
+		return to.op.Delete(tx)
+	})
+}
+
+// Finalize records the options in the volume entry.
+func (to *VolumeTranslatorsOperation) Finalize() error {
+	return to.db.Update(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, to.vol.Info.Id)
+		if err != nil {
+			return err
+		}
+		if v.Info.TranslatorOptions == nil {
+			v.Info.TranslatorOptions = map[string]map[string]string{}
+		}
+		options := v.Info.TranslatorOptions[to.req.Translator]
+		if options == nil {
+			options = map[string]string{}
+			v.Info.TranslatorOptions[to.req.Translator] = options
+		}
+		set := []string{}
+		for name, value := range to.req.Options {
+			options[name] = value
+			set = append(set, name+" "+value)
+		}
+		sort.Strings(set)
+		v.GlusterVolumeOptions = replaceVolumeOptions(
+			v.GlusterVolumeOptions, set)
+		if err := v.Save(tx); err != nil {
+			return err
+		}
+		to.vol = v
+		return to.op.Delete(tx)
+	})
+}
+
+// Clean sets the options an interrupted operation did not set. Setting
+// an option again is harmless so the operation is rolled forward
+// rather than back. The checkpoint is not updated as Clean must not
+// write to the db.
+func (to *VolumeTranslatorsOperation) Clean(executor executors.Executor) error {
+	logger.Info("Starting Clean for %v op:%v", to.Label(), to.op.Id)
+	steps := to.steps(executor)
+	resumed := checkpointMatches(to.op, steps)
+	for i, s := range steps {
+		if resumed && to.op.Checkpoint[i].Executed {
+			continue
+		}
+		if err := s.run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CleanDone records the options in the volume entry.
+func (to *VolumeTranslatorsOperation) CleanDone() error {
+	logger.Info("Clean is done for %v op:%v", to.Label(), to.op.Id)
+	return to.Finalize()
+}
@@ -0,0 +1,276 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+func setupVolumeTranslatorsTest(t *testing.T, tmpfile string) (
+	*App, *httptest.Server, *VolumeEntry) {
+
+	app := NewTestApp(tmpfile)
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+	ts := httptest.NewServer(router)
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	return app, ts, v
+}
+
+func TestVolumeTranslators(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts, v := setupVolumeTranslatorsTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		vinfo := &executors.Volume{VolumeName: volume}
+		vinfo.Options.OptionList = []executors.Option{
+			{Name: "performance.io-thread-count", Value: "32"},
+			{Name: "performance.write-behind-window-size", Value: "4MB"},
+			{Name: "performance.flush-behind", Value: "off"},
+			{Name: "server.root-squash", Value: "on"},
+		}
+		return vinfo, nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	resp, err := c.VolumeTranslators(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	expected := map[string]map[string]string{
+		"io-threads": {
+			"performance.io-thread-count": "32",
+		},
+		"write-behind": {
+			"performance.write-behind-window-size": "4MB",
+			"performance.flush-behind":             "off",
+		},
+	}
+	tests.Assert(t, reflect.DeepEqual(resp.Translators, expected),
+		"expected", expected, "got:", resp.Translators)
+
+	_, err = c.VolumeTranslators("0123456789abcdef")
+	tests.Assert(t, err != nil, "expected err != nil")
+}
+
+func TestVolumeSetTranslator(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts, v := setupVolumeTranslatorsTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	cmds := recordVolumeSets(app, "")
+	c := client.NewClientNoAuth(ts.URL)
+	info, err := c.VolumeSetTranslator(v.Info.Id, &api.VolumeTranslatorRequest{
+		Translator: "write-behind",
+		Options: map[string]string{
+			"performance.write-behind-window-size": "4MB",
+			"performance.flush-behind":             "off",
+		},
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	expected := []string{
+		"gluster volume set " + v.Info.Name + " performance.flush-behind off",
+		"gluster volume set " + v.Info.Name +
+			" performance.write-behind-window-size 4MB",
+	}
+	tests.Assert(t, reflect.DeepEqual(*cmds, expected),
+		"expected", expected, "got:", *cmds)
+	tests.Assert(t,
+		info.TranslatorOptions["write-behind"]["performance.flush-behind"] == "off",
+		"unexpected translator options:", info.TranslatorOptions)
+
+	// options of other translators are kept
+	_, err = c.VolumeSetTranslator(v.Info.Id, &api.VolumeTranslatorRequest{
+		Translator: "io-threads",
+		Options: map[string]string{
+			"performance.io-thread-count": "32",
+		},
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	info, err = c.VolumeInfo(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(info.TranslatorOptions) == 2,
+		"unexpected translator options:", info.TranslatorOptions)
+	tests.Assert(t,
+		info.TranslatorOptions["io-threads"]["performance.io-thread-count"] == "32",
+		"unexpected translator options:", info.TranslatorOptions)
+	found := false
+	for _, o := range info.GlusterVolumeOptions {
+		found = found || o == "performance.io-thread-count 32"
+	}
+	tests.Assert(t, found, "expected option in", info.GlusterVolumeOptions)
+}
+
+func TestVolumeSetTranslatorInvalid(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts, v := setupVolumeTranslatorsTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	cmds := recordVolumeSets(app, "")
+	for _, body := range []string{
+		`{"translator": "nope", "options": {"performance.io-cache": "on"}}`,
+		`{"translator": "io-cache", "options": {"server.root-squash": "on"}}`,
+		`{"translator": "io-cache", "options": {"performance.cache-size": "1MB; reboot"}}`,
+		`{"translator": "io-cache", "options": {}}`,
+	} {
+		req, err := http.NewRequest("PUT",
+			ts.URL+"/volumes/"+v.Info.Id+"/translators",
+			strings.NewReader(body))
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		r, err := http.DefaultClient.Do(req)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, r.StatusCode == http.StatusBadRequest,
+			"expected r.StatusCode == http.StatusBadRequest, got:",
+			r.StatusCode, body)
+	}
+	tests.Assert(t, len(*cmds) == 0, "expected no commands, got:", *cmds)
+}
+
+func TestVolumeTranslatorsOperationRestart(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts, v := setupVolumeTranslatorsTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	req := api.VolumeTranslatorRequest{
+		Translator: "io-cache",
+		Options: map[string]string{
+			"performance.cache-max-file-size": "64MB",
+			"performance.cache-min-file-size": "1KB",
+			"performance.cache-size":          "256MB",
+		},
+	}
+
+	// the server goes away while setting the second option
+	cmds := recordVolumeSets(app, "performance.cache-min-file-size")
+	to := NewVolumeTranslatorsOperation(v, app.db, req)
+	err := to.Build(context.Background())
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = to.Exec(context.Background(), app.executor)
+	_, ok := err.(StepRetryError)
+	tests.Assert(t, ok, "expected StepRetryError, got:", err)
+	tests.Assert(t, len(*cmds) == 1, "expected 1 command, got:", *cmds)
+
+	// the operation is completed from what was saved in the db
+	cmds = recordVolumeSets(app, "")
+	var p *PendingOperationEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		p, err = NewPendingOperationEntryFromId(tx, to.Id())
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	op, err := LoadOperation(app.db, p)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	cop, ok := op.(CleanableOperation)
+	tests.Assert(t, ok, "expected a cleanable operation")
+	err = cop.Clean(app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	expected := []string{
+		"gluster volume set " + v.Info.Name +
+			" performance.cache-min-file-size 1KB",
+		"gluster volume set " + v.Info.Name + " performance.cache-size 256MB",
+	}
+	tests.Assert(t, reflect.DeepEqual(*cmds, expected),
+		"expected", expected, "got:", *cmds)
+	err = cop.CleanDone()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		l, err := PendingOperationList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(l) == 0, "expected no pending operations, got:", l)
+
+		v, err = NewVolumeEntryFromId(tx, v.Info.Id)
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, reflect.DeepEqual(v.Info.TranslatorOptions["io-cache"], req.Options),
+		"expected", req.Options, "got:", v.Info.TranslatorOptions)
+}
+
+func TestVolumeTranslatorsOperationRollback(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts, v := setupVolumeTranslatorsTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	recordVolumeSets(app, "")
+	req := api.VolumeTranslatorRequest{
+		Translator: "read-ahead",
+		Options: map[string]string{
+			"performance.read-ahead": "off",
+		},
+	}
+	err := RunOperation(NewVolumeTranslatorsOperation(v, app.db, req),
+		app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// the previous value of an option set through heketi is restored
+	cmds := recordVolumeSets(app, "performance.read-ahead-page-count")
+	req.Options = map[string]string{
+		"performance.read-ahead":            "on",
+		"performance.read-ahead-page-count": "16",
+	}
+	err = RunOperation(NewVolumeTranslatorsOperation(v, app.db, req),
+		app.executor)
+	tests.Assert(t, err != nil, "expected err != nil")
+	expected := []string{
+		"gluster volume set " + v.Info.Name + " performance.read-ahead on",
+		"gluster volume set " + v.Info.Name + " performance.read-ahead off",
+	}
+	tests.Assert(t, reflect.DeepEqual(*cmds, expected),
+		"expected", expected, "got:", *cmds)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		l, err := PendingOperationList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(l) == 0, "expected no pending operations, got:", l)
+
+		v, err = NewVolumeEntryFromId(tx, v.Info.Id)
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t,
+		v.Info.TranslatorOptions["read-ahead"]["performance.read-ahead"] == "off",
+		"unexpected translator options:", v.Info.TranslatorOptions)
+}
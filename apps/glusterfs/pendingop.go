@@ -10,9 +10,17 @@
 package glusterfs
 
 import (
+	"encoding/gob"
 	"fmt"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
 )
 
+func init() {
+	// stored in the delta of OpSetVolumeTranslators actions
+	gob.Register(api.VolumeTranslatorRequest{})
+}
+
 // The pendingop.go file defines the basic structures needed to track
 // life-cycle of database entries w/in Heketi. There are generally two
 // levels of objects which we track: the pending operation a higher-level
@@ -38,6 +46,15 @@ const (
 	OperationRemoveDevice
 	OperationCloneVolume
 	OperationBrickEvict
+	OperationVolumeAclConfig
+	OperationMigrateBlockGateway
+	OperationRepairVolume
+	OperationImportVolume
+	OperationReplaceDevice
+	OperationSetSnapshotPolicy
+	OperationReinitializeDevice
+	OperationSetVolumeTranslators
+	OperationAddDevices
 )
 
 // PendingChangeType identifies what kind of lower-level new item or change
@@ -60,6 +77,13 @@ const (
 	OpAddVolumeClone
 	OpChildOperation
 	OpParentOperation
+	OpVolumeAclConfig
+	OpMigrateBlockGateway
+	OpRepairVolume
+	OpSetSnapshotPolicy
+	OpReinitializeDevice
+	OpSetVolumeTranslators
+	OpAddDevice
 )
 
 // PendingOperationAction tracks individual changes to entries within the
@@ -83,6 +107,8 @@ type PendingOperation struct {
 	Timestamp int64
 	Type      PendingOperationType
 	Actions   []PendingOperationAction
+	// user defined metadata
+	Tags map[string]string
 }
 
 // ExpandSize extracts an int value for a pending size expansion from the
@@ -97,6 +123,19 @@ func (a PendingOperationAction) ExpandSize() (int, error) {
 	return 0, fmt.Errorf("Action delta for ExpandSize is missing/invalid")
 }
 
+// TranslatorRequest extracts the requested translator options from the
+// PendingOperationAction if the change type is correct. If the type is
+// not correct error will be non-nil.
+func (a PendingOperationAction) TranslatorRequest() (api.VolumeTranslatorRequest, error) {
+	if a.Change == OpSetVolumeTranslators {
+		if v, ok := a.Delta.(api.VolumeTranslatorRequest); ok {
+			return v, nil
+		}
+	}
+	return api.VolumeTranslatorRequest{},
+		fmt.Errorf("Action delta for TranslatorRequest is missing/invalid")
+}
+
 // Name returns the pending operation type as a brief string.
 // NOTE: Stringer was considered but not used as the literal
 // names of the variables were not desired. Thus to avoid
@@ -122,10 +161,86 @@ func (v PendingOperationType) Name() string {
 		return "clone-volume"
 	case OperationBrickEvict:
 		return "evict-brick"
+	case OperationVolumeAclConfig:
+		return "volume-acl-config"
+	case OperationMigrateBlockGateway:
+		return "migrate-block-gateway"
+	case OperationRepairVolume:
+		return "repair-volume"
+	case OperationImportVolume:
+		return "import-volume"
+	case OperationReplaceDevice:
+		return "replace-device"
+	case OperationSetSnapshotPolicy:
+		return "set-snapshot-policy"
+	case OperationReinitializeDevice:
+		return "reinitialize-device"
+	case OperationSetVolumeTranslators:
+		return "set-volume-translators"
+	case OperationAddDevices:
+		return "add-devices"
 	}
 	return "unknown"
 }
 
+// AllOperations returns all of the defined pending operation types,
+// except OperationUnknown, in the order they are defined.
+func AllOperations() []PendingOperationType {
+	return []PendingOperationType{
+		OperationCreateVolume,
+		OperationDeleteVolume,
+		OperationExpandVolume,
+		OperationCreateBlockVolume,
+		OperationDeleteBlockVolume,
+		OperationExpandBlockVolume,
+		OperationRemoveDevice,
+		OperationCloneVolume,
+		OperationBrickEvict,
+		OperationVolumeAclConfig,
+		OperationMigrateBlockGateway,
+		OperationRepairVolume,
+		OperationImportVolume,
+		OperationReplaceDevice,
+		OperationSetSnapshotPolicy,
+		OperationReinitializeDevice,
+		OperationSetVolumeTranslators,
+		OperationAddDevices,
+	}
+}
+
+// IsWriteOperation returns true if the operation type modifies the
+// state of the cluster.
+func (v PendingOperationType) IsWriteOperation() bool {
+	switch v {
+	case OperationCreateVolume,
+		OperationDeleteVolume,
+		OperationExpandVolume,
+		OperationCreateBlockVolume,
+		OperationDeleteBlockVolume,
+		OperationExpandBlockVolume,
+		OperationRemoveDevice,
+		OperationCloneVolume,
+		OperationBrickEvict,
+		OperationVolumeAclConfig,
+		OperationMigrateBlockGateway,
+		OperationRepairVolume,
+		OperationImportVolume,
+		OperationReplaceDevice,
+		OperationSetSnapshotPolicy,
+		OperationReinitializeDevice,
+		OperationSetVolumeTranslators,
+		OperationAddDevices:
+		return true
+	}
+	return false
+}
+
+// IsReadOperation returns true if the operation type only queries the
+// state of the cluster. No such operation types are currently tracked.
+func (v PendingOperationType) IsReadOperation() bool {
+	return false
+}
+
 // Name returns a short description of a change action.
 func (c PendingChangeType) Name() string {
 	switch c {
@@ -157,6 +272,48 @@ func (c PendingChangeType) Name() string {
 		return "Performing child operation"
 	case OpParentOperation:
 		return "Belongs to parent operation"
+	case OpVolumeAclConfig:
+		return "Configure volume ACL"
+	case OpMigrateBlockGateway:
+		return "Migrate block volume gateway"
+	case OpRepairVolume:
+		return "Repair volume"
+	case OpSetSnapshotPolicy:
+		return "Set snapshot policy"
+	case OpReinitializeDevice:
+		return "Reinitialize device"
+	case OpSetVolumeTranslators:
+		return "Set volume translator options"
+	case OpAddDevice:
+		return "Add device"
 	}
 	return "Unknown"
 }
+
+// AllChangeTypes returns all of the defined pending change types,
+// except OpUnknown, in the order they are defined.
+func AllChangeTypes() []PendingChangeType {
+	return []PendingChangeType{
+		OpAddBrick,
+		OpAddVolume,
+		OpDeleteBrick,
+		OpDeleteVolume,
+		OpExpandVolume,
+		OpAddBlockVolume,
+		OpDeleteBlockVolume,
+		OpExpandBlockVolume,
+		OpRemoveDevice,
+		OpCloneVolume,
+		OpSnapshotVolume,
+		OpAddVolumeClone,
+		OpChildOperation,
+		OpParentOperation,
+		OpVolumeAclConfig,
+		OpMigrateBlockGateway,
+		OpRepairVolume,
+		OpSetSnapshotPolicy,
+		OpReinitializeDevice,
+		OpSetVolumeTranslators,
+		OpAddDevice,
+	}
+}
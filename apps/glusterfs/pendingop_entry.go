@@ -27,7 +27,9 @@ type OperationStatus string
 const (
 	NEW_ID                    = ""
 	BOLTDB_BUCKET_PENDING_OPS = "PENDING_OPERATIONS"
-	DB_HAS_PENDING_OPS_BUCKET = "DB_HAS_PENDING_OPS_BUCKET"
+	// failed and stale pending operations moved out of the way
+	BOLTDB_BUCKET_PENDING_OPS_ARCHIVE = "PENDING_OPERATIONS_ARCHIVE"
+	DB_HAS_PENDING_OPS_BUCKET         = "DB_HAS_PENDING_OPS_BUCKET"
 )
 
 // define constants for OperationStatus
@@ -48,6 +50,13 @@ type PendingOperationEntry struct {
 
 	// tracking the status of operations
 	Status OperationStatus
+
+	// progress of the operation's exec steps, if tracked
+	Checkpoint []OperationStep
+
+	// percentage of the work of the operation that is done, for
+	// operations that track it
+	Progress int
 }
 
 // PendingOperationList returns the IDs of all pending operation entries
@@ -60,6 +69,20 @@ func PendingOperationList(tx *bolt.Tx) ([]string, error) {
 	return list, nil
 }
 
+// ArchivedPendingOperationList returns the IDs of all pending operation
+// entries in the archive bucket of the Heketi db.
+func ArchivedPendingOperationList(tx *bolt.Tx) ([]string, error) {
+	if tx.Bucket([]byte(BOLTDB_BUCKET_PENDING_OPS_ARCHIVE)) == nil {
+		// a db opened read-only may predate the archive
+		return []string{}, nil
+	}
+	list := EntryKeys(tx, BOLTDB_BUCKET_PENDING_OPS_ARCHIVE)
+	if list == nil {
+		return nil, ErrAccessList
+	}
+	return list, nil
+}
+
 // HasPendingOperations returns true if the db contains one or more pending
 // operation entries. If the db cannot be read the function panics.
 func HasPendingOperations(db wdb.RODB) bool {
@@ -120,6 +143,31 @@ func NewPendingOperationEntryFromId(tx *bolt.Tx, id string) (
 	return entry, nil
 }
 
+// NewArchivedPendingOperationEntryFromId fetches a pending operation
+// entry from the archive bucket of the heketi db.
+func NewArchivedPendingOperationEntryFromId(tx *bolt.Tx, id string) (
+	*PendingOperationEntry, error) {
+	godbc.Require(tx != nil)
+	godbc.Require(id != "")
+
+	b := tx.Bucket([]byte(BOLTDB_BUCKET_PENDING_OPS_ARCHIVE))
+	if b == nil {
+		return nil, ErrNotFound
+	}
+	val := b.Get([]byte(id))
+	if val == nil {
+		return nil, ErrNotFound
+	}
+	entry := &PendingOperationEntry{}
+	if err := entry.Unmarshal(val); err != nil {
+		return nil, err
+	}
+	if entry.Actions == nil {
+		entry.Actions = []PendingOperationAction{}
+	}
+	return entry, nil
+}
+
 // Save records the pending operation entry object in the db, keyed by the
 // value of its ID.
 func (p *PendingOperationEntry) Save(tx *bolt.Tx) error {
@@ -136,6 +184,23 @@ func (p *PendingOperationEntry) Delete(tx *bolt.Tx) error {
 	return EntryDelete(tx, p, p.Id)
 }
 
+// archive moves the pending operation entry from the pending operations
+// bucket to the archive bucket.
+func (p *PendingOperationEntry) archive(tx *bolt.Tx) error {
+	b := tx.Bucket([]byte(BOLTDB_BUCKET_PENDING_OPS_ARCHIVE))
+	if b == nil {
+		return ErrDbAccess
+	}
+	buffer, err := p.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := b.Put([]byte(p.Id), buffer); err != nil {
+		return err
+	}
+	return EntryDelete(tx, p, p.Id)
+}
+
 // Reset clears the all of PendingOperationEntry's state except for
 // the ID so that it may be reused.
 func (p *PendingOperationEntry) Reset() {
@@ -231,6 +296,54 @@ func (p *PendingOperationEntry) FinalizeBrick(b *BrickEntry) {
 func (p *PendingOperationEntry) RecordExpandVolume(v *VolumeEntry, sizeGB int) {
 	p.recordSizeChange(OpExpandVolume, v.Info.Id, sizeGB)
 	p.Type = OperationExpandVolume
+	v.Expanding.Id = p.Id
+}
+
+// FinalizeExpandVolume removes the link from the volume to the
+// expand operation.
+func (p *PendingOperationEntry) FinalizeExpandVolume(v *VolumeEntry) {
+	v.Expanding.Id = ""
+}
+
+// RecordVolumeAclConfig adds tracking metadata for a volume whose
+// access control settings are being changed to the PendingOperationEntry.
+// The volume remains visible while the settings are applied.
+func (p *PendingOperationEntry) RecordVolumeAclConfig(v *VolumeEntry) {
+	p.recordChange(OpVolumeAclConfig, v.Info.Id)
+	p.Type = OperationVolumeAclConfig
+}
+
+// RecordSetVolumeTranslators adds tracking metadata for a volume whose
+// performance translator options are being set to the
+// PendingOperationEntry. The requested options are kept with the change
+// so that the operation can be completed after a restart.
+func (p *PendingOperationEntry) RecordSetVolumeTranslators(v *VolumeEntry,
+	req api.VolumeTranslatorRequest) {
+
+	godbc.Require(p.Id != "")
+	p.Actions = append(p.Actions, PendingOperationAction{
+		Change: OpSetVolumeTranslators,
+		Id:     v.Info.Id,
+		Delta:  req,
+	})
+	p.Type = OperationSetVolumeTranslators
+}
+
+// RecordRepairVolume adds tracking metadata for a volume that is being
+// repaired to the PendingOperationEntry. The volume remains visible
+// while the repair runs.
+func (p *PendingOperationEntry) RecordRepairVolume(v *VolumeEntry) {
+	p.recordChange(OpRepairVolume, v.Info.Id)
+	p.Type = OperationRepairVolume
+}
+
+// RecordImportVolume adds tracking metadata for a volume being created
+// out of pre-existing bricks to the PendingOperationEntry and
+// VolumeEntry.
+func (p *PendingOperationEntry) RecordImportVolume(v *VolumeEntry) {
+	p.recordChange(OpAddVolume, v.Info.Id)
+	p.Type = OperationImportVolume
+	v.Pending.Id = p.Id
 }
 
 // RecordDeleteVolume adds tracking metadata for a to-be-deleted volume
@@ -291,6 +404,13 @@ func (p *PendingOperationEntry) RecordExpandBlockVolume(bv *BlockVolumeEntry, ne
 	p.Type = OperationExpandBlockVolume
 }
 
+// RecordMigrateBlockGateway adds tracking metadata for a block volume
+// whose export is being moved to another gateway node.
+func (p *PendingOperationEntry) RecordMigrateBlockGateway(bv *BlockVolumeEntry) {
+	p.recordChange(OpMigrateBlockGateway, bv.Info.Id)
+	p.Type = OperationMigrateBlockGateway
+}
+
 // RecordRemoveDevice adds tracking metadata for a long-running device
 // removal operation.
 func (p *PendingOperationEntry) RecordRemoveDevice(d *DeviceEntry) {
@@ -298,6 +418,36 @@ func (p *PendingOperationEntry) RecordRemoveDevice(d *DeviceEntry) {
 	p.Type = OperationRemoveDevice
 }
 
+// RecordReplaceDevice adds tracking metadata for a device being
+// replaced by a new device to the PendingOperationEntry. The device is
+// recorded as being removed.
+func (p *PendingOperationEntry) RecordReplaceDevice(d *DeviceEntry) {
+	p.recordChange(OpRemoveDevice, d.Info.Id)
+	p.Type = OperationReplaceDevice
+}
+
+// RecordSetSnapshotPolicy adds tracking metadata for a cluster whose
+// snapshot policy is being changed to the PendingOperationEntry.
+func (p *PendingOperationEntry) RecordSetSnapshotPolicy(c *ClusterEntry) {
+	p.recordChange(OpSetSnapshotPolicy, c.Info.Id)
+	p.Type = OperationSetSnapshotPolicy
+}
+
+// RecordReinitializeDevice adds tracking metadata for a device being
+// wiped and set up again to the PendingOperationEntry.
+func (p *PendingOperationEntry) RecordReinitializeDevice(d *DeviceEntry) {
+	p.recordChange(OpReinitializeDevice, d.Info.Id)
+	p.Type = OperationReinitializeDevice
+}
+
+// RecordAddDevice adds tracking metadata for a device being set up as
+// part of a batch to the PendingOperationEntry. The device is only
+// saved to the db once all of the devices of the batch are set up.
+func (p *PendingOperationEntry) RecordAddDevice(d *DeviceEntry) {
+	p.recordChange(OpAddDevice, d.Info.Id)
+	p.Type = OperationAddDevices
+}
+
 // RecordChild adds or replaces a child operation for the current
 // pending operation entry. Both child and parent can only have
 // one parent/child relationship. Both are updated.
@@ -358,10 +508,39 @@ func (p *PendingOperationEntry) ToInfo() api.PendingOperationInfo {
 		Id:       p.Id,
 		TypeName: p.Type.Name(),
 		Status:   string(p.Status),
+		Progress: p.Progress,
+		Tags:     p.Tags,
 		// label and substatus must be filled in later
 	}
 }
 
+// ToDetails returns the api representation of the pending operation
+// entry including the changes it tracks.
+func (p *PendingOperationEntry) ToDetails() *api.PendingOperationDetails {
+	info := &api.PendingOperationDetails{
+		PendingOperationInfo: p.ToInfo(),
+		Changes:              make([]api.PendingChangeInfo, len(p.Actions)),
+	}
+	for i, a := range p.Actions {
+		info.Changes[i] = api.PendingChangeInfo{
+			Id:          a.Id,
+			Description: a.Change.Name(),
+		}
+	}
+	return info
+}
+
+// Targets returns true if any of the actions of the pending operation
+// entry refer to the given id.
+func (p *PendingOperationEntry) Targets(id string) bool {
+	for _, action := range p.Actions {
+		if action.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
 // PendingOperationUpgrade updates the heketi db with metadata needed to
 // support pending operation entries.
 func PendingOperationUpgrade(tx *bolt.Tx) error {
@@ -444,6 +623,157 @@ func PendingOperationEntrySelection(
 	return selection, nil
 }
 
+// DeletePendingOperationsBefore deletes the pending operation entries
+// with one of the given statuses and a timestamp earlier than before.
+// New operations may still be running and are never deleted, nor are
+// the entries for which keep returns true. Entries of operations that
+// left volumes, bricks or block volumes marked pending are kept as well:
+// removing them would orphan these markers, so such operations must be
+// rolled back by the operations cleaner. The entries are scanned with
+// a cursor, at most batchSize entries per db transaction, so that the
+// db is not held for long. The number of deleted entries is returned.
+func DeletePendingOperationsBefore(db wdb.DB,
+	statuses map[OperationStatus]bool, before int64,
+	batchSize int, keep func(id string) bool) (int, error) {
+
+	return removePendingOperationsBefore(db, statuses, before, batchSize, keep,
+		func(tx *bolt.Tx, pop *PendingOperationEntry) error {
+			logger.Info("Deleting %v pending operation %v (%v)",
+				pop.Status, pop.Id, pop.Type.Name())
+			return pop.Delete(tx)
+		})
+}
+
+// ArchivePendingOperationsBefore moves the failed and stale pending
+// operation entries with a timestamp earlier than before to the archive
+// bucket. The entries are selected and scanned in the same way as by
+// DeletePendingOperationsBefore. The number of archived entries is
+// returned.
+func ArchivePendingOperationsBefore(db wdb.DB, before int64,
+	batchSize int, keep func(id string) bool) (int, error) {
+
+	statuses := map[OperationStatus]bool{
+		FailedOperation: true,
+		StaleOperation:  true,
+	}
+	return removePendingOperationsBefore(db, statuses, before, batchSize, keep,
+		func(tx *bolt.Tx, pop *PendingOperationEntry) error {
+			logger.Info("Archiving %v pending operation %v (%v)",
+				pop.Status, pop.Id, pop.Type.Name())
+			return pop.archive(tx)
+		})
+}
+
+// removePendingOperationsBefore calls remove for each of the selected
+// pending operation entries, see DeletePendingOperationsBefore. The
+// remove function must take the entry out of the pending operations
+// bucket.
+func removePendingOperationsBefore(db wdb.DB,
+	statuses map[OperationStatus]bool, before int64,
+	batchSize int, keep func(id string) bool,
+	remove func(*bolt.Tx, *PendingOperationEntry) error) (int, error) {
+
+	godbc.Require(batchSize > 0)
+
+	removed := 0
+	last := ""
+	for done := false; !done; {
+		n := 0
+		err := db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte(BOLTDB_BUCKET_PENDING_OPS))
+			if b == nil {
+				return ErrDbAccess
+			}
+			c := b.Cursor()
+			var k []byte
+			if last == "" {
+				k, _ = c.First()
+			} else {
+				// the last key may have been removed by the previous batch
+				k, _ = c.Seek([]byte(last))
+				if k != nil && string(k) == last {
+					k, _ = c.Next()
+				}
+			}
+			ids := []string{}
+			for ; k != nil && len(ids) < batchSize; k, _ = c.Next() {
+				ids = append(ids, string(k))
+			}
+			done = (k == nil)
+
+			for _, id := range ids {
+				last = id
+				pop, err := NewPendingOperationEntryFromId(tx, id)
+				if err != nil {
+					return err
+				}
+				if pop.Status == NewOperation || !statuses[pop.Status] ||
+					pop.Timestamp >= before || (keep != nil && keep(id)) {
+					continue
+				}
+				marked, err := pop.marksPendingEntries(tx)
+				if err != nil {
+					return err
+				}
+				if marked {
+					logger.Info("Keeping %v pending operation %v (%v):"+
+						" entries are still marked pending by it",
+						pop.Status, pop.Id, pop.Type.Name())
+					continue
+				}
+				if err := remove(tx, pop); err != nil {
+					return err
+				}
+				n++
+			}
+			return nil
+		})
+		if err != nil {
+			return removed, err
+		}
+		removed += n
+	}
+	return removed, nil
+}
+
+// marksPendingEntries returns true if a volume, brick or block volume
+// changed by the actions of the pending operation entry is still
+// marked pending by it.
+func (p *PendingOperationEntry) marksPendingEntries(tx *bolt.Tx) (bool, error) {
+	for _, action := range p.Actions {
+		var pending PendingItem
+		var err error
+		switch action.Change {
+		case OpAddBrick, OpDeleteBrick:
+			var b *BrickEntry
+			if b, err = NewBrickEntryFromId(tx, action.Id); err == nil {
+				pending = b.Pending
+			}
+		case OpAddVolume, OpDeleteVolume, OpCloneVolume, OpSnapshotVolume, OpAddVolumeClone:
+			var v *VolumeEntry
+			if v, err = NewVolumeEntryFromId(tx, action.Id); err == nil {
+				pending = v.Pending
+			}
+		case OpAddBlockVolume, OpDeleteBlockVolume:
+			var bv *BlockVolumeEntry
+			if bv, err = NewBlockVolumeEntryFromId(tx, action.Id); err == nil {
+				pending = bv.Pending
+			}
+		default:
+			continue
+		}
+		if err == ErrNotFound {
+			continue
+		} else if err != nil {
+			return false, err
+		}
+		if pending.Id == p.Id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (p *PendingOperationEntry) consistencyCheck(db Db) (response DbEntryCheckResponse) {
 
 	for _, action := range p.Actions {
@@ -460,17 +790,27 @@ func (p *PendingOperationEntry) consistencyCheck(db Db) (response DbEntryCheckRe
 			if p.Id != db.BlockVolumes[action.Id].Pending.Id {
 				response.Inconsistencies = append(response.Inconsistencies, fmt.Sprintf("pending op %v id in change missing %v not found in blockvolumes", p.Id, action.Id))
 			}
-		case OpExpandVolume:
+		case OpExpandVolume, OpVolumeAclConfig, OpRepairVolume, OpSetVolumeTranslators:
 			if _, found := db.Volumes[action.Id]; !found {
 				response.Inconsistencies = append(response.Inconsistencies,
 					fmt.Sprintf("pending op %v: change id missing %v not found in volumes", p.Id, action.Id))
 			}
-		case OpExpandBlockVolume:
+		case OpExpandBlockVolume, OpMigrateBlockGateway:
 			if _, found := db.BlockVolumes[action.Id]; !found {
 				response.Inconsistencies = append(response.Inconsistencies,
 					fmt.Sprintf("pending op %v: change id missing %v not found in blockvolumes", p.Id, action.Id))
 			}
-		case OpRemoveDevice:
+		case OpSetSnapshotPolicy:
+			if _, found := db.Clusters[action.Id]; !found {
+				response.Inconsistencies = append(response.Inconsistencies,
+					fmt.Sprintf("pending op %v: change id missing %v not found in clusters", p.Id, action.Id))
+			}
+		case OpReinitializeDevice:
+			if _, found := db.Devices[action.Id]; !found {
+				response.Inconsistencies = append(response.Inconsistencies,
+					fmt.Sprintf("pending op %v: change id missing %v not found in devices", p.Id, action.Id))
+			}
+		case OpRemoveDevice, OpAddDevice:
 			// This is a noop
 		default:
 			response.Inconsistencies = append(response.Inconsistencies, fmt.Sprintf("Pending Op %v unexpected change type %v", p.Id, action.Change))
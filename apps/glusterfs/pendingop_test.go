@@ -10,6 +10,9 @@
 package glusterfs
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"testing"
 
 	"github.com/heketi/tests"
@@ -28,6 +31,12 @@ func TestPendingOperationTypeName(t *testing.T) {
 		{OperationDeleteBlockVolume, "delete-block-volume"},
 		{OperationRemoveDevice, "remove-device"},
 		{OperationCloneVolume, "clone-volume"},
+		{OperationRepairVolume, "repair-volume"},
+		{OperationImportVolume, "import-volume"},
+		{OperationReplaceDevice, "replace-device"},
+		{OperationSetSnapshotPolicy, "set-snapshot-policy"},
+		{OperationReinitializeDevice, "reinitialize-device"},
+		{OperationAddDevices, "add-devices"},
 		{OperationUnknown, "unknown"},
 		{nope, "unknown"},
 	}
@@ -38,6 +47,43 @@ func TestPendingOperationTypeName(t *testing.T) {
 	}
 }
 
+func TestPendingOperationTypeIsWrite(t *testing.T) {
+	vals := []PendingOperationType{
+		OperationCreateVolume,
+		OperationDeleteVolume,
+		OperationExpandVolume,
+		OperationCreateBlockVolume,
+		OperationDeleteBlockVolume,
+		OperationExpandBlockVolume,
+		OperationRemoveDevice,
+		OperationCloneVolume,
+		OperationBrickEvict,
+		OperationVolumeAclConfig,
+		OperationMigrateBlockGateway,
+		OperationRepairVolume,
+		OperationImportVolume,
+		OperationReplaceDevice,
+		OperationSetSnapshotPolicy,
+		OperationReinitializeDevice,
+		OperationAddDevices,
+	}
+
+	for _, v := range vals {
+		tests.Assert(t, v.IsWriteOperation(),
+			"expected IsWriteOperation for", v.Name())
+		tests.Assert(t, !v.IsReadOperation(),
+			"expected !IsReadOperation for", v.Name())
+	}
+
+	nope := PendingOperationType(9999)
+	for _, v := range []PendingOperationType{OperationUnknown, nope} {
+		tests.Assert(t, !v.IsWriteOperation(),
+			"expected !IsWriteOperation for", v)
+		tests.Assert(t, !v.IsReadOperation(),
+			"expected !IsReadOperation for", v)
+	}
+}
+
 func TestPendingChangeTypeName(t *testing.T) {
 	nope := PendingChangeType(9999)
 	vals := []struct {
@@ -55,6 +101,10 @@ func TestPendingChangeTypeName(t *testing.T) {
 		{OpCloneVolume, "Clone volume from"},
 		{OpSnapshotVolume, "Snapshot volume"},
 		{OpAddVolumeClone, "Expand volume to"},
+		{OpRepairVolume, "Repair volume"},
+		{OpSetSnapshotPolicy, "Set snapshot policy"},
+		{OpReinitializeDevice, "Reinitialize device"},
+		{OpAddDevice, "Add device"},
 		{OpUnknown, "Unknown"},
 		{nope, "Unknown"},
 	}
@@ -64,3 +114,59 @@ func TestPendingChangeTypeName(t *testing.T) {
 			"expected", v.name, "got", v.p.Name())
 	}
 }
+
+// definedConsts returns the names of the constants of the given type
+// declared in pendingop.go.
+func definedConsts(t *testing.T, typeName string) []string {
+	f, err := parser.ParseFile(token.NewFileSet(), "pendingop.go", nil, 0)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	names := []string{}
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.CONST || len(gd.Specs) == 0 {
+			continue
+		}
+		// the type is given in the first spec of an iota block
+		vs := gd.Specs[0].(*ast.ValueSpec)
+		if id, ok := vs.Type.(*ast.Ident); !ok || id.Name != typeName {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			for _, n := range spec.(*ast.ValueSpec).Names {
+				names = append(names, n.Name)
+			}
+		}
+	}
+	return names
+}
+
+func TestAllOperations(t *testing.T) {
+	consts := definedConsts(t, "PendingOperationType")
+	tests.Assert(t, len(consts) > 1, "expected constants, got:", consts)
+
+	all := AllOperations()
+	tests.Assert(t, len(all) == len(consts)-1,
+		"expected", len(consts)-1, "operation types, got:", len(all),
+		"(AllOperations must list every constant but OperationUnknown)")
+	for i, v := range all {
+		tests.Assert(t, v == PendingOperationType(i+1),
+			"expected", PendingOperationType(i+1), "got:", v)
+		tests.Assert(t, v.Name() != "unknown", "expected name for", v)
+	}
+}
+
+func TestAllChangeTypes(t *testing.T) {
+	consts := definedConsts(t, "PendingChangeType")
+	tests.Assert(t, len(consts) > 1, "expected constants, got:", consts)
+
+	all := AllChangeTypes()
+	tests.Assert(t, len(all) == len(consts)-1,
+		"expected", len(consts)-1, "change types, got:", len(all),
+		"(AllChangeTypes must list every constant but OpUnknown)")
+	for i, v := range all {
+		tests.Assert(t, v == PendingChangeType(i+1),
+			"expected", PendingChangeType(i+1), "got:", v)
+		tests.Assert(t, v.Name() != "Unknown", "expected name for", v)
+	}
+}
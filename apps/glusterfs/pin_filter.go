@@ -0,0 +1,96 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+)
+
+// PinnedNodesFilter returns a device filter that only accepts devices
+// on the given nodes.
+func PinnedNodesFilter(nodeIds []string) DeviceFilter {
+	pinned := map[string]bool{}
+	for _, id := range nodeIds {
+		pinned[id] = true
+	}
+	return func(bs *BrickSet, d *DeviceEntry) bool {
+		return pinned[d.NodeId]
+	}
+}
+
+// PinnedNodesClusters returns the ids of the clusters the given nodes
+// belong to. ErrNotFound is returned if a node does not exist.
+func PinnedNodesClusters(tx *bolt.Tx, nodeIds []string) ([]string, error) {
+	clusters := []string{}
+	seen := map[string]bool{}
+	for _, nodeId := range nodeIds {
+		n, err := NewNodeEntryFromId(tx, nodeId)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[n.Info.ClusterId] {
+			seen[n.Info.ClusterId] = true
+			clusters = append(clusters, n.Info.ClusterId)
+		}
+	}
+	return clusters, nil
+}
+
+// PinnedNodesFit checks if the online devices of the pinned nodes of
+// the volume can hold bricks of the volume of the given size in KiB.
+// This is an estimate: an empty string only means that the placer may
+// succeed, otherwise the reason the bricks do not fit is returned.
+func PinnedNodesFit(tx *bolt.Tx, v *VolumeEntry, size uint64) (string, error) {
+	sets, brickSize, err := v.Durability.BrickSizeGenerator(size)()
+	if err != nil {
+		return "", err
+	}
+	bricksInSet := v.Durability.BricksInSet()
+
+	nodes := 0
+	var free uint64
+	for _, nodeId := range v.Info.PinnedNodeIds {
+		n, err := NewNodeEntryFromId(tx, nodeId)
+		if err != nil {
+			return "", err
+		}
+		if !n.isOnline() {
+			continue
+		}
+		var nodeFree uint64
+		for _, deviceId := range n.Devices {
+			d, err := NewDeviceEntryFromId(tx, deviceId)
+			if err != nil {
+				return "", err
+			}
+			if d.isOnline() {
+				nodeFree += d.Info.Storage.Free
+			}
+		}
+		if nodeFree > 0 {
+			nodes++
+			free += nodeFree
+		}
+	}
+
+	// the bricks of a brick set are placed on different nodes
+	if nodes < bricksInSet {
+		return fmt.Sprintf("%v pinned nodes have online devices, "+
+			"a brick set needs %v", nodes, bricksInSet), nil
+	}
+	needed := uint64(sets*bricksInSet) * brickSize
+	if free < needed {
+		return fmt.Sprintf("pinned nodes have %v KiB free, "+
+			"bricks need %v KiB", free, needed), nil
+	}
+	return "", nil
+}
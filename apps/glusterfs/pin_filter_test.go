@@ -0,0 +1,257 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// assertBricksOnNodes checks that all bricks of the volume are on
+// devices of the given nodes.
+func assertBricksOnNodes(t *testing.T, app *App, volumeId string, nodeIds []string) {
+	nodes := map[string]bool{}
+	for _, id := range nodeIds {
+		nodes[id] = true
+	}
+	err := app.db.View(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, volumeId)
+		if err != nil {
+			return err
+		}
+		for _, brickId := range v.BricksIds() {
+			b, err := NewBrickEntryFromId(tx, brickId)
+			if err != nil {
+				return err
+			}
+			tests.Assert(t, nodes[b.Info.NodeId],
+				"expected brick on pinned nodes, got node:", b.Info.NodeId)
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func sampleNodeIds(t *testing.T, app *App) []string {
+	var nodeIds []string
+	err := app.db.View(func(tx *bolt.Tx) error {
+		var err error
+		nodeIds, err = NodeList(tx)
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	return nodeIds
+}
+
+func TestVolumeCreatePinnedPlacement(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		6,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	pinned := sampleNodeIds(t, app)[:3]
+
+	for i := 0; i < 4; i++ {
+		v := createSampleReplicaVolumeEntry(100, 3)
+		v.Info.PinnedNodeIds = pinned
+		err := v.Create(app.db, app.executor)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		assertBricksOnNodes(t, app, v.Info.Id, pinned)
+	}
+
+	// the pinned nodes are full, other nodes are not used
+	v := createSampleReplicaVolumeEntry(800, 3)
+	v.Info.PinnedNodeIds = pinned
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == ErrNoSpace, "expected err == ErrNoSpace, got:", err)
+}
+
+func TestVolumeCreatePinnedHttp(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		6,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	nodeIds := sampleNodeIds(t, app)
+	pinned := nodeIds[:3]
+
+	post := func(size int, nodes []string) *http.Response {
+		req := &api.VolumeCreateRequest{}
+		req.Size = size
+		req.Durability.Type = api.DurabilityReplicate
+		req.Durability.Replicate.Replica = 3
+		req.PinnedNodeIds = nodes
+		b, err := json.Marshal(req)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		r, err := http.Post(ts.URL+"/volumes", "application/json",
+			bytes.NewReader(b))
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		return r
+	}
+
+	// the pinned nodes hold 1TB each which is not enough
+	r := post(1500, pinned)
+	tests.Assert(t, r.StatusCode == 422, "expected 422, got:", r.StatusCode)
+
+	// a brick set needs three nodes
+	r = post(10, nodeIds[:2])
+	tests.Assert(t, r.StatusCode == 422, "expected 422, got:", r.StatusCode)
+
+	// unknown nodes are rejected
+	r = post(10, []string{"0123456789abcdef0123456789abcdef"})
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest,
+		"expected 400, got:", r.StatusCode)
+
+	c := client.NewClientNoAuth(ts.URL)
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+	req.PinnedNodeIds = pinned
+	info, err := c.VolumeCreate(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(info.PinnedNodeIds) == 3,
+		"expected len(info.PinnedNodeIds) == 3, got:", info.PinnedNodeIds)
+	assertBricksOnNodes(t, app, info.Id, pinned)
+}
+
+func TestVolumePin(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		2,      // clusters
+		6,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var pinned, others []string
+	err = app.db.View(func(tx *bolt.Tx) error {
+		cl, err := ClusterList(tx)
+		if err != nil {
+			return err
+		}
+		for _, clusterId := range cl {
+			c, err := NewClusterEntryFromId(tx, clusterId)
+			if err != nil {
+				return err
+			}
+			if clusterId == v.Info.Cluster {
+				pinned = c.Info.Nodes[:3]
+			} else {
+				others = c.Info.Nodes
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+
+	// nodes of another cluster can not be pinned
+	_, err = c.VolumePin(v.Info.Id, &api.VolumePinRequest{
+		PinnedNodeIds: others[:3],
+	})
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	info, err := c.VolumePin(v.Info.Id, &api.VolumePinRequest{
+		PinnedNodeIds: pinned,
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(info.PinnedNodeIds) == 3,
+		"expected len(info.PinnedNodeIds) == 3, got:", info.PinnedNodeIds)
+
+	// new bricks of the volume respect the updated pinning
+	before := map[string]bool{}
+	for _, b := range info.Bricks {
+		before[b.Id] = true
+	}
+	err = app.db.View(func(tx *bolt.Tx) error {
+		v, err = NewVolumeEntryFromId(tx, v.Info.Id)
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = v.Expand(app.db, app.executor, 100)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	nodes := map[string]bool{}
+	for _, id := range pinned {
+		nodes[id] = true
+	}
+	added := 0
+	app.db.View(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		for _, brickId := range v.BricksIds() {
+			if before[brickId] {
+				continue
+			}
+			added++
+			b, err := NewBrickEntryFromId(tx, brickId)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+			tests.Assert(t, nodes[b.Info.NodeId],
+				"expected new brick on pinned nodes, got node:", b.Info.NodeId)
+		}
+		return nil
+	})
+	tests.Assert(t, added == 3, "expected added == 3, got:", added)
+
+	// an empty list removes the pinning
+	info, err = c.VolumePin(v.Info.Id, &api.VolumePinRequest{})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(info.PinnedNodeIds) == 0,
+		"expected len(info.PinnedNodeIds) == 0, got:", info.PinnedNodeIds)
+}
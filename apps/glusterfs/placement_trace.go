@@ -0,0 +1,147 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// namedDeviceFilter is a device filter along with the reason given
+// when the filter rejects a device.
+type namedDeviceFilter struct {
+	reason string
+	filter DeviceFilter
+}
+
+// tracePlacement evaluates every device of the cluster against the
+// conditions used to place bricks of the given size for the volume
+// and returns a trace entry per device. Filters that depend on the
+// other bricks of a set are evaluated against an empty brick set.
+// The trace does not modify the db.
+func (v *VolumeEntry) tracePlacement(db wdb.RODB,
+	cluster string, brickSize uint64) ([]api.PlacementTrace, error) {
+
+	trace := []api.PlacementTrace{}
+	err := db.View(func(tx *bolt.Tx) error {
+		dsrc := NewClusterDeviceSource(tx, cluster)
+		filters, err := v.deviceFilters(wdb.WrapTx(tx), dsrc)
+		if err != nil {
+			return err
+		}
+		if len(v.Info.RequiredRegions) > 0 {
+			drm, err := NewDeviceRegionMapFromSource(
+				dsrc, v.Info.RequiredRegions)
+			switch err {
+			case nil:
+				filters = append(filters,
+					namedDeviceFilter{"not in a required region", drm.Filter})
+			case ErrNoStorage, ErrEmptyCluster:
+				// no device is usable, the checks below say why
+			default:
+				return err
+			}
+		}
+
+		c, err := NewClusterEntryFromId(tx, cluster)
+		if err != nil {
+			return err
+		}
+		nodeUp := currentNodeHealthStatus()
+		snapFactor := float64(v.Info.Snapshot.Factor)
+		bs := NewBrickSet(v.Durability.BricksInSet())
+		for _, nodeId := range c.Info.Nodes {
+			n, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			up, found := nodeUp[nodeId]
+			unhealthy := found && !up
+			for _, deviceId := range n.Devices {
+				d, err := NewDeviceEntryFromId(tx, deviceId)
+				if err != nil {
+					return err
+				}
+				t := api.PlacementTrace{
+					DeviceId: deviceId,
+					NodeId:   nodeId,
+				}
+				switch {
+				case !n.isOnline():
+					t.Reason = "node is not online"
+				case unhealthy:
+					t.Reason = "node is not healthy"
+				case !d.isOnline():
+					t.Reason = "device is not online"
+				default:
+					t.Reason = rejectedBy(filters, bs, d)
+				}
+				if t.Reason == "" {
+					needed := d.SpaceNeeded(brickSize, snapFactor).Total
+					if !d.StorageCheck(needed) {
+						t.Reason = fmt.Sprintf(
+							"insufficient free space: %v KiB free, %v KiB needed",
+							d.Info.Storage.Free, needed)
+					}
+				}
+				t.Rejected = t.Reason != ""
+				trace = append(trace, t)
+			}
+		}
+		return nil
+	})
+	return trace, err
+}
+
+// rejectedBy returns the reason of the first filter rejecting the
+// device or an empty string if all filters accept it.
+func rejectedBy(filters []namedDeviceFilter,
+	bs *BrickSet, d *DeviceEntry) string {
+
+	for _, f := range filters {
+		if !f.filter(bs, d) {
+			return f.reason
+		}
+	}
+	return ""
+}
+
+// placementTraceOrWarn returns the trace of placing bricks of the
+// given size in the cluster. Failing to trace the placement is not
+// fatal to the allocation, nil is returned in that case.
+func (v *VolumeEntry) placementTraceOrWarn(db wdb.RODB,
+	cluster string, brickSize uint64) []api.PlacementTrace {
+
+	trace, err := v.tracePlacement(db, cluster, brickSize)
+	if err != nil {
+		logger.Warning("Unable to trace placement of volume %v: %v",
+			v.Info.Id, err)
+		return nil
+	}
+	return trace
+}
+
+func (v *VolumeEntry) logPlacementTrace(trace []api.PlacementTrace) {
+	for _, t := range trace {
+		logger.Debug("Placement of volume %v on device %v (node %v): "+
+			"rejected=%v %v", v.Info.Id, t.DeviceId, t.NodeId,
+			t.Rejected, t.Reason)
+	}
+}
+
+// PlacementTrace returns the devices considered, and rejected, by the
+// last failed attempt to allocate the bricks of the volume.
+func (v *VolumeEntry) PlacementTrace() []api.PlacementTrace {
+	return v.placementTrace
+}
@@ -0,0 +1,151 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+func TestVolumeCreatePlacementTraceNoSpace(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,     // clusters
+		3,     // nodes_per_cluster
+		2,     // devices_per_node,
+		10*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	devices := map[string]string{}
+	err = app.db.View(func(tx *bolt.Tx) error {
+		dl, err := DeviceList(tx)
+		if err != nil {
+			return err
+		}
+		for _, id := range dl {
+			d, err := NewDeviceEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			devices[id] = d.NodeId
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// no device is large enough for even the smallest brick
+	c := client.NewClientNoAuth(ts.URL)
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+	_, err = c.VolumeCreate(req)
+	assertErrorCode(t, err, api.ErrorInsufficientSpace)
+
+	detail := err.(*api.ErrorResponse).Detail
+	trace, ok := detail["placement_trace"].([]interface{})
+	tests.Assert(t, ok, "expected placement_trace detail, got:", detail)
+	tests.Assert(t, len(trace) == len(devices),
+		"expected len(trace) == len(devices), got:", len(trace), len(devices))
+	for _, x := range trace {
+		e := x.(map[string]interface{})
+		deviceId := e["device_id"].(string)
+		tests.Assert(t, devices[deviceId] == e["node_id"],
+			"expected node", devices[deviceId], "got:", e["node_id"])
+		tests.Assert(t, e["rejected"] == true, "expected rejected device:", e)
+		reason := e["reason"].(string)
+		tests.Assert(t, strings.Contains(reason, "insufficient free space"),
+			`expected "insufficient free space" in reason, got:`, reason)
+	}
+}
+
+func TestTracePlacement(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		1,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var clusterId, offlineDevice, pinnedNode string
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		cl, err := ClusterList(tx)
+		if err != nil {
+			return err
+		}
+		clusterId = cl[0]
+		dl, err := DeviceList(tx)
+		if err != nil {
+			return err
+		}
+		d, err := NewDeviceEntryFromId(tx, dl[0])
+		if err != nil {
+			return err
+		}
+		d.State = api.EntryStateOffline
+		offlineDevice = d.Info.Id
+		if err := d.Save(tx); err != nil {
+			return err
+		}
+		d, err = NewDeviceEntryFromId(tx, dl[1])
+		if err != nil {
+			return err
+		}
+		pinnedNode = d.NodeId
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	v.Info.PinnedNodeIds = []string{pinnedNode}
+	trace, err := v.tracePlacement(app.db, clusterId, 50*GB)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(trace) == 3, "expected len(trace) == 3, got:", len(trace))
+	for _, e := range trace {
+		switch {
+		case e.DeviceId == offlineDevice:
+			tests.Assert(t, e.Rejected && e.Reason == "device is not online",
+				"expected offline device rejected, got:", e)
+		case e.NodeId == pinnedNode:
+			tests.Assert(t, !e.Rejected && e.Reason == "",
+				"expected device on pinned node accepted, got:", e)
+		default:
+			tests.Assert(t, e.Rejected && e.Reason == "node not pinned",
+				"expected device on other node rejected, got:", e)
+		}
+	}
+}
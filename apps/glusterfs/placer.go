@@ -9,6 +9,10 @@
 
 package glusterfs
 
+import (
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
 type DeviceAndNode struct {
 	Device *DeviceEntry
 	Node   *NodeEntry
@@ -52,6 +56,9 @@ type PlacementOpts interface {
 	SetCount() int
 	// AverageFileSize returns the average file size for the volume
 	AverageFileSize() uint64
+	// AccessPattern returns the expected access pattern of the
+	// volume the bricks belong to.
+	AccessPattern() api.VolumeAccessPattern
 }
 
 // DeviceFilter functions can be defined by the caller of a
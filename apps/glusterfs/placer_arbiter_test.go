@@ -15,6 +15,8 @@ import (
 	"testing"
 
 	"github.com/heketi/tests"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
 )
 
 type TestDeviceSource struct {
@@ -109,6 +111,7 @@ type TestPlacementOpts struct {
 	setSize         int
 	setCount        int
 	averageFileSize uint64
+	accessPattern   api.VolumeAccessPattern
 }
 
 func (tpo *TestPlacementOpts) BrickSizes() (uint64, float64) {
@@ -135,6 +138,10 @@ func (tpo *TestPlacementOpts) AverageFileSize() uint64 {
 	return tpo.averageFileSize
 }
 
+func (tpo *TestPlacementOpts) AccessPattern() api.VolumeAccessPattern {
+	return tpo.accessPattern
+}
+
 func TestTestDeviceSource(t *testing.T) {
 	dsrc := NewTestDeviceSource()
 	dsrc.QuickAdd(
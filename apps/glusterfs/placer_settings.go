@@ -19,4 +19,13 @@ const (
 
 var (
 	ZoneChecking = ZONE_CHECKING_NONE
+
+	// PreferLowLatencyNodes causes the allocator to favor devices on
+	// nodes with a lower p99 ssh latency when the free space of the
+	// devices is comparable.
+	PreferLowLatencyNodes = false
+
+	// ClusterSelection orders the clusters tried for volumes that do
+	// not request specific clusters.
+	ClusterSelection ClusterSelector = NewWeightedClusterSelector()
 )
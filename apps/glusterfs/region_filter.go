@@ -0,0 +1,103 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+)
+
+// RegionNoSpaceError is returned when a volume requires bricks in
+// a region that has no devices able to hold them.
+type RegionNoSpaceError struct {
+	Region string
+}
+
+func (e *RegionNoSpaceError) Error() string {
+	return fmt.Sprintf(
+		"No nodes with sufficient free space in required region %q",
+		e.Region)
+}
+
+// DeviceRegionMap tracks the regions of devices in order to place
+// at least one brick of every brick set in each required region.
+type DeviceRegionMap struct {
+	RequiredRegions []string
+	DeviceRegions   map[string]string
+	// largest amount of free space on any one device in the region
+	RegionFree map[string]uint64
+}
+
+func NewDeviceRegionMap(required []string) *DeviceRegionMap {
+	return &DeviceRegionMap{
+		RequiredRegions: required,
+		DeviceRegions:   map[string]string{},
+		RegionFree:      map[string]uint64{},
+	}
+}
+
+func NewDeviceRegionMapFromSource(dsrc DeviceSource,
+	required []string) (*DeviceRegionMap, error) {
+
+	drm := NewDeviceRegionMap(required)
+	dnl, err := dsrc.Devices()
+	if err != nil {
+		return nil, err
+	}
+	for _, dan := range dnl {
+		drm.Add(dan.Device.Info.Id,
+			dan.Node.Info.Region,
+			dan.Device.Info.Storage.Free)
+	}
+	return drm, nil
+}
+
+func (drm *DeviceRegionMap) Add(deviceId, region string, free uint64) {
+	drm.DeviceRegions[deviceId] = region
+	if free >= drm.RegionFree[region] {
+		drm.RegionFree[region] = free
+	}
+}
+
+// CheckSpace returns an error if any required region lacks a
+// device with at least size bytes free.
+func (drm *DeviceRegionMap) CheckSpace(size uint64) error {
+	for _, r := range drm.RequiredRegions {
+		free, found := drm.RegionFree[r]
+		if !found || free < size {
+			return &RegionNoSpaceError{Region: r}
+		}
+	}
+	return nil
+}
+
+// Filter rejects devices that would leave too few slots in the
+// brick set to cover the required regions not yet in use.
+func (drm *DeviceRegionMap) Filter(bs *BrickSet, d *DeviceEntry) bool {
+	contents := bs.Contents()
+	regionsUsed := map[string]bool{}
+	for _, b := range contents {
+		regionsUsed[drm.DeviceRegions[b.Info.DeviceId]] = true
+	}
+	missing := 0
+	dregion := drm.DeviceRegions[d.Info.Id]
+	for _, r := range drm.RequiredRegions {
+		if regionsUsed[r] {
+			continue
+		}
+		if r == dregion {
+			// this device covers a missing region
+			return true
+		}
+		missing++
+	}
+	// slots left in the set after this device is used
+	remaining := bs.SetSize - len(contents) - 1
+	return remaining >= missing
+}
@@ -0,0 +1,159 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/tests"
+)
+
+func setupSampleDbRegions(t *testing.T, app *App, regions ...string) {
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		4,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		nl, err := NodeList(tx)
+		if err != nil {
+			return err
+		}
+		for i, nodeId := range nl {
+			node, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			node.Info.Region = regions[i%len(regions)]
+			if err := node.Save(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestVolumeCreateRequiredRegions(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	setupSampleDbRegions(t, app, "us-east", "us-west")
+
+	for i := 0; i < 5; i++ {
+		v := createSampleReplicaVolumeEntry(100, 2)
+		v.Info.RequiredRegions = []string{"us-east", "us-west"}
+		err := v.Create(app.db, app.executor)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(v.Bricks) == 2,
+			"expected len(v.Bricks) == 2, got:", len(v.Bricks))
+
+		regions := map[string]int{}
+		err = app.db.View(func(tx *bolt.Tx) error {
+			for _, brickId := range v.BricksIds() {
+				brick, err := NewBrickEntryFromId(tx, brickId)
+				if err != nil {
+					return err
+				}
+				node, err := NewNodeEntryFromId(tx, brick.Info.NodeId)
+				if err != nil {
+					return err
+				}
+				regions[node.Info.Region]++
+			}
+			return nil
+		})
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, regions["us-east"] == 1,
+			"expected one brick in us-east, got:", regions)
+		tests.Assert(t, regions["us-west"] == 1,
+			"expected one brick in us-west, got:", regions)
+	}
+}
+
+func TestVolumeCreateRequiredRegionMissing(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	setupSampleDbRegions(t, app, "us-east", "us-west")
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	v.Info.RequiredRegions = []string{"us-east", "us-west", "eu-central"}
+	err := v.Create(app.db, app.executor)
+	tests.Assert(t, err != nil, "expected err != nil")
+	rerr, ok := err.(*RegionNoSpaceError)
+	tests.Assert(t, ok, "expected RegionNoSpaceError, got:", err)
+	tests.Assert(t, rerr.Region == "eu-central",
+		`expected rerr.Region == "eu-central", got:`, rerr.Region)
+
+	// no bricks were left behind
+	var bricks []string
+	err = app.db.View(func(tx *bolt.Tx) error {
+		bricks = EntryKeys(tx, BOLTDB_BUCKET_BRICK)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(bricks) == 0,
+		"expected len(bricks) == 0, got:", len(bricks))
+}
+
+func TestDeviceRegionMapFilter(t *testing.T) {
+	drm := NewDeviceRegionMap([]string{"east", "west"})
+	drm.Add("d1", "east", 100)
+	drm.Add("d2", "east", 100)
+	drm.Add("d3", "west", 50)
+	drm.Add("d4", "", 100)
+
+	tests.Assert(t, drm.CheckSpace(50) == nil,
+		"expected CheckSpace(50) == nil")
+	err := drm.CheckSpace(60)
+	rerr, ok := err.(*RegionNoSpaceError)
+	tests.Assert(t, ok, "expected RegionNoSpaceError, got:", err)
+	tests.Assert(t, rerr.Region == "west",
+		`expected rerr.Region == "west", got:`, rerr.Region)
+
+	dev := func(id string) *DeviceEntry {
+		d := NewDeviceEntry()
+		d.Info.Id = id
+		return d
+	}
+	brick := func(deviceId string) *BrickEntry {
+		return NewBrickEntry(1, 1, 0, deviceId, "n"+deviceId, 0, "")
+	}
+
+	// replica 2: first brick may go anywhere in a required region
+	bs := NewBrickSet(2)
+	tests.Assert(t, drm.Filter(bs, dev("d1")))
+	tests.Assert(t, drm.Filter(bs, dev("d3")))
+	// a device outside the required regions leaves too few slots
+	tests.Assert(t, !drm.Filter(bs, dev("d4")))
+
+	// second brick must cover the remaining region
+	bs.Add(brick("d1"))
+	tests.Assert(t, !drm.Filter(bs, dev("d2")))
+	tests.Assert(t, drm.Filter(bs, dev("d3")))
+
+	// replica 3 leaves one extra slot for any device
+	bs = NewBrickSet(3)
+	bs.Add(brick("d1"))
+	tests.Assert(t, drm.Filter(bs, dev("d2")))
+	tests.Assert(t, drm.Filter(bs, dev("d4")))
+}
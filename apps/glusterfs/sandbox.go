@@ -0,0 +1,175 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors/mockexec"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// sandboxBuildFunc creates the operation to simulate using the
+// cloned db.
+type sandboxBuildFunc func(db wdb.DB) (Operation, error)
+
+// sandboxCloneDb copies the contents of the db to a new temporary
+// bolt db. The returned function closes and removes the clone.
+func sandboxCloneDb(db wdb.DB) (*bolt.DB, func(), error) {
+	fp, err := ioutil.TempFile("", "heketi-sandbox-")
+	if err != nil {
+		return nil, nil, err
+	}
+	path := fp.Name()
+	err = db.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(fp)
+		return err
+	})
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, nil, err
+	}
+
+	clone, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 3 * time.Second})
+	if err != nil {
+		os.Remove(path)
+		return nil, nil, err
+	}
+	return clone, func() {
+		clone.Close()
+		os.Remove(path)
+	}, nil
+}
+
+// sandboxBuckets converts a db dump into a map of bucket name to
+// the JSON encoding of each entry in the bucket.
+func sandboxBuckets(dump Db) (map[string]map[string]json.RawMessage, error) {
+	b, err := json.Marshal(dump)
+	if err != nil {
+		return nil, err
+	}
+	buckets := map[string]map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// sandboxDiff returns the entries that differ between the two db
+// dumps sorted by bucket and id.
+func sandboxDiff(before, after Db) ([]api.SandboxChange, error) {
+	bb, err := sandboxBuckets(before)
+	if err != nil {
+		return nil, err
+	}
+	ab, err := sandboxBuckets(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for name := range bb {
+		names[name] = true
+	}
+	for name := range ab {
+		names[name] = true
+	}
+	sortedNames := []string{}
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	changes := []api.SandboxChange{}
+	for _, name := range sortedNames {
+		ids := []string{}
+		for id := range bb[name] {
+			ids = append(ids, id)
+		}
+		for id := range ab[name] {
+			if _, found := bb[name][id]; !found {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			old, inBefore := bb[name][id]
+			cur, inAfter := ab[name][id]
+			switch {
+			case !inBefore:
+				changes = append(changes, api.SandboxChange{
+					Bucket: name, Id: id, Change: "added", After: cur})
+			case !inAfter:
+				changes = append(changes, api.SandboxChange{
+					Bucket: name, Id: id, Change: "removed", Before: old})
+			case !bytes.Equal(old, cur):
+				changes = append(changes, api.SandboxChange{
+					Bucket: name, Id: id, Change: "modified",
+					Before: old, After: cur})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// RunSandboxOperation simulates an operation against a copy of the
+// db using the mock executor and returns the changes the operation
+// made to the copy. The db itself is never modified. A failure of the
+// simulated operation is reported in the response, not as an error.
+func RunSandboxOperation(ctx context.Context, db wdb.DB,
+	opType string, build sandboxBuildFunc) (*api.SandboxOperationResponse, error) {
+
+	clone, cleanup, err := sandboxCloneDb(db)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	before, err := dbDumpInternal(clone)
+	if err != nil {
+		return nil, err
+	}
+	op, err := build(clone)
+	if err != nil {
+		return nil, err
+	}
+	executor, err := mockexec.NewMockExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &api.SandboxOperationResponse{OperationType: opType}
+	if err := RunOperationContext(ctx, op, executor); err != nil {
+		resp.Error = err.Error()
+	}
+
+	after, err := dbDumpInternal(clone)
+	if err != nil {
+		return nil, err
+	}
+	resp.Changes, err = sandboxDiff(before, after)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
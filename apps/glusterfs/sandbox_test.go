@@ -0,0 +1,153 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+func sandboxChanges(changes []api.SandboxChange, bucket, change string) []api.SandboxChange {
+	found := []api.SandboxChange{}
+	for _, c := range changes {
+		if c.Bucket == bucket && c.Change == change {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+func TestSandboxOperationCreateVolume(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	before, err := dbDumpInternal(app.db)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+	params, err := json.Marshal(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+	resp, err := c.SandboxOperation(&api.SandboxOperationRequest{
+		OperationType: api.SandboxCreateVolume,
+		Params:        params,
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, resp.Error == "", "expected no error, got:", resp.Error)
+
+	// the response shows the projected volume and its bricks
+	vols := sandboxChanges(resp.Changes, "volumeentries", "added")
+	tests.Assert(t, len(vols) == 1, "expected len(vols) == 1, got:", vols)
+	var vol VolumeEntry
+	err = json.Unmarshal(vols[0].After, &vol)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, vol.Info.Size == 100,
+		"expected vol.Info.Size == 100, got:", vol.Info.Size)
+	tests.Assert(t, vol.Info.Id == vols[0].Id,
+		"expected vol.Info.Id == vols[0].Id, got:", vol.Info.Id, vols[0].Id)
+	bricks := sandboxChanges(resp.Changes, "brickentries", "added")
+	tests.Assert(t, len(bricks) == 3, "expected len(bricks) == 3, got:", bricks)
+	devices := sandboxChanges(resp.Changes, "deviceentries", "modified")
+	tests.Assert(t, len(devices) == 3, "expected len(devices) == 3, got:", devices)
+	ops := sandboxChanges(resp.Changes, "pendingoperations", "added")
+	tests.Assert(t, len(ops) == 0, "expected len(ops) == 0, got:", ops)
+
+	// the production db is unchanged
+	after, err := dbDumpInternal(app.db)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, reflect.DeepEqual(before, after),
+		"expected db to be unchanged")
+	tests.Assert(t, len(after.Volumes) == 0,
+		"expected len(after.Volumes) == 0, got:", len(after.Volumes))
+}
+
+func TestSandboxOperationDeleteVolume(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		3,      // nodes_per_cluster
+		2,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+	params, err := json.Marshal(&api.SandboxVolumeParams{Id: v.Info.Id})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	resp, err := c.SandboxOperation(&api.SandboxOperationRequest{
+		OperationType: api.SandboxDeleteVolume,
+		Params:        params,
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, resp.Error == "", "expected no error, got:", resp.Error)
+	vols := sandboxChanges(resp.Changes, "volumeentries", "removed")
+	tests.Assert(t, len(vols) == 1 && vols[0].Id == v.Info.Id,
+		"expected volume to be removed, got:", vols)
+	bricks := sandboxChanges(resp.Changes, "brickentries", "removed")
+	tests.Assert(t, len(bricks) == 3, "expected len(bricks) == 3, got:", bricks)
+
+	// the volume still exists
+	_, err = c.VolumeInfo(v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// unknown volumes are not found
+	params, err = json.Marshal(&api.SandboxVolumeParams{
+		Id: "0123456789abcdef0123456789abcdef",
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, err = c.SandboxOperation(&api.SandboxOperationRequest{
+		OperationType: api.SandboxDeleteVolume,
+		Params:        params,
+	})
+	tests.Assert(t, err != nil, "expected err != nil")
+}
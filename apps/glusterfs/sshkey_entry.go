@@ -0,0 +1,269 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/lpabon/godbc"
+
+	"github.com/heketi/heketi/executors/sshexec"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/idgen"
+)
+
+const (
+	BOLTDB_BUCKET_SSHKEY = "SSHKEY"
+)
+
+var (
+	ErrSshKeyStoreDisabled = errors.New(
+		"ssh key store is disabled, no ssh_key_encryption_key configured")
+	ErrSshKeyDecrypt = errors.New("Unable to decrypt ssh key")
+)
+
+// SshKeyEntry stores a per-node ssh key. The private key and its
+// passphrase are only ever stored in encrypted form.
+type SshKeyEntry struct {
+	Info    api.SshKeyInfo
+	Created int64
+	Secret  []byte
+}
+
+// sshKeySecret is the encrypted content of an ssh key entry.
+type sshKeySecret struct {
+	PrivateKey string
+	Passphrase string
+}
+
+func SshKeyList(tx *bolt.Tx) ([]string, error) {
+	list := EntryKeys(tx, BOLTDB_BUCKET_SSHKEY)
+	if list == nil {
+		return nil, ErrAccessList
+	}
+	return list, nil
+}
+
+func NewSshKeyEntry() *SshKeyEntry {
+	return &SshKeyEntry{}
+}
+
+func NewSshKeyEntryFromRequest(req *api.SshKeyRequest,
+	encKey []byte) (*SshKeyEntry, error) {
+
+	godbc.Require(req != nil)
+
+	entry := NewSshKeyEntry()
+	entry.Info.Id = idgen.GenUUID()
+	entry.Created = time.Now().UnixNano()
+	if err := entry.Update(req, encKey); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func NewSshKeyEntryFromId(tx *bolt.Tx, id string) (*SshKeyEntry, error) {
+	entry := NewSshKeyEntry()
+	err := EntryLoad(tx, entry, id)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// SshKeyEntries returns all the ssh key entries in the db in
+// priority order: the oldest key first.
+func SshKeyEntries(tx *bolt.Tx) ([]*SshKeyEntry, error) {
+	ids, err := SshKeyList(tx)
+	if err != nil {
+		return nil, err
+	}
+	entries := []*SshKeyEntry{}
+	for _, id := range ids {
+		entry, err := NewSshKeyEntryFromId(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Created < entries[j].Created
+	})
+	return entries, nil
+}
+
+func (s *SshKeyEntry) BucketName() string {
+	return BOLTDB_BUCKET_SSHKEY
+}
+
+func (s *SshKeyEntry) Save(tx *bolt.Tx) error {
+	godbc.Require(tx != nil)
+	godbc.Require(len(s.Info.Id) > 0)
+
+	return EntrySave(tx, s, s.Info.Id)
+}
+
+func (s *SshKeyEntry) Delete(tx *bolt.Tx) error {
+	return EntryDelete(tx, s, s.Info.Id)
+}
+
+func (s *SshKeyEntry) Marshal() ([]byte, error) {
+	var buffer bytes.Buffer
+	enc := gob.NewEncoder(&buffer)
+	err := enc.Encode(*s)
+
+	return buffer.Bytes(), err
+}
+
+func (s *SshKeyEntry) Unmarshal(buffer []byte) error {
+	dec := gob.NewDecoder(bytes.NewReader(buffer))
+	return dec.Decode(s)
+}
+
+// Update replaces the pattern and the key material of the entry.
+func (s *SshKeyEntry) Update(req *api.SshKeyRequest, encKey []byte) error {
+	var buffer bytes.Buffer
+	enc := gob.NewEncoder(&buffer)
+	err := enc.Encode(sshKeySecret{
+		PrivateKey: req.PrivateKey,
+		Passphrase: req.Passphrase,
+	})
+	if err != nil {
+		return err
+	}
+	secret, err := sealSecret(encKey, buffer.Bytes())
+	if err != nil {
+		return err
+	}
+	s.Info.NodePattern = req.NodePattern
+	s.Secret = secret
+	return nil
+}
+
+// SshKey returns the decrypted key in the form used by the executor.
+func (s *SshKeyEntry) SshKey(encKey []byte) (sshexec.SshKey, error) {
+	k := sshexec.SshKey{
+		Id:          s.Info.Id,
+		NodePattern: s.Info.NodePattern,
+	}
+	plain, err := openSecret(encKey, s.Secret)
+	if err != nil {
+		return k, err
+	}
+	var secret sshKeySecret
+	dec := gob.NewDecoder(bytes.NewReader(plain))
+	if err := dec.Decode(&secret); err != nil {
+		return k, err
+	}
+	k.PrivateKey = secret.PrivateKey
+	k.Passphrase = secret.Passphrase
+	return k, nil
+}
+
+// sshKeyEncryptionKey derives the AES-256 key used to encrypt ssh
+// keys in the db from the configured passphrase.
+func sshKeyEncryptionKey(s string) []byte {
+	if s == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(s))
+	return sum[:]
+}
+
+func sealSecret(key, plain []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+func openSecret(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrSshKeyDecrypt
+	}
+	nonce, data := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, ErrSshKeyDecrypt
+	}
+	return plain, nil
+}
+
+// sshKeyInvalidator is implemented by the executors that cache the
+// ssh keys stored in the db.
+type sshKeyInvalidator interface {
+	InvalidateSshKeys()
+}
+
+// sshKeysChanged drops the cached ssh keys after keys were added,
+// updated or deleted.
+func (a *App) sshKeysChanged() {
+	if a.sshKeyCache != nil {
+		a.sshKeyCache.InvalidateSshKeys()
+	}
+}
+
+// sshKeyStore provides the ssh keys stored in the db to the
+// ssh executor.
+type sshKeyStore struct {
+	app *App
+}
+
+func (ks *sshKeyStore) SshKeys() ([]sshexec.SshKey, error) {
+	keys := []sshexec.SshKey{}
+	err := ks.app.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(BOLTDB_BUCKET_SSHKEY)) == nil {
+			// read-only db from an older version
+			return nil
+		}
+		entries, err := SshKeyEntries(tx)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			k, err := entry.SshKey(ks.app.sshKeyEncKey)
+			if err != nil {
+				logger.LogError("Unable to load ssh key %v: %v",
+					entry.Info.Id, err)
+				continue
+			}
+			keys = append(keys, k)
+		}
+		return nil
+	})
+	return keys, err
+}
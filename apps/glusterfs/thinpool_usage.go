@@ -0,0 +1,224 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/paths"
+)
+
+const (
+	// default data usage (percent) of the thin pools of a device
+	// above which a warning is logged
+	THINPOOL_WARN_THRESHOLD = 80.0
+)
+
+// thinPoolProbe is a node and the vgs of its devices.
+type thinPoolProbe struct {
+	host string
+	// vg name -> device id
+	vgs map[string]string
+}
+
+// parseLvmSize parses a size as reported by lvm with a unit suffix,
+// such as "1024.00k", into a number in the units of the suffix.
+func parseLvmSize(s string) (float64, error) {
+	s = strings.TrimLeft(strings.TrimSpace(s), "<>")
+	s = strings.TrimRight(s, "bBkKmMgGtTpPeE")
+	return strconv.ParseFloat(s, 64)
+}
+
+// thinPoolUsage computes the data usage, in percent, of the thin pools
+// in each of the given vgs from the lvs report. The usage of a vg is
+// the data used by all of its thin pools relative to their total
+// size. Vgs without any thin pools are not included in the result.
+func thinPoolUsage(lvs *executors.LVSCommandOutput,
+	vgs map[string]string) map[string]float64 {
+
+	size := map[string]float64{}
+	used := map[string]float64{}
+	for _, report := range lvs.LVSReport {
+		for _, lv := range report.LVS {
+			deviceId, found := vgs[lv.VGName]
+			// thin pools are marked with a 't' as volume type
+			if !found || !strings.HasPrefix(lv.LVAttr, "t") {
+				continue
+			}
+			lvSize, err := parseLvmSize(lv.LVSize)
+			if err != nil {
+				logger.Warning("Unable to parse size of lv %v/%v: %v",
+					lv.VGName, lv.LVName, err)
+				continue
+			}
+			pct, err := strconv.ParseFloat(strings.TrimSpace(lv.DataPercent), 64)
+			if err != nil {
+				logger.Warning("Unable to parse data usage of lv %v/%v: %v",
+					lv.VGName, lv.LVName, err)
+				continue
+			}
+			size[deviceId] += lvSize
+			used[deviceId] += lvSize * pct / 100
+		}
+	}
+
+	usage := map[string]float64{}
+	for deviceId, s := range size {
+		if s > 0 {
+			usage[deviceId] = used[deviceId] * 100 / s
+		}
+	}
+	return usage
+}
+
+// ThinPoolUsageCache periodically queries lvm on each online node
+// for the actual data usage of the thin pools of the bricks, which
+// can be far lower than the space allocated for them in heketi.
+type ThinPoolUsageCache struct {
+	// tunables
+	StartInterval time.Duration
+	CheckInterval time.Duration
+	WarnThreshold float64
+
+	db      wdb.RODB
+	exec    executors.Executor
+	devices map[string]float64
+	lock    sync.RWMutex
+
+	// to stop the monitor
+	stop chan<- interface{}
+}
+
+func NewThinPoolUsageCache(reftime, starttime uint32, db wdb.RODB, e executors.Executor) *ThinPoolUsageCache {
+	return &ThinPoolUsageCache{
+		db:            db,
+		exec:          e,
+		devices:       map[string]float64{},
+		StartInterval: time.Second * time.Duration(starttime),
+		CheckInterval: time.Second * time.Duration(reftime),
+		WarnThreshold: THINPOOL_WARN_THRESHOLD,
+	}
+}
+
+// Status returns a map of device ids to the data usage of the thin
+// pools on the device in percent.
+func (tc *ThinPoolUsageCache) Status() map[string]float64 {
+	tc.lock.RLock()
+	defer tc.lock.RUnlock()
+	out := map[string]float64{}
+	for k, v := range tc.devices {
+		out[k] = v
+	}
+	return out
+}
+
+func (tc *ThinPoolUsageCache) Refresh() error {
+	logger.Debug("Starting Thin Pool Usage refresh")
+	probes, err := tc.toProbe()
+	if err != nil {
+		return err
+	}
+	devices := map[string]float64{}
+	for nodeId, p := range probes {
+		lvs, err := tc.exec.LVS(p.host)
+		if err != nil {
+			logger.Warning("Unable to get lvs of node %v: %v", nodeId, err)
+			continue
+		}
+		for deviceId, pct := range thinPoolUsage(lvs, p.vgs) {
+			if pct > tc.WarnThreshold {
+				logger.Warning(
+					"Thin pools of device %v are %.2f%% full (threshold %.2f%%)",
+					deviceId, pct, tc.WarnThreshold)
+			}
+			devices[deviceId] = pct
+		}
+	}
+
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+	tc.devices = devices
+	return nil
+}
+
+func (tc *ThinPoolUsageCache) Monitor() {
+	startTimer := time.NewTimer(tc.StartInterval)
+	ticker := time.NewTicker(tc.CheckInterval)
+	stop := make(chan interface{})
+	tc.stop = stop
+
+	go func() {
+		logger.Info("Started Thin Pool Usage Monitor")
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				logger.Info("Stopping Thin Pool Usage Monitor")
+				return
+			case <-startTimer.C:
+				err := tc.Refresh()
+				if err != nil {
+					logger.LogError("Thin Pool Usage Monitor: %v", err.Error())
+				}
+			case <-ticker.C:
+				err := tc.Refresh()
+				if err != nil {
+					logger.LogError("Thin Pool Usage Monitor: %v", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+func (tc *ThinPoolUsageCache) Stop() {
+	tc.stop <- true
+}
+
+// toProbe returns a map of online node ids to the management hostname
+// and the vgs of the devices of the node.
+func (tc *ThinPoolUsageCache) toProbe() (map[string]*thinPoolProbe, error) {
+	probes := map[string]*thinPoolProbe{}
+	err := tc.db.View(func(tx *bolt.Tx) error {
+		n, err := NodeList(tx)
+		if err != nil {
+			return err
+		}
+		for _, nodeId := range n {
+			if strings.HasPrefix(nodeId, "MANAGE") ||
+				strings.HasPrefix(nodeId, "STORAGE") {
+				continue
+			}
+			node, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			if !node.isOnline() || len(node.Devices) == 0 {
+				continue
+			}
+			p := &thinPoolProbe{
+				host: node.ManageHostName(),
+				vgs:  map[string]string{},
+			}
+			for _, deviceId := range node.Devices {
+				p.vgs[paths.VgIdToName(deviceId)] = deviceId
+			}
+			probes[nodeId] = p
+		}
+		return nil
+	})
+	return probes, err
+}
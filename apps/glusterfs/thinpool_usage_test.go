@@ -0,0 +1,123 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+)
+
+// lvsReport returns the output of "lvs --reportformat json --units k"
+// for a vg with two thin pools and the thin lvs of the bricks.
+func lvsReport(vg string) string {
+	return fmt.Sprintf(`{
+      "report": [
+          {
+              "lv": [
+                  {"lv_name":"brick_a", "vg_name":"%[1]v", "lv_attr":"Vwi-aotz--", "lv_size":"1048576.00k", "pool_lv":"tp_a", "origin":"", "data_percent":"50.00", "metadata_percent":"", "move_pv":"", "mirror_log":"", "copy_percent":"", "convert_lv":""},
+                  {"lv_name":"tp_a", "vg_name":"%[1]v", "lv_attr":"twi-aotz--", "lv_size":"1048576.00k", "pool_lv":"", "origin":"", "data_percent":"50.00", "metadata_percent":"1.20", "move_pv":"", "mirror_log":"", "copy_percent":"", "convert_lv":""},
+                  {"lv_name":"brick_b", "vg_name":"%[1]v", "lv_attr":"Vwi-aotz--", "lv_size":"3145728.00k", "pool_lv":"tp_b", "origin":"", "data_percent":"100.00", "metadata_percent":"", "move_pv":"", "mirror_log":"", "copy_percent":"", "convert_lv":""},
+                  {"lv_name":"tp_b", "vg_name":"%[1]v", "lv_attr":"twi-aotz--", "lv_size":"3145728.00k", "pool_lv":"", "origin":"", "data_percent":"100.00", "metadata_percent":"5.00", "move_pv":"", "mirror_log":"", "copy_percent":"", "convert_lv":""},
+                  {"lv_name":"root", "vg_name":"rhel", "lv_attr":"-wi-ao----", "lv_size":"52428800.00k", "pool_lv":"", "origin":"", "data_percent":"", "metadata_percent":"", "move_pv":"", "mirror_log":"", "copy_percent":"", "convert_lv":""}
+              ]
+          }
+      ]
+  }`, vg)
+}
+
+func TestThinPoolUsage(t *testing.T) {
+	var lvs executors.LVSCommandOutput
+	err := json.Unmarshal([]byte(lvsReport("vg_abc")), &lvs)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	usage := thinPoolUsage(&lvs, map[string]string{"vg_abc": "abc"})
+	tests.Assert(t, len(usage) == 1, "expected len(usage) == 1, got:", usage)
+	// (1GiB * 50% + 3GiB * 100%) / 4GiB
+	tests.Assert(t, usage["abc"] == 87.5,
+		"expected usage[abc] == 87.5, got:", usage["abc"])
+
+	// vgs of other devices are ignored
+	usage = thinPoolUsage(&lvs, map[string]string{"vg_def": "def"})
+	tests.Assert(t, len(usage) == 0, "expected len(usage) == 0, got:", usage)
+}
+
+func TestThinPoolUsageCacheRefresh(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var deviceId, host string
+	app.db.View(func(tx *bolt.Tx) error {
+		dl, err := DeviceList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		deviceId = dl[0]
+		d, err := NewDeviceEntryFromId(tx, deviceId)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		n, err := NewNodeEntryFromId(tx, d.NodeId)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		host = n.ManageHostName()
+		return nil
+	})
+
+	queried := 0
+	app.xo.MockLVS = func(h string) (*executors.LVSCommandOutput, error) {
+		queried++
+		var lvs executors.LVSCommandOutput
+		if h == host {
+			err := json.Unmarshal([]byte(lvsReport("vg_"+deviceId)), &lvs)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		}
+		return &lvs, nil
+	}
+
+	tc := NewThinPoolUsageCache(1, 0, app.db, app.executor)
+	err = tc.Refresh()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, queried == 3, "expected queried == 3, got:", queried)
+	status := tc.Status()
+	tests.Assert(t, len(status) == 1, "expected len(status) == 1, got:", status)
+	tests.Assert(t, status[deviceId] == 87.5,
+		"expected status[deviceId] == 87.5, got:", status[deviceId])
+
+	// the usage is reported with the device info
+	currentThinPoolUsageCache = tc
+	defer func() { currentThinPoolUsageCache = nil }()
+	c := client.NewClientNoAuth(ts.URL)
+	info, err := c.DeviceInfo(deviceId)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.ThinPoolUsedPercent == 87.5,
+		"expected info.ThinPoolUsedPercent == 87.5, got:",
+		info.ThinPoolUsedPercent)
+}
@@ -0,0 +1,74 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// Value of the tier tag marking a device as part of that tier.
+const TAG_VAL_TIER_ENABLED string = "true"
+
+// hasTier returns true if the merged node and device tags place
+// the device in the given tier.
+func hasTier(n *NodeEntry, d *DeviceEntry, tier api.VolumeTier) bool {
+	return MergeTags(n, d)[string(tier)] == TAG_VAL_TIER_ENABLED
+}
+
+// TierFilter returns a device filter that only accepts devices
+// tagged as belonging to the given tier.
+func TierFilter(dsrc DeviceSource, tier api.VolumeTier) DeviceFilter {
+	return func(bs *BrickSet, d *DeviceEntry) bool {
+		n, err := dsrc.Node(d.NodeId)
+		if err != nil {
+			logger.LogError("failed to fetch node (%v) in tier filter: %v",
+				d.NodeId, err)
+			return false
+		}
+		return hasTier(n, d, tier)
+	}
+}
+
+// TierAvailable returns true if at least one device in the given
+// clusters carries the label of the tier. If no clusters are given
+// all clusters are checked.
+func TierAvailable(tx *bolt.Tx, clusters []string, tier api.VolumeTier) (bool, error) {
+	if len(clusters) == 0 {
+		var err error
+		clusters, err = ClusterList(tx)
+		if err != nil {
+			return false, err
+		}
+	}
+	for _, clusterId := range clusters {
+		cluster, err := NewClusterEntryFromId(tx, clusterId)
+		if err != nil {
+			return false, err
+		}
+		for _, nodeId := range cluster.Info.Nodes {
+			node, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return false, err
+			}
+			for _, deviceId := range node.Devices {
+				device, err := NewDeviceEntryFromId(tx, deviceId)
+				if err != nil {
+					return false, err
+				}
+				if hasTier(node, device, tier) {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,175 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+// setupSampleDbTiers creates a topology where the first half of
+// every node's devices are gold and the others are silver.
+func setupSampleDbTiers(t *testing.T, app *App) {
+	err := setupSampleDbWithTopology(app,
+		1,      // clusters
+		4,      // nodes_per_cluster
+		4,      // devices_per_node,
+		500*GB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		nl, err := NodeList(tx)
+		if err != nil {
+			return err
+		}
+		for _, nodeId := range nl {
+			node, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			for i, deviceId := range node.Devices {
+				device, err := NewDeviceEntryFromId(tx, deviceId)
+				if err != nil {
+					return err
+				}
+				tier := api.VolumeTierGold
+				if i >= len(node.Devices)/2 {
+					tier = api.VolumeTierSilver
+				}
+				device.SetTags(map[string]string{
+					string(tier): TAG_VAL_TIER_ENABLED,
+				})
+				if err := device.Save(tx); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestVolumeCreateTierPlacement(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	setupSampleDbTiers(t, app)
+
+	for _, tier := range []api.VolumeTier{
+		api.VolumeTierGold, api.VolumeTierSilver} {
+
+		for i := 0; i < 4; i++ {
+			v := createSampleReplicaVolumeEntry(100, 3)
+			v.Info.Tier = tier
+			err := v.Create(app.db, app.executor)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+			err = app.db.View(func(tx *bolt.Tx) error {
+				for _, brickId := range v.BricksIds() {
+					brick, err := NewBrickEntryFromId(tx, brickId)
+					if err != nil {
+						return err
+					}
+					device, err := NewDeviceEntryFromId(tx, brick.Info.DeviceId)
+					if err != nil {
+						return err
+					}
+					tests.Assert(t,
+						device.Info.Tags[string(tier)] == TAG_VAL_TIER_ENABLED,
+						"expected brick on", tier, "device, got tags:",
+						device.Info.Tags)
+				}
+				return nil
+			})
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		}
+	}
+}
+
+func TestTierAvailable(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	setupSampleDbTiers(t, app)
+
+	checks := map[api.VolumeTier]bool{
+		api.VolumeTierGold:   true,
+		api.VolumeTierSilver: true,
+		api.VolumeTierBronze: false,
+	}
+	err := app.db.View(func(tx *bolt.Tx) error {
+		for tier, expected := range checks {
+			ok, err := TierAvailable(tx, nil, tier)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+			tests.Assert(t, ok == expected,
+				"expected", expected, "for", tier, "got:", ok)
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestVolumeCreateTierUnavailable(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	setupSampleDbTiers(t, app)
+
+	request := []byte(`{
+        "size" : 100,
+        "tier" : "bronze"
+    }`)
+	r, err := http.Post(ts.URL+"/volumes", "application/json",
+		bytes.NewBuffer(request))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == 422,
+		"expected r.StatusCode == 422, got:", r.StatusCode)
+	body, err := utils.GetStringFromResponse(r)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, strings.Contains(body, "No devices available in tier bronze"),
+		"unexpected body:", body)
+
+	// unknown tiers are rejected by validation
+	request = []byte(`{
+        "size" : 100,
+        "tier" : "platinum"
+    }`)
+	r, err = http.Post(ts.URL+"/volumes", "application/json",
+		bytes.NewBuffer(request))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest,
+		"expected r.StatusCode == http.StatusBadRequest, got:", r.StatusCode)
+}
@@ -0,0 +1,58 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"github.com/boltdb/bolt"
+)
+
+// TspFilter returns a device filter that only accepts devices on
+// nodes of the given trusted storage pool. The bricks of a gluster
+// volume must all be hosted by peers of the same pool.
+func TspFilter(dsrc DeviceSource, tspId string) DeviceFilter {
+	return func(bs *BrickSet, d *DeviceEntry) bool {
+		n, err := dsrc.Node(d.NodeId)
+		if err != nil {
+			logger.LogError("failed to fetch node (%v) in pool filter: %v",
+				d.NodeId, err)
+			return false
+		}
+		return n.Info.TspId == tspId
+	}
+}
+
+// TspAvailable returns true if at least one node in the given
+// clusters is part of the trusted storage pool. If no clusters are
+// given all clusters are checked.
+func TspAvailable(tx *bolt.Tx, clusters []string, tspId string) (bool, error) {
+	if len(clusters) == 0 {
+		var err error
+		clusters, err = ClusterList(tx)
+		if err != nil {
+			return false, err
+		}
+	}
+	for _, clusterId := range clusters {
+		cluster, err := NewClusterEntryFromId(tx, clusterId)
+		if err != nil {
+			return false, err
+		}
+		for _, nodeId := range cluster.Info.Nodes {
+			node, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return false, err
+			}
+			if node.Info.TspId == tspId {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,191 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+func TestTrustedStoragePools(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	// Create the app
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	var lock sync.Mutex
+	probes := map[string]string{}
+	app.xo.MockPeerProbe = func(exec_host, newnode string) error {
+		lock.Lock()
+		defer lock.Unlock()
+		probes[newnode] = exec_host
+		return nil
+	}
+
+	c := client.NewClientNoAuth(ts.URL)
+	cluster, err := c.ClusterCreate(&api.ClusterCreateRequest{
+		ClusterFlags: api.ClusterFlags{Block: true, File: true},
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// three nodes in each of two pools, the pool id is part of the
+	// hostnames of the nodes
+	tsp := map[string]string{}
+	for _, tspId := range []string{"tsp1", "tsp2"} {
+		for i := 0; i < 3; i++ {
+			req := &api.NodeAddRequest{
+				Zone:      i + 1,
+				ClusterId: cluster.Id,
+				TspId:     tspId,
+			}
+			req.Hostnames.Manage = []string{
+				fmt.Sprintf("manage-%v-%v", tspId, i)}
+			req.Hostnames.Storage = []string{
+				fmt.Sprintf("storage-%v-%v", tspId, i)}
+			node, err := c.NodeAdd(req)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+			tests.Assert(t, node.TspId == tspId,
+				"expected", tspId, "got:", node.TspId)
+			tsp[node.Id] = tspId
+
+			err = c.DeviceAdd(&api.DeviceAddRequest{
+				Device: api.Device{
+					Name: "/dev/sdb",
+				},
+				NodeId: node.Id,
+			})
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		}
+	}
+
+	// the first node of each pool is not probed, the others are
+	// probed from a node of their own pool
+	tests.Assert(t, len(probes) == 4, "expected 4 probes, got:", probes)
+	for newnode, host := range probes {
+		tspId := strings.Split(newnode, "-")[1]
+		tests.Assert(t, strings.HasPrefix(host, "manage-"+tspId+"-"),
+			"node", newnode, "probed from", host)
+	}
+
+	info, err := c.ClusterInfo(cluster.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(info.TrustedStoragePools) == 2,
+		"expected 2 pools, got:", info.TrustedStoragePools)
+	for i, tspId := range []string{"tsp1", "tsp2"} {
+		pool := info.TrustedStoragePools[i]
+		tests.Assert(t, pool.Id == tspId, "expected", tspId, "got:", pool.Id)
+		tests.Assert(t, len(pool.PeerNodes) == 3,
+			"expected 3 nodes, got:", pool.PeerNodes)
+		for _, nodeId := range pool.PeerNodes {
+			tests.Assert(t, tsp[nodeId] == tspId,
+				"expected node", nodeId, "in", tspId, "got:", tsp[nodeId])
+		}
+	}
+
+	// volumes are created within their pool and do not probe peers
+	volumeHosts := []string{}
+	app.xo.MockVolumeCreate = func(host string,
+		volume *executors.VolumeRequest) (*executors.Volume, error) {
+		volumeHosts = append(volumeHosts, host)
+		return &executors.Volume{}, nil
+	}
+	for i := 0; i < 2; i++ {
+		req := &api.VolumeCreateRequest{}
+		req.Size = 10
+		req.Durability.Type = api.DurabilityReplicate
+		req.Durability.Replicate.Replica = 3
+		req.TspId = "tsp1"
+		vol, err := c.VolumeCreate(req)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, vol.TspId == "tsp1", "expected tsp1, got:", vol.TspId)
+		tests.Assert(t, len(vol.Bricks) == 3,
+			"expected 3 bricks, got:", len(vol.Bricks))
+		for _, b := range vol.Bricks {
+			tests.Assert(t, tsp[b.NodeId] == "tsp1",
+				"expected brick on tsp1 node, got:", tsp[b.NodeId])
+		}
+	}
+	tests.Assert(t, len(probes) == 4, "expected 4 probes, got:", probes)
+	tests.Assert(t, len(volumeHosts) == 2,
+		"expected 2 volume creates, got:", volumeHosts)
+	for _, host := range volumeHosts {
+		tests.Assert(t, strings.HasPrefix(host, "manage-tsp1-"),
+			"expected volume created from a tsp1 node, got:", host)
+	}
+
+	// unknown pools are rejected
+	req := &api.VolumeCreateRequest{}
+	req.Size = 10
+	req.TspId = "tsp3"
+	_, err = c.VolumeCreate(req)
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "No nodes in trusted storage pool tsp3"),
+		"unexpected error:", err)
+}
+
+func TestTspFilterDefaultPool(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	err = app.db.View(func(tx *bolt.Tx) error {
+		cids, err := ClusterList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		dsrc := NewClusterDeviceSource(tx, cids[0])
+		dnl, err := dsrc.Devices()
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+		// nodes without a pool are all in the default pool
+		inDefault := TspFilter(dsrc, "")
+		inOther := TspFilter(dsrc, "tsp1")
+		for _, dan := range dnl {
+			tests.Assert(t, inDefault(nil, dan.Device),
+				"expected device in default pool")
+			tests.Assert(t, !inOther(nil, dan.Device),
+				"expected device not in tsp1")
+		}
+
+		ok, err := TspAvailable(tx, nil, "tsp1")
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, !ok, "expected tsp1 not available")
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
@@ -0,0 +1,78 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/pkg/paths"
+)
+
+// setupVgNameTemplate parses the vg name template of the configured
+// executor. Starting the app fails if the template is invalid or does
+// not produce unique names.
+func (app *App) setupVgNameTemplate() error {
+	var text string
+	switch app.conf.Executor {
+	case "kube", "kubernetes":
+		text = app.conf.KubeConfig.VgNameTemplate
+	case "ssh", "", "inject/ssh":
+		text = app.conf.SshConfig.VgNameTemplate
+	}
+	if text == "" {
+		return nil
+	}
+	t, err := paths.NewVgNameTemplate(text)
+	if err != nil {
+		return err
+	}
+	logger.Info("Naming vgs of new devices using template %q", text)
+	app.vgNameTemplate = t
+	return nil
+}
+
+// setDeviceVgName names the vg of a new device on the given node
+// using the vg name template, if one is configured. The name is kept
+// in the device entry so that changing the template does not affect
+// existing devices.
+func (app *App) setDeviceVgName(device *DeviceEntry, node *NodeEntry) error {
+	if app.vgNameTemplate == nil {
+		return nil
+	}
+	name, err := app.vgNameTemplate.Name(
+		node.Info.ClusterId, node.Info.Id, device.Info.Id)
+	if err != nil {
+		return err
+	}
+	device.VgName = name
+	paths.SetVgName(device.Info.Id, name)
+	return nil
+}
+
+// loadVgNames records the names of the vgs of the devices in the db
+// that were named by a template.
+func loadVgNames(db *bolt.DB) error {
+	return db.View(func(tx *bolt.Tx) error {
+		dl, err := DeviceList(tx)
+		if err != nil {
+			return err
+		}
+		for _, id := range dl {
+			d, err := NewDeviceEntryFromId(tx, id)
+			if err != nil {
+				return err
+			}
+			if d.VgName != "" {
+				paths.SetVgName(d.Info.Id, d.VgName)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,91 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/paths"
+)
+
+func TestDeviceAddVgNameTemplate(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	vt, err := paths.NewVgNameTemplate("vg-{{.ShortID}}")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	app.vgNameTemplate = vt
+
+	c := client.NewClientNoAuth(ts.URL)
+	cluster, err := c.ClusterCreate(&api.ClusterCreateRequest{
+		ClusterFlags: api.ClusterFlags{Block: true, File: true},
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	nodeReq := &api.NodeAddRequest{ClusterId: cluster.Id, Zone: 1}
+	nodeReq.Hostnames.Manage = []string{"manage"}
+	nodeReq.Hostnames.Storage = []string{"storage"}
+	node, err := c.NodeAdd(nodeReq)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// the vg is set up using the name from the template
+	setupVgName := ""
+	deviceSetup := app.xo.MockDeviceSetup
+	app.xo.MockDeviceSetup = func(host, device, vgid string, destroy bool) (*executors.DeviceInfo, error) {
+		setupVgName = paths.VgIdToName(vgid)
+		return deviceSetup(host, device, vgid, destroy)
+	}
+	deviceReq := &api.DeviceAddRequest{}
+	deviceReq.Name = "/dev/fake1"
+	deviceReq.NodeId = node.Id
+	err = c.DeviceAdd(deviceReq)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var device *DeviceEntry
+	err = app.db.View(func(tx *bolt.Tx) error {
+		n, err := NewNodeEntryFromId(tx, node.Id)
+		if err != nil {
+			return err
+		}
+		device, err = NewDeviceEntryFromId(tx, n.Devices[0])
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	defer paths.SetVgName(device.Info.Id, "")
+
+	expected := "vg-" + device.Info.Id[:8]
+	tests.Assert(t, device.VgName == expected,
+		"expected", expected, "got:", device.VgName)
+	tests.Assert(t, setupVgName == expected,
+		"expected", expected, "got:", setupVgName)
+
+	// the name is restored from the db
+	paths.SetVgName(device.Info.Id, "")
+	err = loadVgNames(app.db)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, paths.VgIdToName(device.Info.Id) == expected,
+		"expected", expected, "got:", paths.VgIdToName(device.Info.Id))
+}
@@ -69,6 +69,13 @@ type VolumeEntry struct {
 	Durability           VolumeDurability `json:"-"`
 	GlusterVolumeOptions []string
 	Pending              PendingItem
+	// the expand operation allocating bricks for the volume, only
+	// one expansion of a volume may be in progress at a time
+	Expanding PendingItem
+
+	// devices rejected by the last failed brick allocation,
+	// not stored in the db
+	placementTrace []api.PlacementTrace
 }
 
 func VolumeList(tx *bolt.Tx) ([]string, error) {
@@ -97,6 +104,12 @@ func NewVolumeEntryFromRequest(req *api.VolumeCreateRequest) *VolumeEntry {
 	vol.Info.Snapshot = req.Snapshot
 	vol.Info.Size = req.Size
 	vol.Info.Block = req.Block
+	vol.Info.RequiredRegions = req.RequiredRegions
+	vol.Info.Tier = req.Tier
+	vol.Info.PinnedNodeIds = req.PinnedNodeIds
+	vol.Info.AccessPattern = req.AccessPattern
+	vol.Info.TspId = req.TspId
+	vol.Info.Labels = copyTags(req.Labels)
 
 	// Set default durability values
 	durability := vol.Info.Durability.Type
@@ -225,10 +238,12 @@ func (v *VolumeEntry) Save(tx *bolt.Tx) error {
 	godbc.Require(tx != nil)
 	godbc.Require(len(v.Info.Id) > 0)
 
+	invalidateVolumeInfo(tx, v.Info.Id)
 	return EntrySave(tx, v, v.Info.Id)
 }
 
 func (v *VolumeEntry) Delete(tx *bolt.Tx) error {
+	invalidateVolumeInfo(tx, v.Info.Id)
 	return EntryDelete(tx, v, v.Info.Id)
 }
 
@@ -249,6 +264,17 @@ func (v *VolumeEntry) NewInfoResponse(tx *bolt.Tx) (*api.VolumeInfoResponse, err
 	info.Block = v.Info.Block
 	info.BlockInfo = v.Info.BlockInfo
 	info.Gid = v.Info.Gid
+	info.RequiredRegions = v.Info.RequiredRegions
+	info.Tier = v.Info.Tier
+	info.Labels = v.Info.Labels
+	info.PinnedNodeIds = v.Info.PinnedNodeIds
+	info.AccessPattern = v.Info.AccessPattern
+	info.TspId = v.Info.TspId
+	info.ACLConfig = v.Info.ACLConfig
+	info.TranslatorOptions = v.Info.TranslatorOptions
+	if bs, found := currentBitrotStatus()[v.Info.Id]; found {
+		info.Bitrot = &bs
+	}
 
 	for _, brickid := range v.BricksIds() {
 		brick, err := NewBrickEntryFromId(tx, brickid)
@@ -474,6 +500,7 @@ func (v *VolumeEntry) tryAllocateBricks(
 	possibleClusters []string) (brick_entries []*BrickEntry, err error) {
 
 	cerr := ClusterErrorMap{}
+	v.placementTrace = nil
 	for _, cluster := range possibleClusters {
 		// Check this cluster for space
 		brick_entries, err = v.allocBricksInCluster(db, cluster, v.Info.Size)
@@ -495,6 +522,9 @@ func (v *VolumeEntry) tryAllocateBricks(
 			err == ErrNoStorage {
 			logger.Debug("Issue on cluster %v: %v", cluster, err)
 			cerr.Add(cluster, err)
+		} else if _, ok := err.(*RegionNoSpaceError); ok {
+			logger.Debug("Issue on cluster %v: %v", cluster, err)
+			cerr.Add(cluster, err)
 		} else {
 			// A genuine error occurred - bail out
 			logger.LogError("Error calling v.allocBricksInCluster: %v", err)
@@ -602,6 +632,17 @@ func (v *VolumeEntry) createVolumeComponents(
 	if err != nil {
 		return nil, err
 	}
+	if len(v.Info.Clusters) == 0 && ClusterSelection != nil {
+		err = db.View(func(tx *bolt.Tx) error {
+			var err error
+			possibleClusters, err = ClusterSelection.SelectClusters(
+				tx, v, possibleClusters)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 	logger.Debug("Using the following clusters: %+v", possibleClusters)
 
 	return v.saveCreateVolume(db, possibleClusters)
@@ -791,32 +832,6 @@ func (v *VolumeEntry) expandVolumeComponents(db wdb.DB,
 	return
 }
 
-func (v *VolumeEntry) expandVolumeExec(db wdb.DB,
-	executor executors.Executor,
-	brick_entries []*BrickEntry) (e error) {
-
-	// Create bricks
-	err := CreateBricks(db, executor, brick_entries)
-	if err != nil {
-		return err
-	}
-
-	// Create a volume request to send to executor
-	// so that it can add the new bricks
-	vr, host, err := v.createVolumeRequest(db, brick_entries)
-	if err != nil {
-		return err
-	}
-
-	// Expand the volume
-	_, err = executor.VolumeExpand(host, vr)
-	if err != nil {
-		return err
-	}
-
-	return err
-}
-
 func (v *VolumeEntry) Expand(db wdb.DB,
 	executor executors.Executor,
 	sizeGB int) (e error) {
@@ -947,8 +962,8 @@ func eligibleClusters(db wdb.RODB, req clusterReq,
 }
 
 // hosts returns a node-to-host mapping for all nodes in the
-// volume's cluster. These hosts can be used as destinations
-// for gluster commands.
+// volume's cluster and trusted storage pool. These hosts can be
+// used as destinations for gluster commands.
 func (v *VolumeEntry) hosts(db wdb.RODB) (nodeHosts, error) {
 	var hosts nodeHosts
 	err := db.View(func(tx *bolt.Tx) error {
@@ -961,7 +976,7 @@ func (v *VolumeEntry) hosts(db wdb.RODB) (nodeHosts, error) {
 		if err != nil {
 			return err
 		}
-		hosts, err = cluster.hosts(wdb.WrapTx(tx))
+		hosts, err = cluster.tspHosts(wdb.WrapTx(tx), vol.Info.TspId)
 		return err
 	})
 	return hosts, err
@@ -16,6 +16,7 @@ import (
 	"github.com/heketi/heketi/executors"
 	wdb "github.com/heketi/heketi/pkg/db"
 	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/logging"
 )
 
 func (v *VolumeEntry) allocBricksInCluster(db wdb.DB,
@@ -30,10 +31,24 @@ func (v *VolumeEntry) allocBricksInCluster(db wdb.DB,
 	gen := v.Durability.BrickSizeGenerator(size)
 
 	// Try decreasing possible brick sizes until space is found
+	var regionErr error
+	// smallest brick size that did not fit
+	var failedBrickSize uint64
+	traceFailure := func() {
+		if failedBrickSize > 0 {
+			v.placementTrace = append(v.placementTrace,
+				v.placementTraceOrWarn(db, cluster, failedBrickSize)...)
+		}
+	}
 	for {
 		// Determine next possible brick size
 		sets, brick_size, err := gen()
 		if err != nil {
+			if regionErr != nil {
+				// a required region was the limiting factor
+				err = regionErr
+			}
+			traceFailure()
 			logger.Err(err)
 			return nil, err
 		}
@@ -47,13 +62,31 @@ func (v *VolumeEntry) allocBricksInCluster(db wdb.DB,
 		// Check that the volume would not have too many bricks
 		if (num_bricks + len(v.Bricks)) > BrickMaxNum {
 			logger.Debug("Maximum number of bricks reached")
+			traceFailure()
+			if regionErr != nil {
+				// a required region was the limiting factor
+				return nil, regionErr
+			}
 			return nil, ErrMaxBricks
 		}
 
+		// Trace the placement before the allocation changes the devices
+		var trace []api.PlacementTrace
+		if logger.Level() == logging.LEVEL_DEBUG {
+			trace = v.placementTraceOrWarn(db, cluster, brick_size)
+		}
+
 		// Allocate bricks in the cluster
 		brick_entries, err := v.allocBricks(db, cluster, sets, brick_size)
 		if err == ErrNoSpace {
 			logger.Debug("No space, re-trying with smaller brick size")
+			failedBrickSize = brick_size
+			continue
+		}
+		if _, ok := err.(*RegionNoSpaceError); ok {
+			logger.Debug("%v, re-trying with smaller brick size", err)
+			regionErr = err
+			failedBrickSize = brick_size
 			continue
 		}
 		if err != nil {
@@ -62,6 +95,7 @@ func (v *VolumeEntry) allocBricksInCluster(db wdb.DB,
 		}
 
 		// We were able to allocate bricks
+		v.logPlacementTrace(trace)
 		return brick_entries, nil
 	}
 }
@@ -233,7 +267,8 @@ func (v *VolumeEntry) prepForBrickReplacement(db wdb.DB,
 	node = oldBrickNodeEntry.ManageHostName()
 	err = executor.GlusterdCheck(node)
 	if err != nil {
-		node, err = GetVerifiedManageHostname(db, executor, oldBrickNodeEntry.Info.ClusterId)
+		node, err = GetVerifiedTspManageHostname(db, executor,
+			oldBrickNodeEntry.Info.ClusterId, oldBrickNodeEntry.Info.TspId)
 		if err != nil {
 			return
 		}
@@ -261,7 +296,22 @@ func (v *VolumeEntry) prepForBrickReplacement(db wdb.DB,
 
 func (v *VolumeEntry) generateDeviceFilter(db wdb.RODB, dsrc DeviceSource) (DeviceFilter, error) {
 
+	filters, err := v.deviceFilters(db, dsrc)
+	if err != nil {
+		return nil, err
+	}
 	var filter DeviceFilter = nil
+	for _, f := range filters {
+		filter = appendDeviceFilter(filter, f.filter)
+	}
+	return filter, nil
+}
+
+// deviceFilters returns the device filters that apply to the volume
+// along with the reason reported when each of them rejects a device.
+func (v *VolumeEntry) deviceFilters(db wdb.RODB, dsrc DeviceSource) ([]namedDeviceFilter, error) {
+
+	filters := []namedDeviceFilter{}
 	zoneChecking := v.GetZoneCheckingStrategy()
 	if zoneChecking == ZONE_CHECKING_UNSET {
 		zoneChecking = ZoneChecking
@@ -274,7 +324,8 @@ func (v *VolumeEntry) generateDeviceFilter(db wdb.RODB, dsrc DeviceSource) (Devi
 			return nil, err
 		}
 
-		filter = dzm.Filter
+		filters = append(filters,
+			namedDeviceFilter{"zone already used by brick set", dzm.Filter})
 	case ZONE_CHECKING_NONE:
 	default:
 		logger.Warning(
@@ -288,17 +339,43 @@ func (v *VolumeEntry) generateDeviceFilter(db wdb.RODB, dsrc DeviceSource) (Devi
 			"Invalid tag matching rule: %v", err)
 	} else if tagMatchingRule != nil {
 		logger.Debug("Configuring a tag matching device filter")
-		filter = appendDeviceFilter(filter, tagMatchingRule.GetFilter(dsrc))
+		filters = append(filters, namedDeviceFilter{
+			"tags do not match " + HEKETI_TAG_MATCH_KEY,
+			tagMatchingRule.GetFilter(dsrc)})
 	}
 
-	return filter, nil
+	if v.Info.Tier != api.VolumeTierNone {
+		logger.Debug("Configuring a %v tier device filter", v.Info.Tier)
+		filters = append(filters, namedDeviceFilter{
+			fmt.Sprintf("not in tier %v", v.Info.Tier),
+			TierFilter(dsrc, v.Info.Tier)})
+	}
+
+	// bricks must not span trusted storage pools
+	filters = append(filters, namedDeviceFilter{
+		"node not in trusted storage pool of volume",
+		TspFilter(dsrc, v.Info.TspId)})
+
+	if len(v.Info.PinnedNodeIds) > 0 {
+		logger.Debug("Configuring a device filter for pinned nodes %v",
+			v.Info.PinnedNodeIds)
+		filters = append(filters, namedDeviceFilter{
+			"node not pinned",
+			PinnedNodesFilter(v.Info.PinnedNodeIds)})
+	}
+
+	return filters, nil
 }
 
+// allocBrickReplacement places a new brick to replace the old brick
+// in the brick set. If filter is not nil it further restricts the
+// devices the new brick may be placed on.
 func (v *VolumeEntry) allocBrickReplacement(db wdb.DB,
 	oldBrickEntry *BrickEntry,
 	oldDeviceEntry *DeviceEntry,
 	bs *BrickSet,
-	index int) (newBrickEntry *BrickEntry,
+	index int,
+	filter DeviceFilter) (newBrickEntry *BrickEntry,
 	newDeviceEntry *DeviceEntry, err error) {
 
 	var r *BrickAllocation
@@ -315,6 +392,7 @@ func (v *VolumeEntry) allocBrickReplacement(db wdb.DB,
 		if err != nil {
 			return err
 		}
+		defaultFilter = appendDeviceFilter(defaultFilter, filter)
 
 		deviceFilter := func(bs *BrickSet, d *DeviceEntry) bool {
 			if defaultFilter != nil && !defaultFilter(bs, d) {
@@ -370,7 +448,7 @@ func (v *VolumeEntry) replaceBrickInVolume(db wdb.DB, executor executors.Executo
 	oldBrickNodeEntry := ri.oldBrickNodeEntry
 
 	newBrickEntry, newDeviceEntry, err := v.allocBrickReplacement(
-		db, oldBrickEntry, oldDeviceEntry, ri.bs, ri.index)
+		db, oldBrickEntry, oldDeviceEntry, ri.bs, ri.index, nil)
 	if err != nil {
 		return err
 	}
@@ -520,6 +598,17 @@ func (v *VolumeEntry) allocBricks(
 		if err != nil {
 			return err
 		}
+		if len(v.Info.RequiredRegions) > 0 {
+			drm, err := NewDeviceRegionMapFromSource(
+				dsrc, v.Info.RequiredRegions)
+			if err != nil {
+				return err
+			}
+			if err := drm.CheckSpace(brick_size); err != nil {
+				return err
+			}
+			deviceFilter = appendDeviceFilter(deviceFilter, drm.Filter)
+		}
 
 		r, e := placer.PlaceAll(dsrc, opts, deviceFilter)
 		if e != nil {
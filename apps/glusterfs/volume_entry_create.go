@@ -53,7 +53,9 @@ func (v *VolumeEntry) updateHostandMountPoint(hosts []string, deletedHost string
 		strings.Join(hosts[1:], ",")
 }
 
-func getHostsFromCluster(db wdb.RODB, clusterID string) ([]string, error) {
+// getHostsFromCluster returns the storage hostnames of the nodes of
+// the given trusted storage pool of the cluster.
+func getHostsFromCluster(db wdb.RODB, clusterID, tspId string) ([]string, error) {
 	hosts := []string{}
 	if err := db.View(func(tx *bolt.Tx) error {
 		cluster, err := NewClusterEntryFromId(tx, clusterID)
@@ -65,6 +67,9 @@ func getHostsFromCluster(db wdb.RODB, clusterID string) ([]string, error) {
 			if err != nil {
 				return err
 			}
+			if node.Info.TspId != tspId {
+				continue
+			}
 			hosts = append(hosts, node.StorageHostName())
 		}
 		return err
@@ -78,7 +83,7 @@ func (v *VolumeEntry) updateMountInfo(db wdb.RODB, vinfo *api.VolumeInfo) error
 	godbc.Require(v.Info.Cluster != "")
 
 	// Every host in the Gluster Trusted Storage Pool can serve the volfile
-	hosts, err := getHostsFromCluster(db, v.Info.Cluster)
+	hosts, err := getHostsFromCluster(db, v.Info.Cluster, v.Info.TspId)
 	if err != nil {
 		return err
 	}
@@ -10,6 +10,7 @@
 package glusterfs
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -1410,9 +1411,14 @@ func TestVolumeEntryExpandMaxBrickLimit(t *testing.T) {
 	err = v.Create(app.db, app.executor)
 	tests.Assert(t, err == nil)
 
-	// Add a bunch of bricks until the limit
+	// Add a bunch of bricks until the limit, the expansion is
+	// built from the entry in the db
 	fakebricks := make(sort.StringSlice, BrickMaxNum-len(v.Bricks))
 	v.Bricks = append(v.Bricks, fakebricks...)
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		return v.Save(tx)
+	})
+	tests.Assert(t, err == nil)
 
 	// Try to expand the volume, but it will return that the max number
 	// of bricks has been reached
@@ -2693,13 +2699,13 @@ func TestVolumeCreateRollbackSpaceReclaimed(t *testing.T) {
 	vol := NewVolumeEntryFromRequest(req)
 	vc := NewVolumeCreateOperation(vol, app.db)
 
-	e := vc.Build()
+	e := vc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
-	e = vc.Exec(app.executor)
+	e = vc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e != nil, "expected e != nil, got", e)
 
-	e = vc.Rollback(app.executor)
+	e = vc.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	err = app.db.View(func(tx *bolt.Tx) error {
@@ -2765,13 +2771,13 @@ func TestBlockVolumeCreateRollbackSpaceReclaimed(t *testing.T) {
 	bv.Info.Name = "myvol"
 	bc := NewBlockVolumeCreateOperation(bv, app.db)
 
-	e := bc.Build()
+	e := bc.Build(context.Background())
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
-	e = bc.Exec(app.executor)
+	e = bc.Exec(context.Background(), app.executor)
 	tests.Assert(t, e != nil, "expected e != nil, got", e)
 
-	e = bc.Rollback(app.executor)
+	e = bc.Rollback(context.Background(), app.executor)
 	tests.Assert(t, e == nil, "expected e == nil, got", e)
 
 	err = app.db.View(func(tx *bolt.Tx) error {
@@ -0,0 +1,159 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// number of volume info responses kept by default
+	DEFAULT_VOLUME_INFO_CACHE_SIZE = 1000
+)
+
+// VolumeInfoCacheEntry is the serialized info response of a volume.
+type VolumeInfoCacheEntry struct {
+	Data []byte
+	ETag string
+
+	// position in the lru list, guarded by the lock of the cache
+	elem *list.Element
+}
+
+// VolumeInfoCache keeps the serialized info responses of the volumes
+// so that frequent GETs of a volume do not read the db. An entry is
+// dropped once a change to the volume commits. When the cache is full
+// the least recently used entry is evicted.
+type VolumeInfoCache struct {
+	MaxSize int
+
+	entries sync.Map
+	lock    sync.Mutex
+	// volume ids, the most recently used first
+	lru *list.List
+	// incremented by every invalidation
+	generation uint64
+}
+
+func NewVolumeInfoCache(maxSize int) *VolumeInfoCache {
+	return &VolumeInfoCache{
+		MaxSize: maxSize,
+		lru:     list.New(),
+	}
+}
+
+// Generation returns a value to pass to Put. It must be taken before
+// the db is read for the response to be cached.
+func (c *VolumeInfoCache) Generation() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.generation
+}
+
+// Get returns the cached response of the volume, if any.
+func (c *VolumeInfoCache) Get(id string) (*VolumeInfoCacheEntry, bool) {
+	v, ok := c.entries.Load(id)
+	if !ok {
+		return nil, false
+	}
+	e := v.(*VolumeInfoCacheEntry)
+	c.lock.Lock()
+	if e.elem != nil {
+		c.lru.MoveToFront(e.elem)
+	}
+	c.lock.Unlock()
+	return e, true
+}
+
+// Put caches the response of the volume and returns the entry. The
+// response is not cached if anything was invalidated since gen was
+// taken because it may have been read before the change committed.
+func (c *VolumeInfoCache) Put(id string, data []byte, gen uint64) *VolumeInfoCacheEntry {
+	e := &VolumeInfoCacheEntry{
+		Data: data,
+		ETag: fmt.Sprintf("\"%x\"", sha256.Sum256(data)),
+	}
+	if c.MaxSize <= 0 {
+		return e
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if gen != c.generation {
+		return e
+	}
+	if v, ok := c.entries.Load(id); ok {
+		c.lru.Remove(v.(*VolumeInfoCacheEntry).elem)
+	}
+	e.elem = c.lru.PushFront(id)
+	c.entries.Store(id, e)
+	for c.lru.Len() > c.MaxSize {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		c.entries.Delete(oldest.Value.(string))
+	}
+	return e
+}
+
+// Invalidate drops the cached response of the volume.
+func (c *VolumeInfoCache) Invalidate(id string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	if v, ok := c.entries.Load(id); ok {
+		c.lru.Remove(v.(*VolumeInfoCacheEntry).elem)
+		c.entries.Delete(id)
+	}
+}
+
+// InvalidateAll drops the cached responses of all volumes.
+func (c *VolumeInfoCache) InvalidateAll() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		c.entries.Delete(e.Value.(string))
+	}
+	c.lru.Init()
+}
+
+// Len returns the number of cached responses.
+func (c *VolumeInfoCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.lru.Len()
+}
+
+// invalidateVolumeInfo drops the cached info of the volume once the
+// transaction commits.
+func invalidateVolumeInfo(tx *bolt.Tx, id string) {
+	c := currentVolumeInfoCache
+	if c == nil || id == "" {
+		return
+	}
+	tx.OnCommit(func() {
+		c.Invalidate(id)
+	})
+}
+
+// invalidateAllVolumeInfo drops the cached info of all volumes once
+// the transaction commits.
+func invalidateAllVolumeInfo(tx *bolt.Tx) {
+	c := currentVolumeInfoCache
+	if c == nil {
+		return
+	}
+	tx.OnCommit(c.InvalidateAll)
+}
@@ -0,0 +1,206 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+func TestVolumeInfoCacheLRU(t *testing.T) {
+	c := NewVolumeInfoCache(2)
+	c.Put("a", []byte("a"), c.Generation())
+	c.Put("b", []byte("b"), c.Generation())
+
+	// using a makes b the least recently used entry
+	_, ok := c.Get("a")
+	tests.Assert(t, ok, "expected a to be cached")
+	c.Put("c", []byte("c"), c.Generation())
+	tests.Assert(t, c.Len() == 2, "expected c.Len() == 2, got:", c.Len())
+	_, ok = c.Get("b")
+	tests.Assert(t, !ok, "expected b to be evicted")
+	_, ok = c.Get("a")
+	tests.Assert(t, ok, "expected a to be cached")
+	e, ok := c.Get("c")
+	tests.Assert(t, ok, "expected c to be cached")
+	tests.Assert(t, string(e.Data) == "c", "unexpected data:", string(e.Data))
+
+	// replacing an entry does not grow the cache
+	c.Put("c", []byte("cc"), c.Generation())
+	tests.Assert(t, c.Len() == 2, "expected c.Len() == 2, got:", c.Len())
+
+	c.InvalidateAll()
+	tests.Assert(t, c.Len() == 0, "expected c.Len() == 0, got:", c.Len())
+	_, ok = c.Get("a")
+	tests.Assert(t, !ok, "expected a to be dropped")
+}
+
+func TestVolumeInfoCacheStalePut(t *testing.T) {
+	c := NewVolumeInfoCache(10)
+
+	// a response read before an invalidation is not cached
+	gen := c.Generation()
+	c.Invalidate("a")
+	e := c.Put("a", []byte("a"), gen)
+	tests.Assert(t, e.ETag != "", "expected an etag")
+	_, ok := c.Get("a")
+	tests.Assert(t, !ok, "expected a not to be cached")
+
+	c.Put("a", []byte("a"), c.Generation())
+	_, ok = c.Get("a")
+	tests.Assert(t, ok, "expected a to be cached")
+
+	// disabled
+	c = NewVolumeInfoCache(-1)
+	c.Put("a", []byte("a"), c.Generation())
+	_, ok = c.Get("a")
+	tests.Assert(t, !ok, "expected a not to be cached")
+}
+
+func setupVolumeInfoCacheTest(t *testing.T, tmpfile string) (*App, *httptest.Server) {
+	app := NewTestApp(tmpfile)
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+	ts := httptest.NewServer(router)
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		4,    // devices_per_node,
+		6*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	return app, ts
+}
+
+func TestVolumeInfoCacheHit(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts := setupVolumeInfoCacheTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err := v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	r, err := http.Get(ts.URL + "/volumes/" + v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusOK,
+		"expected r.StatusCode == http.StatusOK, got:", r.StatusCode)
+	etag := r.Header.Get("ETag")
+	tests.Assert(t, etag != "", "expected an etag")
+	var info api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &info)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Name == v.Info.Name, "unexpected name:", info.Name)
+
+	// change the db without going through the volume entry, the
+	// cached response is still returned
+	err = app.db.Update(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		if err != nil {
+			return err
+		}
+		entry.Info.Name = "renamed"
+		return EntrySave(tx, entry, entry.Info.Id)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	r, err = http.Get(ts.URL + "/volumes/" + v.Info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.Header.Get("ETag") == etag,
+		"expected the same etag, got:", r.Header.Get("ETag"))
+	err = utils.GetJsonFromResponse(r, &info)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Name == v.Info.Name, "unexpected name:", info.Name)
+
+	// clients holding the current version get no body
+	req, err := http.NewRequest("GET", ts.URL+"/volumes/"+v.Info.Id, nil)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	req.Header.Set("If-None-Match", etag)
+	r, err = http.DefaultClient.Do(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusNotModified,
+		"expected r.StatusCode == http.StatusNotModified, got:", r.StatusCode)
+}
+
+func TestVolumeInfoCacheExpand(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts := setupVolumeInfoCacheTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+
+	c := client.NewClientNoAuth(ts.URL)
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+	info, err := c.VolumeCreate(req)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	info, err = c.VolumeInfo(info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Size == 100, "expected size 100, got:", info.Size)
+	_, ok := app.volinfo.Get(info.Id)
+	tests.Assert(t, ok, "expected the volume info to be cached")
+
+	_, err = c.VolumeExpand(info.Id, &api.VolumeExpandRequest{Size: 50})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	info, err = c.VolumeInfo(info.Id)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Size == 150, "expected size 150, got:", info.Size)
+	tests.Assert(t, len(info.Bricks) == 6,
+		"expected 6 bricks, got:", len(info.Bricks))
+}
+
+func TestVolumeInfoCacheEviction(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	app, ts := setupVolumeInfoCacheTest(t, tmpfile)
+	defer app.Close()
+	defer ts.Close()
+	app.volinfo.MaxSize = 2
+
+	c := client.NewClientNoAuth(ts.URL)
+	ids := []string{}
+	for i := 0; i < 3; i++ {
+		v := createSampleReplicaVolumeEntry(10, 3)
+		err := v.Create(app.db, app.executor)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		_, err = c.VolumeInfo(v.Info.Id)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		ids = append(ids, v.Info.Id)
+	}
+
+	tests.Assert(t, app.volinfo.Len() == 2,
+		"expected app.volinfo.Len() == 2, got:", app.volinfo.Len())
+	_, ok := app.volinfo.Get(ids[0])
+	tests.Assert(t, !ok, "expected the first volume to be evicted")
+	_, ok = app.volinfo.Get(ids[2])
+	tests.Assert(t, ok, "expected the last volume to be cached")
+
+	// evicted volumes are read from the db again
+	info, err := c.VolumeInfo(ids[0])
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Id == ids[0], "unexpected volume:", info.Id)
+}
@@ -0,0 +1,139 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// moveTarget tracks the planned state of a target device.
+type moveTarget struct {
+	device *DeviceEntry
+	free   uint64
+}
+
+// EstimateVolumeMove plans the placement of the bricks of the volume
+// on the given target devices without modifying the db. Bricks already
+// on one of the target devices stay in place. Every other brick is
+// placed on the target device with the most free space remaining that
+// does not already hold as many bricks of the volume as the volume has
+// brick sets. If a brick can not be placed the estimate is not feasible
+// and the reason is reported.
+func EstimateVolumeMove(tx *bolt.Tx, v *VolumeEntry,
+	targetIds []string) (*api.VolumeMoveEstimateResponse, error) {
+
+	est := &api.VolumeMoveEstimateResponse{
+		Feasible:      true,
+		BrickMoves:    []api.VolumeMoveBrick{},
+		RemainingFree: map[string]int64{},
+	}
+	infeasible := func(format string, args ...interface{}) {
+		if est.Feasible {
+			est.Feasible = false
+			est.Reason = fmt.Sprintf(format, args...)
+		}
+	}
+
+	targets := map[string]*moveTarget{}
+	for _, id := range targetIds {
+		if _, ok := targets[id]; ok {
+			continue
+		}
+		d, err := NewDeviceEntryFromId(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		targets[id] = &moveTarget{device: d, free: d.Info.Storage.Free}
+		est.RemainingFree[id] = int64(d.Info.Storage.Free)
+	}
+
+	// validate the targets in a stable order for stable reasons
+	sortedIds := make([]string, 0, len(targets))
+	for id := range targets {
+		sortedIds = append(sortedIds, id)
+	}
+	sort.Strings(sortedIds)
+	for _, id := range sortedIds {
+		d := targets[id].device
+		n, err := NewNodeEntryFromId(tx, d.NodeId)
+		if err != nil {
+			return nil, err
+		}
+		if n.Info.ClusterId != v.Info.Cluster {
+			infeasible("Device %v is not in cluster %v", id, v.Info.Cluster)
+		} else if n.State != api.EntryStateOnline || d.State != api.EntryStateOnline {
+			infeasible("Device %v is not online", id)
+		}
+	}
+
+	bricks := []*BrickEntry{}
+	for _, brickId := range v.BricksIds() {
+		b, err := NewBrickEntryFromId(tx, brickId)
+		if err != nil {
+			return nil, err
+		}
+		bricks = append(bricks, b)
+	}
+
+	// limit the bricks of the volume per node to keep the bricks
+	// of a brick set on distinct nodes
+	perNode := len(bricks) / v.Durability.BricksInSet()
+	if perNode < 1 {
+		perNode = 1
+	}
+	nodeBricks := map[string]int{}
+	moving := []*BrickEntry{}
+	for _, b := range bricks {
+		if _, ok := targets[b.Info.DeviceId]; ok {
+			nodeBricks[b.Info.NodeId]++
+		} else {
+			moving = append(moving, b)
+		}
+	}
+
+	for _, b := range moving {
+		size := b.TotalSize()
+		var best *moveTarget
+		for _, id := range sortedIds {
+			t := targets[id]
+			if t.free <= size || nodeBricks[t.device.NodeId] >= perNode {
+				continue
+			}
+			if best == nil || t.free > best.free {
+				best = t
+			}
+		}
+		if best == nil {
+			infeasible("No target device has %v GiB free for brick %v",
+				kibToGiB(size), b.Info.Id)
+			continue
+		}
+		best.free -= size
+		nodeBricks[best.device.NodeId]++
+		est.RemainingFree[best.device.Info.Id] = int64(best.free)
+		est.BrickMoves = append(est.BrickMoves, api.VolumeMoveBrick{
+			BrickId:        b.Info.Id,
+			SourceDeviceId: b.Info.DeviceId,
+			TargetDeviceId: best.device.Info.Id,
+			SizeGB:         int64(kibToGiB(size)),
+		})
+	}
+	return est, nil
+}
+
+// kibToGiB converts a size in KiB to GiB, rounding up.
+func kibToGiB(size uint64) uint64 {
+	return (size + GB - 1) / GB
+}
@@ -0,0 +1,174 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+)
+
+// setupMoveEstimate creates a replica 3 volume and returns it along
+// with one device on each of the nodes not hosting a brick of it.
+func setupMoveEstimate(t *testing.T, app *App) (*VolumeEntry, []string) {
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		6,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	targets := []string{}
+	err = app.db.View(func(tx *bolt.Tx) error {
+		used := map[string]bool{}
+		for _, brickId := range v.BricksIds() {
+			b, err := NewBrickEntryFromId(tx, brickId)
+			if err != nil {
+				return err
+			}
+			used[b.Info.NodeId] = true
+		}
+		nl, err := NodeList(tx)
+		if err != nil {
+			return err
+		}
+		for _, nodeId := range nl {
+			if used[nodeId] {
+				continue
+			}
+			n, err := NewNodeEntryFromId(tx, nodeId)
+			if err != nil {
+				return err
+			}
+			targets = append(targets, n.Devices[0])
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(targets) == 3,
+		"expected len(targets) == 3, got:", len(targets))
+	return v, targets
+}
+
+func TestEstimateVolumeMove(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	v, targets := setupMoveEstimate(t, app)
+
+	err := app.db.View(func(tx *bolt.Tx) error {
+		est, err := EstimateVolumeMove(tx, v, targets)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, est.Feasible, "expected feasible, got:", est.Reason)
+		tests.Assert(t, len(est.BrickMoves) == 3,
+			"expected len(est.BrickMoves) == 3, got:", len(est.BrickMoves))
+
+		used := map[string]bool{}
+		for _, m := range est.BrickMoves {
+			tests.Assert(t, !used[m.TargetDeviceId],
+				"expected distinct target devices, got:", est.BrickMoves)
+			used[m.TargetDeviceId] = true
+
+			b, err := NewBrickEntryFromId(tx, m.BrickId)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+			tests.Assert(t, m.SourceDeviceId == b.Info.DeviceId,
+				"expected source device", b.Info.DeviceId, "got:",
+				m.SourceDeviceId)
+			tests.Assert(t, m.SizeGB == int64(kibToGiB(b.TotalSize())),
+				"unexpected brick size:", m.SizeGB)
+			d, err := NewDeviceEntryFromId(tx, m.TargetDeviceId)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+			tests.Assert(t,
+				est.RemainingFree[d.Info.Id] ==
+					int64(d.Info.Storage.Free-b.TotalSize()),
+				"unexpected remaining free:", est.RemainingFree[d.Info.Id])
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestEstimateVolumeMoveNoSpace(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	v, targets := setupMoveEstimate(t, app)
+
+	// shrink one of the targets below the size of a brick
+	err := app.db.Update(func(tx *bolt.Tx) error {
+		d, err := NewDeviceEntryFromId(tx, targets[1])
+		if err != nil {
+			return err
+		}
+		d.Info.Storage.Used += d.Info.Storage.Free - 10*GB
+		d.Info.Storage.Free = 10 * GB
+		return d.Save(tx)
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+	est, err := c.VolumeMoveEstimate(v.Info.Id, targets)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, !est.Feasible, "expected estimate to be infeasible")
+	tests.Assert(t, strings.Contains(est.Reason, "No target device has"),
+		"unexpected reason:", est.Reason)
+	tests.Assert(t, len(est.BrickMoves) == 2,
+		"expected len(est.BrickMoves) == 2, got:", len(est.BrickMoves))
+	for _, m := range est.BrickMoves {
+		tests.Assert(t, m.TargetDeviceId != targets[1],
+			"expected no brick on the small device, got:", m)
+	}
+	tests.Assert(t, est.RemainingFree[targets[1]] == 10*GB,
+		"expected unchanged free space, got:", est.RemainingFree[targets[1]])
+
+	// the estimate did not change the db
+	err = app.db.View(func(tx *bolt.Tx) error {
+		for _, id := range []string{targets[0], targets[2]} {
+			d, err := NewDeviceEntryFromId(tx, id)
+			tests.Assert(t, err == nil, "expected err == nil, got:", err)
+			tests.Assert(t, len(d.Bricks) == 0,
+				"expected len(d.Bricks) == 0, got:", len(d.Bricks))
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// unknown devices and missing targets are rejected
+	_, err = c.VolumeMoveEstimate(v.Info.Id, []string{"abcd"})
+	tests.Assert(t, err != nil, "expected err != nil")
+	r, err := http.Get(ts.URL + "/volumes/" + v.Info.Id + "/move-estimate")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusBadRequest,
+		"expected r.StatusCode == http.StatusBadRequest, got:", r.StatusCode)
+}
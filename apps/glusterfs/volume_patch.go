@@ -0,0 +1,139 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// paths of volume fields that may never be patched
+var volumeReadOnlyPaths = map[string]bool{
+	"/id":         true,
+	"/cluster":    true,
+	"/bricks":     true,
+	"/name":       true,
+	"/size":       true,
+	"/durability": true,
+	"/mount":      true,
+	"/blockinfo":  true,
+}
+
+// VolumePatchError is returned when a JSON Patch document can not
+// be applied to a volume.
+type VolumePatchError struct {
+	Index  int
+	Reason string
+}
+
+func (e *VolumePatchError) Error() string {
+	return fmt.Sprintf("patch operation %v: %v", e.Index, e.Reason)
+}
+
+// unescapePointerToken decodes a single JSON pointer (RFC 6901)
+// reference token.
+func unescapePointerToken(s string) string {
+	return strings.Replace(strings.Replace(s, "~1", "/", -1), "~0", "~", -1)
+}
+
+// ApplyPatch applies the JSON Patch operations to the volume's
+// metadata. Either all operations are applied or, if an error is
+// returned, the volume may be partially modified and must not be
+// saved. Only the labels and the tier of a volume may be changed.
+func (v *VolumeEntry) ApplyPatch(ops []api.JsonPatchOperation) error {
+	for i, op := range ops {
+		if err := v.applyPatchOp(op); err != nil {
+			return &VolumePatchError{Index: i, Reason: err.Error()}
+		}
+	}
+	switch v.Info.Tier {
+	case api.VolumeTierNone, api.VolumeTierGold,
+		api.VolumeTierSilver, api.VolumeTierBronze:
+	default:
+		return fmt.Errorf("invalid tier %q", v.Info.Tier)
+	}
+	if err := api.ValidateTags(v.Info.Labels); err != nil {
+		return fmt.Errorf("invalid labels: %v", err)
+	}
+	return nil
+}
+
+func (v *VolumeEntry) applyPatchOp(op api.JsonPatchOperation) error {
+	switch op.Op {
+	case api.JsonPatchAdd, api.JsonPatchRemove, api.JsonPatchReplace:
+	default:
+		return fmt.Errorf("unsupported operation %q", op.Op)
+	}
+	if op.Op != api.JsonPatchRemove && len(op.Value) == 0 {
+		return fmt.Errorf("operation %q requires a value", op.Op)
+	}
+
+	switch {
+	case volumeReadOnlyPaths[op.Path]:
+		return fmt.Errorf("%v is read-only", op.Path)
+	case op.Path == "/tier":
+		return v.patchTier(op)
+	case op.Path == "/labels":
+		return v.patchLabels(op)
+	case strings.HasPrefix(op.Path, "/labels/"):
+		key := unescapePointerToken(strings.TrimPrefix(op.Path, "/labels/"))
+		return v.patchLabel(op, key)
+	}
+	return fmt.Errorf("unsupported path %v", op.Path)
+}
+
+func (v *VolumeEntry) patchTier(op api.JsonPatchOperation) error {
+	if op.Op == api.JsonPatchRemove {
+		v.Info.Tier = api.VolumeTierNone
+		return nil
+	}
+	var tier api.VolumeTier
+	if err := json.Unmarshal(op.Value, &tier); err != nil {
+		return fmt.Errorf("invalid value for /tier: %v", err)
+	}
+	v.Info.Tier = tier
+	return nil
+}
+
+func (v *VolumeEntry) patchLabels(op api.JsonPatchOperation) error {
+	if op.Op == api.JsonPatchRemove {
+		v.Info.Labels = nil
+		return nil
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(op.Value, &labels); err != nil {
+		return fmt.Errorf("invalid value for /labels: %v", err)
+	}
+	v.Info.Labels = labels
+	return nil
+}
+
+func (v *VolumeEntry) patchLabel(op api.JsonPatchOperation, key string) error {
+	_, exists := v.Info.Labels[key]
+	if op.Op != api.JsonPatchAdd && !exists {
+		return fmt.Errorf("label %v does not exist", key)
+	}
+	if op.Op == api.JsonPatchRemove {
+		delete(v.Info.Labels, key)
+		return nil
+	}
+	var value string
+	if err := json.Unmarshal(op.Value, &value); err != nil {
+		return fmt.Errorf("invalid value for label %v: %v", key, err)
+	}
+	if v.Info.Labels == nil {
+		v.Info.Labels = map[string]string{}
+	}
+	v.Info.Labels[key] = value
+	return nil
+}
@@ -0,0 +1,177 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+func patchOp(op, path string, value interface{}) api.JsonPatchOperation {
+	p := api.JsonPatchOperation{Op: op, Path: path}
+	if value != nil {
+		b, err := json.Marshal(value)
+		if err != nil {
+			panic(err)
+		}
+		p.Value = b
+	}
+	return p
+}
+
+func TestVolumePatch(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	setupSampleDbTiers(t, app)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	v.Info.Tier = api.VolumeTierGold
+	v.Info.Labels = map[string]string{"team": "storage", "env": "prod"}
+	err := v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	c := client.NewClientNoAuth(ts.URL)
+
+	// rename the team label and move the volume to the silver tier
+	info, err := c.VolumePatch(v.Info.Id, []api.JsonPatchOperation{
+		patchOp(api.JsonPatchRemove, "/labels/team", nil),
+		patchOp(api.JsonPatchAdd, "/labels/owner", "storage"),
+		patchOp(api.JsonPatchReplace, "/tier", api.VolumeTierSilver),
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Tier == api.VolumeTierSilver,
+		"expected info.Tier == silver, got:", info.Tier)
+	tests.Assert(t, len(info.Labels) == 2,
+		"expected len(info.Labels) == 2, got:", info.Labels)
+	tests.Assert(t, info.Labels["owner"] == "storage",
+		"expected owner label, got:", info.Labels)
+	_, found := info.Labels["team"]
+	tests.Assert(t, !found, "expected team label removed, got:", info.Labels)
+
+	app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, entry.Info.Tier == api.VolumeTierSilver,
+			"expected entry.Info.Tier == silver, got:", entry.Info.Tier)
+		tests.Assert(t, entry.Info.Labels["owner"] == "storage",
+			"expected owner label, got:", entry.Info.Labels)
+		return nil
+	})
+
+	// read-only fields are rejected and nothing is applied
+	_, err = c.VolumePatch(v.Info.Id, []api.JsonPatchOperation{
+		patchOp(api.JsonPatchAdd, "/labels/extra", "x"),
+		patchOp(api.JsonPatchReplace, "/id", "abcd"),
+	})
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "/id is read-only"),
+		"unexpected error:", err)
+
+	app.db.View(func(tx *bolt.Tx) error {
+		entry, err := NewVolumeEntryFromId(tx, v.Info.Id)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		_, found := entry.Info.Labels["extra"]
+		tests.Assert(t, !found, "expected no extra label, got:",
+			entry.Info.Labels)
+		return nil
+	})
+
+	// a tier without devices is rejected
+	_, err = c.VolumePatch(v.Info.Id, []api.JsonPatchOperation{
+		patchOp(api.JsonPatchReplace, "/tier", api.VolumeTierBronze),
+	})
+	tests.Assert(t, err != nil, "expected err != nil")
+}
+
+func TestVolumePatchStatus(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	err := setupSampleDbWithTopology(app,
+		1,    // clusters
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		1*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	v := createSampleReplicaVolumeEntry(100, 3)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	patch := func(ct, body string) int {
+		req, err := http.NewRequest("PATCH", ts.URL+"/volumes/"+v.Info.Id,
+			strings.NewReader(body))
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		req.Header.Set("Content-Type", ct)
+		r, err := http.DefaultClient.Do(req)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		return r.StatusCode
+	}
+
+	checks := []struct {
+		ct     string
+		body   string
+		status int
+	}{
+		{"application/json-patch+json",
+			`[{"op": "replace", "path": "/id", "value": "x"}]`, 422},
+		{"application/json-patch+json",
+			`[{"op": "replace", "path": "/cluster", "value": "x"}]`, 422},
+		{"application/json-patch+json",
+			`[{"op": "add", "path": "/bricks", "value": []}]`, 422},
+		{"application/json-patch+json",
+			`[{"op": "replace", "path": "/labels/nope", "value": "x"}]`, 422},
+		{"application/json-patch+json",
+			`[{"op": "move", "from": "/labels/a", "path": "/labels/b"}]`, 422},
+		{"application/json-patch+json", `{"op": "add"}`, 422},
+		{"application/json",
+			`[{"op": "add", "path": "/labels/a", "value": "b"}]`,
+			http.StatusUnsupportedMediaType},
+		{"application/json-patch+json",
+			`[{"op": "add", "path": "/labels/a~1b", "value": "c"}]`, 422},
+		{"application/json-patch+json",
+			`[{"op": "add", "path": "/labels/a", "value": "b"}]`,
+			http.StatusOK},
+	}
+	for _, c := range checks {
+		status := patch(c.ct, c.body)
+		tests.Assert(t, status == c.status,
+			"expected", c.status, "for", c.body, "got:", status)
+	}
+}
@@ -0,0 +1,193 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/boltdb/bolt"
+
+	"github.com/heketi/heketi/executors"
+	wdb "github.com/heketi/heketi/pkg/db"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// clusterZoneIsUsable returns true if the cluster has at least one
+// online device on an online node in the zone.
+func clusterZoneIsUsable(tx *bolt.Tx, c *ClusterEntry, zone int) (bool, error) {
+	for _, nodeId := range c.Info.Nodes {
+		n, err := NewNodeEntryFromId(tx, nodeId)
+		if err != nil {
+			return false, err
+		}
+		if n.Info.Zone != zone || !n.isOnline() {
+			continue
+		}
+		for _, deviceId := range n.Devices {
+			d, err := NewDeviceEntryFromId(tx, deviceId)
+			if err != nil {
+				return false, err
+			}
+			if d.isOnline() {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// PlanZoneRebalance returns the brick moves needed to give every volume
+// of the cluster without a brick in the zone a brick in the zone. One
+// brick is moved per volume. The arbiter brick is preferred for arbiter
+// volumes as it is the cheapest to move, otherwise a brick is taken from
+// the zone holding the most bricks of the volume. Volumes that are
+// pending or whose bricks can not be replaced are skipped.
+func PlanZoneRebalance(tx *bolt.Tx, c *ClusterEntry,
+	zone int) (*api.ClusterRebalanceZonesResponse, error) {
+
+	plan := &api.ClusterRebalanceZonesResponse{
+		Zone:  zone,
+		Moves: []api.ZoneRebalanceMove{},
+	}
+
+	volumeIds := append([]string{}, c.Info.Volumes...)
+	sort.Strings(volumeIds)
+	for _, volumeId := range volumeIds {
+		v, err := NewVolumeEntryFromId(tx, volumeId)
+		if err != nil {
+			return nil, err
+		}
+		if v.Pending.Id != "" {
+			logger.Info("Skipping pending volume %v", v.Info.Id)
+			continue
+		}
+		if v.Info.Durability.Type == api.DurabilityDistributeOnly {
+			logger.Info("Skipping volume %v: bricks of %v volumes can not be replaced",
+				v.Info.Id, v.Info.Durability.Type)
+			continue
+		}
+
+		bricks := []*BrickEntry{}
+		zones := map[string]int{}
+		zoneCount := map[int]int{}
+		inZone := false
+		for _, brickId := range v.BricksIds() {
+			b, err := NewBrickEntryFromId(tx, brickId)
+			if err != nil {
+				return nil, err
+			}
+			n, err := NewNodeEntryFromId(tx, b.Info.NodeId)
+			if err != nil {
+				return nil, err
+			}
+			if n.Info.Zone == zone {
+				inZone = true
+				break
+			}
+			bricks = append(bricks, b)
+			zones[b.Info.Id] = n.Info.Zone
+			zoneCount[n.Info.Zone]++
+		}
+		if inZone || len(bricks) == 0 {
+			continue
+		}
+
+		// bricks are sorted by id so the choice is stable
+		var move *BrickEntry
+		for _, b := range bricks {
+			if b.SubType == ArbiterSubType {
+				move = b
+				break
+			}
+			if move == nil || zoneCount[zones[b.Info.Id]] > zoneCount[zones[move.Info.Id]] {
+				move = b
+			}
+		}
+		plan.Moves = append(plan.Moves, api.ZoneRebalanceMove{
+			VolumeId:   v.Info.Id,
+			BrickId:    move.Info.Id,
+			SourceZone: zones[move.Info.Id],
+			Arbiter:    move.SubType == ArbiterSubType,
+		})
+	}
+	return plan, nil
+}
+
+// RunZoneRebalance moves the bricks of the plan to the zone of the plan
+// by evicting each brick with the replacement restricted to devices in
+// the zone. Up to batchSize bricks are moved concurrently. Every brick
+// move is counted against the limit of in-flight operations and moves
+// that would exceed the limit are deferred to the next batch. No more
+// batches are started once a move of a batch has failed. The share of
+// bricks moved is passed to the progress function after every batch.
+func RunZoneRebalance(db wdb.DB, executor executors.Executor,
+	optracker *OpTracker, plan *api.ClusterRebalanceZonesResponse,
+	batchSize int, progress func(int)) error {
+
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	dzm, err := NewDeviceZoneMapFromDb(db)
+	if err != nil {
+		return err
+	}
+	zone := plan.Zone
+	inZone := func(bs *BrickSet, d *DeviceEntry) bool {
+		z, found := dzm.DeviceZones[d.Info.Id]
+		return found && z == zone
+	}
+
+	moves := plan.Moves
+	for len(moves) > 0 {
+		var (
+			wg     sync.WaitGroup
+			lock   sync.Mutex
+			failed []string
+		)
+		started := 0
+		for started < batchSize && started < len(moves) {
+			throttled, token := optracker.ThrottleOrToken()
+			if throttled {
+				break
+			}
+			m := moves[started]
+			started++
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer optracker.Remove(token)
+				logger.Info("Moving brick %v of volume %v to zone %v",
+					m.BrickId, m.VolumeId, zone)
+				beo := NewBrickEvictOperation(m.BrickId, db, api.HealCheckEnable)
+				beo.deviceFilter = inZone
+				if err := RunOperation(beo, executor); err != nil {
+					logger.LogError("Unable to move brick %v of volume %v to zone %v: %v",
+						m.BrickId, m.VolumeId, zone, err)
+					lock.Lock()
+					failed = append(failed, fmt.Sprintf("%v: %v", m.BrickId, err))
+					lock.Unlock()
+				}
+			}()
+		}
+		if started == 0 {
+			return ErrTooManyOperations
+		}
+		wg.Wait()
+		if len(failed) > 0 {
+			return fmt.Errorf("Failed to move %v bricks to zone %v: %v",
+				len(failed), zone, failed)
+		}
+		moves = moves[started:]
+		progress((len(plan.Moves) - len(moves)) * 100 / len(plan.Moves))
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package glusterfs
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/gorilla/mux"
+	"github.com/heketi/tests"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// addSampleZoneNode adds a node with one device in the given zone to
+// the cluster.
+func addSampleZoneNode(app *App, clusterId string, zone int) error {
+	return app.db.Update(func(tx *bolt.Tx) error {
+		cluster, err := NewClusterEntryFromId(tx, clusterId)
+		if err != nil {
+			return err
+		}
+		node := createSampleNodeEntry()
+		node.Info.ClusterId = clusterId
+		node.Info.Zone = zone
+		cluster.NodeAdd(node.Info.Id)
+		device := createSampleDeviceEntry(node.Info.Id, 2*TB)
+		node.DeviceAdd(device.Id())
+		if err := device.Save(tx); err != nil {
+			return err
+		}
+		if err := node.Save(tx); err != nil {
+			return err
+		}
+		return cluster.Save(tx)
+	})
+}
+
+// volumeBrickZones returns the zones of the bricks of the volume.
+func volumeBrickZones(t *testing.T, app *App, volumeId string) []int {
+	zones := []int{}
+	err := app.db.View(func(tx *bolt.Tx) error {
+		v, err := NewVolumeEntryFromId(tx, volumeId)
+		if err != nil {
+			return err
+		}
+		for _, brickId := range v.BricksIds() {
+			b, err := NewBrickEntryFromId(tx, brickId)
+			if err != nil {
+				return err
+			}
+			n, err := NewNodeEntryFromId(tx, b.Info.NodeId)
+			if err != nil {
+				return err
+			}
+			zones = append(zones, n.Info.Zone)
+		}
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	return zones
+}
+
+func TestClusterRebalanceZones(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+	router := mux.NewRouter()
+	app.SetRoutes(router)
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	// all nodes in one zone
+	err := setupSampleDbWithTopologyWithZones(app,
+		1,    // clusters
+		1,    // zones_per_cluster
+		2,    // nodes_per_cluster
+		1,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 2
+	v := NewVolumeEntryFromRequest(req)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	zones := volumeBrickZones(t, app, v.Info.Id)
+	tests.Assert(t, len(zones) == 2 && zones[0] == 0 && zones[1] == 0,
+		"expected both bricks in zone 0, got:", zones)
+
+	c := client.NewClientNoAuth(ts.URL)
+
+	// the zone must exist before bricks can be moved to it
+	_, err = c.ClusterRebalanceZonesPlan(v.Info.Cluster, 2)
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	err = addSampleZoneNode(app, v.Info.Cluster, 2)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	plan, err := c.ClusterRebalanceZonesPlan(v.Info.Cluster, 2)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(plan.Moves) == 1,
+		"expected len(plan.Moves) == 1, got:", plan.Moves)
+	tests.Assert(t, plan.Moves[0].VolumeId == v.Info.Id,
+		"expected plan.Moves[0].VolumeId == v.Info.Id, got:", plan.Moves[0])
+	tests.Assert(t, plan.Moves[0].SourceZone == 0,
+		"expected plan.Moves[0].SourceZone == 0, got:", plan.Moves[0])
+	tests.Assert(t, !plan.Moves[0].Arbiter,
+		"expected plan.Moves[0].Arbiter == false, got:", plan.Moves[0])
+
+	// planning does not move any bricks
+	zones = volumeBrickZones(t, app, v.Info.Id)
+	tests.Assert(t, zones[0] == 0 && zones[1] == 0,
+		"expected both bricks in zone 0, got:", zones)
+
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		return mockVolumeInfoFromDb(app.db, volume)
+	}
+	app.xo.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		return mockHealStatusFromDb(app.db, volume)
+	}
+
+	err = c.ClusterRebalanceZones(v.Info.Cluster,
+		&api.ClusterRebalanceZonesRequest{Zone: 2})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	zones = volumeBrickZones(t, app, v.Info.Id)
+	tests.Assert(t, len(zones) == 2, "expected len(zones) == 2, got:", zones)
+	tests.Assert(t, (zones[0] == 0 && zones[1] == 2) ||
+		(zones[0] == 2 && zones[1] == 0),
+		"expected one brick in zone 0 and one in zone 2, got:", zones)
+	app.db.View(func(tx *bolt.Tx) error {
+		l, err := PendingOperationList(tx)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(l) == 0, "expected len(l) == 0, got:", len(l))
+		return nil
+	})
+
+	// nothing is left to move
+	plan, err = c.ClusterRebalanceZonesPlan(v.Info.Cluster, 2)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(plan.Moves) == 0,
+		"expected len(plan.Moves) == 0, got:", plan.Moves)
+}
+
+func TestPlanZoneRebalanceArbiter(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopologyWithZones(app,
+		1,    // clusters
+		1,    // zones_per_cluster
+		3,    // nodes_per_cluster
+		1,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	req := &api.VolumeCreateRequest{}
+	req.Size = 100
+	req.Durability.Type = api.DurabilityReplicate
+	req.Durability.Replicate.Replica = 3
+	req.GlusterVolumeOptions = []string{"user.heketi.arbiter true"}
+	v := NewVolumeEntryFromRequest(req)
+	err = v.Create(app.db, app.executor)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	err = addSampleZoneNode(app, v.Info.Cluster, 2)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	app.db.View(func(tx *bolt.Tx) error {
+		c, err := NewClusterEntryFromId(tx, v.Info.Cluster)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		plan, err := PlanZoneRebalance(tx, c, 2)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, len(plan.Moves) == 1,
+			"expected len(plan.Moves) == 1, got:", plan.Moves)
+		// the arbiter brick is preferred
+		tests.Assert(t, plan.Moves[0].Arbiter,
+			"expected plan.Moves[0].Arbiter, got:", plan.Moves[0])
+		b, err := NewBrickEntryFromId(tx, plan.Moves[0].BrickId)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, b.SubType == ArbiterSubType,
+			"expected b.SubType == ArbiterSubType, got:", b.SubType)
+		return nil
+	})
+}
+
+func TestRunZoneRebalanceProgress(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	app := NewTestApp(tmpfile)
+	defer app.Close()
+
+	err := setupSampleDbWithTopologyWithZones(app,
+		1,    // clusters
+		1,    // zones_per_cluster
+		2,    // nodes_per_cluster
+		1,    // devices_per_node,
+		2*TB, // disksize)
+	)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var clusterId string
+	for i := 0; i < 3; i++ {
+		req := &api.VolumeCreateRequest{}
+		req.Size = 100
+		req.Durability.Type = api.DurabilityReplicate
+		req.Durability.Replicate.Replica = 2
+		v := NewVolumeEntryFromRequest(req)
+		err = v.Create(app.db, app.executor)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		clusterId = v.Info.Cluster
+	}
+	err = addSampleZoneNode(app, clusterId, 2)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	var plan *api.ClusterRebalanceZonesResponse
+	err = app.db.View(func(tx *bolt.Tx) error {
+		c, err := NewClusterEntryFromId(tx, clusterId)
+		if err != nil {
+			return err
+		}
+		plan, err = PlanZoneRebalance(tx, c, 2)
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(plan.Moves) == 3,
+		"expected len(plan.Moves) == 3, got:", plan.Moves)
+
+	app.xo.MockVolumeInfo = func(host string, volume string) (*executors.Volume, error) {
+		return mockVolumeInfoFromDb(app.db, volume)
+	}
+	app.xo.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		return mockHealStatusFromDb(app.db, volume)
+	}
+
+	progress := []int{}
+	err = RunZoneRebalance(app.db, app.executor, app.optracker, plan, 1,
+		func(p int) { progress = append(progress, p) })
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	expected := []int{33, 66, 100}
+	tests.Assert(t, len(progress) == len(expected),
+		"expected", expected, "got:", progress)
+	for i := range expected {
+		tests.Assert(t, progress[i] == expected[i],
+			"expected", expected, "got:", progress)
+	}
+}
@@ -76,3 +76,68 @@ func (c *Client) AdminStatusSet(request *api.AdminStatus) error {
 	}
 	return utils.GetErrorFromResponse(r)
 }
+
+// AdminModeSet switches the server between read-only and read-write
+// mode. Unlike AdminStatusSet it works while the server is read-only.
+func (c *Client) AdminModeSet(request *api.AdminMode) error {
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("PUT", c.host+"/admin/mode", bytes.NewBuffer(buffer))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return utils.GetErrorFromResponse(r)
+	}
+	return nil
+}
+
+// ConfigDriftCheck compares the live gluster configuration with the
+// configuration heketi expects and returns the differences.
+func (c *Client) ConfigDriftCheck() (*api.DriftReport, error) {
+	req, err := http.NewRequest("POST", c.host+"/admin/config-drift-check", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	var report api.DriftReport
+	err = utils.GetJsonFromResponse(r, &report)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
 	"github.com/heketi/heketi/pkg/utils"
 )
 
@@ -54,6 +55,8 @@ type ClientOptions struct {
 	RetryMinDelay, RetryMaxDelay int
 	// control wait time while polling for responses (milliseconds)
 	PollDelay int
+	// tags attached to the operations started by the client's requests
+	OperationTags map[string]string
 }
 
 // Client object
@@ -189,6 +192,10 @@ func (c *Client) doBasic(req *http.Request) (*http.Response, error) {
 		<-c.throttle
 	}()
 
+	for k, v := range c.opts.OperationTags {
+		req.Header.Set(api.OperationTagHeaderPrefix+k, v)
+	}
+
 	httpClient := &http.Client{}
 	if c.tlsClientConfig != nil {
 		httpClient.Transport = &http.Transport{
@@ -13,6 +13,7 @@
 package client
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -1199,6 +1200,83 @@ func TestAdminStatus(t *testing.T) {
 		"expected as.State == api.AdminStateNormal, got:", as.State)
 }
 
+func TestAdminReadOnlyMode(t *testing.T) {
+	db := tests.Tempfile()
+	defer os.Remove(db)
+
+	// Create the app
+	app := glusterfs.NewTestApp(db)
+	defer app.Close()
+
+	// Setup the server
+	ts := setupHeketiServer(app)
+	defer ts.Close()
+
+	c := newTestClient(ts.URL, "admin", TEST_ADMIN_KEY)
+	tests.Assert(t, c != nil, "newTestClient failed:", c)
+
+	cluster, err := c.ClusterCreate(&api.ClusterCreateRequest{
+		ClusterFlags: api.ClusterFlags{Block: true, File: true},
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	for n := 0; n < 3; n++ {
+		nodeReq := &api.NodeAddRequest{}
+		nodeReq.ClusterId = cluster.Id
+		nodeReq.Hostnames.Manage = []string{"manage" + fmt.Sprintf("%v", n)}
+		nodeReq.Hostnames.Storage = []string{"storage" + fmt.Sprintf("%v", n)}
+		nodeReq.Zone = n + 1
+		node, err := c.NodeAdd(nodeReq)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+		deviceReq := &api.DeviceAddRequest{}
+		deviceReq.Name = "/dev/by-magic/id:" + idgen.GenUUID()
+		deviceReq.NodeId = node.Id
+		err = c.DeviceAdd(deviceReq)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	}
+
+	// only admins may change the mode
+	u := newTestClient(ts.URL, "user", "userkey")
+	err = u.AdminModeSet(&api.AdminMode{Mode: api.AdminModeReadOnly})
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	err = c.AdminModeSet(&api.AdminMode{Mode: api.AdminModeReadOnly})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	as, err := c.AdminStatusGet()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, as.State == api.AdminStateReadOnly,
+		"expected as.State == api.AdminStateReadOnly, got:", as.State)
+
+	volumeReq := &api.VolumeCreateRequest{}
+	volumeReq.Size = 10
+	_, err = c.VolumeCreate(volumeReq)
+	tests.Assert(t, err != nil, "expected err != nil")
+	e, ok := err.(*api.ErrorResponse)
+	tests.Assert(t, ok, "expected *api.ErrorResponse, got:", err)
+	tests.Assert(t, e.Code == api.ErrorServiceUnavailable,
+		"expected SERVICE_UNAVAILABLE, got:", e.Code)
+	tests.Assert(t, e.Message == "heketi is in read-only mode",
+		"expected read-only message, got:", e.Message)
+
+	// reads still work
+	_, err = c.ClusterList()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	err = c.AdminModeSet(&api.AdminMode{Mode: api.AdminModeReadWrite})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	as, err = c.AdminStatusGet()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, as.State == api.AdminStateNormal,
+		"expected as.State == api.AdminStateNormal, got:", as.State)
+
+	volume, err := c.VolumeCreate(volumeReq)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, volume.Size == 10, "expected volume.Size == 10, got:", volume.Size)
+
+	err = c.AdminModeSet(&api.AdminMode{Mode: "maintenance"})
+	tests.Assert(t, err != nil, "expected err != nil")
+}
+
 func TestVolumeSetBlockRestriction(t *testing.T) {
 	db := tests.Tempfile()
 	defer os.Remove(db)
@@ -1269,3 +1347,88 @@ func TestVolumeSetBlockRestriction(t *testing.T) {
 	tests.Assert(t, err == nil)
 	tests.Assert(t, v2.BlockInfo.Restriction == api.Unrestricted)
 }
+
+func TestResponseEnvelope(t *testing.T) {
+	db := tests.Tempfile()
+	defer os.Remove(db)
+
+	// Create the app
+	app := glusterfs.NewTestApp(db)
+	defer app.Close()
+
+	// the volume is created through a server without the envelope
+	ts := setupHeketiServer(app)
+	defer ts.Close()
+	ets := setupHeketiServerAndMiddleware(app, middleware.NewEnvelope())
+	defer ets.Close()
+
+	c := newTestClient(ts.URL, "admin", TEST_ADMIN_KEY)
+	cluster, err := c.ClusterCreate(&api.ClusterCreateRequest{
+		ClusterFlags: api.ClusterFlags{Block: true, File: true},
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	for n := 0; n < 3; n++ {
+		nodeReq := &api.NodeAddRequest{}
+		nodeReq.ClusterId = cluster.Id
+		nodeReq.Hostnames.Manage = []string{"manage" + fmt.Sprintf("%v", n)}
+		nodeReq.Hostnames.Storage = []string{"storage" + fmt.Sprintf("%v", n)}
+		nodeReq.Zone = n + 1
+		node, err := c.NodeAdd(nodeReq)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+		deviceReq := &api.DeviceAddRequest{}
+		deviceReq.Name = "/dev/by-magic/id:" + idgen.GenUUID()
+		deviceReq.NodeId = node.Id
+		err = c.DeviceAdd(deviceReq)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	}
+	volumeReq := &api.VolumeCreateRequest{}
+	volumeReq.Size = 10
+	volume, err := c.VolumeCreate(volumeReq)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	ec := newTestClient(ets.URL, "admin", TEST_ADMIN_KEY)
+	get := func(path string) (*http.Response, map[string]json.RawMessage) {
+		req, err := http.NewRequest("GET", ets.URL+path, nil)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		req.Header.Set(middleware.RequestIdHeader, "req-1234")
+		err = ec.setToken(req)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		r, err := ec.do(req)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		var body map[string]json.RawMessage
+		err = utils.GetJsonFromResponse(r, &body)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		return r, body
+	}
+
+	r, body := get("/volumes/" + volume.Id)
+	tests.Assert(t, r.StatusCode == http.StatusOK,
+		"expected http.StatusOK, got:", r.StatusCode)
+	tests.Assert(t, len(body) == 2, "expected data and meta, got:", body)
+	var info api.VolumeInfoResponse
+	err = json.Unmarshal(body["data"], &info)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.Id == volume.Id, "expected", volume.Id, "got:", info.Id)
+	tests.Assert(t, info.Size == 10, "expected size 10, got:", info.Size)
+	var meta middleware.EnvelopeMeta
+	err = json.Unmarshal(body["meta"], &meta)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, meta.RequestId == "req-1234",
+		"expected req-1234, got:", meta.RequestId)
+	tests.Assert(t, meta.ApiVersion == "v1", "expected v1, got:", meta.ApiVersion)
+	tests.Assert(t, meta.Timestamp != "", "expected timestamp")
+
+	r, body = get("/volumes/" + idgen.GenUUID())
+	tests.Assert(t, r.StatusCode == http.StatusNotFound,
+		"expected http.StatusNotFound, got:", r.StatusCode)
+	_, ok := body["data"]
+	tests.Assert(t, !ok, "expected no data, got:", body)
+	var e api.ErrorResponse
+	err = json.Unmarshal(body["error"], &e)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, e.Code == api.ErrorVolumeNotFound,
+		"expected VOLUME_NOT_FOUND, got:", e.Code)
+	_, ok = body["meta"]
+	tests.Assert(t, ok, "expected meta, got:", body)
+}
@@ -16,6 +16,8 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/heketi/heketi/pkg/glusterfs/api"
 	"github.com/heketi/heketi/pkg/utils"
@@ -130,6 +132,179 @@ func (c *Client) ClusterInfo(id string) (*api.ClusterInfoResponse, error) {
 	return &cluster, nil
 }
 
+// ClusterTopology returns the zones, nodes and devices of the cluster.
+func (c *Client) ClusterTopology(id string) (*api.ClusterTopologyResponse, error) {
+
+	// Create request
+	req, err := http.NewRequest("GET", c.host+"/clusters/"+id+"/topology", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get info
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var topo api.ClusterTopologyResponse
+	err = utils.GetJsonFromResponse(r, &topo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &topo, nil
+}
+
+// ClusterEvents returns the changes made to the cluster at or after
+// the given time, in seconds since the epoch, oldest first. A limit of
+// zero uses the server's default.
+func (c *Client) ClusterEvents(
+	id string, since int64, limit int) (*api.ClusterEventListResponse, error) {
+
+	q := url.Values{}
+	if since > 0 {
+		q.Set("since", strconv.FormatInt(since, 10))
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	u := c.host + "/clusters/" + id + "/events"
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get events
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var events api.ClusterEventListResponse
+	err = utils.GetJsonFromResponse(r, &events)
+	if err != nil {
+		return nil, err
+	}
+
+	return &events, nil
+}
+
+// ClusterSnapshotPolicy returns the snapshot policy of the cluster.
+func (c *Client) ClusterSnapshotPolicy(id string) (*api.ClusterSnapshotPolicy, error) {
+
+	// Create request
+	req, err := http.NewRequest("GET", c.host+"/clusters/"+id+"/snapshot-policy", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get info
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var policy api.ClusterSnapshotPolicy
+	err = utils.GetJsonFromResponse(r, &policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// ClusterSetSnapshotPolicy changes the gluster snapshot settings of
+// the cluster and returns the new policy.
+func (c *Client) ClusterSetSnapshotPolicy(id string,
+	request *api.ClusterSnapshotPolicy) (*api.ClusterSnapshotPolicy, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("PUT",
+		c.host+"/clusters/"+id+"/snapshot-policy",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.pollResponse(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var policy api.ClusterSnapshotPolicy
+	err = utils.GetJsonFromResponse(r, &policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
 func (c *Client) ClusterList() (*api.ClusterListResponse, error) {
 
 	// Create request
@@ -190,3 +365,85 @@ func (c *Client) ClusterDelete(id string) error {
 
 	return nil
 }
+
+func (c *Client) sendClusterRebalanceZones(id string,
+	request *api.ClusterRebalanceZonesRequest) (*http.Response, error) {
+
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST",
+		c.host+"/clusters/"+id+"/rebalance-zones",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	return c.do(req)
+}
+
+// ClusterRebalanceZonesPlan returns the bricks that would be moved to
+// the zone by ClusterRebalanceZones. No bricks are moved.
+func (c *Client) ClusterRebalanceZonesPlan(id string, zone int) (
+	*api.ClusterRebalanceZonesResponse, error) {
+
+	r, err := c.sendClusterRebalanceZones(id,
+		&api.ClusterRebalanceZonesRequest{Zone: zone, PlanOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var plan api.ClusterRebalanceZonesResponse
+	err = utils.GetJsonFromResponse(r, &plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return &plan, nil
+}
+
+// ClusterRebalanceZones moves a brick of every volume of the cluster
+// without a brick in the zone of the request to that zone.
+func (c *Client) ClusterRebalanceZones(id string,
+	request *api.ClusterRebalanceZonesRequest) error {
+
+	r, err := c.sendClusterRebalanceZones(id, request)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode == http.StatusOK {
+		// nothing to move (or only a plan was requested)
+		return nil
+	}
+	if r.StatusCode != http.StatusAccepted {
+		return utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.pollResponse(r)
+	if err != nil {
+		return err
+	}
+	if r.StatusCode != http.StatusNoContent {
+		return utils.GetErrorFromResponse(r)
+	}
+
+	return nil
+}
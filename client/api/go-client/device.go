@@ -64,6 +64,61 @@ func (c *Client) DeviceAdd(request *api.DeviceAddRequest) error {
 	return nil
 }
 
+// DeviceBatchAdd adds all of the given devices to the node or, if any
+// of them can not be set up, none of them.
+func (c *Client) DeviceBatchAdd(nodeId string,
+	request *api.DeviceBatchAddRequest) (*api.DeviceBatchAddResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST",
+		c.host+"/nodes/"+nodeId+"/devices/batch",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.pollResponse(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var result api.DeviceBatchAddResponse
+	err = utils.GetJsonFromResponse(r, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
 func (c *Client) DeviceInfo(id string) (*api.DeviceInfoResponse, error) {
 
 	// Create request
@@ -148,6 +203,117 @@ func (c *Client) DeviceDeleteWithOptions(
 	return nil
 }
 
+// DeviceReplace moves the bricks of the device to a new device on the
+// same node and removes the device. The information of the new device
+// is returned.
+func (c *Client) DeviceReplace(id string,
+	request *api.DeviceReplaceRequest) (*api.DeviceInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST",
+		c.host+"/devices/"+id+"/replace",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.pollResponse(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var device api.DeviceInfoResponse
+	err = utils.GetJsonFromResponse(r, &device)
+	if err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
+// DeviceReinitialize wipes the device, which must not have any
+// bricks, and sets it up again.
+func (c *Client) DeviceReinitialize(id string,
+	request *api.DeviceReinitializeRequest) (*api.DeviceInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST",
+		c.host+"/devices/"+id+"/reinitialize",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.pollResponse(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var device api.DeviceInfoResponse
+	err = utils.GetJsonFromResponse(r, &device)
+	if err != nil {
+		return nil, err
+	}
+
+	return &device, nil
+}
+
 func (c *Client) DeviceState(id string,
 	request *api.StateRequest) error {
 
@@ -261,3 +427,92 @@ func (c *Client) DeviceSetTags(id string, request *api.TagsChangeRequest) error
 	}
 	return nil
 }
+
+// LvmSnapshotCreate takes an LVM snapshot of the lv of a brick on
+// the given device and returns the information of the new snapshot.
+func (c *Client) LvmSnapshotCreate(deviceId, brickId string,
+	request *api.LvmSnapshotCreateRequest) (*api.LvmSnapshotInfo, error) {
+
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST",
+		c.host+"/devices/"+deviceId+"/bricks/"+brickId+"/lvm-snapshot",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.pollResponse(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	var snapshot api.LvmSnapshotInfo
+	err = utils.GetJsonFromResponse(r, &snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (c *Client) LvmSnapshotDelete(deviceId, brickId, id string) error {
+
+	// Create a request
+	req, err := http.NewRequest("DELETE",
+		c.host+"/devices/"+deviceId+"/bricks/"+brickId+"/lvm-snapshots/"+id,
+		nil)
+	if err != nil {
+		return err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusAccepted {
+		return utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.pollResponse(r)
+	if err != nil {
+		return err
+	}
+	if r.StatusCode != http.StatusNoContent {
+		return utils.GetErrorFromResponse(r)
+	}
+	return nil
+}
@@ -216,3 +216,149 @@ func (c *Client) NodeSetTags(id string, request *api.TagsChangeRequest) error {
 	}
 	return nil
 }
+
+func (c *Client) NodeSetIp(id string, request *api.NodeSetIpRequest) (
+	*api.NodeInfoResponse, error) {
+
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("PUT",
+		c.host+"/nodes/"+id+"/ip",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get info
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	var node api.NodeInfoResponse
+	err = utils.GetJsonFromResponse(r, &node)
+	if err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
+
+func (c *Client) NodeBricks(id string) (*api.NodeBricksResponse, error) {
+
+	// Create request
+	req, err := http.NewRequest("GET", c.host+"/nodes/"+id+"/bricks", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get info
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var bricks api.NodeBricksResponse
+	err = utils.GetJsonFromResponse(r, &bricks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bricks, nil
+}
+
+func (c *Client) NodePing(id string) (*api.NodePingResponse, error) {
+
+	// Create request
+	req, err := http.NewRequest("POST", c.host+"/nodes/"+id+"/ping", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var ping api.NodePingResponse
+	err = utils.GetJsonFromResponse(r, &ping)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ping, nil
+}
+
+// NodeUpdateKnownHost records the ssh host key currently presented by
+// the node and returns its fingerprint.
+func (c *Client) NodeUpdateKnownHost(id string) (*api.NodeKnownHostResponse, error) {
+
+	// Create request
+	req, err := http.NewRequest("POST",
+		c.host+"/nodes/"+id+"/update-known-host", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var resp api.NodeKnownHostResponse
+	err = utils.GetJsonFromResponse(r, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
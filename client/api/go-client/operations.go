@@ -16,6 +16,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/heketi/heketi/pkg/glusterfs/api"
 	"github.com/heketi/heketi/pkg/utils"
@@ -51,7 +54,39 @@ func (c *Client) OperationsInfo() (*api.OperationsInfo, error) {
 }
 
 func (c *Client) PendingOperationList() (*api.PendingOperationListResponse, error) {
-	req, err := http.NewRequest("GET", c.host+"/operations/pending", nil)
+	return c.PendingOperationListByTags(nil)
+}
+
+// PendingOperationListByTags lists the pending operations that carry
+// all of the given tags.
+func (c *Client) PendingOperationListByTags(
+	tags map[string]string) (*api.PendingOperationListResponse, error) {
+
+	q := url.Values{}
+	for k, v := range tags {
+		q.Set(api.OperationTagQueryPrefix+k, v)
+	}
+	return c.pendingOperationList(q)
+}
+
+// PendingOperationListArchived lists the pending operations including
+// the operations moved to the archive of the server.
+func (c *Client) PendingOperationListArchived() (
+	*api.PendingOperationListResponse, error) {
+
+	q := url.Values{}
+	q.Set("include_archived", "true")
+	return c.pendingOperationList(q)
+}
+
+func (c *Client) pendingOperationList(
+	q url.Values) (*api.PendingOperationListResponse, error) {
+
+	u := c.host + "/operations/pending"
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequest("GET", u, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -159,3 +194,119 @@ func (c *Client) PendingOperationCleanUp(
 	}
 	return nil
 }
+
+// VolumeOperations returns a page of the operations that target the
+// given volume. Pass an empty cursor to fetch the first page and the
+// NextCursor value of the response to fetch subsequent pages. A limit
+// of zero uses the server's default page size.
+func (c *Client) VolumeOperations(
+	id, cursor string, limit int) (*api.VolumeOperationsResponse, error) {
+
+	q := url.Values{}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	u := c.host + "/volumes/" + id + "/operations"
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+	var vo api.VolumeOperationsResponse
+	err = utils.GetJsonFromResponse(r, &vo)
+	if err != nil {
+		return nil, err
+	}
+	return &vo, nil
+}
+
+// OperationCancel cancels an operation that has been accepted by the
+// server but has not yet started making changes to the storage system.
+func (c *Client) OperationCancel(id string) error {
+	req, err := http.NewRequest("DELETE", c.host+"/operations/"+id, nil)
+	if err != nil {
+		return err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusNoContent {
+		return utils.GetErrorFromResponse(r)
+	}
+	return nil
+}
+
+// PendingOperationDeleteBefore deletes the records of pending
+// operations with the given status ("failed", "stale" or "all") that
+// were started before the given time. The operations are not cleaned
+// up. A batchSize of zero uses the server's default batch size.
+func (c *Client) PendingOperationDeleteBefore(
+	status string, before time.Time,
+	batchSize int) (*api.PendingOperationsDeleteResponse, error) {
+
+	q := url.Values{}
+	q.Set("status", status)
+	q.Set("before", before.Format(time.RFC3339))
+	if batchSize > 0 {
+		q.Set("batch_size", strconv.Itoa(batchSize))
+	}
+	req, err := http.NewRequest("DELETE",
+		c.host+"/admin/operations/cleanup?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+	var resp api.PendingOperationsDeleteResponse
+	err = utils.GetJsonFromResponse(r, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,166 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), as published by the Free Software Foundation,
+// or under the Apache License, Version 2.0 <LICENSE-APACHE2 or
+// http://www.apache.org/licenses/LICENSE-2.0>.
+//
+// You may not use this file except in compliance with those terms.
+//
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+func (c *Client) sshKeyRequest(method, url string,
+	request *api.SshKeyRequest, expected int) (*api.SshKeyInfo, error) {
+
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != expected {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var info api.SshKeyInfo
+	err = utils.GetJsonFromResponse(r, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) SshKeyCreate(request *api.SshKeyRequest) (*api.SshKeyInfo, error) {
+	return c.sshKeyRequest("POST", c.host+"/admin/ssh-keys",
+		request, http.StatusCreated)
+}
+
+func (c *Client) SshKeyUpdate(id string,
+	request *api.SshKeyRequest) (*api.SshKeyInfo, error) {
+
+	return c.sshKeyRequest("PUT", c.host+"/admin/ssh-keys/"+id,
+		request, http.StatusOK)
+}
+
+func (c *Client) SshKeyInfo(id string) (*api.SshKeyInfo, error) {
+
+	// Create request
+	req, err := http.NewRequest("GET", c.host+"/admin/ssh-keys/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get info
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var info api.SshKeyInfo
+	err = utils.GetJsonFromResponse(r, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) SshKeyList() (*api.SshKeyListResponse, error) {
+
+	// Create request
+	req, err := http.NewRequest("GET", c.host+"/admin/ssh-keys", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get info
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var list api.SshKeyListResponse
+	err = utils.GetJsonFromResponse(r, &list)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (c *Client) SshKeyDelete(id string) error {
+
+	// Create request
+	req, err := http.NewRequest("DELETE", c.host+"/admin/ssh-keys/"+id, nil)
+	if err != nil {
+		return err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusNoContent {
+		return utils.GetErrorFromResponse(r)
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/heketi/heketi/pkg/glusterfs/api"
 	"github.com/heketi/heketi/pkg/utils"
@@ -129,6 +130,148 @@ func (c *Client) VolumeSetBlockRestriction(id string, request *api.VolumeBlockRe
 
 }
 
+func (c *Client) VolumeSetACLConfig(id string, request *api.VolumeACLConfig) (
+	*api.VolumeInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("PUT",
+		c.host+"/volumes/"+id+"/acl-config",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.pollResponse(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var volume api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &volume)
+	if err != nil {
+		return nil, err
+	}
+
+	return &volume, nil
+}
+
+func (c *Client) VolumeTranslators(id string) (
+	*api.VolumeTranslatorsResponse, error) {
+
+	// Create request
+	req, err := http.NewRequest("GET",
+		c.host+"/volumes/"+id+"/translators", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get translators
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var translators api.VolumeTranslatorsResponse
+	err = utils.GetJsonFromResponse(r, &translators)
+	if err != nil {
+		return nil, err
+	}
+
+	return &translators, nil
+}
+
+func (c *Client) VolumeSetTranslator(id string,
+	request *api.VolumeTranslatorRequest) (*api.VolumeInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("PUT",
+		c.host+"/volumes/"+id+"/translators",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.pollResponse(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var volume api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &volume)
+	if err != nil {
+		return nil, err
+	}
+
+	return &volume, nil
+}
+
 func (c *Client) VolumeExpand(id string, request *api.VolumeExpandRequest) (
 	*api.VolumeInfoResponse, error) {
 
@@ -251,6 +394,206 @@ func (c *Client) VolumeInfo(id string) (*api.VolumeInfoResponse, error) {
 	return &volume, nil
 }
 
+// VolumeMoveEstimate returns the planned placement of the bricks of
+// the volume on the given target devices. No changes are made.
+func (c *Client) VolumeMoveEstimate(id string, targetDeviceIds []string) (
+	*api.VolumeMoveEstimateResponse, error) {
+
+	// Create request
+	req, err := http.NewRequest("GET",
+		c.host+"/volumes/"+id+"/move-estimate", nil)
+	if err != nil {
+		return nil, err
+	}
+	q := req.URL.Query()
+	q.Set("target_device_ids", strings.Join(targetDeviceIds, ","))
+	req.URL.RawQuery = q.Encode()
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get estimate
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var est api.VolumeMoveEstimateResponse
+	err = utils.GetJsonFromResponse(r, &est)
+	if err != nil {
+		return nil, err
+	}
+
+	return &est, nil
+}
+
+// VolumeBitrotStatus returns the state of the bitrot scrubber of
+// the volume. The server may return a status up to a minute old.
+func (c *Client) VolumeBitrotStatus(id string) (*api.BitrotStatusResponse, error) {
+
+	// Create request
+	req, err := http.NewRequest("GET",
+		c.host+"/volumes/"+id+"/bitrot/status", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get status
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var status api.BitrotStatusResponse
+	err = utils.GetJsonFromResponse(r, &status)
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// VolumeGeoRepStatus returns the status of the geo-replication session
+// of the volume with the given slave, "[<user>@]<host>::<volume>", on
+// each brick of the volume. The server may return a status up to 30
+// seconds old.
+func (c *Client) VolumeGeoRepStatus(id string,
+	session string) (*api.GeoRepSessionStatusResponse, error) {
+
+	// Create request
+	req, err := http.NewRequest("GET",
+		c.host+"/volumes/"+id+"/georep/sessions/"+session+"/status", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get status
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var status api.GeoRepSessionStatusResponse
+	err = utils.GetJsonFromResponse(r, &status)
+	if err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// volumeCommand posts to the given sub path of the volume and expects
+// an empty response.
+func (c *Client) volumeCommand(id string, path string) error {
+
+	// Create request
+	req, err := http.NewRequest("POST", c.host+"/volumes/"+id+path, nil)
+	if err != nil {
+		return err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusNoContent {
+		return utils.GetErrorFromResponse(r)
+	}
+	return nil
+}
+
+// VolumeProfileStart starts gathering profiling statistics on the
+// bricks of the volume.
+func (c *Client) VolumeProfileStart(id string) error {
+	return c.volumeCommand(id, "/profile/start")
+}
+
+// VolumeProfileStop stops profiling the volume.
+func (c *Client) VolumeProfileStop(id string) error {
+	return c.volumeCommand(id, "/profile/stop")
+}
+
+// VolumeStatedump dumps the state of the brick processes of the
+// volume to files on their nodes.
+func (c *Client) VolumeStatedump(id string) error {
+	return c.volumeCommand(id, "/statedump")
+}
+
+// VolumeProfileInfo returns the statistics gathered since profiling
+// of the volume was started.
+func (c *Client) VolumeProfileInfo(id string) (*api.VolumeProfileInfoResponse, error) {
+
+	// Create request
+	req, err := http.NewRequest("GET",
+		c.host+"/volumes/"+id+"/profile/info", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get info
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var profile api.VolumeProfileInfoResponse
+	err = utils.GetJsonFromResponse(r, &profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
 func (c *Client) VolumeDelete(id string) error {
 
 	// Create a request
@@ -335,3 +678,224 @@ func (c *Client) VolumeClone(id string, request *api.VolumeCloneRequest) (*api.V
 
 	return &volume, nil
 }
+
+// VolumePin restricts the placement of new bricks of the volume to
+// the given nodes. Existing bricks are not moved. An empty list of
+// nodes removes the pinning.
+func (c *Client) VolumePin(id string, request *api.VolumePinRequest) (
+	*api.VolumeInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("PUT",
+		c.host+"/volumes/"+id+"/pin",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var volume api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &volume)
+	if err != nil {
+		return nil, err
+	}
+
+	return &volume, nil
+}
+
+// VolumeImportLayout creates a volume out of bricks that were created
+// outside of heketi.
+func (c *Client) VolumeImportLayout(request *api.VolumeImportLayoutRequest) (
+	*api.VolumeInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("POST",
+		c.host+"/volumes/import-layout",
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusAccepted {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Wait for response
+	r, err = c.pollResponse(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var volume api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &volume)
+	if err != nil {
+		return nil, err
+	}
+
+	return &volume, nil
+}
+
+// VolumeRepair checks the health of the volume and has the server fix
+// the problems it can. The repair runs within the request.
+func (c *Client) VolumeRepair(id string) (*api.VolumeRepairResponse, error) {
+
+	// Create a request
+	req, err := http.NewRequest("POST", c.host+"/volumes/"+id+"/repair", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var repair api.VolumeRepairResponse
+	err = utils.GetJsonFromResponse(r, &repair)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repair, nil
+}
+
+// VolumeDetach removes the volume from heketi without deleting the
+// gluster volume. The returned information describes the volume as it
+// was before it was detached.
+func (c *Client) VolumeDetach(id string) (*api.VolumeInfoResponse, error) {
+
+	// Create a request
+	req, err := http.NewRequest("POST", c.host+"/volumes/"+id+"/detach", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var volume api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &volume)
+	if err != nil {
+		return nil, err
+	}
+
+	return &volume, nil
+}
+
+// VolumePatch applies the JSON Patch (RFC 6902) operations to the
+// metadata of the volume.
+func (c *Client) VolumePatch(id string, ops []api.JsonPatchOperation) (
+	*api.VolumeInfoResponse, error) {
+
+	// Marshal request to JSON
+	buffer, err := json.Marshal(ops)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a request
+	req, err := http.NewRequest("PATCH",
+		c.host+"/volumes/"+id,
+		bytes.NewBuffer(buffer))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json-patch+json")
+
+	// Set token
+	err = c.setToken(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	r, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, utils.GetErrorFromResponse(r)
+	}
+
+	// Read JSON response
+	var volume api.VolumeInfoResponse
+	err = utils.GetJsonFromResponse(r, &volume)
+	if err != nil {
+		return nil, err
+	}
+
+	return &volume, nil
+}
@@ -0,0 +1,264 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+var (
+	benchmarkVolumeCount int
+	benchmarkParallel    int
+	benchmarkSize        int
+	benchmarkReplica     int
+	benchmarkKeep        bool
+	benchmarkOutput      string
+)
+
+func init() {
+	RootCmd.AddCommand(benchmarkCommand)
+	benchmarkCommand.Flags().IntVar(&benchmarkVolumeCount, "volume-count", 10,
+		"\n\tNumber of volumes to create")
+	benchmarkCommand.Flags().IntVar(&benchmarkParallel, "parallel", 1,
+		"\n\tNumber of volumes to create at the same time")
+	benchmarkCommand.Flags().IntVar(&benchmarkSize, "size", 1,
+		"\n\tSize of each volume in GiB")
+	benchmarkCommand.Flags().IntVar(&benchmarkReplica, "replica", 3,
+		"\n\tReplica count of the volumes")
+	benchmarkCommand.Flags().BoolVar(&benchmarkKeep, "keep", false,
+		"\n\tOptional: Do not delete the volumes created by the benchmark")
+	benchmarkCommand.Flags().StringVar(&benchmarkOutput, "output", "heketi-benchmark.json",
+		"\n\tFile the results are written to as JSON")
+	benchmarkCommand.SilenceUsage = true
+}
+
+var benchmarkCommand = &cobra.Command{
+	Use:   "benchmark",
+	Short: "Measures the volume provisioning throughput",
+	Long: "Creates volumes in parallel, reports the provisioning latency\n" +
+		"and deletes the volumes again.",
+	Example: "  $ heketi-cli benchmark --volume-count 20 --parallel 4",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if benchmarkVolumeCount < 1 {
+			return errors.New("volume-count must be at least 1")
+		}
+		if benchmarkParallel < 1 {
+			return errors.New("parallel must be at least 1")
+		}
+		if benchmarkSize < 1 {
+			return errors.New("size must be at least 1")
+		}
+
+		heketi, err := newHeketiClient()
+		if err != nil {
+			return err
+		}
+		results := runBenchmark(heketi, benchmarkOptions{
+			VolumeCount: benchmarkVolumeCount,
+			Parallel:    benchmarkParallel,
+			Size:        benchmarkSize,
+			Replica:     benchmarkReplica,
+			Keep:        benchmarkKeep,
+		})
+		if err := saveJson(results, benchmarkOutput); err != nil {
+			return err
+		}
+		if options.Json {
+			data, err := json.Marshal(results)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(stdout, string(data))
+		} else {
+			printBenchmarkResults(stdout, results)
+		}
+		if results.Created == 0 {
+			return errors.New("Unable to create any volume")
+		}
+		return nil
+	},
+}
+
+// volumeProvisioner is the subset of the client api used by the
+// benchmark.
+type volumeProvisioner interface {
+	VolumeCreate(request *api.VolumeCreateRequest) (*api.VolumeInfoResponse, error)
+	VolumeDelete(id string) error
+}
+
+type benchmarkOptions struct {
+	VolumeCount int
+	Parallel    int
+	Size        int
+	Replica     int
+	Keep        bool
+}
+
+// benchmarkLatency holds the statistics of the provisioning latency
+// of the volumes, in milliseconds.
+type benchmarkLatency struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	P50 float64 `json:"p50"`
+	P99 float64 `json:"p99"`
+}
+
+type benchmarkResults struct {
+	VolumeCount int `json:"volume_count"`
+	Parallel    int `json:"parallel"`
+	Size        int `json:"size"`
+	Created     int `json:"created"`
+	Failed      int `json:"failed"`
+	Deleted     int `json:"deleted"`
+	// time taken to create all volumes, in seconds
+	DurationSeconds  float64          `json:"duration_seconds"`
+	VolumesPerMinute float64          `json:"volumes_per_minute"`
+	LatencyMs        benchmarkLatency `json:"latency_ms"`
+	Errors           []string         `json:"errors,omitempty"`
+}
+
+// percentile returns the nearest rank percentile of the sorted values.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// runBenchmark creates the volumes using up to Parallel concurrent
+// requests and deletes them again unless Keep is set. Failed requests
+// are counted and reported in the results.
+func runBenchmark(c volumeProvisioner, opts benchmarkOptions) *benchmarkResults {
+	results := &benchmarkResults{
+		VolumeCount: opts.VolumeCount,
+		Parallel:    opts.Parallel,
+		Size:        opts.Size,
+	}
+	var (
+		lock      sync.Mutex
+		wg        sync.WaitGroup
+		latencies []time.Duration
+		created   []string
+	)
+	fail := func(err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		results.Errors = append(results.Errors, err.Error())
+	}
+
+	queue := make(chan int)
+	start := time.Now()
+	for i := 0; i < opts.Parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range queue {
+				req := &api.VolumeCreateRequest{}
+				req.Size = opts.Size
+				req.Durability.Type = api.DurabilityReplicate
+				req.Durability.Replicate.Replica = opts.Replica
+
+				t := time.Now()
+				volume, err := c.VolumeCreate(req)
+				latency := time.Since(t)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				lock.Lock()
+				latencies = append(latencies, latency)
+				created = append(created, volume.Id)
+				lock.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < opts.VolumeCount; i++ {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	results.Created = len(created)
+	results.Failed = opts.VolumeCount - len(created)
+	results.DurationSeconds = elapsed.Seconds()
+	if elapsed > 0 {
+		results.VolumesPerMinute = float64(len(created)) / elapsed.Minutes()
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool {
+			return latencies[i] < latencies[j]
+		})
+		results.LatencyMs = benchmarkLatency{
+			Min: milliseconds(latencies[0]),
+			Max: milliseconds(latencies[len(latencies)-1]),
+			P50: milliseconds(percentile(latencies, 50)),
+			P99: milliseconds(percentile(latencies, 99)),
+		}
+	}
+
+	if opts.Keep {
+		return results
+	}
+	ids := make(chan string)
+	for i := 0; i < opts.Parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				if err := c.VolumeDelete(id); err != nil {
+					fail(fmt.Errorf("Unable to delete volume %v: %v", id, err))
+					continue
+				}
+				lock.Lock()
+				results.Deleted++
+				lock.Unlock()
+			}
+		}()
+	}
+	for _, id := range created {
+		ids <- id
+	}
+	close(ids)
+	wg.Wait()
+	return results
+}
+
+func printBenchmarkResults(w io.Writer, r *benchmarkResults) {
+	fmt.Fprintf(w, "Volumes created: %v of %v (%v in parallel)\n",
+		r.Created, r.VolumeCount, r.Parallel)
+	fmt.Fprintf(w, "Volumes deleted: %v\n", r.Deleted)
+	fmt.Fprintf(w, "Duration (s): %.1f\n", r.DurationSeconds)
+	fmt.Fprintf(w, "Throughput (volumes/min): %.1f\n", r.VolumesPerMinute)
+	fmt.Fprintf(w, "Latency (ms): min %.0f, max %.0f, p50 %.0f, p99 %.0f\n",
+		r.LatencyMs.Min, r.LatencyMs.Max, r.LatencyMs.P50, r.LatencyMs.P99)
+	for _, e := range r.Errors {
+		fmt.Fprintf(w, "Error: %v\n", e)
+	}
+}
@@ -0,0 +1,196 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/tests"
+)
+
+// fakeVolumeServer serves the asynchronous volume create and delete
+// requests of the heketi api. Creating a volume takes the given
+// latency.
+type fakeVolumeServer struct {
+	latency time.Duration
+
+	lock    sync.Mutex
+	next    int
+	volumes map[string]bool
+	deletes int
+	// volume creates that fail, by order of the request
+	failCreate map[int]bool
+}
+
+func newFakeVolumeServer(latency time.Duration) *fakeVolumeServer {
+	return &fakeVolumeServer{
+		latency:    latency,
+		volumes:    map[string]bool{},
+		failCreate: map[int]bool{},
+	}
+}
+
+func (f *fakeVolumeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case r.Method == "POST" && path == "/volumes":
+		time.Sleep(f.latency)
+		f.lock.Lock()
+		f.next++
+		n := f.next
+		id := fmt.Sprintf("%032x", n)
+		failed := f.failCreate[n]
+		if !failed {
+			f.volumes[id] = true
+		}
+		f.lock.Unlock()
+		if failed {
+			http.Error(w, "No space", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Location", "/queue/create/"+id)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == "GET" && strings.HasPrefix(path, "/queue/create/"):
+		id := strings.TrimPrefix(path, "/queue/create/")
+		http.Redirect(w, r, "/volumes/"+id, http.StatusSeeOther)
+	case r.Method == "GET" && strings.HasPrefix(path, "/volumes/"):
+		info := api.VolumeInfoResponse{}
+		info.Id = strings.TrimPrefix(path, "/volumes/")
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		json.NewEncoder(w).Encode(info)
+	case r.Method == "DELETE" && strings.HasPrefix(path, "/volumes/"):
+		id := strings.TrimPrefix(path, "/volumes/")
+		f.lock.Lock()
+		found := f.volumes[id]
+		delete(f.volumes, id)
+		f.deletes++
+		f.lock.Unlock()
+		if !found {
+			http.Error(w, "Id not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Location", "/queue/delete/"+id)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == "GET" && strings.HasPrefix(path, "/queue/delete/"):
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []time.Duration{}
+	for i := 1; i <= 200; i++ {
+		values = append(values, time.Duration(i))
+	}
+	tests.Assert(t, percentile(values, 50) == 100, percentile(values, 50))
+	tests.Assert(t, percentile(values, 99) == 198, percentile(values, 99))
+	tests.Assert(t, percentile(values, 0) == 1, percentile(values, 0))
+	tests.Assert(t, percentile(values, 100) == 200, percentile(values, 100))
+	tests.Assert(t, percentile(values[:1], 99) == 1, percentile(values[:1], 99))
+	tests.Assert(t, percentile(nil, 50) == 0, percentile(nil, 50))
+}
+
+func TestBenchmark(t *testing.T) {
+	latency := 20 * time.Millisecond
+	f := newFakeVolumeServer(latency)
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+
+	c := client.NewClientNoAuth(ts.URL)
+	r := runBenchmark(c, benchmarkOptions{
+		VolumeCount: 8,
+		Parallel:    4,
+		Size:        1,
+		Replica:     3,
+	})
+	tests.Assert(t, len(r.Errors) == 0, "expected no errors, got:", r.Errors)
+	tests.Assert(t, r.Created == 8, "expected 8 volumes created, got:", r.Created)
+	tests.Assert(t, r.Failed == 0, "expected no failures, got:", r.Failed)
+	tests.Assert(t, r.Deleted == 8, "expected 8 volumes deleted, got:", r.Deleted)
+	tests.Assert(t, f.deletes == 8, "expected 8 delete requests, got:", f.deletes)
+	tests.Assert(t, len(f.volumes) == 0, "expected no volumes left, got:", f.volumes)
+
+	// every create takes at least the latency of the server, the
+	// upper bound leaves room for slow test machines
+	l := r.LatencyMs
+	tests.Assert(t, l.Min >= 20, "expected min >= 20ms, got:", l)
+	tests.Assert(t, l.Max < 1000, "expected max < 1s, got:", l)
+	tests.Assert(t, l.Min <= l.P50 && l.P50 <= l.P99 && l.P99 <= l.Max,
+		"expected ordered statistics, got:", l)
+	// nearest rank p99 of 8 values is the largest value
+	tests.Assert(t, l.P99 == l.Max, "expected p99 == max, got:", l)
+
+	// two batches of four parallel creates
+	tests.Assert(t, r.DurationSeconds >= 0.04,
+		"expected at least 40ms, got:", r.DurationSeconds)
+	tests.Assert(t, r.VolumesPerMinute > 0,
+		"expected throughput, got:", r.VolumesPerMinute)
+}
+
+func TestBenchmarkKeep(t *testing.T) {
+	f := newFakeVolumeServer(time.Millisecond)
+	f.failCreate[2] = true
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+
+	c := client.NewClientNoAuth(ts.URL)
+	r := runBenchmark(c, benchmarkOptions{
+		VolumeCount: 3,
+		Parallel:    1,
+		Size:        1,
+		Replica:     3,
+		Keep:        true,
+	})
+	tests.Assert(t, r.Created == 2, "expected 2 volumes created, got:", r.Created)
+	tests.Assert(t, r.Failed == 1, "expected 1 failure, got:", r.Failed)
+	tests.Assert(t, len(r.Errors) == 1, "expected 1 error, got:", r.Errors)
+	tests.Assert(t, r.Deleted == 0, "expected no volumes deleted, got:", r.Deleted)
+	tests.Assert(t, f.deletes == 0, "expected no delete requests, got:", f.deletes)
+	tests.Assert(t, len(f.volumes) == 2, "expected 2 volumes left, got:", f.volumes)
+	tests.Assert(t, r.LatencyMs.Min >= 1, "expected min >= 1ms, got:", r.LatencyMs)
+}
+
+func TestBenchmarkResultsFile(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	r := &benchmarkResults{
+		VolumeCount: 2,
+		Parallel:    1,
+		Size:        1,
+		Created:     2,
+		Deleted:     2,
+		LatencyMs:   benchmarkLatency{Min: 10, Max: 30, P50: 10, P99: 30},
+	}
+	err := saveJson(r, tmpfile)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	data, err := ioutil.ReadFile(tmpfile)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	var m map[string]interface{}
+	err = json.Unmarshal(data, &m)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, m["created"] == float64(2), "expected created, got:", m)
+	latency, ok := m["latency_ms"].(map[string]interface{})
+	tests.Assert(t, ok, "expected latency_ms, got:", m)
+	tests.Assert(t, latency["p99"] == float64(30), "expected p99, got:", latency)
+}
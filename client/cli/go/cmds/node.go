@@ -21,6 +21,8 @@ import (
 
 var (
 	zone               int
+	region             string
+	nodeTspId          string
 	managmentHostNames string
 	storageHostNames   string
 	clusterId          string
@@ -37,7 +39,10 @@ func init() {
 	nodeCommand.AddCommand(nodeRemoveCommand)
 	nodeCommand.AddCommand(nodeSetTagsCommand)
 	nodeCommand.AddCommand(nodeRmTagsCommand)
+	nodeCommand.AddCommand(nodeDiagnoseCommand)
 	nodeAddCommand.Flags().IntVar(&zone, "zone", 0, "The zone in which the node should reside")
+	nodeAddCommand.Flags().StringVar(&region, "region", "", "Optional: The geographic region in which the node resides")
+	nodeAddCommand.Flags().StringVar(&nodeTspId, "tsp-id", "", "Optional: The trusted storage pool of the cluster the node is peered into")
 	nodeAddCommand.Flags().StringVar(&clusterId, "cluster", "", "The cluster in which the node should reside")
 	nodeAddCommand.Flags().StringVar(&managmentHostNames, "management-host-name", "", "Management host name")
 	nodeAddCommand.Flags().StringVar(&storageHostNames, "storage-host-name", "", "Storage host name")
@@ -51,6 +56,7 @@ func init() {
 	nodeListCommand.SilenceUsage = true
 	nodeRemoveCommand.SilenceUsage = true
 	nodeSetTagsCommand.SilenceUsage = true
+	nodeDiagnoseCommand.SilenceUsage = true
 }
 
 var nodeCommand = &cobra.Command{
@@ -90,6 +96,8 @@ var nodeAddCommand = &cobra.Command{
 		req.Hostnames.Manage = []string{managmentHostNames}
 		req.Hostnames.Storage = []string{storageHostNames}
 		req.Zone = zone
+		req.Region = region
+		req.TspId = nodeTspId
 
 		// Create a client
 		heketi, err := newHeketiClient()
@@ -375,6 +383,131 @@ var nodeRmTagsCommand = &cobra.Command{
 	},
 }
 
+var nodeDiagnoseCommand = &cobra.Command{
+	Use:   "diagnose [node_id]",
+	Short: "Runs a series of checks on a node",
+	Long: "Runs a series of checks on a node and prints a report of\n" +
+		"the node's registration, bricks, connectivity, devices and health.",
+	Example: "  $ heketi-cli node diagnose 886a86a868711bef83001",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		//ensure proper number of args
+		s := cmd.Flags().Args()
+		if len(s) < 1 {
+			return errors.New("Node id missing")
+		}
+		nodeId := cmd.Flags().Arg(0)
+
+		heketi, err := newHeketiClient()
+		if err != nil {
+			return err
+		}
+		return nodeDiagnose(stdout, heketi, nodeId)
+	},
+}
+
+// nodeDiagnoser is the subset of the client api used to diagnose a node.
+type nodeDiagnoser interface {
+	NodeInfo(id string) (*api.NodeInfoResponse, error)
+	NodeBricks(id string) (*api.NodeBricksResponse, error)
+	NodePing(id string) (*api.NodePingResponse, error)
+	DeviceInfo(id string) (*api.DeviceInfoResponse, error)
+}
+
+// nodeDiagnose writes a multi-section report about the node to w.
+// Failures of individual checks are included in the report, only
+// a failure to fetch the node itself is returned as an error.
+func nodeDiagnose(w io.Writer, c nodeDiagnoser, nodeId string) error {
+	section := func(title string) {
+		fmt.Fprintf(w, "=== %v ===\n", title)
+	}
+
+	info, err := c.NodeInfo(nodeId)
+	if err != nil {
+		return err
+	}
+	section("Node")
+	fmt.Fprintf(w, "Node Id: %v\n"+
+		"State: %v\n"+
+		"Cluster Id: %v\n"+
+		"Zone: %v\n"+
+		"Management Hostname: %v\n"+
+		"Storage Hostname: %v\n",
+		info.Id,
+		entryStateString(info.State),
+		info.ClusterId,
+		info.Zone,
+		info.Hostnames.Manage[0],
+		info.Hostnames.Storage[0])
+
+	fmt.Fprintf(w, "\n")
+	section("Bricks")
+	bricks, err := c.NodeBricks(nodeId)
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+	} else if len(bricks.Bricks) == 0 {
+		fmt.Fprintf(w, "No bricks\n")
+	} else {
+		for _, b := range bricks.Bricks {
+			fmt.Fprintf(w, "Id:%-35v"+
+				"Size (GiB):%-8v"+
+				"Volume:%-35v"+
+				"Path: %v\n",
+				b.Id,
+				b.Size/(1024*1024),
+				b.VolumeId,
+				b.Path)
+		}
+	}
+
+	fmt.Fprintf(w, "\n")
+	section("Connectivity")
+	ping, err := c.NodePing(nodeId)
+	switch {
+	case err != nil:
+		fmt.Fprintf(w, "Error: %v\n", err)
+	case ping.Reachable:
+		fmt.Fprintf(w, "Reachable: yes\nLatency (ms): %.1f\n", ping.LatencyMs)
+	default:
+		fmt.Fprintf(w, "Reachable: no\nError: %v\n", ping.Error)
+	}
+
+	fmt.Fprintf(w, "\n")
+	section("Devices")
+	if len(info.DevicesInfo) == 0 {
+		fmt.Fprintf(w, "No devices\n")
+	}
+	for _, d := range info.DevicesInfo {
+		device, err := c.DeviceInfo(d.Id)
+		if err != nil {
+			fmt.Fprintf(w, "Id:%-35vError: %v\n", d.Id, err)
+			continue
+		}
+		fmt.Fprintf(w, "Id:%-35v"+
+			"Name:%-20v"+
+			"State:%-10v"+
+			"Size (GiB):%-8v"+
+			"Used (GiB):%-8v"+
+			"Free (GiB):%-8v"+
+			"Bricks:%-8v\n",
+			device.Id,
+			device.Name,
+			entryStateString(device.State),
+			device.Storage.Total/(1024*1024),
+			device.Storage.Used/(1024*1024),
+			device.Storage.Free/(1024*1024),
+			len(device.Bricks))
+	}
+
+	fmt.Fprintf(w, "\n")
+	section("Health Monitor")
+	if ping != nil {
+		fmt.Fprintf(w, "State: %v\n", ping.Health)
+	} else {
+		fmt.Fprintf(w, "State: %v\n", api.NodeHealthUnknown)
+	}
+	return nil
+}
+
 func printNodeInfo(w io.Writer, info *api.NodeInfoResponse) {
 	fmt.Fprintf(stdout, "Node Id: %v\n"+
 		"State: %v\n"+
@@ -388,6 +521,12 @@ func printNodeInfo(w io.Writer, info *api.NodeInfoResponse) {
 		info.Zone,
 		info.Hostnames.Manage[0],
 		info.Hostnames.Storage[0])
+	if info.Region != "" {
+		fmt.Fprintf(stdout, "Region: %v\n", info.Region)
+	}
+	if info.TspId != "" {
+		fmt.Fprintf(stdout, "Trusted Storage Pool: %v\n", info.TspId)
+	}
 	if len(info.Tags) != 0 {
 		fmt.Fprintf(stdout, "Tags:\n")
 		for k, v := range info.Tags {
@@ -0,0 +1,182 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/tests"
+)
+
+type fakeNodeDiagnoser struct {
+	info    *api.NodeInfoResponse
+	bricks  *api.NodeBricksResponse
+	ping    *api.NodePingResponse
+	devices map[string]*api.DeviceInfoResponse
+	calls   []string
+}
+
+func (f *fakeNodeDiagnoser) NodeInfo(id string) (*api.NodeInfoResponse, error) {
+	f.calls = append(f.calls, "NodeInfo")
+	if f.info == nil {
+		return nil, errors.New("Id not found")
+	}
+	return f.info, nil
+}
+
+func (f *fakeNodeDiagnoser) NodeBricks(id string) (*api.NodeBricksResponse, error) {
+	f.calls = append(f.calls, "NodeBricks")
+	return f.bricks, nil
+}
+
+func (f *fakeNodeDiagnoser) NodePing(id string) (*api.NodePingResponse, error) {
+	f.calls = append(f.calls, "NodePing")
+	if f.ping == nil {
+		return nil, errors.New("ping failed")
+	}
+	return f.ping, nil
+}
+
+func (f *fakeNodeDiagnoser) DeviceInfo(id string) (*api.DeviceInfoResponse, error) {
+	f.calls = append(f.calls, "DeviceInfo")
+	d, ok := f.devices[id]
+	if !ok {
+		return nil, errors.New("Id not found")
+	}
+	return d, nil
+}
+
+func newFakeNodeDiagnoser() *fakeNodeDiagnoser {
+	info := &api.NodeInfoResponse{}
+	info.Id = "abc123"
+	info.ClusterId = "c1"
+	info.Zone = 1
+	info.Hostnames.Manage = []string{"node1.manage"}
+	info.Hostnames.Storage = []string{"10.0.0.1"}
+	info.State = api.EntryStateOnline
+	d1 := api.DeviceInfoResponse{}
+	d1.Id = "d1"
+	d2 := api.DeviceInfoResponse{}
+	d2.Id = "d2"
+	info.DevicesInfo = []api.DeviceInfoResponse{d1, d2}
+
+	dev := &api.DeviceInfoResponse{}
+	dev.Id = "d1"
+	dev.Name = "/dev/sdb"
+	dev.State = api.EntryStateOnline
+	dev.Storage.Total = 10 * 1024 * 1024
+
+	return &fakeNodeDiagnoser{
+		info: info,
+		bricks: &api.NodeBricksResponse{
+			Bricks: []api.BrickInfo{
+				{Id: "b1", Path: "/bricks/b1", VolumeId: "v1", Size: 2 * 1024 * 1024},
+			},
+		},
+		ping: &api.NodePingResponse{
+			Reachable: true,
+			LatencyMs: 3.4,
+			Health:    api.NodeHealthUp,
+		},
+		devices: map[string]*api.DeviceInfoResponse{"d1": dev},
+	}
+}
+
+func TestNodeDiagnoseReport(t *testing.T) {
+	f := newFakeNodeDiagnoser()
+	var out bytes.Buffer
+	err := nodeDiagnose(&out, f, "abc123")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	report := out.String()
+	sections := []string{
+		"=== Node ===",
+		"=== Bricks ===",
+		"=== Connectivity ===",
+		"=== Devices ===",
+		"=== Health Monitor ===",
+	}
+	last := -1
+	for _, s := range sections {
+		idx := strings.Index(report, s)
+		tests.Assert(t, idx > last, "expected section", s, "in order, got:", report)
+		last = idx
+	}
+	for _, s := range []string{
+		"Node Id: abc123",
+		"Management Hostname: node1.manage",
+		"Id:b1",
+		"Path: /bricks/b1",
+		"Reachable: yes",
+		"Latency (ms): 3.4",
+		"Name:/dev/sdb",
+		"Id:d2",
+		"State: up",
+	} {
+		tests.Assert(t, strings.Contains(report, s),
+			"expected report to contain", s, "got:", report)
+	}
+
+	// every device of the node is looked up
+	devCalls := 0
+	for _, c := range f.calls {
+		if c == "DeviceInfo" {
+			devCalls++
+		}
+	}
+	tests.Assert(t, devCalls == 2, "expected devCalls == 2, got:", devCalls)
+}
+
+func TestNodeDiagnoseUnreachable(t *testing.T) {
+	f := newFakeNodeDiagnoser()
+	f.ping = &api.NodePingResponse{
+		Error:  "connection refused",
+		Health: api.NodeHealthDown,
+	}
+	f.bricks = &api.NodeBricksResponse{}
+	var out bytes.Buffer
+	err := nodeDiagnose(&out, f, "abc123")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	report := out.String()
+	for _, s := range []string{
+		"No bricks",
+		"Reachable: no",
+		"Error: connection refused",
+		"State: down",
+	} {
+		tests.Assert(t, strings.Contains(report, s),
+			"expected report to contain", s, "got:", report)
+	}
+
+	// a failed ping request still produces the full report
+	f.ping = nil
+	out.Reset()
+	err = nodeDiagnose(&out, f, "abc123")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	report = out.String()
+	tests.Assert(t, strings.Contains(report, "Error: ping failed"),
+		"expected ping error in report, got:", report)
+	tests.Assert(t, strings.Contains(report, "State: unknown"),
+		"expected unknown health in report, got:", report)
+}
+
+func TestNodeDiagnoseMissingNode(t *testing.T) {
+	f := newFakeNodeDiagnoser()
+	f.info = nil
+	var out bytes.Buffer
+	err := nodeDiagnose(&out, f, "abc123")
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, out.Len() == 0, "expected no output, got:", out.String())
+}
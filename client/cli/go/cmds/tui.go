@@ -0,0 +1,243 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"github.com/spf13/cobra"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+const (
+	// lines kept in the log view
+	dashboardLogLines = 500
+	// width of the device utilization bars
+	dashboardBarWidth = 40
+)
+
+var tuiRefresh time.Duration
+
+func init() {
+	RootCmd.AddCommand(tuiCommand)
+	tuiCommand.Flags().DurationVar(&tuiRefresh, "refresh", 5*time.Second,
+		"\n\tTime between two updates of the dashboard")
+	tuiCommand.SilenceUsage = true
+}
+
+var tuiCommand = &cobra.Command{
+	Use:   "tui",
+	Short: "Shows a dashboard of the clusters in the terminal",
+	Long: "Shows the clusters, the pending operations, the utilization\n" +
+		"of the devices and the recent cluster events. The dashboard is\n" +
+		"updated periodically. Tab moves between the panels, q quits.",
+	Example: "  $ heketi-cli tui --refresh 10s",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if tuiRefresh < time.Second {
+			return errors.New("refresh must be at least 1s")
+		}
+		heketi, err := newHeketiClient()
+		if err != nil {
+			return err
+		}
+		return newDashboard(newDashboardPoller(heketi)).Run(tuiRefresh)
+	},
+}
+
+// dashboard renders the snapshots of a dashboardPoller.
+type dashboard struct {
+	app    *tview.Application
+	poller *dashboardPoller
+
+	clusters   *tview.Table
+	operations *tview.Table
+	devices    *tview.TextView
+	log        *tview.TextView
+	status     *tview.TextView
+	// panels Tab moves the focus between
+	focus   []tview.Primitive
+	focused int
+
+	logLines []string
+}
+
+func newDashboard(p *dashboardPoller) *dashboard {
+	d := &dashboard{
+		app:    tview.NewApplication(),
+		poller: p,
+	}
+
+	d.clusters = tview.NewTable().SetFixed(1, 0)
+	d.clusters.SetBorder(true).SetTitle(" Clusters ")
+	d.operations = tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	d.operations.SetBorder(true).SetTitle(" Pending Operations ")
+	d.devices = tview.NewTextView().SetDynamicColors(true)
+	d.devices.SetBorder(true).SetTitle(" Device Utilization ")
+	d.log = tview.NewTextView().SetDynamicColors(true)
+	d.log.SetBorder(true).SetTitle(" Log ")
+	d.status = tview.NewTextView()
+	d.focus = []tview.Primitive{d.operations, d.devices, d.log}
+
+	top := tview.NewFlex().
+		AddItem(d.clusters, 0, 1, false).
+		AddItem(d.operations, 0, 1, true)
+	root := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(top, 0, 1, true).
+		AddItem(d.devices, 0, 1, false).
+		AddItem(d.log, 0, 1, false).
+		AddItem(d.status, 1, 0, false)
+	d.app.SetRoot(root, true).SetFocus(d.operations)
+	d.app.SetInputCapture(d.handleKey)
+	return d
+}
+
+func (d *dashboard) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	switch {
+	case event.Key() == tcell.KeyTab:
+		d.focused = (d.focused + 1) % len(d.focus)
+		d.app.SetFocus(d.focus[d.focused])
+		return nil
+	case event.Rune() == 'q':
+		d.app.Stop()
+		return nil
+	}
+	return event
+}
+
+// Run shows the dashboard until the user quits. The dashboard is
+// updated every refresh interval.
+func (d *dashboard) Run(refresh time.Duration) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		t := time.NewTicker(refresh)
+		defer t.Stop()
+		for {
+			d.update()
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+		}
+	}()
+	return d.app.Run()
+}
+
+// update polls the server and redraws the panels. On errors the last
+// data stays on screen.
+func (d *dashboard) update() {
+	s, err := d.poller.Poll()
+	d.app.QueueUpdateDraw(func() {
+		now := time.Now().Format("2006-01-02 15:04:05")
+		if err != nil {
+			d.appendLog([]string{fmt.Sprintf("%v %v", now, err)})
+			d.status.SetText(fmt.Sprintf("Update failed at %v", now))
+			return
+		}
+		d.showClusters(s.Clusters)
+		d.showOperations(s.Operations)
+		d.showDevices(s.Devices)
+		d.appendLog(s.Log)
+		d.status.SetText(fmt.Sprintf("Updated at %v", now))
+	})
+}
+
+func dashboardHeader(t *tview.Table, titles ...string) {
+	for i, title := range titles {
+		t.SetCell(0, i, tview.NewTableCell(title).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false))
+	}
+}
+
+func (d *dashboard) showClusters(clusters []dashboardCluster) {
+	d.clusters.Clear()
+	dashboardHeader(d.clusters,
+		"Cluster", "Nodes", "Devices", "Volumes", "Used", "Free")
+	for i, c := range clusters {
+		row := []string{
+			c.Id,
+			fmt.Sprintf("%v/%v", c.OnlineNodes, c.Nodes),
+			fmt.Sprintf("%v", c.Devices),
+			fmt.Sprintf("%v", c.Volumes),
+			fmt.Sprintf("%v GiB", c.Used/(1024*1024)),
+			fmt.Sprintf("%v GiB", c.Free/(1024*1024)),
+		}
+		for j, text := range row {
+			d.clusters.SetCell(i+1, j, tview.NewTableCell(text))
+		}
+	}
+}
+
+func (d *dashboard) showOperations(ops []api.PendingOperationInfo) {
+	d.operations.Clear()
+	dashboardHeader(d.operations, "Operation", "Type", "Status", "Progress")
+	for i, op := range ops {
+		status := op.Status
+		if op.SubStatus != "" {
+			status += "/" + op.SubStatus
+		}
+		row := []string{
+			op.Id,
+			op.TypeName,
+			status,
+			fmt.Sprintf("%v%%", op.Progress),
+		}
+		for j, text := range row {
+			d.operations.SetCell(i+1, j, tview.NewTableCell(text))
+		}
+	}
+}
+
+func (d *dashboard) showDevices(devices []dashboardDevice) {
+	var b bytes.Buffer
+	for _, dev := range devices {
+		pct := dev.UsedPercent()
+		filled := int(pct * dashboardBarWidth / 100)
+		if filled > dashboardBarWidth {
+			filled = dashboardBarWidth
+		}
+		color := "green"
+		switch {
+		case pct >= 90:
+			color = "red"
+		case pct >= 75:
+			color = "yellow"
+		}
+		name := fmt.Sprintf("%-30.30s", dev.Node+":"+dev.Name)
+		fmt.Fprintf(&b, "%v [%v]%v[-]%v %5.1f%%\n",
+			tview.Escape(name),
+			color, strings.Repeat("█", filled),
+			strings.Repeat("░", dashboardBarWidth-filled), pct)
+	}
+	d.devices.SetText(b.String())
+}
+
+func (d *dashboard) appendLog(lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	for _, l := range lines {
+		d.logLines = append(d.logLines, tview.Escape(l))
+	}
+	if len(d.logLines) > dashboardLogLines {
+		d.logLines = d.logLines[len(d.logLines)-dashboardLogLines:]
+	}
+	d.log.SetText(strings.Join(d.logLines, "\n"))
+	d.log.ScrollToEnd()
+}
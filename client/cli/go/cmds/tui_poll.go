@@ -0,0 +1,230 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// how far back the cluster events are shown when the dashboard starts
+const dashboardEventBacklog = time.Hour
+
+// dashboardClient is the subset of the client api polled by the
+// dashboard.
+type dashboardClient interface {
+	TopologyInfo() (*api.TopologyInfoResponse, error)
+	PendingOperationList() (*api.PendingOperationListResponse, error)
+	ClusterEvents(id string, since int64, limit int) (*api.ClusterEventListResponse, error)
+}
+
+// dashboardCluster summarizes a cluster. Sizes are in KiB.
+type dashboardCluster struct {
+	Id          string
+	Nodes       int
+	OnlineNodes int
+	Devices     int
+	Volumes     int
+	Total       uint64
+	Used        uint64
+	Free        uint64
+}
+
+// dashboardDevice holds the utilization of a device. Sizes are in KiB.
+type dashboardDevice struct {
+	Id    string
+	Node  string
+	Name  string
+	Total uint64
+	Used  uint64
+}
+
+// UsedPercent returns the share of the device used by bricks.
+func (d dashboardDevice) UsedPercent() float64 {
+	if d.Total == 0 {
+		return 0
+	}
+	return float64(d.Used) * 100 / float64(d.Total)
+}
+
+// dashboardSnapshot is the state of the server at the time of a poll.
+type dashboardSnapshot struct {
+	Time       time.Time
+	Clusters   []dashboardCluster
+	Devices    []dashboardDevice
+	Operations []api.PendingOperationInfo
+	// lines to add to the log since the previous poll
+	Log []string
+}
+
+// dashboardPoller fetches the state shown by the dashboard. It keeps
+// track of what was seen before so that every poll only logs the
+// cluster events and operation changes that are new.
+type dashboardPoller struct {
+	client dashboardClient
+	start  time.Time
+	// timestamp of the newest event of each cluster
+	since map[string]int64
+	// ids of the events at the since timestamp of each cluster, these
+	// are returned again by the next poll
+	seen map[string]map[string]bool
+	// status of the operations of the previous poll, nil before the
+	// first poll
+	ops map[string]string
+}
+
+func newDashboardPoller(c dashboardClient) *dashboardPoller {
+	return &dashboardPoller{
+		client: c,
+		start:  time.Now(),
+		since:  map[string]int64{},
+		seen:   map[string]map[string]bool{},
+	}
+}
+
+// Poll returns the current state of the server.
+func (p *dashboardPoller) Poll() (*dashboardSnapshot, error) {
+	topo, err := p.client.TopologyInfo()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get topology: %v", err)
+	}
+	pops, err := p.client.PendingOperationList()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get pending operations: %v", err)
+	}
+
+	s := &dashboardSnapshot{
+		Time:       time.Now(),
+		Clusters:   []dashboardCluster{},
+		Devices:    []dashboardDevice{},
+		Operations: pops.PendingOperations,
+		Log:        []string{},
+	}
+	for _, c := range topo.ClusterList {
+		dc := dashboardCluster{
+			Id:      c.Id,
+			Nodes:   len(c.Nodes),
+			Volumes: len(c.Volumes),
+		}
+		for _, n := range c.Nodes {
+			if n.State == api.EntryStateOnline {
+				dc.OnlineNodes++
+			}
+			node := n.Id
+			if len(n.Hostnames.Manage) > 0 {
+				node = n.Hostnames.Manage[0]
+			}
+			for _, d := range n.DevicesInfo {
+				dc.Devices++
+				dc.Total += d.Storage.Total
+				dc.Used += d.Storage.Used
+				dc.Free += d.Storage.Free
+				s.Devices = append(s.Devices, dashboardDevice{
+					Id:    d.Id,
+					Node:  node,
+					Name:  d.Name,
+					Total: d.Storage.Total,
+					Used:  d.Storage.Used,
+				})
+			}
+		}
+		s.Clusters = append(s.Clusters, dc)
+
+		lines, err := p.pollEvents(c.Id)
+		if err != nil {
+			return nil, err
+		}
+		s.Log = append(s.Log, lines...)
+	}
+	s.Log = append(s.Log, p.operationChanges(s.Time, s.Operations)...)
+
+	sort.Slice(s.Devices, func(i, j int) bool {
+		if s.Devices[i].Node != s.Devices[j].Node {
+			return s.Devices[i].Node < s.Devices[j].Node
+		}
+		return s.Devices[i].Name < s.Devices[j].Name
+	})
+	return s, nil
+}
+
+// pollEvents returns a log line for each event of the cluster that was
+// not seen before.
+func (p *dashboardPoller) pollEvents(id string) ([]string, error) {
+	since, ok := p.since[id]
+	if !ok {
+		since = p.start.Add(-dashboardEventBacklog).Unix()
+	}
+	events, err := p.client.ClusterEvents(id, since, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get events of cluster %v: %v", id, err)
+	}
+
+	seen := p.seen[id]
+	if seen == nil {
+		seen = map[string]bool{}
+	}
+	lines := []string{}
+	for _, e := range events.Events {
+		if seen[e.Id] {
+			continue
+		}
+		if e.Timestamp > since {
+			since = e.Timestamp
+			seen = map[string]bool{}
+		}
+		if e.Timestamp == since {
+			seen[e.Id] = true
+		}
+		lines = append(lines, fmt.Sprintf("%v cluster %v: %v %v",
+			time.Unix(e.Timestamp, 0).Format("2006-01-02 15:04:05"),
+			e.ClusterId, e.EventType, e.Description))
+	}
+	p.since[id] = since
+	p.seen[id] = seen
+	return lines, nil
+}
+
+// operationChanges returns a log line for each operation that started,
+// changed its status or went away since the previous poll.
+func (p *dashboardPoller) operationChanges(
+	now time.Time, pops []api.PendingOperationInfo) []string {
+
+	ts := now.Format("2006-01-02 15:04:05")
+	lines := []string{}
+	current := map[string]string{}
+	for _, op := range pops {
+		current[op.Id] = op.Status
+		if p.ops == nil {
+			continue
+		}
+		if status, ok := p.ops[op.Id]; !ok {
+			lines = append(lines, fmt.Sprintf("%v operation %v (%v) started",
+				ts, op.Id, op.TypeName))
+		} else if status != op.Status {
+			lines = append(lines, fmt.Sprintf("%v operation %v (%v) is %v",
+				ts, op.Id, op.TypeName, op.Status))
+		}
+	}
+	ended := []string{}
+	for id := range p.ops {
+		if _, ok := current[id]; !ok {
+			ended = append(ended, id)
+		}
+	}
+	sort.Strings(ended)
+	for _, id := range ended {
+		lines = append(lines, fmt.Sprintf("%v operation %v ended", ts, id))
+	}
+	p.ops = current
+	return lines
+}
@@ -0,0 +1,225 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmds
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	client "github.com/heketi/heketi/client/api/go-client"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/tests"
+)
+
+// fakeDashboardServer serves the requests polled by the dashboard for
+// a single cluster.
+type fakeDashboardServer struct {
+	lock   sync.Mutex
+	nodes  []api.NodeInfoResponse
+	ops    []api.PendingOperationInfo
+	events []api.ClusterEvent
+	// the since parameter of the event requests
+	since []int64
+	// fail the pending operation requests
+	failOps bool
+}
+
+func (f *fakeDashboardServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var resp interface{}
+	path := r.URL.Path
+	switch {
+	case path == "/clusters":
+		resp = api.ClusterListResponse{Clusters: []string{"c1"}}
+	case path == "/clusters/c1":
+		info := api.ClusterInfoResponse{Id: "c1"}
+		for _, n := range f.nodes {
+			info.Nodes = append(info.Nodes, n.Id)
+		}
+		resp = info
+	case strings.HasPrefix(path, "/nodes/"):
+		for _, n := range f.nodes {
+			if "/nodes/"+n.Id == path {
+				resp = n
+			}
+		}
+	case path == "/clusters/c1/events":
+		since, _ := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+		f.since = append(f.since, since)
+		l := api.ClusterEventListResponse{Events: []api.ClusterEvent{}}
+		for _, e := range f.events {
+			if e.Timestamp >= since {
+				l.Events = append(l.Events, e)
+			}
+		}
+		resp = l
+	case path == "/operations/pending":
+		if f.failOps {
+			http.Error(w, "db is locked", http.StatusInternalServerError)
+			return
+		}
+		resp = api.PendingOperationListResponse{PendingOperations: f.ops}
+	}
+	if resp == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func newFakeDashboardNode(id, host string, state api.EntryState,
+	devices ...api.DeviceInfoResponse) api.NodeInfoResponse {
+
+	n := api.NodeInfoResponse{State: state, DevicesInfo: devices}
+	n.Id = id
+	n.ClusterId = "c1"
+	n.Hostnames.Manage = []string{host}
+	return n
+}
+
+func newFakeDashboardDevice(id, name string, total, used uint64) api.DeviceInfoResponse {
+	d := api.DeviceInfoResponse{}
+	d.Id = id
+	d.Name = name
+	d.Storage.Total = total
+	d.Storage.Used = used
+	d.Storage.Free = total - used
+	return d
+}
+
+func TestDashboardPoll(t *testing.T) {
+	f := &fakeDashboardServer{
+		nodes: []api.NodeInfoResponse{
+			newFakeDashboardNode("n2", "node2", api.EntryStateOffline,
+				newFakeDashboardDevice("d3", "/dev/sdb", 100, 90)),
+			newFakeDashboardNode("n1", "node1", api.EntryStateOnline,
+				newFakeDashboardDevice("d2", "/dev/sdc", 200, 50),
+				newFakeDashboardDevice("d1", "/dev/sdb", 100, 0)),
+		},
+		ops: []api.PendingOperationInfo{
+			{Id: "op1", TypeName: "create-volume", Status: "new"},
+		},
+	}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+
+	p := newDashboardPoller(client.NewClientNoAuth(ts.URL))
+	s, err := p.Poll()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(s.Clusters) == 1, "expected 1 cluster, got:", s.Clusters)
+	c := s.Clusters[0]
+	tests.Assert(t, c.Id == "c1", "unexpected cluster:", c.Id)
+	tests.Assert(t, c.Nodes == 2 && c.OnlineNodes == 1,
+		"unexpected node counts:", c.Nodes, c.OnlineNodes)
+	tests.Assert(t, c.Devices == 3, "expected 3 devices, got:", c.Devices)
+	tests.Assert(t, c.Total == 400 && c.Used == 140 && c.Free == 260,
+		"unexpected sizes:", c.Total, c.Used, c.Free)
+
+	// devices are sorted by node and name
+	tests.Assert(t, len(s.Devices) == 3, "expected 3 devices, got:", s.Devices)
+	tests.Assert(t, s.Devices[0].Id == "d1" && s.Devices[1].Id == "d2" &&
+		s.Devices[2].Id == "d3", "unexpected order:", s.Devices)
+	tests.Assert(t, s.Devices[1].Node == "node1", s.Devices[1].Node)
+	tests.Assert(t, s.Devices[1].UsedPercent() == 25,
+		"expected 25% used, got:", s.Devices[1].UsedPercent())
+	tests.Assert(t, s.Devices[2].UsedPercent() == 90,
+		"expected 90% used, got:", s.Devices[2].UsedPercent())
+
+	tests.Assert(t, len(s.Operations) == 1 && s.Operations[0].Id == "op1",
+		"unexpected operations:", s.Operations)
+	// the operations pending at the start are not logged
+	tests.Assert(t, len(s.Log) == 0, "expected no log lines, got:", s.Log)
+}
+
+func TestDashboardPollLog(t *testing.T) {
+	f := &fakeDashboardServer{
+		ops: []api.PendingOperationInfo{
+			{Id: "op1", TypeName: "create-volume", Status: "new"},
+			{Id: "op2", TypeName: "delete-volume", Status: "new"},
+		},
+	}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+
+	p := newDashboardPoller(client.NewClientNoAuth(ts.URL))
+	now := p.start.Unix()
+	f.events = []api.ClusterEvent{
+		// too old to be shown
+		{Id: "e0", ClusterId: "c1", Timestamp: now - 7200,
+			EventType: api.ClusterEventNodeAdded, Description: "old"},
+		{Id: "e1", ClusterId: "c1", Timestamp: now - 60,
+			EventType: api.ClusterEventNodeAdded, Description: "node1"},
+		{Id: "e2", ClusterId: "c1", Timestamp: now,
+			EventType: api.ClusterEventNodeAdded, Description: "node2"},
+	}
+
+	s, err := p.Poll()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(s.Log) == 2, "expected 2 log lines, got:", s.Log)
+	tests.Assert(t, strings.HasSuffix(s.Log[0], "node1"), s.Log[0])
+	tests.Assert(t, strings.HasSuffix(s.Log[1], "node2"), s.Log[1])
+
+	// only new events and operation changes are logged
+	f.lock.Lock()
+	f.events = append(f.events, api.ClusterEvent{Id: "e3", ClusterId: "c1",
+		Timestamp: now, EventType: api.ClusterEventNodeRemoved,
+		Description: "node3"})
+	f.ops = []api.PendingOperationInfo{
+		{Id: "op1", TypeName: "create-volume", Status: "failed"},
+		{Id: "op3", TypeName: "expand-volume", Status: "new"},
+	}
+	f.lock.Unlock()
+
+	s, err = p.Poll()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(s.Log) == 4, "expected 4 log lines, got:", s.Log)
+	tests.Assert(t, strings.HasSuffix(s.Log[0], "node3"), s.Log[0])
+	tests.Assert(t, strings.HasSuffix(s.Log[1], "op1 (create-volume) is failed"),
+		s.Log[1])
+	tests.Assert(t, strings.HasSuffix(s.Log[2], "op3 (expand-volume) started"),
+		s.Log[2])
+	tests.Assert(t, strings.HasSuffix(s.Log[3], "op2 ended"), s.Log[3])
+
+	// the events are requested from the newest one seen
+	tests.Assert(t, len(f.since) == 2, "expected 2 requests, got:", f.since)
+	tests.Assert(t, f.since[0] == now-3600, "unexpected since:", f.since[0])
+	tests.Assert(t, f.since[1] == now, "unexpected since:", f.since[1])
+
+	s, err = p.Poll()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(s.Log) == 0, "expected no log lines, got:", s.Log)
+}
+
+func TestDashboardPollFails(t *testing.T) {
+	f := &fakeDashboardServer{failOps: true}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+
+	p := newDashboardPoller(client.NewClientNoAuth(ts.URL))
+	_, err := p.Poll()
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "db is locked"),
+		"unexpected error:", err)
+
+	// the dashboard recovers once the server does
+	f.lock.Lock()
+	f.failOps = false
+	f.lock.Unlock()
+	_, err = p.Poll()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
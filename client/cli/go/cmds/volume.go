@@ -39,6 +39,10 @@ var (
 	kubePv               bool
 	glusterVolumeOptions string
 	block                bool
+	requiredRegions      string
+	tier                 string
+	accessPattern        string
+	volumeTspId          string
 )
 
 func init() {
@@ -85,6 +89,20 @@ func init() {
 	volumeCreateCommand.Flags().StringVar(&glusterVolumeOptions, "gluster-volume-options", "",
 		"\n\tOptional: Comma separated list of volume options which can be set on the volume."+
 			"\n\tIf omitted, Heketi will set no volume option for the volume.")
+	volumeCreateCommand.Flags().StringVar(&requiredRegions, "required-regions", "",
+		"\n\tOptional: Comma separated list of regions. Each replica set of the"+
+			"\n\tvolume will have at least one brick in each of the regions.")
+	volumeCreateCommand.Flags().StringVar(&tier, "tier", "",
+		"\n\tOptional: Storage tier of the volume: gold, silver or bronze."+
+			"\n\tBricks are only placed on devices tagged with <tier>=true.")
+	volumeCreateCommand.Flags().StringVar(&accessPattern, "access-pattern", "",
+		"\n\tOptional: Expected access pattern of the volume: write-intensive,"+
+			"\n\tread-intensive or balanced. Bricks of write-intensive volumes are"+
+			"\n\tplaced on the devices with the fewest bricks, bricks of read-intensive"+
+			"\n\tvolumes on the devices with the most free space.")
+	volumeCreateCommand.Flags().StringVar(&volumeTspId, "tsp-id", "",
+		"\n\tOptional: Trusted storage pool of the cluster to place the bricks in."+
+			"\n\tIf omitted, the bricks are placed in the default pool.")
 	volumeCreateCommand.Flags().BoolVar(&kubePv, "persistent-volume", false,
 		"\n\tOptional: Output to standard out a persistent volume JSON file for OpenShift or"+
 			"\n\tKubernetes with the name provided.")
@@ -179,6 +197,26 @@ var volumeCreateCommand = &cobra.Command{
 			req.GlusterVolumeOptions = strings.Split(glusterVolumeOptions, ",")
 		}
 
+		// Check required regions
+		if requiredRegions != "" {
+			req.RequiredRegions = strings.Split(requiredRegions, ",")
+		}
+
+		// Check storage tier
+		if tier != "" {
+			req.Tier = api.VolumeTier(tier)
+		}
+
+		// Check access pattern
+		if accessPattern != "" {
+			req.AccessPattern = api.VolumeAccessPattern(accessPattern)
+		}
+
+		// Check trusted storage pool
+		if volumeTspId != "" {
+			req.TspId = volumeTspId
+		}
+
 		// Set group id if specified
 		if gid != 0 {
 			req.Gid = gid
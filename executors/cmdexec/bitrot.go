@@ -0,0 +1,118 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmdexec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lpabon/godbc"
+
+	"github.com/heketi/heketi/executors"
+	rex "github.com/heketi/heketi/pkg/remoteexec"
+)
+
+// VolumeBitrotStatus returns the state of the bitrot scrubber of
+// the given volume.
+func (s *CmdExecutor) VolumeBitrotStatus(host string, volume string) (*executors.BitrotStatus, error) {
+
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+
+	// the scrub status has no xml output
+	command := rex.OneCmd(
+		fmt.Sprintf("%v volume bitrot %v scrub status", s.glusterCommand(), volume),
+	)
+	results, err := s.RemoteExecutor.ExecCommands(host, command,
+		s.GlusterCliExecTimeout())
+	if err := rex.AnyError(results, err); err != nil {
+		return nil, fmt.Errorf(
+			"Unable to get bitrot status of volume : %v : %v", volume, err)
+	}
+	status, err := parseBitrotScrubStatus(results[0].Output)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Unable to determine bitrot status of volume : %v : %v", volume, err)
+	}
+	logger.Debug("%+v\n", status)
+	return status, nil
+}
+
+// parseScrubDuration parses the duration of a scrub as printed by
+// gluster. Even though gluster labels it D:M:H:M:S it prints the
+// days, hours, minutes and seconds.
+func parseScrubDuration(s string) (int, error) {
+	fields := strings.Split(s, ":")
+	if len(fields) > 4 {
+		return 0, fmt.Errorf("invalid scrub duration: %v", s)
+	}
+	units := []int{1, 60, 60 * 60, 24 * 60 * 60}
+	seconds := 0
+	for i := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(fields[len(fields)-1-i]))
+		if err != nil {
+			return 0, fmt.Errorf("invalid scrub duration: %v", s)
+		}
+		seconds += n * units[i]
+	}
+	return seconds, nil
+}
+
+// parseBitrotScrubStatus sums up the per node sections of the output
+// of the "volume bitrot <volume> scrub status" command.
+func parseBitrotScrubStatus(output string) (*executors.BitrotStatus, error) {
+	status := &executors.BitrotStatus{}
+	for _, line := range strings.Split(output, "\n") {
+		i := strings.LastIndex(line, ": ")
+		if i < 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+2:])
+
+		var err error
+		var n int
+		switch {
+		case key == "State of scrub":
+			// such as "Active (Idle)" or "Paused"
+			if f := strings.Fields(value); len(f) > 0 {
+				status.State = f[0]
+			}
+		case key == "Number of Scrubbed files":
+			n, err = strconv.Atoi(value)
+			status.FilesScrubbed += n
+		case key == "Error count":
+			n, err = strconv.Atoi(value)
+			status.FilesCorrupted += n
+		case strings.HasPrefix(key, "Duration of last scrub"):
+			n, err = parseScrubDuration(value)
+			if n > status.DurationSeconds {
+				status.DurationSeconds = n
+			}
+		case key == "Last completed scrub time":
+			// nodes that never completed a scrub print a message
+			// instead of the time
+			if value == "" || value[0] < '0' || value[0] > '9' {
+				continue
+			}
+			if value > status.LastCompleted {
+				status.LastCompleted = value
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse %v: %v", key, err)
+		}
+	}
+	if status.State == "" {
+		return nil, fmt.Errorf("no scrub state found")
+	}
+	return status, nil
+}
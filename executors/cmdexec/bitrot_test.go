@@ -0,0 +1,132 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmdexec
+
+import (
+	"testing"
+
+	rex "github.com/heketi/heketi/pkg/remoteexec"
+	"github.com/heketi/tests"
+)
+
+const bitrotScrubStatusOutput = `
+Volume name : vol_4f4b4b4a2a6c4b1e8a5c1e6c1b2a3d4e
+
+State of scrub: Active (Idle)
+
+Scrub impact: lazy
+
+Scrub frequency: biweekly
+
+Bitrot error log location: /var/log/glusterfs/bitd.log
+
+Scrubber error log location: /var/log/glusterfs/scrub.log
+
+
+=========================================================
+
+Node: localhost
+
+Number of Scrubbed files: 1523
+
+Number of Skipped files: 0
+
+Last completed scrub time: 2018-08-20 10:12:43
+
+Duration of last scrub (D:M:H:M:S): 0:2:15:27
+
+Error count: 0
+
+
+=========================================================
+
+Node: 192.168.10.101
+
+Number of Scrubbed files: 1498
+
+Number of Skipped files: 2
+
+Last completed scrub time: 2018-08-20 11:02:10
+
+Duration of last scrub (D:M:H:M:S): 1:0:3:5
+
+Error count: 2
+
+Corrupted object's [GFID]:
+
+a0c7b6e4-5e57-4fbb-9a0b-02d52b3f6b69 ==> BRICK: /var/lib/heketi/mounts/vg_1/brick_1/brick
+ path: /data/file1
+
+d3b9a1f2-0c4e-4e8e-b2f1-7a6b5c4d3e2f ==> BRICK: /var/lib/heketi/mounts/vg_1/brick_1/brick
+ path: /data/file2
+
+=========================================================
+
+Node: 192.168.10.102
+
+Number of Scrubbed files: 0
+
+Number of Skipped files: 0
+
+Last completed scrub time: Scrubber pending to complete.
+
+Duration of last scrub (D:M:H:M:S): 0:0:0:0
+
+Error count: 0
+
+=========================================================
+`
+
+func TestParseBitrotScrubStatus(t *testing.T) {
+	status, err := parseBitrotScrubStatus(bitrotScrubStatusOutput)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, status.State == "Active", "expected Active, got:", status.State)
+	tests.Assert(t, status.FilesScrubbed == 3021,
+		"expected 3021 files scrubbed, got:", status.FilesScrubbed)
+	tests.Assert(t, status.FilesCorrupted == 2,
+		"expected 2 files corrupted, got:", status.FilesCorrupted)
+	// the longest scrub, one day, three minutes and five seconds
+	tests.Assert(t, status.DurationSeconds == 86400+3*60+5,
+		"expected 86585 seconds, got:", status.DurationSeconds)
+	tests.Assert(t, status.LastCompleted == "2018-08-20 11:02:10",
+		"expected last completed time, got:", status.LastCompleted)
+
+	_, err = parseBitrotScrubStatus("Volume name : vol1\n")
+	tests.Assert(t, err != nil, "expected err != nil")
+	_, err = parseBitrotScrubStatus("State of scrub: Paused\nError count: many\n")
+	tests.Assert(t, err != nil, "expected err != nil")
+}
+
+func TestVolumeBitrotStatus(t *testing.T) {
+	f := NewCommandFaker()
+	s, err := NewFakeExecutor(f)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster --mode=script --timeout=42 volume bitrot vol1 scrub status",
+			commands[0])
+		return rex.Results{
+			{Completed: true, Output: "State of scrub: Paused\n"},
+		}, nil
+	}
+
+	status, err := s.VolumeBitrotStatus("host", "vol1")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, status.State == "Paused", "expected Paused, got:", status.State)
+	tests.Assert(t, status.FilesCorrupted == 0,
+		"expected no corrupted files, got:", status.FilesCorrupted)
+}
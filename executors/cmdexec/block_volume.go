@@ -375,3 +375,61 @@ func (s *CmdExecutor) BlockVolumeExpand(host string, blockHostingVolumeName stri
 
 	return nil
 }
+
+// BlockVolumeReplace configures the target of the block volume
+// on the new node and removes it from the old node.
+func (s *CmdExecutor) BlockVolumeReplace(host string,
+	req *executors.BlockGatewayReplaceRequest) error {
+
+	godbc.Require(host != "")
+	godbc.Require(req != nil)
+	godbc.Require(req.GlusterVolumeName != "")
+	godbc.Require(req.Name != "")
+	godbc.Require(req.OldNode != "")
+	godbc.Require(req.NewNode != "")
+
+	commands := []string{
+		fmt.Sprintf("gluster-block replace %v/%v %v %v --json",
+			req.GlusterVolumeName, req.Name, req.OldNode, req.NewNode),
+	}
+	results, err := s.RemoteExecutor.ExecCommands(host, rex.ToCmds(commands), 10)
+	if err != nil {
+		// non-command error conditions
+		return err
+	}
+
+	output := results[0].Output
+	if output == "" {
+		output = results[0].ErrOutput
+	}
+
+	type CliOutput struct {
+		Result string `json:"RESULT"`
+		ErrCod int    `json:"errCode"`
+		ErrMsg string `json:"errMsg"`
+	}
+
+	var blockVolumeReplace CliOutput
+	err = json.Unmarshal([]byte(output), &blockVolumeReplace)
+	if err != nil {
+		logger.Warning("Unable to parse gluster-block output [%v]: %v",
+			output, err)
+		err = fmt.Errorf("Unparsable error during block volume replace: %v",
+			output)
+	} else if blockVolumeReplace.Result == "FAIL" {
+		// the fail flag was set in the output json
+		err = fmt.Errorf("Failed to replace block volume gateway: %v",
+			blockVolumeReplace.ErrMsg)
+	} else if !results.Ok() {
+		// the fail flag is not set but the command still
+		// exited non-zero for some reason
+		err = fmt.Errorf("Failed to replace block volume gateway: %v",
+			results[0].Error())
+	}
+
+	if err != nil {
+		logger.LogError("Failed BlockVolumeReplace: %v", err)
+		return err
+	}
+	return nil
+}
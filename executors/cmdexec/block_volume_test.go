@@ -12,6 +12,8 @@ package cmdexec
 import (
 	"testing"
 
+	"github.com/heketi/heketi/executors"
+	rex "github.com/heketi/heketi/pkg/remoteexec"
 	"github.com/heketi/tests"
 )
 
@@ -87,3 +89,43 @@ func TestToGigaBytes(t *testing.T) {
 	tests.Assert(t, err == nil, "expected err == nil, got:", err)
 	tests.Assert(t, size == 6442450944, "expected: size == 6442450944, got:", size)
 }
+
+func TestSshExecBlockVolumeReplace(t *testing.T) {
+	f := NewCommandFaker()
+	s, err := NewFakeExecutor(f)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+	s.portStr = "100"
+
+	req := &executors.BlockGatewayReplaceRequest{
+		GlusterVolumeName: "hv",
+		Name:              "bv",
+		OldNode:           "node1",
+		NewNode:           "node4",
+	}
+
+	result := `{"RESULT":"SUCCESS"}`
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		tests.Assert(t, host == "myhost:100", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t,
+			commands[0] == "gluster-block replace hv/bv node1 node4 --json",
+			commands[0])
+		return fakeResults(result), nil
+	}
+
+	err = s.BlockVolumeReplace("myhost", req)
+	tests.Assert(t, err == nil, err)
+
+	result = `{"RESULT":"FAIL","errCode":255,"errMsg":"node4 is not reachable"}`
+	err = s.BlockVolumeReplace("myhost", req)
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	result = `garbage`
+	err = s.BlockVolumeReplace("myhost", req)
+	tests.Assert(t, err != nil, "expected err != nil")
+}
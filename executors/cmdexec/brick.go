@@ -148,6 +148,101 @@ func (s *CmdExecutor) deleteBrickLV(host, lv string) error {
 	return err
 }
 
+// LvmSnapshotCreate takes an LVM snapshot of the lv of a brick. The
+// snapshot is sized as a percentage of the origin lv.
+func (s *CmdExecutor) LvmSnapshotCreate(host string,
+	snap *executors.LvmSnapshotRequest) (*executors.LvmSnapshotInfo, error) {
+
+	godbc.Require(snap != nil)
+	godbc.Require(host != "")
+	godbc.Require(snap.VgId != "")
+	godbc.Require(snap.LvName != "")
+	godbc.Require(snap.Name != "")
+	godbc.Require(snap.SizePercent > 0 && snap.SizePercent <= 100)
+
+	vg := paths.VgIdToName(snap.VgId)
+	commands := []string{
+		fmt.Sprintf("%s lvcreate -qq --autobackup=%v --snapshot --name %v --extents %v%%ORIGIN %v/%v",
+			s.lvmCommand(),
+			conv.BoolToYN(s.BackupLVM),
+			snap.Name,
+			snap.SizePercent,
+			vg,
+			snap.LvName),
+	}
+	err := rex.AnyError(s.RemoteExecutor.ExecCommands(host, rex.ToCmds(commands), 5))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create snapshot %v of %v/%v: %v",
+			snap.Name, vg, snap.LvName, err)
+	}
+	return &executors.LvmSnapshotInfo{
+		Path: fmt.Sprintf("/dev/%v/%v", vg, snap.Name),
+	}, nil
+}
+
+// LvmSnapshotDestroy removes an LVM snapshot created by
+// LvmSnapshotCreate.
+func (s *CmdExecutor) LvmSnapshotDestroy(host string,
+	snap *executors.LvmSnapshotRequest) error {
+
+	godbc.Require(snap != nil)
+	godbc.Require(host != "")
+	godbc.Require(snap.VgId != "")
+	godbc.Require(snap.Name != "")
+
+	lv := fmt.Sprintf("%v/%v", paths.VgIdToName(snap.VgId), snap.Name)
+	err := s.deleteBrickLV(host, lv)
+	if err != nil && errIsLvNotFound(err) {
+		logger.Warning("Snapshot %v not found, assuming removed", lv)
+		return nil
+	}
+	return err
+}
+
+// BrickLayoutCheck verifies that the given brick path exists on the
+// host and that its mount point is backed by a thin lv. It returns
+// the names of the vg, lv and thin pool of the brick.
+func (s *CmdExecutor) BrickLayoutCheck(host string,
+	brickPath string) (*executors.BrickLvInfo, error) {
+
+	godbc.Require(host != "")
+	godbc.Require(brickPath != "")
+
+	mountPath := paths.BrickMountFromPath(brickPath)
+	commands := []string{
+		fmt.Sprintf("test -d %v", brickPath),
+		fmt.Sprintf("findmnt --noheadings --output SOURCE --mountpoint %v",
+			mountPath),
+	}
+	results, err := s.RemoteExecutor.ExecCommands(host, rex.ToCmds(commands), 5)
+	if err := rex.AnyError(results, err); err != nil {
+		return nil, fmt.Errorf("Brick %v is not a mounted brick on host %v: %v",
+			brickPath, host, err)
+	}
+	dev := strings.TrimSpace(results[1].Output)
+
+	commands = []string{
+		fmt.Sprintf("%s lvs --noheadings --separator=/ "+
+			"--options=vg_name,lv_name,pool_lv %v", s.lvmCommand(), dev),
+	}
+	results, err = s.RemoteExecutor.ExecCommands(host, rex.ToCmds(commands), 5)
+	if err := rex.AnyError(results, err); err != nil {
+		return nil, fmt.Errorf("Unable to find the lv of brick %v on host %v: %v",
+			brickPath, host, err)
+	}
+	parts := strings.Split(strings.TrimSpace(results[0].Output), "/")
+	if len(parts) != 3 || parts[2] == "" {
+		return nil, fmt.Errorf(
+			"Brick %v on host %v is not on a thin lv (lvs: %q)",
+			brickPath, host, results[0].Output)
+	}
+	return &executors.BrickLvInfo{
+		VgName: parts[0],
+		LvName: parts[1],
+		TpName: parts[2],
+	}, nil
+}
+
 func (s *CmdExecutor) countThinLVsInPool(host, tp string) (int, error) {
 	// Detect the number of bricks using the thin-pool
 	commands := []string{
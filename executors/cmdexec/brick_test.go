@@ -360,3 +360,95 @@ func fakeResults(f ...string) rex.Results {
 	}
 	return results
 }
+
+func TestSshExecLvmSnapshotCreate(t *testing.T) {
+	f := NewCommandFaker()
+	s, err := NewFakeExecutor(f)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+	s.portStr = "100"
+
+	snap := &executors.LvmSnapshotRequest{
+		VgId:        "xvgid",
+		LvName:      "brick_id",
+		Name:        "backup1",
+		SizePercent: 20,
+	}
+
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		tests.Assert(t, host == "myhost:100", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t,
+			commands[0] == "/usr/sbin/lvm lvcreate -qq --autobackup="+conv.BoolToYN(s.BackupLVM)+
+				" --snapshot --name backup1 --extents 20%ORIGIN vg_xvgid/brick_id",
+			commands[0])
+		return nil, nil
+	}
+
+	info, err := s.LvmSnapshotCreate("myhost", snap)
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, info.Path == "/dev/vg_xvgid/backup1", info.Path)
+
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t,
+			commands[0] == "/usr/sbin/lvm lvremove --autobackup="+conv.BoolToYN(s.BackupLVM)+
+				" -f vg_xvgid/backup1",
+			commands[0])
+		return nil, nil
+	}
+
+	err = s.LvmSnapshotDestroy("myhost", snap)
+	tests.Assert(t, err == nil, err)
+}
+
+func TestSshExecBrickLayoutCheck(t *testing.T) {
+	f := NewCommandFaker()
+	s, err := NewFakeExecutor(f)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+	s.portStr = "100"
+
+	lvsOutput := "  vg_custom/lv_b1/tp_b1\n"
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		tests.Assert(t, host == "myhost:100", host)
+		switch len(commands) {
+		case 2:
+			tests.Assert(t, commands[0] == "test -d /bricks/b1/brick",
+				commands[0])
+			tests.Assert(t, commands[1] == "findmnt --noheadings "+
+				"--output SOURCE --mountpoint /bricks/b1", commands[1])
+			return fakeResults("", "/dev/mapper/vg_custom-lv_b1\n"), nil
+		case 1:
+			tests.Assert(t, commands[0] == "/usr/sbin/lvm lvs --noheadings "+
+				"--separator=/ --options=vg_name,lv_name,pool_lv "+
+				"/dev/mapper/vg_custom-lv_b1", commands[0])
+			return fakeResults(lvsOutput), nil
+		}
+		t.Fatalf("unexpected commands: %v", commands)
+		return nil, nil
+	}
+
+	info, err := s.BrickLayoutCheck("myhost", "/bricks/b1/brick")
+	tests.Assert(t, err == nil, err)
+	tests.Assert(t, info.VgName == "vg_custom", info.VgName)
+	tests.Assert(t, info.LvName == "lv_b1", info.LvName)
+	tests.Assert(t, info.TpName == "tp_b1", info.TpName)
+
+	// a thick lv can not be managed as a brick
+	lvsOutput = "  vg_custom/lv_b1/\n"
+	_, err = s.BrickLayoutCheck("myhost", "/bricks/b1/brick")
+	tests.Assert(t, err != nil, "expected err != nil")
+}
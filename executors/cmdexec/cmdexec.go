@@ -15,6 +15,7 @@ import (
 	"regexp"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/heketi/heketi/pkg/logging"
 	rex "github.com/heketi/heketi/pkg/remoteexec"
@@ -43,6 +44,8 @@ type CmdExecutor struct {
 	Throttlemap map[string]chan bool
 	Lock        sync.Mutex
 
+	volumeCreateTemplate *VolumeCreateTemplate
+
 	RemoteExecutor RemoteCommandTransport
 	Fstab          string
 	MountOpts      string
@@ -109,11 +112,24 @@ func setWithEnvVariables(config *CmdConfig) {
 	}
 }
 
-func (c *CmdExecutor) Init(config *CmdConfig) {
+func (c *CmdExecutor) Init(config *CmdConfig) error {
 	c.Throttlemap = make(map[string]chan bool)
 	c.config = config
 
 	setWithEnvVariables(config)
+
+	text := config.VolumeCreateTemplate
+	if text == "" {
+		text = DefaultVolumeCreateTemplate
+	} else {
+		logger.Info("Creating volumes using template %q", text)
+	}
+	t, err := NewVolumeCreateTemplate(text)
+	if err != nil {
+		return err
+	}
+	c.volumeCreateTemplate = t
+	return nil
 }
 
 func (s *CmdExecutor) AccessConnection(host string) {
@@ -236,3 +252,22 @@ func (c *CmdExecutor) BlockVolumeDefaultPrealloc() string {
 func (c *CmdExecutor) LVMWrapper() string {
 	return c.config.LVMWrapper
 }
+
+// PeerProbeRetry returns the retry settings for peer probes with
+// defaults filled in. By default a peer probe is attempted only once.
+func (c *CmdExecutor) PeerProbeRetry() PeerProbeRetryConfig {
+	r := c.config.PeerProbeRetry
+	if r.MaxAttempts < 1 {
+		r.MaxAttempts = 1
+	}
+	if r.InitialDelay <= 0 {
+		r.InitialDelay = time.Second
+	}
+	if r.MaxDelay <= 0 {
+		r.MaxDelay = 30 * time.Second
+	}
+	if r.MaxDelay < r.InitialDelay {
+		r.MaxDelay = r.InitialDelay
+	}
+	return r
+}
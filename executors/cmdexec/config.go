@@ -9,6 +9,19 @@
 
 package cmdexec
 
+import (
+	"time"
+)
+
+// PeerProbeRetryConfig controls how often a failed peer probe is
+// retried before giving up. Delays double after each failed attempt
+// up to MaxDelay.
+type PeerProbeRetryConfig struct {
+	MaxAttempts  int           `json:"max_attempts"`
+	InitialDelay time.Duration `json:"initial_delay"`
+	MaxDelay     time.Duration `json:"max_delay"`
+}
+
 type CmdConfig struct {
 	Fstab                string `json:"fstab"`
 	MountOpts            string `json:"mountopts"`
@@ -25,4 +38,10 @@ type CmdConfig struct {
 	DebugUmountFailures  bool   `json:"debug_umount_failures"`
 	BlockVolumePrealloc  string `json:"block_prealloc"`
 	LVMWrapper           string `json:"lvm_wrapper"`
+	// go template naming the vgs of new devices
+	VgNameTemplate string `json:"vg_name_template"`
+	// go template of the gluster volume create command
+	VolumeCreateTemplate string `json:"volume_create_template"`
+
+	PeerProbeRetry PeerProbeRetryConfig `json:"peer_probe_retry"`
 }
@@ -46,19 +46,7 @@ func (s *CmdExecutor) DeviceSetup(host, device, vgid string, destroy bool) (d *e
 		logger.Info("Data on device %v (host %v) will be destroyed", device, host)
 		commands = append(commands, fmt.Sprintf("wipefs --all %v", device))
 	}
-	commands = append(commands, fmt.Sprintf("%s pvcreate -qq --metadatasize=128M --dataalignment=%v '%v'", s.lvmCommand(), s.PVDataAlignment(), device))
-	commands = append(commands, fmt.Sprintf("%s vgcreate -qq --physicalextentsize=%v --autobackup=%v %v %v",
-		s.lvmCommand(),
-
-		// Physical extent size
-		s.VGPhysicalExtentSize(),
-
-		// Autobackup
-		conv.BoolToYN(s.BackupLVM),
-
-		// Device
-		paths.VgIdToName(vgid), device),
-	)
+	commands = append(commands, s.vgCreateCommands(device, vgid)...)
 
 	// Execute command
 	err := rex.AnyError(s.RemoteExecutor.ExecCommands(host, rex.ToCmds(commands), 5))
@@ -66,6 +54,64 @@ func (s *CmdExecutor) DeviceSetup(host, device, vgid string, destroy bool) (d *e
 		err = s.deviceSetupError(err, host, device)
 		return nil, err
 	}
+	return s.newDeviceInfo(host, device, vgid)
+}
+
+// DeviceReinitialize wipes the device and creates its pv and vg
+// again. What is left of the old vg of the device is removed first.
+// The device is expected to hold no bricks.
+func (s *CmdExecutor) DeviceReinitialize(host, device, vgid string) (*executors.DeviceInfo, error) {
+
+	logger.Info("Data on device %v (host %v) will be destroyed", device, host)
+
+	// the vg may be too damaged for lvm to remove it, in which case
+	// wiping the device takes care of it
+	cleanup := []string{
+		fmt.Sprintf("%s vgremove -qq --force %v", s.lvmCommand(), paths.VgIdToName(vgid)),
+	}
+	err := rex.AnyError(s.RemoteExecutor.ExecCommands(host, rex.ToCmds(cleanup), 5))
+	if err != nil {
+		logger.Warning("Unable to remove vg of device %v (host %v): %v",
+			device, host, err)
+	}
+
+	commands := []string{
+		fmt.Sprintf("wipefs --all --force '%v'", device),
+		// clears the old lvm metadata area
+		fmt.Sprintf("dd if=/dev/zero of='%v' bs=1M count=128 oflag=direct", device),
+	}
+	commands = append(commands, s.vgCreateCommands(device, vgid)...)
+	err = rex.AnyError(s.RemoteExecutor.ExecCommands(host, rex.ToCmds(commands), 5))
+	if err != nil {
+		return nil, logger.LogError(
+			"Failed to reinitialize device %v with id %v on host %v: %v",
+			device, vgid, host, err)
+	}
+	return s.newDeviceInfo(host, device, vgid)
+}
+
+// vgCreateCommands returns the commands that create the pv and vg of
+// a device.
+func (s *CmdExecutor) vgCreateCommands(device, vgid string) []string {
+	return []string{
+		fmt.Sprintf("%s pvcreate -qq --metadatasize=128M --dataalignment=%v '%v'", s.lvmCommand(), s.PVDataAlignment(), device),
+		fmt.Sprintf("%s vgcreate -qq --physicalextentsize=%v --autobackup=%v %v %v",
+			s.lvmCommand(),
+
+			// Physical extent size
+			s.VGPhysicalExtentSize(),
+
+			// Autobackup
+			conv.BoolToYN(s.BackupLVM),
+
+			// Device
+			paths.VgIdToName(vgid), device),
+	}
+}
+
+// newDeviceInfo reads the handle and the size of a device whose vg
+// was just created. The vg is torn down if that fails.
+func (s *CmdExecutor) newDeviceInfo(host, device, vgid string) (d *executors.DeviceInfo, e error) {
 
 	// Create a cleanup function if anything fails
 	defer func() {
@@ -10,12 +10,14 @@
 package cmdexec
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
 	"github.com/heketi/tests"
 
 	"github.com/heketi/heketi/executors"
+	rex "github.com/heketi/heketi/pkg/remoteexec"
 )
 
 func TestCheckHandle(t *testing.T) {
@@ -154,3 +156,86 @@ func TestParsePvsResult(t *testing.T) {
 `)
 	tests.Assert(t, err != nil)
 }
+
+func TestDeviceReinitialize(t *testing.T) {
+	f := NewCommandFaker()
+	s, err := NewFakeExecutor(f)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	ran := []string{}
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		ran = append(ran, commands...)
+		results := make(rex.Results, len(commands))
+		for i, c := range commands {
+			results[i].Completed = true
+			switch {
+			case strings.Contains(c, "vgremove"):
+				// the vg is damaged
+				results[i].Err = errors.New("exit status 5")
+				results[i].ExitStatus = 5
+				results[i].ErrOutput = "Volume group vg_abc not found"
+			case strings.Contains(c, "pvs -o"):
+				results[i].Output = `{"report": [{"pv": [
+					{"pv_name":"/dev/sdb", "pv_uuid":"abcdef1", "vg_name":"vg_abc"}]}]}`
+			case strings.Contains(c, "udevadm"):
+				results[i].Err = errors.New("exit status 1")
+				results[i].ExitStatus = 1
+			case strings.Contains(c, "vgdisplay"):
+				results[i].Output = "vg_abc:r/w:772:-1:0:0:0:-1:0:1:1:" +
+					"2097135616:4096:511996:0:511996:rJ0bIG"
+			}
+		}
+		return results, nil
+	}
+
+	info, err := s.DeviceReinitialize("host", "/dev/sdb", "abc")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, info.TotalSize == 511996*4096, info.TotalSize)
+	tests.Assert(t, info.FreeSize == 511996*4096, info.FreeSize)
+	tests.Assert(t, info.Meta.UUID == "abcdef1", info.Meta)
+
+	tests.Assert(t, len(ran) == 8, "expected 8 commands, got:", ran)
+	tests.Assert(t, ran[0] == "/usr/sbin/lvm vgremove -qq --force vg_abc", ran[0])
+	tests.Assert(t, ran[1] == "wipefs --all --force '/dev/sdb'", ran[1])
+	tests.Assert(t, ran[2] == "dd if=/dev/zero of='/dev/sdb' bs=1M count=128 oflag=direct", ran[2])
+	tests.Assert(t, strings.HasPrefix(ran[3], "/usr/sbin/lvm pvcreate "), ran[3])
+	tests.Assert(t, strings.HasSuffix(ran[3], " '/dev/sdb'"), ran[3])
+	tests.Assert(t, strings.HasPrefix(ran[4], "/usr/sbin/lvm vgcreate "), ran[4])
+	tests.Assert(t, strings.HasSuffix(ran[4], " vg_abc /dev/sdb"), ran[4])
+	tests.Assert(t, strings.Contains(ran[5], "pvs -o"), ran[5])
+	tests.Assert(t, strings.Contains(ran[7], "vgdisplay -c vg_abc"), ran[7])
+
+	// failing to wipe the device fails the reinitialization
+	ran = []string{}
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		ran = append(ran, commands...)
+		results := make(rex.Results, len(commands))
+		for i, c := range commands {
+			results[i].Completed = true
+			if strings.HasPrefix(c, "wipefs") {
+				results[i].Err = errors.New("exit status 1")
+				results[i].ExitStatus = 1
+				results[i].ErrOutput = "wipefs: error: /dev/sdb: probing initialization failed"
+				break
+			}
+		}
+		return results, nil
+	}
+	_, err = s.DeviceReinitialize("host", "/dev/sdb", "abc")
+	tests.Assert(t, err != nil, "expected err != nil")
+	// the device is not looked at after the failure
+	for _, c := range ran {
+		tests.Assert(t, !strings.Contains(c, "pvs -o"),
+			"expected no commands after the failed wipe, got:", ran)
+	}
+}
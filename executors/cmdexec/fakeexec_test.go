@@ -47,7 +47,9 @@ func NewFakeExecutor(f *CommandFaker) (*FakeExecutor, error) {
 	config.LVChunkSize = "256K"
 	config.XfsSu = 0
 	config.XfsSw = 0
-	t.CmdExecutor.Init(config)
+	if err := t.CmdExecutor.Init(config); err != nil {
+		return nil, err
+	}
 	t.fake = f
 	t.Fstab = "/my/fstab"
 	t.portStr = "22"
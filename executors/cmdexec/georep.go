@@ -0,0 +1,154 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmdexec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lpabon/godbc"
+
+	"github.com/heketi/heketi/executors"
+	rex "github.com/heketi/heketi/pkg/remoteexec"
+)
+
+// VolumeGeoRepStatus returns the state of the geo-replication session
+// of the given volume with the given slave on each brick of the volume.
+func (s *CmdExecutor) VolumeGeoRepStatus(host string, volume string, slave string) (*executors.GeoRepStatus, error) {
+
+	godbc.Require(volume != "")
+	godbc.Require(slave != "")
+	godbc.Require(host != "")
+
+	command := rex.OneCmd(
+		fmt.Sprintf("%v volume geo-replication %v %v status detail",
+			s.glusterCommand(), volume, slave),
+	)
+	results, err := s.RemoteExecutor.ExecCommands(host, command,
+		s.GlusterCliExecTimeout())
+	if err := rex.AnyError(results, err); err != nil {
+		return nil, fmt.Errorf(
+			"Unable to get geo-replication status of volume : %v : %v", volume, err)
+	}
+	status, err := parseGeoRepStatus(results[0].Output)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Unable to determine geo-replication status of volume : %v : %v", volume, err)
+	}
+	logger.Debug("%+v\n", status)
+	return status, nil
+}
+
+// geoRepValue returns a value of the geo-replication status, which is
+// "N/A" when the session is not active on the brick, or "" if unknown.
+func geoRepValue(s string) string {
+	if s == "N/A" {
+		return ""
+	}
+	return s
+}
+
+// geoRepNumber parses a counter of the geo-replication status.
+func geoRepNumber(s string) (int64, error) {
+	if s = geoRepValue(s); s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// parseGeoRepStatus parses the table printed by the "volume
+// geo-replication <master> <slave> status detail" command. The values
+// may contain spaces, such as "Changelog Crawl", so the columns are
+// cut at the positions of the names in the header line. Columns that
+// the version of gluster does not print are left empty.
+func parseGeoRepStatus(output string) (*executors.GeoRepStatus, error) {
+	lines := strings.Split(output, "\n")
+	header := -1
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "MASTER NODE") {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, fmt.Errorf("no geo-replication status header found")
+	}
+
+	// the columns start where the header names start, the names are
+	// separated by at least two spaces
+	hl := strings.TrimRight(lines[header], " ")
+	columns := map[int]string{}
+	positions := []int{}
+	for i := 0; i < len(hl); {
+		if hl[i] == ' ' {
+			i++
+			continue
+		}
+		end := strings.Index(hl[i:], "  ")
+		if end < 0 {
+			end = len(hl)
+		} else {
+			end += i
+		}
+		columns[i] = hl[i:end]
+		positions = append(positions, i)
+		i = end
+	}
+
+	status := &executors.GeoRepStatus{}
+	for _, line := range lines[header+1:] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.Trim(trimmed, "-") == "" {
+			continue
+		}
+		values := map[string]string{}
+		for i, p := range positions {
+			if p >= len(line) {
+				break
+			}
+			end := len(line)
+			if i+1 < len(positions) && positions[i+1] < end {
+				end = positions[i+1]
+			}
+			values[columns[p]] = strings.TrimSpace(line[p:end])
+		}
+
+		b := executors.GeoRepBrickStatus{
+			MasterNode:  values["MASTER NODE"],
+			BrickPath:   values["MASTER BRICK"],
+			SlaveNode:   geoRepValue(values["SLAVE NODE"]),
+			Status:      values["STATUS"],
+			CrawlStatus: geoRepValue(values["CRAWL STATUS"]),
+			LastSynced:  geoRepValue(values["LAST_SYNCED"]),
+		}
+		if b.BrickPath == "" || b.Status == "" {
+			return nil, fmt.Errorf("unable to parse geo-replication status: %v", trimmed)
+		}
+		counts := map[string]int64{}
+		for _, name := range []string{"FILES SYNCD", "FILES PENDING",
+			"BYTES PENDING", "ENTRY", "DATA", "META"} {
+			n, err := geoRepNumber(values[name])
+			if err != nil {
+				return nil, fmt.Errorf("Unable to parse %v: %v", name, err)
+			}
+			counts[name] = n
+		}
+		b.FilesSynced = int(counts["FILES SYNCD"])
+		b.FilesPending = int(counts["FILES PENDING"] +
+			counts["ENTRY"] + counts["DATA"] + counts["META"])
+		b.BytesPending = counts["BYTES PENDING"]
+		status.Bricks = append(status.Bricks, b)
+	}
+	if len(status.Bricks) == 0 {
+		return nil, fmt.Errorf("no bricks found in geo-replication status")
+	}
+	return status, nil
+}
@@ -0,0 +1,126 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmdexec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/heketi/tests"
+
+	"github.com/heketi/heketi/executors"
+	rex "github.com/heketi/heketi/pkg/remoteexec"
+)
+
+// output of "status detail" on gluster 3.7 and later
+var geoRepStatusDetail = `
+ MASTER NODE     MASTER VOL     MASTER BRICK                                    SLAVE USER     SLAVE               SLAVE NODE     STATUS      CRAWL STATUS        LAST_SYNCED             ENTRY     DATA     META     FAILURES     CHECKPOINT TIME     CHECKPOINT COMPLETED     CHECKPOINT COMPLETION TIME
+---------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+ gfs-node1       gv0            /var/lib/heketi/mounts/vg_a1/brick_b1/brick     root           gfs-dr1::gv0-dr     gfs-dr1        Active      Changelog Crawl     2018-10-15 09:47:12     0         12       3        0            N/A                 N/A                      N/A
+ gfs-node2       gv0            /var/lib/heketi/mounts/vg_a2/brick_b2/brick     root           gfs-dr1::gv0-dr     N/A            Faulty      N/A                 N/A                     N/A       N/A      N/A      N/A          N/A                 N/A                      N/A
+ gfs-node3       gv0            /var/lib/heketi/mounts/vg_a3/brick_b3/brick     root           gfs-dr1::gv0-dr     gfs-dr2        Passive     N/A                 N/A                     N/A       N/A      N/A      N/A          N/A                 N/A                      N/A
+`
+
+// output of "status detail" on gluster 3.6
+var geoRepStatusDetailOld = `
+ MASTER NODE     MASTER VOL     MASTER BRICK     SLAVE               STATUS      CHECKPOINT STATUS     CRAWL STATUS     FILES SYNCD     FILES PENDING     BYTES PENDING     DELETES PENDING     FILES SKIPPED
+------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------
+ gfs-node1       gv0            /bricks/b1       gfs-dr1::gv0-dr     Active      N/A                   Hybrid Crawl     10234           17                5242880           0                   0
+ gfs-node2       gv0            /bricks/b2       gfs-dr1::gv0-dr     Stopped     N/A                   N/A              0               0                 0                 0                   0
+`
+
+func TestParseGeoRepStatus(t *testing.T) {
+	status, err := parseGeoRepStatus(geoRepStatusDetail)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	expected := []executors.GeoRepBrickStatus{
+		{
+			MasterNode:   "gfs-node1",
+			BrickPath:    "/var/lib/heketi/mounts/vg_a1/brick_b1/brick",
+			SlaveNode:    "gfs-dr1",
+			Status:       "Active",
+			CrawlStatus:  "Changelog Crawl",
+			FilesPending: 15,
+			LastSynced:   "2018-10-15 09:47:12",
+		},
+		{
+			MasterNode: "gfs-node2",
+			BrickPath:  "/var/lib/heketi/mounts/vg_a2/brick_b2/brick",
+			Status:     "Faulty",
+		},
+		{
+			MasterNode: "gfs-node3",
+			BrickPath:  "/var/lib/heketi/mounts/vg_a3/brick_b3/brick",
+			SlaveNode:  "gfs-dr2",
+			Status:     "Passive",
+		},
+	}
+	tests.Assert(t, reflect.DeepEqual(status.Bricks, expected),
+		"expected", expected, "got:", status.Bricks)
+}
+
+func TestParseGeoRepStatusOld(t *testing.T) {
+	status, err := parseGeoRepStatus(geoRepStatusDetailOld)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	expected := []executors.GeoRepBrickStatus{
+		{
+			MasterNode:   "gfs-node1",
+			BrickPath:    "/bricks/b1",
+			Status:       "Active",
+			CrawlStatus:  "Hybrid Crawl",
+			FilesSynced:  10234,
+			FilesPending: 17,
+			BytesPending: 5242880,
+		},
+		{
+			MasterNode: "gfs-node2",
+			BrickPath:  "/bricks/b2",
+			Status:     "Stopped",
+		},
+	}
+	tests.Assert(t, reflect.DeepEqual(status.Bricks, expected),
+		"expected", expected, "got:", status.Bricks)
+}
+
+func TestParseGeoRepStatusInvalid(t *testing.T) {
+	_, err := parseGeoRepStatus("No active geo-replication sessions\n")
+	tests.Assert(t, err != nil, "expected err != nil")
+	_, err = parseGeoRepStatus(" MASTER NODE    MASTER BRICK    STATUS\n")
+	tests.Assert(t, err != nil, "expected err != nil")
+	_, err = parseGeoRepStatus(
+		" MASTER NODE    MASTER BRICK    STATUS    ENTRY\n" +
+			" node1          /bricks/b1      Active    many\n")
+	tests.Assert(t, err != nil, "expected err != nil")
+}
+
+func TestVolumeGeoRepStatus(t *testing.T) {
+	f := NewCommandFaker()
+	s, err := NewFakeExecutor(f)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster --mode=script --timeout=42 volume geo-replication gv0 gfs-dr1::gv0-dr status detail",
+			commands[0])
+		return rex.Results{
+			{Completed: true, Output: geoRepStatusDetail},
+		}, nil
+	}
+
+	status, err := s.VolumeGeoRepStatus("host", "gv0", "gfs-dr1::gv0-dr")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(status.Bricks) == 3,
+		"expected 3 bricks, got:", status.Bricks)
+}
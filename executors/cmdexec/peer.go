@@ -10,10 +10,13 @@
 package cmdexec
 
 import (
+	"encoding/xml"
 	"fmt"
+	"time"
 
 	"github.com/lpabon/godbc"
 
+	"github.com/heketi/heketi/executors"
 	rex "github.com/heketi/heketi/pkg/remoteexec"
 )
 
@@ -28,8 +31,23 @@ func (s *CmdExecutor) PeerProbe(host, newnode string) error {
 	commands := []string{
 		fmt.Sprintf("%v peer probe %v", s.glusterCommand(), newnode),
 	}
-	err := rex.AnyError(s.RemoteExecutor.ExecCommands(host, rex.ToCmds(commands),
-		s.GlusterCliExecTimeout()))
+	retry := s.PeerProbeRetry()
+	delay := retry.InitialDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = rex.AnyError(s.RemoteExecutor.ExecCommands(host, rex.ToCmds(commands),
+			s.GlusterCliExecTimeout()))
+		if err == nil || attempt >= retry.MaxAttempts {
+			break
+		}
+		logger.Warning("Peer probe %v -> %v failed (attempt %v of %v), retrying in %v: %v",
+			host, newnode, attempt, retry.MaxAttempts, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+		if delay > retry.MaxDelay {
+			delay = retry.MaxDelay
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -69,6 +87,48 @@ func (s *CmdExecutor) PeerDetach(host, detachnode string) error {
 	return nil
 }
 
+// PeerStatus returns the peers of the given host. The host itself
+// is not part of the list.
+func (s *CmdExecutor) PeerStatus(host string) ([]executors.Peer, error) {
+	godbc.Require(host != "")
+
+	type CliOutput struct {
+		OpRet      int    `xml:"opRet"`
+		OpErrno    int    `xml:"opErrno"`
+		OpErrStr   string `xml:"opErrstr"`
+		PeerStatus struct {
+			Peers []executors.Peer `xml:"peer"`
+		} `xml:"peerStatus"`
+	}
+
+	command := rex.OneCmd(
+		fmt.Sprintf("%v peer status --xml", s.glusterCommand()),
+	)
+	results, err := s.RemoteExecutor.ExecCommands(host, command,
+		s.GlusterCliExecTimeout())
+	if err := rex.AnyError(results, err); err != nil {
+		return nil, fmt.Errorf("Unable to get peer status on %v: %v", host, err)
+	}
+	var peerStatus CliOutput
+	err = xml.Unmarshal([]byte(results[0].Output), &peerStatus)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Unable to determine peer status on %v: %v", host, err)
+	}
+	return peerStatus.PeerStatus.Peers, nil
+}
+
+// NodePing runs a trivial command on the given host. It is meant to
+// check that the host can be reached and to measure how long a round
+// trip to the host takes.
+func (s *CmdExecutor) NodePing(host string) error {
+	godbc.Require(host != "")
+
+	cmd := rex.ToCmd("true")
+	cmd.Options.Quiet = true
+	return rex.AnyError(s.RemoteExecutor.ExecCommands(host, rex.Cmds{cmd}, 10))
+}
+
 func (s *CmdExecutor) GlusterdCheck(host string) error {
 	godbc.Require(host != "")
 
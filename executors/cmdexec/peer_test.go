@@ -11,6 +11,7 @@ package cmdexec
 
 import (
 	"testing"
+	"time"
 
 	rex "github.com/heketi/heketi/pkg/remoteexec"
 	"github.com/heketi/tests"
@@ -101,3 +102,46 @@ func TestSshExecGlusterdCheck(t *testing.T) {
 	err = s.GlusterdCheck("newhost")
 	tests.Assert(t, err == nil, err)
 }
+
+func TestSshExecPeerProbeRetry(t *testing.T) {
+	f := NewCommandFaker()
+	s, err := NewFakeExecutor(f)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+	s.config.PeerProbeRetry = PeerProbeRetryConfig{
+		MaxAttempts:  5,
+		InitialDelay: time.Millisecond,
+		MaxDelay:     2 * time.Millisecond,
+	}
+
+	// fail three times before glusterd accepts the probe
+	count := 0
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster --mode=script --timeout=42 peer probe newnode", commands)
+		count++
+		if count <= 3 {
+			return rex.Results{{
+				Completed:  true,
+				ExitStatus: 1,
+				ErrOutput:  "Connection failed. Please check if gluster daemon is operational.",
+			}}, nil
+		}
+		return nil, nil
+	}
+
+	err = s.PeerProbe("host", "newnode")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, count == 4, "expected count == 4, got:", count)
+
+	// give up once the attempts are exhausted
+	count = 0
+	s.config.PeerProbeRetry.MaxAttempts = 2
+	err = s.PeerProbe("host", "newnode")
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, count == 2, "expected count == 2, got:", count)
+}
@@ -0,0 +1,126 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmdexec
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/lpabon/godbc"
+
+	"github.com/heketi/heketi/executors"
+	rex "github.com/heketi/heketi/pkg/remoteexec"
+)
+
+// VolumeProfileStart starts gathering profiling statistics on the
+// bricks of the given volume.
+func (s *CmdExecutor) VolumeProfileStart(host string, volume string) error {
+
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+
+	command := rex.OneCmd(
+		fmt.Sprintf("%v volume profile %v start", s.glusterCommand(), volume),
+	)
+	err := rex.AnyError(s.RemoteExecutor.ExecCommands(host, command,
+		s.GlusterCliExecTimeout()))
+	if err != nil {
+		return fmt.Errorf(
+			"Unable to start profiling volume %v: %v", volume, err)
+	}
+	return nil
+}
+
+// VolumeProfileStop stops profiling the given volume. The gathered
+// statistics are dropped.
+func (s *CmdExecutor) VolumeProfileStop(host string, volume string) error {
+
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+
+	command := rex.OneCmd(
+		fmt.Sprintf("%v volume profile %v stop", s.glusterCommand(), volume),
+	)
+	err := rex.AnyError(s.RemoteExecutor.ExecCommands(host, command,
+		s.GlusterCliExecTimeout()))
+	if err != nil {
+		return fmt.Errorf(
+			"Unable to stop profiling volume %v: %v", volume, err)
+	}
+	return nil
+}
+
+// VolumeProfileInfo returns the statistics gathered since profiling
+// of the given volume was started.
+func (s *CmdExecutor) VolumeProfileInfo(host string, volume string) (*executors.VolumeProfile, error) {
+
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+
+	// only the cumulative statistics are asked for, asking for the
+	// interval statistics would reset them
+	command := rex.OneCmd(
+		fmt.Sprintf("%v volume profile %v info cumulative --xml",
+			s.glusterCommand(), volume),
+	)
+	results, err := s.RemoteExecutor.ExecCommands(host, command,
+		s.GlusterCliExecTimeout())
+	if err := rex.AnyError(results, err); err != nil {
+		return nil, fmt.Errorf(
+			"Unable to get profile info of volume %v: %v", volume, err)
+	}
+	profile, err := parseVolumeProfileInfo(results[0].Output)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Unable to determine profile info of volume %v: %v", volume, err)
+	}
+	logger.Debug("%+v\n", profile)
+	return profile, nil
+}
+
+// parseVolumeProfileInfo parses the xml output of the "volume profile
+// <volume> info" command.
+func parseVolumeProfileInfo(output string) (*executors.VolumeProfile, error) {
+	type CliOutput struct {
+		OpRet      int                     `xml:"opRet"`
+		OpErrno    int                     `xml:"opErrno"`
+		OpErrStr   string                  `xml:"opErrstr"`
+		VolProfile executors.VolumeProfile `xml:"volProfile"`
+	}
+
+	var profile CliOutput
+	err := xml.Unmarshal([]byte(output), &profile)
+	if err != nil {
+		return nil, err
+	}
+	if profile.OpRet != 0 {
+		return nil, fmt.Errorf("%v", profile.OpErrStr)
+	}
+	return &profile.VolProfile, nil
+}
+
+// VolumeStatedump dumps the state of the brick processes of the given
+// volume to files in the statedump directory of their nodes.
+func (s *CmdExecutor) VolumeStatedump(host string, volume string) error {
+
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+
+	command := rex.OneCmd(
+		fmt.Sprintf("%v volume statedump %v", s.glusterCommand(), volume),
+	)
+	err := rex.AnyError(s.RemoteExecutor.ExecCommands(host, command,
+		s.GlusterCliExecTimeout()))
+	if err != nil {
+		return fmt.Errorf(
+			"Unable to take statedump of volume %v: %v", volume, err)
+	}
+	return nil
+}
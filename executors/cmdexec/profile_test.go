@@ -0,0 +1,167 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmdexec
+
+import (
+	"testing"
+
+	rex "github.com/heketi/heketi/pkg/remoteexec"
+	"github.com/heketi/tests"
+)
+
+const volumeProfileInfoOutput = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cliOutput>
+  <opRet>0</opRet>
+  <opErrno>0</opErrno>
+  <opErrstr/>
+  <volProfile>
+    <volname>vol1</volname>
+    <profileOp>3</profileOp>
+    <brickCount>2</brickCount>
+    <brick>
+      <brickName>192.168.10.100:/var/lib/heketi/mounts/vg_1/brick_1/brick</brickName>
+      <cumulativeStats>
+        <blockStats>
+          <block>
+            <size>4096</size>
+            <reads>10</reads>
+            <writes>120</writes>
+          </block>
+          <block>
+            <size>131072</size>
+            <reads>0</reads>
+            <writes>3</writes>
+          </block>
+        </blockStats>
+        <fopStats>
+          <fop>
+            <name>WRITE</name>
+            <hits>123</hits>
+            <avgLatency>96.21</avgLatency>
+            <minLatency>31.00</minLatency>
+            <maxLatency>1543.00</maxLatency>
+          </fop>
+          <fop>
+            <name>LOOKUP</name>
+            <hits>17</hits>
+            <avgLatency>212.47</avgLatency>
+            <minLatency>18.00</minLatency>
+            <maxLatency>802.00</maxLatency>
+          </fop>
+        </fopStats>
+        <duration>348</duration>
+        <totalRead>40960</totalRead>
+        <totalWrite>884736</totalWrite>
+      </cumulativeStats>
+    </brick>
+    <brick>
+      <brickName>192.168.10.101:/var/lib/heketi/mounts/vg_2/brick_2/brick</brickName>
+      <cumulativeStats>
+        <blockStats/>
+        <fopStats>
+          <fop>
+            <name>STATFS</name>
+            <hits>2</hits>
+            <avgLatency>44.50</avgLatency>
+            <minLatency>40.00</minLatency>
+            <maxLatency>49.00</maxLatency>
+          </fop>
+        </fopStats>
+        <duration>347</duration>
+        <totalRead>0</totalRead>
+        <totalWrite>0</totalWrite>
+      </cumulativeStats>
+    </brick>
+  </volProfile>
+</cliOutput>
+`
+
+func TestParseVolumeProfileInfo(t *testing.T) {
+	profile, err := parseVolumeProfileInfo(volumeProfileInfoOutput)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(profile.Bricks) == 2,
+		"expected 2 bricks, got:", len(profile.Bricks))
+
+	b := profile.Bricks[0]
+	tests.Assert(t, b.Name == "192.168.10.100:/var/lib/heketi/mounts/vg_1/brick_1/brick",
+		"unexpected brick name:", b.Name)
+	tests.Assert(t, b.Stats.DurationSeconds == 348,
+		"expected 348 seconds, got:", b.Stats.DurationSeconds)
+	tests.Assert(t, b.Stats.TotalRead == 40960, b.Stats.TotalRead)
+	tests.Assert(t, b.Stats.TotalWrite == 884736, b.Stats.TotalWrite)
+	tests.Assert(t, len(b.Stats.Blocks) == 2,
+		"expected 2 block sizes, got:", len(b.Stats.Blocks))
+	tests.Assert(t, b.Stats.Blocks[1].Size == 131072, b.Stats.Blocks[1])
+	tests.Assert(t, b.Stats.Blocks[1].Reads == 0, b.Stats.Blocks[1])
+	tests.Assert(t, b.Stats.Blocks[1].Writes == 3, b.Stats.Blocks[1])
+	tests.Assert(t, len(b.Stats.Fops) == 2,
+		"expected 2 fops, got:", len(b.Stats.Fops))
+	fop := b.Stats.Fops[0]
+	tests.Assert(t, fop.Name == "WRITE", fop.Name)
+	tests.Assert(t, fop.Hits == 123, fop.Hits)
+	tests.Assert(t, fop.AvgLatency == 96.21, fop.AvgLatency)
+	tests.Assert(t, fop.MinLatency == 31, fop.MinLatency)
+	tests.Assert(t, fop.MaxLatency == 1543, fop.MaxLatency)
+
+	b = profile.Bricks[1]
+	tests.Assert(t, len(b.Stats.Blocks) == 0,
+		"expected no block sizes, got:", b.Stats.Blocks)
+	tests.Assert(t, len(b.Stats.Fops) == 1 && b.Stats.Fops[0].Name == "STATFS",
+		"unexpected fops:", b.Stats.Fops)
+
+	_, err = parseVolumeProfileInfo(`<cliOutput>
+  <opRet>-1</opRet>
+  <opErrno>30800</opErrno>
+  <opErrstr>Profile on Volume vol1 is not started</opErrstr>
+</cliOutput>`)
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, err.Error() == "Profile on Volume vol1 is not started", err)
+
+	_, err = parseVolumeProfileInfo("Profile on Volume vol1 is not started")
+	tests.Assert(t, err != nil, "expected err != nil")
+}
+
+func TestVolumeProfile(t *testing.T) {
+	f := NewCommandFaker()
+	s, err := NewFakeExecutor(f)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	var ran []string
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		ran = append(ran, commands[0])
+		return rex.Results{
+			{Completed: true, Output: volumeProfileInfoOutput},
+		}, nil
+	}
+
+	err = s.VolumeProfileStart("host", "vol1")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	profile, err := s.VolumeProfileInfo("host", "vol1")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(profile.Bricks) == 2,
+		"expected 2 bricks, got:", len(profile.Bricks))
+	err = s.VolumeProfileStop("host", "vol1")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = s.VolumeStatedump("host", "vol1")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	tests.Assert(t, len(ran) == 4, "expected 4 commands, got:", ran)
+	tests.Assert(t, ran[0] == "gluster --mode=script --timeout=42 volume profile vol1 start", ran[0])
+	tests.Assert(t, ran[1] == "gluster --mode=script --timeout=42 volume profile vol1 info cumulative --xml", ran[1])
+	tests.Assert(t, ran[2] == "gluster --mode=script --timeout=42 volume profile vol1 stop", ran[2])
+	tests.Assert(t, ran[3] == "gluster --mode=script --timeout=42 volume statedump vol1", ran[3])
+}
@@ -176,3 +176,66 @@ func (s *CmdExecutor) SnapshotDestroy(host string, snapshot string) error {
 
 	return nil
 }
+
+// SnapshotConfigSet sets a snapshot config option of the trusted
+// storage pool of the host.
+func (s *CmdExecutor) SnapshotConfigSet(host string, option string, value string) error {
+	godbc.Require(host != "")
+	godbc.Require(option != "")
+	godbc.Require(value != "")
+
+	command := rex.OneCmd(
+		fmt.Sprintf("%v snapshot config %v %v", s.glusterCommand(), option, value),
+	)
+	err := rex.AnyError(s.RemoteExecutor.ExecCommands(host, command,
+		s.GlusterCliExecTimeout()))
+	if err != nil {
+		return fmt.Errorf("Unable to set snapshot config %v to %v: %v",
+			option, value, err)
+	}
+	return nil
+}
+
+// snapshotScheduleJob returns the name of the snapshot scheduler job
+// heketi uses for the volume.
+func snapshotScheduleJob(volume string) string {
+	return "heketi_" + volume
+}
+
+// SnapshotScheduleSet replaces the snapshot scheduler job of the
+// volume by a job running at the given cron schedule. An empty schedule
+// removes the job. The snapshot scheduler must have been initialized
+// on the nodes of the pool.
+func (s *CmdExecutor) SnapshotScheduleSet(host string, volume string, schedule string) error {
+	godbc.Require(host != "")
+	godbc.Require(volume != "")
+
+	job := snapshotScheduleJob(volume)
+	// the job may not exist, failures to delete it are ignored
+	err := rex.AnyError(s.RemoteExecutor.ExecCommands(host,
+		rex.OneCmd(fmt.Sprintf("snap_scheduler.py delete %v", job)),
+		s.GlusterCliExecTimeout()))
+	if err != nil {
+		logger.Debug("Unable to delete snapshot schedule %v: %v", job, err)
+	}
+	if schedule == "" {
+		return nil
+	}
+
+	// enabling an already enabled scheduler fails, ignore it
+	err = rex.AnyError(s.RemoteExecutor.ExecCommands(host,
+		rex.OneCmd("snap_scheduler.py enable"),
+		s.GlusterCliExecTimeout()))
+	if err != nil {
+		logger.Debug("Unable to enable snapshot scheduler: %v", err)
+	}
+	err = rex.AnyError(s.RemoteExecutor.ExecCommands(host,
+		rex.OneCmd(fmt.Sprintf(`snap_scheduler.py add %v "%v" %v`,
+			job, schedule, volume)),
+		s.GlusterCliExecTimeout()))
+	if err != nil {
+		return fmt.Errorf("Unable to schedule snapshots of volume %v: %v",
+			volume, err)
+	}
+	return nil
+}
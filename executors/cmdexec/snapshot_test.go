@@ -0,0 +1,83 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmdexec
+
+import (
+	"fmt"
+	"testing"
+
+	rex "github.com/heketi/heketi/pkg/remoteexec"
+	"github.com/heketi/tests"
+)
+
+func TestSnapshotConfigSet(t *testing.T) {
+	f := NewCommandFaker()
+	s, err := NewFakeExecutor(f)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	cmds := []string{}
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		cmds = append(cmds, commands...)
+		return nil, nil
+	}
+
+	err = s.SnapshotConfigSet("host", "snap-max-hard-limit", "10")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = s.SnapshotConfigSet("host", "auto-delete", "enable")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(cmds) == 2, "expected 2 commands, got:", cmds)
+	tests.Assert(t, cmds[0] == "gluster --mode=script --timeout=42 snapshot config snap-max-hard-limit 10",
+		cmds[0])
+	tests.Assert(t, cmds[1] == "gluster --mode=script --timeout=42 snapshot config auto-delete enable",
+		cmds[1])
+}
+
+func TestSnapshotScheduleSet(t *testing.T) {
+	f := NewCommandFaker()
+	s, err := NewFakeExecutor(f)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	cmds := []string{}
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		cmds = append(cmds, commands...)
+		if commands[0] == "snap_scheduler.py delete heketi_vol1" {
+			// the job does not exist yet
+			return rex.Results{{Completed: true, Output: "",
+				Err: fmt.Errorf("job not found"), ExitStatus: 1}}, nil
+		}
+		return nil, nil
+	}
+
+	err = s.SnapshotScheduleSet("host", "vol1", "0 * * * *")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(cmds) == 3, "expected 3 commands, got:", cmds)
+	tests.Assert(t, cmds[0] == "snap_scheduler.py delete heketi_vol1", cmds[0])
+	tests.Assert(t, cmds[1] == "snap_scheduler.py enable", cmds[1])
+	tests.Assert(t, cmds[2] == `snap_scheduler.py add heketi_vol1 "0 * * * *" vol1`,
+		cmds[2])
+
+	// an empty schedule only removes the job
+	cmds = []string{}
+	err = s.SnapshotScheduleSet("host", "vol1", "")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(cmds) == 1, "expected 1 command, got:", cmds)
+	tests.Assert(t, cmds[0] == "snap_scheduler.py delete heketi_vol1", cmds[0])
+}
@@ -29,8 +29,6 @@ func (s *CmdExecutor) VolumeCreate(host string,
 	godbc.Require(len(volume.Bricks) > 0)
 	godbc.Require(volume.Name != "")
 
-	cmd := fmt.Sprintf("%v volume create %v ", s.glusterCommand(), volume.Name)
-
 	var (
 		inSet     int
 		maxPerSet int
@@ -42,16 +40,11 @@ func (s *CmdExecutor) VolumeCreate(host string,
 		maxPerSet = 15
 	case executors.DurabilityReplica:
 		logger.Info("Creating volume %v replica %v", volume.Name, volume.Replica)
-		cmd += fmt.Sprintf("replica %v ", volume.Replica)
-		if volume.Arbiter {
-			cmd += "arbiter 1 "
-		}
 		inSet = volume.Replica
 		maxPerSet = 5
 	case executors.DurabilityDispersion:
 		logger.Info("Creating volume %v dispersion %v+%v",
 			volume.Name, volume.Data, volume.Redundancy)
-		cmd += fmt.Sprintf("disperse-data %v redundancy %v ", volume.Data, volume.Redundancy)
 		inSet = volume.Data + volume.Redundancy
 		maxPerSet = 1
 	}
@@ -61,10 +54,11 @@ func (s *CmdExecutor) VolumeCreate(host string,
 	// Therefore, we initially create the volume with the first brick set
 	// only, and then add each brick set in one subsequent command.
 
-	for _, brick := range volume.Bricks[:inSet] {
-		cmd += fmt.Sprintf("%v:%v ", brick.Host, brick.Path)
+	cmd, err := s.volumeCreateTemplate.Render(
+		NewVolumeCreateParams(s.glusterCommand(), volume, inSet))
+	if err != nil {
+		return nil, err
 	}
-
 	commands := []string{cmd}
 
 	commands = append(commands, s.createAddBrickCommands(volume, inSet, inSet, maxPerSet)...)
@@ -73,7 +67,7 @@ func (s *CmdExecutor) VolumeCreate(host string,
 
 	commands = append(commands, fmt.Sprintf("%v volume start %v", s.glusterCommand(), volume.Name))
 
-	err := rex.AnyError(s.RemoteExecutor.ExecCommands(host, rex.ToCmds(commands),
+	err = rex.AnyError(s.RemoteExecutor.ExecCommands(host, rex.ToCmds(commands),
 		s.GlusterCliExecTimeout()))
 	if err != nil {
 		return nil, err
@@ -438,6 +432,179 @@ func (s *CmdExecutor) HealInfo(host string, volume string) (*executors.HealInfo,
 	return &healInfo.HealInfo, nil
 }
 
+// VolumeStatus returns the online state of the brick processes of
+// the given volume.
+func (s *CmdExecutor) VolumeStatus(host string, volume string) (*executors.VolumeStatus, error) {
+
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+
+	type CliOutput struct {
+		OpRet     int    `xml:"opRet"`
+		OpErrno   int    `xml:"opErrno"`
+		OpErrStr  string `xml:"opErrstr"`
+		VolStatus struct {
+			Volumes struct {
+				VolumeList []executors.VolumeStatus `xml:"volume"`
+			} `xml:"volumes"`
+		} `xml:"volStatus"`
+	}
+
+	command := rex.OneCmd(
+		fmt.Sprintf("%v volume status %v --xml", s.glusterCommand(), volume),
+	)
+
+	results, err := s.RemoteExecutor.ExecCommands(host, command,
+		s.GlusterCliExecTimeout())
+	if err := rex.AnyError(results, err); err != nil {
+		return nil, fmt.Errorf(
+			"Unable to get status of volume : %v : %v", volume, err)
+	}
+	var volStatus CliOutput
+	err = xml.Unmarshal([]byte(results[0].Output), &volStatus)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Unable to determine status of volume : %v : %v", volume, err)
+	}
+	if len(volStatus.VolStatus.Volumes.VolumeList) == 0 {
+		return nil, fmt.Errorf(
+			"No status reported for volume : %v", volume)
+	}
+	logger.Debug("%+v\n", volStatus)
+	return &volStatus.VolStatus.Volumes.VolumeList[0], nil
+}
+
+// HealSplitBrainInfo returns the entries of the given volume that are
+// in split-brain, listed per brick.
+func (s *CmdExecutor) HealSplitBrainInfo(host string, volume string) (*executors.HealInfo, error) {
+
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+
+	type CliOutput struct {
+		OpRet    int                `xml:"opRet"`
+		OpErrno  int                `xml:"opErrno"`
+		OpErrStr string             `xml:"opErrstr"`
+		HealInfo executors.HealInfo `xml:"healInfo"`
+	}
+
+	command := rex.OneCmd(
+		fmt.Sprintf("%v volume heal %v info split-brain --xml", s.glusterCommand(), volume),
+	)
+
+	results, err := s.RemoteExecutor.ExecCommands(host, command,
+		s.GlusterCliExecTimeout())
+	if err := rex.AnyError(results, err); err != nil {
+		return nil, fmt.Errorf(
+			"Unable to get split-brain info of volume : %v : %v", volume, err)
+	}
+	var healInfo CliOutput
+	err = xml.Unmarshal([]byte(results[0].Output), &healInfo)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Unable to determine split-brain info of volume : %v : %v", volume, err)
+	}
+	logger.Debug("%+v\n", healInfo)
+	return &healInfo.HealInfo, nil
+}
+
+// HealSplitBrainResolve resolves the split-brain of a single entry of
+// the given volume by picking the copy with the latest modification
+// time as the source of the heal.
+func (s *CmdExecutor) HealSplitBrainResolve(host string, volume string, file string) error {
+
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+	godbc.Require(file != "")
+
+	// heal info reports gfid entries as <gfid:UUID>, the heal
+	// command expects gfid:UUID
+	file = strings.TrimSuffix(strings.TrimPrefix(file, "<"), ">")
+	command := rex.OneCmd(
+		fmt.Sprintf("%v volume heal %v split-brain latest-mtime '%v'",
+			s.glusterCommand(), volume, file),
+	)
+
+	err := rex.AnyError(s.RemoteExecutor.ExecCommands(host, command,
+		s.GlusterCliExecTimeout()))
+	if err != nil {
+		return fmt.Errorf(
+			"Unable to resolve split-brain of %v on volume %v: %v",
+			file, volume, err)
+	}
+	return nil
+}
+
+// VolumeRebalanceStatus returns the state of the last rebalance of
+// the given volume.
+func (s *CmdExecutor) VolumeRebalanceStatus(host string, volume string) (*executors.RebalanceStatus, error) {
+
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+
+	type CliOutput struct {
+		OpRet        int    `xml:"opRet"`
+		OpErrno      int    `xml:"opErrno"`
+		OpErrStr     string `xml:"opErrstr"`
+		VolRebalance struct {
+			Nodes     []executors.RebalanceNodeStatus `xml:"node"`
+			Aggregate executors.RebalanceStatus       `xml:"aggregate"`
+		} `xml:"volRebalance"`
+	}
+
+	command := rex.OneCmd(
+		fmt.Sprintf("%v volume rebalance %v status --xml", s.glusterCommand(), volume),
+	)
+
+	results, err := s.RemoteExecutor.ExecCommands(host, command,
+		s.GlusterCliExecTimeout())
+	if err != nil {
+		return nil, fmt.Errorf(
+			"Unable to get rebalance status of volume : %v : %v", volume, err)
+	}
+	// gluster fails the status command of volumes that were never
+	// rebalanced, the xml output tells us why
+	var rebStatus CliOutput
+	xerr := xml.Unmarshal([]byte(results[0].Output), &rebStatus)
+	if xerr == nil && rebStatus.OpRet != 0 &&
+		strings.Contains(rebStatus.OpErrStr, "not started") {
+		return &executors.RebalanceStatus{
+			StatusStr: executors.RebalanceNotStarted,
+		}, nil
+	}
+	if err := results.FirstError(); err != nil {
+		return nil, fmt.Errorf(
+			"Unable to get rebalance status of volume : %v : %v", volume, err)
+	}
+	if xerr != nil {
+		return nil, fmt.Errorf(
+			"Unable to determine rebalance status of volume : %v : %v", volume, xerr)
+	}
+	logger.Debug("%+v\n", rebStatus)
+	status := rebStatus.VolRebalance.Aggregate
+	status.Nodes = rebStatus.VolRebalance.Nodes
+	return &status, nil
+}
+
+// VolumeRebalanceStart starts a rebalance of the given volume.
+func (s *CmdExecutor) VolumeRebalanceStart(host string, volume string) error {
+
+	godbc.Require(volume != "")
+	godbc.Require(host != "")
+
+	command := rex.OneCmd(
+		fmt.Sprintf("%v volume rebalance %v start", s.glusterCommand(), volume),
+	)
+
+	err := rex.AnyError(s.RemoteExecutor.ExecCommands(host, command,
+		s.GlusterCliExecTimeout()))
+	if err != nil {
+		return fmt.Errorf(
+			"Unable to start rebalance on volume %v: %v", volume, err)
+	}
+	return nil
+}
+
 // VolumeModify is used to alter the configuration of an existing volume.
 func (s *CmdExecutor) VolumeModify(host string, mod *executors.VolumeModifyRequest) error {
 
@@ -0,0 +1,152 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmdexec
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/heketi/heketi/executors"
+)
+
+// DefaultVolumeCreateTemplate renders the gluster volume create
+// command used when no template is configured.
+const DefaultVolumeCreateTemplate = `{{.Gluster}} volume create {{.Name}} ` +
+	`{{if eq .Type "replicate"}}replica {{.Replica}} {{if .Arbiter}}arbiter 1 {{end}}` +
+	`{{else if eq .Type "disperse"}}disperse-data {{.Data}} redundancy {{.Redundancy}} {{end}}` +
+	`{{range .Bricks}}{{.Host}}:{{.Path}} {{end}}`
+
+// VolumeCreateBrick is a brick of the volume create template.
+type VolumeCreateBrick struct {
+	Host string
+	Path string
+}
+
+// VolumeCreateParams are the variables available to a volume create
+// template. Type is one of "none", "replicate" and "disperse". Bricks
+// holds the bricks the volume is created with, the other bricks of
+// the volume are added afterwards.
+type VolumeCreateParams struct {
+	Gluster string
+	Name    string
+	Type    string
+
+	// Replica
+	Replica int
+	Arbiter bool
+
+	// Dispersion
+	Data       int
+	Redundancy int
+
+	Bricks []VolumeCreateBrick
+}
+
+// VolumeCreateTemplate renders the gluster volume create command.
+type VolumeCreateTemplate struct {
+	tmpl *template.Template
+}
+
+// NewVolumeCreateTemplate parses the given go template text and checks
+// that it renders a command for volumes of each durability type.
+func NewVolumeCreateTemplate(text string) (*VolumeCreateTemplate, error) {
+	tmpl, err := template.New("volume_create").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid volume create template: %v", err)
+	}
+	t := &VolumeCreateTemplate{tmpl}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func durabilityName(d executors.DurabilityType) string {
+	switch d {
+	case executors.DurabilityReplica:
+		return "replicate"
+	case executors.DurabilityDispersion:
+		return "disperse"
+	}
+	return "none"
+}
+
+// NewVolumeCreateParams returns the template variables for creating
+// the given volume with its first inSet bricks.
+func NewVolumeCreateParams(gluster string,
+	volume *executors.VolumeRequest, inSet int) *VolumeCreateParams {
+
+	p := &VolumeCreateParams{
+		Gluster:    gluster,
+		Name:       volume.Name,
+		Type:       durabilityName(volume.Type),
+		Replica:    volume.Replica,
+		Arbiter:    volume.Arbiter,
+		Data:       volume.Data,
+		Redundancy: volume.Redundancy,
+	}
+	for _, brick := range volume.Bricks[:inSet] {
+		p.Bricks = append(p.Bricks, VolumeCreateBrick{
+			Host: brick.Host,
+			Path: brick.Path,
+		})
+	}
+	return p
+}
+
+// Render returns the volume create command for the given variables.
+func (t *VolumeCreateTemplate) Render(p *VolumeCreateParams) (string, error) {
+	var b bytes.Buffer
+	if err := t.tmpl.Execute(&b, p); err != nil {
+		return "", fmt.Errorf("unable to render volume create command: %v", err)
+	}
+	cmd := b.String()
+	if !strings.Contains(cmd, p.Name) {
+		return "", fmt.Errorf(
+			"volume create command %q does not contain the volume name", cmd)
+	}
+	for _, brick := range p.Bricks {
+		if !strings.Contains(cmd, brick.Path) {
+			return "", fmt.Errorf(
+				"volume create command %q does not contain brick %v",
+				cmd, brick.Path)
+		}
+	}
+	return cmd, nil
+}
+
+func (t *VolumeCreateTemplate) validate() error {
+	bricks := func(n int) []executors.BrickInfo {
+		b := []executors.BrickInfo{}
+		for i := 0; i < n; i++ {
+			b = append(b, executors.BrickInfo{
+				Host: fmt.Sprintf("192.168.0.%v", i+1),
+				Path: fmt.Sprintf("/var/lib/heketi/mounts/vg_sim/brick_%v/brick", i),
+			})
+		}
+		return b
+	}
+	volumes := []*executors.VolumeRequest{
+		{Type: executors.DurabilityNone, Bricks: bricks(1)},
+		{Type: executors.DurabilityReplica, Replica: 3, Bricks: bricks(3)},
+		{Type: executors.DurabilityReplica, Replica: 3, Arbiter: true, Bricks: bricks(3)},
+		{Type: executors.DurabilityDispersion, Data: 4, Redundancy: 2, Bricks: bricks(6)},
+	}
+	for _, v := range volumes {
+		v.Name = "vol_sim"
+		p := NewVolumeCreateParams("gluster", v, len(v.Bricks))
+		if _, err := t.Render(p); err != nil {
+			return fmt.Errorf("invalid volume create template: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmdexec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/heketi/heketi/executors"
+	rex "github.com/heketi/heketi/pkg/remoteexec"
+	"github.com/heketi/tests"
+)
+
+func sampleReplicaVolumeRequest(arbiter bool) *executors.VolumeRequest {
+	return &executors.VolumeRequest{
+		Name:    "vol1",
+		Type:    executors.DurabilityReplica,
+		Replica: 3,
+		Arbiter: arbiter,
+		Bricks: []executors.BrickInfo{
+			{Host: "h1", Path: "/b/1"},
+			{Host: "h2", Path: "/b/2"},
+			{Host: "h3", Path: "/b/3"},
+			{Host: "h4", Path: "/b/4"},
+			{Host: "h5", Path: "/b/5"},
+			{Host: "h6", Path: "/b/6"},
+		},
+	}
+}
+
+func TestVolumeCreateTemplateDefault(t *testing.T) {
+	tmpl, err := NewVolumeCreateTemplate(DefaultVolumeCreateTemplate)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	cmd, err := tmpl.Render(NewVolumeCreateParams("gluster",
+		sampleReplicaVolumeRequest(false), 3))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, cmd == "gluster volume create vol1 replica 3 h1:/b/1 h2:/b/2 h3:/b/3 ",
+		"unexpected command:", cmd)
+
+	cmd, err = tmpl.Render(NewVolumeCreateParams("gluster",
+		sampleReplicaVolumeRequest(true), 3))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, cmd == "gluster volume create vol1 replica 3 arbiter 1 h1:/b/1 h2:/b/2 h3:/b/3 ",
+		"unexpected command:", cmd)
+
+	v := sampleReplicaVolumeRequest(false)
+	v.Type = executors.DurabilityDispersion
+	v.Data = 4
+	v.Redundancy = 2
+	cmd, err = tmpl.Render(NewVolumeCreateParams("gluster", v, 6))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, cmd == "gluster volume create vol1 disperse-data 4 redundancy 2 "+
+		"h1:/b/1 h2:/b/2 h3:/b/3 h4:/b/4 h5:/b/5 h6:/b/6 ",
+		"unexpected command:", cmd)
+
+	v.Type = executors.DurabilityNone
+	cmd, err = tmpl.Render(NewVolumeCreateParams("gluster", v, 1))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, cmd == "gluster volume create vol1 h1:/b/1 ",
+		"unexpected command:", cmd)
+}
+
+func TestVolumeCreateTemplateCustom(t *testing.T) {
+	tmpl, err := NewVolumeCreateTemplate(
+		`{{.Gluster}} volume create {{.Name}} ` +
+			`{{if .Arbiter}}replica 2 arbiter 1{{else}}replica {{.Replica}}{{end}} ` +
+			`transport tcp {{range $i, $b := .Bricks}}{{if $i}} {{end}}{{$b.Host}}:{{$b.Path}}{{end}} force`)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	cmd, err := tmpl.Render(NewVolumeCreateParams("gluster",
+		sampleReplicaVolumeRequest(false), 3))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, cmd == "gluster volume create vol1 replica 3 transport tcp h1:/b/1 h2:/b/2 h3:/b/3 force",
+		"unexpected command:", cmd)
+
+	cmd, err = tmpl.Render(NewVolumeCreateParams("gluster",
+		sampleReplicaVolumeRequest(true), 3))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, cmd == "gluster volume create vol1 replica 2 arbiter 1 transport tcp h1:/b/1 h2:/b/2 h3:/b/3 force",
+		"unexpected command:", cmd)
+}
+
+func TestVolumeCreateTemplateInvalid(t *testing.T) {
+	for _, text := range []string{
+		// does not parse
+		"{{.Gluster} volume create",
+		// unknown variable
+		"{{.Gluster}} volume create {{.Name}} {{.Transport}}",
+		// no volume name
+		"{{.Gluster}} volume create vol {{range .Bricks}}{{.Host}}:{{.Path}} {{end}}",
+		// no bricks
+		"{{.Gluster}} volume create {{.Name}}",
+	} {
+		_, err := NewVolumeCreateTemplate(text)
+		tests.Assert(t, err != nil, "expected err != nil for:", text)
+		tests.Assert(t, strings.Contains(err.Error(), "invalid volume create template"),
+			"unexpected error:", err)
+	}
+}
+
+func TestVolumeCreateUsesTemplate(t *testing.T) {
+	f := NewCommandFaker()
+	s, err := NewFakeExecutor(f)
+	tests.Assert(t, err == nil)
+	s.volumeCreateTemplate, err = NewVolumeCreateTemplate(
+		DefaultVolumeCreateTemplate + "force")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	ran := []string{}
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		ran = append(ran, commands...)
+		results := make(rex.Results, len(commands))
+		for i := range commands {
+			results[i].Completed = true
+		}
+		return results, nil
+	}
+
+	_, err = s.VolumeCreate("host", sampleReplicaVolumeRequest(true))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(ran) == 3, "expected 3 commands, got:", ran)
+	tests.Assert(t, ran[0] == "gluster --mode=script --timeout=42 volume create vol1 "+
+		"replica 3 arbiter 1 h1:/b/1 h2:/b/2 h3:/b/3 force", ran[0])
+	tests.Assert(t, ran[1] == "gluster --mode=script --timeout=42 volume add-brick vol1 "+
+		"h4:/b/4 h5:/b/5 h6:/b/6 ", ran[1])
+	tests.Assert(t, ran[2] == "gluster --mode=script --timeout=42 volume start vol1", ran[2])
+}
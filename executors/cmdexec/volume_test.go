@@ -0,0 +1,99 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package cmdexec
+
+import (
+	"testing"
+
+	"github.com/heketi/heketi/executors"
+	rex "github.com/heketi/heketi/pkg/remoteexec"
+	"github.com/heketi/tests"
+)
+
+const rebalanceStatusOutput = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cliOutput>
+  <opRet>0</opRet>
+  <opErrno>0</opErrno>
+  <opErrstr/>
+  <volRebalance>
+    <task-id>2c6a8e1e-4f4a-4b57-9a0e-6c0a3f1e5d21</task-id>
+    <op>3</op>
+    <nodeCount>2</nodeCount>
+    <node>
+      <nodeName>localhost</nodeName>
+      <id>7d9a4c1b-3e0f-4a52-8f6e-1b2c3d4e5f60</id>
+      <files>12</files>
+      <size>1048576</size>
+      <lookups>40</lookups>
+      <failures>0</failures>
+      <skipped>0</skipped>
+      <status>3</status>
+      <statusStr>completed</statusStr>
+      <runtime>5.00</runtime>
+    </node>
+    <node>
+      <nodeName>192.168.10.101</nodeName>
+      <id>0e1f2a3b-4c5d-4e6f-8a9b-0c1d2e3f4a5b</id>
+      <files>7</files>
+      <size>524288</size>
+      <lookups>31</lookups>
+      <failures>0</failures>
+      <skipped>0</skipped>
+      <status>1</status>
+      <statusStr>in progress</statusStr>
+      <runtime>5.00</runtime>
+    </node>
+    <aggregate>
+      <files>19</files>
+      <size>1572864</size>
+      <lookups>71</lookups>
+      <failures>0</failures>
+      <skipped>0</skipped>
+      <status>1</status>
+      <statusStr>in progress</statusStr>
+      <runtime>5.00</runtime>
+    </aggregate>
+  </volRebalance>
+</cliOutput>
+`
+
+func TestVolumeRebalanceStatus(t *testing.T) {
+	f := NewCommandFaker()
+	s, err := NewFakeExecutor(f)
+	tests.Assert(t, err == nil)
+	tests.Assert(t, s != nil)
+
+	f.FakeConnectAndExec = func(host string,
+		commands []string,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		tests.Assert(t, host == "host:22", host)
+		tests.Assert(t, len(commands) == 1)
+		tests.Assert(t, commands[0] == "gluster --mode=script --timeout=42 volume rebalance vol1 status --xml",
+			commands[0])
+		return rex.Results{
+			{Completed: true, Output: rebalanceStatusOutput},
+		}, nil
+	}
+
+	status, err := s.VolumeRebalanceStatus("host", "vol1")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, status.StatusStr == executors.RebalanceInProgress,
+		"expected in progress, got:", status.StatusStr)
+	tests.Assert(t, status.Files == 19, "expected 19 files, got:", status.Files)
+	tests.Assert(t, len(status.Nodes) == 2,
+		"expected 2 nodes, got:", len(status.Nodes))
+	tests.Assert(t, status.Nodes[1].NodeName == "192.168.10.101",
+		"expected 192.168.10.101, got:", status.Nodes[1].NodeName)
+	tests.Assert(t, status.Running(), "expected rebalance to be running")
+	tests.Assert(t, status.Progress() == 50,
+		"expected 50% progress, got:", status.Progress())
+}
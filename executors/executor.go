@@ -16,14 +16,18 @@ import (
 
 type Executor interface {
 	GlusterdCheck(host string) error
+	NodePing(host string) error
 	PeerProbe(exec_host, newnode string) error
 	PeerDetach(exec_host, detachnode string) error
+	PeerStatus(host string) ([]Peer, error)
 	DeviceSetup(host, device, vgid string, destroy bool) (*DeviceInfo, error)
 	GetDeviceInfo(host string, dh *DeviceVgHandle) (*DeviceInfo, error)
 	DeviceTeardown(host string, dh *DeviceVgHandle) error
 	DeviceForget(host string, dh *DeviceVgHandle) error
+	DeviceReinitialize(host, device, vgid string) (*DeviceInfo, error)
 	BrickCreate(host string, brick *BrickRequest) (*BrickInfo, error)
 	BrickDestroy(host string, brick *BrickRequest) (bool, error)
+	BrickLayoutCheck(host string, path string) (*BrickLvInfo, error)
 	VolumeCreate(host string, volume *VolumeRequest) (*Volume, error)
 	VolumeDestroy(host string, volume string) error
 	VolumeDestroyCheck(host, volume string) error
@@ -37,12 +41,28 @@ type Executor interface {
 	SnapshotCloneVolume(host string, scr *SnapshotCloneRequest) (*Volume, error)
 	SnapshotCloneBlockVolume(host string, scr *SnapshotCloneRequest) (*BlockVolumeInfo, error)
 	SnapshotDestroy(host string, snapshot string) error
+	SnapshotConfigSet(host string, option string, value string) error
+	SnapshotScheduleSet(host string, volume string, schedule string) error
 	HealInfo(host string, volume string) (*HealInfo, error)
+	HealSplitBrainInfo(host string, volume string) (*HealInfo, error)
+	HealSplitBrainResolve(host string, volume string, file string) error
+	LvmSnapshotCreate(host string, snap *LvmSnapshotRequest) (*LvmSnapshotInfo, error)
+	LvmSnapshotDestroy(host string, snap *LvmSnapshotRequest) error
+	VolumeStatus(host string, volume string) (*VolumeStatus, error)
+	VolumeRebalanceStatus(host string, volume string) (*RebalanceStatus, error)
+	VolumeRebalanceStart(host string, volume string) error
+	VolumeBitrotStatus(host string, volume string) (*BitrotStatus, error)
+	VolumeGeoRepStatus(host string, volume string, slave string) (*GeoRepStatus, error)
+	VolumeProfileStart(host string, volume string) error
+	VolumeProfileStop(host string, volume string) error
+	VolumeProfileInfo(host string, volume string) (*VolumeProfile, error)
+	VolumeStatedump(host string, volume string) error
 	SetLogLevel(level string)
 	BlockVolumeCreate(host string, blockVolume *BlockVolumeRequest) (*BlockVolumeInfo, error)
 	BlockVolumeDestroy(host string, blockHostingVolumeName string, blockVolumeName string) error
 	BlockVolumeExpand(host string, blockHostingVolumeName string, blockVolumeName string, newSize int) error
 	BlockVolumeInfo(host string, blockhostingvolume string, blockVolumeName string) (*BlockVolumeInfo, error)
+	BlockVolumeReplace(host string, req *BlockGatewayReplaceRequest) error
 	PVS(host string) (*PVSCommandOutput, error)
 	VGS(host string) (*VGSCommandOutput, error)
 	LVS(host string) (*LVSCommandOutput, error)
@@ -150,6 +170,13 @@ type BrickRequest struct {
 	Format BrickFormatType
 }
 
+// BrickLvInfo identifies the lvm volumes backing a mounted brick.
+type BrickLvInfo struct {
+	VgName string
+	LvName string
+	TpName string
+}
+
 // Returns information about the location of the brick
 type BrickInfo struct {
 	Path string
@@ -248,6 +275,8 @@ type BrickHealStatus struct {
 	Name            string `xml:"name"`
 	Status          string `xml:"status"`
 	NumberOfEntries string `xml:"numberOfEntries"`
+	// only reported by heal info split-brain
+	Files []string `xml:"file"`
 }
 
 type Option struct {
@@ -301,6 +330,195 @@ type HealInfo struct {
 	Bricks  HealInfoBricks `xml:"bricks"`
 }
 
+// Peer is a member of the trusted storage pool as seen from the
+// node peer status was run on.
+type Peer struct {
+	Uuid      string   `xml:"uuid"`
+	Hostname  string   `xml:"hostname"`
+	Hostnames []string `xml:"hostnames>hostname"`
+	Connected int      `xml:"connected"`
+	StateStr  string   `xml:"stateStr"`
+}
+
+func (p Peer) Online() bool {
+	return p.Connected == 1
+}
+
+// HasHostname returns true if the peer is known by the given name.
+func (p Peer) HasHostname(name string) bool {
+	if p.Hostname == name {
+		return true
+	}
+	for _, h := range p.Hostnames {
+		if h == name {
+			return true
+		}
+	}
+	return false
+}
+
+// BrickStatus is the state of a single brick process as reported
+// by gluster volume status.
+type BrickStatus struct {
+	Hostname string `xml:"hostname"`
+	Path     string `xml:"path"`
+	PeerId   string `xml:"peerid"`
+	Status   int    `xml:"status"`
+	Pid      int    `xml:"pid"`
+}
+
+func (b BrickStatus) Online() bool {
+	return b.Status == 1
+}
+
+type VolumeStatus struct {
+	VolumeName string        `xml:"volName"`
+	Bricks     []BrickStatus `xml:"node"`
+}
+
+// States of a rebalance as reported by gluster.
+const (
+	RebalanceNotStarted = "not started"
+	RebalanceInProgress = "in progress"
+	RebalanceStopped    = "stopped"
+	RebalanceCompleted  = "completed"
+	RebalanceFailed     = "failed"
+)
+
+// RebalanceStatus is the aggregated state of the last rebalance of
+// a volume.
+type RebalanceStatus struct {
+	StatusStr string `xml:"statusStr"`
+	// files migrated by all nodes
+	Files int `xml:"files"`
+	// state of the rebalance on each node of the volume
+	Nodes []RebalanceNodeStatus `xml:"-"`
+}
+
+// RebalanceNodeStatus is the state of a rebalance on one node.
+type RebalanceNodeStatus struct {
+	NodeName  string `xml:"nodeName"`
+	Files     int    `xml:"files"`
+	StatusStr string `xml:"statusStr"`
+}
+
+// Running returns true while the rebalance is still migrating files.
+func (r RebalanceStatus) Running() bool {
+	return r.StatusStr == RebalanceInProgress
+}
+
+// Progress returns how far the rebalance got, from 0 to 100. Gluster
+// does not report how many files a node has left to migrate, so the
+// progress is the share of nodes that are done with their part of the
+// rebalance. A rebalance that is no longer running is done.
+func (r RebalanceStatus) Progress() int {
+	if !r.Running() {
+		return 100
+	}
+	if len(r.Nodes) == 0 {
+		return 0
+	}
+	done := 0
+	for _, n := range r.Nodes {
+		if n.StatusStr != RebalanceInProgress &&
+			n.StatusStr != RebalanceNotStarted {
+			done++
+		}
+	}
+	return done * 100 / len(r.Nodes)
+}
+
+// BitrotStatus is the state of the bitrot scrubber of a volume
+// summed up over the nodes of the volume.
+type BitrotStatus struct {
+	// state of the scrubber, such as "Active" or "Paused"
+	State string
+	// duration of the longest of the last scrubs of the nodes
+	DurationSeconds int
+	FilesScrubbed   int
+	FilesCorrupted  int
+	// time the most recent scrub of a node completed, empty if the
+	// volume was never scrubbed
+	LastCompleted string
+}
+
+// GeoRepStatus is the state of a geo-replication session of a volume
+// as reported by each of the bricks of the volume.
+type GeoRepStatus struct {
+	Bricks []GeoRepBrickStatus
+}
+
+// GeoRepBrickStatus is the state of a geo-replication session on one
+// brick of the master volume.
+type GeoRepBrickStatus struct {
+	MasterNode string
+	BrickPath  string
+	SlaveNode  string
+	// such as "Active", "Passive", "Faulty" or "Stopped"
+	Status      string
+	CrawlStatus string
+	FilesSynced int
+	// the pending entry, data and metadata operations on gluster
+	// versions that no longer count the pending files
+	FilesPending int
+	BytesPending int64
+	// empty if nothing was synced yet
+	LastSynced string
+}
+
+// VolumeProfile holds the statistics gathered by the profiler of a
+// volume since profiling was started.
+type VolumeProfile struct {
+	Bricks []BrickProfile `xml:"brick"`
+}
+
+// BrickProfile holds the cumulative statistics of a brick.
+type BrickProfile struct {
+	Name  string       `xml:"brickName"`
+	Stats ProfileStats `xml:"cumulativeStats"`
+}
+
+// ProfileStats holds the statistics of a brick over a period of time.
+type ProfileStats struct {
+	// reads and writes by block size
+	Blocks          []ProfileBlockStats `xml:"blockStats>block"`
+	Fops            []ProfileFopStats   `xml:"fopStats>fop"`
+	DurationSeconds int64               `xml:"duration"`
+	// bytes read and written
+	TotalRead  int64 `xml:"totalRead"`
+	TotalWrite int64 `xml:"totalWrite"`
+}
+
+// ProfileBlockStats counts the reads and writes of a block size.
+type ProfileBlockStats struct {
+	Size   int64 `xml:"size"`
+	Reads  int64 `xml:"reads"`
+	Writes int64 `xml:"writes"`
+}
+
+// ProfileFopStats holds the number of calls and the latencies, in
+// microseconds, of a file operation.
+type ProfileFopStats struct {
+	Name       string  `xml:"name"`
+	Hits       int64   `xml:"hits"`
+	AvgLatency float64 `xml:"avgLatency"`
+	MinLatency float64 `xml:"minLatency"`
+	MaxLatency float64 `xml:"maxLatency"`
+}
+
+// LvmSnapshotRequest describes a snapshot of the logical
+// volume of a brick.
+type LvmSnapshotRequest struct {
+	VgId        string
+	LvName      string
+	Name        string
+	SizePercent int
+}
+
+type LvmSnapshotInfo struct {
+	Path string
+}
+
 type BlockVolumeRequest struct {
 	Name              string
 	Size              int
@@ -324,6 +542,16 @@ type BlockVolumeInfo struct {
 	Password          string
 }
 
+// BlockGatewayReplaceRequest moves the iSCSI target of a block volume
+// from one gateway node to another. The nodes are given by their
+// storage hostnames.
+type BlockGatewayReplaceRequest struct {
+	GlusterVolumeName string
+	Name              string
+	OldNode           string
+	NewNode           string
+}
+
 type VolumeDoesNotExistErr struct {
 	Name string
 }
@@ -26,24 +26,36 @@ func newMockBase() *mockexec.MockExecutor {
 	m.MockGlusterdCheck = func(host string) error {
 		return NotSupportedError
 	}
+	m.MockNodePing = func(host string) error {
+		return NotSupportedError
+	}
 	m.MockPeerProbe = func(exec_host, newnode string) error {
 		return NotSupportedError
 	}
 	m.MockPeerDetach = func(exec_host, newnode string) error {
 		return NotSupportedError
 	}
+	m.MockPeerStatus = func(host string) ([]executors.Peer, error) {
+		return nil, NotSupportedError
+	}
 	m.MockDeviceSetup = func(host, device, vgid string, destroy bool) (*executors.DeviceInfo, error) {
 		return nil, NotSupportedError
 	}
 	m.MockDeviceTeardown = func(host string, dh *executors.DeviceVgHandle) error {
 		return NotSupportedError
 	}
+	m.MockDeviceReinitialize = func(host, device, vgid string) (*executors.DeviceInfo, error) {
+		return nil, NotSupportedError
+	}
 	m.MockBrickCreate = func(host string, brick *executors.BrickRequest) (*executors.BrickInfo, error) {
 		return nil, NotSupportedError
 	}
 	m.MockBrickDestroy = func(host string, brick *executors.BrickRequest) (bool, error) {
 		return true, NotSupportedError
 	}
+	m.MockBrickLayoutCheck = func(host string, path string) (*executors.BrickLvInfo, error) {
+		return nil, NotSupportedError
+	}
 	m.MockVolumeCreate = func(host string, volume *executors.VolumeRequest) (*executors.Volume, error) {
 		return nil, NotSupportedError
 	}
@@ -68,6 +80,45 @@ func newMockBase() *mockexec.MockExecutor {
 	m.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
 		return nil, NotSupportedError
 	}
+	m.MockVolumeStatus = func(host string, volume string) (*executors.VolumeStatus, error) {
+		return nil, NotSupportedError
+	}
+	m.MockHealSplitBrainInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		return nil, NotSupportedError
+	}
+	m.MockHealSplitBrainResolve = func(host string, volume string, file string) error {
+		return NotSupportedError
+	}
+	m.MockVolumeRebalanceStatus = func(host string, volume string) (*executors.RebalanceStatus, error) {
+		return nil, NotSupportedError
+	}
+	m.MockVolumeRebalanceStart = func(host string, volume string) error {
+		return NotSupportedError
+	}
+	m.MockVolumeBitrotStatus = func(host string, volume string) (*executors.BitrotStatus, error) {
+		return nil, NotSupportedError
+	}
+	m.MockVolumeGeoRepStatus = func(host string, volume string, slave string) (*executors.GeoRepStatus, error) {
+		return nil, NotSupportedError
+	}
+	m.MockVolumeProfileStart = func(host string, volume string) error {
+		return NotSupportedError
+	}
+	m.MockVolumeProfileStop = func(host string, volume string) error {
+		return NotSupportedError
+	}
+	m.MockVolumeProfileInfo = func(host string, volume string) (*executors.VolumeProfile, error) {
+		return nil, NotSupportedError
+	}
+	m.MockVolumeStatedump = func(host string, volume string) error {
+		return NotSupportedError
+	}
+	m.MockLvmSnapshotCreate = func(host string, snap *executors.LvmSnapshotRequest) (*executors.LvmSnapshotInfo, error) {
+		return nil, NotSupportedError
+	}
+	m.MockLvmSnapshotDestroy = func(host string, snap *executors.LvmSnapshotRequest) error {
+		return NotSupportedError
+	}
 	m.MockBlockVolumeCreate = func(host string, blockVolume *executors.BlockVolumeRequest) (*executors.BlockVolumeInfo, error) {
 		return nil, NotSupportedError
 	}
@@ -89,6 +140,12 @@ func newMockBase() *mockexec.MockExecutor {
 	m.MockSnapshotDestroy = func(host string, snapshot string) error {
 		return NotSupportedError
 	}
+	m.MockSnapshotConfigSet = func(host string, option string, value string) error {
+		return NotSupportedError
+	}
+	m.MockSnapshotScheduleSet = func(host string, volume string, schedule string) error {
+		return NotSupportedError
+	}
 	m.MockPVS = func(host string) (*executors.PVSCommandOutput, error) {
 		return nil, NotSupportedError
 	}
@@ -110,6 +167,9 @@ func newMockBase() *mockexec.MockExecutor {
 	m.MockBlockVolumeExpand = func(host string, blockHostingVolumeName string, blockVolumeName string, newSize int) error {
 		return NotSupportedError
 	}
+	m.MockBlockVolumeReplace = func(host string, req *executors.BlockGatewayReplaceRequest) error {
+		return NotSupportedError
+	}
 	m.MockBlockVolumeInfo = func(host string, blockHostingVolumeName string, blockVolumeName string) (*executors.BlockVolumeInfo, error) {
 		return nil, NotSupportedError
 	}
@@ -104,7 +104,9 @@ func NewKubeExecutor(config *KubeConfig) (*KubeExecutor, error) {
 	// Initialize
 	k := &KubeExecutor{}
 	k.config = config
-	k.CmdExecutor.Init(&config.CmdConfig)
+	if err := k.CmdExecutor.Init(&config.CmdConfig); err != nil {
+		return nil, err
+	}
 	k.RemoteExecutor = k
 
 	if k.config.Fstab == "" {
@@ -11,7 +11,9 @@ package mockexec
 
 import (
 	"os"
+	"path"
 	"strconv"
+	"strings"
 
 	"github.com/heketi/heketi/executors"
 )
@@ -19,13 +21,17 @@ import (
 type MockExecutor struct {
 	// These functions can be overwritten for testing
 	MockGlusterdCheck            func(host string) error
+	MockNodePing                 func(host string) error
 	MockPeerProbe                func(exec_host, newnode string) error
 	MockPeerDetach               func(exec_host, newnode string) error
+	MockPeerStatus               func(host string) ([]executors.Peer, error)
 	MockDeviceSetup              func(host, device, vgid string, destroy bool) (*executors.DeviceInfo, error)
 	MockDeviceTeardown           func(host string, dh *executors.DeviceVgHandle) error
+	MockDeviceReinitialize       func(host, device, vgid string) (*executors.DeviceInfo, error)
 	MockGetDeviceInfo            func(host string, dh *executors.DeviceVgHandle) (*executors.DeviceInfo, error)
 	MockBrickCreate              func(host string, brick *executors.BrickRequest) (*executors.BrickInfo, error)
 	MockBrickDestroy             func(host string, brick *executors.BrickRequest) (bool, error)
+	MockBrickLayoutCheck         func(host string, path string) (*executors.BrickLvInfo, error)
 	MockVolumeCreate             func(host string, volume *executors.VolumeRequest) (*executors.Volume, error)
 	MockVolumeExpand             func(host string, volume *executors.VolumeRequest) (*executors.Volume, error)
 	MockVolumeDestroy            func(host string, volume string) error
@@ -39,11 +45,27 @@ type MockExecutor struct {
 	MockSnapshotCloneVolume      func(host string, volume *executors.SnapshotCloneRequest) (*executors.Volume, error)
 	MockSnapshotCloneBlockVolume func(host string, volume *executors.SnapshotCloneRequest) (*executors.BlockVolumeInfo, error)
 	MockSnapshotDestroy          func(host string, snapshot string) error
+	MockSnapshotConfigSet        func(host string, option string, value string) error
+	MockSnapshotScheduleSet      func(host string, volume string, schedule string) error
 	MockHealInfo                 func(host string, volume string) (*executors.HealInfo, error)
+	MockHealSplitBrainInfo       func(host string, volume string) (*executors.HealInfo, error)
+	MockHealSplitBrainResolve    func(host string, volume string, file string) error
+	MockVolumeStatus             func(host string, volume string) (*executors.VolumeStatus, error)
+	MockVolumeRebalanceStatus    func(host string, volume string) (*executors.RebalanceStatus, error)
+	MockVolumeBitrotStatus       func(host string, volume string) (*executors.BitrotStatus, error)
+	MockVolumeGeoRepStatus       func(host string, volume string, slave string) (*executors.GeoRepStatus, error)
+	MockVolumeProfileStart       func(host string, volume string) error
+	MockVolumeProfileStop        func(host string, volume string) error
+	MockVolumeProfileInfo        func(host string, volume string) (*executors.VolumeProfile, error)
+	MockVolumeStatedump          func(host string, volume string) error
+	MockVolumeRebalanceStart     func(host string, volume string) error
+	MockLvmSnapshotCreate        func(host string, snap *executors.LvmSnapshotRequest) (*executors.LvmSnapshotInfo, error)
+	MockLvmSnapshotDestroy       func(host string, snap *executors.LvmSnapshotRequest) error
 	MockBlockVolumeCreate        func(host string, blockVolume *executors.BlockVolumeRequest) (*executors.BlockVolumeInfo, error)
 	MockBlockVolumeDestroy       func(host string, blockHostingVolumeName string, blockVolumeName string) error
 	MockBlockVolumeInfo          func(host string, blockHostingVolumeName string, blockVolumeName string) (*executors.BlockVolumeInfo, error)
 	MockBlockVolumeExpand        func(host string, blockHostingVolumeName string, blockVolumeName string, newSize int) error
+	MockBlockVolumeReplace       func(host string, req *executors.BlockGatewayReplaceRequest) error
 	MockPVS                      func(host string) (*executors.PVSCommandOutput, error)
 	MockVGS                      func(host string) (*executors.VGSCommandOutput, error)
 	MockLVS                      func(host string) (*executors.LVSCommandOutput, error)
@@ -61,6 +83,10 @@ func NewMockExecutor() (*MockExecutor, error) {
 		return nil
 	}
 
+	m.MockNodePing = func(host string) error {
+		return nil
+	}
+
 	m.MockPeerProbe = func(exec_host, newnode string) error {
 		return nil
 	}
@@ -69,6 +95,10 @@ func NewMockExecutor() (*MockExecutor, error) {
 		return nil
 	}
 
+	m.MockPeerStatus = func(host string) ([]executors.Peer, error) {
+		return []executors.Peer{}, nil
+	}
+
 	m.MockDeviceSetup = func(host, device, vgid string, destroy bool) (*executors.DeviceInfo, error) {
 		dsize := m.DeviceSizeGb() * 1024 * 1024
 		d := &executors.DeviceInfo{}
@@ -83,6 +113,10 @@ func NewMockExecutor() (*MockExecutor, error) {
 		return nil
 	}
 
+	m.MockDeviceReinitialize = func(host, device, vgid string) (*executors.DeviceInfo, error) {
+		return m.MockDeviceSetup(host, device, vgid, true)
+	}
+
 	m.MockGetDeviceInfo = func(host string, dh *executors.DeviceVgHandle) (*executors.DeviceInfo, error) {
 		dsize := m.DeviceSizeGb() * 1024 * 1024
 		d := &executors.DeviceInfo{}
@@ -105,6 +139,18 @@ func NewMockExecutor() (*MockExecutor, error) {
 		return true, nil
 	}
 
+	m.MockBrickLayoutCheck = func(host string, brickPath string) (*executors.BrickLvInfo, error) {
+		// assume the brick follows heketi's own layout:
+		// <root>/<vg>/brick_<id>/brick
+		mount := path.Dir(brickPath)
+		lv := path.Base(mount)
+		return &executors.BrickLvInfo{
+			VgName: path.Base(path.Dir(mount)),
+			LvName: lv,
+			TpName: "tp_" + strings.TrimPrefix(lv, "brick_"),
+		}, nil
+	}
+
 	m.MockVolumeCreate = func(host string, volume *executors.VolumeRequest) (*executors.Volume, error) {
 		return &executors.Volume{}, nil
 	}
@@ -210,10 +256,74 @@ func NewMockExecutor() (*MockExecutor, error) {
 		return nil
 	}
 
+	m.MockSnapshotConfigSet = func(host string, option string, value string) error {
+		return nil
+	}
+
+	m.MockSnapshotScheduleSet = func(host string, volume string, schedule string) error {
+		return nil
+	}
+
 	m.MockHealInfo = func(host string, volume string) (*executors.HealInfo, error) {
 		return &executors.HealInfo{}, nil
 	}
 
+	m.MockVolumeStatus = func(host string, volume string) (*executors.VolumeStatus, error) {
+		return &executors.VolumeStatus{VolumeName: volume}, nil
+	}
+
+	m.MockHealSplitBrainInfo = func(host string, volume string) (*executors.HealInfo, error) {
+		return &executors.HealInfo{}, nil
+	}
+
+	m.MockHealSplitBrainResolve = func(host string, volume string, file string) error {
+		return nil
+	}
+
+	m.MockVolumeRebalanceStatus = func(host string, volume string) (*executors.RebalanceStatus, error) {
+		return &executors.RebalanceStatus{
+			StatusStr: executors.RebalanceCompleted,
+		}, nil
+	}
+
+	m.MockVolumeRebalanceStart = func(host string, volume string) error {
+		return nil
+	}
+
+	m.MockVolumeBitrotStatus = func(host string, volume string) (*executors.BitrotStatus, error) {
+		return &executors.BitrotStatus{State: "Active"}, nil
+	}
+
+	m.MockVolumeGeoRepStatus = func(host string, volume string, slave string) (*executors.GeoRepStatus, error) {
+		return &executors.GeoRepStatus{}, nil
+	}
+
+	m.MockVolumeProfileStart = func(host string, volume string) error {
+		return nil
+	}
+
+	m.MockVolumeProfileStop = func(host string, volume string) error {
+		return nil
+	}
+
+	m.MockVolumeProfileInfo = func(host string, volume string) (*executors.VolumeProfile, error) {
+		return &executors.VolumeProfile{}, nil
+	}
+
+	m.MockVolumeStatedump = func(host string, volume string) error {
+		return nil
+	}
+
+	m.MockLvmSnapshotCreate = func(host string, snap *executors.LvmSnapshotRequest) (*executors.LvmSnapshotInfo, error) {
+		return &executors.LvmSnapshotInfo{
+			Path: "/dev/vg_" + snap.VgId + "/" + snap.Name,
+		}, nil
+	}
+
+	m.MockLvmSnapshotDestroy = func(host string, snap *executors.LvmSnapshotRequest) error {
+		return nil
+	}
+
 	m.MockBlockVolumeCreate = func(host string, blockVolume *executors.BlockVolumeRequest) (*executors.BlockVolumeInfo, error) {
 		var blockVolumeInfo executors.BlockVolumeInfo
 		blockVolumeInfo.BlockHosts = blockVolume.BlockHosts
@@ -243,6 +353,10 @@ func NewMockExecutor() (*MockExecutor, error) {
 		return nil
 	}
 
+	m.MockBlockVolumeReplace = func(host string, req *executors.BlockGatewayReplaceRequest) error {
+		return nil
+	}
+
 	m.MockPVS = func(host string) (*executors.PVSCommandOutput, error) {
 		return &executors.PVSCommandOutput{}, nil
 	}
@@ -289,6 +403,10 @@ func (m *MockExecutor) GlusterdCheck(host string) error {
 	return m.MockGlusterdCheck(host)
 }
 
+func (m *MockExecutor) NodePing(host string) error {
+	return m.MockNodePing(host)
+}
+
 func (m *MockExecutor) PeerProbe(exec_host, newnode string) error {
 	return m.MockPeerProbe(exec_host, newnode)
 }
@@ -297,6 +415,10 @@ func (m *MockExecutor) PeerDetach(exec_host, newnode string) error {
 	return m.MockPeerDetach(exec_host, newnode)
 }
 
+func (m *MockExecutor) PeerStatus(host string) ([]executors.Peer, error) {
+	return m.MockPeerStatus(host)
+}
+
 func (m *MockExecutor) DeviceSetup(host, device, vgid string, destroy bool) (*executors.DeviceInfo, error) {
 	return m.MockDeviceSetup(host, device, vgid, destroy)
 }
@@ -313,6 +435,10 @@ func (m *MockExecutor) DeviceForget(host string, dh *executors.DeviceVgHandle) e
 	return m.MockDeviceTeardown(host, dh)
 }
 
+func (m *MockExecutor) DeviceReinitialize(host, device, vgid string) (*executors.DeviceInfo, error) {
+	return m.MockDeviceReinitialize(host, device, vgid)
+}
+
 func (m *MockExecutor) BrickCreate(host string, brick *executors.BrickRequest) (*executors.BrickInfo, error) {
 	return m.MockBrickCreate(host, brick)
 }
@@ -321,6 +447,10 @@ func (m *MockExecutor) BrickDestroy(host string, brick *executors.BrickRequest)
 	return m.MockBrickDestroy(host, brick)
 }
 
+func (m *MockExecutor) BrickLayoutCheck(host string, path string) (*executors.BrickLvInfo, error) {
+	return m.MockBrickLayoutCheck(host, path)
+}
+
 func (m *MockExecutor) VolumeCreate(host string, volume *executors.VolumeRequest) (*executors.Volume, error) {
 	return m.MockVolumeCreate(host, volume)
 }
@@ -369,10 +499,70 @@ func (m *MockExecutor) SnapshotDestroy(host string, snapshot string) error {
 	return m.MockSnapshotDestroy(host, snapshot)
 }
 
+func (m *MockExecutor) SnapshotConfigSet(host string, option string, value string) error {
+	return m.MockSnapshotConfigSet(host, option, value)
+}
+
+func (m *MockExecutor) SnapshotScheduleSet(host string, volume string, schedule string) error {
+	return m.MockSnapshotScheduleSet(host, volume, schedule)
+}
+
 func (m *MockExecutor) HealInfo(host string, volume string) (*executors.HealInfo, error) {
 	return m.MockHealInfo(host, volume)
 }
 
+func (m *MockExecutor) VolumeStatus(host string, volume string) (*executors.VolumeStatus, error) {
+	return m.MockVolumeStatus(host, volume)
+}
+
+func (m *MockExecutor) HealSplitBrainInfo(host string, volume string) (*executors.HealInfo, error) {
+	return m.MockHealSplitBrainInfo(host, volume)
+}
+
+func (m *MockExecutor) HealSplitBrainResolve(host string, volume string, file string) error {
+	return m.MockHealSplitBrainResolve(host, volume, file)
+}
+
+func (m *MockExecutor) VolumeRebalanceStatus(host string, volume string) (*executors.RebalanceStatus, error) {
+	return m.MockVolumeRebalanceStatus(host, volume)
+}
+
+func (m *MockExecutor) VolumeRebalanceStart(host string, volume string) error {
+	return m.MockVolumeRebalanceStart(host, volume)
+}
+
+func (m *MockExecutor) VolumeBitrotStatus(host string, volume string) (*executors.BitrotStatus, error) {
+	return m.MockVolumeBitrotStatus(host, volume)
+}
+
+func (m *MockExecutor) VolumeGeoRepStatus(host string, volume string, slave string) (*executors.GeoRepStatus, error) {
+	return m.MockVolumeGeoRepStatus(host, volume, slave)
+}
+
+func (m *MockExecutor) VolumeProfileStart(host string, volume string) error {
+	return m.MockVolumeProfileStart(host, volume)
+}
+
+func (m *MockExecutor) VolumeProfileStop(host string, volume string) error {
+	return m.MockVolumeProfileStop(host, volume)
+}
+
+func (m *MockExecutor) VolumeProfileInfo(host string, volume string) (*executors.VolumeProfile, error) {
+	return m.MockVolumeProfileInfo(host, volume)
+}
+
+func (m *MockExecutor) VolumeStatedump(host string, volume string) error {
+	return m.MockVolumeStatedump(host, volume)
+}
+
+func (m *MockExecutor) LvmSnapshotCreate(host string, snap *executors.LvmSnapshotRequest) (*executors.LvmSnapshotInfo, error) {
+	return m.MockLvmSnapshotCreate(host, snap)
+}
+
+func (m *MockExecutor) LvmSnapshotDestroy(host string, snap *executors.LvmSnapshotRequest) error {
+	return m.MockLvmSnapshotDestroy(host, snap)
+}
+
 func (m *MockExecutor) BlockVolumeCreate(host string, blockVolume *executors.BlockVolumeRequest) (*executors.BlockVolumeInfo, error) {
 	return m.MockBlockVolumeCreate(host, blockVolume)
 }
@@ -385,6 +575,10 @@ func (m *MockExecutor) BlockVolumeExpand(host string, blockHostingVolumeName str
 	return m.MockBlockVolumeExpand(host, blockHostingVolumeName, blockVolumeName, newSize)
 }
 
+func (m *MockExecutor) BlockVolumeReplace(host string, req *executors.BlockGatewayReplaceRequest) error {
+	return m.MockBlockVolumeReplace(host, req)
+}
+
 func (m *MockExecutor) BlockVolumeInfo(host string, blockHostingVolumeName string, blockVolumeName string) (*executors.BlockVolumeInfo, error) {
 	return m.MockBlockVolumeInfo(host, blockHostingVolumeName, blockVolumeName)
 }
@@ -0,0 +1,173 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	rex "github.com/heketi/heketi/pkg/remoteexec"
+)
+
+const (
+	// the stdout and stderr of a command are cut to this many bytes
+	auditOutputLimit = 4096
+	// exit code recorded for commands that did not complete
+	auditNoExitCode = -1
+)
+
+// SshAuditRotationConfig controls the rotation of the ssh audit log.
+// The log is rotated once it grows beyond MaxSizeMB, keeping at most
+// MaxBackups old files named <path>.1 (the newest) to <path>.N.
+type SshAuditRotationConfig struct {
+	MaxSizeMB  int `json:"max_size_mb"`
+	MaxBackups int `json:"max_backups"`
+}
+
+// SshAuditEntry is the record of a single command in the audit log.
+type SshAuditEntry struct {
+	Time     string `json:"time"`
+	Host     string `json:"host"`
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SshAuditLogger writes every command sent over ssh to a file, one
+// JSON object per line.
+type SshAuditLogger struct {
+	lock       sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewSshAuditLogger returns a new audit logger appending to the file
+// at path. A maxSizeMB of 0 disables rotation.
+func NewSshAuditLogger(path string, rotation SshAuditRotationConfig) (
+	*SshAuditLogger, error) {
+
+	a := &SshAuditLogger{
+		path:       path,
+		maxSize:    int64(rotation.MaxSizeMB) * 1024 * 1024,
+		maxBackups: rotation.MaxBackups,
+	}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *SshAuditLogger) open() error {
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("Unable to open ssh audit log %v: %v", a.path, err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	a.file = f
+	a.size = fi.Size()
+	return nil
+}
+
+// rotate moves the current file to the first backup, shifting the
+// older backups and dropping the oldest one.
+func (a *SshAuditLogger) rotate() error {
+	a.file.Close()
+	if a.maxBackups > 0 {
+		for i := a.maxBackups - 1; i > 0; i-- {
+			src := fmt.Sprintf("%v.%v", a.path, i)
+			if _, err := os.Stat(src); err == nil {
+				os.Rename(src, fmt.Sprintf("%v.%v", a.path, i+1))
+			}
+		}
+		if err := os.Rename(a.path, a.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(a.path); err != nil {
+		return err
+	}
+	return a.open()
+}
+
+// Log records the commands run on host. The results hold the commands
+// that completed, the command that was running when err was returned
+// is recorded with the error. Commands that were never started are
+// not recorded.
+func (a *SshAuditLogger) Log(host string,
+	commands rex.Cmds, results rex.Results, err error) error {
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	entries := []SshAuditEntry{}
+	for i, c := range commands {
+		e := SshAuditEntry{
+			Time:     now,
+			Host:     host,
+			Command:  c.String(),
+			ExitCode: auditNoExitCode,
+		}
+		if i < len(results) && results[i].Completed {
+			r := results[i]
+			e.ExitCode = r.ExitStatus
+			if r.Err != nil {
+				e.Error = r.Err.Error()
+			}
+			if !c.Opts().Quiet {
+				e.Stdout = truncateAuditOutput(r.Output)
+				e.Stderr = truncateAuditOutput(r.ErrOutput)
+			}
+			entries = append(entries, e)
+			continue
+		}
+		if err != nil {
+			e.Error = err.Error()
+			entries = append(entries, e)
+		}
+		break
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+		if a.maxSize > 0 && a.size > 0 && a.size+int64(len(line)) > a.maxSize {
+			if err := a.rotate(); err != nil {
+				return err
+			}
+		}
+		n, err := a.file.Write(line)
+		a.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func truncateAuditOutput(s string) string {
+	if len(s) <= auditOutputLimit {
+		return s
+	}
+	return s[:auditOutputLimit] + "...(truncated)"
+}
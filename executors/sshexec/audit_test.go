@@ -0,0 +1,177 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package sshexec
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/heketi/tests"
+
+	"github.com/heketi/heketi/executors"
+	"github.com/heketi/heketi/pkg/logging"
+	rex "github.com/heketi/heketi/pkg/remoteexec"
+)
+
+func readAuditLog(t *testing.T, path string) []SshAuditEntry {
+	f, err := os.Open(path)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	defer f.Close()
+
+	entries := []SshAuditEntry{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e SshAuditEntry
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		entries = append(entries, e)
+	}
+	tests.Assert(t, scanner.Err() == nil, scanner.Err())
+	return entries
+}
+
+func TestSshAuditLogVolumeCreate(t *testing.T) {
+	logfile := tests.Tempfile()
+	defer os.Remove(logfile)
+
+	sent := []string{}
+	f := NewFakeSsh()
+	f.FakeExecCommands = func(host string,
+		commands rex.Cmds,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+
+		results := make(rex.Results, len(commands))
+		for i, c := range commands {
+			sent = append(sent, c.String())
+			results[i] = rex.Result{Completed: true, Output: "success"}
+		}
+		return results, nil
+	}
+	defer tests.Patch(&sshNew,
+		func(logger *logging.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	s, err := NewSshExecutor(&SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		AuditLogPath:   logfile,
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	bricks := []executors.BrickInfo{}
+	for _, h := range []string{"node1", "node2", "node3", "node1", "node2", "node3"} {
+		bricks = append(bricks, executors.BrickInfo{
+			Host: h,
+			Path: "/bricks/" + h,
+		})
+	}
+	_, err = s.VolumeCreate("node1", &executors.VolumeRequest{
+		Bricks:               bricks,
+		Name:                 "vol1",
+		Type:                 executors.DurabilityReplica,
+		Replica:              3,
+		GlusterVolumeOptions: []string{"performance.rda-cache-limit 10MB"},
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	entries := readAuditLog(t, logfile)
+	tests.Assert(t, len(sent) > 2, "expected several commands, got:", sent)
+	tests.Assert(t, len(entries) == len(sent),
+		"expected", len(sent), "entries, got:", len(entries))
+	for i, e := range entries {
+		tests.Assert(t, e.Command == sent[i],
+			"expected", sent[i], "got:", e.Command)
+		tests.Assert(t, e.Host == "node1", "expected node1, got:", e.Host)
+		tests.Assert(t, e.ExitCode == 0, "expected exit code 0, got:", e.ExitCode)
+		tests.Assert(t, e.Stdout == "success", "expected stdout, got:", e.Stdout)
+		tests.Assert(t, e.Time != "", "expected time to be set")
+	}
+	tests.Assert(t, strings.Contains(entries[0].Command, "volume create vol1"),
+		"expected volume create, got:", entries[0].Command)
+}
+
+func TestSshAuditLogFailures(t *testing.T) {
+	logfile := tests.Tempfile()
+	defer os.Remove(logfile)
+
+	a, err := NewSshAuditLogger(logfile, SshAuditRotationConfig{})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// the second command fails and the third is never run
+	cmds := rex.ToCmds([]string{"true", "false", "true"})
+	err = a.Log("node1", cmds, rex.Results{
+		{Completed: true},
+		{
+			Completed:  true,
+			ErrOutput:  strings.Repeat("x", auditOutputLimit+10),
+			Err:        errors.New("exit status 2"),
+			ExitStatus: 2,
+		},
+		{},
+	}, nil)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// the connection to the node fails
+	err = a.Log("node2", rex.OneCmd("lvs"), nil, errors.New("no route to host"))
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	entries := readAuditLog(t, logfile)
+	tests.Assert(t, len(entries) == 3, "expected 3 entries, got:", entries)
+	tests.Assert(t, entries[0].Command == "true" && entries[0].ExitCode == 0,
+		"unexpected entry:", entries[0])
+	tests.Assert(t, entries[1].Command == "false" && entries[1].ExitCode == 2,
+		"unexpected entry:", entries[1])
+	tests.Assert(t, entries[1].Error == "exit status 2",
+		"expected error, got:", entries[1].Error)
+	tests.Assert(t, strings.HasSuffix(entries[1].Stderr, "...(truncated)"),
+		"expected stderr truncated, got:", len(entries[1].Stderr))
+	tests.Assert(t, entries[2].Host == "node2" && entries[2].Command == "lvs",
+		"unexpected entry:", entries[2])
+	tests.Assert(t, entries[2].ExitCode == auditNoExitCode,
+		"expected no exit code, got:", entries[2].ExitCode)
+	tests.Assert(t, entries[2].Error == "no route to host",
+		"expected error, got:", entries[2].Error)
+}
+
+func TestSshAuditLogRotation(t *testing.T) {
+	logfile := tests.Tempfile()
+	defer os.Remove(logfile)
+	defer os.Remove(logfile + ".1")
+	defer os.Remove(logfile + ".2")
+	defer os.Remove(logfile + ".3")
+
+	a, err := NewSshAuditLogger(logfile, SshAuditRotationConfig{
+		MaxSizeMB:  1,
+		MaxBackups: 2,
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	// rotate after every few entries
+	a.maxSize = 400
+
+	for i := 0; i < 20; i++ {
+		err := a.Log("node1", rex.OneCmd("gluster volume list"),
+			rex.Results{{Completed: true}}, nil)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	}
+
+	for _, p := range []string{logfile, logfile + ".1", logfile + ".2"} {
+		fi, err := os.Stat(p)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		tests.Assert(t, fi.Size() <= 400, "expected at most 400 bytes, got:", fi.Size())
+		tests.Assert(t, len(readAuditLog(t, p)) > 0, "expected entries in", p)
+	}
+	_, err = os.Stat(logfile + ".3")
+	tests.Assert(t, os.IsNotExist(err), "expected no third backup, got:", err)
+}
@@ -19,4 +19,24 @@ type SshConfig struct {
 	PrivateKeyFile string `json:"keyfile"`
 	User           string `json:"user"`
 	Port           string `json:"port"`
+
+	// Clusters overrides the ssh settings for the nodes of a
+	// cluster, indexed by cluster id.
+	Clusters map[string]ClusterSshConfig `json:"clusters,omitempty"`
+
+	// AuditLogPath is a file recording every command sent over ssh.
+	AuditLogPath     string                 `json:"ssh_audit_log_path"`
+	AuditLogRotation SshAuditRotationConfig `json:"ssh_audit_log_rotation"`
+
+	// KnownHostsFile holds the host keys the nodes are verified
+	// against. Host keys are not verified if not set.
+	KnownHostsFile string `json:"known_hosts_file"`
+}
+
+// ClusterSshConfig holds the ssh settings of the nodes of one cluster.
+// Settings left empty are taken from the global configuration.
+type ClusterSshConfig struct {
+	PrivateKeyFile string `json:"keyfile"`
+	User           string `json:"user"`
+	Port           string `json:"port"`
 }
@@ -12,8 +12,11 @@ package sshexec
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os"
+	"path"
 	"strconv"
+	"sync"
 
 	"github.com/lpabon/godbc"
 
@@ -36,17 +39,105 @@ type SshExecutor struct {
 	exec            Ssher
 	config          *SshConfig
 	port            string
+
+	// per-node keys, the keys are loaded from the source once and
+	// kept until invalidated
+	keySource  SshKeySource
+	keyLock    sync.Mutex
+	keys       []SshKey
+	keysLoaded bool
+	keyExecs   map[string]*keyedSsher
+
+	// per-cluster settings
+	clusterSource HostClusterSource
+	clusterExecs  map[hostSshConfig]Ssher
+
+	// addresses of hosts to use if name resolution fails
+	ipCache HostIPCache
+
+	// records the commands sent, nil if not enabled
+	audit *SshAuditLogger
+
+	// verifies the host keys of the nodes, nil if not enabled
+	knownHosts *ssh.KnownHosts
+}
+
+// SshKey is a private key used to connect to the nodes whose
+// hostnames match the NodePattern glob.
+type SshKey struct {
+	Id          string
+	NodePattern string
+	PrivateKey  string
+	Passphrase  string
+}
+
+// SshKeySource provides the per-node ssh keys. Keys are returned in
+// priority order, the first key matching a host is used.
+type SshKeySource interface {
+	SshKeys() ([]SshKey, error)
+}
+
+// HostClusterSource maps the hostname of a node to the id of the
+// cluster the node belongs to. An empty id is returned for hosts
+// that are not part of any cluster.
+type HostClusterSource interface {
+	HostCluster(host string) (string, error)
+}
+
+// HostIPCache keeps the address a hostname last resolved to. An empty
+// address is returned for hosts without a cached (or with an expired)
+// address.
+type HostIPCache interface {
+	HostIP(host string) (string, error)
+	SetHostIP(host, ip string) error
+}
+
+// addressSsher is implemented by the sshers that can connect to an
+// address other than the one the hostname resolves to while still
+// verifying the host key of the hostname.
+type addressSsher interface {
+	ExecCommandsAt(addr, host string, commands rex.Cmds,
+		timeoutMinutes int, useSudo bool) (rex.Results, error)
+}
+
+// knownHostsSsher is implemented by the sshers that can verify the
+// host keys of the nodes.
+type knownHostsSsher interface {
+	SetKnownHosts(k *ssh.KnownHosts)
+}
+
+type keyedSsher struct {
+	key  SshKey
+	user string
+	exec Ssher
+}
+
+// hostSshConfig is the ssh configuration used to connect to a host.
+type hostSshConfig struct {
+	user    string
+	keyfile string
+	port    string
 }
 
 var (
-	ErrSshPrivateKey = errors.New("Unable to read private key file")
-	sshNew           = func(logger *logging.Logger, user string, file string) (Ssher, error) {
+	ErrSshPrivateKey      = errors.New("Unable to read private key file")
+	ErrKnownHostsDisabled = errors.New("no known_hosts_file configured")
+	sshNew                = func(logger *logging.Logger, user string, file string) (Ssher, error) {
 		s := ssh.NewSshExecWithKeyFile(logger, user, file)
 		if s == nil {
 			return nil, ErrSshPrivateKey
 		}
 		return s, nil
 	}
+	sshNewWithKey = func(logger *logging.Logger,
+		user string, key []byte, passphrase string) (Ssher, error) {
+		return ssh.NewSshExecWithKey(logger, user, key, passphrase)
+	}
+	lookupHost       = net.LookupHost
+	updateKnownHosts = func(k *ssh.KnownHosts,
+		addr string, hostnames ...string) (string, error) {
+		return k.Update(addr, hostnames...)
+	}
 )
 
 func setWithEnvVariables(config *SshConfig) {
@@ -92,7 +183,9 @@ func NewSshExecutor(config *SshConfig) (*SshExecutor, error) {
 	setWithEnvVariables(config)
 
 	s := &SshExecutor{}
-	s.CmdExecutor.Init(&config.CmdConfig)
+	if err := s.CmdExecutor.Init(&config.CmdConfig); err != nil {
+		return nil, err
+	}
 	s.RemoteExecutor = s
 
 	// Set configuration
@@ -130,13 +223,31 @@ func NewSshExecutor(config *SshConfig) (*SshExecutor, error) {
 	// Save the configuration
 	s.config = config
 
-	// Setup key
 	var err error
+	if config.KnownHostsFile != "" {
+		s.knownHosts, err = ssh.NewKnownHosts(config.KnownHostsFile)
+		if err != nil {
+			s.Logger().Err(err)
+			return nil, err
+		}
+	}
+
+	// Setup key
 	s.exec, err = sshNew(s.Logger(), s.user, s.private_keyfile)
 	if err != nil {
 		s.Logger().Err(err)
 		return nil, err
 	}
+	s.useKnownHosts(s.exec)
+
+	if config.AuditLogPath != "" {
+		s.audit, err = NewSshAuditLogger(config.AuditLogPath,
+			config.AuditLogRotation)
+		if err != nil {
+			s.Logger().Err(err)
+			return nil, err
+		}
+	}
 
 	godbc.Ensure(s != nil)
 	godbc.Ensure(s.config == config)
@@ -156,8 +267,239 @@ func (s *SshExecutor) ExecCommands(
 	s.AccessConnection(host)
 	defer s.FreeConnection(host)
 
+	hc, err := s.configForHost(host)
+	if err != nil {
+		return nil, err
+	}
+	exec, err := s.ssherForConfig(host, hc)
+	if err != nil {
+		return nil, err
+	}
+	addr, ip := s.addressForHost(host)
+
 	// Execute
-	return s.exec.ExecCommands(host+":"+s.port, commands, timeoutMinutes, s.config.Sudo)
+	var results rex.Results
+	if as, ok := exec.(addressSsher); ok && addr != host {
+		results, err = as.ExecCommandsAt(net.JoinHostPort(addr, hc.port),
+			net.JoinHostPort(host, hc.port),
+			commands, timeoutMinutes, s.config.Sudo)
+	} else {
+		results, err = exec.ExecCommands(net.JoinHostPort(addr, hc.port),
+			commands, timeoutMinutes, s.config.Sudo)
+	}
+	if err == nil && ip != "" {
+		if cerr := s.ipCache.SetHostIP(host, ip); cerr != nil {
+			s.Logger().LogError("Unable to cache address of %v: %v", host, cerr)
+		}
+	}
+	if s.audit != nil {
+		if aerr := s.audit.Log(host, commands, results, err); aerr != nil {
+			s.Logger().LogError("Unable to write ssh audit log: %v", aerr)
+		}
+	}
+	return results, err
+}
+
+// SetKeySource configures a source of per-node ssh keys. Hosts that
+// do not match any of the keys use the key from the configuration file.
+func (s *SshExecutor) SetKeySource(src SshKeySource) {
+	s.keyLock.Lock()
+	defer s.keyLock.Unlock()
+	s.keySource = src
+	s.keys = nil
+	s.keysLoaded = false
+	s.keyExecs = map[string]*keyedSsher{}
+}
+
+// InvalidateSshKeys drops the keys loaded from the key source and the
+// ssh clients using them. It must be called whenever keys are added
+// to, changed in or removed from the source.
+func (s *SshExecutor) InvalidateSshKeys() {
+	s.keyLock.Lock()
+	defer s.keyLock.Unlock()
+	s.keys = nil
+	s.keysLoaded = false
+	s.keyExecs = map[string]*keyedSsher{}
+}
+
+// SetHostClusterSource configures the source used to find the cluster
+// of a host. The ssh settings of the cluster in the configuration file
+// are used for the hosts of that cluster.
+func (s *SshExecutor) SetHostClusterSource(src HostClusterSource) {
+	s.keyLock.Lock()
+	defer s.keyLock.Unlock()
+	s.clusterSource = src
+	s.clusterExecs = map[hostSshConfig]Ssher{}
+}
+
+// SetHostIPCache configures a cache of the addresses of the hosts.
+// Hostnames are then resolved before connecting and the address is
+// cached after a successful connection. If a hostname can not be
+// resolved the cached address is used.
+func (s *SshExecutor) SetHostIPCache(c HostIPCache) {
+	s.ipCache = c
+}
+
+// addressForHost returns the address to connect to for the host and
+// the address the hostname freshly resolved to, if any. The hostname
+// itself is connected to unless it can not be resolved, in which case
+// its cached address is used. The host key is always verified as the
+// key of the hostname.
+func (s *SshExecutor) addressForHost(host string) (string, string) {
+	if s.ipCache == nil || net.ParseIP(host) != nil {
+		return host, ""
+	}
+	ips, err := lookupHost(host)
+	if err == nil && len(ips) > 0 {
+		return host, ips[0]
+	}
+	ip, cerr := s.ipCache.HostIP(host)
+	if cerr != nil || ip == "" {
+		// let the connection report the failure
+		return host, ""
+	}
+	s.Logger().Warning("Unable to resolve %v (%v), using cached address %v",
+		host, err, ip)
+	return ip, ""
+}
+
+// MatchSshKey returns the first key whose node pattern matches
+// the given host or nil if no key matches.
+func MatchSshKey(keys []SshKey, host string) *SshKey {
+	for i := range keys {
+		if ok, _ := path.Match(keys[i].NodePattern, host); ok {
+			return &keys[i]
+		}
+	}
+	return nil
+}
+
+// configForHost returns the ssh settings for the host, merging the
+// settings of the cluster of the host over the global settings.
+func (s *SshExecutor) configForHost(host string) (hostSshConfig, error) {
+	hc := hostSshConfig{
+		user:    s.user,
+		keyfile: s.private_keyfile,
+		port:    s.port,
+	}
+	if s.clusterSource == nil || len(s.config.Clusters) == 0 {
+		return hc, nil
+	}
+	clusterId, err := s.clusterSource.HostCluster(host)
+	if err != nil {
+		return hc, s.Logger().LogError(
+			"Unable to find cluster of host %v: %v", host, err)
+	}
+	cc, ok := s.config.Clusters[clusterId]
+	if !ok {
+		return hc, nil
+	}
+	if cc.User != "" {
+		hc.user = cc.User
+	}
+	if cc.PrivateKeyFile != "" {
+		hc.keyfile = cc.PrivateKeyFile
+	}
+	if cc.Port != "" {
+		hc.port = cc.Port
+	}
+	return hc, nil
+}
+
+func (s *SshExecutor) ssherForHost(host string) (Ssher, error) {
+	hc, err := s.configForHost(host)
+	if err != nil {
+		return nil, err
+	}
+	return s.ssherForConfig(host, hc)
+}
+
+func (s *SshExecutor) ssherForConfig(host string, hc hostSshConfig) (Ssher, error) {
+	s.keyLock.Lock()
+	defer s.keyLock.Unlock()
+
+	if s.keySource != nil {
+		if !s.keysLoaded {
+			keys, err := s.keySource.SshKeys()
+			if err != nil {
+				return nil, err
+			}
+			s.keys = keys
+			s.keysLoaded = true
+		}
+		if key := MatchSshKey(s.keys, host); key != nil {
+			return s.keyedSsher(host, hc.user, key)
+		}
+	}
+
+	if hc.user == s.user && hc.keyfile == s.private_keyfile {
+		return s.exec, nil
+	}
+	// the port is not part of the ssh client
+	hc.port = ""
+	if exec, ok := s.clusterExecs[hc]; ok {
+		return exec, nil
+	}
+	s.Logger().Debug("Using ssh user %v and key file %v for host %v",
+		hc.user, hc.keyfile, host)
+	exec, err := sshNew(s.Logger(), hc.user, hc.keyfile)
+	if err != nil {
+		return nil, s.Logger().LogError(
+			"Unable to load ssh key file %v: %v", hc.keyfile, err)
+	}
+	s.useKnownHosts(exec)
+	s.clusterExecs[hc] = exec
+	return exec, nil
+}
+
+func (s *SshExecutor) keyedSsher(host, user string, key *SshKey) (Ssher, error) {
+	if ke, ok := s.keyExecs[key.Id]; ok && ke.key == *key && ke.user == user {
+		return ke.exec, nil
+	}
+
+	s.Logger().Debug("Using ssh key %v for host %v", key.Id, host)
+	exec, err := sshNewWithKey(s.Logger(), user,
+		[]byte(key.PrivateKey), key.Passphrase)
+	if err != nil {
+		return nil, s.Logger().LogError(
+			"Unable to load ssh key %v: %v", key.Id, err)
+	}
+	s.useKnownHosts(exec)
+	s.keyExecs[key.Id] = &keyedSsher{key: *key, user: user, exec: exec}
+	return exec, nil
+}
+
+// useKnownHosts makes the ssher verify the host keys of the nodes
+// if a known hosts file is configured.
+func (s *SshExecutor) useKnownHosts(exec Ssher) {
+	if s.knownHosts == nil {
+		return
+	}
+	if ks, ok := exec.(knownHostsSsher); ok {
+		ks.SetKnownHosts(s.knownHosts)
+	}
+}
+
+// UpdateKnownHost records the host key currently presented by the
+// host in the known hosts file, replacing any key previously recorded
+// for it. It returns the fingerprint of the key.
+func (s *SshExecutor) UpdateKnownHost(host string) (string, error) {
+	if s.knownHosts == nil {
+		return "", ErrKnownHostsDisabled
+	}
+	hc, err := s.configForHost(host)
+	if err != nil {
+		return "", err
+	}
+	addr, _ := s.addressForHost(host)
+	fingerprint, err := updateKnownHosts(s.knownHosts,
+		net.JoinHostPort(addr, hc.port), net.JoinHostPort(host, hc.port))
+	if err != nil {
+		return "", s.Logger().LogError(
+			"Unable to update the host key of %v: %v", host, err)
+	}
+	s.Logger().Info("Recorded host key %v of %v", fingerprint, host)
+	return fingerprint, nil
 }
 
 func (s *SshExecutor) RebalanceOnExpansion() bool {
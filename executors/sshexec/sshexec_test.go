@@ -10,12 +10,14 @@
 package sshexec
 
 import (
+	"errors"
 	"os"
 	"testing"
 
 	"github.com/heketi/heketi/executors/cmdexec"
 	"github.com/heketi/heketi/pkg/logging"
 	rex "github.com/heketi/heketi/pkg/remoteexec"
+	"github.com/heketi/heketi/pkg/remoteexec/ssh"
 	"github.com/heketi/tests"
 )
 
@@ -205,3 +207,336 @@ func TestSshExecutorEnvVariables(t *testing.T) {
 	tests.Assert(t, s.exec != nil)
 
 }
+
+type fakeKeySource []SshKey
+
+func (f fakeKeySource) SshKeys() ([]SshKey, error) {
+	return f, nil
+}
+
+func TestMatchSshKeyPriority(t *testing.T) {
+	keys := []SshKey{
+		{Id: "k1", NodePattern: "storage-1.*"},
+		{Id: "k2", NodePattern: "storage-*"},
+		{Id: "k3", NodePattern: "*"},
+	}
+
+	k := MatchSshKey(keys, "storage-1.example.com")
+	tests.Assert(t, k != nil && k.Id == "k1", "expected k1, got:", k)
+	k = MatchSshKey(keys, "storage-2.example.com")
+	tests.Assert(t, k != nil && k.Id == "k2", "expected k2, got:", k)
+	k = MatchSshKey(keys, "10.0.0.1")
+	tests.Assert(t, k != nil && k.Id == "k3", "expected k3, got:", k)
+
+	// earlier keys win even if a later key is more specific
+	k = MatchSshKey(keys[1:], "storage-1.example.com")
+	tests.Assert(t, k != nil && k.Id == "k2", "expected k2, got:", k)
+
+	k = MatchSshKey(keys[:2], "10.0.0.1")
+	tests.Assert(t, k == nil, "expected nil, got:", k)
+}
+
+func TestSshExecPerNodeKey(t *testing.T) {
+	global := NewFakeSsh()
+	defer tests.Patch(&sshNew,
+		func(logger *logging.Logger, user string, file string) (Ssher, error) {
+			return global, nil
+		}).Restore()
+
+	created := map[string]*FakeSsh{}
+	defer tests.Patch(&sshNewWithKey,
+		func(logger *logging.Logger,
+			user string, key []byte, passphrase string) (Ssher, error) {
+			f := NewFakeSsh()
+			created[string(key)] = f
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+	}
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	s.SetKeySource(fakeKeySource{
+		{Id: "k1", NodePattern: "node1*", PrivateKey: "key-one"},
+		{Id: "k2", NodePattern: "node2*", PrivateKey: "key-two"},
+	})
+
+	used := map[string][]string{}
+	track := func(name string, f *FakeSsh) {
+		f.FakeExecCommands = func(host string,
+			commands rex.Cmds,
+			timeoutMinutes int,
+			useSudo bool) (rex.Results, error) {
+			used[name] = append(used[name], host)
+			return rex.Results{}, nil
+		}
+	}
+	track("global", global)
+
+	_, err = s.ExecCommands("node3", rex.ToCmds([]string{"true"}), 1)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(used["global"]) == 1,
+		"expected global key to be used, got:", used)
+
+	// the first call for a key creates the ssh client
+	_, err = s.ssherForHost("node1.example.com")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, created["key-one"] != nil,
+		"expected key-one to be loaded, got:", created)
+	track("k1", created["key-one"])
+
+	_, err = s.ExecCommands("node1.example.com", rex.ToCmds([]string{"true"}), 1)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, err = s.ExecCommands("node2.example.com", rex.ToCmds([]string{"true"}), 1)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(used["k1"]) == 1,
+		"expected key k1 to be used once, got:", used)
+	tests.Assert(t, len(used["global"]) == 1,
+		"expected global key to be used once, got:", used)
+	tests.Assert(t, created["key-two"] != nil,
+		"expected key-two to be loaded, got:", created)
+	tests.Assert(t, len(created) == 2,
+		"expected len(created) == 2, got:", len(created))
+}
+
+type countingKeySource struct {
+	keys  []SshKey
+	loads int
+}
+
+func (c *countingKeySource) SshKeys() ([]SshKey, error) {
+	c.loads++
+	return c.keys, nil
+}
+
+func TestSshExecKeyCache(t *testing.T) {
+	defer tests.Patch(&sshNew,
+		func(logger *logging.Logger, user string, file string) (Ssher, error) {
+			return NewFakeSsh(), nil
+		}).Restore()
+	created := []string{}
+	defer tests.Patch(&sshNewWithKey,
+		func(logger *logging.Logger,
+			user string, key []byte, passphrase string) (Ssher, error) {
+			created = append(created, string(key))
+			return NewFakeSsh(), nil
+		}).Restore()
+
+	s, err := NewSshExecutor(&SshConfig{PrivateKeyFile: "xkeyfile"})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	src := &countingKeySource{keys: []SshKey{
+		{Id: "k1", NodePattern: "node1*", PrivateKey: "key-one"},
+	}}
+	s.SetKeySource(src)
+
+	// the keys are loaded and parsed once
+	for i := 0; i < 3; i++ {
+		_, err = s.ExecCommands("node1", rex.ToCmds([]string{"true"}), 1)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	}
+	tests.Assert(t, src.loads == 1, "expected 1 load, got:", src.loads)
+	tests.Assert(t, len(created) == 1, "expected 1 client, got:", created)
+
+	// a changed key is used once the cache is invalidated
+	src.keys = []SshKey{
+		{Id: "k1", NodePattern: "node1*", PrivateKey: "key-new"},
+	}
+	_, err = s.ExecCommands("node1", rex.ToCmds([]string{"true"}), 1)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(created) == 1, "expected 1 client, got:", created)
+	s.InvalidateSshKeys()
+	_, err = s.ExecCommands("node1", rex.ToCmds([]string{"true"}), 1)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, src.loads == 2, "expected 2 loads, got:", src.loads)
+	tests.Assert(t, len(created) == 2 && created[1] == "key-new",
+		"expected key-new to be loaded, got:", created)
+}
+
+type fakeClusterSource map[string]string
+
+func (f fakeClusterSource) HostCluster(host string) (string, error) {
+	return f[host], nil
+}
+
+func TestSshExecPerClusterConfig(t *testing.T) {
+	// record the hosts each user and key file connects to
+	used := map[string][]string{}
+	defer tests.Patch(&sshNew,
+		func(logger *logging.Logger, user string, file string) (Ssher, error) {
+			name := user + ":" + file
+			f := NewFakeSsh()
+			f.FakeExecCommands = func(host string,
+				commands rex.Cmds,
+				timeoutMinutes int,
+				useSudo bool) (rex.Results, error) {
+				used[name] = append(used[name], host)
+				return rex.Results{}, nil
+			}
+			return f, nil
+		}).Restore()
+
+	config := &SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		User:           "xuser",
+		Clusters: map[string]ClusterSshConfig{
+			"c1": {User: "user1"},
+			"c2": {User: "user2", PrivateKeyFile: "keyfile2", Port: "2222"},
+		},
+	}
+	s, err := NewSshExecutor(config)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	s.SetHostClusterSource(fakeClusterSource{
+		"node1": "c1",
+		"node2": "c2",
+		"node3": "c3",
+	})
+
+	for _, host := range []string{"node1", "node2", "node3", "node4", "node1"} {
+		_, err := s.ExecCommands(host, rex.ToCmds([]string{"true"}), 1)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	}
+	tests.Assert(t, len(used["user1:xkeyfile"]) == 2 &&
+		used["user1:xkeyfile"][0] == "node1:22",
+		"expected user1 for node1, got:", used)
+	tests.Assert(t, len(used["user2:keyfile2"]) == 1 &&
+		used["user2:keyfile2"][0] == "node2:2222",
+		"expected user2 for node2, got:", used)
+	tests.Assert(t, len(used["xuser:xkeyfile"]) == 2,
+		"expected global user for node3 and node4, got:", used)
+	tests.Assert(t, len(s.clusterExecs) == 2,
+		"expected 2 cluster clients, got:", len(s.clusterExecs))
+}
+
+type fakeIPCache map[string]string
+
+func (f fakeIPCache) HostIP(host string) (string, error) {
+	return f[host], nil
+}
+
+func (f fakeIPCache) SetHostIP(host, ip string) error {
+	f[host] = ip
+	return nil
+}
+
+type fakeAddressSsh struct {
+	FakeSsh
+	// the address connected to for each host checked
+	addrs map[string]string
+}
+
+func (f *fakeAddressSsh) ExecCommandsAt(addr, host string,
+	commands rex.Cmds,
+	timeoutMinutes int,
+	useSudo bool) (rex.Results, error) {
+	f.addrs[host] = addr
+	return rex.Results{}, nil
+}
+
+func TestSshExecCachedHostIP(t *testing.T) {
+	addrs := []string{}
+	f := &fakeAddressSsh{FakeSsh: *NewFakeSsh(), addrs: map[string]string{}}
+	f.FakeExecCommands = func(host string,
+		commands rex.Cmds,
+		timeoutMinutes int,
+		useSudo bool) (rex.Results, error) {
+		addrs = append(addrs, host)
+		return rex.Results{}, nil
+	}
+	defer tests.Patch(&sshNew,
+		func(logger *logging.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+
+	s, err := NewSshExecutor(&SshConfig{PrivateKeyFile: "xkeyfile"})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	cache := fakeIPCache{}
+	s.SetHostIPCache(cache)
+
+	// the hostname is connected to and its address is cached after
+	// a successful connection
+	restore := tests.Patch(&lookupHost, func(host string) ([]string, error) {
+		return []string{"10.0.0.5"}, nil
+	})
+	_, err = s.ExecCommands("node1", rex.OneCmd("true"), 1)
+	restore.Restore()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, cache["node1"] == "10.0.0.5",
+		"expected cached address, got:", cache)
+
+	// the cached address is used when dns fails, the host key is
+	// still checked against the hostname
+	defer tests.Patch(&lookupHost, func(host string) ([]string, error) {
+		return nil, errors.New("no such host")
+	}).Restore()
+	_, err = s.ExecCommands("node1", rex.OneCmd("true"), 1)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, f.addrs["node1:22"] == "10.0.0.5:22",
+		"expected cached address for node1, got:", f.addrs)
+
+	// hosts without a cached address are connected to by name
+	_, err = s.ExecCommands("node2", rex.OneCmd("true"), 1)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(cache) == 1, "expected one cached address, got:", cache)
+
+	tests.Assert(t, len(addrs) == 2, "expected 2 connections by name, got:", addrs)
+	tests.Assert(t, addrs[0] == "node1:22", "expected hostname, got:", addrs[0])
+	tests.Assert(t, addrs[1] == "node2:22", "expected hostname, got:", addrs[1])
+	tests.Assert(t, len(f.addrs) == 1,
+		"expected 1 connection by address, got:", f.addrs)
+}
+
+type fakeKnownHostsSsh struct {
+	FakeSsh
+	knownHosts *ssh.KnownHosts
+}
+
+func (f *fakeKnownHostsSsh) SetKnownHosts(k *ssh.KnownHosts) {
+	f.knownHosts = k
+}
+
+func TestSshExecKnownHosts(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	f := &fakeKnownHostsSsh{FakeSsh: *NewFakeSsh()}
+	defer tests.Patch(&sshNew,
+		func(logger *logging.Logger, user string, file string) (Ssher, error) {
+			return f, nil
+		}).Restore()
+	updated := []string{}
+	defer tests.Patch(&updateKnownHosts,
+		func(k *ssh.KnownHosts, addr string, hostnames ...string) (string, error) {
+			updated = append(updated, addr)
+			updated = append(updated, hostnames...)
+			return "SHA256:xyz", nil
+		}).Restore()
+
+	// host keys can not be updated unless a known hosts file is set
+	s, err := NewSshExecutor(&SshConfig{PrivateKeyFile: "xkeyfile"})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, f.knownHosts == nil, "expected no known hosts")
+	_, err = s.UpdateKnownHost("node1")
+	tests.Assert(t, err == ErrKnownHostsDisabled, "expected ErrKnownHostsDisabled, got:", err)
+
+	s, err = NewSshExecutor(&SshConfig{
+		PrivateKeyFile: "xkeyfile",
+		Port:           "2222",
+		KnownHostsFile: tmpfile,
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, f.knownHosts != nil, "expected known hosts")
+	_, err = os.Stat(tmpfile)
+	tests.Assert(t, err == nil, "expected known hosts file, got:", err)
+
+	s.SetHostIPCache(fakeIPCache{})
+	defer tests.Patch(&lookupHost, func(host string) ([]string, error) {
+		return []string{"10.0.0.5"}, nil
+	}).Restore()
+	fingerprint, err := s.UpdateKnownHost("node1")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, fingerprint == "SHA256:xyz", "unexpected fingerprint:", fingerprint)
+	tests.Assert(t, len(updated) == 2 &&
+		updated[0] == "node1:2222" && updated[1] == "node1:2222",
+		"unexpected addresses:", updated)
+}
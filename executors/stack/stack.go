@@ -46,6 +46,16 @@ func (es *ExecutorStack) GlusterdCheck(host string) error {
 	return err
 }
 
+func (es *ExecutorStack) NodePing(host string) error {
+	for _, e := range es.executors {
+		err := e.NodePing(host)
+		if err != NotSupportedError {
+			return err
+		}
+	}
+	return NotSupportedError
+}
+
 func (es *ExecutorStack) PeerProbe(exec_host, newnode string) error {
 	for _, e := range es.executors {
 		err := e.PeerProbe(exec_host, newnode)
@@ -66,6 +76,16 @@ func (es *ExecutorStack) PeerDetach(exec_host, detachnode string) error {
 	return NotSupportedError
 }
 
+func (es *ExecutorStack) PeerStatus(host string) ([]executors.Peer, error) {
+	for _, e := range es.executors {
+		peers, err := e.PeerStatus(host)
+		if err != NotSupportedError {
+			return peers, err
+		}
+	}
+	return nil, NotSupportedError
+}
+
 func (es *ExecutorStack) DeviceSetup(host, device, vgid string, destroy bool) (*executors.DeviceInfo, error) {
 	for _, e := range es.executors {
 		di, err := e.DeviceSetup(host, device, vgid, destroy)
@@ -106,6 +126,16 @@ func (es *ExecutorStack) DeviceForget(host string, dh *executors.DeviceVgHandle)
 	return NotSupportedError
 }
 
+func (es *ExecutorStack) DeviceReinitialize(host, device, vgid string) (*executors.DeviceInfo, error) {
+	for _, e := range es.executors {
+		di, err := e.DeviceReinitialize(host, device, vgid)
+		if err != NotSupportedError {
+			return di, err
+		}
+	}
+	return nil, NotSupportedError
+}
+
 func (es *ExecutorStack) BrickCreate(host string, brick *executors.BrickRequest) (*executors.BrickInfo, error) {
 	for _, e := range es.executors {
 		bi, err := e.BrickCreate(host, brick)
@@ -126,6 +156,16 @@ func (es *ExecutorStack) BrickDestroy(host string, brick *executors.BrickRequest
 	return false, NotSupportedError
 }
 
+func (es *ExecutorStack) BrickLayoutCheck(host string, path string) (*executors.BrickLvInfo, error) {
+	for _, e := range es.executors {
+		v, err := e.BrickLayoutCheck(host, path)
+		if err != NotSupportedError {
+			return v, err
+		}
+	}
+	return nil, NotSupportedError
+}
+
 func (es *ExecutorStack) VolumeCreate(host string, volume *executors.VolumeRequest) (*executors.Volume, error) {
 	for _, e := range es.executors {
 		v, err := e.VolumeCreate(host, volume)
@@ -196,6 +236,36 @@ func (es *ExecutorStack) VolumesInfo(host string) (*executors.VolInfo, error) {
 	return nil, NotSupportedError
 }
 
+func (es *ExecutorStack) VolumeStatus(host string, volume string) (*executors.VolumeStatus, error) {
+	for _, e := range es.executors {
+		vs, err := e.VolumeStatus(host, volume)
+		if err != NotSupportedError {
+			return vs, err
+		}
+	}
+	return nil, NotSupportedError
+}
+
+func (es *ExecutorStack) LvmSnapshotCreate(host string, snap *executors.LvmSnapshotRequest) (*executors.LvmSnapshotInfo, error) {
+	for _, e := range es.executors {
+		si, err := e.LvmSnapshotCreate(host, snap)
+		if err != NotSupportedError {
+			return si, err
+		}
+	}
+	return nil, NotSupportedError
+}
+
+func (es *ExecutorStack) LvmSnapshotDestroy(host string, snap *executors.LvmSnapshotRequest) error {
+	for _, e := range es.executors {
+		err := e.LvmSnapshotDestroy(host, snap)
+		if err != NotSupportedError {
+			return err
+		}
+	}
+	return NotSupportedError
+}
+
 func (es *ExecutorStack) HealInfo(host string, volume string) (*executors.HealInfo, error) {
 	for _, e := range es.executors {
 		hi, err := e.HealInfo(host, volume)
@@ -206,6 +276,106 @@ func (es *ExecutorStack) HealInfo(host string, volume string) (*executors.HealIn
 	return nil, NotSupportedError
 }
 
+func (es *ExecutorStack) HealSplitBrainInfo(host string, volume string) (*executors.HealInfo, error) {
+	for _, e := range es.executors {
+		hi, err := e.HealSplitBrainInfo(host, volume)
+		if err != NotSupportedError {
+			return hi, err
+		}
+	}
+	return nil, NotSupportedError
+}
+
+func (es *ExecutorStack) HealSplitBrainResolve(host string, volume string, file string) error {
+	for _, e := range es.executors {
+		err := e.HealSplitBrainResolve(host, volume, file)
+		if err != NotSupportedError {
+			return err
+		}
+	}
+	return NotSupportedError
+}
+
+func (es *ExecutorStack) VolumeRebalanceStatus(host string, volume string) (*executors.RebalanceStatus, error) {
+	for _, e := range es.executors {
+		rs, err := e.VolumeRebalanceStatus(host, volume)
+		if err != NotSupportedError {
+			return rs, err
+		}
+	}
+	return nil, NotSupportedError
+}
+
+func (es *ExecutorStack) VolumeRebalanceStart(host string, volume string) error {
+	for _, e := range es.executors {
+		err := e.VolumeRebalanceStart(host, volume)
+		if err != NotSupportedError {
+			return err
+		}
+	}
+	return NotSupportedError
+}
+
+func (es *ExecutorStack) VolumeBitrotStatus(host string, volume string) (*executors.BitrotStatus, error) {
+	for _, e := range es.executors {
+		bs, err := e.VolumeBitrotStatus(host, volume)
+		if err != NotSupportedError {
+			return bs, err
+		}
+	}
+	return nil, NotSupportedError
+}
+
+func (es *ExecutorStack) VolumeGeoRepStatus(host string, volume string, slave string) (*executors.GeoRepStatus, error) {
+	for _, e := range es.executors {
+		gs, err := e.VolumeGeoRepStatus(host, volume, slave)
+		if err != NotSupportedError {
+			return gs, err
+		}
+	}
+	return nil, NotSupportedError
+}
+
+func (es *ExecutorStack) VolumeProfileStart(host string, volume string) error {
+	for _, e := range es.executors {
+		err := e.VolumeProfileStart(host, volume)
+		if err != NotSupportedError {
+			return err
+		}
+	}
+	return NotSupportedError
+}
+
+func (es *ExecutorStack) VolumeProfileStop(host string, volume string) error {
+	for _, e := range es.executors {
+		err := e.VolumeProfileStop(host, volume)
+		if err != NotSupportedError {
+			return err
+		}
+	}
+	return NotSupportedError
+}
+
+func (es *ExecutorStack) VolumeProfileInfo(host string, volume string) (*executors.VolumeProfile, error) {
+	for _, e := range es.executors {
+		vp, err := e.VolumeProfileInfo(host, volume)
+		if err != NotSupportedError {
+			return vp, err
+		}
+	}
+	return nil, NotSupportedError
+}
+
+func (es *ExecutorStack) VolumeStatedump(host string, volume string) error {
+	for _, e := range es.executors {
+		err := e.VolumeStatedump(host, volume)
+		if err != NotSupportedError {
+			return err
+		}
+	}
+	return NotSupportedError
+}
+
 func (es *ExecutorStack) SetLogLevel(level string) {
 	for _, e := range es.executors {
 		e.SetLogLevel(level)
@@ -252,6 +422,16 @@ func (es *ExecutorStack) BlockVolumeExpand(host string, blockHostingVolumeName s
 	return NotSupportedError
 }
 
+func (es *ExecutorStack) BlockVolumeReplace(host string, req *executors.BlockGatewayReplaceRequest) error {
+	for _, e := range es.executors {
+		err := e.BlockVolumeReplace(host, req)
+		if err != NotSupportedError {
+			return err
+		}
+	}
+	return NotSupportedError
+}
+
 func (es *ExecutorStack) VolumeClone(
 	host string, vsr *executors.VolumeCloneRequest) (*executors.Volume, error) {
 
@@ -312,6 +492,30 @@ func (es *ExecutorStack) SnapshotDestroy(
 	return NotSupportedError
 }
 
+func (es *ExecutorStack) SnapshotConfigSet(
+	host string, option string, value string) error {
+
+	for _, e := range es.executors {
+		err := e.SnapshotConfigSet(host, option, value)
+		if err != NotSupportedError {
+			return err
+		}
+	}
+	return NotSupportedError
+}
+
+func (es *ExecutorStack) SnapshotScheduleSet(
+	host string, volume string, schedule string) error {
+
+	for _, e := range es.executors {
+		err := e.SnapshotScheduleSet(host, volume, schedule)
+		if err != NotSupportedError {
+			return err
+		}
+	}
+	return NotSupportedError
+}
+
 func (es *ExecutorStack) PVS(host string) (*executors.PVSCommandOutput, error) {
 	for _, e := range es.executors {
 		v, err := e.PVS(host)
@@ -28,9 +28,12 @@ import (
 
 	"github.com/heketi/heketi/apps/glusterfs"
 	"github.com/heketi/heketi/middleware"
+	"github.com/heketi/heketi/pkg/glusterfs/api"
 	"github.com/heketi/heketi/pkg/metrics"
+	rexssh "github.com/heketi/heketi/pkg/remoteexec/ssh"
 	"github.com/heketi/heketi/server/admin"
 	"github.com/heketi/heketi/server/config"
+	"github.com/heketi/heketi/server/fips"
 	"github.com/heketi/heketi/server/profiling"
 )
 
@@ -512,11 +515,41 @@ func main() {
 	// Substitute values using any set environment variables
 	setWithEnvVariables(options)
 
+	// Refuse to start in FIPS mode without a validated crypto module
+	// or if the approved algorithms fail
+	if options.FipsMode {
+		if err := fips.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+		if !disableAuth {
+			for name, key := range map[string]string{
+				"admin": options.JwtConfig.Admin.PrivateKey,
+				"user":  options.JwtConfig.User.PrivateKey,
+			} {
+				if err := fips.ValidateKey(name, key); err != nil {
+					fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+					os.Exit(1)
+				}
+			}
+		}
+		rexssh.SetFipsMode(true)
+		fmt.Println("FIPS mode enabled")
+	}
+
 	// Use negroni to add middleware.  Here we add two
 	// middlewares: Recovery and Logger, which come with
 	// Negroni
 	n := negroni.New(negroni.NewRecovery(), negroni.NewLogger())
 
+	// Wrap JSON responses in a {data, meta} envelope
+	if options.ResponseEnvelope {
+		n.Use(middleware.NewEnvelope())
+	}
+
+	// Reject oversized request bodies before they are decoded
+	n.Use(middleware.NewBodyLimit(options.MaxRequestBodyBytes))
+
 	// Setup a new GlusterFS application
 	app := setupApp(options)
 
@@ -569,6 +602,9 @@ func main() {
 		fmt.Fprintln(os.Stderr, "ERROR: unable to set admin state:", err)
 		os.Exit(1)
 	}
+	if options.ReadOnlyMode {
+		adminss.Set(api.AdminStateReadOnly)
+	}
 
 	if options.BackupDbToKubeSecret {
 		// Check if running in a Kubernetes environment
@@ -599,7 +635,12 @@ func main() {
 		// Start the server.
 		if options.EnableTls {
 			fmt.Printf("Listening on port %v with TLS enabled\n", options.Port)
-			err = http.ListenAndServeTLS(":"+options.Port, options.CertFile, options.KeyFile, router)
+			server := &http.Server{
+				Addr:      ":" + options.Port,
+				Handler:   router,
+				TLSConfig: fips.TLSConfig(options.FipsMode),
+			}
+			err = server.ListenAndServeTLS(options.CertFile, options.KeyFile)
 		} else {
 			fmt.Printf("Listening on port %v\n", options.Port)
 			err = http.ListenAndServe(":"+options.Port, router)
@@ -0,0 +1,69 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+const (
+	// default limit on the size of request bodies (1 MiB)
+	DefaultMaxRequestBodyBytes int64 = 1 << 20
+)
+
+// BodyLimit rejects requests with bodies larger than MaxBytes with
+// a 413 status before any handler attempts to decode them.
+type BodyLimit struct {
+	MaxBytes int64
+}
+
+// NewBodyLimit returns a body size limiting middleware. If max is
+// not positive the default limit is used.
+func NewBodyLimit(max int64) *BodyLimit {
+	if max <= 0 {
+		max = DefaultMaxRequestBodyBytes
+	}
+	return &BodyLimit{MaxBytes: max}
+}
+
+func (b *BodyLimit) tooLarge(w http.ResponseWriter) {
+	utils.HttpError(w,
+		fmt.Sprintf("Request body exceeds limit of %v bytes", b.MaxBytes),
+		http.StatusRequestEntityTooLarge)
+}
+
+func (b *BodyLimit) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if r.Body == nil || r.Body == http.NoBody {
+		next(w, r)
+		return
+	}
+
+	// reject bodies of known size right away
+	if r.ContentLength > b.MaxBytes {
+		b.tooLarge(w)
+		return
+	}
+
+	// the length of chunked bodies is only known after reading them
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, b.MaxBytes))
+	r.Body.Close()
+	if err != nil {
+		b.tooLarge(w)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	next(w, r)
+}
@@ -0,0 +1,97 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/heketi/tests"
+	"github.com/urfave/negroni"
+)
+
+func TestNewBodyLimit(t *testing.T) {
+	b := NewBodyLimit(0)
+	tests.Assert(t, b.MaxBytes == DefaultMaxRequestBodyBytes,
+		"expected default limit, got:", b.MaxBytes)
+	b = NewBodyLimit(100)
+	tests.Assert(t, b.MaxBytes == 100, "expected 100, got:", b.MaxBytes)
+}
+
+func TestBodyLimit(t *testing.T) {
+	const max = 1024
+
+	// record what the handler was able to read
+	var called bool
+	var received int
+	n := negroni.New(NewBodyLimit(max))
+	n.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		data, err := ioutil.ReadAll(r.Body)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		received = len(data)
+		w.WriteHeader(http.StatusOK)
+	})
+	ts := httptest.NewServer(n)
+	defer ts.Close()
+
+	post := func(body io.Reader) int {
+		called, received = false, 0
+		r, err := http.Post(ts.URL, "application/json", body)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+		return r.StatusCode
+	}
+
+	// exactly the limit is accepted
+	status := post(bytes.NewReader(make([]byte, max)))
+	tests.Assert(t, status == http.StatusOK,
+		"expected http.StatusOK, got:", status)
+	tests.Assert(t, called, "expected handler to be called")
+	tests.Assert(t, received == max,
+		"expected received == max, got:", received)
+
+	// one byte more is rejected without calling the handler
+	status = post(bytes.NewReader(make([]byte, max+1)))
+	tests.Assert(t, status == http.StatusRequestEntityTooLarge,
+		"expected http.StatusRequestEntityTooLarge, got:", status)
+	tests.Assert(t, !called, "expected handler not to be called")
+
+	// bodies of unknown length are checked as they are read
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write(make([]byte, max+1))
+		pw.Close()
+	}()
+	status = post(pr)
+	tests.Assert(t, status == http.StatusRequestEntityTooLarge,
+		"expected http.StatusRequestEntityTooLarge, got:", status)
+	tests.Assert(t, !called, "expected handler not to be called")
+
+	pr, pw = io.Pipe()
+	go func() {
+		pw.Write(make([]byte, max))
+		pw.Close()
+	}()
+	status = post(pr)
+	tests.Assert(t, status == http.StatusOK,
+		"expected http.StatusOK, got:", status)
+	tests.Assert(t, received == max,
+		"expected received == max, got:", received)
+
+	// requests without a body are passed through
+	r, err := http.Get(ts.URL)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusOK,
+		"expected http.StatusOK, got:", r.StatusCode)
+}
@@ -0,0 +1,140 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/heketi/heketi/pkg/idgen"
+)
+
+const (
+	EnvelopeApiVersion = "v1"
+	RequestIdHeader    = "X-Request-Id"
+)
+
+// EnvelopeMeta describes the request a response belongs to.
+type EnvelopeMeta struct {
+	RequestId  string `json:"request_id"`
+	ApiVersion string `json:"api_version"`
+	Timestamp  string `json:"timestamp"`
+}
+
+// Envelope wraps the JSON responses of the API in an object that also
+// carries metadata about the request. Successful responses become
+// {"data": <response>, "meta": <meta>} and error responses become
+// {"error": <response>, "meta": <meta>}. Responses that are not JSON,
+// such as redirects, db backups and event streams, are passed on
+// unchanged and without being buffered.
+//
+// The request id is taken from the X-Request-Id header of the request
+// if given and is returned in the X-Request-Id header of the response.
+type Envelope struct{}
+
+// NewEnvelope returns a response envelope middleware.
+func NewEnvelope() *Envelope {
+	return &Envelope{}
+}
+
+func isJsonContent(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/json")
+}
+
+// envelopeWriter holds back JSON responses so that they can be
+// wrapped once the handler is done.
+type envelopeWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	// nil if the response is passed through
+	buffer *bytes.Buffer
+}
+
+func (ew *envelopeWriter) WriteHeader(status int) {
+	if ew.wroteHeader {
+		return
+	}
+	ew.wroteHeader = true
+	ew.status = status
+	if isJsonContent(ew.Header().Get("Content-Type")) {
+		ew.buffer = &bytes.Buffer{}
+		return
+	}
+	ew.ResponseWriter.WriteHeader(status)
+}
+
+func (ew *envelopeWriter) Write(b []byte) (int, error) {
+	if !ew.wroteHeader {
+		ew.WriteHeader(http.StatusOK)
+	}
+	if ew.buffer != nil {
+		return ew.buffer.Write(b)
+	}
+	return ew.ResponseWriter.Write(b)
+}
+
+// Flush sends the data written so far to the client if the response is
+// passed through. Buffered JSON responses are only sent once wrapped.
+func (ew *envelopeWriter) Flush() {
+	if !ew.wroteHeader {
+		ew.WriteHeader(http.StatusOK)
+	}
+	if ew.buffer != nil {
+		return
+	}
+	if f, ok := ew.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (e *Envelope) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	requestId := r.Header.Get(RequestIdHeader)
+	if requestId == "" {
+		requestId = idgen.GenUUID()
+	}
+	w.Header().Set(RequestIdHeader, requestId)
+
+	ew := &envelopeWriter{ResponseWriter: w}
+	next(ew, r)
+	if ew.buffer == nil {
+		return
+	}
+
+	body := bytes.TrimSpace(ew.buffer.Bytes())
+	if len(body) == 0 || !json.Valid(body) {
+		// nothing that can be wrapped
+		w.WriteHeader(ew.status)
+		w.Write(ew.buffer.Bytes())
+		return
+	}
+
+	key := "data"
+	if ew.status >= http.StatusBadRequest {
+		key = "error"
+	}
+	wrapped, err := json.Marshal(map[string]interface{}{
+		key: json.RawMessage(body),
+		"meta": EnvelopeMeta{
+			RequestId:  requestId,
+			ApiVersion: EnvelopeApiVersion,
+			Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Del("Content-Length")
+	w.WriteHeader(ew.status)
+	w.Write(append(wrapped, '\n'))
+}
@@ -0,0 +1,96 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package middleware
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/heketi/tests"
+	"github.com/urfave/negroni"
+
+	"github.com/heketi/heketi/pkg/utils"
+)
+
+func TestEnvelope(t *testing.T) {
+	n := negroni.New(NewEnvelope())
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"abc"}`))
+	})
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		utils.HttpError(w, "no such thing", http.StatusNotFound)
+	})
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Hello from Heketi"))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/text", http.StatusSeeOther)
+	})
+	n.UseHandler(mux)
+	ts := httptest.NewServer(n)
+	defer ts.Close()
+
+	r, err := http.Get(ts.URL + "/ok")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusOK,
+		"expected http.StatusOK, got:", r.StatusCode)
+	requestId := r.Header.Get(RequestIdHeader)
+	tests.Assert(t, requestId != "", "expected request id header")
+	var ok struct {
+		Data struct {
+			Id string `json:"id"`
+		} `json:"data"`
+		Meta EnvelopeMeta `json:"meta"`
+	}
+	err = utils.GetJsonFromResponse(r, &ok)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, ok.Data.Id == "abc", "expected abc, got:", ok.Data.Id)
+	tests.Assert(t, ok.Meta.RequestId == requestId,
+		"expected", requestId, "got:", ok.Meta.RequestId)
+	tests.Assert(t, ok.Meta.ApiVersion == EnvelopeApiVersion,
+		"expected", EnvelopeApiVersion, "got:", ok.Meta.ApiVersion)
+	tests.Assert(t, ok.Meta.Timestamp != "", "expected timestamp")
+
+	r, err = http.Get(ts.URL + "/fail")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusNotFound,
+		"expected http.StatusNotFound, got:", r.StatusCode)
+	var fail map[string]json.RawMessage
+	err = utils.GetJsonFromResponse(r, &fail)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, found := fail["data"]
+	tests.Assert(t, !found, "expected no data, got:", fail)
+	var e struct {
+		Message string `json:"message"`
+	}
+	err = json.Unmarshal(fail["error"], &e)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, e.Message == "no such thing",
+		"expected error message, got:", e.Message)
+
+	// other content types are not touched
+	r, err = http.Get(ts.URL + "/redirect")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, r.StatusCode == http.StatusOK,
+		"expected http.StatusOK, got:", r.StatusCode)
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, string(body) == "Hello from Heketi",
+		"expected text body, got:", string(body))
+}
@@ -24,6 +24,7 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/context"
 	"github.com/heketi/heketi/pkg/logging"
+	"github.com/heketi/heketi/pkg/utils"
 )
 
 var (
@@ -127,13 +128,13 @@ func (j *JwtAuth) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Ha
 	// Access token from header
 	rawtoken, err := jwtmiddleware.FromAuthHeader(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Determine if we have the token
 	if rawtoken == "" {
-		http.Error(w, "Required authorization token not found", http.StatusUnauthorized)
+		utils.HttpError(w, "Required authorization token not found", http.StatusUnauthorized)
 		return
 	}
 
@@ -171,18 +172,18 @@ func (j *JwtAuth) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.Ha
 		if strings.Contains(err.Error(), "used before issued") {
 			errmsg += " (client and server clocks may differ)"
 		}
-		http.Error(w, errmsg, http.StatusUnauthorized)
+		utils.HttpError(w, errmsg, http.StatusUnauthorized)
 		return
 	}
 
 	if !token.Valid {
-		http.Error(w, "Invalid JWT token", http.StatusUnauthorized)
+		utils.HttpError(w, "Invalid JWT token", http.StatusUnauthorized)
 		return
 	}
 
 	// Check qsh claim
 	if claims.Qsh != generate_qsh(r) {
-		http.Error(w, "Invalid qsh claim in token", http.StatusUnauthorized)
+		utils.HttpError(w, "Invalid qsh claim in token", http.StatusUnauthorized)
 		return
 	}
 
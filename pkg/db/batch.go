@@ -0,0 +1,61 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// BatchDB is implemented by db connections that can coalesce the
+// write transactions of concurrent goroutines into one transaction.
+type BatchDB interface {
+	DB
+	Batch(func(*bolt.Tx) error) error
+}
+
+// Batch wraps a read-write transaction that may be committed together
+// with the transactions of other goroutines, see BatchWrite.
+// If Batch is called on a read-only DBWrap it panics.
+func (w *DBWrap) Batch(cb func(*bolt.Tx) error) error {
+	if w.readOnly {
+		panic(errors.New("Can not update a read-only DBWrap"))
+	}
+	return w.db.Batch(func(tx *bolt.Tx) error {
+		return cb(tx)
+	})
+}
+
+// BatchWrite runs fn in a read-write transaction. If db supports
+// batching the functions passed by concurrent goroutines within the
+// batch window of the db are run in a single transaction, sparing the
+// fsync of a separate commit for each. BatchWrite blocks until the
+// transaction running fn is committed.
+//
+// If another function of a batch fails, fn may be run again in a new
+// transaction. Thus fn must be idempotent and must not change any
+// state outside of the transaction.
+func BatchWrite(db DB, fn func(*bolt.Tx) error) error {
+	if b, ok := db.(BatchDB); ok {
+		return b.Batch(fn)
+	}
+	return db.Update(fn)
+}
+
+// SetBatchWindow sets how long a batch of writes of the db waits for
+// more writes before it is committed. A window of zero keeps the
+// default of bolt.
+func SetBatchWindow(db *bolt.DB, window time.Duration) {
+	if window > 0 {
+		db.MaxBatchDelay = window
+	}
+}
@@ -0,0 +1,136 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package db
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/heketi/tests"
+)
+
+// openBatchTestDB returns a new db with a bucket named "entries".
+func openBatchTestDB(t *testing.T, path string) *bolt.DB {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 3 * time.Second})
+	tests.Assert(t, err == nil, "expected (bolt.Open) err == nil, got:", err)
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("entries"))
+		return err
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	return db
+}
+
+func putEntry(i int) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		key := fmt.Sprintf("entry%03d", i)
+		return tx.Bucket([]byte("entries")).Put([]byte(key), []byte(key))
+	}
+}
+
+func TestBatchWrite(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+	tmpfile2 := tests.Tempfile()
+	defer os.Remove(tmpfile2)
+
+	// the same writes, each in its own transaction
+	single := openBatchTestDB(t, tmpfile)
+	defer single.Close()
+	w := NewDBWrap(single)
+	before := single.Stats()
+	for i := 0; i < 100; i++ {
+		err := w.Update(putEntry(i))
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	}
+	after := single.Stats()
+	singlePages := after.Sub(&before).TxStats.PageCount
+
+	batched := openBatchTestDB(t, tmpfile2)
+	defer batched.Close()
+	SetBatchWindow(batched, 50*time.Millisecond)
+	w = NewDBWrap(batched)
+	before = batched.Stats()
+	var wg sync.WaitGroup
+	errs := make([]error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = BatchWrite(w, putEntry(i))
+		}(i)
+	}
+	wg.Wait()
+	after = batched.Stats()
+	batchedPages := after.Sub(&before).TxStats.PageCount
+
+	for i, err := range errs {
+		tests.Assert(t, err == nil, "expected err == nil for", i, "got:", err)
+	}
+	err := batched.View(func(tx *bolt.Tx) error {
+		n := tx.Bucket([]byte("entries")).Stats().KeyN
+		tests.Assert(t, n == 100, "expected 100 entries, got:", n)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, batchedPages < singlePages,
+		"expected fewer pages than", singlePages, "got:", batchedPages)
+}
+
+func TestBatchWriteFailure(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	db := openBatchTestDB(t, tmpfile)
+	defer db.Close()
+	w := NewDBWrap(db)
+
+	// a failing write does not fail the others of its batch
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fn := putEntry(i)
+			if i == 3 {
+				fn = func(tx *bolt.Tx) error {
+					return fmt.Errorf("write %v failed", i)
+				}
+			}
+			errs[i] = BatchWrite(w, fn)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if i == 3 {
+			tests.Assert(t, err != nil, "expected err != nil")
+		} else {
+			tests.Assert(t, err == nil, "expected err == nil for", i, "got:", err)
+		}
+	}
+
+	// writes within a transaction are not batched
+	err := w.Update(func(tx *bolt.Tx) error {
+		return BatchWrite(WrapTx(tx), putEntry(20))
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = db.View(func(tx *bolt.Tx) error {
+		n := tx.Bucket([]byte("entries")).Stats().KeyN
+		tests.Assert(t, n == 10, "expected 10 entries, got:", n)
+		return nil
+	})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
@@ -0,0 +1,117 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+// Package dlock provides locks shared by several heketi servers
+// through an external coordination service, etcd or redis.
+package dlock
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/heketi/heketi/pkg/idgen"
+	"github.com/heketi/heketi/pkg/logging"
+)
+
+const (
+	DEFAULT_TTL    = 30 * time.Second
+	DEFAULT_PREFIX = "/heketi/locks/"
+)
+
+var (
+	// how long a request to the coordination service may take
+	requestTimeout = 5 * time.Second
+
+	// ErrLocked is returned by TryLock if the key is held by
+	// another holder
+	ErrLocked = errors.New("Lock is held by another holder")
+
+	logger = logging.NewLogger("[dlock]", logging.LEVEL_INFO)
+)
+
+// DistributedLock takes locks that are visible to all servers using
+// the same coordination service. A lock is released when the holder
+// unlocks it or when it is not extended within its ttl, such that a
+// server that dies does not keep its locks forever.
+type DistributedLock interface {
+	// TryLock takes the lock of the key without waiting for it and
+	// returns the function releasing it. ErrLocked is returned if
+	// the lock is held by another holder.
+	TryLock(key string, ttl time.Duration) (unlock func(), err error)
+	// Extend renews all locks currently held for ttl. Every lock is
+	// tried, the error lists the locks that could not be renewed.
+	// Locks that expired before they could be renewed are dropped.
+	Extend(ttl time.Duration) error
+	// Close releases the connections to the coordination service.
+	Close() error
+}
+
+// Config selects the coordination service of the distributed lock.
+type Config struct {
+	// "etcd" or "redis", the lock is disabled if empty
+	Type string `json:"type"`
+	// urls of the etcd servers, such as "http://etcd:2379", or the
+	// address of the redis server, such as "redis:6379"
+	Endpoints []string `json:"endpoints"`
+	// password of the redis server
+	Password string `json:"password"`
+	// prepended to the keys of the locks
+	Prefix string `json:"prefix"`
+	// seconds a lock is kept without being extended
+	Ttl int `json:"ttl"`
+}
+
+// TtlDuration returns the ttl of the locks.
+func (c Config) TtlDuration() time.Duration {
+	if c.Ttl <= 0 {
+		return DEFAULT_TTL
+	}
+	return time.Duration(c.Ttl) * time.Second
+}
+
+// New returns the distributed lock configured by c.
+func New(c Config) (DistributedLock, error) {
+	prefix := c.Prefix
+	if prefix == "" {
+		prefix = DEFAULT_PREFIX
+	}
+	if len(c.Endpoints) == 0 {
+		return nil, fmt.Errorf("No endpoints given for the %v lock", c.Type)
+	}
+	switch c.Type {
+	case "etcd":
+		return NewEtcdLock(c.Endpoints, prefix)
+	case "redis":
+		if len(c.Endpoints) != 1 {
+			return nil, fmt.Errorf("The redis lock takes a single endpoint")
+		}
+		return NewRedisLock(c.Endpoints[0], c.Password, prefix), nil
+	default:
+		return nil, fmt.Errorf("Unknown distributed lock type: %v", c.Type)
+	}
+}
+
+// newHolder returns the value identifying the holder of the locks.
+func newHolder() string {
+	return idgen.GenUUID()
+}
+
+// extendError returns the error of an Extend that was unable to renew
+// the locks described by failed, nil if all locks were renewed.
+func extendError(failed []string) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	sort.Strings(failed)
+	return fmt.Errorf("Unable to extend %v locks: %v",
+		len(failed), strings.Join(failed, ", "))
+}
@@ -0,0 +1,166 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package dlock
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/heketi/tests"
+)
+
+// fakeClock is the time of the fake coordination services
+type fakeClock struct {
+	lock   sync.Mutex
+	offset time.Duration
+}
+
+func (c *fakeClock) now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return time.Now().Add(c.offset)
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.offset += d
+}
+
+// testMutualExclusion checks that a lock held by one holder can not
+// be taken by another one until it is released.
+func testMutualExclusion(t *testing.T, a, b DistributedLock) {
+	unlock, err := a.TryLock("volume/1", 10*time.Second)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, err = b.TryLock("volume/1", 10*time.Second)
+	tests.Assert(t, err == ErrLocked, "expected err == ErrLocked, got:", err)
+
+	// other keys are not affected
+	unlock2, err := b.TryLock("volume/2", 10*time.Second)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	unlock2()
+
+	unlock()
+	unlock, err = b.TryLock("volume/1", 10*time.Second)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, err = a.TryLock("volume/1", 10*time.Second)
+	tests.Assert(t, err == ErrLocked, "expected err == ErrLocked, got:", err)
+	unlock()
+}
+
+// testConcurrentLock checks that only one of several holders trying
+// to take the same lock at once gets it.
+func testConcurrentLock(t *testing.T, newLock func() DistributedLock) {
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	taken := 0
+	for i := 0; i < 10; i++ {
+		l := newLock()
+		defer l.Close()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := l.TryLock("cluster/1", 10*time.Second)
+			if err == nil {
+				lock.Lock()
+				taken++
+				lock.Unlock()
+			} else if err != ErrLocked {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	tests.Assert(t, taken == 1, "expected 1 holder, got:", taken)
+}
+
+// testExtend checks that a lock is kept while it is extended and is
+// released once it is not.
+func testExtend(t *testing.T, clock *fakeClock, a, b DistributedLock) {
+	_, err := a.TryLock("volume/1", 10*time.Second)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	clock.advance(5 * time.Second)
+	err = a.Extend(10 * time.Second)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	clock.advance(8 * time.Second)
+	_, err = b.TryLock("volume/1", 10*time.Second)
+	tests.Assert(t, err == ErrLocked, "expected err == ErrLocked, got:", err)
+
+	// not extended in time
+	clock.advance(3 * time.Second)
+	unlock, err := b.TryLock("volume/1", 10*time.Second)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = a.Extend(10 * time.Second)
+	tests.Assert(t, err != nil, "expected err != nil")
+	unlock()
+}
+
+// testExtendAll checks that Extend renews every held lock even if
+// some of them can not be renewed.
+func testExtendAll(t *testing.T, clock *fakeClock, a, b DistributedLock) {
+	_, err := a.TryLock("volume/1", 10*time.Second)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	keys := []string{"volume/2", "volume/3", "volume/4", "cluster/1"}
+	for _, key := range keys {
+		_, err := a.TryLock(key, 20*time.Second)
+		tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	}
+
+	// only volume/1 expired
+	clock.advance(15 * time.Second)
+	err = a.Extend(20 * time.Second)
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "volume/1: expired"),
+		"expected volume/1 to have expired, got:", err)
+	for _, key := range keys {
+		tests.Assert(t, !strings.Contains(err.Error(), key),
+			"expected", key, "to be renewed, got:", err)
+	}
+
+	// past the ttl the locks were taken with
+	clock.advance(15 * time.Second)
+	for _, key := range keys {
+		_, err := b.TryLock(key, 20*time.Second)
+		tests.Assert(t, err == ErrLocked, "expected err == ErrLocked, got:", err)
+	}
+
+	// the expired lock is no longer extended
+	err = a.Extend(20 * time.Second)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{Type: "zookeeper", Endpoints: []string{"zk:2181"}})
+	tests.Assert(t, err != nil, "expected err != nil")
+	_, err = New(Config{Type: "etcd"})
+	tests.Assert(t, err != nil, "expected err != nil")
+	_, err = New(Config{Type: "redis",
+		Endpoints: []string{"redis-1:6379", "redis-2:6379"}})
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	l, err := New(Config{Type: "etcd", Endpoints: []string{"http://etcd:2379"}})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	e, ok := l.(*EtcdLock)
+	tests.Assert(t, ok && e.prefix == DEFAULT_PREFIX, "unexpected lock:", l)
+	l.Close()
+
+	l, err = New(Config{Type: "redis", Endpoints: []string{"redis:6379"},
+		Prefix: "/locks/"})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	r, ok := l.(*RedisLock)
+	tests.Assert(t, ok && r.prefix == "/locks/", "unexpected lock:", l)
+	l.Close()
+
+	tests.Assert(t, Config{}.TtlDuration() == DEFAULT_TTL)
+	tests.Assert(t, Config{Ttl: 5}.TtlDuration() == 5*time.Second)
+}
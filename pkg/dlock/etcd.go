@@ -0,0 +1,138 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package dlock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"github.com/coreos/etcd/pkg/logutil"
+	"go.uber.org/zap"
+)
+
+// EtcdLock takes locks as etcd keys attached to a lease. A key is only
+// created if it does not exist and is deleted by etcd once its lease
+// expires.
+type EtcdLock struct {
+	client *clientv3.Client
+	prefix string
+	holder string
+
+	lock sync.Mutex
+	// leases of the held locks, by key
+	leases map[string]clientv3.LeaseID
+}
+
+// NewEtcdLock returns a lock using the etcd servers at the given
+// urls. The servers are not contacted until a lock is taken.
+func NewEtcdLock(endpoints []string, prefix string) (*EtcdLock, error) {
+	// failed requests are logged by the lock, not by each retry
+	logConfig := logutil.DefaultZapLoggerConfig
+	logConfig.Level = zap.NewAtomicLevelAt(zap.ErrorLevel)
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: requestTimeout,
+		LogConfig:   &logConfig,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to set up etcd client: %v", err)
+	}
+	return &EtcdLock{
+		client: client,
+		prefix: prefix,
+		holder: newHolder(),
+		leases: map[string]clientv3.LeaseID{},
+	}, nil
+}
+
+// Close closes the connections to the etcd servers.
+func (l *EtcdLock) Close() error {
+	return l.client.Close()
+}
+
+func (l *EtcdLock) revoke(lease clientv3.LeaseID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	_, err := l.client.Revoke(ctx, lease)
+	return err
+}
+
+func (l *EtcdLock) TryLock(key string, ttl time.Duration) (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	// etcd leases have a ttl in whole seconds
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	grant, err := l.client.Grant(ctx, seconds)
+	if err != nil {
+		return nil, err
+	}
+	lease := grant.ID
+
+	// create the key only if it does not exist yet
+	k := l.prefix + key
+	result, err := l.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(k), "=", 0)).
+		Then(clientv3.OpPut(k, l.holder, clientv3.WithLease(lease))).
+		Commit()
+	if err == nil && !result.Succeeded {
+		err = ErrLocked
+	}
+	if err != nil {
+		if rerr := l.revoke(lease); rerr != nil {
+			logger.Warning("Unable to revoke lease %x: %v", lease, rerr)
+		}
+		return nil, err
+	}
+
+	l.lock.Lock()
+	l.leases[key] = lease
+	l.lock.Unlock()
+	return func() {
+		l.lock.Lock()
+		delete(l.leases, key)
+		l.lock.Unlock()
+		// revoking the lease deletes the key
+		if err := l.revoke(lease); err != nil {
+			logger.Warning("Unable to unlock %v: %v", key, err)
+		}
+	}, nil
+}
+
+// Extend renews the leases of all held locks. etcd renews a lease for
+// the ttl it was granted with, the given ttl is not used.
+func (l *EtcdLock) Extend(ttl time.Duration) error {
+	l.lock.Lock()
+	leases := map[string]clientv3.LeaseID{}
+	for key, lease := range l.leases {
+		leases[key] = lease
+	}
+	l.lock.Unlock()
+
+	failed := []string{}
+	for key, lease := range leases {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		_, err := l.client.KeepAliveOnce(ctx, lease)
+		cancel()
+		if err == rpctypes.ErrLeaseNotFound {
+			l.lock.Lock()
+			delete(l.leases, key)
+			l.lock.Unlock()
+			failed = append(failed, key+": expired")
+		} else if err != nil {
+			failed = append(failed, fmt.Sprintf("%v: %v", key, err))
+		}
+	}
+	return extendError(failed)
+}
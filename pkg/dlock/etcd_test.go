@@ -0,0 +1,246 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package dlock
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	pb "github.com/coreos/etcd/etcdserver/etcdserverpb"
+	"github.com/heketi/tests"
+	"google.golang.org/grpc"
+)
+
+type fakeLease struct {
+	ttl     int64
+	expires time.Time
+}
+
+// fakeEtcd serves the kv and lease requests of the etcd v3 api used by
+// EtcdLock.
+type fakeEtcd struct {
+	pb.UnimplementedKVServer
+	pb.UnimplementedLeaseServer
+
+	clock    fakeClock
+	listener net.Listener
+	server   *grpc.Server
+
+	lock   sync.Mutex
+	nextId int64
+	leases map[int64]*fakeLease
+	// lease of each key
+	keys map[string]int64
+}
+
+func newFakeEtcd(t *testing.T) *fakeEtcd {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	f := &fakeEtcd{
+		listener: l,
+		server:   grpc.NewServer(),
+		leases:   map[int64]*fakeLease{},
+		keys:     map[string]int64{},
+	}
+	pb.RegisterKVServer(f.server, f)
+	pb.RegisterLeaseServer(f.server, f)
+	go f.server.Serve(l)
+	return f
+}
+
+func (f *fakeEtcd) Endpoint() string {
+	return "http://" + f.listener.Addr().String()
+}
+
+func (f *fakeEtcd) Close() {
+	f.server.Stop()
+}
+
+func (f *fakeEtcd) newLock(t *testing.T, endpoints ...string) *EtcdLock {
+	if len(endpoints) == 0 {
+		endpoints = []string{f.Endpoint()}
+	}
+	l, err := NewEtcdLock(endpoints, DEFAULT_PREFIX)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	return l
+}
+
+// expire drops the leases that were not renewed in time along with
+// their keys. Must be called with the lock held.
+func (f *fakeEtcd) expire() {
+	now := f.clock.now()
+	for id, l := range f.leases {
+		if now.After(l.expires) {
+			f.revoke(id)
+		}
+	}
+}
+
+func (f *fakeEtcd) revoke(id int64) {
+	delete(f.leases, id)
+	for k, lease := range f.keys {
+		if lease == id {
+			delete(f.keys, k)
+		}
+	}
+}
+
+func (f *fakeEtcd) Txn(ctx context.Context, r *pb.TxnRequest) (*pb.TxnResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.expire()
+
+	// the only transaction used creates a key that does not exist
+	if _, found := f.keys[string(r.Compare[0].Key)]; found {
+		return &pb.TxnResponse{Header: &pb.ResponseHeader{}}, nil
+	}
+	put := r.Success[0].GetRequestPut()
+	if _, found := f.leases[put.Lease]; !found {
+		return nil, rpctypes.ErrGRPCLeaseNotFound
+	}
+	f.keys[string(put.Key)] = put.Lease
+	return &pb.TxnResponse{
+		Header:    &pb.ResponseHeader{},
+		Succeeded: true,
+		Responses: []*pb.ResponseOp{{
+			Response: &pb.ResponseOp_ResponsePut{
+				ResponsePut: &pb.PutResponse{Header: &pb.ResponseHeader{}},
+			},
+		}},
+	}, nil
+}
+
+func (f *fakeEtcd) LeaseGrant(ctx context.Context, r *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.nextId++
+	f.leases[f.nextId] = &fakeLease{
+		ttl:     r.TTL,
+		expires: f.clock.now().Add(time.Duration(r.TTL) * time.Second),
+	}
+	return &pb.LeaseGrantResponse{
+		Header: &pb.ResponseHeader{},
+		ID:     f.nextId,
+		TTL:    r.TTL,
+	}, nil
+}
+
+func (f *fakeEtcd) LeaseRevoke(ctx context.Context, r *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.expire()
+
+	if _, found := f.leases[r.ID]; !found {
+		return nil, rpctypes.ErrGRPCLeaseNotFound
+	}
+	f.revoke(r.ID)
+	return &pb.LeaseRevokeResponse{Header: &pb.ResponseHeader{}}, nil
+}
+
+func (f *fakeEtcd) LeaseKeepAlive(s pb.Lease_LeaseKeepAliveServer) error {
+	for {
+		r, err := s.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		f.lock.Lock()
+		f.expire()
+		resp := &pb.LeaseKeepAliveResponse{
+			Header: &pb.ResponseHeader{},
+			ID:     r.ID,
+		}
+		// a lease that is gone is reported with a ttl of 0
+		if l, found := f.leases[r.ID]; found {
+			l.expires = f.clock.now().Add(time.Duration(l.ttl) * time.Second)
+			resp.TTL = l.ttl
+		}
+		f.lock.Unlock()
+
+		if err := s.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func TestEtcdLockMutualExclusion(t *testing.T) {
+	f := newFakeEtcd(t)
+	defer f.Close()
+
+	a := f.newLock(t)
+	defer a.Close()
+	b := f.newLock(t)
+	defer b.Close()
+	testMutualExclusion(t, a, b)
+
+	// the leases of released locks are revoked
+	tests.Assert(t, len(f.leases) == 0, "unexpected leases:", f.leases)
+	tests.Assert(t, len(f.keys) == 0, "unexpected keys:", f.keys)
+}
+
+func TestEtcdLockConcurrent(t *testing.T) {
+	f := newFakeEtcd(t)
+	defer f.Close()
+
+	testConcurrentLock(t, func() DistributedLock {
+		return f.newLock(t)
+	})
+}
+
+func TestEtcdLockExtend(t *testing.T) {
+	f := newFakeEtcd(t)
+	defer f.Close()
+
+	a := f.newLock(t)
+	defer a.Close()
+	b := f.newLock(t)
+	defer b.Close()
+	testExtend(t, &f.clock, a, b)
+}
+
+func TestEtcdLockExtendAll(t *testing.T) {
+	f := newFakeEtcd(t)
+	defer f.Close()
+
+	a := f.newLock(t)
+	defer a.Close()
+	b := f.newLock(t)
+	defer b.Close()
+	testExtendAll(t, &f.clock, a, b)
+}
+
+func TestEtcdLockEndpoints(t *testing.T) {
+	f := newFakeEtcd(t)
+	defer f.Close()
+
+	defer func(d time.Duration) { requestTimeout = d }(requestTimeout)
+	requestTimeout = 500 * time.Millisecond
+
+	// unreachable servers are skipped
+	l := f.newLock(t, "http://127.0.0.1:1", f.Endpoint())
+	defer l.Close()
+	unlock, err := l.TryLock("volume/1", time.Second)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	unlock()
+
+	l = f.newLock(t, "http://127.0.0.1:1")
+	defer l.Close()
+	_, err = l.TryLock("volume/1", time.Second)
+	tests.Assert(t, err != nil && err != ErrLocked,
+		"expected connection error, got:", err)
+}
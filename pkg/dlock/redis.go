@@ -0,0 +1,124 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package dlock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// delete or expire the key only if this holder still has it
+	redisUnlockSource = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+	redisExtendSource = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+)
+
+var (
+	redisUnlockScript = redis.NewScript(1, redisUnlockSource)
+	redisExtendScript = redis.NewScript(1, redisExtendSource)
+)
+
+// RedisLock takes locks as redis keys set with SET NX PX. The value of
+// the key identifies the holder such that a lock that expired and was
+// taken by another holder is not released or extended.
+type RedisLock struct {
+	pool   *redis.Pool
+	prefix string
+	holder string
+
+	lock sync.Mutex
+	// keys of the held locks
+	keys map[string]bool
+}
+
+// NewRedisLock returns a lock using the redis server at the given
+// address. The server is not contacted until a lock is taken.
+func NewRedisLock(address, password, prefix string) *RedisLock {
+	return &RedisLock{
+		pool: &redis.Pool{
+			MaxIdle:     2,
+			IdleTimeout: time.Minute,
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial("tcp", address,
+					redis.DialPassword(password),
+					redis.DialConnectTimeout(requestTimeout),
+					redis.DialReadTimeout(requestTimeout),
+					redis.DialWriteTimeout(requestTimeout))
+			},
+		},
+		prefix: prefix,
+		holder: newHolder(),
+		keys:   map[string]bool{},
+	}
+}
+
+// Close closes the idle connections to the redis server.
+func (l *RedisLock) Close() error {
+	return l.pool.Close()
+}
+
+func (l *RedisLock) TryLock(key string, ttl time.Duration) (func(), error) {
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	k := l.prefix + key
+	_, err := redis.String(conn.Do("SET", k, l.holder, "NX", "PX",
+		int64(ttl/time.Millisecond)))
+	if err == redis.ErrNil {
+		return nil, ErrLocked
+	} else if err != nil {
+		return nil, err
+	}
+
+	l.lock.Lock()
+	l.keys[key] = true
+	l.lock.Unlock()
+	return func() {
+		l.lock.Lock()
+		delete(l.keys, key)
+		l.lock.Unlock()
+
+		conn := l.pool.Get()
+		defer conn.Close()
+		if _, err := redisUnlockScript.Do(conn, k, l.holder); err != nil {
+			logger.Warning("Unable to unlock %v: %v", key, err)
+		}
+	}, nil
+}
+
+// Extend renews all held locks for ttl.
+func (l *RedisLock) Extend(ttl time.Duration) error {
+	l.lock.Lock()
+	keys := []string{}
+	for key := range l.keys {
+		keys = append(keys, key)
+	}
+	l.lock.Unlock()
+
+	failed := []string{}
+	for _, key := range keys {
+		conn := l.pool.Get()
+		n, err := redis.Int(redisExtendScript.Do(conn, l.prefix+key,
+			l.holder, int64(ttl/time.Millisecond)))
+		conn.Close()
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%v: %v", key, err))
+		} else if n != 1 {
+			l.lock.Lock()
+			delete(l.keys, key)
+			l.lock.Unlock()
+			failed = append(failed, key+": expired")
+		}
+	}
+	return extendError(failed)
+}
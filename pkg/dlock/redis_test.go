@@ -0,0 +1,244 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package dlock
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/heketi/tests"
+)
+
+type fakeRedisKey struct {
+	value   string
+	expires time.Time
+}
+
+// fakeRedis serves the redis commands used by RedisLock.
+type fakeRedis struct {
+	clock    fakeClock
+	password string
+	listener net.Listener
+	lock     sync.Mutex
+	keys     map[string]fakeRedisKey
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	f := &fakeRedis{
+		password: password,
+		listener: l,
+		keys:     map[string]fakeRedisKey{},
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) Addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeRedis) Close() {
+	f.listener.Close()
+}
+
+func readRedisCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := []string{}
+	for i := 0; i < n; i++ {
+		line, err = r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readRedisCommand(r)
+		if err != nil {
+			return
+		}
+		if args[0] == "AUTH" {
+			if args[1] != f.password {
+				fmt.Fprint(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			authed = true
+			fmt.Fprint(conn, "+OK\r\n")
+			continue
+		}
+		if !authed {
+			fmt.Fprint(conn, "-NOAUTH Authentication required\r\n")
+			continue
+		}
+		fmt.Fprint(conn, f.run(args))
+	}
+}
+
+func (f *fakeRedis) get(key string) (string, bool) {
+	k, found := f.keys[key]
+	if !found || f.clock.now().After(k.expires) {
+		return "", false
+	}
+	return k.value, true
+}
+
+func (f *fakeRedis) run(args []string) string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	ttl := func(ms string) time.Time {
+		n, _ := strconv.Atoi(ms)
+		return f.clock.now().Add(time.Duration(n) * time.Millisecond)
+	}
+	switch {
+	case args[0] == "EVALSHA":
+		// scripts are not cached, the client falls back to EVAL
+		return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+	case args[0] == "SET" && len(args) == 6 &&
+		args[3] == "NX" && args[4] == "PX":
+		if _, found := f.get(args[1]); found {
+			return "$-1\r\n"
+		}
+		f.keys[args[1]] = fakeRedisKey{args[2], ttl(args[5])}
+		return "+OK\r\n"
+	case args[0] == "EVAL" && args[1] == redisUnlockSource:
+		if v, found := f.get(args[3]); !found || v != args[4] {
+			return ":0\r\n"
+		}
+		delete(f.keys, args[3])
+		return ":1\r\n"
+	case args[0] == "EVAL" && args[1] == redisExtendSource:
+		if v, found := f.get(args[3]); !found || v != args[4] {
+			return ":0\r\n"
+		}
+		f.keys[args[3]] = fakeRedisKey{args[4], ttl(args[5])}
+		return ":1\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func TestRedisLockMutualExclusion(t *testing.T) {
+	f := newFakeRedis(t, "")
+	defer f.Close()
+
+	a := NewRedisLock(f.Addr(), "", DEFAULT_PREFIX)
+	defer a.Close()
+	b := NewRedisLock(f.Addr(), "", DEFAULT_PREFIX)
+	defer b.Close()
+	testMutualExclusion(t, a, b)
+	tests.Assert(t, len(f.keys) == 0, "unexpected keys:", f.keys)
+}
+
+func TestRedisLockConcurrent(t *testing.T) {
+	f := newFakeRedis(t, "")
+	defer f.Close()
+
+	testConcurrentLock(t, func() DistributedLock {
+		return NewRedisLock(f.Addr(), "", DEFAULT_PREFIX)
+	})
+}
+
+func TestRedisLockExtend(t *testing.T) {
+	f := newFakeRedis(t, "")
+	defer f.Close()
+
+	a := NewRedisLock(f.Addr(), "", DEFAULT_PREFIX)
+	defer a.Close()
+	b := NewRedisLock(f.Addr(), "", DEFAULT_PREFIX)
+	defer b.Close()
+	testExtend(t, &f.clock, a, b)
+}
+
+func TestRedisLockExtendAll(t *testing.T) {
+	f := newFakeRedis(t, "")
+	defer f.Close()
+
+	a := NewRedisLock(f.Addr(), "", DEFAULT_PREFIX)
+	defer a.Close()
+	b := NewRedisLock(f.Addr(), "", DEFAULT_PREFIX)
+	defer b.Close()
+	testExtendAll(t, &f.clock, a, b)
+}
+
+func TestRedisLockExpiredUnlock(t *testing.T) {
+	f := newFakeRedis(t, "")
+	defer f.Close()
+
+	a := NewRedisLock(f.Addr(), "", DEFAULT_PREFIX)
+	defer a.Close()
+	b := NewRedisLock(f.Addr(), "", DEFAULT_PREFIX)
+	defer b.Close()
+	unlock, err := a.TryLock("volume/1", time.Second)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	f.clock.advance(2 * time.Second)
+	_, err = b.TryLock("volume/1", 10*time.Second)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// the expired holder does not release the lock of the new one
+	unlock()
+	_, err = a.TryLock("volume/1", 10*time.Second)
+	tests.Assert(t, err == ErrLocked, "expected err == ErrLocked, got:", err)
+}
+
+func TestRedisLockAuth(t *testing.T) {
+	f := newFakeRedis(t, "secret")
+	defer f.Close()
+
+	l := NewRedisLock(f.Addr(), "secret", DEFAULT_PREFIX)
+	defer l.Close()
+	unlock, err := l.TryLock("volume/1", time.Second)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	unlock()
+
+	l = NewRedisLock(f.Addr(), "wrong", DEFAULT_PREFIX)
+	defer l.Close()
+	_, err = l.TryLock("volume/1", time.Second)
+	tests.Assert(t, err != nil && strings.Contains(err.Error(), "WRONGPASS"),
+		"expected auth error, got:", err)
+}
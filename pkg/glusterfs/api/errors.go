@@ -0,0 +1,98 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), as published by the Free Software Foundation,
+// or under the Apache License, Version 2.0 <LICENSE-APACHE2 or
+// http://www.apache.org/licenses/LICENSE-2.0>.
+//
+// You may not use this file except in compliance with those terms.
+//
+
+package api
+
+import (
+	"net/http"
+)
+
+// ErrorCode is a machine-readable identifier for the condition that
+// caused a request to fail.
+type ErrorCode string
+
+const (
+	// generic codes derived from the http status
+	ErrorInvalidRequest     ErrorCode = "INVALID_REQUEST"
+	ErrorUnprocessable      ErrorCode = "UNPROCESSABLE_REQUEST"
+	ErrorUnauthorized       ErrorCode = "UNAUTHORIZED"
+	ErrorNotFound           ErrorCode = "NOT_FOUND"
+	ErrorConflict           ErrorCode = "CONFLICT"
+	ErrorRequestTooLarge    ErrorCode = "REQUEST_TOO_LARGE"
+	ErrorUnsupportedMedia   ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	ErrorServerBusy         ErrorCode = "SERVER_BUSY"
+	ErrorInternal           ErrorCode = "INTERNAL_ERROR"
+	ErrorServiceUnavailable ErrorCode = "SERVICE_UNAVAILABLE"
+
+	// specific conditions
+	ErrorClusterNotFound     ErrorCode = "CLUSTER_NOT_FOUND"
+	ErrorNodeNotFound        ErrorCode = "NODE_NOT_FOUND"
+	ErrorDeviceNotFound      ErrorCode = "DEVICE_NOT_FOUND"
+	ErrorVolumeNotFound      ErrorCode = "VOLUME_NOT_FOUND"
+	ErrorBlockVolumeNotFound ErrorCode = "BLOCK_VOLUME_NOT_FOUND"
+	ErrorOperationInProgress ErrorCode = "OPERATION_IN_PROGRESS"
+	ErrorInsufficientSpace   ErrorCode = "INSUFFICIENT_SPACE"
+)
+
+// ErrorResponse is the body of all error responses sent by the
+// server.
+type ErrorResponse struct {
+	Code    ErrorCode              `json:"code"`
+	Message string                 `json:"message"`
+	Detail  map[string]interface{} `json:"detail,omitempty"`
+}
+
+func (e *ErrorResponse) Error() string {
+	return e.Message
+}
+
+// PlacementTrace records whether a device was considered for the
+// bricks of a volume and, if it was rejected, why. A list of traces
+// is included in the "placement_trace" detail of INSUFFICIENT_SPACE
+// errors returned by volume create.
+type PlacementTrace struct {
+	DeviceId string `json:"device_id"`
+	NodeId   string `json:"node_id"`
+	Rejected bool   `json:"rejected"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ErrorCodeForStatus returns the generic error code for the given
+// http status.
+func ErrorCodeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrorInvalidRequest
+	case http.StatusUnprocessableEntity:
+		return ErrorUnprocessable
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrorUnauthorized
+	case http.StatusNotFound:
+		return ErrorNotFound
+	case http.StatusConflict:
+		return ErrorConflict
+	case http.StatusRequestEntityTooLarge:
+		return ErrorRequestTooLarge
+	case http.StatusUnsupportedMediaType:
+		return ErrorUnsupportedMedia
+	case http.StatusTooManyRequests:
+		return ErrorServerBusy
+	case http.StatusInsufficientStorage:
+		return ErrorInsufficientSpace
+	case http.StatusServiceUnavailable:
+		return ErrorServiceUnavailable
+	}
+	if status >= 400 && status < 500 {
+		return ErrorInvalidRequest
+	}
+	return ErrorInternal
+}
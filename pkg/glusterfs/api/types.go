@@ -17,9 +17,12 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
+	"path"
 	"regexp"
 	"sort"
+	"strings"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/go-ozzo/ozzo-validation/is"
@@ -37,6 +40,26 @@ var (
 	blockVolNameRe = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
 
 	tagNameRe = regexp.MustCompile("^[a-zA-Z0-9_.-]+$")
+
+	// geo-replication slaves are named "[<user>@]<host>::<volume>"
+	geoRepSessionRe = regexp.MustCompile("^([a-zA-Z0-9_.-]+@)?[a-zA-Z0-9_.-]+::[a-zA-Z0-9_-]+$")
+
+	// LVM names may not start with a hyphen
+	lvmSnapshotNameRe = regexp.MustCompile("^[a-zA-Z0-9_.+][a-zA-Z0-9_.+-]*$")
+
+	// Addresses accepted by the auth.allow volume option: hostnames,
+	// IP addresses with optional wildcards and CIDR ranges
+	aclHostRe = regexp.MustCompile("^[a-zA-Z0-9_.:*/-]+$")
+
+	// a field of a cron schedule
+	cronFieldRe = regexp.MustCompile("^[0-9*/,-]+$")
+
+	// values accepted for performance translator options: sizes,
+	// counts, durations and on/off switches
+	translatorValueRe = regexp.MustCompile("^[a-zA-Z0-9_.:,-]+$")
+
+	// names of trusted storage pools
+	tspIdRe = regexp.MustCompile("^[a-zA-Z0-9_.-]+$")
 )
 
 // ValidateUUID is written this way because heketi UUID does not
@@ -212,6 +235,8 @@ type DeviceInfoResponse struct {
 	DeviceInfo
 	State  EntryState  `json:"state"`
 	Bricks []BrickInfo `json:"bricks"`
+	// data usage of the thin pools on the device as last measured
+	ThinPoolUsedPercent float64 `json:"thin_pool_used_percent,omitempty"`
 }
 
 // Node
@@ -220,6 +245,11 @@ type NodeAddRequest struct {
 	Hostnames HostAddresses     `json:"hostnames"`
 	ClusterId string            `json:"cluster"`
 	Tags      map[string]string `json:"tags,omitempty"`
+	// geographic region of the node, independent of the zone
+	Region string `json:"region,omitempty"`
+	// gluster trusted storage pool of the node within the cluster,
+	// empty for the default pool
+	TspId string `json:"tsp_id,omitempty"`
 }
 
 func (req NodeAddRequest) Validate() error {
@@ -228,18 +258,60 @@ func (req NodeAddRequest) Validate() error {
 		validation.Field(&req.Hostnames, validation.Required),
 		validation.Field(&req.ClusterId, validation.Required, validation.By(ValidateUUID)),
 		validation.Field(&req.Tags, validation.By(ValidateTags)),
+		validation.Field(&req.TspId, validation.Match(tspIdRe)),
 	)
 }
 
 type NodeInfo struct {
 	NodeAddRequest
 	Id string `json:"id"`
+	// address the manage hostname last resolved to, used when the
+	// hostname can not be resolved
+	ResolvedIP string `json:"resolved_ip,omitempty"`
+}
+
+// NodeSetIpRequest sets the address used to reach a node whose
+// hostname can not be resolved.
+type NodeSetIpRequest struct {
+	Ip string `json:"ip"`
+}
+
+func (req NodeSetIpRequest) Validate() error {
+	return validation.ValidateStruct(&req,
+		validation.Field(&req.Ip, validation.Required, is.IP),
+	)
 }
 
 type NodeInfoResponse struct {
 	NodeInfo
 	State       EntryState           `json:"state"`
 	DevicesInfo []DeviceInfoResponse `json:"devices"`
+	// recent ssh round-trip times, if known
+	SshLatencyP50Ms float64 `json:"ssh_latency_p50_ms,omitempty"`
+	SshLatencyP99Ms float64 `json:"ssh_latency_p99_ms,omitempty"`
+}
+
+type NodeBricksResponse struct {
+	Bricks []BrickInfo `json:"bricks"`
+}
+
+// node health states as seen by the server's node monitor
+const (
+	NodeHealthUp      = "up"
+	NodeHealthDown    = "down"
+	NodeHealthUnknown = "unknown"
+)
+
+type NodePingResponse struct {
+	Reachable bool    `json:"reachable"`
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+	// last state recorded by the node health monitor
+	Health string `json:"health"`
+}
+
+type NodeKnownHostResponse struct {
+	Fingerprint string `json:"fingerprint"`
 }
 
 // Cluster
@@ -262,6 +334,36 @@ type TopologyInfoResponse struct {
 	ClusterList []Cluster `json:"clusters"`
 }
 
+// ClusterTopologyDevice is a device in the topology of a cluster.
+type ClusterTopologyDevice struct {
+	Id         string `json:"device_id"`
+	Path       string `json:"path"`
+	FreeGb     uint64 `json:"free_gb"`
+	BrickCount int    `json:"brick_count"`
+}
+
+// ClusterTopologyNode is a node in the topology of a cluster.
+type ClusterTopologyNode struct {
+	Id       string                  `json:"node_id"`
+	Hostname string                  `json:"hostname"`
+	State    EntryState              `json:"state"`
+	Devices  []ClusterTopologyDevice `json:"devices"`
+}
+
+// ClusterTopologyZone holds the nodes of a cluster in the same zone.
+// Zones are numbered, the zone name is the zone number.
+type ClusterTopologyZone struct {
+	Name  string                `json:"zone_name"`
+	Nodes []ClusterTopologyNode `json:"nodes"`
+}
+
+// ClusterTopologyResponse is the topology of a cluster, nested by
+// zone, node and device.
+type ClusterTopologyResponse struct {
+	ClusterId string                `json:"cluster_id"`
+	Zones     []ClusterTopologyZone `json:"zones"`
+}
+
 type ClusterCreateRequest struct {
 	ClusterFlags
 }
@@ -270,18 +372,125 @@ type ClusterSetFlagsRequest struct {
 	ClusterFlags
 }
 
+// TrustedStoragePool is a set of nodes of a cluster that are peers
+// of each other in gluster. The bricks of a volume are always placed
+// within a single pool.
+type TrustedStoragePool struct {
+	// empty for the default pool
+	Id        string   `json:"id"`
+	PeerNodes []string `json:"peer_nodes"`
+}
+
 type ClusterInfoResponse struct {
 	Id      string           `json:"id"`
 	Nodes   sort.StringSlice `json:"nodes"`
 	Volumes sort.StringSlice `json:"volumes"`
 	ClusterFlags
 	BlockVolumes sort.StringSlice `json:"blockvolumes"`
+	// pools the nodes of the cluster belong to, if any
+	TrustedStoragePools []TrustedStoragePool `json:"trusted_storage_pools,omitempty"`
 }
 
 type ClusterListResponse struct {
 	Clusters []string `json:"clusters"`
 }
 
+// Types of the events of a cluster
+const (
+	ClusterEventClusterCreated        = "cluster_created"
+	ClusterEventFlagsChanged          = "cluster_flags_changed"
+	ClusterEventSnapshotPolicyChanged = "snapshot_policy_changed"
+	ClusterEventNodeAdded             = "node_added"
+	ClusterEventNodeRemoved           = "node_removed"
+	ClusterEventDeviceAdded           = "device_added"
+	ClusterEventDeviceRemoved         = "device_removed"
+)
+
+// ClusterEvent records a change made to a cluster.
+type ClusterEvent struct {
+	Id        string `json:"id"`
+	ClusterId string `json:"cluster_id"`
+	// seconds since the epoch
+	Timestamp int64  `json:"timestamp"`
+	EventType string `json:"event_type"`
+	// issuer of the token of the request, empty without authentication
+	ActorId     string            `json:"actor_id"`
+	Description string            `json:"description"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+type ClusterEventListResponse struct {
+	Events []ClusterEvent `json:"events"`
+}
+
+// ClusterRebalanceZonesRequest requests that a brick of every volume
+// of the cluster without a brick in the given zone is moved to the
+// zone. If PlanOnly is set the planned moves are returned without
+// moving any bricks.
+type ClusterRebalanceZonesRequest struct {
+	Zone int `json:"zone"`
+	// number of bricks moved concurrently (default 1)
+	BatchSize int  `json:"batch_size,omitempty"`
+	PlanOnly  bool `json:"plan_only,omitempty"`
+}
+
+func (req ClusterRebalanceZonesRequest) Validate() error {
+	return validation.ValidateStruct(&req,
+		validation.Field(&req.Zone, validation.Required, validation.Min(1)),
+		validation.Field(&req.BatchSize, validation.Min(0), validation.Max(32)),
+	)
+}
+
+// ClusterSnapshotPolicy holds the gluster snapshot settings of a
+// cluster. A MaxSnapshotsPerVolume of zero uses the gluster default and
+// an empty CronSchedule disables scheduled snapshots.
+type ClusterSnapshotPolicy struct {
+	MaxSnapshotsPerVolume int    `json:"max_snapshots_per_volume"`
+	AutoDelete            bool   `json:"auto_delete"`
+	CronSchedule          string `json:"cron_schedule"`
+}
+
+// ValidateCronSchedule checks that the value is a cron schedule of
+// five fields.
+func ValidateCronSchedule(value interface{}) error {
+	s, _ := value.(string)
+	if s == "" {
+		return nil
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return fmt.Errorf("%v is not a cron schedule of 5 fields", s)
+	}
+	for _, f := range fields {
+		if !cronFieldRe.MatchString(f) {
+			return fmt.Errorf("invalid field %v in cron schedule %v", f, s)
+		}
+	}
+	return nil
+}
+
+func (p ClusterSnapshotPolicy) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.MaxSnapshotsPerVolume,
+			validation.Min(0), validation.Max(256)),
+		validation.Field(&p.CronSchedule, validation.By(ValidateCronSchedule)),
+	)
+}
+
+// ZoneRebalanceMove describes the planned move of a brick of a volume
+// to the target zone.
+type ZoneRebalanceMove struct {
+	VolumeId   string `json:"volume_id"`
+	BrickId    string `json:"brick_id"`
+	SourceZone int    `json:"source_zone"`
+	Arbiter    bool   `json:"arbiter"`
+}
+
+type ClusterRebalanceZonesResponse struct {
+	Zone  int                 `json:"zone"`
+	Moves []ZoneRebalanceMove `json:"moves"`
+}
+
 // Durabilities
 type ReplicaDurability struct {
 	Replica int `json:"replica,omitempty"`
@@ -293,6 +502,26 @@ type DisperseDurability struct {
 }
 
 // Volume
+type VolumeTier string
+
+const (
+	VolumeTierNone   VolumeTier = ""
+	VolumeTierGold   VolumeTier = "gold"
+	VolumeTierSilver VolumeTier = "silver"
+	VolumeTierBronze VolumeTier = "bronze"
+)
+
+// VolumeAccessPattern is a hint on how a volume is going to be used
+// which the brick allocator takes into account.
+type VolumeAccessPattern string
+
+const (
+	AccessPatternNone           VolumeAccessPattern = ""
+	AccessPatternWriteIntensive VolumeAccessPattern = "write-intensive"
+	AccessPatternReadIntensive  VolumeAccessPattern = "read-intensive"
+	AccessPatternBalanced       VolumeAccessPattern = "balanced"
+)
+
 type VolumeDurabilityInfo struct {
 	Type      DurabilityType     `json:"type,omitempty"`
 	Replicate ReplicaDurability  `json:"replicate,omitempty"`
@@ -312,6 +541,19 @@ type VolumeCreateRequest struct {
 		Enable bool    `json:"enable"`
 		Factor float32 `json:"factor"`
 	} `json:"snapshot"`
+	// every brick set must have a brick in each of these regions
+	RequiredRegions []string `json:"required_regions,omitempty"`
+	// bricks are only placed on devices carrying the tier's label
+	Tier VolumeTier `json:"tier,omitempty"`
+	// bricks are only placed on devices of these nodes
+	PinnedNodeIds []string `json:"pinned_node_ids,omitempty"`
+	// how the volume is expected to be used, affects brick placement
+	AccessPattern VolumeAccessPattern `json:"access_pattern,omitempty"`
+	// trusted storage pool of the cluster the bricks are placed in,
+	// empty for the default pool
+	TspId string `json:"tsp_id,omitempty"`
+	// user defined metadata, not used by heketi
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 func (volCreateRequest VolumeCreateRequest) Validate() error {
@@ -323,6 +565,14 @@ func (volCreateRequest VolumeCreateRequest) Validate() error {
 		validation.Field(&volCreateRequest.Gid, validation.Skip),
 		validation.Field(&volCreateRequest.GlusterVolumeOptions, validation.Skip),
 		validation.Field(&volCreateRequest.Block, validation.In(true, false)),
+		validation.Field(&volCreateRequest.Tier,
+			validation.In(VolumeTierGold, VolumeTierSilver, VolumeTierBronze)),
+		validation.Field(&volCreateRequest.PinnedNodeIds, validation.By(ValidateIds)),
+		validation.Field(&volCreateRequest.AccessPattern,
+			validation.In(AccessPatternWriteIntensive,
+				AccessPatternReadIntensive, AccessPatternBalanced)),
+		validation.Field(&volCreateRequest.TspId, validation.Match(tspIdRe)),
+		validation.Field(&volCreateRequest.Labels, validation.By(ValidateTags)),
 		// This is possibly a bug in validation lib, ignore next two lines for now
 		// validation.Field(&volCreateRequest.Snapshot.Enable, validation.In(true, false)),
 		// validation.Field(&volCreateRequest.Snapshot.Factor, validation.Min(1.0)),
@@ -368,11 +618,95 @@ type VolumeInfo struct {
 		BlockVolumes sort.StringSlice `json:"blockvolume,omitempty"`
 		Restriction  BlockRestriction `json:"restriction,omitempty"`
 	} `json:"blockinfo,omitempty"`
+	ACLConfig *VolumeACLConfig `json:"acl_config,omitempty"`
+	// performance translator options set through heketi, by
+	// translator and option name
+	TranslatorOptions map[string]map[string]string `json:"translator_options,omitempty"`
 }
 
 type VolumeInfoResponse struct {
 	VolumeInfo
 	Bricks []BrickInfo `json:"bricks"`
+	// last known bitrot status, if it was ever queried
+	Bitrot *BitrotStatusResponse `json:"bitrot,omitempty"`
+}
+
+// BitrotStatusResponse is the state of the bitrot scrubber of a
+// volume summed up over all of its nodes.
+type BitrotStatusResponse struct {
+	// "Active" or "Paused"
+	State           string `json:"state"`
+	DurationSeconds int    `json:"duration_seconds"`
+	FilesScrubbed   int    `json:"files_scrubbed"`
+	FilesCorrupted  int    `json:"files_corrupted"`
+	LastCompleted   string `json:"last_completed"`
+}
+
+// ValidateGeoRepSession checks that the value names the slave of a
+// geo-replication session.
+func ValidateGeoRepSession(value interface{}) error {
+	s, _ := value.(string)
+	if !geoRepSessionRe.MatchString(s) {
+		return fmt.Errorf("%v is not a valid geo-replication session", s)
+	}
+	return nil
+}
+
+// GeoRepSessionStatusResponse is the state of a geo-replication
+// session of a volume on each brick of the volume.
+type GeoRepSessionStatusResponse struct {
+	// the slave of the session, "[<user>@]<host>::<volume>"
+	Session string              `json:"session"`
+	Bricks  []GeoRepBrickStatus `json:"bricks"`
+}
+
+// GeoRepBrickStatus is the state of a geo-replication session on one
+// brick of the master volume.
+type GeoRepBrickStatus struct {
+	MasterNode string `json:"master_node"`
+	BrickPath  string `json:"brick_path"`
+	SlaveNode  string `json:"slave_node,omitempty"`
+	// such as "Active", "Passive", "Faulty" or "Stopped"
+	Status       string `json:"status"`
+	CrawlStatus  string `json:"crawl_status"`
+	FilesSynced  int    `json:"files_synced"`
+	FilesPending int    `json:"files_pending"`
+	BytesPending int64  `json:"bytes_pending"`
+	LastSynced   string `json:"last_synced"`
+}
+
+// VolumeProfileInfoResponse holds the statistics gathered by the
+// profiler of a volume since profiling was started.
+type VolumeProfileInfoResponse struct {
+	Bricks []BrickProfileInfo `json:"bricks"`
+}
+
+// BrickProfileInfo holds the cumulative statistics of a brick.
+type BrickProfileInfo struct {
+	// "<host>:<path>" of the brick as named by gluster
+	Brick           string `json:"brick"`
+	DurationSeconds int64  `json:"duration_seconds"`
+	BytesRead       int64  `json:"bytes_read"`
+	BytesWritten    int64  `json:"bytes_written"`
+	// number of reads and writes by block size
+	BlockStats []ProfileBlockStats `json:"block_stats"`
+	FopStats   []ProfileFopStats   `json:"fop_stats"`
+}
+
+type ProfileBlockStats struct {
+	Size   int64 `json:"size"`
+	Reads  int64 `json:"reads"`
+	Writes int64 `json:"writes"`
+}
+
+// ProfileFopStats holds the number of calls and the latencies, in
+// microseconds, of a file operation.
+type ProfileFopStats struct {
+	Fop          string  `json:"fop"`
+	Hits         int64   `json:"hits"`
+	AvgLatencyUs float64 `json:"avg_latency_us"`
+	MinLatencyUs float64 `json:"min_latency_us"`
+	MaxLatencyUs float64 `json:"max_latency_us"`
 }
 
 type VolumeListResponse struct {
@@ -389,6 +723,36 @@ func (volExpandReq VolumeExpandRequest) Validate() error {
 	)
 }
 
+// Supported JSON Patch (RFC 6902) operations
+const (
+	JsonPatchAdd     = "add"
+	JsonPatchRemove  = "remove"
+	JsonPatchReplace = "replace"
+)
+
+// JsonPatchOperation is a single operation of a JSON Patch document.
+type JsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// VolumeMoveBrick describes the planned move of a single brick.
+type VolumeMoveBrick struct {
+	BrickId        string `json:"brick_id"`
+	SourceDeviceId string `json:"source_device_id"`
+	TargetDeviceId string `json:"target_device_id"`
+	SizeGB         int64  `json:"size_gb"`
+}
+
+type VolumeMoveEstimateResponse struct {
+	Feasible   bool              `json:"feasible"`
+	BrickMoves []VolumeMoveBrick `json:"brick_moves"`
+	// free space (KiB) left on each target device after the moves
+	RemainingFree map[string]int64 `json:"remaining_free"`
+	Reason        string           `json:"reason,omitempty"`
+}
+
 type VolumeCloneRequest struct {
 	Name string `json:"name,omitempty"`
 }
@@ -399,6 +763,99 @@ func (vcr VolumeCloneRequest) Validate() error {
 	)
 }
 
+// VolumeRepairResponse lists the actions a volume repair took and the
+// problems it was unable to fix.
+type VolumeRepairResponse struct {
+	ActionsTaken []string `json:"actions_taken"`
+	UnableToFix  []string `json:"unable_to_fix"`
+}
+
+// VolumeImportBrick is a pre-existing brick to be adopted by heketi.
+// The brick directory must be named "brick" and sit at the root of
+// the mounted thin lv of the brick, like the bricks heketi creates.
+type VolumeImportBrick struct {
+	NodeId   string `json:"node_id"`
+	DeviceId string `json:"device_id"`
+	Path     string `json:"path"`
+	SizeGb   int    `json:"size_gb"`
+}
+
+func (b VolumeImportBrick) Validate() error {
+	return validation.ValidateStruct(&b,
+		validation.Field(&b.NodeId, validation.Required, validation.By(ValidateUUID)),
+		validation.Field(&b.DeviceId, validation.Required, validation.By(ValidateUUID)),
+		validation.Field(&b.Path, validation.Required, validation.By(validateBrickPath)),
+		validation.Field(&b.SizeGb, validation.Required, validation.Min(1)),
+	)
+}
+
+func validateBrickPath(value interface{}) error {
+	p, _ := value.(string)
+	if !path.IsAbs(p) || path.Clean(p) != p || !deviceNameRe.MatchString(p) {
+		return fmt.Errorf("%v is not a valid absolute path", p)
+	}
+	if path.Base(p) != "brick" || path.Dir(p) == "/" {
+		return fmt.Errorf("%v is not a brick directory (<mount point>/brick)", p)
+	}
+	return nil
+}
+
+// VolumeImportLayoutRequest creates a volume out of pre-existing
+// bricks. Bricks are grouped in replica sets in the order given.
+type VolumeImportLayoutRequest struct {
+	Name      string `json:"name"`
+	ClusterId string `json:"cluster_id"`
+	// bricks in each replica set, defaults to the number of bricks
+	Replica int                 `json:"replica,omitempty"`
+	Bricks  []VolumeImportBrick `json:"bricks"`
+}
+
+func (req VolumeImportLayoutRequest) Validate() error {
+	err := validation.ValidateStruct(&req,
+		validation.Field(&req.Name, validation.Required, validation.Match(volumeNameRe)),
+		validation.Field(&req.ClusterId, validation.Required, validation.By(ValidateUUID)),
+		validation.Field(&req.Replica, validation.Min(0), validation.Max(3)),
+		validation.Field(&req.Bricks, validation.Required, validation.Skip),
+	)
+	if err != nil {
+		return err
+	}
+	for i, b := range req.Bricks {
+		if err := b.Validate(); err != nil {
+			return fmt.Errorf("bricks[%v]: %v", i, err)
+		}
+	}
+	replica := req.ReplicaCount()
+	if replica > 3 {
+		return fmt.Errorf("replica: %v bricks per replica set not supported", replica)
+	}
+	if len(req.Bricks)%replica != 0 {
+		return fmt.Errorf("bricks: %v bricks can not be split in sets of %v",
+			len(req.Bricks), replica)
+	}
+	return nil
+}
+
+// ReplicaCount returns the number of bricks in each replica set.
+func (req VolumeImportLayoutRequest) ReplicaCount() int {
+	if req.Replica == 0 {
+		return len(req.Bricks)
+	}
+	return req.Replica
+}
+
+// VolumePinRequest replaces the nodes the bricks of a volume are pinned
+// to. An empty list removes the pinning.
+type VolumePinRequest struct {
+	PinnedNodeIds []string `json:"pinned_node_ids"`
+}
+
+func (vpr VolumePinRequest) Validate() error {
+	return validation.ValidateStruct(&vpr,
+		validation.Field(&vpr.PinnedNodeIds, validation.By(ValidateIds)),
+	)
+}
+
 type VolumeBlockRestrictionRequest struct {
 	Restriction BlockRestriction `json:"restriction"`
 }
@@ -409,6 +866,131 @@ func (vbrr VolumeBlockRestrictionRequest) Validate() error {
 			validation.In(Unrestricted, Locked)))
 }
 
+// VolumeACLConfig controls the access to a volume. Anonymous ids
+// left unset use the gluster default (nfsnobody). An empty list of
+// allowed hosts allows all hosts.
+type VolumeACLConfig struct {
+	RootSquash   bool     `json:"root_squash"`
+	AnonUID      int      `json:"anon_uid,omitempty"`
+	AnonGID      int      `json:"anon_gid,omitempty"`
+	AllowedHosts []string `json:"allowed_hosts,omitempty"`
+}
+
+func ValidateACLHosts(value interface{}) error {
+	hosts, _ := value.([]string)
+	for _, h := range hosts {
+		err := validation.Validate(h, validation.Required,
+			validation.Match(aclHostRe))
+		if err != nil {
+			return fmt.Errorf("%v is not a valid host", h)
+		}
+	}
+	return nil
+}
+
+func (acl VolumeACLConfig) Validate() error {
+	return validation.ValidateStruct(&acl,
+		validation.Field(&acl.AnonUID, validation.Min(0)),
+		validation.Field(&acl.AnonGID, validation.Min(0)),
+		validation.Field(&acl.AllowedHosts, validation.By(ValidateACLHosts)),
+	)
+}
+
+// PerformanceTranslators maps the performance translators of a volume
+// that can be configured to the gluster volume options they accept.
+var PerformanceTranslators = map[string][]string{
+	"io-cache": {
+		"performance.io-cache",
+		"performance.cache-size",
+		"performance.cache-min-file-size",
+		"performance.cache-max-file-size",
+		"performance.cache-refresh-timeout",
+	},
+	"io-threads": {
+		"performance.client-io-threads",
+		"performance.io-thread-count",
+		"performance.high-prio-threads",
+		"performance.normal-prio-threads",
+		"performance.low-prio-threads",
+		"performance.least-prio-threads",
+	},
+	"write-behind": {
+		"performance.write-behind",
+		"performance.write-behind-window-size",
+		"performance.flush-behind",
+		"performance.strict-o-direct",
+	},
+	"read-ahead": {
+		"performance.read-ahead",
+		"performance.read-ahead-page-count",
+	},
+	"quick-read": {
+		"performance.quick-read",
+	},
+	"md-cache": {
+		"performance.stat-prefetch",
+		"performance.md-cache-timeout",
+		"performance.cache-invalidation",
+	},
+	"open-behind": {
+		"performance.open-behind",
+	},
+	"readdir-ahead": {
+		"performance.readdir-ahead",
+		"performance.parallel-readdir",
+	},
+}
+
+// PerformanceTranslatorOf returns the performance translator the
+// gluster volume option belongs to, or an empty string.
+func PerformanceTranslatorOf(option string) string {
+	for t, options := range PerformanceTranslators {
+		for _, o := range options {
+			if o == option {
+				return t
+			}
+		}
+	}
+	return ""
+}
+
+// VolumeTranslatorRequest sets options of a performance translator of
+// a volume. The options are gluster volume option names and values.
+type VolumeTranslatorRequest struct {
+	Translator string            `json:"translator"`
+	Options    map[string]string `json:"options"`
+}
+
+func (tr VolumeTranslatorRequest) Validate() error {
+	err := validation.ValidateStruct(&tr,
+		validation.Field(&tr.Translator, validation.Required),
+		validation.Field(&tr.Options, validation.Required),
+	)
+	if err != nil {
+		return err
+	}
+	if _, ok := PerformanceTranslators[tr.Translator]; !ok {
+		return fmt.Errorf("unknown translator %v", tr.Translator)
+	}
+	for o, value := range tr.Options {
+		if PerformanceTranslatorOf(o) != tr.Translator {
+			return fmt.Errorf("%v is not an option of translator %v",
+				o, tr.Translator)
+		}
+		if !translatorValueRe.MatchString(value) {
+			return fmt.Errorf("%v is not a valid value for %v", value, o)
+		}
+	}
+	return nil
+}
+
+// VolumeTranslatorsResponse holds the performance translator options
+// reconfigured on a volume, as reported by gluster, by translator and
+// option name.
+type VolumeTranslatorsResponse struct {
+	Translators map[string]map[string]string `json:"translators"`
+}
+
 // BlockVolume
 
 type BlockVolumeCreateRequest struct {
@@ -466,6 +1048,22 @@ func (blockVolExpandReq BlockVolumeExpandRequest) Validate() error {
 	)
 }
 
+// BlockVolumeMigrateGatewayRequest moves the export of a block volume
+// from one gateway node to another. The source gateway may be omitted
+// if the block volume is exported by a single gateway.
+type BlockVolumeMigrateGatewayRequest struct {
+	TargetGatewayNodeId string `json:"target_gateway_node_id"`
+	SourceGatewayNodeId string `json:"source_gateway_node_id,omitempty"`
+}
+
+func (req BlockVolumeMigrateGatewayRequest) Validate() error {
+	return validation.ValidateStruct(&req,
+		validation.Field(&req.TargetGatewayNodeId, validation.Required,
+			validation.By(ValidateUUID)),
+		validation.Field(&req.SourceGatewayNodeId, validation.By(ValidateUUID)),
+	)
+}
+
 type LogLevelInfo struct {
 	// should contain one or more logger to log-level-name mapping
 	LogLevel map[string]string `json:"loglevel"`
@@ -573,6 +1171,28 @@ func (v *VolumeInfoResponse) String() string {
 		s += fmt.Sprintf("Snapshot Factor: %.2f\n",
 			v.Snapshot.Factor)
 	}
+	if v.Tier != VolumeTierNone {
+		s += fmt.Sprintf("Tier: %v\n", v.Tier)
+	}
+	if len(v.PinnedNodeIds) > 0 {
+		s += fmt.Sprintf("Pinned Nodes: %v\n", v.PinnedNodeIds)
+	}
+	if v.AccessPattern != AccessPatternNone {
+		s += fmt.Sprintf("Access Pattern: %v\n", v.AccessPattern)
+	}
+	if v.TspId != "" {
+		s += fmt.Sprintf("Trusted Storage Pool: %v\n", v.TspId)
+	}
+	if v.ACLConfig != nil {
+		s += fmt.Sprintf("Root Squash: %v\n"+
+			"Anonymous UID: %v\n"+
+			"Anonymous GID: %v\n"+
+			"Allowed Hosts: %v\n",
+			v.ACLConfig.RootSquash,
+			v.ACLConfig.AnonUID,
+			v.ACLConfig.AnonGID,
+			v.ACLConfig.AllowedHosts)
+	}
 	return s
 }
 
@@ -637,6 +1257,8 @@ type OperationsInfo struct {
 	Stale  uint64 `json:"stale"`
 	Failed uint64 `json:"failed"`
 	New    uint64 `json:"new"`
+	// bricks replaced by offline brick detection
+	AutoBrickReplaced uint64 `json:"auto_brick_replaced"`
 }
 
 type AdminState string
@@ -658,6 +1280,26 @@ func (as AdminStatus) Validate() error {
 			validation.In(AdminStateNormal, AdminStateReadOnly, AdminStateLocal)))
 }
 
+// AdminModeName is a simplified view of the admin state that only
+// tells if the server accepts changes.
+type AdminModeName string
+
+const (
+	AdminModeReadOnly  AdminModeName = "readonly"
+	AdminModeReadWrite AdminModeName = "readwrite"
+)
+
+type AdminMode struct {
+	Mode AdminModeName `json:"mode"`
+}
+
+func (am AdminMode) Validate() error {
+	return validation.ValidateStruct(&am,
+		validation.Field(&am.Mode,
+			validation.Required,
+			validation.In(AdminModeReadOnly, AdminModeReadWrite)))
+}
+
 // DeviceDeleteOptions is used to specify additional behavior for device
 // deletes.
 type DeviceDeleteOptions struct {
@@ -666,13 +1308,24 @@ type DeviceDeleteOptions struct {
 	ForceForget bool `json:"forceforget"`
 }
 
+const (
+	// OperationTagHeaderPrefix prefixes the request headers used to tag
+	// the operation started by the request, e.g. X-Heketi-Tag-Env: prod.
+	OperationTagHeaderPrefix = "X-Heketi-Tag-"
+	// OperationTagQueryPrefix prefixes the query parameters used to
+	// filter pending operations by tag, e.g. ?tag.env=prod.
+	OperationTagQueryPrefix = "tag."
+)
+
 // PendingOperationInfo contains metadata to summarize a pending
 // operation.
 type PendingOperationInfo struct {
-	Id        string `json:"id"`
-	TypeName  string `json:"type_name"`
-	Status    string `json:"status"`
-	SubStatus string `json:"sub_status"`
+	Id        string            `json:"id"`
+	TypeName  string            `json:"type_name"`
+	Status    string            `json:"status"`
+	SubStatus string            `json:"sub_status"`
+	Progress  int               `json:"progress"`
+	Tags      map[string]string `json:"tags,omitempty"`
 	// TODO label, timestamp?
 }
 
@@ -690,6 +1343,20 @@ type PendingOperationListResponse struct {
 	PendingOperations []PendingOperationInfo `json:"pendingoperations"`
 }
 
+// VolumeOperationsResponse lists the operations that target a
+// volume. If NextCursor is non-empty more operations may be fetched
+// by passing it as the cursor of a subsequent request.
+type VolumeOperationsResponse struct {
+	Operations []PendingOperationDetails `json:"operations"`
+	NextCursor string                    `json:"next_cursor,omitempty"`
+}
+
+// PendingOperationsDeleteResponse reports the number of pending
+// operation records removed by a cleanup request.
+type PendingOperationsDeleteResponse struct {
+	DeletedCount int `json:"deleted_count"`
+}
+
 type PendingOperationsCleanRequest struct {
 	Operations []string `json:"operations,omitempty"`
 }
@@ -726,3 +1393,237 @@ func (brickops BrickEvictOptions) Validate() error {
 	return validation.ValidateStruct(&brickops,
 		validation.Field(&brickops.HealCheck, validation.By(ValidateHealCheck)))
 }
+
+// DeviceReplaceRequest replaces a device with a new device on the same
+// node. The bricks of the device are moved to the new device.
+type DeviceReplaceRequest struct {
+	NewDevicePath string        `json:"new_device_path"`
+	HealCheck     HealInfoCheck `json:"healcheck"`
+}
+
+func (req DeviceReplaceRequest) Validate() error {
+	return validation.ValidateStruct(&req,
+		validation.Field(&req.NewDevicePath, validation.Required, validation.Match(deviceNameRe)),
+		validation.Field(&req.HealCheck, validation.By(ValidateHealCheck)),
+	)
+}
+
+// DeviceReinitializeConfirmation must be given as the confirmation of
+// a device reinitialize request.
+const DeviceReinitializeConfirmation = "yes-wipe-all-data"
+
+// DeviceReinitializeRequest wipes a device without bricks and sets it
+// up again. All data on the device is lost.
+type DeviceReinitializeRequest struct {
+	Confirm string `json:"confirm"`
+}
+
+func (req DeviceReinitializeRequest) Validate() error {
+	return validation.ValidateStruct(&req,
+		validation.Field(&req.Confirm, validation.Required,
+			validation.In(DeviceReinitializeConfirmation)),
+	)
+}
+
+// DeviceBatchAddRequest registers several devices of a node at once.
+// Either all of the devices are added or none of them.
+type DeviceBatchAddRequest struct {
+	Devices []string `json:"devices"`
+}
+
+func (req DeviceBatchAddRequest) Validate() error {
+	return validation.ValidateStruct(&req,
+		validation.Field(&req.Devices, validation.Required,
+			validation.By(ValidateDeviceNames)),
+	)
+}
+
+func ValidateDeviceNames(v interface{}) error {
+	names, ok := v.([]string)
+	if !ok {
+		return fmt.Errorf("must be a list of strings")
+	}
+	if len(names) > 64 {
+		return fmt.Errorf("too many devices specified (%v), up to %v supported",
+			len(names), 64)
+	}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !deviceNameRe.MatchString(name) {
+			return fmt.Errorf("%v is not a valid device name", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("device %v is listed more than once", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// Results of the devices of a device batch add request
+const (
+	DeviceBatchAdded      = "added"
+	DeviceBatchFailed     = "failed"
+	DeviceBatchRolledBack = "rolled-back"
+	DeviceBatchSkipped    = "skipped"
+)
+
+// DeviceBatchResult is the result of adding one device of a batch.
+type DeviceBatchResult struct {
+	Id     string `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// DeviceBatchAddResponse maps the names of the devices of a batch to
+// their results.
+type DeviceBatchAddResponse struct {
+	NodeId  string                       `json:"node"`
+	Devices map[string]DeviceBatchResult `json:"devices"`
+}
+
+// SshKeyRequest is used to add or replace a per-node ssh key.
+type SshKeyRequest struct {
+	// glob matched against node management hostnames
+	NodePattern string `json:"node_pattern"`
+	PrivateKey  string `json:"private_key"`
+	Passphrase  string `json:"passphrase,omitempty"`
+}
+
+func (req SshKeyRequest) Validate() error {
+	return validation.ValidateStruct(&req,
+		validation.Field(&req.NodePattern,
+			validation.Required, validation.By(ValidateGlob)),
+		validation.Field(&req.PrivateKey, validation.Required),
+	)
+}
+
+func ValidateGlob(value interface{}) error {
+	s, _ := value.(string)
+	if _, err := path.Match(s, ""); err != nil {
+		return fmt.Errorf("%v is not a valid pattern: %v", s, err)
+	}
+	return nil
+}
+
+// SshKeyInfo describes a stored ssh key. The key material is
+// never returned by the server.
+type SshKeyInfo struct {
+	Id          string `json:"id"`
+	NodePattern string `json:"node_pattern"`
+}
+
+type SshKeyListResponse struct {
+	SshKeys []SshKeyInfo `json:"sshkeys"`
+}
+
+// LvmSnapshotCreateRequest is used to take a point-in-time LVM
+// snapshot of the logical volume of a brick.
+type LvmSnapshotCreateRequest struct {
+	Name string `json:"snapshot_name"`
+	// size of the snapshot as a percentage of the brick's lv
+	SizePercent int `json:"size_percent"`
+}
+
+func (req LvmSnapshotCreateRequest) Validate() error {
+	return validation.ValidateStruct(&req,
+		validation.Field(&req.Name, validation.Required,
+			validation.Length(1, 127), validation.Match(lvmSnapshotNameRe)),
+		validation.Field(&req.SizePercent, validation.Required,
+			validation.Min(1), validation.Max(100)),
+	)
+}
+
+type LvmSnapshotInfo struct {
+	Id          string `json:"id"`
+	Name        string `json:"snapshot_name"`
+	DeviceId    string `json:"device"`
+	BrickId     string `json:"brick"`
+	SizePercent int    `json:"size_percent"`
+	// block device of the snapshot on the node
+	Path string `json:"path"`
+}
+
+// VolumeDrift is a difference between the configuration heketi
+// expects a volume to have and the configuration found in gluster.
+type VolumeDrift struct {
+	VolumeId string `json:"volume_id"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// PeerDrift is a difference between the peers heketi expects a node
+// to have and the peers reported by gluster on that node.
+type PeerDrift struct {
+	NodeId   string `json:"node_id"`
+	Peer     string `json:"peer"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+type DriftReport struct {
+	VolumeDrifts []VolumeDrift `json:"volume_drifts"`
+	PeerDrifts   []PeerDrift   `json:"peer_drifts"`
+}
+
+// Operation types supported by the sandbox
+const (
+	SandboxCreateVolume = "create-volume"
+	SandboxExpandVolume = "expand-volume"
+	SandboxDeleteVolume = "delete-volume"
+)
+
+// SandboxOperationRequest describes an operation to be simulated
+// against a copy of the database. For create-volume the params are a
+// VolumeCreateRequest. For expand-volume and delete-volume the params
+// are a SandboxVolumeParams.
+type SandboxOperationRequest struct {
+	OperationType string          `json:"operation_type"`
+	Params        json.RawMessage `json:"params"`
+}
+
+func (req SandboxOperationRequest) Validate() error {
+	return validation.ValidateStruct(&req,
+		validation.Field(&req.OperationType, validation.Required,
+			validation.In(SandboxCreateVolume, SandboxExpandVolume,
+				SandboxDeleteVolume)),
+	)
+}
+
+type SandboxVolumeParams struct {
+	Id         string `json:"id"`
+	ExpandSize int    `json:"expand_size,omitempty"`
+}
+
+func (p SandboxVolumeParams) Validate() error {
+	return validation.ValidateStruct(&p,
+		validation.Field(&p.Id, validation.Required, validation.By(ValidateUUID)),
+		validation.Field(&p.ExpandSize, validation.Min(0)),
+	)
+}
+
+// SandboxChange is a db entry that was added, removed or modified
+// by a simulated operation. The bucket names match those of the db
+// dump.
+type SandboxChange struct {
+	Bucket string          `json:"bucket"`
+	Id     string          `json:"id"`
+	Change string          `json:"change"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+type SandboxOperationResponse struct {
+	OperationType string          `json:"operation_type"`
+	Error         string          `json:"error,omitempty"`
+	Changes       []SandboxChange `json:"changes"`
+}
+
+// ApiChangelogEntry lists the changes of the REST API in a release.
+type ApiChangelogEntry struct {
+	Version string   `json:"version"`
+	Date    string   `json:"date"`
+	Changes []string `json:"changes"`
+}
@@ -0,0 +1,139 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package kubernetes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/heketi/heketi/pkg/logging"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+var logger = logging.NewLogger("[kubernetes]", logging.LEVEL_INFO)
+
+// NodeLabelsFunc is called with the name and the labels of a
+// kubernetes node.
+type NodeLabelsFunc func(name string, labels map[string]string)
+
+// NodeLabelWatcher calls a function with the labels of every
+// kubernetes node when the watcher starts, whenever a node is added or
+// changed and, for all nodes, once every resync interval.
+type NodeLabelWatcher struct {
+	client clientset.Interface
+	resync time.Duration
+	f      NodeLabelsFunc
+
+	// to stop the watcher
+	stop chan<- interface{}
+}
+
+func NewNodeLabelWatcher(c clientset.Interface,
+	resync time.Duration, f NodeLabelsFunc) *NodeLabelWatcher {
+
+	return &NodeLabelWatcher{
+		client: c,
+		resync: resync,
+		f:      f,
+	}
+}
+
+// NewInClusterNodeLabelWatcher returns a NodeLabelWatcher using the
+// kubernetes cluster heketi runs in.
+func NewInClusterNodeLabelWatcher(
+	resync time.Duration, f NodeLabelsFunc) (*NodeLabelWatcher, error) {
+
+	kubeConfig, err := inClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get kubernetes configuration: %v", err)
+	}
+	c, err := newForConfig(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get kubernetes clientset: %v", err)
+	}
+	return NewNodeLabelWatcher(c, resync, f), nil
+}
+
+// sync lists all nodes, calling the function for each of them, and
+// then watches for changes of the nodes until the resync interval is
+// over, the watch ends or the watcher is stopped. It returns false if
+// the watcher was stopped.
+func (w *NodeLabelWatcher) sync(stop <-chan interface{}) bool {
+	nodes := w.client.CoreV1().Nodes()
+	l, err := nodes.List(metav1.ListOptions{})
+	if err != nil {
+		logger.LogError("Unable to list kubernetes nodes: %v", err)
+		return w.wait(stop, time.After(w.resync))
+	}
+	for _, n := range l.Items {
+		w.f(n.Name, n.Labels)
+	}
+
+	watcher, err := nodes.Watch(metav1.ListOptions{
+		ResourceVersion: l.ResourceVersion,
+	})
+	if err != nil {
+		logger.LogError("Unable to watch kubernetes nodes: %v", err)
+		return w.wait(stop, time.After(w.resync))
+	}
+	defer watcher.Stop()
+
+	resync := time.After(w.resync)
+	for {
+		select {
+		case <-stop:
+			return false
+		case <-resync:
+			return true
+		case e, ok := <-watcher.ResultChan():
+			if !ok {
+				return true
+			}
+			if e.Type != watch.Added && e.Type != watch.Modified {
+				continue
+			}
+			if n, ok := e.Object.(*v1.Node); ok {
+				w.f(n.Name, n.Labels)
+			}
+		}
+	}
+}
+
+func (w *NodeLabelWatcher) wait(stop <-chan interface{}, t <-chan time.Time) bool {
+	select {
+	case <-stop:
+		return false
+	case <-t:
+		return true
+	}
+}
+
+// Start watches the nodes in the background until Stop is called.
+func (w *NodeLabelWatcher) Start() {
+	stop := make(chan interface{})
+	w.stop = stop
+	go func() {
+		logger.Info("Started kubernetes node label watcher")
+		for w.sync(stop) {
+		}
+		logger.Info("Stopped kubernetes node label watcher")
+	}()
+}
+
+func (w *NodeLabelWatcher) Stop() {
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+}
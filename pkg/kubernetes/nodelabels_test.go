@@ -0,0 +1,68 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package kubernetes
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/heketi/tests"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodeLabelWatcher(t *testing.T) {
+	node := &v1.Node{}
+	node.Name = "node1"
+	node.Labels = map[string]string{"zone": "a"}
+	fakeclient := fakeclientset.NewSimpleClientset(node)
+
+	var lock sync.Mutex
+	seen := map[string]map[string]string{}
+	w := NewNodeLabelWatcher(fakeclient, 100*time.Millisecond,
+		func(name string, labels map[string]string) {
+			lock.Lock()
+			defer lock.Unlock()
+			seen[name] = labels
+		})
+	w.Start()
+	defer w.Stop()
+
+	labelOf := func(name, key string) string {
+		lock.Lock()
+		defer lock.Unlock()
+		return seen[name][key]
+	}
+	waitFor := func(name, key, value string) {
+		for i := 0; i < 100 && labelOf(name, key) != value; i++ {
+			time.Sleep(50 * time.Millisecond)
+		}
+		tests.Assert(t, labelOf(name, key) == value,
+			"expected", key, "=", value, "got:", labelOf(name, key))
+	}
+	waitFor("node1", "zone", "a")
+
+	node, err := fakeclient.CoreV1().Nodes().Get("node1", metav1.GetOptions{})
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	node.Labels["zone"] = "b"
+	_, err = fakeclient.CoreV1().Nodes().Update(node)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	waitFor("node1", "zone", "b")
+
+	// nodes added later are seen too
+	node2 := &v1.Node{}
+	node2.Name = "node2"
+	node2.Labels = map[string]string{"zone": "c"}
+	_, err = fakeclient.CoreV1().Nodes().Create(node2)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	waitFor("node2", "zone", "c")
+}
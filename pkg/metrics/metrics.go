@@ -109,6 +109,30 @@ var (
 		[]string{"cluster", "hostname", "storage_hostname", "id", "device", "pv_uuid"},
 	)
 
+	thinPoolUsedPercent = promDesc(
+		"thin_pool_used_percent",
+		"Data usage of the thin pools on the device in percent",
+		[]string{"cluster", "hostname", "storage_hostname", "id", "device", "pv_uuid"},
+	)
+
+	nodeSshLatencyP50 = promDesc(
+		"node_ssh_latency_p50_ms",
+		"Median ssh round-trip time to the node in milliseconds",
+		[]string{"cluster", "hostname", "storage_hostname"},
+	)
+
+	nodeSshLatencyP99 = promDesc(
+		"node_ssh_latency_p99_ms",
+		"99th percentile ssh round-trip time to the node in milliseconds",
+		[]string{"cluster", "hostname", "storage_hostname"},
+	)
+
+	bitrotCorruptedFiles = promDesc(
+		"bitrot_corrupted_files",
+		"Number of corrupted files found by the bitrot scrubber of the volume",
+		[]string{"volume"},
+	)
+
 	staleCount = promDesc(
 		"operations_stale_count",
 		"Number of Stale Operations",
@@ -138,6 +162,12 @@ var (
 		"Number of in flight Operations",
 		nil,
 	)
+
+	autoBrickReplacedCount = promDesc(
+		"brick_auto_replace_total",
+		"Number of offline bricks automatically replaced",
+		nil,
+	)
 )
 
 func promDesc(name, help string, variableLabels []string) *prometheus.Desc {
@@ -163,12 +193,17 @@ func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
 	ch <- deviceFreeInBytes
 	ch <- deviceUsedInBytes
 	ch <- brickCount
+	ch <- thinPoolUsedPercent
+	ch <- nodeSshLatencyP50
+	ch <- nodeSshLatencyP99
+	ch <- bitrotCorruptedFiles
 	/* following metrics are grabbed from operations list, gives number of stale|failed|new|total|inFlight operations */
 	ch <- staleCount
 	ch <- failedCount
 	ch <- newCount
 	ch <- totalCount
 	ch <- inFlightCount
+	ch <- autoBrickReplacedCount
 
 }
 
@@ -223,6 +258,11 @@ func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
 			inFlightCount,
 			prometheus.GaugeValue,
 			float64(opinfo.InFlight))
+
+		ch <- prometheus.MustNewConstMetric(
+			autoBrickReplacedCount,
+			prometheus.CounterValue,
+			float64(opinfo.AutoBrickReplaced))
 	}
 
 	for _, cluster := range topinfo.ClusterList {
@@ -247,6 +287,18 @@ func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
 			cluster.Id,
 		)
 
+		// only volumes whose bitrot status was queried
+		for _, volume := range cluster.Volumes {
+			if volume.Bitrot != nil {
+				ch <- prometheus.MustNewConstMetric(
+					bitrotCorruptedFiles,
+					prometheus.GaugeValue,
+					float64(volume.Bitrot.FilesCorrupted),
+					volume.Name,
+				)
+			}
+		}
+
 		for _, node := range cluster.Nodes {
 			ch <- prometheus.MustNewConstMetric(
 				deviceCount,
@@ -256,6 +308,24 @@ func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
 				node.Hostnames.Manage[0],
 				node.Hostnames.Storage[0],
 			)
+			if node.SshLatencyP99Ms > 0 {
+				ch <- prometheus.MustNewConstMetric(
+					nodeSshLatencyP50,
+					prometheus.GaugeValue,
+					node.SshLatencyP50Ms,
+					cluster.Id,
+					node.Hostnames.Manage[0],
+					node.Hostnames.Storage[0],
+				)
+				ch <- prometheus.MustNewConstMetric(
+					nodeSshLatencyP99,
+					prometheus.GaugeValue,
+					node.SshLatencyP99Ms,
+					cluster.Id,
+					node.Hostnames.Manage[0],
+					node.Hostnames.Storage[0],
+				)
+			}
 			for _, device := range node.DevicesInfo {
 				ch <- prometheus.MustNewConstMetric(
 					deviceSize,
@@ -333,6 +403,19 @@ func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
 					device.Name,
 					device.PvUUID,
 				)
+				if device.ThinPoolUsedPercent > 0 {
+					ch <- prometheus.MustNewConstMetric(
+						thinPoolUsedPercent,
+						prometheus.GaugeValue,
+						device.ThinPoolUsedPercent,
+						cluster.Id,
+						node.Hostnames.Manage[0],
+						node.Hostnames.Storage[0],
+						device.Id,
+						device.Name,
+						device.PvUUID,
+					)
+				}
 			}
 		}
 	}
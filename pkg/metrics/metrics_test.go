@@ -48,6 +48,22 @@ func TestMetricsEndpoint(t *testing.T) {
 			ClusterList: []api.Cluster{
 				{
 					Id: "c1",
+					Volumes: []api.VolumeInfoResponse{
+						{
+							VolumeInfo: api.VolumeInfo{
+								VolumeCreateRequest: api.VolumeCreateRequest{Name: "vol1"},
+							},
+							Bitrot: &api.BitrotStatusResponse{
+								State:          "Active",
+								FilesCorrupted: 3,
+							},
+						},
+						{
+							VolumeInfo: api.VolumeInfo{
+								VolumeCreateRequest: api.VolumeCreateRequest{Name: "vol2"},
+							},
+						},
+					},
 					Nodes: []api.NodeInfoResponse{
 						{
 							NodeInfo: api.NodeInfo{NodeAddRequest: api.NodeAddRequest{
@@ -65,6 +81,7 @@ func TestMetricsEndpoint(t *testing.T) {
 										Id:     "id1",
 										PvUUID: "pv1",
 									},
+									ThinPoolUsedPercent: 42.5,
 									Bricks: []api.BrickInfo{
 										{
 											Id:   "b1",
@@ -110,6 +127,21 @@ func TestMetricsEndpoint(t *testing.T) {
 		t.Fatal("heketi_device_size{cluster=\"c1\",device=\"d1\",hostname=\"n1\",id=\"id1\",pv_uuid=\"pv1\",storage_hostname=\"n1\"} 2 should be present in the metrics output")
 	}
 
+	match, err = regexp.Match("heketi_thin_pool_used_percent{cluster=\"c1\",device=\"d1\",hostname=\"n1\",id=\"id1\",pv_uuid=\"pv1\",storage_hostname=\"n1\"} 42.5", body)
+	if !match || err != nil {
+		t.Fatal("heketi_thin_pool_used_percent{cluster=\"c1\",device=\"d1\",hostname=\"n1\",id=\"id1\",pv_uuid=\"pv1\",storage_hostname=\"n1\"} 42.5 should be present in the metrics output")
+	}
+
+	match, err = regexp.Match("heketi_bitrot_corrupted_files{volume=\"vol1\"} 3", body)
+	if !match || err != nil {
+		t.Fatal("heketi_bitrot_corrupted_files{volume=\"vol1\"} 3 should be present in the metrics output")
+	}
+
+	match, err = regexp.Match("heketi_bitrot_corrupted_files{volume=\"vol2\"}", body)
+	if match || err != nil {
+		t.Fatal("heketi_bitrot_corrupted_files{volume=\"vol2\"} should not be present in the metrics output")
+	}
+
 	match, err = regexp.Match("operations_total_count 7", body)
 	if !match || err != nil {
 		t.Fatal("operations_total_count 7 should be present in the metrics output")
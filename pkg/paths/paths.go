@@ -21,8 +21,12 @@ const (
 )
 
 // VgIdToName return the string to be used for the name of
-// an LVM VG given the id of the vg.
+// an LVM VG given the id of the vg. Vgs named by a template,
+// see SetVgName, use the recorded name.
 func VgIdToName(vgId string) string {
+	if name, ok := lookupVgName(vgId); ok {
+		return name
+	}
 	return "vg_" + vgId
 }
 
@@ -87,9 +91,14 @@ func BrickThinLvName(vgId, brickId string) string {
 // BrickDevNode returns the path to the device node
 // managed by LVM/device-mapper for a brick.
 func BrickDevNode(vgId, brickId string) string {
+	// device-mapper escapes dashes within the vg and lv names
 	return path.Join(
 		deviceMapperRoot,
-		VgIdToName(vgId)+"-"+BrickIdToName(brickId))
+		dmEscape(VgIdToName(vgId))+"-"+dmEscape(BrickIdToName(brickId)))
+}
+
+func dmEscape(s string) string {
+	return strings.Replace(s, "-", "--", -1)
 }
 
 // VolumeIdToCloneLv converts a gluster volume UUID into the
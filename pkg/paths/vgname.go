@@ -0,0 +1,138 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package paths
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sync"
+	"text/template"
+)
+
+const (
+	// length of the ShortID template variable
+	shortIdLen = 8
+
+	// size of the simulated topology used to validate templates
+	simClusters        = 2
+	simNodesPerCluster = 4
+	simDevicesPerNode  = 8
+)
+
+var (
+	// characters lvm allows in vg names
+	vgNameRe = regexp.MustCompile(`^[a-zA-Z0-9+_.][a-zA-Z0-9+_.-]*$`)
+
+	// names of the vgs not following the default naming scheme,
+	// keyed by vg id
+	vgNames = struct {
+		sync.RWMutex
+		m map[string]string
+	}{m: map[string]string{}}
+)
+
+// VgNameParams are the variables available to a vg name template.
+type VgNameParams struct {
+	ClusterID string
+	NodeID    string
+	DeviceID  string
+	ShortID   string
+}
+
+// VgNameTemplate renders the names of the vgs of new devices.
+type VgNameTemplate struct {
+	tmpl *template.Template
+}
+
+// NewVgNameTemplate parses the given go template text and checks that
+// it produces valid and unique vg names for a simulated set of
+// clusters, nodes and devices.
+func NewVgNameTemplate(text string) (*VgNameTemplate, error) {
+	tmpl, err := template.New("vg_name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vg name template: %v", err)
+	}
+	t := &VgNameTemplate{tmpl}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Name returns the vg name for the device with the given id on the
+// given node and cluster.
+func (t *VgNameTemplate) Name(clusterId, nodeId, deviceId string) (string, error) {
+	shortId := deviceId
+	if len(shortId) > shortIdLen {
+		shortId = shortId[:shortIdLen]
+	}
+	var b bytes.Buffer
+	err := t.tmpl.Execute(&b, VgNameParams{
+		ClusterID: clusterId,
+		NodeID:    nodeId,
+		DeviceID:  deviceId,
+		ShortID:   shortId,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to render vg name: %v", err)
+	}
+	name := b.String()
+	if !vgNameRe.MatchString(name) {
+		return "", fmt.Errorf("invalid vg name %q", name)
+	}
+	return name, nil
+}
+
+func (t *VgNameTemplate) validate() error {
+	simId := func(f string, v ...interface{}) string {
+		sum := sha256.Sum256([]byte(fmt.Sprintf(f, v...)))
+		return fmt.Sprintf("%x", sum[:16])
+	}
+	seen := map[string]bool{}
+	for c := 0; c < simClusters; c++ {
+		clusterId := simId("cluster-%v", c)
+		for n := 0; n < simNodesPerCluster; n++ {
+			nodeId := simId("node-%v-%v", c, n)
+			for d := 0; d < simDevicesPerNode; d++ {
+				name, err := t.Name(clusterId, nodeId, simId("device-%v-%v-%v", c, n, d))
+				if err != nil {
+					return fmt.Errorf("invalid vg name template: %v", err)
+				}
+				if seen[name] {
+					return fmt.Errorf(
+						"invalid vg name template: vg name %q is not unique", name)
+				}
+				seen[name] = true
+			}
+		}
+	}
+	return nil
+}
+
+// SetVgName records the name of the vg with the given id for use
+// by VgIdToName. An empty name restores the default naming scheme.
+func SetVgName(vgId, name string) {
+	vgNames.Lock()
+	defer vgNames.Unlock()
+	if name == "" {
+		delete(vgNames.m, vgId)
+		return
+	}
+	vgNames.m[vgId] = name
+}
+
+func lookupVgName(vgId string) (string, bool) {
+	vgNames.RLock()
+	defer vgNames.RUnlock()
+	name, ok := vgNames.m[vgId]
+	return name, ok
+}
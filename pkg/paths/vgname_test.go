@@ -0,0 +1,76 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package paths
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/heketi/tests"
+)
+
+func TestVgNameTemplateShortID(t *testing.T) {
+	vt, err := NewVgNameTemplate("vg-{{.ShortID}}")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	name, err := vt.Name("c0ffee", "abcdef",
+		"0123456789abcdef0123456789abcdef")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, name == "vg-01234567",
+		`expected name == "vg-01234567", got:`, name)
+}
+
+func TestVgNameTemplateAllFields(t *testing.T) {
+	vt, err := NewVgNameTemplate(
+		"{{.ClusterID}}.{{.NodeID}}.{{.DeviceID}}")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	name, err := vt.Name("c1", "n1", "d1")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, name == "c1.n1.d1", `expected name == "c1.n1.d1", got:`, name)
+}
+
+func TestVgNameTemplateInvalid(t *testing.T) {
+	for _, text := range []string{
+		// not unique across devices
+		"vg-{{.ClusterID}}",
+		"vg-{{.NodeID}}",
+		// not valid in a vg name
+		"vg/{{.DeviceID}}",
+		"-{{.DeviceID}}",
+		// not a valid template
+		"vg-{{.ShortID",
+		"vg-{{.Missing}}",
+	} {
+		_, err := NewVgNameTemplate(text)
+		tests.Assert(t, err != nil, "expected err != nil for", text)
+		tests.Assert(t, strings.Contains(err.Error(), "invalid vg name template"),
+			"expected invalid template error, got:", err)
+	}
+}
+
+func TestSetVgName(t *testing.T) {
+	defer SetVgName("asdf", "")
+
+	SetVgName("asdf", "vg-custom")
+	tests.Assert(t, VgIdToName("asdf") == "vg-custom",
+		`expected "vg-custom", got:`, VgIdToName("asdf"))
+	tests.Assert(t, VgIdToName("other") == "vg_other",
+		`expected "vg_other", got:`, VgIdToName("other"))
+
+	// device-mapper doubles the dashes in vg names
+	result := BrickDevNode("asdf", "fireplace")
+	tests.Assert(t, result == "/dev/mapper/vg--custom-brick_fireplace",
+		"expected escaped device node, got:", result)
+
+	SetVgName("asdf", "")
+	tests.Assert(t, VgIdToName("asdf") == "vg_asdf",
+		`expected "vg_asdf", got:`, VgIdToName("asdf"))
+}
@@ -0,0 +1,54 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package ssh
+
+import (
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// key exchanges, ciphers and MACs approved by FIPS 140-2
+	FipsKeyExchanges = []string{
+		"ecdh-sha2-nistp256",
+		"ecdh-sha2-nistp384",
+		"ecdh-sha2-nistp521",
+	}
+	FipsCiphers = []string{
+		"aes128-gcm@openssh.com",
+		"aes256-ctr",
+		"aes192-ctr",
+		"aes128-ctr",
+	}
+	FipsMACs = []string{
+		"hmac-sha2-256-etm@openssh.com",
+		"hmac-sha2-256",
+	}
+
+	fipsMode = false
+)
+
+// SetFipsMode limits the algorithms of the ssh connections created
+// afterwards to the ones approved by FIPS 140-2.
+func SetFipsMode(enabled bool) {
+	fipsMode = enabled
+}
+
+// algorithmConfig returns the algorithms offered to the servers. The
+// defaults of the ssh package are used outside of FIPS mode.
+func algorithmConfig() ssh.Config {
+	if !fipsMode {
+		return ssh.Config{}
+	}
+	return ssh.Config{
+		KeyExchanges: FipsKeyExchanges,
+		Ciphers:      FipsCiphers,
+		MACs:         FipsMACs,
+	}
+}
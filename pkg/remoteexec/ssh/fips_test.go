@@ -0,0 +1,67 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package ssh
+
+import (
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	rex "github.com/heketi/heketi/pkg/remoteexec"
+	"github.com/heketi/tests"
+)
+
+func TestFipsModeAlgorithms(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	hostKey, _ := newTestSigner(t)
+	addr, stop := startTestServer(t, hostKey)
+	defer stop()
+	k, err := NewKnownHosts(tmpfile)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = k.Add([]string{addr}, hostKey.PublicKey())
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	cmds := rex.ToCmds([]string{"true"})
+
+	defer SetFipsMode(false)
+	SetFipsMode(true)
+	s := newTestExec(t, k)
+	tests.Assert(t, len(s.clientConfig.Ciphers) == len(FipsCiphers),
+		"expected", FipsCiphers, "got:", s.clientConfig.Ciphers)
+	tests.Assert(t, len(s.clientConfig.KeyExchanges) == len(FipsKeyExchanges),
+		"expected", FipsKeyExchanges, "got:", s.clientConfig.KeyExchanges)
+	tests.Assert(t, len(s.clientConfig.MACs) == len(FipsMACs),
+		"expected", FipsMACs, "got:", s.clientConfig.MACs)
+
+	// servers offering approved algorithms are connected to
+	_, err = s.ExecCommands(addr, cmds, 1, false)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	// servers offering only algorithms that are not approved are not
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.KeyExchanges = []string{"curve25519-sha256@libssh.org"}
+	config.Ciphers = []string{"chacha20-poly1305@openssh.com"}
+	config.AddHostKey(hostKey)
+	other, stopOther := startTestServerWithConfig(t, config)
+	defer stopOther()
+	err = k.Add([]string{other}, hostKey.PublicKey())
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	_, err = s.ExecCommands(other, cmds, 1, false)
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	SetFipsMode(false)
+	s = newTestExec(t, k)
+	tests.Assert(t, s.clientConfig.Ciphers == nil,
+		"expected default ciphers, got:", s.clientConfig.Ciphers)
+	_, err = s.ExecCommands(other, cmds, 1, false)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
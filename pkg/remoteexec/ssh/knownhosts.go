@@ -0,0 +1,191 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const fetchHostKeyTimeout = 30 * time.Second
+
+var errHostKeyFetched = errors.New("host key fetched")
+
+// KnownHosts verifies the host keys of the servers against a
+// known_hosts file. The file is read again when it changes.
+type KnownHosts struct {
+	path string
+
+	lock     sync.Mutex
+	modTime  time.Time
+	callback ssh.HostKeyCallback
+}
+
+// NewKnownHosts returns a KnownHosts using the file at path. The file
+// is created if it does not exist.
+func NewKnownHosts(path string) (*KnownHosts, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	k := &KnownHosts{path: path}
+	if _, err := k.load(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// load returns the callback for the current content of the file.
+func (k *KnownHosts) load() (ssh.HostKeyCallback, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	fi, err := os.Stat(k.path)
+	if err != nil {
+		return nil, err
+	}
+	if k.callback != nil && fi.ModTime().Equal(k.modTime) {
+		return k.callback, nil
+	}
+	callback, err := knownhosts.New(k.path)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing known hosts file %v: %v",
+			k.path, err)
+	}
+	k.callback = callback
+	k.modTime = fi.ModTime()
+	return callback, nil
+}
+
+// HostKeyCallback returns a callback that rejects the servers whose
+// host key is not in the file.
+func (k *KnownHosts) HostKeyCallback() ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		callback, err := k.load()
+		if err != nil {
+			return err
+		}
+		return callback(hostname, remote, key)
+	}
+}
+
+// Add records key as the host key of the given addresses. Lines of
+// the file naming any of the addresses are replaced.
+func (k *KnownHosts) Add(addresses []string, key ssh.PublicKey) error {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+
+	data, err := ioutil.ReadFile(k.path)
+	if err != nil {
+		return err
+	}
+
+	names := map[string]bool{}
+	for _, a := range addresses {
+		names[knownhosts.Normalize(a)] = true
+	}
+	lines := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" || !knownHostsLineMatches(line, names) {
+			lines = append(lines, line)
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	lines = append(lines, knownhosts.Line(addresses, key), "")
+
+	// replace the file at once so that connections never see
+	// a partially written file
+	tmp := k.path + ".tmp"
+	err = ioutil.WriteFile(tmp, []byte(strings.Join(lines, "\n")), 0600)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, k.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	k.callback = nil
+	return nil
+}
+
+// Update fetches the host key of the server at addr and records it
+// for addr and the given hostnames. It returns the fingerprint of
+// the key.
+func (k *KnownHosts) Update(addr string, hostnames ...string) (string, error) {
+	key, err := FetchHostKey(addr, fetchHostKeyTimeout)
+	if err != nil {
+		return "", err
+	}
+	addresses := []string{addr}
+	for _, h := range hostnames {
+		if knownhosts.Normalize(h) != knownhosts.Normalize(addr) {
+			addresses = append(addresses, h)
+		}
+	}
+	if err := k.Add(addresses, key); err != nil {
+		return "", err
+	}
+	return ssh.FingerprintSHA256(key), nil
+}
+
+// knownHostsLineMatches returns true if the known_hosts line names
+// any of the normalized addresses. Comments, markers and hashed
+// hostnames never match.
+func knownHostsLineMatches(line string, names map[string]bool) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 ||
+		strings.HasPrefix(fields[0], "#") ||
+		strings.HasPrefix(fields[0], "@") {
+		return false
+	}
+	for _, h := range strings.Split(fields[0], ",") {
+		if names[h] {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchHostKey returns the host key presented by the server at addr.
+// The connection is closed once the key is received so no credentials
+// are needed.
+func FetchHostKey(addr string, timeout time.Duration) (ssh.PublicKey, error) {
+	var hostKey ssh.PublicKey
+	config := &ssh.ClientConfig{
+		Config: algorithmConfig(),
+		User:   "heketi",
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errHostKeyFetched
+		},
+		Timeout: timeout,
+	}
+	client, err := ssh.Dial("tcp", addr, config)
+	if err == nil {
+		client.Close()
+	}
+	if hostKey == nil {
+		return nil, fmt.Errorf("Unable to get host key of %v: %v", addr, err)
+	}
+	return hostKey, nil
+}
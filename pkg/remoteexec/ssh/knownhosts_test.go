@@ -0,0 +1,237 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package ssh
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/heketi/heketi/pkg/logging"
+	rex "github.com/heketi/heketi/pkg/remoteexec"
+	"github.com/heketi/tests"
+)
+
+func newTestSigner(t *testing.T) (ssh.Signer, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	signer, err := ssh.NewSignerFromKey(key)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	der, err := x509.MarshalECPrivateKey(key)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	return signer, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+// startTestServer starts an ssh server presenting the given host key
+// that accepts any client and answers every command with "ok".
+func startTestServer(t *testing.T, hostKey ssh.Signer) (string, func()) {
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(hostKey)
+	return startTestServerWithConfig(t, config)
+}
+
+// startTestServerWithConfig starts an ssh server using the given
+// configuration that answers every command with "ok".
+func startTestServerWithConfig(t *testing.T,
+	config *ssh.ServerConfig) (string, func()) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestConn(c, config)
+		}
+	}()
+	return l.Addr().String(), func() { l.Close() }
+}
+
+func serveTestConn(c net.Conn, config *ssh.ServerConfig) {
+	conn, chans, reqs, err := ssh.NewServerConn(c, config)
+	if err != nil {
+		c.Close()
+		return
+	}
+	defer conn.Close()
+	go ssh.DiscardRequests(reqs)
+
+	for newCh := range chans {
+		if newCh.ChannelType() != "session" {
+			newCh.Reject(ssh.UnknownChannelType, "")
+			continue
+		}
+		ch, requests, err := newCh.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range requests {
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+				req.Reply(true, nil)
+				ch.Write([]byte("ok\n"))
+				ch.SendRequest("exit-status", false,
+					ssh.Marshal(struct{ Status uint32 }{0}))
+				ch.Close()
+			}
+		}()
+	}
+}
+
+func newTestExec(t *testing.T, k *KnownHosts) *SshExec {
+	_, clientKey := newTestSigner(t)
+	s, err := NewSshExecWithKey(logging.NewLogger("[test]", logging.LEVEL_NOLOG),
+		"heketi", clientKey, "")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	s.SetKnownHosts(k)
+	return s
+}
+
+func TestKnownHostsVerify(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	hostKey, _ := newTestSigner(t)
+	addr, stop := startTestServer(t, hostKey)
+	defer stop()
+
+	k, err := NewKnownHosts(tmpfile)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	s := newTestExec(t, k)
+	cmds := rex.ToCmds([]string{"true"})
+
+	// hosts not in the file are rejected
+	_, err = s.ExecCommands(addr, cmds, 1, false)
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "key is unknown"),
+		"unexpected error:", err)
+
+	err = k.Add([]string{addr}, hostKey.PublicKey())
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	results, err := s.ExecCommands(addr, cmds, 1, false)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(results) == 1 && results[0].Output == "ok\n",
+		"unexpected results:", results)
+}
+
+func TestKnownHostsVerifyHostname(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	hostKey, _ := newTestSigner(t)
+	addr, stop := startTestServer(t, hostKey)
+	defer stop()
+	_, port, err := net.SplitHostPort(addr)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	host := net.JoinHostPort("node1", port)
+
+	// the key is only known for the hostname
+	k, err := NewKnownHosts(tmpfile)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = k.Add([]string{host}, hostKey.PublicKey())
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	s := newTestExec(t, k)
+	cmds := rex.ToCmds([]string{"true"})
+
+	_, err = s.ExecCommands(addr, cmds, 1, false)
+	tests.Assert(t, err != nil, "expected err != nil")
+
+	results, err := s.ExecCommandsAt(addr, host, cmds, 1, false)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, len(results) == 1 && results[0].Output == "ok\n",
+		"unexpected results:", results)
+}
+
+func TestKnownHostsMismatch(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	hostKey, _ := newTestSigner(t)
+	otherKey, _ := newTestSigner(t)
+	addr, stop := startTestServer(t, hostKey)
+	defer stop()
+
+	k, err := NewKnownHosts(tmpfile)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = k.Add([]string{addr}, otherKey.PublicKey())
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	s := newTestExec(t, k)
+	_, err = s.ExecCommands(addr, rex.ToCmds([]string{"true"}), 1, false)
+	tests.Assert(t, err != nil, "expected err != nil")
+	tests.Assert(t, strings.Contains(err.Error(), "key mismatch"),
+		"unexpected error:", err)
+}
+
+func TestKnownHostsUpdate(t *testing.T) {
+	tmpfile := tests.Tempfile()
+	defer os.Remove(tmpfile)
+
+	hostKey, _ := newTestSigner(t)
+	otherKey, _ := newTestSigner(t)
+	addr, stop := startTestServer(t, hostKey)
+	defer stop()
+
+	key, err := FetchHostKey(addr, time.Second)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, bytes.Equal(key.Marshal(), hostKey.PublicKey().Marshal()),
+		"expected the host key of the server")
+
+	k, err := NewKnownHosts(tmpfile)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = k.Add([]string{addr, "node1"}, otherKey.PublicKey())
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	err = k.Add([]string{"node2"}, otherKey.PublicKey())
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+
+	fingerprint, err := k.Update(addr, "node1")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, fingerprint == ssh.FingerprintSHA256(hostKey.PublicKey()),
+		"unexpected fingerprint:", fingerprint)
+
+	// the stale entry is replaced, other hosts are kept
+	data, err := ioutil.ReadFile(tmpfile)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	tests.Assert(t, len(lines) == 2, "expected 2 lines, got:", lines)
+	tests.Assert(t, strings.HasPrefix(lines[0], "node2 "), lines[0])
+
+	s := newTestExec(t, k)
+	_, err = s.ExecCommands(addr, rex.ToCmds([]string{"true"}), 1, false)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestFetchHostKeyFails(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	addr := l.Addr().String()
+	l.Close()
+
+	_, err = FetchHostKey(addr, time.Second)
+	tests.Assert(t, err != nil, "expected err != nil")
+}
@@ -74,6 +74,7 @@ func NewSshExecWithAuth(logger *logging.Logger, user string) *SshExec {
 		User:            user,
 		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signers...)},
 		HostKeyCallback: getHostKeyCallback(),
+		Config:          algorithmConfig(),
 	}
 
 	return sshexec
@@ -99,11 +100,42 @@ func NewSshExecWithKeyFile(logger *logging.Logger, user string, file string) *Ss
 			ssh.PublicKeys(key),
 		},
 		HostKeyCallback: getHostKeyCallback(),
+		Config:          algorithmConfig(),
 	}
 
 	return sshexec
 }
 
+// NewSshExecWithKey returns an SshExec that authenticates using the
+// given PEM encoded private key. If passphrase is not empty it is used
+// to decrypt the key.
+func NewSshExecWithKey(logger *logging.Logger,
+	user string, pemBytes []byte, passphrase string) (*SshExec, error) {
+
+	var key ssh.Signer
+	var err error
+	if passphrase == "" {
+		key, err = ssh.ParsePrivateKey(pemBytes)
+	} else {
+		key, err = ssh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sshexec := &SshExec{}
+	sshexec.logger = logger
+	sshexec.clientConfig = &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeys(key),
+		},
+		HostKeyCallback: getHostKeyCallback(),
+		Config:          algorithmConfig(),
+	}
+	return sshexec, nil
+}
+
 func getHostKeyCallback() ssh.HostKeyCallback {
 	hostKeysFiles := os.Getenv("SSH_KNOWN_HOSTS")
 	if len(hostKeysFiles) == 0 {
@@ -118,6 +150,30 @@ func getHostKeyCallback() ssh.HostKeyCallback {
 	return knownHostsCallback
 }
 
+// SetKnownHosts verifies the host keys of the servers against the
+// given known hosts instead of the SSH_KNOWN_HOSTS files.
+func (s *SshExec) SetKnownHosts(k *KnownHosts) {
+	s.clientConfig.HostKeyCallback = k.HostKeyCallback()
+}
+
+// dial connects to the server at addr, verifying its host key as the
+// key of host.
+func (s *SshExec) dial(addr, host string) (*ssh.Client, error) {
+	if addr == host {
+		return ssh.Dial("tcp", addr, s.clientConfig)
+	}
+	conn, err := net.DialTimeout("tcp", addr, s.clientConfig.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, host, s.clientConfig)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
 // This function was based from https://github.com/coreos/etcd-manager/blob/master/main.go
 func (s *SshExec) ConnectAndExec(host string, commands []string, timeoutMinutes int, useSudo bool) ([]string, error) {
 
@@ -132,11 +188,21 @@ func (s *SshExec) ExecCommands(
 	host string, commands rex.Cmds,
 	timeoutMinutes int, useSudo bool) (rex.Results, error) {
 
+	return s.ExecCommandsAt(host, host, commands, timeoutMinutes, useSudo)
+}
+
+// ExecCommandsAt runs the commands on the server listening at addr
+// and verifies its host key as the key of host. This allows connecting
+// to a known address of a host whose name can not be resolved.
+func (s *SshExec) ExecCommandsAt(
+	addr, host string, commands rex.Cmds,
+	timeoutMinutes int, useSudo bool) (rex.Results, error) {
+
 	results := make(rex.Results, len(commands))
 	cmdlog := rexlog.NewCommandLogger(s.logger)
 
 	// :TODO: Will need a timeout here in case the server does not respond
-	client, err := ssh.Dial("tcp", host, s.clientConfig)
+	client, err := s.dial(addr, host)
 	if err != nil {
 		s.logger.Warning("Failed to create SSH connection to %v: %v", host, err)
 		return nil, err
@@ -13,12 +13,15 @@
 package utils
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
 )
 
 // Return the body from a response as a string
@@ -42,5 +45,11 @@ func GetErrorFromResponse(r *http.Response) error {
 	if len(s) == 0 {
 		return fmt.Errorf("server did not provide a message (status %v: %v)", r.StatusCode, http.StatusText(r.StatusCode))
 	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var e api.ErrorResponse
+		if err := json.Unmarshal([]byte(s), &e); err == nil && e.Message != "" {
+			return &e
+		}
+	}
 	return errors.New(s)
 }
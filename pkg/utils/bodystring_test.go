@@ -16,9 +16,13 @@ import (
 	"bytes"
 	"errors"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/heketi/tests"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
 )
 
 type testGetStringFromResponseBody struct {
@@ -134,3 +138,31 @@ func TestGetErrorFromResponseEmptyString(t *testing.T) {
 	tests.Assert(t, err.Error() == "server did not provide a message (status 200: OK)",
 		`expected err.Error() == "server did not provide a message (status 200: OK)", got:`, err.Error())
 }
+
+func TestGetErrorFromResponseJson(t *testing.T) {
+	w := httptest.NewRecorder()
+	HttpErrorCode(w, api.ErrorVolumeNotFound, "Id <x> not found",
+		http.StatusNotFound)
+	resp := w.Result()
+	resp.ContentLength = int64(w.Body.Len())
+
+	err := GetErrorFromResponse(resp)
+	tests.Assert(t, err != nil, "expected err != nil, got:", err)
+	e, ok := err.(*api.ErrorResponse)
+	tests.Assert(t, ok, "expected *api.ErrorResponse, got:", err)
+	tests.Assert(t, e.Code == api.ErrorVolumeNotFound,
+		"expected e.Code == api.ErrorVolumeNotFound, got:", e.Code)
+	tests.Assert(t, err.Error() == "Id <x> not found",
+		`expected err.Error() == "Id <x> not found", got:`, err.Error())
+}
+
+func TestHttpErrorStatusCode(t *testing.T) {
+	w := httptest.NewRecorder()
+	HttpError(w, "too busy", http.StatusTooManyRequests)
+	tests.Assert(t, w.Code == http.StatusTooManyRequests,
+		"expected w.Code == http.StatusTooManyRequests, got:", w.Code)
+	tests.Assert(t, w.Header().Get("Content-Type") == "application/json; charset=UTF-8",
+		"unexpected content type:", w.Header().Get("Content-Type"))
+	tests.Assert(t, strings.Contains(w.Body.String(), `"code":"SERVER_BUSY"`),
+		"unexpected body:", w.Body.String())
+}
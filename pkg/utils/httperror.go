@@ -0,0 +1,46 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), as published by the Free Software Foundation,
+// or under the Apache License, Version 2.0 <LICENSE-APACHE2 or
+// http://www.apache.org/licenses/LICENSE-2.0>.
+//
+// You may not use this file except in compliance with those terms.
+//
+
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+)
+
+// WriteErrorResponse replies to the request with the given status and
+// the error encoded as JSON.
+func WriteErrorResponse(w http.ResponseWriter, status int, e *api.ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(e); err != nil {
+		panic(err)
+	}
+}
+
+// HttpErrorCode replies to the request with a structured error using
+// the given error code.
+func HttpErrorCode(w http.ResponseWriter, code api.ErrorCode, msg string, status int) {
+	WriteErrorResponse(w, status, &api.ErrorResponse{Code: code, Message: msg})
+}
+
+// HttpError replies to the request with a structured error. It is a
+// replacement for http.Error where the error code is derived from
+// the status.
+func HttpError(w http.ResponseWriter, msg string, status int) {
+	HttpErrorCode(w, api.ErrorCodeForStatus(status), msg, status)
+}
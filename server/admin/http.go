@@ -31,6 +31,10 @@ var (
 func (s *ServerState) AllowRequest(r *http.Request) bool {
 	switch s.Get() {
 	case api.AdminStateReadOnly:
+		if r.Method == http.MethodPut && r.URL.Path == "/admin/mode" {
+			// the only way out of read-only mode over the api
+			return true
+		}
 		return r.Method == http.MethodGet || r.Method == http.MethodHead
 	case api.AdminStateLocal:
 		if r.Method == http.MethodGet || r.Method == http.MethodHead {
@@ -47,9 +51,11 @@ func (s *ServerState) ServeHTTP(
 	w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 
 	if !s.AllowRequest(r) {
-		http.Error(w,
-			"Service disabled for maintenance",
-			http.StatusServiceUnavailable)
+		msg := "Service disabled for maintenance"
+		if s.Get() == api.AdminStateReadOnly {
+			msg = "heketi is in read-only mode"
+		}
+		utils.HttpError(w, msg, http.StatusServiceUnavailable)
 		return
 	}
 	next(w, r)
@@ -66,6 +72,11 @@ func (s *ServerState) SetRoutes(router *mux.Router) error {
 		Path("/admin").
 		Name("SetAdminState").
 		Handler(http.HandlerFunc(s.SetAdminState))
+	router.
+		Methods("PUT").
+		Path("/admin/mode").
+		Name("SetAdminMode").
+		Handler(http.HandlerFunc(s.SetAdminMode))
 	return nil
 }
 
@@ -85,17 +96,46 @@ func (s *ServerState) SetAdminState(w http.ResponseWriter, r *http.Request) {
 	msg := api.AdminStatus{}
 	err := utils.GetJsonFromRequest(r, &msg)
 	if err != nil {
-		http.Error(w,
+		utils.HttpError(w,
 			fmt.Sprintf("request unable to be parsed: %s", err.Error()),
 			http.StatusBadRequest)
 		return
 	}
 
 	if err := msg.Validate(); err != nil {
-		http.Error(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	s.Set(msg.State)
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// SetAdminMode switches the server between read-only and normal
+// operation. Unlike SetAdminState it is allowed in read-only mode.
+func (s *ServerState) SetAdminMode(w http.ResponseWriter, r *http.Request) {
+	msg := api.AdminMode{}
+	err := utils.GetJsonFromRequest(r, &msg)
+	if err != nil {
+		utils.HttpError(w,
+			fmt.Sprintf("request unable to be parsed: %s", err.Error()),
+			http.StatusBadRequest)
+		return
+	}
+
+	if err := msg.Validate(); err != nil {
+		utils.HttpError(w, "validation failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if msg.Mode == api.AdminModeReadOnly {
+		s.Set(api.AdminStateReadOnly)
+	} else {
+		s.Set(api.AdminStateNormal)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(msg); err != nil {
+		panic(err)
+	}
+}
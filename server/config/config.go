@@ -27,8 +27,12 @@ type Config struct {
 	EnableTls            bool                     `json:"enable_tls"`
 	CertFile             string                   `json:"cert_file"`
 	KeyFile              string                   `json:"key_file"`
+	FipsMode             bool                     `json:"fips_mode"`
 	Profiling            bool                     `json:"profiling"`
 	DefaultState         string                   `json:"default_state"`
+	ReadOnlyMode         bool                     `json:"readonly_mode"`
+	MaxRequestBodyBytes  int64                    `json:"max_request_body_bytes"`
+	ResponseEnvelope     bool                     `json:"response_envelope"`
 
 	// pull in the config sub-object for glusterfs app
 	GlusterFS *glusterfs.GlusterFSConfig `json:"glusterfs"`
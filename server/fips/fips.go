@@ -0,0 +1,185 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+// Package fips restricts the cryptography of the server to algorithms
+// approved by FIPS 140-2.
+package fips
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const (
+	// minimum length of the keys used to sign the api requests,
+	// 112 bits as required for HMAC keys by NIST SP 800-131A
+	MinKeyLength = 14
+)
+
+var (
+	ErrModuleDisabled = errors.New("no FIPS 140 validated crypto module" +
+		" is enabled (build with GOEXPERIMENT=boringcrypto or run with" +
+		" GODEBUG=fips140=on)")
+
+	// reports whether the crypto of go is provided by a FIPS 140
+	// validated module, depends on how the server is built
+	moduleEnabled = cryptoModuleEnabled
+
+	// TLS 1.2 cipher suites with approved key exchange, AES-GCM
+	// encryption and SHA-2 MACs
+	CipherSuites = []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+	}
+
+	// NIST curves, X25519 is not approved
+	CurvePreferences = []tls.CurveID{
+		tls.CurveP256,
+		tls.CurveP384,
+		tls.CurveP521,
+	}
+)
+
+// TLSConfig returns the TLS configuration of the server. Outside of
+// FIPS mode the defaults of go are used. In FIPS mode only TLS 1.2 is
+// offered as the cipher suites of later versions can not be limited.
+func TLSConfig(fipsMode bool) *tls.Config {
+	if !fipsMode {
+		return &tls.Config{}
+	}
+	return &tls.Config{
+		MinVersion:               tls.VersionTLS12,
+		MaxVersion:               tls.VersionTLS12,
+		CipherSuites:             CipherSuites,
+		CurvePreferences:         CurvePreferences,
+		PreferServerCipherSuites: true,
+	}
+}
+
+// ValidateKey checks that a key used to sign the api requests with
+// HMAC-SHA256 is long enough to be used in FIPS mode.
+func ValidateKey(name, key string) error {
+	if len(key) < MinKeyLength {
+		return fmt.Errorf("%v key must be at least %v bytes long in FIPS mode",
+			name, MinKeyLength)
+	}
+	return nil
+}
+
+func decodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Validate checks that the crypto of the server is provided by a
+// FIPS 140 validated module and runs known answer tests of the
+// algorithms used in FIPS mode. An error is returned if no validated
+// module is enabled or if one of the algorithms is not available or
+// does not compute the expected result.
+func Validate() error {
+	if !moduleEnabled() {
+		return ErrModuleDisabled
+	}
+	for _, test := range []struct {
+		name string
+		f    func() error
+	}{
+		{"SHA-256", testSha256},
+		{"HMAC-SHA256", testHmacSha256},
+		{"AES-256-GCM", testAesGcm},
+		{"ECDSA P-256", testEcdsa},
+	} {
+		if err := test.f(); err != nil {
+			return fmt.Errorf("FIPS self-test of %v failed: %v", test.name, err)
+		}
+	}
+	return nil
+}
+
+func testSha256() error {
+	expected := decodeHex(
+		"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad")
+	sum := sha256.Sum256([]byte("abc"))
+	if !bytes.Equal(sum[:], expected) {
+		return fmt.Errorf("unexpected digest %x", sum)
+	}
+	return nil
+}
+
+// test case 2 of RFC 4231
+func testHmacSha256() error {
+	expected := decodeHex(
+		"5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843")
+	mac := hmac.New(sha256.New, []byte("Jefe"))
+	mac.Write([]byte("what do ya want for nothing?"))
+	if sum := mac.Sum(nil); !hmac.Equal(sum, expected) {
+		return fmt.Errorf("unexpected mac %x", sum)
+	}
+	return nil
+}
+
+// test case 14 of the GCM specification
+func testAesGcm() error {
+	expected := decodeHex(
+		"cea7403d4d606b6e074ec5d3baf39d18d0d1c8a799996bf0265b98b5d48ab919")
+	block, err := aes.NewCipher(make([]byte, 32))
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	sealed := gcm.Seal(nil, nonce, make([]byte, 16), nil)
+	if !bytes.Equal(sealed, expected) {
+		return fmt.Errorf("unexpected ciphertext %x", sealed)
+	}
+	plain, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(plain, make([]byte, 16)) {
+		return fmt.Errorf("unexpected plaintext %x", plain)
+	}
+	return nil
+}
+
+func testEcdsa() error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte("heketi"))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return err
+	}
+	if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+		return fmt.Errorf("signature does not verify")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+package fips
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/heketi/tests"
+)
+
+func TestTLSConfigDefault(t *testing.T) {
+	c := TLSConfig(false)
+	tests.Assert(t, c.CipherSuites == nil,
+		"expected default cipher suites, got:", c.CipherSuites)
+	tests.Assert(t, c.MinVersion == 0, "expected default min version, got:", c.MinVersion)
+}
+
+func TestTLSConfigFips(t *testing.T) {
+	c := TLSConfig(true)
+	tests.Assert(t, c.MinVersion == tls.VersionTLS12,
+		"expected TLS 1.2, got:", c.MinVersion)
+	tests.Assert(t, len(c.CipherSuites) == len(CipherSuites),
+		"expected", CipherSuites, "got:", c.CipherSuites)
+	for _, s := range c.CipherSuites {
+		for _, weak := range []uint16{
+			tls.TLS_RSA_WITH_RC4_128_SHA,
+			tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+		} {
+			tests.Assert(t, s != weak, "unexpected cipher suite", s)
+		}
+	}
+	for _, curve := range c.CurvePreferences {
+		tests.Assert(t, curve != tls.X25519, "unexpected curve X25519")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	defer tests.Patch(&moduleEnabled, func() bool { return true }).Restore()
+	err := Validate()
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
+
+func TestValidateModuleDisabled(t *testing.T) {
+	defer tests.Patch(&moduleEnabled, func() bool { return false }).Restore()
+	err := Validate()
+	tests.Assert(t, err == ErrModuleDisabled,
+		"expected ErrModuleDisabled, got:", err)
+}
+
+func TestValidateKey(t *testing.T) {
+	err := ValidateKey("admin", "secret")
+	tests.Assert(t, err != nil, "expected err != nil")
+	err = ValidateKey("admin", "a much longer secret")
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+}
@@ -0,0 +1,23 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+//go:build boringcrypto
+// +build boringcrypto
+
+package fips
+
+import (
+	"crypto/boring"
+)
+
+// built with GOEXPERIMENT=boringcrypto, the BoringCrypto module is
+// used if the platform supports it
+func cryptoModuleEnabled() bool {
+	return boring.Enabled()
+}
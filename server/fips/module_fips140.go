@@ -0,0 +1,23 @@
+//
+// Copyright (c) 2018 The heketi Authors
+//
+// This file is licensed to you under your choice of the GNU Lesser
+// General Public License, version 3 or any later version (LGPLv3 or
+// later), or the GNU General Public License, version 2 (GPLv2), in all
+// cases as published by the Free Software Foundation.
+//
+
+//go:build go1.24 && !boringcrypto
+// +build go1.24,!boringcrypto
+
+package fips
+
+import (
+	"crypto/fips140"
+)
+
+// the go cryptographic module runs in FIPS 140-3 mode if enabled with
+// GODEBUG=fips140=on
+func cryptoModuleEnabled() bool {
+	return fips140.Enabled()
+}
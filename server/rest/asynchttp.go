@@ -11,12 +11,14 @@ package rest
 
 import (
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/heketi/heketi/pkg/idgen"
 	"github.com/heketi/heketi/pkg/logging"
+	"github.com/heketi/heketi/pkg/utils"
 	"github.com/lpabon/godbc"
 )
 
@@ -28,6 +30,8 @@ var (
 type AsyncHttpHandler struct {
 	err          error
 	completed    bool
+	progress     int
+	hasProgress  bool
 	manager      *AsyncHttpManager
 	location, id string
 }
@@ -138,6 +142,21 @@ func (a *AsyncHttpManager) AsyncHttpRedirectFunc(w http.ResponseWriter,
 	http.Redirect(w, r, handler.Url(), http.StatusAccepted)
 }
 
+// AsyncHttpRedirectProgressFunc is like AsyncHttpRedirectFunc for
+// handler functions that report how far they got. The function passed to
+// handlerfunc records the progress, from 0 to 100, which is returned in
+// the X-Progress header while the operation is pending.
+func (a *AsyncHttpManager) AsyncHttpRedirectProgressFunc(w http.ResponseWriter,
+	r *http.Request,
+	handlerfunc func(progress func(int)) (string, error)) {
+
+	handler := a.NewHandler()
+	handler.handle(func() (string, error) {
+		return handlerfunc(handler.SetProgress)
+	})
+	http.Redirect(w, r, handler.Url(), http.StatusAccepted)
+}
+
 func (a *AsyncHttpManager) AsyncHttpRedirectUsing(w http.ResponseWriter,
 	r *http.Request,
 	id string,
@@ -152,7 +171,8 @@ func (a *AsyncHttpManager) AsyncHttpRedirectUsing(w http.ResponseWriter,
 // Register this handler with a router like Gorilla Mux
 //
 // Returns the following HTTP status codes
-// 		200 Operation is still pending
+// 		200 Operation is still pending, the X-Progress header holds the
+//			progress of operations that report it
 //		404 Id requested does not exist
 //		500 Operation finished and has failed.  Body will be filled in with the
 //			error in plain text.
@@ -196,7 +216,7 @@ func (a *AsyncHttpManager) HandlerStatus(w http.ResponseWriter, r *http.Request)
 			if handler.err != nil {
 
 				// Return 500 status
-				http.Error(w, handler.err.Error(), http.StatusInternalServerError)
+				utils.HttpError(w, handler.err.Error(), http.StatusInternalServerError)
 			} else {
 				if handler.location != "" {
 
@@ -215,11 +235,14 @@ func (a *AsyncHttpManager) HandlerStatus(w http.ResponseWriter, r *http.Request)
 			// Still pending
 			// Could add a JSON body here later
 			w.Header().Add("X-Pending", "true")
+			if handler.hasProgress {
+				w.Header().Add("X-Progress", strconv.Itoa(handler.progress))
+			}
 			w.WriteHeader(http.StatusOK)
 		}
 
 	} else {
-		http.Error(w, "Id not found", http.StatusNotFound)
+		utils.HttpError(w, "Id not found", http.StatusNotFound)
 	}
 }
 
@@ -231,6 +254,16 @@ func (h *AsyncHttpHandler) Url() string {
 	return h.manager.route + "/" + h.id
 }
 
+// Records how far, from 0 to 100, the asynchronous operation got
+func (h *AsyncHttpHandler) SetProgress(percent int) {
+
+	h.manager.lock.Lock()
+	defer h.manager.lock.Unlock()
+
+	h.progress = percent
+	h.hasProgress = true
+}
+
 // Registers that the handler has completed with an error
 func (h *AsyncHttpHandler) CompletedWithError(err error) {
 
@@ -22,6 +22,9 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/heketi/tests"
+
+	"github.com/heketi/heketi/pkg/glusterfs/api"
+	"github.com/heketi/heketi/pkg/utils"
 )
 
 func TestNewManager(t *testing.T) {
@@ -124,9 +127,13 @@ func TestHandlerCompletions(t *testing.T) {
 	tests.Assert(t, err == nil)
 
 	// Check body has error string
-	body, err := ioutil.ReadAll(r.Body)
-	r.Body.Close()
-	tests.Assert(t, string(body) == error_string+"\n")
+	var e api.ErrorResponse
+	err = utils.GetJsonFromResponse(r, &e)
+	tests.Assert(t, err == nil, "expected err == nil, got:", err)
+	tests.Assert(t, e.Message == error_string,
+		"expected e.Message == error_string, got:", e.Message)
+	tests.Assert(t, e.Code == api.ErrorInternal,
+		"expected e.Code == api.ErrorInternal, got:", e.Code)
 
 	// Create new handler
 	handler = manager.NewHandler()
@@ -190,10 +197,9 @@ func TestAsyncHttpRedirectFunc(t *testing.T) {
 		tests.Assert(t, err == nil)
 		if r.Header.Get("X-Pending") != "true" {
 			tests.Assert(t, r.StatusCode == http.StatusInternalServerError)
-			body, err := ioutil.ReadAll(r.Body)
-			r.Body.Close()
-			tests.Assert(t, err == nil)
-			tests.Assert(t, string(body) == "Test Handler Function\n")
+			err = utils.GetErrorFromResponse(r)
+			tests.Assert(t, err.Error() == "Test Handler Function",
+				"expected error message, got:", err)
 			break
 		} else {
 			tests.Assert(t, r.StatusCode == http.StatusOK)
@@ -260,6 +266,69 @@ func TestAsyncHttpRedirectFunc(t *testing.T) {
 
 }
 
+func TestAsyncHttpRedirectProgressFunc(t *testing.T) {
+	// Setup asynchronous manager
+	route := "/x"
+	manager := NewAsyncHttpManager(route)
+
+	// Setup the route
+	router := mux.NewRouter()
+	router.HandleFunc(route+"/{id}", manager.HandlerStatus).Methods("GET")
+
+	step := make(chan bool)
+	router.HandleFunc("/app", func(w http.ResponseWriter, r *http.Request) {
+		manager.AsyncHttpRedirectProgressFunc(w, r,
+			func(progress func(int)) (string, error) {
+				<-step
+				progress(50)
+				<-step
+				return "", nil
+			})
+	}).Methods("GET")
+
+	// Setup the server
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	r, err := http.Get(ts.URL + "/app")
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.StatusCode == http.StatusAccepted)
+	location, err := r.Location()
+	tests.Assert(t, err == nil)
+
+	// no progress is reported until the function sets it
+	r, err = http.Get(location.String())
+	tests.Assert(t, err == nil)
+	tests.Assert(t, r.Header.Get("X-Pending") == "true")
+	tests.Assert(t, r.Header.Get("X-Progress") == "",
+		"expected no progress, got:", r.Header.Get("X-Progress"))
+
+	step <- true
+	for {
+		r, err = http.Get(location.String())
+		tests.Assert(t, err == nil)
+		tests.Assert(t, r.Header.Get("X-Pending") == "true")
+		if r.Header.Get("X-Progress") != "" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	tests.Assert(t, r.Header.Get("X-Progress") == "50",
+		"expected progress 50, got:", r.Header.Get("X-Progress"))
+
+	step <- true
+	for {
+		r, err = http.Get(location.String())
+		tests.Assert(t, err == nil)
+		if r.Header.Get("X-Pending") != "true" {
+			tests.Assert(t, r.StatusCode == http.StatusNoContent)
+			tests.Assert(t, r.Header.Get("X-Progress") == "")
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
 func TestHandlerConcurrency(t *testing.T) {
 
 	// Setup asynchronous manager